@@ -22,6 +22,21 @@ import (
 //	@name						Authorization
 //	@description				Private server authentication
 
+//	@securitydefinitions.apikey	EnrollmentToken
+//	@in							header
+//	@name						Authorization
+//	@description				Device enrollment token
+
+//	@securitydefinitions.apikey	UpstreamKey
+//	@in							header
+//	@name						Authorization
+//	@description				Private instance key for push relay
+
+//	@securitydefinitions.apikey	AdminToken
+//	@in							header
+//	@name						Authorization
+//	@description				Admin API token
+
 //	@title			SMS Gateway for Android™ API
 //	@version		{APP_VERSION}
 //	@description	This API provides programmatic access to sending SMS messages on Android devices. Features include sending SMS, checking message status, device management, webhook configuration, and system health checks.
@@ -1,16 +1,38 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/ratelimit"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/alerts"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/autoreplies"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/crashes"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/email"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/listener"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/mqtt"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/relay"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sandbox"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sse"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/stats"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/storage"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/online"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/redaction"
 	"github.com/capcom6/go-infra-fx/config"
 	"github.com/capcom6/go-infra-fx/db"
 	"github.com/capcom6/go-infra-fx/http"
@@ -33,11 +55,22 @@ var Module = fx.Module(
 	fx.Provide(func(cfg Config) http.Config {
 		return http.Config{
 			Listen:  cfg.HTTP.Listen,
-			Proxies: cfg.HTTP.Proxies,
+			Proxies: listener.TrustedProxies(cfg.HTTP.Listen, cfg.HTTP.Proxies),
 
 			WriteTimeout: 30 * time.Minute, // SSE requires longer timeout
 		}
 	}),
+	fx.Provide(func(cfg Config) (listener.Config, error) {
+		mode, err := strconv.ParseUint(cfg.HTTP.SocketMode, 8, 32)
+		if err != nil {
+			return listener.Config{}, fmt.Errorf("invalid socket mode %q: %w", cfg.HTTP.SocketMode, err)
+		}
+
+		return listener.Config{
+			Listen:     cfg.HTTP.Listen,
+			SocketMode: os.FileMode(mode),
+		}, nil
+	}),
 	fx.Provide(func(cfg Config) db.Config {
 		return db.Config{
 			Dialect:  db.Dialect(cfg.Database.Dialect),
@@ -58,11 +91,21 @@ var Module = fx.Module(
 		if cfg.Gateway.Mode == GatewayModePrivate {
 			mode = push.ModeUpstream
 		}
+		if cfg.Push.URL != "" {
+			mode = push.ModeCustom
+		}
 
 		return push.Config{
 			Mode: mode,
 			ClientOptions: map[string]string{
-				"credentials": cfg.FCM.CredentialsJSON,
+				"credentials":      cfg.FCM.CredentialsJSON,
+				"credentials_file": cfg.FCM.CredentialsFile,
+				"key":              cfg.Upstream.Key,
+				"url":              cfg.Push.URL,
+				"method":           cfg.Push.Method,
+				"template":         cfg.Push.Template,
+				"content_type":     cfg.Push.ContentType,
+				"auth":             cfg.Push.Auth,
 			},
 			Debounce: time.Duration(cfg.FCM.DebounceSeconds) * time.Second,
 			Timeout:  time.Duration(cfg.FCM.TimeoutSeconds) * time.Second,
@@ -71,6 +114,37 @@ var Module = fx.Module(
 	fx.Provide(func(cfg Config) messages.HashingTaskConfig {
 		return messages.HashingTaskConfig{
 			Interval: time.Duration(cfg.Tasks.Hashing.IntervalSeconds) * time.Second,
+			// the stuck messages are requeued within the timeout, the grace
+			// keeps the content of the ones requeued by the last check
+			ProcessedTimeout: 2 * time.Duration(cfg.Messages.ProcessedTimeoutSeconds) * time.Second,
+		}
+	}),
+	fx.Provide(func(cfg Config) (messages.StuckTaskConfig, error) {
+		action, err := messages.ParseStuckAction(cfg.Tasks.Stuck.Action)
+		if err != nil {
+			return messages.StuckTaskConfig{}, fmt.Errorf("invalid stuck task config: %w", err)
+		}
+
+		return messages.StuckTaskConfig{
+			Interval: time.Duration(cfg.Tasks.Stuck.IntervalSeconds) * time.Second,
+			Timeout:  time.Duration(cfg.Tasks.Stuck.TimeoutSeconds) * time.Second,
+			Action:   action,
+		}, nil
+	}),
+	fx.Provide(func(cfg Config) email.Config {
+		return email.Config{
+			Listen:             cfg.Email.Listen,
+			Domain:             cfg.Email.Domain,
+			AllowedSenders:     cfg.Email.AllowedSenders,
+			TLSCertFile:        cfg.Email.TLSCertFile,
+			TLSKeyFile:         cfg.Email.TLSKeyFile,
+			ImplicitTLS:        cfg.Email.ImplicitTLS,
+			AllowInsecureAuth:  cfg.Email.AllowInsecureAuth,
+			MaxAuthFailures:    int(cfg.Email.MaxAuthFailures),
+			AuthFailuresWindow: time.Duration(cfg.Email.AuthFailuresWindowSeconds) * time.Second,
+			MaxMessageSize:     int(cfg.Email.MaxSizeKB) * 1024,
+			MaxRecipients:      int(cfg.Email.MaxRecipients),
+			Timeout:            time.Duration(cfg.Email.TimeoutSeconds) * time.Second,
 		}
 	}),
 	fx.Provide(func(cfg Config) auth.Config {
@@ -79,7 +153,7 @@ var Module = fx.Module(
 			PrivateToken: cfg.Gateway.PrivateToken,
 		}
 	}),
-	fx.Provide(func(cfg Config) handlers.Config {
+	fx.Provide(func(cfg Config) (handlers.Config, error) {
 		// Default and normalize API path/host
 		if cfg.HTTP.API.Host == "" {
 			cfg.HTTP.API.Path = "/api"
@@ -94,31 +168,272 @@ var Module = fx.Module(
 		// Guard against misconfigured scheme in host (accept "host[:port]" only)
 		cfg.HTTP.API.Host = strings.TrimPrefix(strings.TrimPrefix(cfg.HTTP.API.Host, "https://"), "http://")
 
+		cachedGroups, err := responsesConfig(cfg.HTTP.Responses)
+		if err != nil {
+			return handlers.Config{}, err
+		}
+
 		return handlers.Config{
 			PublicHost:      cfg.HTTP.API.Host,
 			PublicPath:      cfg.HTTP.API.Path,
 			UpstreamEnabled: cfg.Gateway.Mode == GatewayModePublic,
 			OpenAPIEnabled:  cfg.HTTP.OpenAPI.Enabled,
-		}
+
+			UpstreamKeys:      cfg.Upstream.Keys,
+			UpstreamRateLimit: int(cfg.Upstream.RateLimit),
+
+			AdminToken: cfg.Admin.Token,
+
+			CompressedGroups: cfg.HTTP.Responses.Compress,
+			CachedGroups:     cachedGroups,
+
+			ThirdPartyRateLimit: ratelimit.Config{
+				Max:    int(cfg.HTTP.RateLimits.ThirdPartyRequests),
+				Window: time.Duration(cfg.HTTP.RateLimits.ThirdPartyWindowSeconds) * time.Second,
+			},
+			MobileRateLimit: ratelimit.Config{
+				Max:    int(cfg.HTTP.RateLimits.MobileRequests),
+				Window: time.Duration(cfg.HTTP.RateLimits.MobileWindowSeconds) * time.Second,
+			},
+		}, nil
 	}),
-	fx.Provide(func(cfg Config) messages.Config {
+	fx.Provide(func(cfg Config) (messages.Config, error) {
+		sendWindows := make(map[string]messages.SendWindow, len(cfg.Messages.SendWindows))
+		for country, value := range cfg.Messages.SendWindows {
+			window, err := messages.ParseSendWindow(value)
+			if err != nil {
+				return messages.Config{}, fmt.Errorf("can't parse send window of %s: %w", country, err)
+			}
+			sendWindows[strings.ToUpper(country)] = window
+		}
+
 		return messages.Config{
 			ProcessedLifetime: 30 * 24 * time.Hour, //TODO: make it configurable
+			ProcessedTimeout:  time.Duration(cfg.Messages.ProcessedTimeoutSeconds) * time.Second,
+
+			ContentPolicy: messages.ContentPolicyConfig{
+				Mode:              messages.ContentPolicyMode(cfg.Messages.ContentPolicy.Mode),
+				URLShorteners:     cfg.Messages.ContentPolicy.URLShorteners,
+				BannedWords:       cfg.Messages.ContentPolicy.BannedWords,
+				MaxUppercaseRatio: cfg.Messages.ContentPolicy.MaxUppercaseRatio,
+			},
+			Polling: messages.PollingConfig{
+				ActiveInterval:   time.Duration(cfg.Messages.Polling.ActiveSeconds) * time.Second,
+				IdleInterval:     time.Duration(cfg.Messages.Polling.IdleSeconds) * time.Second,
+				IdlePushInterval: time.Duration(cfg.Messages.Polling.IdlePushSeconds) * time.Second,
+			},
+			ClockSkew: messages.ClockSkewConfig{
+				Tolerance: time.Duration(cfg.Messages.ClockSkew.ToleranceSeconds) * time.Second,
+				Policy:    messages.ClockSkewPolicy(cfg.Messages.ClockSkew.Policy),
+			},
+			Dedup: messages.DedupConfig{
+				Window:   time.Duration(cfg.Messages.Dedup.WindowSeconds) * time.Second,
+				Strategy: messages.DedupStrategy(cfg.Messages.Dedup.Strategy),
+			},
+			TTL: messages.TTLConfig{
+				Default: time.Duration(cfg.Messages.TTL.DefaultSeconds) * time.Second,
+				Max:     time.Duration(cfg.Messages.TTL.MaxSeconds) * time.Second,
+			},
+			Health: messages.HealthConfig{
+				Interval:          time.Duration(cfg.Messages.Health.IntervalSeconds) * time.Second,
+				Window:            time.Duration(cfg.Messages.Health.WindowSeconds) * time.Second,
+				MinSamples:        cfg.Messages.Health.MinSamples,
+				FailureThreshold:  cfg.Messages.Health.FailureThreshold,
+				RecoveryThreshold: cfg.Messages.Health.RecoveryThreshold,
+			},
+			SendWindows: messages.SendWindowsConfig{
+				Countries: sendWindows,
+			},
+		}, nil
+	}),
+	fx.Provide(devicesConfig),
+	fx.Provide(func(cfg Config) exports.Config {
+		return exports.Config{
+			BaseURL:     cfg.Exports.BaseURL,
+			SigningKey:  cfg.Exports.SigningKey,
+			MaxSize:     int64(cfg.Exports.MaxSizeKB) * 1024,
+			ArtifactTTL: time.Duration(cfg.Exports.TTLHours) * time.Hour,
+
+			// the broker is configured only if there are multiple instances
+			MultiInstance: cfg.SSE.BrokerURL != "",
+			SharedCache:   strings.HasPrefix(cfg.Cache.URL, "redis://"),
+		}
+	}),
+	fx.Provide(func(cfg Config) links.Config {
+		return links.Config{
+			BaseURL: cfg.Links.BaseURL,
+			TTL:     time.Duration(cfg.Links.TTLDays) * 24 * time.Hour,
+		}
+	}),
+	fx.Provide(func(cfg Config) alerts.Config {
+		return alerts.Config{
+			Interval:    time.Duration(cfg.Alerts.IntervalSeconds) * time.Second,
+			Window:      time.Duration(cfg.Alerts.WindowMinutes) * time.Minute,
+			Baseline:    time.Duration(cfg.Alerts.BaselineHours) * time.Hour,
+			MinFailures: uint(cfg.Alerts.MinFailures),
+			Factor:      cfg.Alerts.Factor,
+		}
+	}),
+	fx.Provide(func(cfg Config) autoreplies.Config {
+		return autoreplies.Config{
+			MaxPerSender: uint(cfg.AutoReplies.MaxPerSender),
+			MaxPerUser:   uint(cfg.AutoReplies.MaxPerUser),
+			Window:       time.Duration(cfg.AutoReplies.WindowSeconds) * time.Second,
+		}
+	}),
+	fx.Provide(func(cfg Config) sandbox.Config {
+		return sandbox.Config{
+			StepDelay:      time.Duration(cfg.Sandbox.StepDelaySeconds) * time.Second,
+			Workers:        int(cfg.Sandbox.Workers),
+			MaxPending:     int(cfg.Sandbox.MaxPending),
+			MaxAccounts:    int(cfg.Sandbox.MaxAccounts),
+			AccountsWindow: time.Duration(cfg.Sandbox.AccountsWindowHours) * time.Hour,
+			VirtualDevice: sandbox.VirtualDeviceConfig{
+				Enabled:       cfg.Sandbox.VirtualDevice.Enabled,
+				Login:         cfg.Sandbox.VirtualDevice.Login,
+				Password:      cfg.Sandbox.VirtualDevice.Password,
+				PollInterval:  time.Duration(cfg.Sandbox.VirtualDevice.PollSeconds) * time.Second,
+				Latency:       time.Duration(cfg.Sandbox.VirtualDevice.LatencyMS) * time.Millisecond,
+				FailedRate:    cfg.Sandbox.VirtualDevice.FailedRate,
+				DeliveredRate: cfg.Sandbox.VirtualDevice.DeliveredRate,
+			},
+		}
+	}),
+	fx.Provide(func(cfg Config) relay.Config {
+		return relay.Config{
+			URL:          cfg.Relay.URL,
+			Login:        cfg.Relay.Login,
+			Password:     cfg.Relay.Password,
+			OfflineAfter: time.Duration(cfg.Relay.OfflineMinutes) * time.Minute,
+			SyncInterval: time.Duration(cfg.Relay.SyncSeconds) * time.Second,
+			TrackPeriod:  time.Duration(cfg.Relay.TrackHours) * time.Hour,
+		}
+	}),
+	fx.Provide(func(cfg Config) mqtt.Config {
+		return mqtt.Config{
+			URL:      cfg.MQTT.URL,
+			Topic:    cfg.MQTT.Topic,
+			QoS:      cfg.MQTT.QoS,
+			ClientID: cfg.MQTT.ClientID,
+			Timeout:  time.Duration(cfg.MQTT.TimeoutSeconds) * time.Second,
+		}
+	}),
+	fx.Provide(func(cfg Config) egress.Config {
+		return egress.Config{
+			Proxy: cfg.Egress.Proxy,
+			Overrides: map[egress.Destination]string{
+				egress.DestinationFCM:      cfg.Egress.Overrides.FCM,
+				egress.DestinationWebhooks: cfg.Egress.Overrides.Webhooks,
+				egress.DestinationUpstream: cfg.Egress.Overrides.Upstream,
+				egress.DestinationStorage:  cfg.Egress.Overrides.Storage,
+				egress.DestinationPush:     cfg.Egress.Overrides.Push,
+			},
+		}
+	}),
+	fx.Provide(func(cfg Config) storage.Config {
+		return storage.Config{
+			Endpoint:       cfg.Storage.Endpoint,
+			PublicEndpoint: cfg.Storage.PublicEndpoint,
+			Region:         cfg.Storage.Region,
+			Bucket:         cfg.Storage.Bucket,
+			AccessKey:      cfg.Storage.AccessKey,
+			SecretKey:      cfg.Storage.SecretKey,
+			PathStyle:      cfg.Storage.PathStyle,
+			Timeout:        time.Duration(cfg.Storage.TimeoutSeconds) * time.Second,
+		}
+	}),
+	fx.Provide(func(cfg Config) stats.Config {
+		return stats.Config{
+			Interval: time.Duration(cfg.Tasks.Stats.IntervalSeconds) * time.Second,
 		}
 	}),
-	fx.Provide(func(cfg Config) devices.Config {
-		return devices.Config{
-			UnusedLifetime: 365 * 24 * time.Hour, //TODO: make it configurable
+	fx.Provide(func(cfg Config) crashes.Config {
+		return crashes.Config{
+			SentryDSN: cfg.Crashes.SentryDSN,
 		}
 	}),
-	fx.Provide(func(cfg Config) sse.Config {
+	fx.Provide(func(cfg Config) settings.Config {
+		return settings.Config{
+			EncryptionKey: cfg.Settings.EncryptionKey,
+		}
+	}),
+	fx.Provide(func(cfg Config) (sse.Config, error) {
+		overflowPolicy, err := sse.ParseOverflowPolicy(cfg.SSE.OverflowPolicy)
+		if err != nil {
+			return sse.Config{}, fmt.Errorf("invalid sse config: %w", err)
+		}
+		if cfg.SSE.BufferSize == 0 {
+			return sse.Config{}, errors.New("invalid sse config: buffer size must be positive")
+		}
+
+		coalesceWindows := make(map[string]time.Duration, len(cfg.SSE.CoalesceWindowsMs))
+		for eventType, window := range cfg.SSE.CoalesceWindowsMs {
+			coalesceWindows[eventType] = time.Duration(window) * time.Millisecond
+		}
+
 		return sse.NewConfig(
-			sse.WithKeepAlivePeriod(time.Duration(cfg.SSE.KeepAlivePeriodSeconds) * time.Second),
-		)
+			sse.WithKeepAlivePeriod(time.Duration(cfg.SSE.KeepAlivePeriodSeconds)*time.Second),
+			sse.WithBrokerURL(cfg.SSE.BrokerURL),
+			sse.WithReplayBufferSize(int(cfg.SSE.ReplayBufferSize)),
+			sse.WithBufferSize(int(cfg.SSE.BufferSize)),
+			sse.WithOverflowPolicy(overflowPolicy),
+			sse.WithWriteTimeout(time.Duration(cfg.SSE.WriteTimeoutSeconds)*time.Second),
+			sse.WithCoalesceWindows(coalesceWindows),
+			sse.WithCompression(cfg.SSE.Compression),
+		), nil
+	}),
+	fx.Provide(func(cfg Config) redaction.Config {
+		return redaction.Config{
+			Enabled:           cfg.Redaction.Enabled,
+			PhoneVisibleChars: int(cfg.Redaction.PhoneVisibleChars),
+			HideContent:       cfg.Redaction.HideContent,
+		}
 	}),
 	fx.Provide(func(cfg Config) cache.Config {
 		return cache.Config{
-			URL: cfg.Cache.URL,
+			URL:        cfg.Cache.URL,
+			MaxEntries: int(cfg.Cache.MaxEntries),
+			MaxBytes:   int64(cfg.Cache.MaxSizeMB) * 1024 * 1024,
+
+			JanitorInterval: time.Duration(cfg.Cache.CleanupIntervalSeconds) * time.Second,
+			SaveInterval:    time.Duration(cfg.Cache.SaveIntervalSeconds) * time.Second,
 		}
 	}),
 )
+
+// responsesConfig checks the route groups of the responses config against
+// handlers.ResponseGroups, so a misspelled group doesn't disable the feature
+// silently, and returns the cache TTL per group.
+func responsesConfig(cfg Responses) (map[string]time.Duration, error) {
+	for _, group := range cfg.Compress {
+		if !slices.Contains(handlers.ResponseGroups, group) {
+			return nil, fmt.Errorf("invalid responses config: unknown compress group %q, expected one of %s", group, strings.Join(handlers.ResponseGroups, ", "))
+		}
+	}
+
+	cachedGroups := make(map[string]time.Duration, len(cfg.CacheTTL))
+	for group, ttl := range cfg.CacheTTL {
+		if !slices.Contains(handlers.ResponseGroups, group) {
+			return nil, fmt.Errorf("invalid responses config: unknown cache_ttl group %q, expected one of %s", group, strings.Join(handlers.ResponseGroups, ", "))
+		}
+		cachedGroups[group] = time.Duration(ttl) * time.Second
+	}
+
+	return cachedGroups, nil
+}
+
+// devicesConfig returns the devices config. The online window can't be
+// shorter than the interval the last seen time is stored with, otherwise the
+// active devices would be reported offline between the updates.
+func devicesConfig(cfg Config) (devices.Config, error) {
+	onlineWindow := time.Duration(cfg.Devices.OnlineWindowSeconds) * time.Second
+	if onlineWindow < online.PersistInterval {
+		return devices.Config{}, fmt.Errorf("invalid devices config: online window %s is shorter than the last seen interval %s", onlineWindow, online.PersistInterval)
+	}
+
+	return devices.Config{
+		UnusedLifetime: 365 * 24 * time.Hour, //TODO: make it configurable
+		TokenCacheTTL:  time.Duration(cfg.Cache.DeviceTokensTTLSeconds) * time.Second,
+		OnlineWindow:   onlineWindow,
+	}, nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestDevicesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds uint16
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "Default", seconds: defaultConfig.Devices.OnlineWindowSeconds, want: 15 * time.Minute},
+		{name: "Persist interval", seconds: 60, want: time.Minute},
+		{name: "Shorter than persist interval", seconds: 30, wantErr: true},
+		{name: "Zero", seconds: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{Devices: Devices{OnlineWindowSeconds: tt.seconds}}
+
+			got, err := devicesConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("devicesConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.OnlineWindow != tt.want {
+				t.Errorf("OnlineWindow = %s, want %s", got.OnlineWindow, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponsesConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Responses
+		want    map[string]time.Duration
+		wantErr bool
+	}{
+		{name: "Empty", cfg: Responses{}, want: map[string]time.Duration{}},
+		{
+			name: "Known groups",
+			cfg:  Responses{Compress: []string{"messages", "stats"}, CacheTTL: map[string]uint16{"stats": 60, "devices": 0}},
+			want: map[string]time.Duration{"stats": time.Minute, "devices": 0},
+		},
+		{name: "Unknown compress group", cfg: Responses{Compress: []string{"stat"}}, wantErr: true},
+		{name: "Unknown cache group", cfg: Responses{CacheTTL: map[string]uint16{"stat": 60}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := responsesConfig(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("responsesConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !maps.Equal(got, tt.want) {
+				t.Errorf("responsesConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
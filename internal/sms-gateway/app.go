@@ -7,20 +7,44 @@ import (
 	appconfig "github.com/android-sms-gateway/server/internal/config"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/alerts"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/autoreplies"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/cleaner"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/crashes"
 	appdb "github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devicelogs"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/doctor"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/email"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/enrollments"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/health"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/keys"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/listener"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/metrics"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/mqtt"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/otp"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/overview"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/pings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/privacy"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/relay"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sandbox"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sims"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sse"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/stats"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/storage"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/online"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/openapi"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/redaction"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/validation"
 	"github.com/capcom6/go-infra-fx/cli"
 	"github.com/capcom6/go-infra-fx/db"
 	"github.com/capcom6/go-infra-fx/http"
@@ -36,9 +60,11 @@ var Module = fx.Module(
 	"server",
 	logger.Module,
 	appconfig.Module,
+	redaction.Module,
 	appdb.Module,
 	http.Module,
 	validator.Module,
+	validation.Module,
 	openapi.Module(),
 	handlers.Module,
 	auth.Module,
@@ -48,13 +74,35 @@ var Module = fx.Module(
 	events.Module,
 	messages.Module,
 	health.Module,
+	doctor.Module,
 	webhooks.Module,
+	autoreplies.Module,
 	settings.Module,
 	devices.Module,
+	enrollments.Module,
+	pings.Module,
+	devicelogs.Module,
+	crashes.Module,
+	otp.Module,
+	links.Module,
+	sims.Module,
+	exports.Module,
+	stats.Module,
+	alerts.Module,
+	sandbox.Module,
+	relay.Module,
+	keys.Module,
 	metrics.Module,
 	cleaner.Module,
 	sse.Module,
+	mqtt.Module,
+	email.Module,
+	listener.Module,
+	egress.Module,
+	storage.Module,
 	online.Module(),
+	overview.Module,
+	privacy.Module,
 )
 
 func Run() {
@@ -78,6 +126,7 @@ type StartParams struct {
 	Shut   fx.Shutdowner
 
 	Server          *http.Server
+	Listener        *listener.Listener
 	MessagesService *messages.Service
 	PushService     *push.Service
 	CleanerService  *cleaner.Service
@@ -96,10 +145,15 @@ func Start(p StartParams) error {
 				p.PushService.Run(ctx)
 			}()
 
+			start := p.Server.Start
+			if p.Listener.Enabled() {
+				start = p.Listener.Start
+			}
+
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				if err := p.Server.Start(); err != nil {
+				if err := start(); err != nil {
 					p.Logger.Error("Error starting server", zap.Error(err))
 					_ = p.Shut.Shutdown()
 				}
@@ -1,6 +1,35 @@
 package cache
 
-// Config controls the cache backend via a URL (e.g., "memory://", "redis://...").
+import (
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+// Config controls the cache backend via a URL (e.g., "memory://", "redis://...",
+// "file:///var/lib/sms-gateway/cache").
 type Config struct {
 	URL string
+
+	// MaxEntries and MaxBytes bound every memory and file cache created with
+	// WithEvictable, zero means no limit. They are ignored by Redis.
+	MaxEntries int
+	MaxBytes   int64
+	// JanitorInterval is how often expired items are removed from memory and
+	// file caches, zero disables the background cleanup.
+	JanitorInterval time.Duration
+	// SaveInterval is how often file caches are written to disk, zero saves
+	// them only on shutdown.
+	SaveInterval time.Duration
+}
+
+// memoryOptions returns the options of the memory and file caches. The size
+// limits apply to the evictable caches only.
+func (c Config) memoryOptions(o *options) []cache.MemoryOption {
+	opts := []cache.MemoryOption{cache.WithJanitorInterval(c.JanitorInterval)}
+	if o.evictable {
+		opts = append(opts, cache.WithMaxEntries(c.MaxEntries), cache.WithMaxBytes(c.MaxBytes))
+	}
+
+	return opts
 }
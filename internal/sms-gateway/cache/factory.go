@@ -1,11 +1,20 @@
 package cache
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/android-sms-gateway/core/redis"
 	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
 const (
@@ -15,14 +24,61 @@ const (
 type Cache = cache.Cache
 
 type Factory interface {
-	New(name string) (Cache, error)
+	New(name string, opts ...Option) (Cache, error)
+}
+
+type namedCache struct {
+	name  string
+	cache Cache
+}
+
+type FactoryParams struct {
+	fx.In
+
+	Config     Config
+	Metrics    *metrics
+	Preloaders []Preloader `group:"cache-preloaders"`
+
+	Logger *zap.Logger
 }
 
 type factory struct {
-	new func(name string) (Cache, error)
+	new func(name string, o *options) (Cache, error)
+
+	// preloaders are the preload functions by the cache name
+	preloaders map[string][]Preloader
+	// pending are the caches created before Start, they are preloaded by it
+	pending []namedCache
+	started bool
+
+	caches []Cache
+	mux    sync.Mutex
+
+	logger *zap.Logger
 }
 
-func NewFactory(config Config) (Factory, error) {
+func NewFactory(params FactoryParams) (Factory, error) {
+	newCache, err := newBackend(params.Config, params.Metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &factory{
+		new: newCache,
+
+		preloaders: make(map[string][]Preloader, len(params.Preloaders)),
+
+		logger: params.Logger,
+	}
+	for _, p := range params.Preloaders {
+		f.preloaders[p.CacheName()] = append(f.preloaders[p.CacheName()], p)
+	}
+
+	return f, nil
+}
+
+// newBackend returns the constructor of the caches of the configured backend.
+func newBackend(config Config, metrics *metrics) (func(name string, o *options) (Cache, error), error) {
 	if config.URL == "" {
 		config.URL = "memory://"
 	}
@@ -34,27 +90,140 @@ func NewFactory(config Config) (Factory, error) {
 
 	switch u.Scheme {
 	case "memory":
-		return &factory{
-			new: func(name string) (Cache, error) {
-				return cache.NewMemory(0), nil
-			},
+		return func(name string, o *options) (Cache, error) {
+			c := cache.NewMemory(0, config.memoryOptions(o)...)
+			metrics.track(strings.TrimPrefix(name, keyPrefix), c)
+
+			return c, nil
+		}, nil
+	case "file":
+		if u.Path == "" {
+			return nil, errors.New("file cache requires a directory path")
+		}
+		if err := os.MkdirAll(u.Path, 0o750); err != nil {
+			return nil, fmt.Errorf("can't create cache directory: %w", err)
+		}
+		return func(name string, o *options) (Cache, error) {
+			name = strings.TrimPrefix(name, keyPrefix)
+			c, err := cache.NewFile(
+				filepath.Join(u.Path, name+".json"),
+				0,
+				config.SaveInterval,
+				config.memoryOptions(o)...,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("can't create file cache: %w", err)
+			}
+			metrics.track(name, c)
+
+			return c, nil
 		}, nil
 	case "redis":
 		client, err := redis.New(redis.Config{URL: config.URL})
 		if err != nil {
 			return nil, fmt.Errorf("can't create redis client: %w", err)
 		}
-		return &factory{
-			new: func(name string) (Cache, error) {
-				return cache.NewRedis(client, name, 0), nil
-			},
+		return func(name string, o *options) (Cache, error) {
+			c := cache.NewRedis(
+				client, name, 0,
+				cache.WithWriteBuffer(o.writeBufferSize, o.writeBufferInterval),
+			)
+			metrics.track(strings.TrimPrefix(name, keyPrefix), c)
+
+			return c, nil
 		}, nil
 	default:
 		return nil, fmt.Errorf("invalid scheme: %s", u.Scheme)
 	}
 }
 
-// New implements Factory.
-func (f *factory) New(name string) (Cache, error) {
-	return f.new(keyPrefix + name)
+// New implements Factory. The cache with the registered preloaders is
+// populated by Start, or right away if the factory is already started. A
+// failed preload of the started factory is returned as the error.
+func (f *factory) New(name string, opts ...Option) (Cache, error) {
+	c, err := f.new(keyPrefix+name, new(options).apply(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	f.mux.Lock()
+	f.caches = append(f.caches, c)
+	started := f.started
+	if !started && len(f.preloaders[name]) > 0 {
+		f.pending = append(f.pending, namedCache{name: name, cache: c})
+	}
+	f.mux.Unlock()
+
+	if started {
+		if err := f.preload(context.Background(), name, c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Start preloads the caches created so far, so they are populated before the
+// application starts serving requests. It fails if any of the caches can't be
+// preloaded, so the application doesn't start with a cold cache.
+func (f *factory) Start(ctx context.Context) error {
+	f.mux.Lock()
+	pending := f.pending
+	f.pending = nil
+	f.started = true
+	f.mux.Unlock()
+
+	var errs error
+	for _, item := range pending {
+		errs = errors.Join(errs, f.preload(ctx, item.name, item.cache))
+	}
+
+	return errs
+}
+
+// preload runs the preloaders of the named cache. A failed preloader is
+// retried up to preloadAttempts times.
+func (f *factory) preload(ctx context.Context, name string, c Cache) error {
+	for _, p := range f.preloaders[name] {
+		var err error
+		for attempt := range preloadAttempts {
+			if attempt > 0 {
+				f.logger.Warn("Retrying cache preload", zap.String("name", name), zap.Int("attempt", attempt+1), zap.Error(err))
+
+				select {
+				case <-ctx.Done():
+					return fmt.Errorf("can't preload cache %s: %w", name, errors.Join(err, ctx.Err()))
+				case <-time.After(preloadRetryDelay):
+				}
+			}
+
+			pctx, cancel := context.WithTimeout(ctx, preloadTimeout)
+			err = p.Preload(pctx, c)
+			cancel()
+
+			if err == nil {
+				break
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("can't preload cache %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes all the caches created by the factory.
+func (f *factory) Close() error {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	var errs error
+	for _, c := range f.caches {
+		errs = errors.Join(errs, c.Close())
+	}
+	f.caches = nil
+
+	return errs
 }
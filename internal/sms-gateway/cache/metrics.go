@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric constants
+const (
+	metricEntries   = "entries"
+	metricBytes     = "bytes"
+	metricEvictions = "evictions_total"
+
+	metricHits        = "hits_total"
+	metricMisses      = "misses_total"
+	metricExpirations = "expirations_total"
+
+	labelName = "name"
+)
+
+// metrics collects usage of the caches created by the factory.
+type metrics struct {
+	entries   *prometheus.Desc
+	bytes     *prometheus.Desc
+	evictions *prometheus.Desc
+
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	expirations *prometheus.Desc
+
+	caches map[string]cache.StatsProvider
+	mux    sync.Mutex
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		entries: prometheus.NewDesc(
+			prometheus.BuildFQName("sms", "cache", metricEntries),
+			"Number of items in the cache",
+			[]string{labelName}, nil,
+		),
+		bytes: prometheus.NewDesc(
+			prometheus.BuildFQName("sms", "cache", metricBytes),
+			"Size of keys and values in the memory cache",
+			[]string{labelName}, nil,
+		),
+		evictions: prometheus.NewDesc(
+			prometheus.BuildFQName("sms", "cache", metricEvictions),
+			"Total number of items evicted from the memory cache",
+			[]string{labelName}, nil,
+		),
+		hits: prometheus.NewDesc(
+			prometheus.BuildFQName("sms", "cache", metricHits),
+			"Total number of cache lookups that found the item",
+			[]string{labelName}, nil,
+		),
+		misses: prometheus.NewDesc(
+			prometheus.BuildFQName("sms", "cache", metricMisses),
+			"Total number of cache lookups that found nothing or an expired item",
+			[]string{labelName}, nil,
+		),
+		expirations: prometheus.NewDesc(
+			prometheus.BuildFQName("sms", "cache", metricExpirations),
+			"Total number of expired items removed from the memory cache",
+			[]string{labelName}, nil,
+		),
+
+		caches: make(map[string]cache.StatsProvider),
+	}
+
+	prometheus.MustRegister(m)
+
+	return m
+}
+
+// track adds the cache to the collected ones if it reports its usage.
+func (m *metrics) track(name string, c Cache) {
+	provider, ok := c.(cache.StatsProvider)
+	if !ok {
+		return
+	}
+
+	m.mux.Lock()
+	m.caches[name] = provider
+	m.mux.Unlock()
+}
+
+// Describe implements prometheus.Collector.
+func (m *metrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.entries
+	ch <- m.bytes
+	ch <- m.evictions
+	ch <- m.hits
+	ch <- m.misses
+	ch <- m.expirations
+}
+
+// Collect implements prometheus.Collector.
+func (m *metrics) Collect(ch chan<- prometheus.Metric) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for name, provider := range m.caches {
+		stats := provider.Stats()
+		ch <- prometheus.MustNewConstMetric(m.entries, prometheus.GaugeValue, float64(stats.Entries), name)
+		ch <- prometheus.MustNewConstMetric(m.bytes, prometheus.GaugeValue, float64(stats.Bytes), name)
+		ch <- prometheus.MustNewConstMetric(m.evictions, prometheus.CounterValue, float64(stats.Evictions), name)
+		ch <- prometheus.MustNewConstMetric(m.hits, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(m.misses, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(m.expirations, prometheus.CounterValue, float64(stats.Expirations), name)
+	}
+}
@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"context"
+
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -11,6 +13,20 @@ func Module() fx.Option {
 		fx.Decorate(func(log *zap.Logger) *zap.Logger {
 			return log.Named("cache")
 		}),
+		fx.Provide(newMetrics, fx.Private),
 		fx.Provide(NewFactory),
+		fx.Invoke(func(lc fx.Lifecycle, f Factory) {
+			impl, ok := f.(*factory)
+			if !ok {
+				return
+			}
+
+			lc.Append(fx.Hook{
+				OnStart: impl.Start,
+				OnStop: func(_ context.Context) error {
+					return impl.Close()
+				},
+			})
+		}),
 	)
 }
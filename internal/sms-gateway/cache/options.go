@@ -0,0 +1,40 @@
+package cache
+
+import "time"
+
+// Option configures a cache created by the factory.
+type Option func(*options)
+
+type options struct {
+	writeBufferSize     int
+	writeBufferInterval time.Duration
+	evictable           bool
+}
+
+func (o *options) apply(opts ...Option) *options {
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithWriteBuffer coalesces Set calls of the Redis cache into pipelined
+// batches written when the buffer holds size items or every interval. It's
+// ignored by the memory cache, which has no round trips to save.
+func WithWriteBuffer(size int, interval time.Duration) Option {
+	return func(o *options) {
+		o.writeBufferSize = size
+		o.writeBufferInterval = interval
+	}
+}
+
+// WithEvictable bounds the memory or file cache by the configured MaxEntries
+// and MaxBytes, the least recently used items are evicted. It's meant for the
+// caches of derived data only, which can be reloaded on a miss. The caches
+// without it are never evicted, so their items live until expired.
+func WithEvictable() Option {
+	return func(o *options) {
+		o.evictable = true
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+const (
+	// preloadTimeout limits a single preload function.
+	preloadTimeout = 30 * time.Second
+	// preloadAttempts is the number of attempts of the failed preload.
+	preloadAttempts = 3
+	// preloadRetryDelay is the delay before the next attempt.
+	preloadRetryDelay = time.Second
+)
+
+// Preloader populates the named cache created by the factory, e.g. from the
+// database, so the cache is warm before the first request.
+type Preloader interface {
+	// CacheName returns the name of the cache as passed to Factory.New.
+	CacheName() string
+	// Preload stores the initial items in the cache.
+	Preload(ctx context.Context, c Cache) error
+}
+
+type preloaderFunc struct {
+	name string
+	load func(ctx context.Context, c Cache) error
+}
+
+// NewPreloader returns the Preloader of the named cache calling load.
+func NewPreloader(name string, load func(ctx context.Context, c Cache) error) Preloader {
+	return preloaderFunc{name: name, load: load}
+}
+
+// CacheName implements Preloader.
+func (p preloaderFunc) CacheName() string {
+	return p.name
+}
+
+// Preload implements Preloader.
+func (p preloaderFunc) Preload(ctx context.Context, c Cache) error {
+	return p.load(ctx, c)
+}
+
+// AsPreloader annotates the constructor of a Preloader for the factory.
+func AsPreloader(f any) any {
+	return fx.Annotate(
+		f,
+		fx.As(new(Preloader)),
+		fx.ResultTags(`group:"cache-preloaders"`),
+	)
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func newTestFactory(preloaders ...Preloader) *factory {
+	f := &factory{
+		new: func(_ string, _ *options) (Cache, error) {
+			return cache.NewMemory(0), nil
+		},
+		preloaders: map[string][]Preloader{},
+		logger:     zap.NewNop(),
+	}
+	for _, p := range preloaders {
+		f.preloaders[p.CacheName()] = append(f.preloaders[p.CacheName()], p)
+	}
+
+	return f
+}
+
+func TestFactory_Preload(t *testing.T) {
+	ctx := context.Background()
+	f := newTestFactory(NewPreloader("warm", func(ctx context.Context, c Cache) error {
+		return c.Set(ctx, "key", "value")
+	}))
+
+	warm, err := f.New("warm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cold, err := f.New("cold")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := warm.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Get() before Start error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+
+	if err := f.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if value, err := warm.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Get() after Start = %q, %v, want %q", value, err, "value")
+	}
+	if _, err := cold.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Get() of cache without preloaders error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+
+	// the cache created after Start is preloaded right away
+	late, err := f.New("warm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := late.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Get() of cache created after Start = %q, %v, want %q", value, err, "value")
+	}
+}
+
+func TestFactory_PreloadRetry(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	f := newTestFactory(NewPreloader("warm", func(ctx context.Context, c Cache) error {
+		calls++
+		if calls == 1 {
+			return errors.New("unavailable")
+		}
+		return c.Set(ctx, "key", "value")
+	}))
+
+	warm, err := f.New("warm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("preload calls = %d, want 2", calls)
+	}
+	if value, err := warm.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Get() after Start = %q, %v, want %q", value, err, "value")
+	}
+}
+
+func TestFactory_PreloadFailure(t *testing.T) {
+	errPreload := errors.New("unavailable")
+	f := newTestFactory(NewPreloader("warm", func(context.Context, Cache) error {
+		return errPreload
+	}))
+
+	if _, err := f.New("warm"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the retries are interrupted by the canceled start
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := f.Start(ctx); !errors.Is(err, errPreload) {
+		t.Errorf("Start() error = %v, want %v", err, errPreload)
+	}
+
+	if _, err := f.New("warm"); !errors.Is(err, errPreload) {
+		t.Errorf("New() after Start error = %v, want %v", err, errPreload)
+	}
+}
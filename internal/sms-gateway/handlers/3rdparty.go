@@ -1,16 +1,29 @@
 package handlers
 
 import (
+	"slices"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/autoreplies"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/keys"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/logs"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/ratelimit"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/respcache"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/otp"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/privacy"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/sandbox"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/settings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/stats"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/users"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/webhooks"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -18,15 +31,27 @@ import (
 type ThirdPartyHandlerParams struct {
 	fx.In
 
+	Config Config
+
 	HealthHandler   *healthHandler
 	MessagesHandler *messages.ThirdPartyController
 	WebhooksHandler *webhooks.ThirdPartyController
 	DevicesHandler  *devices.ThirdPartyController
 	SettingsHandler *settings.ThirdPartyController
 	LogsHandler     *logs.ThirdPartyController
+	OTPHandler      *otp.ThirdPartyController
+	StatsHandler    *stats.ThirdPartyController
+	SandboxHandler  *sandbox.ThirdPartyController
+	KeysHandler     *keys.ThirdPartyController
+	UsersHandler    *users.ThirdPartyController
+	PrivacyHandler  *privacy.ThirdPartyController
+
+	AutorepliesHandler *autoreplies.ThirdPartyController
 
 	AuthSvc *auth.Service
 
+	ResponsesCache cache.Cache
+
 	Logger    *zap.Logger
 	Validator *validator.Validate
 }
@@ -34,14 +59,26 @@ type ThirdPartyHandlerParams struct {
 type thirdPartyHandler struct {
 	base.Handler
 
+	config Config
+
 	healthHandler   *healthHandler
 	messagesHandler *messages.ThirdPartyController
 	webhooksHandler *webhooks.ThirdPartyController
 	devicesHandler  *devices.ThirdPartyController
 	settingsHandler *settings.ThirdPartyController
 	logsHandler     *logs.ThirdPartyController
+	otpHandler      *otp.ThirdPartyController
+	statsHandler    *stats.ThirdPartyController
+	sandboxHandler  *sandbox.ThirdPartyController
+	keysHandler     *keys.ThirdPartyController
+	usersHandler    *users.ThirdPartyController
+	privacyHandler  *privacy.ThirdPartyController
+
+	autorepliesHandler *autoreplies.ThirdPartyController
 
 	authSvc *auth.Service
+
+	responsesCache cache.Cache
 }
 
 func (h *thirdPartyHandler) Register(router fiber.Router) {
@@ -52,30 +89,75 @@ func (h *thirdPartyHandler) Register(router fiber.Router) {
 	router.Use(
 		userauth.NewBasic(h.authSvc),
 		userauth.UserRequired(),
+		ratelimit.New("3rdparty", h.config.ThirdPartyRateLimit, func(c *fiber.Ctx) string {
+			return userauth.GetUser(c).ID
+		}),
 	)
 
-	h.messagesHandler.Register(router.Group("/message")) // TODO: remove after 2025-12-31
-	h.messagesHandler.Register(router.Group("/messages"))
+	// the legacy paths share the group, so their writes invalidate the cached
+	// responses too. The group middlewares match the paths by prefix, so the
+	// legacy groups go last, otherwise they would also run for the new paths.
+	h.messagesHandler.Register(h.group(router, "/messages", "messages"))
+	h.messagesHandler.Register(h.group(router, "/message", "messages")) // TODO: remove after 2025-12-31
+
+	h.devicesHandler.Register(h.group(router, "/devices", "devices"))
+	h.devicesHandler.Register(h.group(router, "/device", "devices")) // TODO: remove after 2025-07-11
+
+	h.settingsHandler.Register(h.group(router, "/settings", "settings"))
+
+	h.webhooksHandler.Register(h.group(router, "/webhooks", "webhooks"))
+
+	h.logsHandler.Register(h.group(router, "/logs", "logs"))
 
-	h.devicesHandler.Register(router.Group("/device")) // TODO: remove after 2025-07-11
-	h.devicesHandler.Register(router.Group("/devices"))
+	h.otpHandler.Register(router.Group("/otp"))
 
-	h.settingsHandler.Register(router.Group("/settings"))
+	h.statsHandler.Register(h.group(router, "/stats", "stats"))
 
-	h.webhooksHandler.Register(router.Group("/webhooks"))
+	h.sandboxHandler.Register(router.Group("/sandbox"))
 
-	h.logsHandler.Register(router.Group("/logs"))
+	h.keysHandler.Register(h.group(router, "/keys", "keys"))
+
+	h.usersHandler.Register(router.Group("/user"))
+
+	h.privacyHandler.Register(router.Group("/privacy"))
+
+	h.autorepliesHandler.Register(h.group(router, "/autoreplies", "autoreplies"))
+}
+
+// group creates a route group with response compression and caching enabled
+// according to the configuration of the named group.
+func (h *thirdPartyHandler) group(router fiber.Router, prefix, name string) fiber.Router {
+	middlewares := []fiber.Handler{}
+
+	if slices.Contains(h.config.CompressedGroups, name) {
+		middlewares = append(middlewares, compress.New(compress.Config{Level: compress.LevelBestSpeed}))
+	}
+	if ttl := h.config.CachedGroups[name]; ttl > 0 {
+		middlewares = append(middlewares, respcache.New(h.responsesCache, name, ttl))
+	}
+
+	return router.Group(prefix, middlewares...)
 }
 
 func newThirdPartyHandler(params ThirdPartyHandlerParams) *thirdPartyHandler {
 	return &thirdPartyHandler{
 		Handler:         base.Handler{Logger: params.Logger.Named("ThirdPartyHandler"), Validator: params.Validator},
+		config:          params.Config,
 		healthHandler:   params.HealthHandler,
 		messagesHandler: params.MessagesHandler,
 		webhooksHandler: params.WebhooksHandler,
 		devicesHandler:  params.DevicesHandler,
 		settingsHandler: params.SettingsHandler,
 		logsHandler:     params.LogsHandler,
+		otpHandler:      params.OTPHandler,
+		statsHandler:    params.StatsHandler,
+		sandboxHandler:  params.SandboxHandler,
+		keysHandler:     params.KeysHandler,
+		usersHandler:    params.UsersHandler,
+		privacyHandler:  params.PrivacyHandler,
 		authSvc:         params.AuthSvc,
+		responsesCache:  params.ResponsesCache,
+
+		autorepliesHandler: params.AutorepliesHandler,
 	}
 }
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/crashes"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/overview"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sse"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/keyauth"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type adminHandlerParams struct {
+	fx.In
+
+	Config      Config
+	CrashesSvc  *crashes.Service
+	OverviewSvc *overview.Service
+	SSESvc      *sse.Service
+
+	Logger    *zap.Logger
+	Validator *validator.Validate
+}
+
+type adminHandler struct {
+	base.Handler
+
+	config      Config
+	crashesSvc  *crashes.Service
+	overviewSvc *overview.Service
+	sseSvc      *sse.Service
+}
+
+type adminCrashStats struct {
+	// App version
+	AppVersion string `json:"appVersion" example:"1.40.0"`
+	// Failure type
+	Kind string `json:"kind" example:"crash"`
+	// Number of unique failures
+	Unique int64 `json:"unique" example:"3"`
+	// Total number of reports
+	Total int64 `json:"total" example:"120"`
+}
+
+type adminCrashesQueryParams struct {
+	AppVersion string `query:"appVersion" validate:"omitempty,max=32"`
+	Limit      int    `query:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type adminCrashReport struct {
+	// Stack hash
+	StackHash string `json:"stackHash" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// App version
+	AppVersion string `json:"appVersion" example:"1.40.0"`
+	// Failure type
+	Kind string `json:"kind" example:"crash"`
+	// Exception class
+	Exception string `json:"exception" example:"java.lang.IllegalStateException"`
+	// Last exception message
+	Message string `json:"message" example:"Can't send message"`
+	// Stack trace of the first report
+	StackTrace string `json:"stackTrace"`
+	// Number of reports
+	Count uint64 `json:"count" example:"40"`
+	// Device of the last report
+	LastDeviceID string `json:"lastDeviceId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Time of the first report
+	FirstSeenAt time.Time `json:"firstSeenAt" example:"2025-10-16T12:00:00.000Z"`
+	// Time of the last report
+	LastSeenAt time.Time `json:"lastSeenAt" example:"2025-10-16T12:00:00.000Z"`
+}
+
+type adminOverview struct {
+	// Number of registered users
+	Users int64 `json:"users" example:"120"`
+	// Devices by activity
+	Devices adminOverviewDevices `json:"devices"`
+	// Messages not sent yet
+	Messages adminOverviewMessages `json:"messages"`
+	// Webhook batch queue of the server instance, the other queues aren't reported
+	Queues adminOverviewQueues `json:"queues"`
+	// Webhook deliveries by the server since the start of the day (UTC), the webhooks delivered by the devices directly aren't counted
+	ServerWebhooks adminOverviewWebhooks `json:"serverWebhooks"`
+	// Time of the computation, totals are cached for a short period
+	GeneratedAt time.Time `json:"generatedAt" example:"2025-10-16T12:00:00.000Z"`
+}
+
+type adminOverviewDevices struct {
+	// Devices seen within the online window
+	Online int64 `json:"online" example:"80"`
+	// Other devices
+	Offline int64 `json:"offline" example:"40"`
+}
+
+type adminOverviewMessages struct {
+	// Messages waiting for the device to fetch them
+	Pending int64 `json:"pending" example:"15"`
+	// Messages fetched by the device, but not sent yet
+	Processed int64 `json:"processed" example:"3"`
+}
+
+type adminOverviewQueues struct {
+	// Webhook batches of the server instance waiting to be sent
+	WebhookBatches int `json:"webhookBatches" example:"2"`
+}
+
+type adminOverviewWebhooks struct {
+	// Delivered webhooks
+	Delivered int64 `json:"delivered" example:"990"`
+	// Failed webhooks
+	Failed int64 `json:"failed" example:"10"`
+	// Share of failed deliveries
+	FailureRate float64 `json:"failureRate" example:"0.01"`
+}
+
+type adminSSEConnections struct {
+	// Host name of the server instance, the connections to other instances
+	// aren't listed
+	Instance string `json:"instance" example:"sms-gateway-7d9c5b6f4-x2x9k"`
+	// Active connections
+	Connections []adminSSEConnection `json:"connections"`
+}
+
+type adminSSEConnection struct {
+	// Device ID
+	DeviceID string `json:"deviceId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Connection ID
+	ConnectionID string `json:"connectionId" example:"1d3f8d2e-7f0a-4b8e-9a57-3a6c2d1e5b4f"`
+	// Connection protocol
+	Transport string `json:"transport" enums:"sse,websocket" example:"sse"`
+	// Time of the connection
+	ConnectedAt time.Time `json:"connectedAt" example:"2025-10-16T12:00:00.000Z"`
+	// Number of events written to the device, including the replayed ones
+	EventsSent uint64 `json:"eventsSent" example:"12"`
+	// Error of the last failed write
+	LastWriteError string `json:"lastWriteError,omitempty" example:"broken pipe"`
+	// Time of the last failed write
+	LastWriteErrorAt *time.Time `json:"lastWriteErrorAt,omitempty" example:"2025-10-16T12:00:00.000Z"`
+}
+
+func newAdminHandler(params adminHandlerParams) *adminHandler {
+	return &adminHandler{
+		Handler:     base.Handler{Logger: params.Logger, Validator: params.Validator},
+		config:      params.Config,
+		crashesSvc:  params.CrashesSvc,
+		overviewSvc: params.OverviewSvc,
+		sseSvc:      params.SSESvc,
+	}
+}
+
+//	@Summary		Get crash statistics
+//	@Description	Returns the number of unique failures and total reports per app version
+//	@Security		AdminToken
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	[]adminCrashStats			"Crash statistics"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/admin/v1/crashes/stats [get]
+//
+// Get crash statistics
+func (h *adminHandler) getCrashStats(c *fiber.Ctx) error {
+	stats, err := h.crashesSvc.Stats()
+	if err != nil {
+		return fmt.Errorf("can't get crash stats: %w", err)
+	}
+
+	return c.JSON(slices.Map(stats, func(s crashes.VersionStats) adminCrashStats {
+		return adminCrashStats{
+			AppVersion: s.AppVersion,
+			Kind:       string(s.Kind),
+			Unique:     s.Unique,
+			Total:      s.Total,
+		}
+	}))
+}
+
+//	@Summary		List crashes
+//	@Description	Returns the most frequent failures
+//	@Security		AdminToken
+//	@Tags			Admin
+//	@Produce		json
+//	@Param			appVersion	query		string						false	"App version"
+//	@Param			limit		query		int							false	"Max number of reports"	default(20)
+//	@Success		200			{object}	[]adminCrashReport			"Crash reports"
+//	@Failure		400			{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401			{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500			{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/admin/v1/crashes [get]
+//
+// List crashes
+func (h *adminHandler) getCrashes(c *fiber.Ctx) error {
+	params := adminCrashesQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	if params.Limit == 0 {
+		params.Limit = 20
+	}
+
+	reports, err := h.crashesSvc.Top(params.AppVersion, params.Limit)
+	if err != nil {
+		return fmt.Errorf("can't select crashes: %w", err)
+	}
+
+	return c.JSON(slices.Map(reports, func(r crashes.CrashReport) adminCrashReport {
+		return adminCrashReport{
+			StackHash:    r.StackHash,
+			AppVersion:   r.AppVersion,
+			Kind:         string(r.Kind),
+			Exception:    r.Exception,
+			Message:      r.Message,
+			StackTrace:   r.StackTrace,
+			Count:        r.Count,
+			LastDeviceID: r.LastDeviceID,
+			FirstSeenAt:  r.CreatedAt,
+			LastSeenAt:   r.UpdatedAt,
+		}
+	}))
+}
+
+//	@Summary		Get system overview
+//	@Description	Returns the totals of users, devices, messages, the webhook batch queue and webhook deliveries by the server
+//	@Security		AdminToken
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	adminOverview				"System overview"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/admin/v1/overview [get]
+//
+// Get system overview
+func (h *adminHandler) getOverview(c *fiber.Ctx) error {
+	o, err := h.overviewSvc.Get(c.Context())
+	if err != nil {
+		return fmt.Errorf("can't get overview: %w", err)
+	}
+
+	return c.JSON(adminOverview{
+		Users: o.Users,
+		Devices: adminOverviewDevices{
+			Online:  o.Devices.Online,
+			Offline: o.Devices.Offline,
+		},
+		Messages: adminOverviewMessages{
+			Pending:   o.Messages.Pending,
+			Processed: o.Messages.Processed,
+		},
+		Queues: adminOverviewQueues{
+			WebhookBatches: o.Queues.WebhookBatches,
+		},
+		ServerWebhooks: adminOverviewWebhooks{
+			Delivered:   o.Webhooks.Delivered,
+			Failed:      o.Webhooks.Failed,
+			FailureRate: o.Webhooks.FailureRate(),
+		},
+		GeneratedAt: o.GeneratedAt,
+	})
+}
+
+//	@Summary		List SSE connections
+//	@Description	Returns the active SSE and WebSocket connections of the devices to the instance serving the request
+//	@Security		AdminToken
+//	@Tags			Admin
+//	@Produce		json
+//	@Success		200	{object}	adminSSEConnections			"Active connections"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/admin/v1/sse/connections [get]
+//
+// List SSE connections
+func (h *adminHandler) getSSEConnections(c *fiber.Ctx) error {
+	// the instance is only informational
+	instance, _ := os.Hostname()
+
+	return c.JSON(adminSSEConnections{
+		Instance: instance,
+		Connections: slices.Map(h.sseSvc.Connections(), func(item sse.ConnectionInfo) adminSSEConnection {
+			return adminSSEConnection{
+				DeviceID:         item.DeviceID,
+				ConnectionID:     item.ConnectionID,
+				Transport:        item.Transport,
+				ConnectedAt:      item.ConnectedAt,
+				EventsSent:       item.EventsSent,
+				LastWriteError:   item.LastWriteError,
+				LastWriteErrorAt: item.LastWriteErrorAt,
+			}
+		}),
+	})
+}
+
+// Register registers admin handlers with the given router.
+//
+// If the admin token is not configured, this function does nothing.
+func (h *adminHandler) Register(router fiber.Router) {
+	if h.config.AdminToken == "" {
+		return
+	}
+
+	router = router.Group("/admin/v1")
+
+	router.Use(keyauth.New(keyauth.Config{
+		Validator: func(c *fiber.Ctx, token string) (bool, error) {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.AdminToken)) != 1 {
+				return false, keyauth.ErrMissingOrMalformedAPIKey
+			}
+			return true, nil
+		},
+	}))
+
+	router.Get("/overview", h.getOverview)
+	router.Get("/crashes", h.getCrashes)
+	router.Get("/crashes/stats", h.getCrashStats)
+	router.Get("/sse/connections", h.getSSEConnections)
+}
@@ -0,0 +1,123 @@
+package autoreplies
+
+import (
+	"errors"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/autoreplies"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type thirdPartyControllerParams struct {
+	fx.In
+
+	AutorepliesSvc *autoreplies.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type ThirdPartyController struct {
+	base.Handler
+
+	autorepliesSvc *autoreplies.Service
+}
+
+//	@Summary		List auto-reply rules
+//	@Description	Returns the auto-reply rules of the user in the order of evaluation
+//	@Security		ApiAuth
+//	@Tags			User, Auto-replies
+//	@Produce		json
+//	@Success		200	{object}	[]ruleResponse				"Auto-reply rules"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/autoreplies [get]
+//
+// List auto-reply rules
+func (h *ThirdPartyController) list(user models.User, c *fiber.Ctx) error {
+	items, err := h.autorepliesSvc.Select(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(slices.Map(items, ruleToDTO))
+}
+
+//	@Summary		Create auto-reply rule
+//	@Description	Creates a rule replying to the incoming messages matching the keyword or the regular expression. The reply is sent from the device which received the message, the first matching rule is applied. Replies are limited per sender and per user to prevent loops between auto-responders
+//	@Security		ApiAuth
+//	@Tags			User, Auto-replies
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		ruleRequest					true	"Auto-reply rule"
+//	@Success		201		{object}	ruleResponse				"Auto-reply rule"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/autoreplies [post]
+//
+// Create auto-reply rule
+func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
+	req := ruleRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	rule, err := h.autorepliesSvc.Create(user.ID, req.toDomain())
+	if err != nil {
+		if errors.Is(err, autoreplies.ErrInvalidRule) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(ruleToDTO(rule))
+}
+
+//	@Summary		Delete auto-reply rule
+//	@Description	Deletes the auto-reply rule
+//	@Security		ApiAuth
+//	@Tags			User, Auto-replies
+//	@Produce		json
+//	@Param			id	path		string						true	"Rule ID"
+//	@Success		204	{object}	object						"Rule deleted"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Rule not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/autoreplies/{id} [delete]
+//
+// Delete auto-reply rule
+func (h *ThirdPartyController) delete(user models.User, c *fiber.Ctx) error {
+	if err := h.autorepliesSvc.Delete(user.ID, c.Params("id")); err != nil {
+		if errors.Is(err, autoreplies.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ThirdPartyController) Register(router fiber.Router) {
+	router.Get("", userauth.WithUser(h.list))
+	router.Post("", userauth.WithUser(h.post))
+	router.Delete("/:id", userauth.WithUser(h.delete))
+}
+
+func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
+	return &ThirdPartyController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("autoreplies"),
+			Validator: params.Validator,
+		},
+		autorepliesSvc: params.AutorepliesSvc,
+	}
+}
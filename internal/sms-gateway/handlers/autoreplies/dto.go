@@ -0,0 +1,59 @@
+package autoreplies
+
+import (
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/autoreplies"
+)
+
+type ruleRequest struct {
+	// ID of the device which replies, all devices of the user if empty
+	DeviceID *string `json:"deviceId,omitempty" validate:"omitempty,nanoid" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Match type: a case-insensitive whole word or a regular expression
+	Type autoreplies.MatchType `json:"type" validate:"required,oneof=keyword regex" example:"keyword"`
+	// Keyword or regular expression matched against the text of the incoming message
+	Pattern string `json:"pattern" validate:"required,max=256" example:"STOP"`
+	// Reply template with {phoneNumber}, {message} and {1}...{9} regular expression group placeholders
+	Reply string `json:"reply" validate:"required,max=1024" example:"You have been unsubscribed, {phoneNumber}"`
+	// Min interval in seconds between the replies of the rule to the same sender
+	Cooldown uint `json:"cooldown,omitempty" validate:"max=604800" example:"3600"`
+}
+
+type ruleResponse struct {
+	// Rule ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// ID of the device which replies, all devices of the user if empty
+	DeviceID *string `json:"deviceId,omitempty" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Match type
+	Type autoreplies.MatchType `json:"type" example:"keyword"`
+	// Keyword or regular expression
+	Pattern string `json:"pattern" example:"STOP"`
+	// Reply template
+	Reply string `json:"reply" example:"You have been unsubscribed, {phoneNumber}"`
+	// Min interval in seconds between the replies of the rule to the same sender
+	Cooldown uint `json:"cooldown" example:"3600"`
+	// Time when the rule was created
+	CreatedAt time.Time `json:"createdAt" example:"2025-11-03T12:00:00Z"`
+}
+
+func (r ruleRequest) toDomain() autoreplies.RuleIn {
+	return autoreplies.RuleIn{
+		DeviceID:  r.DeviceID,
+		MatchType: r.Type,
+		Pattern:   r.Pattern,
+		Reply:     r.Reply,
+		Cooldown:  time.Duration(r.Cooldown) * time.Second,
+	}
+}
+
+func ruleToDTO(rule autoreplies.Rule) ruleResponse {
+	return ruleResponse{
+		ID:        rule.ExtID,
+		DeviceID:  rule.DeviceID,
+		Type:      rule.MatchType,
+		Pattern:   rule.Pattern,
+		Reply:     rule.Reply,
+		Cooldown:  rule.CooldownSeconds,
+		CreatedAt: rule.CreatedAt,
+	}
+}
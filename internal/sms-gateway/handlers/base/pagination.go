@@ -0,0 +1,55 @@
+package base
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	HeaderTotalCount = "X-Total-Count"
+
+	paginationOffsetParam = "offset"
+	paginationLimitParam  = "limit"
+)
+
+// SetPagination sets the X-Total-Count header and the RFC 5988 Link header
+// with the first, prev, next and last pages of an offset-paginated list. The
+// links keep the other query parameters of the request.
+func SetPagination(c *fiber.Ctx, total int64, offset, limit int) {
+	c.Set(HeaderTotalCount, strconv.FormatInt(total, 10))
+
+	if limit <= 0 {
+		return
+	}
+
+	query := url.Values{}
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		query.Add(string(key), string(value))
+	})
+
+	link := func(offset int, rel string) string {
+		query.Set(paginationOffsetParam, strconv.Itoa(offset))
+		query.Set(paginationLimitParam, strconv.Itoa(limit))
+
+		return "<" + c.Path() + "?" + query.Encode() + ">; rel=\"" + rel + "\""
+	}
+
+	last := 0
+	if total > 0 {
+		last = int((total-1)/int64(limit)) * limit
+	}
+
+	links := []string{link(0, "first")}
+	if offset > 0 {
+		links = append(links, link(max(offset-limit, 0), "prev"))
+	}
+	if int64(offset+limit) < total {
+		links = append(links, link(offset+limit, "next"))
+	}
+	links = append(links, link(last, "last"))
+
+	c.Set(fiber.HeaderLink, strings.Join(links, ", "))
+}
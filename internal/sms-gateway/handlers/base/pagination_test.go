@@ -0,0 +1,78 @@
+package base_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestSetPagination(t *testing.T) {
+	tests := []struct {
+		description string
+		total       int64
+		offset      int
+		limit       int
+		wantLink    string
+	}{
+		{
+			description: "first page",
+			total:       25,
+			offset:      0,
+			limit:       10,
+			wantLink: `</items?limit=10&offset=0&state=Sent>; rel="first", ` +
+				`</items?limit=10&offset=10&state=Sent>; rel="next", ` +
+				`</items?limit=10&offset=20&state=Sent>; rel="last"`,
+		},
+		{
+			description: "middle page",
+			total:       25,
+			offset:      10,
+			limit:       10,
+			wantLink: `</items?limit=10&offset=0&state=Sent>; rel="first", ` +
+				`</items?limit=10&offset=0&state=Sent>; rel="prev", ` +
+				`</items?limit=10&offset=20&state=Sent>; rel="next", ` +
+				`</items?limit=10&offset=20&state=Sent>; rel="last"`,
+		},
+		{
+			description: "last page",
+			total:       25,
+			offset:      20,
+			limit:       10,
+			wantLink: `</items?limit=10&offset=0&state=Sent>; rel="first", ` +
+				`</items?limit=10&offset=10&state=Sent>; rel="prev", ` +
+				`</items?limit=10&offset=20&state=Sent>; rel="last"`,
+		},
+		{
+			description: "empty list",
+			total:       0,
+			offset:      0,
+			limit:       10,
+			wantLink: `</items?limit=10&offset=0&state=Sent>; rel="first", ` +
+				`</items?limit=10&offset=0&state=Sent>; rel="last"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/items", func(c *fiber.Ctx) error {
+				base.SetPagination(c, test.total, test.offset, test.limit)
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/items?state=Sent&offset=5", nil))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+
+			if got := resp.Header.Get(base.HeaderTotalCount); got == "" {
+				t.Errorf("expected %s header", base.HeaderTotalCount)
+			}
+			if got := resp.Header.Get(fiber.HeaderLink); got != test.wantLink {
+				t.Errorf("unexpected Link header:\n got: %s\nwant: %s", got, test.wantLink)
+			}
+		})
+	}
+}
@@ -1,5 +1,15 @@
 package handlers
 
+import (
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/ratelimit"
+)
+
+// ResponseGroups are the 3rd-party route groups supporting the response
+// compression and caching.
+var ResponseGroups = []string{"messages", "devices", "settings", "webhooks", "logs", "stats", "keys", "autoreplies"}
+
 type Config struct {
 	// PublicHost is host[:port] without scheme. Empty → use request Host.
 	PublicHost string
@@ -8,4 +18,22 @@ type Config struct {
 
 	UpstreamEnabled bool
 	OpenAPIEnabled  bool
+
+	// UpstreamKeys are instance keys allowed to relay push notifications. Empty → anonymous access.
+	UpstreamKeys []string
+	// UpstreamRateLimit is the max number of upstream requests per minute per instance.
+	UpstreamRateLimit int
+
+	// AdminToken protects the admin API. Empty → admin API is disabled.
+	AdminToken string
+
+	// CompressedGroups are 3rd-party route groups with brotli/gzip response compression.
+	CompressedGroups []string
+	// CachedGroups maps 3rd-party route groups to the TTL of cached GET responses.
+	CachedGroups map[string]time.Duration
+
+	// ThirdPartyRateLimit limits the 3rd-party API requests per user.
+	ThirdPartyRateLimit ratelimit.Config
+	// MobileRateLimit limits the mobile API requests per device.
+	MobileRateLimit ratelimit.Config
 }
@@ -5,11 +5,17 @@ import (
 	"fmt"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
-	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/converters"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devicelogs"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/enrollments"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/pings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sims"
 	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -18,40 +24,281 @@ import (
 type thirdPartyControllerParams struct {
 	fx.In
 
-	DevicesSvc *devices.Service
+	DevicesSvc     *devices.Service
+	EnrollmentsSvc *enrollments.Service
+	MessagesSvc    *messages.Service
+	PingsSvc       *pings.Service
+	DeviceLogsSvc  *devicelogs.Service
+	SimsSvc        *sims.Service
 
-	Logger *zap.Logger
+	Validator *validator.Validate
+	Logger    *zap.Logger
 }
 
 type ThirdPartyController struct {
 	base.Handler
 
-	devicesSvc *devices.Service
+	devicesSvc     *devices.Service
+	enrollmentsSvc *enrollments.Service
+	messagesSvc    *messages.Service
+	pingsSvc       *pings.Service
+	deviceLogsSvc  *devicelogs.Service
+	simsSvc        *sims.Service
 }
 
 //	@Summary		List devices
-//	@Description	Returns list of registered devices
+//	@Description	Returns list of registered devices with filtering, sorting and pagination. All matching devices are returned if the limit is not set
 //	@Security		ApiAuth
 //	@Tags			User, Devices
 //	@Produce		json
-//	@Success		200	{object}	[]smsgateway.Device			"Device list"
-//	@Failure		400	{object}	smsgateway.ErrorResponse	"Invalid request"
-//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
-//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Param			name			query		string						false	"Filter by name substring"						max(128)
+//	@Param			tag				query		string						false	"Filter by tag assigned on enrollment"			max(64)
+//	@Param			status			query		string						false	"Filter by online status"						Enum(online, offline)
+//	@Param			minAppVersion	query		string						false	"Minimum app version, inclusive"				example(1.40.0)
+//	@Param			maxAppVersion	query		string						false	"Maximum app version, inclusive"				example(1.41.0)
+//	@Param			lastSeenAfter	query		string						false	"Filter devices last seen after this timestamp"	Format(date-time)
+//	@Param			lastSeenBefore	query		string						false	"Filter devices last seen before this timestamp"	Format(date-time)
+//	@Param			sort			query		string						false	"Sort field, `-` prefix for descending order"	Enum(name, -name, lastSeen, -lastSeen, createdAt, -createdAt)	default(createdAt)
+//	@Param			limit			query		int							false	"Pagination limit"								min(1)	max(500)
+//	@Param			offset			query		int							false	"Pagination offset"								default(0)
+//	@Success		200				{object}	[]deviceResponse			"Device list"
+//	@Header			200				{integer}	X-Total-Count				"Total number of devices matching the filter"
+//	@Header			200				{string}	Link						"Links to the first, prev, next and last pages, only with the limit"
+//	@Failure		400				{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401				{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500				{object}	smsgateway.ErrorResponse	"Internal server error"
 //	@Router			/3rdparty/v1/devices [get]
 //
 // List devices
 func (h *ThirdPartyController) get(user models.User, c *fiber.Ctx) error {
-	devices, err := h.devicesSvc.Select(user.ID)
+	params := listQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	query := params.ToQuery()
+	devices, total, err := h.devicesSvc.List(user.ID, query)
 	if err != nil {
 		return fmt.Errorf("can't select devices: %w", err)
 	}
 
-	response := slices.Map(devices, converters.DeviceToDTO)
+	base.SetPagination(c, total, query.Offset, query.Limit)
+	return c.JSON(slices.Map(devices, deviceToDTO))
+}
+
+//	@Summary		Get devices presence
+//	@Description	Returns online status, last seen time and the number of pending messages for up to 100 devices
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		presenceRequest				true	"Device IDs"
+//	@Success		200		{object}	[]devicePresence			"Devices presence, in the order of the request"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/presence:batchGet [post]
+//
+// Get devices presence
+func (h *ThirdPartyController) postPresence(user models.User, c *fiber.Ctx) error {
+	req := presenceRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	items, err := h.devicesSvc.Select(user.ID)
+	if err != nil {
+		return fmt.Errorf("can't select devices: %w", err)
+	}
+
+	requested := make(map[string]struct{}, len(req.IDs))
+	for _, id := range req.IDs {
+		requested[id] = struct{}{}
+	}
+
+	found := make(map[string]models.Device, len(req.IDs))
+	ids := make([]string, 0, len(req.IDs))
+	for _, device := range items {
+		if _, ok := requested[device.ID]; ok {
+			found[device.ID] = device
+			ids = append(ids, device.ID)
+		}
+	}
+
+	pending, err := h.messagesSvc.CountPending(c.Context(), ids)
+	if err != nil {
+		return fmt.Errorf("can't count pending messages: %w", err)
+	}
+
+	response := make([]devicePresence, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		device, ok := found[id]
+		if !ok {
+			response = append(response, devicePresence{ID: id})
+			continue
+		}
+
+		response = append(response, devicePresence{
+			ID:       id,
+			Found:    true,
+			Online:   h.devicesSvc.IsOnline(device),
+			LastSeen: &device.LastSeen,
+			Pending:  pending[id],
+		})
+	}
 
 	return c.JSON(response)
 }
 
+//	@Summary		Get device health
+//	@Description	Returns connectivity diagnostics of the device based on recent pings
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Produce		json
+//	@Param			id	path		string						true	"Device ID"
+//	@Success		200	{object}	deviceHealthResponse		"Device health"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Device not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/{id}/health [get]
+//
+// Get device health
+func (h *ThirdPartyController) getHealth(user models.User, c *fiber.Ctx) error {
+	device, err := h.devicesSvc.Get(user.ID, devices.WithID(c.Params("id")))
+	if errors.Is(err, devices.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't get device: %w", err)
+	}
+
+	samples, err := h.pingsSvc.Samples(c.Context(), device.ID)
+	if err != nil {
+		return fmt.Errorf("can't get ping samples: %w", err)
+	}
+
+	return c.JSON(deviceHealthToDTO(device, samples))
+}
+
+//	@Summary		Get device logs
+//	@Description	Returns recent log entries uploaded by the device. Entries are kept for 72 hours, up to 1000 per device
+//	@Security		ApiAuth
+//	@Tags			User, Devices, Logs
+//	@Produce		json
+//	@Param			id		path		string						true	"Device ID"
+//	@Param			from	query		string						false	"Return entries created after this timestamp"	Format(date-time)
+//	@Param			to		query		string						false	"Return entries created before this timestamp"	Format(date-time)
+//	@Success		200		{object}	[]deviceLogEntry			"Log entries, oldest first"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	smsgateway.ErrorResponse	"Device not found"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/{id}/logs [get]
+//
+// Get device logs
+func (h *ThirdPartyController) getLogs(user models.User, c *fiber.Ctx) error {
+	params := logsQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	device, err := h.devicesSvc.Get(user.ID, devices.WithID(c.Params("id")))
+	if errors.Is(err, devices.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't get device: %w", err)
+	}
+
+	from, to := params.Range()
+	entries, err := h.deviceLogsSvc.Select(c.Context(), device.ID, from, to)
+	if err != nil {
+		return fmt.Errorf("can't get device logs: %w", err)
+	}
+
+	return c.JSON(deviceLogsToDTO(entries))
+}
+
+//	@Summary		Get device SIMs
+//	@Description	Returns the last balance and plan data reported by the device for its SIM cards
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Produce		json
+//	@Param			id	path		string						true	"Device ID"
+//	@Success		200	{object}	[]deviceSIM					"SIM cards"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Device not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/{id}/sims [get]
+//
+// Get device SIMs
+func (h *ThirdPartyController) getSims(user models.User, c *fiber.Ctx) error {
+	device, err := h.devicesSvc.Get(user.ID, devices.WithID(c.Params("id")))
+	if errors.Is(err, devices.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't get device: %w", err)
+	}
+
+	items, err := h.simsSvc.Select(user.ID, &device.ID)
+	if err != nil {
+		return fmt.Errorf("can't get device SIMs: %w", err)
+	}
+
+	return c.JSON(slices.Map(items, deviceSIMToDTO))
+}
+
+//	@Summary		Suspend device
+//	@Description	Suspends the device, so its requests are rejected until it is resumed. Messages to the device stay pending
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Produce		json
+//	@Param			id	path	string	true	"Device ID"
+//	@Success		204	"Successfully suspended"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Device not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/{id}/suspend [post]
+//
+// Suspend device
+func (h *ThirdPartyController) postSuspend(user models.User, c *fiber.Ctx) error {
+	err := h.devicesSvc.Suspend(user.ID, c.Params("id"))
+	if errors.Is(err, devices.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't suspend device: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+//	@Summary		Resume device
+//	@Description	Resumes the suspended device
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Produce		json
+//	@Param			id	path	string	true	"Device ID"
+//	@Success		204	"Successfully resumed"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Device not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/{id}/resume [post]
+//
+// Resume device
+func (h *ThirdPartyController) postResume(user models.User, c *fiber.Ctx) error {
+	err := h.devicesSvc.Resume(user.ID, c.Params("id"))
+	if errors.Is(err, devices.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't resume device: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 //	@Summary		Remove device
 //	@Description	Removes device
 //	@Security		ApiAuth
@@ -80,16 +327,111 @@ func (h *ThirdPartyController) remove(user models.User, c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+//	@Summary		Create enrollment token
+//	@Description	Creates a token that registers devices with the given name, tags, group and settings profile. The token is returned only once. Sensitive settings are not applied per device and are ignored
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		enrollmentRequest			true	"Enrollment"
+//	@Success		201		{object}	enrollmentCreatedResponse	"Enrollment with the token"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/enrollments [post]
+//
+// Create enrollment token
+func (h *ThirdPartyController) postEnrollment(user models.User, c *fiber.Ctx) error {
+	req := enrollmentRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	enrollment, token, err := h.enrollmentsSvc.Create(user.ID, req.toDomain())
+	var errValidation settings.ErrValidation
+	if errors.As(err, &errValidation) {
+		return fiber.NewError(fiber.StatusBadRequest, errValidation.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't create enrollment: %w", err)
+	}
+
+	return c.Status(fiber.StatusCreated).
+		JSON(enrollmentCreatedResponse{
+			enrollmentResponse: enrollmentToDTO(enrollment),
+			Token:              token,
+		})
+}
+
+//	@Summary		List enrollment tokens
+//	@Description	Returns enrollment tokens of the user, newest first. The tokens themselves are not returned
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Produce		json
+//	@Success		200	{object}	[]enrollmentResponse		"Enrollments"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/enrollments [get]
+//
+// List enrollment tokens
+func (h *ThirdPartyController) getEnrollments(user models.User, c *fiber.Ctx) error {
+	items, err := h.enrollmentsSvc.Select(user.ID)
+	if err != nil {
+		return fmt.Errorf("can't select enrollments: %w", err)
+	}
+
+	return c.JSON(slices.Map(items, enrollmentToDTO))
+}
+
+//	@Summary		Revoke enrollment token
+//	@Description	Revokes the enrollment token. Devices already registered with it keep their metadata
+//	@Security		ApiAuth
+//	@Tags			User, Devices
+//	@Param			id	path	string	true	"Enrollment ID"
+//	@Success		204	"Successfully revoked"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Enrollment not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/devices/enrollments/{id} [delete]
+//
+// Revoke enrollment token
+func (h *ThirdPartyController) deleteEnrollment(user models.User, c *fiber.Ctx) error {
+	err := h.enrollmentsSvc.Delete(user.ID, c.Params("id"))
+	if errors.Is(err, enrollments.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't delete enrollment: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (h *ThirdPartyController) Register(router fiber.Router) {
 	router.Get("", userauth.WithUser(h.get))
+	router.Post("presence\\:batchGet", userauth.WithUser(h.postPresence))
+	router.Post("enrollments", userauth.WithUser(h.postEnrollment))
+	router.Get("enrollments", userauth.WithUser(h.getEnrollments))
+	router.Delete("enrollments/:id", userauth.WithUser(h.deleteEnrollment))
+	router.Get(":id/health", userauth.WithUser(h.getHealth))
+	router.Get(":id/logs", userauth.WithUser(h.getLogs))
+	router.Get(":id/sims", userauth.WithUser(h.getSims))
+	router.Post(":id/suspend", userauth.WithUser(h.postSuspend))
+	router.Post(":id/resume", userauth.WithUser(h.postResume))
 	router.Delete(":id", userauth.WithUser(h.remove))
 }
 
 func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
 	return &ThirdPartyController{
 		Handler: base.Handler{
-			Logger: params.Logger.Named("devices"),
+			Logger:    params.Logger.Named("devices"),
+			Validator: params.Validator,
 		},
-		devicesSvc: params.DevicesSvc,
+		devicesSvc:     params.DevicesSvc,
+		enrollmentsSvc: params.EnrollmentsSvc,
+		messagesSvc:    params.MessagesSvc,
+		pingsSvc:       params.PingsSvc,
+		deviceLogsSvc:  params.DeviceLogsSvc,
+		simsSvc:        params.SimsSvc,
 	}
 }
@@ -0,0 +1,317 @@
+package devices
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/converters"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devicelogs"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/enrollments"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/pings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sims"
+	"github.com/capcom6/go-helpers/slices"
+)
+
+type deviceResponse struct {
+	smsgateway.Device
+
+	// Tags assigned on enrollment
+	Tags []string `json:"tags,omitempty" example:"warehouse"`
+	// Group assigned on enrollment
+	Group *string `json:"group,omitempty" example:"berlin"`
+	// Time the push token was rejected by the push provider, events are delivered by SSE and polling until the device registers a new token
+	PushDegradedAt *time.Time `json:"pushDegradedAt,omitempty" example:"2025-10-16T12:00:00.000Z"`
+	// Time the device was suspended, the device is not authorized until it is resumed
+	SuspendedAt *time.Time `json:"suspendedAt,omitempty" example:"2025-10-16T12:00:00.000Z"`
+}
+
+func deviceToDTO(d models.Device) deviceResponse {
+	return deviceResponse{
+		Device:         converters.DeviceToDTO(d),
+		Tags:           d.Tags,
+		Group:          d.Group,
+		PushDegradedAt: d.PushDegradedAt,
+		SuspendedAt:    d.SuspendedAt,
+	}
+}
+
+type enrollmentRequest struct {
+	// Name of the registered devices, overrides the name reported by the device
+	Name *string `json:"name,omitempty" validate:"omitempty,max=128" example:"Warehouse Phone"`
+	// Tags of the registered devices
+	Tags []string `json:"tags,omitempty" validate:"max=16,dive,required,max=32" example:"warehouse"`
+	// Group of the registered devices
+	Group *string `json:"group,omitempty" validate:"omitempty,max=64" example:"berlin"`
+	// Settings of the registered devices, applied on top of the user's settings and the assigned profiles
+	Settings map[string]any `json:"settings,omitempty"`
+	// Number of devices the token registers, zero for unlimited
+	MaxUses uint32 `json:"maxUses" example:"50"`
+	// Token expiration time
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" validate:"omitempty,gt" example:"2025-11-01T00:00:00Z"`
+}
+
+func (r enrollmentRequest) toDomain() enrollments.EnrollmentIn {
+	return enrollments.EnrollmentIn{
+		Name:      r.Name,
+		Tags:      r.Tags,
+		Group:     r.Group,
+		Settings:  r.Settings,
+		MaxUses:   r.MaxUses,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
+
+type enrollmentResponse struct {
+	// Enrollment ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Name of the registered devices
+	Name *string `json:"name,omitempty" example:"Warehouse Phone"`
+	// Tags of the registered devices
+	Tags []string `json:"tags,omitempty" example:"warehouse"`
+	// Group of the registered devices
+	Group *string `json:"group,omitempty" example:"berlin"`
+	// Settings of the registered devices
+	Settings map[string]any `json:"settings,omitempty"`
+	// Number of devices the token registers, zero for unlimited
+	MaxUses uint32 `json:"maxUses" example:"50"`
+	// Number of devices registered with the token
+	Uses uint32 `json:"uses" example:"12"`
+	// Token expiration time
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" example:"2025-11-01T00:00:00Z"`
+	// Created at
+	CreatedAt time.Time `json:"createdAt" example:"2025-10-16T12:00:00Z"`
+}
+
+type enrollmentCreatedResponse struct {
+	enrollmentResponse
+
+	// Enrollment token, returned only once
+	Token string `json:"token" example:"Fv3Qk1T9xLr0aWmZb8NcE"`
+}
+
+func enrollmentToDTO(e enrollments.Enrollment) enrollmentResponse {
+	return enrollmentResponse{
+		ID:        e.ID,
+		Name:      e.Name,
+		Tags:      e.Tags,
+		Group:     e.Group,
+		Settings:  e.Settings,
+		MaxUses:   e.MaxUses,
+		Uses:      e.Uses,
+		ExpiresAt: e.ExpiresAt,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+type pingSample struct {
+	// Server time of the ping
+	ReceivedAt time.Time `json:"receivedAt" example:"2025-10-16T12:00:00.060Z"`
+	// Round-trip time in milliseconds reported by the device
+	RTTMs *int64 `json:"rttMs,omitempty" example:"120"`
+	// Estimated clock skew in milliseconds, positive if the device clock is ahead
+	ClockSkewMs int64 `json:"clockSkewMs" example:"-15"`
+}
+
+type deviceHealthResponse struct {
+	// Device ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Last seen at
+	LastSeen time.Time `json:"lastSeen" example:"2025-10-16T12:00:00.000Z"`
+	// Number of recent pings
+	PingCount int `json:"pingCount" example:"20"`
+	// Last round-trip time in milliseconds
+	LastRTTMs *int64 `json:"lastRttMs,omitempty" example:"120"`
+	// Average round-trip time in milliseconds
+	AvgRTTMs *int64 `json:"avgRttMs,omitempty" example:"150"`
+	// Max round-trip time in milliseconds
+	MaxRTTMs *int64 `json:"maxRttMs,omitempty" example:"480"`
+	// Average clock skew in milliseconds, positive if the device clock is ahead
+	ClockSkewMs int64 `json:"clockSkewMs" example:"-15"`
+	// Recent pings, oldest first
+	Samples []pingSample `json:"samples"`
+}
+
+type presenceRequest struct {
+	// Device IDs, up to 100
+	IDs []string `json:"ids" validate:"required,min=1,max=100,dive,nanoid" example:"PyDmBQZZXYmyxMwED8Fzy"`
+}
+
+type devicePresence struct {
+	// Device ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Device is registered for the user
+	Found bool `json:"found" example:"true"`
+	// Device was active recently
+	Online bool `json:"online" example:"true"`
+	// Last seen at
+	LastSeen *time.Time `json:"lastSeen,omitempty" example:"2025-10-16T12:00:00.000Z"`
+	// Number of messages waiting to be delivered to the device
+	Pending int64 `json:"pending" example:"3"`
+}
+
+type listQueryParams struct {
+	Name           string `query:"name" validate:"omitempty,max=128"`
+	Tag            string `query:"tag" validate:"omitempty,max=64"`
+	Status         string `query:"status" validate:"omitempty,oneof=online offline"`
+	MinAppVersion  string `query:"minAppVersion" validate:"omitempty,max=32"`
+	MaxAppVersion  string `query:"maxAppVersion" validate:"omitempty,max=32"`
+	LastSeenAfter  string `query:"lastSeenAfter" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	LastSeenBefore string `query:"lastSeenBefore" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Sort           string `query:"sort" validate:"omitempty,oneof=name -name lastSeen -lastSeen createdAt -createdAt"`
+	Limit          int    `query:"limit" validate:"omitempty,min=1,max=500"`
+	Offset         int    `query:"offset" validate:"omitempty,min=0"`
+}
+
+func (p *listQueryParams) Validate() error {
+	for _, version := range []string{p.MinAppVersion, p.MaxAppVersion} {
+		if version != "" && !appVersionRegexp.MatchString(version) {
+			return fmt.Errorf("invalid app version %q, expected numeric version like 1.40.0", version)
+		}
+	}
+
+	after, before := p.lastSeenRange()
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		return errors.New("`lastSeenAfter` must be before `lastSeenBefore`")
+	}
+
+	return nil
+}
+
+// ToQuery builds the query of the devices, all the devices are returned if
+// the limit is not set.
+func (p *listQueryParams) ToQuery() devices.ListQuery {
+	query := devices.ListQuery{
+		MinAppVersion: p.MinAppVersion,
+		MaxAppVersion: p.MaxAppVersion,
+		SortBy:        devices.SortField(strings.TrimPrefix(p.Sort, "-")),
+		Desc:          strings.HasPrefix(p.Sort, "-"),
+		Limit:         p.Limit,
+		Offset:        p.Offset,
+	}
+
+	if p.Name != "" {
+		query.Filters = append(query.Filters, devices.WithNameContains(p.Name))
+	}
+	if p.Tag != "" {
+		query.Filters = append(query.Filters, devices.WithTag(p.Tag))
+	}
+
+	after, before := p.lastSeenRange()
+	if !after.IsZero() {
+		query.Filters = append(query.Filters, devices.LastSeenAfter(after))
+	}
+	if !before.IsZero() {
+		query.Filters = append(query.Filters, devices.LastSeenBefore(before))
+	}
+
+	if p.Status != "" {
+		online := p.Status == "online"
+		query.Online = &online
+	}
+
+	return query
+}
+
+func (p *listQueryParams) lastSeenRange() (after, before time.Time) {
+	after, _ = time.Parse(time.RFC3339, p.LastSeenAfter)
+	before, _ = time.Parse(time.RFC3339, p.LastSeenBefore)
+	return after, before
+}
+
+var appVersionRegexp = regexp.MustCompile(`^v?\d+(\.\d+)*$`)
+
+type logsQueryParams struct {
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To   string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// Range returns the parsed bounds, zero if not set.
+func (p *logsQueryParams) Range() (from, to time.Time) {
+	from, _ = time.Parse(time.RFC3339, p.From)
+	to, _ = time.Parse(time.RFC3339, p.To)
+	return from, to
+}
+
+type deviceLogEntry struct {
+	// Log priority
+	Priority string `json:"priority" example:"ERROR"`
+	// Module that produced the entry
+	Module string `json:"module" example:"messages"`
+	// Log message
+	Message string `json:"message" example:"Can't send message"`
+	// Additional context
+	Context map[string]string `json:"context,omitempty"`
+	// Time when the entry was created on the device
+	CreatedAt time.Time `json:"createdAt" example:"2025-10-16T12:00:00.000Z"`
+}
+
+func durationToMs(d *time.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+
+	ms := d.Milliseconds()
+	return &ms
+}
+
+func deviceHealthToDTO(device models.Device, samples []pings.Sample) deviceHealthResponse {
+	stats := pings.Summarize(samples)
+
+	return deviceHealthResponse{
+		ID:          device.ID,
+		LastSeen:    device.LastSeen,
+		PingCount:   stats.Count,
+		LastRTTMs:   durationToMs(stats.LastRTT),
+		AvgRTTMs:    durationToMs(stats.AvgRTT),
+		MaxRTTMs:    durationToMs(stats.MaxRTT),
+		ClockSkewMs: stats.AvgSkew.Milliseconds(),
+		Samples: slices.Map(samples, func(s pings.Sample) pingSample {
+			return pingSample{
+				ReceivedAt:  s.ReceivedAt,
+				RTTMs:       durationToMs(s.RTT),
+				ClockSkewMs: s.Skew.Milliseconds(),
+			}
+		}),
+	}
+}
+
+func deviceLogsToDTO(entries []devicelogs.Entry) []deviceLogEntry {
+	return slices.Map(entries, func(e devicelogs.Entry) deviceLogEntry {
+		return deviceLogEntry(e)
+	})
+}
+
+type deviceSIM struct {
+	// SIM card number
+	SimNumber uint8 `json:"simNumber" example:"1"`
+	// Carrier name
+	Operator *string `json:"operator,omitempty" example:"Vodafone"`
+	// Account balance
+	Balance *float64 `json:"balance,omitempty" example:"12.5"`
+	// Balance currency
+	Currency *string `json:"currency,omitempty" example:"EUR"`
+	// Plan name
+	Plan *string `json:"plan,omitempty" example:"Unlimited SMS"`
+	// Plan or balance expiration time
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" example:"2025-11-01T00:00:00Z"`
+	// Time of the last report
+	ReportedAt time.Time `json:"reportedAt" example:"2025-10-16T12:00:00Z"`
+}
+
+func deviceSIMToDTO(sim sims.SIM) deviceSIM {
+	return deviceSIM{
+		SimNumber:  sim.SimNumber,
+		Operator:   sim.Operator,
+		Balance:    sim.Balance,
+		Currency:   sim.Currency,
+		Plan:       sim.Plan,
+		ExpiresAt:  sim.ExpiresAt,
+		ReportedAt: sim.ReportedAt,
+	}
+}
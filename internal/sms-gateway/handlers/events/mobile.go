@@ -1,9 +1,14 @@
 package events
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/deviceauth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	appevents "github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sse"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -16,6 +21,37 @@ type MobileController struct {
 	sseSvc *sse.Service
 }
 
+type eventsQueryParams struct {
+	Events string `query:"events" validate:"omitempty,max=256"`
+}
+
+// EventTypes returns the requested event types, empty for all.
+func (p *eventsQueryParams) EventTypes() []string {
+	types := []string{}
+	for _, item := range strings.Split(p.Events, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			types = append(types, item)
+		}
+	}
+
+	return types
+}
+
+type ackRequest struct {
+	// ID of the last processed event, the events up to it aren't re-sent on reconnect
+	ID int64 `json:"id" validate:"required,min=1" example:"7"`
+}
+
+func (p *eventsQueryParams) Validate() error {
+	for _, item := range p.EventTypes() {
+		if !appevents.IsType(item) {
+			return fmt.Errorf("unknown event type: %s", item)
+		}
+	}
+
+	return nil
+}
+
 func NewMobileController(sseService *sse.Service, validator *validator.Validate, logger *zap.Logger) *MobileController {
 	return &MobileController{
 		Handler: base.Handler{
@@ -27,25 +63,91 @@ func NewMobileController(sseService *sse.Service, validator *validator.Validate,
 }
 
 //	@Summary		Get events
-//	@Description	Returns events stream for a device
+//	@Description	Returns events stream for a device. If coalescing is configured for the event type, the events sent within the window are merged into one with the `count` and `events` data fields. The stream is compressed with the `gzip` or `deflate` encoding from the `Accept-Encoding` header unless the compression is disabled on the server
 //	@Security		MobileToken
 //	@Tags			Device, Events
 //	@x-sse			true
 //	@Produce		text/event-stream
-//	@Header			200	{string}	Content-Type				"text/event-stream"
-//	@Header			200	{string}	Transfer-Encoding			"chunked"
-//	@Header			200	{string}	Connection					"keep-alive"
-//	@Header			200	{string}	Cache-Control				"no-cache"
-//	@Success		200	{string}	string						"Event"
-//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
-//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Param			events	query		string						false	"Comma-separated event types to receive, all if empty"	example(MessageEnqueued,SettingsUpdated)
+//	@Header			200		{string}	Content-Type				"text/event-stream"
+//	@Header			200		{string}	Transfer-Encoding			"chunked"
+//	@Header			200		{string}	Connection					"keep-alive"
+//	@Header			200		{string}	Cache-Control				"no-cache"
+//	@Header			200		{string}	Content-Encoding			"gzip or deflate if accepted by the device"
+//	@Success		200		{string}	string						"Event"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
 //	@Router			/mobile/v1/events [get]
 //
 // Get events
 func (h *MobileController) get(device models.Device, c *fiber.Ctx) error {
-	return h.sseSvc.Handler(device.ID, c)
+	params := eventsQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	return h.sseSvc.Handler(device.ID, params.EventTypes(), c)
+}
+
+//	@Summary		Get events via WebSocket
+//	@Description	Upgrades the connection to WebSocket and streams events for a device as JSON text messages, for networks that buffer the events stream. Missed events are replayed after the `Last-Event-ID` header value
+//	@Security		MobileToken
+//	@Tags			Device, Events
+//	@Param			Last-Event-ID	header		string						false	"ID of the last received event"
+//	@Param			events			query		string						false	"Comma-separated event types to receive, all if empty"	example(MessageEnqueued,SettingsUpdated)
+//	@Success		101				{object}	sse.WebSocketMessage		"Event message"
+//	@Failure		400				{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401				{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		426				{object}	smsgateway.ErrorResponse	"WebSocket upgrade required"
+//	@Failure		500				{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/events/ws [get]
+//
+// Get events via WebSocket
+func (h *MobileController) getWebSocket(device models.Device, c *fiber.Ctx) error {
+	params := eventsQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	return h.sseSvc.WebSocketHandler(device.ID, params.EventTypes(), c)
+}
+
+//	@Summary		Acknowledge events
+//	@Description	Reports the ID of the last event processed by the device. The ack is cumulative: the device acknowledges the event after processing all the previous ones. Once the device acknowledges the events, the events after the acknowledged one are re-sent on reconnect, even if they were received. An ack older than the stored one is ignored
+//	@Security		MobileToken
+//	@Tags			Device, Events
+//	@Accept			json
+//	@Param			request	body	ackRequest	true	"Last processed event"
+//	@Success		204		"Acknowledged"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request or unknown event ID"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	smsgateway.ErrorResponse	"Events replay is disabled"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/events/ack [post]
+//
+// Acknowledge events
+func (h *MobileController) postAck(device models.Device, c *fiber.Ctx) error {
+	req := ackRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	err := h.sseSvc.Ack(device.ID, req.ID)
+	switch {
+	case errors.Is(err, sse.ErrReplayDisabled):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, sse.ErrUnknownEvent):
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	case err != nil:
+		return fmt.Errorf("can't acknowledge events: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
 }
 
 func (h *MobileController) Register(router fiber.Router) {
 	router.Get("", deviceauth.WithDevice(h.get))
+	router.Get("ws", deviceauth.WithDevice(h.getWebSocket))
+	router.Post("ack", deviceauth.WithDevice(h.postAck))
 }
@@ -0,0 +1,96 @@
+package keys
+
+import (
+	"errors"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/keys"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type thirdPartyControllerParams struct {
+	fx.In
+
+	KeysSvc *keys.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type ThirdPartyController struct {
+	base.Handler
+
+	keysSvc *keys.Service
+}
+
+//	@Summary		List encryption keys
+//	@Description	Returns the client-side encryption keys published by the devices of the user. The key material is opaque to the server.
+//	@Security		ApiAuth
+//	@Tags			User, Encryption
+//	@Produce		json
+//	@Param			revoked	query		bool						false	"Include revoked keys"
+//	@Success		200		{object}	[]keyResponse				"Encryption keys"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/keys [get]
+//
+// List encryption keys
+func (h *ThirdPartyController) list(user models.User, c *fiber.Ctx) error {
+	params := thirdPartyGetQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	items, err := h.keysSvc.Select(user.ID, params.Revoked)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(slices.Map(items, keyToDTO))
+}
+
+//	@Summary		Revoke encryption key
+//	@Description	Marks the key as not usable for new messages and notifies the devices. The key is kept to decrypt the existing messages.
+//	@Security		ApiAuth
+//	@Tags			User, Encryption
+//	@Produce		json
+//	@Param			id	path		string						true	"Key ID"
+//	@Success		204	{object}	object						"Key revoked"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Key not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/keys/{id} [delete]
+//
+// Revoke encryption key
+func (h *ThirdPartyController) delete(user models.User, c *fiber.Ctx) error {
+	if err := h.keysSvc.Revoke(user.ID, c.Params("id")); err != nil {
+		if errors.Is(err, keys.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *ThirdPartyController) Register(router fiber.Router) {
+	router.Get("", userauth.WithUser(h.list))
+	router.Delete("/:id", userauth.WithUser(h.delete))
+}
+
+func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
+	return &ThirdPartyController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("keys"),
+			Validator: params.Validator,
+		},
+		keysSvc: params.KeysSvc,
+	}
+}
@@ -0,0 +1,47 @@
+package keys
+
+import (
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/keys"
+)
+
+type keyResponse struct {
+	// Key ID referenced by the messages
+	ID string `json:"id" example:"2025-11-aes"`
+	// Encryption algorithm
+	Algorithm string `json:"algorithm" example:"AES-256-GCM"`
+	// Public or wrapped key material, opaque to the server
+	Material string `json:"material" example:"MCowBQYDK2VuAyEAtD1Z3eYMh2iX2l2cQGd9oCyrT5hCMr7X8sQp0Yv0P0I="`
+	// ID of the device which published the key
+	DeviceID string `json:"deviceId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Time when the key was published
+	CreatedAt time.Time `json:"createdAt" example:"2025-11-03T12:00:00Z"`
+	// Time when the key was revoked
+	RevokedAt *time.Time `json:"revokedAt,omitempty" example:"2025-11-03T12:00:00Z"`
+}
+
+type thirdPartyGetQueryParams struct {
+	// Include revoked keys
+	Revoked bool `query:"revoked"`
+}
+
+type mobilePostRequest struct {
+	// Key ID referenced by the messages
+	ID string `json:"id" validate:"required,max=64" example:"2025-11-aes"`
+	// Encryption algorithm
+	Algorithm string `json:"algorithm" validate:"required,max=32" example:"AES-256-GCM"`
+	// Public or wrapped key material, opaque to the server
+	Material string `json:"material" validate:"required,max=8192" example:"MCowBQYDK2VuAyEAtD1Z3eYMh2iX2l2cQGd9oCyrT5hCMr7X8sQp0Yv0P0I="`
+}
+
+func keyToDTO(key keys.Key) keyResponse {
+	return keyResponse{
+		ID:        key.KeyID,
+		Algorithm: key.Algorithm,
+		Material:  key.Material,
+		DeviceID:  key.DeviceID,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
@@ -0,0 +1,59 @@
+package keys
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/keys"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestMobilePostRequest_Validate(t *testing.T) {
+	valid := mobilePostRequest{ID: "2025-11-aes", Algorithm: "AES-256-GCM", Material: "material"}
+
+	tests := []struct {
+		name    string
+		modify  func(r *mobilePostRequest)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(r *mobilePostRequest) {}},
+		{name: "no ID", modify: func(r *mobilePostRequest) { r.ID = "" }, wantErr: true},
+		{name: "long ID", modify: func(r *mobilePostRequest) { r.ID = strings.Repeat("k", 65) }, wantErr: true},
+		{name: "no algorithm", modify: func(r *mobilePostRequest) { r.Algorithm = "" }, wantErr: true},
+		{name: "long algorithm", modify: func(r *mobilePostRequest) { r.Algorithm = strings.Repeat("a", 33) }, wantErr: true},
+		{name: "no material", modify: func(r *mobilePostRequest) { r.Material = "" }, wantErr: true},
+		{name: "long material", modify: func(r *mobilePostRequest) { r.Material = strings.Repeat("m", 8193) }, wantErr: true},
+	}
+
+	v := validator.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+
+			if err := v.Struct(req); (err != nil) != tt.wantErr {
+				t.Errorf("Struct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyToDTO(t *testing.T) {
+	revokedAt := time.Date(2025, 11, 3, 12, 0, 0, 0, time.UTC)
+	key := keys.Key{
+		KeyID:     "2025-11-aes",
+		DeviceID:  "device",
+		Algorithm: "AES-256-GCM",
+		Material:  "material",
+		RevokedAt: &revokedAt,
+	}
+
+	got := keyToDTO(key)
+	if got.ID != key.KeyID || got.Algorithm != key.Algorithm || got.Material != key.Material || got.DeviceID != key.DeviceID {
+		t.Errorf("keyToDTO() = %+v", got)
+	}
+	if got.RevokedAt == nil || !got.RevokedAt.Equal(revokedAt) {
+		t.Errorf("keyToDTO() revokedAt = %v, want %v", got.RevokedAt, revokedAt)
+	}
+}
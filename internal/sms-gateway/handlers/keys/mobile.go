@@ -0,0 +1,128 @@
+package keys
+
+import (
+	"errors"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/deviceauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/keys"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type mobileControllerParams struct {
+	fx.In
+
+	KeysSvc *keys.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type MobileController struct {
+	base.Handler
+
+	keysSvc *keys.Service
+}
+
+//	@Summary		List encryption keys
+//	@Description	Returns the encryption keys published by the devices of the user including revoked ones, so the existing messages can be decrypted.
+//	@Security		MobileToken
+//	@Tags			Device, Encryption
+//	@Produce		json
+//	@Success		200	{object}	[]keyResponse				"Encryption keys"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/keys [get]
+//
+// List encryption keys
+func (h *MobileController) list(device models.Device, c *fiber.Ctx) error {
+	items, err := h.keysSvc.Select(device.UserID, true)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(slices.Map(items, keyToDTO))
+}
+
+//	@Summary		Publish encryption key
+//	@Description	Publishes the public or wrapped key of the device. The other devices of the user are notified with the `EncryptionKeysUpdated` event.
+//	@Security		MobileToken
+//	@Tags			Device, Encryption
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		mobilePostRequest			true	"Key"
+//	@Success		201		{object}	keyResponse					"Key published"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		409		{object}	smsgateway.ErrorResponse	"Key with such ID already exists"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/keys [post]
+//
+// Publish encryption key
+func (h *MobileController) post(device models.Device, c *fiber.Ctx) error {
+	req := mobilePostRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	key, err := h.keysSvc.Register(device, keys.KeyIn{
+		KeyID:     req.ID,
+		Algorithm: req.Algorithm,
+		Material:  req.Material,
+	})
+	if err != nil {
+		if errors.Is(err, keys.ErrExists) {
+			return fiber.NewError(fiber.StatusConflict, err.Error())
+		}
+
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(keyToDTO(key))
+}
+
+//	@Summary		Revoke encryption key
+//	@Description	Marks the key as not usable for new messages, e.g. on rotation. The key is kept to decrypt the existing messages.
+//	@Security		MobileToken
+//	@Tags			Device, Encryption
+//	@Produce		json
+//	@Param			id	path		string						true	"Key ID"
+//	@Success		204	{object}	object						"Key revoked"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Key not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/keys/{id} [delete]
+//
+// Revoke encryption key
+func (h *MobileController) delete(device models.Device, c *fiber.Ctx) error {
+	if err := h.keysSvc.Revoke(device.UserID, c.Params("id")); err != nil {
+		if errors.Is(err, keys.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *MobileController) Register(router fiber.Router) {
+	router.Get("", deviceauth.WithDevice(h.list))
+	router.Post("", deviceauth.WithDevice(h.post))
+	router.Delete("/:id", deviceauth.WithDevice(h.delete))
+}
+
+func NewMobileController(params mobileControllerParams) *MobileController {
+	return &MobileController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("keys"),
+			Validator: params.Validator,
+		},
+		keysSvc: params.KeysSvc,
+	}
+}
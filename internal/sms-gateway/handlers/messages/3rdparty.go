@@ -3,16 +3,21 @@ package messages
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
-	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/converters"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/keys"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	appmetrics "github.com/android-sms-gateway/server/internal/sms-gateway/modules/metrics"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/relay"
 	"github.com/capcom6/go-helpers/slices"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -22,6 +27,7 @@ import (
 
 const (
 	route3rdPartyGetMessage = "3rdparty.get.message"
+	route3rdPartyGetExport  = "3rdparty.get.export"
 )
 
 type thirdPartyControllerParams struct {
@@ -29,6 +35,11 @@ type thirdPartyControllerParams struct {
 
 	MessagesSvc *messages.Service
 	DevicesSvc  *devices.Service
+	ExportsSvc  *exports.Service
+	LinksSvc    *links.Service
+	RelaySvc    *relay.Service
+	KeysSvc     *keys.Service
+	AuthSvc     *auth.Service
 
 	Validator *validator.Validate
 	Logger    *zap.Logger
@@ -39,23 +50,36 @@ type ThirdPartyController struct {
 
 	messagesSvc *messages.Service
 	devicesSvc  *devices.Service
+	exportsSvc  *exports.Service
+	linksSvc    *links.Service
+	relaySvc    *relay.Service
+	keysSvc     *keys.Service
+	authSvc     *auth.Service
 }
 
 //	@Summary		Enqueue message
 //	@Description	Enqueues a message for sending. If `deviceId` is set, the specified device is used; otherwise a random registered device is chosen.
+//	@Description	If duplicate protection is enabled and an equal message was enqueued within the window, the original message is returned with the `duplicateOf` field set.
+//	@Description	If relaying is configured and none of the selected devices was online recently, the message is forwarded to the upstream instance; its states are relayed back to the message.
+//	@Description	Messages with priority 100 or higher bypass the device sending limits up to the `messages.priority_burst_value` per `messages.priority_burst_period` budget from settings; beyond it they are sent with regular priority.
+//	@Description	The server may limit the message lifetime: messages without `ttl` and `validUntil` get the default TTL, and messages living longer than the max TTL are rejected with 400.
+//	@Description	Messages with priority below 100 to the recipients outside the send window of their country in the local time are rejected with 400; the windows are set by the server and the `send_windows.countries` settings, e.g. `{"FR": "08:00-20:00"}`, which may only narrow the server windows. The message expires when the window closes.
 //	@Security		ApiAuth
 //	@Tags			User, Messages
 //	@Accept			json
 //	@Produce		json
 //	@Param			skipPhoneValidation	query		bool							false	"Skip phone validation"
 //	@Param			deviceActiveWithin	query		int								false	"Filter devices active within the specified number of hours"	default(0)	minimum(0)
-//	@Param			request				body		smsgateway.Message				true	"Send message request"
-//	@Success		202					{object}	smsgateway.GetMessageResponse	"Message enqueued"
+//	@Param			trackLinks			query		bool							false	"Replace URLs with short links counting clicks"
+//	@Param			request				body		postMessageRequest				true	"Send message request"
+//	@Success		202					{object}	postMessageResponse				"Message enqueued"
 //	@Failure		400					{object}	smsgateway.ErrorResponse		"Invalid request"
 //	@Failure		401					{object}	smsgateway.ErrorResponse		"Unauthorized"
 //	@Failure		409					{object}	smsgateway.ErrorResponse		"Message with such ID already exists"
 //	@Failure		500					{object}	smsgateway.ErrorResponse		"Internal server error"
+//	@Failure		503					{object}	smsgateway.ErrorResponse		"Too many pending messages of the sandbox device"
 //	@Header			202					{string}	Location						"Get message state URL"
+//	@Header			202					{string}	X-Content-Warnings				"Comma-separated list of content policy rules triggered by the message"
 //	@Router			/3rdparty/v1/messages [post]
 //
 // Enqueue message
@@ -65,14 +89,27 @@ func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
 		return err
 	}
 
-	var req smsgateway.Message
+	var req postMessageRequest
 	if err := h.BodyParserValidator(c, &req); err != nil {
 		return err
 	}
 
+	if req.Encryption != nil {
+		if _, err := h.keysSvc.Active(user.ID, req.Encryption.KeyID, req.Encryption.Algorithm); err != nil {
+			if errors.Is(err, keys.ErrNotFound) || errors.Is(err, keys.ErrRevoked) {
+				return fiber.NewError(fiber.StatusBadRequest, "Unknown or revoked encryption key")
+			}
+			if errors.Is(err, keys.ErrAlgorithmMismatch) {
+				return fiber.NewError(fiber.StatusBadRequest, "Encryption algorithm doesn't match the key")
+			}
+			return fmt.Errorf("can't get encryption key: %w", err)
+		}
+	}
+
 	var device models.Device
 	var err error
 	var filters []devices.SelectFilter
+	var useRelay bool
 
 	if params.DeviceActiveWithin > 0 {
 		filters = append(filters, devices.ActiveWithin(time.Duration(params.DeviceActiveWithin)*time.Hour))
@@ -101,55 +138,55 @@ func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
 			return fiber.NewError(fiber.StatusBadRequest, "No active devices found")
 		}
 
+		devices = h.messagesSvc.RouteByCountry(user.ID, req.PhoneNumbers, devices)
+		devices = h.messagesSvc.FilterHealthy(devices)
+		useRelay = h.relaySvc.ShouldRelay(devices)
+
 		device, err = slices.Random(devices)
 		if err != nil {
 			return fmt.Errorf("can't get random device: %w", err)
 		}
 	}
 
-	var textContent *messages.TextMessageContent
-	var dataContent *messages.DataMessageContent
-	if text := req.GetTextMessage(); text != nil {
-		textContent = &messages.TextMessageContent{
-			Text: text.Text,
-		}
-	} else if data := req.GetDataMessage(); data != nil {
-		dataContent = &messages.DataMessageContent{
-			Data: data.Data,
-			Port: data.Port,
-		}
-	} else {
-		return fiber.NewError(fiber.StatusBadRequest, "No message content provided")
+	msg, err := requestToMessageIn(req.Message)
+	if err != nil {
+		return err
 	}
+	msg.Encryption = encryptionFromDTO(req.Encryption)
 
-	msg := messages.MessageIn{
-		ID: req.ID,
-
-		TextContent: textContent,
-		DataContent: dataContent,
-
-		PhoneNumbers: req.PhoneNumbers,
-		IsEncrypted:  req.IsEncrypted,
-
-		SimNumber:          req.SimNumber,
-		WithDeliveryReport: req.WithDeliveryReport,
-		TTL:                req.TTL,
-		ValidUntil:         req.ValidUntil,
-		Priority:           req.Priority,
-	}
-	state, err := h.messagesSvc.Enqueue(device, msg, messages.EnqueueOptions{SkipPhoneValidation: params.SkipPhoneValidation})
+	state, err := h.messagesSvc.Enqueue(device, msg, messages.EnqueueOptions{
+		SkipPhoneValidation: params.SkipPhoneValidation,
+		TrackLinks:          params.TrackLinks,
+		Relay:               useRelay,
+		TraceID:             appmetrics.TraceID(c),
+	})
 	if err != nil {
 		var errValidation messages.ErrValidation
 		if isBadRequest := errors.As(err, &errValidation); isBadRequest {
 			return fiber.NewError(fiber.StatusBadRequest, errValidation.Error())
 		}
+		var errContentPolicy messages.ErrContentPolicy
+		if isBlocked := errors.As(err, &errContentPolicy); isBlocked {
+			return fiber.NewError(fiber.StatusBadRequest, errContentPolicy.Error())
+		}
+		var errSendWindow messages.ErrSendWindow
+		if isOutside := errors.As(err, &errSendWindow); isOutside {
+			return fiber.NewError(fiber.StatusBadRequest, errSendWindow.Error())
+		}
 		if isConflict := errors.Is(err, messages.ErrMessageAlreadyExists); isConflict {
 			return fiber.NewError(fiber.StatusConflict, err.Error())
 		}
+		if isBusy := errors.Is(err, messages.ErrSimulatorBusy); isBusy {
+			return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+		}
 
 		return fmt.Errorf("can't enqueue message: %w", err)
 	}
 
+	if warnings := h.messagesSvc.CheckContent(msg); len(warnings) > 0 {
+		c.Set(headerContentWarnings, strings.Join(slices.Map(warnings, contentWarningRule), ","))
+	}
+
 	location, err := c.GetRouteURL(route3rdPartyGetMessage, fiber.Map{
 		"id": state.ID,
 	})
@@ -160,29 +197,80 @@ func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
 	}
 
 	return c.Status(fiber.StatusAccepted).
-		JSON(smsgateway.GetMessageResponse{
-			ID:          state.ID,
-			DeviceID:    state.DeviceID,
-			State:       smsgateway.ProcessingState(state.State),
-			IsHashed:    state.IsHashed,
-			IsEncrypted: state.IsEncrypted,
-			Recipients:  state.Recipients,
-			States:      state.States,
+		JSON(postMessageResponse{
+			GetMessageResponse: smsgateway.GetMessageResponse{
+				ID:          state.ID,
+				DeviceID:    state.DeviceID,
+				State:       smsgateway.ProcessingState(state.State),
+				IsHashed:    state.IsHashed,
+				IsEncrypted: state.IsEncrypted,
+				Recipients:  state.Recipients,
+				States:      state.States,
+			},
+			DuplicateOf: state.DuplicateOf,
 		})
 }
 
+//	@Summary		Preview message
+//	@Description	Validates a message without enqueueing it. Returns normalized phone numbers and content policy warnings that may cause carrier filtering.
+//	@Security		ApiAuth
+//	@Tags			User, Messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			skipPhoneValidation	query		bool						false	"Skip phone validation"
+//	@Param			request				body		smsgateway.Message			true	"Message to preview"
+//	@Success		200					{object}	previewResponse				"Preview result"
+//	@Failure		400					{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401					{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500					{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/messages/preview [post]
+//
+// Preview message
+func (h *ThirdPartyController) postPreview(user models.User, c *fiber.Ctx) error {
+	var params thirdPartyPostQueryParams
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	var req smsgateway.Message
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	msg, err := requestToMessageIn(req)
+	if err != nil {
+		return err
+	}
+
+	preview, err := h.messagesSvc.Preview(msg, messages.EnqueueOptions{SkipPhoneValidation: params.SkipPhoneValidation})
+	if err != nil {
+		var errValidation messages.ErrValidation
+		if errors.As(err, &errValidation) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		return fmt.Errorf("can't preview message: %w", err)
+	}
+
+	return c.JSON(previewToDTO(preview))
+}
+
 //	@Summary		Get messages
 //	@Description	Retrieves a list of messages with filtering and pagination
 //	@Security		ApiAuth
 //	@Tags			User, Messages
 //	@Produce		json
-//	@Param			from		query		string							false	"Start date in RFC3339 format"			Format(date-time)
-//	@Param			to			query		string							false	"End date in RFC3339 format"			Format(date-time)
+//	@Param			from		query		string							false	"Start date-time in RFC3339 format or start date in YYYY-MM-DD format"
+//	@Param			to			query		string							false	"End date-time in RFC3339 format (exclusive) or end date in YYYY-MM-DD format (inclusive)"
+//	@Param			tz			query		string							false	"IANA time zone of the dates, the user's time zone by default"	example(Europe/Berlin)
 //	@Param			state		query		string							false	"Filter messages by processing state"	Enum(Pending, Processed, Sent, Delivered, Failed)
 //	@Param			deviceId	query		string							false	"Filter by device ID"					min(21)		max(21)
+//	@Param			phoneNumber	query		string							false	"Filter by recipient phone number, `+` must be encoded as `%2B`; recipients of encrypted messages aren't matched"	max(128)
 //	@Param			limit		query		int								false	"Pagination limit"						default(50)	min(1)	max(100)
 //	@Param			offset		query		int								false	"Pagination offset"						default(0)
-//	@Success		200			{object}	smsgateway.GetMessagesResponse	"A list of messages"
+//	@Success		200			{object}	[]messageState					"A list of messages"
+//	@Header			200			{integer}	X-Total-Count					"Total number of messages matching the filter"
+//	@Header			200			{string}	Link							"Links to the first, prev, next and last pages"
 //	@Failure		400			{object}	smsgateway.ErrorResponse		"Invalid request"
 //	@Failure		401			{object}	smsgateway.ErrorResponse		"Unauthorized"
 //	@Failure		500			{object}	smsgateway.ErrorResponse		"Internal server error"
@@ -195,28 +283,63 @@ func (h *ThirdPartyController) list(user models.User, c *fiber.Ctx) error {
 		return err
 	}
 
-	messages, total, err := h.messagesSvc.SelectStates(user, params.ToFilter(), params.ToOptions())
+	loc, err := h.location(user, params)
+	if err != nil {
+		return err
+	}
+
+	filter, err := params.ToFilter(loc)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	options := params.ToOptions()
+	messages, total, err := h.messagesSvc.SelectStates(user, filter, options)
 	if err != nil {
 		h.Logger.Error("Failed to get message history", zap.Error(err), zap.String("user_id", user.ID))
 		return fiber.NewError(fiber.StatusInternalServerError, "Failed to retrieve message history")
 	}
 
-	c.Set("X-Total-Count", strconv.Itoa(int(total)))
+	base.SetPagination(c, total, options.Offset, options.Limit)
 	return c.JSON(
-		slices.Map(messages, converters.MessageStateToDTO),
+		slices.Map(messages, messageStateToDTO),
 	)
 }
 
+// location returns the time zone of the date-only filters: the requested one
+// or the current time zone of the user.
+func (h *ThirdPartyController) location(user models.User, params thirdPartyGetQueryParams) (*time.Location, error) {
+	if params.Timezone != "" {
+		loc, err := time.LoadLocation(params.Timezone)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid `tz` value")
+		}
+		return loc, nil
+	}
+
+	if !params.HasDates() {
+		return time.UTC, nil
+	}
+
+	// the authorized user may be cached, so the time zone is read again
+	current, err := h.authSvc.GetUser(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("can't get user: %w", err)
+	}
+
+	return current.Location(), nil
+}
+
 //	@Summary		Get message state and text
 //	@Description	Returns message state and text by ID
 //	@Security		ApiAuth
 //	@Tags			User, Messages
 //	@Produce		json
 //	@Param			id	path		string							true	"Message ID"
-//	@Success		200	{object}	smsgateway.GetMessageResponse	"Message state"
-//	@Failure		400	{object}	smsgateway.ErrorResponse		"Invalid request"
-//	@Failure		401	{object}	smsgateway.ErrorResponse		"Unauthorized"
-//	@Failure		500	{object}	smsgateway.ErrorResponse		"Internal server error"
+//	@Success		200	{object}	messageResponse				"Message state"
+//	@Failure		400	{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
 //	@Router			/3rdparty/v1/messages/{id} [get]
 //
 // Get message state
@@ -232,7 +355,41 @@ func (h *ThirdPartyController) get(user models.User, c *fiber.Ctx) error {
 		return err
 	}
 
-	return c.JSON(converters.MessageToMobileDTO(msg))
+	return c.JSON(messageToDTO(msg))
+}
+
+//	@Summary		Get message links
+//	@Description	Returns short links of the message with click statistics. Links are tracked only for messages sent with `trackLinks`.
+//	@Security		ApiAuth
+//	@Tags			User, Messages
+//	@Produce		json
+//	@Param			id	path		string						true	"Message ID"
+//	@Success		200	{object}	[]messageLink				"Message links"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Message not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/messages/{id}/links [get]
+//
+// Get message links
+func (h *ThirdPartyController) getLinks(user models.User, c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if _, err := h.messagesSvc.GetState(user, id); err != nil {
+		if errors.Is(err, messages.ErrMessageNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+
+		return err
+	}
+
+	items, err := h.linksSvc.SelectByMessage(user.ID, id)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(slices.Map(items, func(l links.ShortLink) messageLink {
+		return messageLinkToDTO(l, h.linksSvc.ShortURL(l))
+	}))
 }
 
 //	@Summary		Request inbox messages export
@@ -271,12 +428,138 @@ func (h *ThirdPartyController) postInboxExport(user models.User, c *fiber.Ctx) e
 	return c.SendStatus(fiber.StatusAccepted)
 }
 
+//	@Summary		Request messages export
+//	@Description	Asks the device to upload incoming messages received in the period. The export status and uploaded messages are available for 24 hours after the last upload. When the device completes the export, the `export:completed` webhook is triggered with the signed download link of the artifact, if artifacts are enabled.
+//	@Security		ApiAuth
+//	@Tags			User, Messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		smsgateway.MessagesExportRequest	true	"Export request"
+//	@Success		202		{object}	exportResponse						"Export requested"
+//	@Failure		400		{object}	smsgateway.ErrorResponse			"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse			"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse			"Internal server error"
+//	@Header			202		{string}	Location							"Export URL"
+//	@Router			/3rdparty/v1/messages/export [post]
+//
+// Request messages export
+func (h *ThirdPartyController) postExport(user models.User, c *fiber.Ctx) error {
+	req := smsgateway.MessagesExportRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	device, err := h.devicesSvc.Get(user.ID, devices.WithID(req.DeviceID))
+	if err != nil {
+		if errors.Is(err, devices.ErrNotFound) {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid device ID")
+		}
+
+		return err
+	}
+
+	export, err := h.exportsSvc.Request(c.Context(), device, req.Since, req.Until)
+	if err != nil {
+		return fmt.Errorf("can't request export: %w", err)
+	}
+
+	location, err := c.GetRouteURL(route3rdPartyGetExport, fiber.Map{
+		"id": export.ID,
+	})
+	if err != nil {
+		h.Logger.Warn("Failed to get route URL", zap.String("route", route3rdPartyGetExport), zap.Error(err))
+	} else {
+		c.Location(location)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(exportToDTO(export, false))
+}
+
+//	@Summary		Get messages export
+//	@Description	Returns the export status and uploaded messages
+//	@Security		ApiAuth
+//	@Tags			User, Messages
+//	@Produce		json
+//	@Param			id	path		string						true	"Export ID"
+//	@Success		200	{object}	exportResponse				"Export"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Export not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/messages/export/{id} [get]
+//
+// Get messages export
+func (h *ThirdPartyController) getExport(user models.User, c *fiber.Ctx) error {
+	export, err := h.exportsSvc.Get(c.Context(), user.ID, c.Params("id"))
+	if err != nil {
+		if errors.Is(err, exports.ErrNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, err.Error())
+		}
+
+		return fmt.Errorf("can't get export: %w", err)
+	}
+
+	return c.JSON(exportToDTO(export, true))
+}
+
+//	@Summary		List export artifacts
+//	@Description	Returns the downloadable artifacts of the completed exports with the signed download links, the most recent first
+//	@Security		ApiAuth
+//	@Tags			User, Messages
+//	@Produce		json
+//	@Success		200	{object}	[]exportArtifact			"Export artifacts"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/messages/artifacts [get]
+//
+// List export artifacts
+func (h *ThirdPartyController) listArtifacts(user models.User, c *fiber.Ctx) error {
+	artifacts, err := h.exportsSvc.Artifacts(c.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("can't list artifacts: %w", err)
+	}
+
+	return c.JSON(slices.Map(artifacts, func(a exports.Artifact) exportArtifact {
+		return artifactToDTO(a, h.exportsSvc.DownloadURL(a))
+	}))
+}
+
+//	@Summary		Delete export artifact
+//	@Description	Removes the downloadable artifact of the export before it expires
+//	@Security		ApiAuth
+//	@Tags			User, Messages
+//	@Param			id	path	string	true	"Export ID"
+//	@Success		204	"Artifact deleted"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Artifact not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/messages/artifacts/{id} [delete]
+//
+// Delete export artifact
+func (h *ThirdPartyController) deleteArtifact(user models.User, c *fiber.Ctx) error {
+	err := h.exportsSvc.DeleteArtifact(c.Context(), user.ID, c.Params("id"))
+	if errors.Is(err, exports.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "artifact not found")
+	}
+	if err != nil {
+		return fmt.Errorf("can't delete artifact: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 func (h *ThirdPartyController) Register(router fiber.Router) {
 	router.Get("", userauth.WithUser(h.list))
 	router.Post("", userauth.WithUser(h.post))
+	router.Post("preview", userauth.WithUser(h.postPreview))
+	// registered before :id to take precedence
+	router.Get("artifacts", userauth.WithUser(h.listArtifacts))
+	router.Delete("artifacts/:id", userauth.WithUser(h.deleteArtifact))
 	router.Get(":id", userauth.WithUser(h.get)).Name(route3rdPartyGetMessage)
+	router.Get(":id/links", userauth.WithUser(h.getLinks))
 
 	router.Post("inbox/export", userauth.WithUser(h.postInboxExport))
+	router.Post("export", userauth.WithUser(h.postExport))
+	router.Get("export/:id", userauth.WithUser(h.getExport)).Name(route3rdPartyGetExport)
 }
 
 func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
@@ -287,5 +570,10 @@ func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyContr
 		},
 		messagesSvc: params.MessagesSvc,
 		devicesSvc:  params.DevicesSvc,
+		exportsSvc:  params.ExportsSvc,
+		linksSvc:    params.LinksSvc,
+		relaySvc:    params.RelaySvc,
+		keysSvc:     params.KeysSvc,
+		authSvc:     params.AuthSvc,
 	}
 }
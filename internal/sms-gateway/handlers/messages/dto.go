@@ -0,0 +1,398 @@
+package messages
+
+import (
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/converters"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	headerContentWarnings = "X-Content-Warnings"
+	headerNextPollIn      = "X-Next-Poll-In"
+)
+
+type contentWarning struct {
+	// Rule code
+	Rule string `json:"rule" example:"url_shortener"`
+	// Human-readable description
+	Message string `json:"message" example:"URL shortener bit.ly is often filtered by carriers"`
+}
+
+type previewResponse struct {
+	// Normalized phone numbers
+	PhoneNumbers []string `json:"phoneNumbers" example:"+79990001234"`
+	// Content policy warnings
+	Warnings []contentWarning `json:"warnings"`
+	// Message would be rejected by the content policy
+	Blocked bool `json:"blocked"`
+}
+
+type recipientState struct {
+	smsgateway.RecipientState
+
+	// Normalized error code
+	ErrorCode *messages.DeliveryErrorCode `json:"errorCode,omitempty" swaggertype:"string" example:"no_service"`
+	// Detected country, ISO 3166-1 alpha-2
+	Country string `json:"country,omitempty" example:"DE"`
+	// Carrier the number range was allocated to, ported numbers are not detected
+	Carrier string `json:"carrier,omitempty" example:"Vodafone"`
+}
+
+type messageEncryption struct {
+	// Client-side encryption algorithm
+	Algorithm string `json:"algorithm" validate:"required,max=32" example:"aes-256-gcm"`
+	// ID of the encryption key registered by the device
+	KeyID string `json:"keyId" validate:"required,max=64" example:"main-2025"`
+}
+
+type postMessageRequest struct {
+	smsgateway.Message
+
+	// Encryption metadata, allowed only for encrypted messages
+	Encryption *messageEncryption `json:"encryption,omitempty"`
+}
+
+type mobileMessage struct {
+	smsgateway.MobileMessage
+
+	// Encryption metadata of encrypted messages
+	Encryption *messageEncryption `json:"encryption,omitempty"`
+}
+
+type postMessageResponse struct {
+	smsgateway.GetMessageResponse
+
+	// ID of the original message if the message was recognized as a duplicate
+	DuplicateOf string `json:"duplicateOf,omitempty" example:"PyDmBQZZXYmyxMwED8Fzy"`
+}
+
+type messageState struct {
+	smsgateway.MessageState
+
+	// Recipients states
+	Recipients []recipientState `json:"recipients"`
+}
+
+type messageResponse struct {
+	smsgateway.MobileMessage
+
+	// Message state with the normalized error codes of the recipients
+	State *messageState `json:"state,omitempty"`
+}
+
+type mobileQueueRequest struct {
+	// Number of messages held locally by the device
+	Pending *uint `json:"pending" validate:"required" example:"0"`
+}
+
+type mobileQueueResponse struct {
+	// Number of messages held locally by the device
+	LocalPending int `json:"localPending" example:"0"`
+	// Number of pending messages on the server
+	ServerPending int64 `json:"serverPending" example:"3"`
+	// Number of stuck messages returned to the queue
+	Requeued int64 `json:"requeued" example:"1"`
+}
+
+type mobilePatchFailure struct {
+	// Index of the update in the request
+	Index int `json:"index" example:"0"`
+	// Message ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Error description
+	Error string `json:"error" example:"record not found"`
+	// The update may succeed on retry
+	Retryable bool `json:"retryable" example:"false"`
+}
+
+type mobilePatchResponse struct {
+	// Failed updates, other updates are applied
+	Failed []mobilePatchFailure `json:"failed"`
+}
+
+type mobileSyncRequest struct {
+	// Token of the previous sync, empty on the first sync
+	Token string `json:"token" validate:"omitempty,max=64" example:"42"`
+	// Message state changes since the previous sync
+	Changes []smsgateway.MobilePatchMessageItem `json:"changes" validate:"max=1000,dive"`
+}
+
+type mobileSyncResponse struct {
+	// Token to submit on the next sync
+	Token string `json:"token" example:"42"`
+	// The submitted token is unknown, the states of recent messages should be resubmitted
+	Resync bool `json:"resync" example:"false"`
+	// Failed changes, retryable ones should be resubmitted on the next sync
+	Failed []mobilePatchFailure `json:"failed"`
+}
+
+type exportMessage struct {
+	// Message ID on the device
+	ID string `json:"id" validate:"required,max=64" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Message type: sms, data or mms
+	Type string `json:"type" validate:"required,oneof=sms data mms" example:"sms"`
+	// Sender phone number
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=128" example:"+79990001234"`
+	// Message text or base64-encoded data
+	Text string `json:"text" example:"Hello World!"`
+	// SIM card number
+	SimNumber *uint8 `json:"simNumber,omitempty" example:"1"`
+	// Time when the message was received
+	ReceivedAt time.Time `json:"receivedAt" validate:"required" example:"2025-10-16T12:00:00Z"`
+}
+
+type exportResponse struct {
+	// Export ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Device ID
+	DeviceID string `json:"deviceId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Start of the exported period
+	Since time.Time `json:"since" example:"2025-10-01T00:00:00Z"`
+	// End of the exported period
+	Until time.Time `json:"until" example:"2025-10-16T00:00:00Z"`
+	// Export status: requested, uploading or completed
+	Status exports.Status `json:"status" swaggertype:"string" example:"completed"`
+	// Number of uploaded messages
+	Count int `json:"count" example:"1"`
+	// Uploaded messages
+	Messages []exportMessage `json:"messages,omitempty"`
+	// Time when the export was requested
+	CreatedAt time.Time `json:"createdAt" example:"2025-10-16T12:00:00Z"`
+	// Time of the last upload
+	UpdatedAt time.Time `json:"updatedAt" example:"2025-10-16T12:00:00Z"`
+}
+
+type exportArtifact struct {
+	// Export ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Device ID
+	DeviceID string `json:"deviceId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Number of exported messages
+	Count int `json:"count" example:"1"`
+	// Size of the artifact in bytes
+	Size int `json:"size" example:"1024"`
+	// Signed download link, valid until the artifact expires
+	URL string `json:"url" example:"https://sms.example.com/exports/PyDmBQZZXYmyxMwED8Fzy?expires=1760702400&signature=3f1c"`
+	// Time when the export was completed
+	CreatedAt time.Time `json:"createdAt" example:"2025-10-16T12:00:00Z"`
+	// Time when the artifact is removed
+	ExpiresAt time.Time `json:"expiresAt" example:"2025-10-17T12:00:00Z"`
+}
+
+type messageLink struct {
+	// Original URL
+	URL string `json:"url" example:"https://example.com/promo"`
+	// Short URL sent in the message
+	ShortURL string `json:"shortUrl" example:"https://sms.example.com/r/Ab3dE6gH9k"`
+	// Number of clicks
+	Clicks uint64 `json:"clicks" example:"3"`
+	// Time of the first click
+	FirstClickAt *time.Time `json:"firstClickAt,omitempty" example:"2025-10-16T12:00:00Z"`
+	// Time of the last click
+	LastClickAt *time.Time `json:"lastClickAt,omitempty" example:"2025-10-16T12:00:00Z"`
+}
+
+type mobileExportUploadRequest struct {
+	// Batch of exported messages
+	Messages []exportMessage `json:"messages" validate:"max=1000,dive"`
+	// Last batch of the export
+	Final bool `json:"final" example:"true"`
+}
+
+func exportToDTO(export exports.Export, withMessages bool) exportResponse {
+	res := exportResponse{
+		ID:        export.ID,
+		DeviceID:  export.DeviceID,
+		Since:     export.Since,
+		Until:     export.Until,
+		Status:    export.Status,
+		Count:     len(export.Messages),
+		CreatedAt: export.CreatedAt,
+		UpdatedAt: export.UpdatedAt,
+	}
+
+	if withMessages {
+		res.Messages = slices.Map(export.Messages, func(m exports.Message) exportMessage {
+			return exportMessage(m)
+		})
+	}
+
+	return res
+}
+
+func artifactToDTO(artifact exports.Artifact, url string) exportArtifact {
+	return exportArtifact{
+		ID:        artifact.ExportID,
+		DeviceID:  artifact.DeviceID,
+		Count:     artifact.Count,
+		Size:      artifact.Size,
+		URL:       url,
+		CreatedAt: artifact.CreatedAt,
+		ExpiresAt: artifact.ExpiresAt,
+	}
+}
+
+func exportMessagesFromDTO(messages []exportMessage) []exports.Message {
+	return slices.Map(messages, func(m exportMessage) exports.Message {
+		return exports.Message(m)
+	})
+}
+
+func messageLinkToDTO(link links.ShortLink, shortURL string) messageLink {
+	return messageLink{
+		URL:          link.URL,
+		ShortURL:     shortURL,
+		Clicks:       link.Clicks,
+		FirstClickAt: link.FirstClickAt,
+		LastClickAt:  link.LastClickAt,
+	}
+}
+
+func queueReconciliationToDTO(r messages.QueueReconciliation) mobileQueueResponse {
+	return mobileQueueResponse{
+		LocalPending:  r.LocalPending,
+		ServerPending: r.ServerPending,
+		Requeued:      r.Requeued,
+	}
+}
+
+func messageStateToDTO(state messages.MessageStateOut) messageState {
+	return messageState{
+		MessageState: converters.MessageStateToDTO(state),
+		Recipients: slices.Map(state.Recipients, func(r smsgateway.RecipientState) recipientState {
+			dto := recipientState{RecipientState: r}
+			if code, ok := state.ErrorCodes[r.PhoneNumber]; ok {
+				dto.ErrorCode = &code
+			}
+			if info, ok := state.Destinations[r.PhoneNumber]; ok {
+				dto.Country = info.Country
+				dto.Carrier = info.Carrier
+			}
+			return dto
+		}),
+	}
+}
+
+func messageStateFromDTO(v smsgateway.MobilePatchMessageItem) messages.MessageStateIn {
+	return messages.MessageStateIn{
+		ID:         v.ID,
+		State:      messages.ProcessingState(v.State),
+		Recipients: v.Recipients,
+		States:     v.States,
+	}
+}
+
+func stateSyncToDTO(s messages.StateSync) mobileSyncResponse {
+	return mobileSyncResponse{
+		Token:  s.Token,
+		Resync: s.Resync,
+		Failed: stateUpdateResultsToDTO(s.Failed).Failed,
+	}
+}
+
+func stateUpdateResultsToDTO(results []messages.StateUpdateResult) mobilePatchResponse {
+	return mobilePatchResponse{
+		Failed: slices.Map(results, func(r messages.StateUpdateResult) mobilePatchFailure {
+			return mobilePatchFailure{
+				Index:     r.Index,
+				ID:        r.ID,
+				Error:     r.Err.Error(),
+				Retryable: r.Retryable(),
+			}
+		}),
+	}
+}
+
+func previewToDTO(preview messages.MessagePreview) previewResponse {
+	return previewResponse{
+		PhoneNumbers: preview.PhoneNumbers,
+		Warnings: slices.Map(preview.Warnings, func(w messages.ContentWarning) contentWarning {
+			return contentWarning{Rule: w.Rule, Message: w.Message}
+		}),
+		Blocked: preview.Blocked,
+	}
+}
+
+func contentWarningRule(w messages.ContentWarning) string {
+	return w.Rule
+}
+
+func encryptionToDTO(info *messages.EncryptionInfo) *messageEncryption {
+	if info == nil {
+		return nil
+	}
+
+	return &messageEncryption{
+		Algorithm: info.Algorithm,
+		KeyID:     info.KeyID,
+	}
+}
+
+func encryptionFromDTO(dto *messageEncryption) *messages.EncryptionInfo {
+	if dto == nil {
+		return nil
+	}
+
+	return &messages.EncryptionInfo{
+		Algorithm: dto.Algorithm,
+		KeyID:     dto.KeyID,
+	}
+}
+
+func messageToMobileDTO(m messages.MessageOut) mobileMessage {
+	return mobileMessage{
+		MobileMessage: converters.MessageToMobileDTO(m),
+		Encryption:    encryptionToDTO(m.Encryption),
+	}
+}
+
+func messageToDTO(m messages.MessageOut) messageResponse {
+	res := messageResponse{
+		MobileMessage: converters.MessageToMobileDTO(m),
+	}
+	if m.State != nil {
+		state := messageStateToDTO(*m.State)
+		res.State = &state
+	}
+
+	return res
+}
+
+func requestToMessageIn(req smsgateway.Message) (messages.MessageIn, error) {
+	var textContent *messages.TextMessageContent
+	var dataContent *messages.DataMessageContent
+	if text := req.GetTextMessage(); text != nil {
+		textContent = &messages.TextMessageContent{
+			Text: text.Text,
+		}
+	} else if data := req.GetDataMessage(); data != nil {
+		dataContent = &messages.DataMessageContent{
+			Data: data.Data,
+			Port: data.Port,
+		}
+	} else {
+		return messages.MessageIn{}, fiber.NewError(fiber.StatusBadRequest, "No message content provided")
+	}
+
+	return messages.MessageIn{
+		ID: req.ID,
+
+		TextContent: textContent,
+		DataContent: dataContent,
+
+		PhoneNumbers: req.PhoneNumbers,
+		IsEncrypted:  req.IsEncrypted,
+
+		SimNumber:          req.SimNumber,
+		WithDeliveryReport: req.WithDeliveryReport,
+		TTL:                req.TTL,
+		ValidUntil:         req.ValidUntil,
+		Priority:           req.Priority,
+	}, nil
+}
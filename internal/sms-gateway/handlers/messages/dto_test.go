@@ -0,0 +1,44 @@
+package messages
+
+import (
+	"testing"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+)
+
+func TestMessageToDTO(t *testing.T) {
+	reason := "RESULT_ERROR_NO_SERVICE"
+	msg := messages.MessageOut{
+		MessageIn: messages.MessageIn{ID: "msg-1", PhoneNumbers: []string{"+79990000001", "+79990000002"}},
+		State: &messages.MessageStateOut{
+			MessageStateIn: messages.MessageStateIn{
+				ID:    "msg-1",
+				State: messages.ProcessingStateFailed,
+				Recipients: []smsgateway.RecipientState{
+					{PhoneNumber: "+79990000001", State: smsgateway.ProcessingStateSent},
+					{PhoneNumber: "+79990000002", State: smsgateway.ProcessingStateFailed, Error: &reason},
+				},
+			},
+			ErrorCodes: map[string]messages.DeliveryErrorCode{"+79990000002": messages.DeliveryErrorNoService},
+		},
+	}
+
+	res := messageToDTO(msg)
+	if res.State == nil {
+		t.Fatal("state is missing")
+	}
+	if len(res.State.Recipients) != 2 {
+		t.Fatalf("recipients = %d, want 2", len(res.State.Recipients))
+	}
+	if res.State.Recipients[0].ErrorCode != nil {
+		t.Errorf("recipients[0].ErrorCode = %s, want nil", *res.State.Recipients[0].ErrorCode)
+	}
+	if code := res.State.Recipients[1].ErrorCode; code == nil || *code != messages.DeliveryErrorNoService {
+		t.Errorf("recipients[1].ErrorCode = %v, want %s", code, messages.DeliveryErrorNoService)
+	}
+
+	if res := messageToDTO(messages.MessageOut{}); res.State != nil {
+		t.Errorf("state = %+v, want nil", res.State)
+	}
+}
@@ -1,14 +1,18 @@
 package messages
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
-	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/converters"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/deviceauth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
 	"github.com/capcom6/go-helpers/slices"
 	"github.com/go-playground/validator/v10"
@@ -21,25 +25,47 @@ type mobileControllerParams struct {
 	fx.In
 
 	MessagesSvc *messages.Service
+	ExportsSvc  *exports.Service
+	EventsSvc   *events.Service
 
 	Validator *validator.Validate
 	Logger    *zap.Logger
 }
 
+// mobileMessagesService is the part of the messages service used by the
+// controller.
+type mobileMessagesService interface {
+	SelectPending(deviceID string, order messages.MessagesOrder) ([]messages.MessageOut, int64, error)
+	NextPollIn(device models.Device, selected int, total int64) time.Duration
+	UpdateStates(deviceID string, updates []messages.MessageStateIn) []messages.StateUpdateResult
+	SyncStates(ctx context.Context, deviceID, token string, changes []messages.MessageStateIn) (messages.StateSync, error)
+	ReconcileQueue(ctx context.Context, device models.Device, localPending int) (messages.QueueReconciliation, error)
+}
+
+// eventsListener notifies the long-polling requests of the enqueued messages.
+type eventsListener interface {
+	Listen(deviceID string, eventType smsgateway.PushEventType) (<-chan struct{}, func())
+}
+
 type MobileController struct {
 	base.Handler
 
-	messagesSvc *messages.Service
+	messagesSvc mobileMessagesService
+	exportsSvc  *exports.Service
+	eventsSvc   eventsListener
 }
 
 //	@Summary		Get messages for sending
-//	@Description	Returns list of pending messages
+//	@Description	Returns list of pending messages. If `wait` is set and there are no pending messages, the request is held until a message is enqueued or the timeout expires. The request is held for up to 20s, longer timeouts are shortened.
 //	@Security		MobileToken
 //	@Tags			Device, Messages
 //	@Accept			json
 //	@Produce		json
 //	@Param			order	query		string									false	"Message processing order: lifo (default) or fifo"	Enums(lifo,fifo) default(lifo)
-//	@Success		200		{object}	smsgateway.MobileGetMessagesResponse	"List of pending messages"
+//	@Param			wait	query		string									false	"Long-polling timeout, e.g. 30s, up to 60s"
+//	@Success		200		{object}	[]mobileMessage							"List of pending messages"
+//	@Header			200		{integer}	Retry-After								"Recommended delay in seconds before the next poll"
+//	@Header			200		{integer}	X-Next-Poll-In							"Recommended delay in seconds before the next poll"
 //	@Failure		400		{object}	smsgateway.ErrorResponse				"Invalid request"
 //	@Failure		500		{object}	smsgateway.ErrorResponse				"Internal server error"
 //	@Router			/mobile/v1/message [get]
@@ -52,29 +78,53 @@ func (h *MobileController) list(device models.Device, c *fiber.Ctx) error {
 		return err
 	}
 
-	msgs, err := h.messagesSvc.SelectPending(device.ID, params.OrderOrDefault())
+	wait, _ := params.WaitDuration()
+	wait = min(wait, mobileMaxHold)
+
+	var enqueued <-chan struct{}
+	if wait > 0 {
+		// subscribe before selecting to not miss messages enqueued in between
+		var cancel func()
+		enqueued, cancel = h.eventsSvc.Listen(device.ID, smsgateway.PushMessageEnqueued)
+		defer cancel()
+	}
+
+	msgs, total, err := h.messagesSvc.SelectPending(device.ID, params.OrderOrDefault())
 	if err != nil {
 		return fmt.Errorf("can't get messages: %w", err)
 	}
 
-	return c.JSON(
-		smsgateway.MobileGetMessagesResponse(
-			slices.Map(
-				msgs,
-				converters.MessageToMobileDTO,
-			),
-		),
-	)
+	if len(msgs) == 0 && enqueued != nil {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-enqueued:
+			msgs, total, err = h.messagesSvc.SelectPending(device.ID, params.OrderOrDefault())
+			if err != nil {
+				return fmt.Errorf("can't get messages: %w", err)
+			}
+		case <-timer.C:
+		case <-c.Context().Done():
+		}
+	}
+
+	nextPollIn := strconv.Itoa(int(h.messagesSvc.NextPollIn(device, len(msgs), total).Seconds()))
+	c.Set(fiber.HeaderRetryAfter, nextPollIn)
+	c.Set(headerNextPollIn, nextPollIn)
+
+	return c.JSON(slices.Map(msgs, messageToMobileDTO))
 }
 
 //	@Summary		Update message state
-//	@Description	Updates message state
+//	@Description	Updates message states. Each update is applied independently and can be safely retried. If some updates fail, responds with 207 and the list of failed updates, so only them should be retried
 //	@Security		MobileToken
 //	@Tags			Device, Messages
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		smsgateway.MobilePatchMessageRequest	true	"List of message state updates"
 //	@Success		204		{object}	nil										"Successfully updated"
+//	@Success		207		{object}	mobilePatchResponse						"Partially updated"
 //	@Failure		400		{object}	smsgateway.ErrorResponse				"Invalid request"
 //	@Failure		500		{object}	smsgateway.ErrorResponse				"Internal server error"
 //	@Router			/mobile/v1/message [patch]
@@ -86,21 +136,104 @@ func (h *MobileController) patch(device models.Device, c *fiber.Ctx) error {
 		return err
 	}
 
-	for _, v := range req {
-		messageState := messages.MessageStateIn{
-			ID:         v.ID,
-			State:      messages.ProcessingState(v.State),
-			Recipients: v.Recipients,
-			States:     v.States,
-		}
+	failed := h.messagesSvc.UpdateStates(device.ID, slices.Map(req, messageStateFromDTO))
+	if len(failed) == 0 {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
 
-		err := h.messagesSvc.UpdateState(device.ID, messageState)
-		if err != nil && !errors.Is(err, messages.ErrMessageNotFound) {
-			h.Logger.Error("Can't update message status",
-				zap.String("message_id", v.ID),
-				zap.Error(err),
-			)
-		}
+	h.logRetryable(failed)
+
+	return c.Status(fiber.StatusMultiStatus).JSON(stateUpdateResultsToDTO(failed))
+}
+
+//	@Summary		Sync message states
+//	@Description	Applies the changelog of message states since the previous sync and returns the token for the next one. Only the last change of every message is applied, the changes already applied by the syncs after the submitted token are skipped. If `resync` is set, the submitted token is unknown and the states of recent messages should be resubmitted. Retryable failed changes should be resubmitted on the next sync.
+//	@Security		MobileToken
+//	@Tags			Device, Messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		mobileSyncRequest			true	"Changelog since the previous sync"
+//	@Success		200		{object}	mobileSyncResponse			"Sync result"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/message/sync [post]
+//
+// Sync message states
+func (h *MobileController) postSync(device models.Device, c *fiber.Ctx) error {
+	req := mobileSyncRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	res, err := h.messagesSvc.SyncStates(c.Context(), device.ID, req.Token, slices.Map(req.Changes, messageStateFromDTO))
+	if err != nil {
+		return fmt.Errorf("can't sync message states: %w", err)
+	}
+
+	h.logRetryable(res.Failed)
+
+	return c.JSON(stateSyncToDTO(res))
+}
+
+//	@Summary		Report local queue
+//	@Description	Reports the number of messages held locally by the device. If the device holds no messages, messages stuck in `Processed` state longer than the configured timeout are returned to the queue.
+//	@Security		MobileToken
+//	@Tags			Device, Messages
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		mobileQueueRequest			true	"Local queue state"
+//	@Success		200		{object}	mobileQueueResponse			"Reconciliation result"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/message/queue [put]
+//
+// Report local queue
+func (h *MobileController) putQueue(device models.Device, c *fiber.Ctx) error {
+	req := mobileQueueRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	res, err := h.messagesSvc.ReconcileQueue(c.Context(), device, int(*req.Pending))
+	if err != nil {
+		return fmt.Errorf("can't reconcile queue: %w", err)
+	}
+
+	return c.JSON(queueReconciliationToDTO(res))
+}
+
+//	@Summary		Upload exported messages
+//	@Description	Uploads a batch of messages requested by the `MessagesExportRequested` event with `exportId`. The last batch must be marked as final.
+//	@Security		MobileToken
+//	@Tags			Device, Messages
+//	@Accept			json
+//	@Param			id		path	string						true	"Export ID"
+//	@Param			request	body	mobileExportUploadRequest	true	"Exported messages"
+//	@Success		204		"Messages uploaded"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		404		{object}	smsgateway.ErrorResponse	"Export not found"
+//	@Failure		409		{object}	smsgateway.ErrorResponse	"Export already completed"
+//	@Failure		413		{object}	smsgateway.ErrorResponse	"Export messages limit exceeded"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/messages/export/{id} [post]
+//
+// Upload exported messages
+func (h *MobileController) postExport(device models.Device, c *fiber.Ctx) error {
+	req := mobileExportUploadRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	_, err := h.exportsSvc.Upload(c.Context(), device, c.Params("id"), exportMessagesFromDTO(req.Messages), req.Final)
+	switch {
+	case errors.Is(err, exports.ErrNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, exports.ErrCompleted):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	case errors.Is(err, exports.ErrLimitExceeded):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, err.Error())
+	case err != nil:
+		return fmt.Errorf("can't upload export: %w", err)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -109,6 +242,20 @@ func (h *MobileController) patch(device models.Device, c *fiber.Ctx) error {
 func (h *MobileController) Register(router fiber.Router) {
 	router.Get("", deviceauth.WithDevice(h.list))
 	router.Patch("", deviceauth.WithDevice(h.patch))
+	router.Put("queue", deviceauth.WithDevice(h.putQueue))
+	router.Post("sync", deviceauth.WithDevice(h.postSync))
+	router.Post("export/:id", deviceauth.WithDevice(h.postExport))
+}
+
+func (h *MobileController) logRetryable(failed []messages.StateUpdateResult) {
+	for _, r := range failed {
+		if r.Retryable() {
+			h.Logger.Error("Can't update message status",
+				zap.String("message_id", r.ID),
+				zap.Error(r.Err),
+			)
+		}
+	}
 }
 
 func NewMobileController(params mobileControllerParams) *MobileController {
@@ -118,5 +265,7 @@ func NewMobileController(params mobileControllerParams) *MobileController {
 			Validator: params.Validator,
 		},
 		messagesSvc: params.MessagesSvc,
+		exportsSvc:  params.ExportsSvc,
+		eventsSvc:   params.EventsSvc,
 	}
 }
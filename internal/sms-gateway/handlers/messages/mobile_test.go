@@ -0,0 +1,107 @@
+package messages
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// selection is the result of a single selection of the pending messages.
+type selection struct {
+	selected int
+	total    int64
+}
+
+// fakeMessages returns the selection and records the arguments of the poll
+// delay.
+type fakeMessages struct {
+	mobileMessagesService
+
+	selection selection
+	next      time.Duration
+
+	selected int
+	total    int64
+}
+
+func (f *fakeMessages) SelectPending(string, messages.MessagesOrder) ([]messages.MessageOut, int64, error) {
+	return make([]messages.MessageOut, f.selection.selected), f.selection.total, nil
+}
+
+func (f *fakeMessages) NextPollIn(_ models.Device, selected int, total int64) time.Duration {
+	f.selected, f.total = selected, total
+	return f.next
+}
+
+func TestMobileController_List_NextPollIn(t *testing.T) {
+	tests := []struct {
+		name         string
+		selection    selection
+		next         time.Duration
+		wantSelected int
+		wantTotal    int64
+	}{
+		{
+			name:         "Pending backlog",
+			selection:    selection{selected: 2, total: 5},
+			next:         0,
+			wantSelected: 2,
+			wantTotal:    5,
+		},
+		{
+			name:         "Recently active",
+			selection:    selection{selected: 2, total: 2},
+			next:         5 * time.Second,
+			wantSelected: 2,
+			wantTotal:    2,
+		},
+		{
+			name:         "Idle",
+			selection:    selection{selected: 0, total: 0},
+			next:         15 * time.Minute,
+			wantSelected: 0,
+			wantTotal:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeMessages{selection: tt.selection, next: tt.next}
+			h := &MobileController{
+				Handler:     base.Handler{Logger: zap.NewNop()},
+				messagesSvc: svc,
+			}
+
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				return h.list(models.Device{ID: "device-1"}, c)
+			})
+
+			res, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d, want %d", res.StatusCode, fiber.StatusOK)
+			}
+
+			if svc.selected != tt.wantSelected || svc.total != tt.wantTotal {
+				t.Errorf("NextPollIn() called with %d of %d, want %d of %d", svc.selected, svc.total, tt.wantSelected, tt.wantTotal)
+			}
+
+			want := strconv.Itoa(int(tt.next.Seconds()))
+			for _, header := range []string{fiber.HeaderRetryAfter, headerNextPollIn} {
+				if got := res.Header.Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package messages
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
@@ -10,38 +12,52 @@ import (
 type thirdPartyPostQueryParams struct {
 	SkipPhoneValidation bool `query:"skipPhoneValidation"`
 	DeviceActiveWithin  uint `query:"deviceActiveWithin"`
+	TrackLinks          bool `query:"trackLinks"`
 }
 
 type thirdPartyGetQueryParams struct {
-	StartDate string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
-	EndDate   string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
-	State     string `query:"state" validate:"omitempty,oneof=Pending Processed Sent Delivered Failed"`
-	DeviceID  string `query:"deviceId" validate:"omitempty,len=21"`
-	Limit     int    `query:"limit" validate:"omitempty,min=1,max=100"`
-	Offset    int    `query:"offset" validate:"omitempty,min=0"`
+	StartDate   string `query:"from"`
+	EndDate     string `query:"to"`
+	Timezone    string `query:"tz" validate:"omitempty,timezone"`
+	State       string `query:"state" validate:"omitempty,oneof=Pending Processed Sent Delivered Failed"`
+	DeviceID    string `query:"deviceId" validate:"omitempty,nanoid"`
+	PhoneNumber string `query:"phoneNumber" validate:"omitempty,max=128"`
+	Limit       int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset      int    `query:"offset" validate:"omitempty,min=0"`
 }
 
 func (p *thirdPartyGetQueryParams) Validate() error {
-	if p.StartDate != "" && p.EndDate != "" && p.StartDate > p.EndDate {
-		return fmt.Errorf("`from` date must be before `to` date")
+	if _, err := parseDate(p.StartDate, time.UTC, false); err != nil {
+		return fmt.Errorf("invalid `from` value: %w", err)
+	}
+	if _, err := parseDate(p.EndDate, time.UTC, true); err != nil {
+		return fmt.Errorf("invalid `to` value: %w", err)
 	}
 
 	return nil
 }
 
-func (p *thirdPartyGetQueryParams) ToFilter() messages.MessagesSelectFilter {
+// HasDates reports whether the date-only values are used, so the location
+// they are interpreted in is required.
+func (p *thirdPartyGetQueryParams) HasDates() bool {
+	return isDate(p.StartDate) || isDate(p.EndDate)
+}
+
+// ToFilter builds the filter, the date-only values are interpreted in the
+// location. The `to` date is inclusive, i.e. the whole day is selected.
+func (p *thirdPartyGetQueryParams) ToFilter(loc *time.Location) (messages.MessagesSelectFilter, error) {
 	filter := messages.MessagesSelectFilter{}
 
-	if p.StartDate != "" {
-		if t, err := time.Parse(time.RFC3339, p.StartDate); err == nil {
-			filter.StartDate = t
-		}
+	var err error
+	if filter.StartDate, err = parseDate(p.StartDate, loc, false); err != nil {
+		return filter, fmt.Errorf("invalid `from` value: %w", err)
+	}
+	if filter.EndDate, err = parseDate(p.EndDate, loc, true); err != nil {
+		return filter, fmt.Errorf("invalid `to` value: %w", err)
 	}
 
-	if p.EndDate != "" {
-		if t, err := time.Parse(time.RFC3339, p.EndDate); err == nil {
-			filter.EndDate = t
-		}
+	if !filter.StartDate.IsZero() && !filter.EndDate.IsZero() && filter.StartDate.After(filter.EndDate) {
+		return filter, fmt.Errorf("`from` date must be before `to` date")
 	}
 
 	if p.State != "" {
@@ -52,7 +68,59 @@ func (p *thirdPartyGetQueryParams) ToFilter() messages.MessagesSelectFilter {
 		filter.DeviceID = p.DeviceID
 	}
 
-	return filter
+	if phone := strings.TrimSpace(p.PhoneNumber); phone != "" {
+		// a plus sign that isn't percent-encoded is decoded as a space
+		if strings.HasPrefix(p.PhoneNumber, " ") {
+			phone = "+" + phone
+		}
+		filter.PhoneNumber = phone
+	}
+
+	return filter, nil
+}
+
+// parseDate parses an RFC3339 date-time or a date. The date is resolved to
+// the start of the day in the location, or to the start of the next day if
+// it's the end of the range, as the end is exclusive. The zero time is
+// returned for the empty value.
+func parseDate(value string, loc *time.Location, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 date-time or YYYY-MM-DD date, got %q", value)
+	}
+	if end {
+		date = date.AddDate(0, 0, 1)
+	}
+
+	return startOfDay(date, loc), nil
+}
+
+func isDate(value string) bool {
+	_, err := time.Parse(time.DateOnly, value)
+	return err == nil
+}
+
+// startOfDay returns the first instant of the date in the location. If the
+// clocks skip midnight on this day, the day starts when the new offset takes
+// effect.
+func startOfDay(date time.Time, loc *time.Location) time.Time {
+	year, month, day := date.Date()
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if t.Day() != day {
+		// the skipped midnight is normalized to the previous day
+		_, t = t.ZoneBounds()
+	}
+
+	return t
 }
 
 func (p *thirdPartyGetQueryParams) ToOptions() messages.MessagesSelectOptions {
@@ -74,8 +142,49 @@ func (p *thirdPartyGetQueryParams) ToOptions() messages.MessagesSelectOptions {
 	return options
 }
 
+// mobileMaxWait is the max long-polling timeout accepted from the device.
+const mobileMaxWait = 60 * time.Second
+
+// mobileMaxHold is the max duration the long-polling request is held. The
+// disconnect of the device isn't detected while the request is held, so the
+// abandoned poll holds the resources no longer than this.
+const mobileMaxHold = 20 * time.Second
+
 type mobileGetQueryParams struct {
 	Order messages.MessagesOrder `query:"order" validate:"omitempty,oneof=lifo fifo"`
+	Wait  string                 `query:"wait"`
+}
+
+func (p *mobileGetQueryParams) Validate() error {
+	wait, err := p.WaitDuration()
+	if err != nil {
+		return err
+	}
+
+	if wait < 0 || wait > mobileMaxWait {
+		return fmt.Errorf("`wait` must be between 0s and %s", mobileMaxWait)
+	}
+
+	return nil
+}
+
+// WaitDuration returns the long-polling timeout. The value is either a Go
+// duration like "30s" or a number of seconds.
+func (p *mobileGetQueryParams) WaitDuration() (time.Duration, error) {
+	if p.Wait == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(p.Wait); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	wait, err := time.ParseDuration(p.Wait)
+	if err != nil {
+		return 0, fmt.Errorf("invalid `wait` value: %s", p.Wait)
+	}
+
+	return wait, nil
 }
 
 func (p *mobileGetQueryParams) OrderOrDefault() messages.MessagesOrder {
@@ -0,0 +1,159 @@
+package messages
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s is not available: %v", name, err)
+	}
+
+	return loc
+}
+
+func TestThirdPartyGetQueryParams_ToFilter(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	santiago := mustLoadLocation(t, "America/Santiago")
+
+	tests := []struct {
+		name      string
+		params    thirdPartyGetQueryParams
+		loc       *time.Location
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name:      "date-time",
+			params:    thirdPartyGetQueryParams{StartDate: "2025-03-09T10:00:00+02:00", EndDate: "2025-03-10T00:00:00Z"},
+			loc:       newYork,
+			wantStart: "2025-03-09T08:00:00Z",
+			wantEnd:   "2025-03-10T00:00:00Z",
+		},
+		{
+			name:      "date in UTC",
+			params:    thirdPartyGetQueryParams{StartDate: "2025-03-09", EndDate: "2025-03-09"},
+			loc:       time.UTC,
+			wantStart: "2025-03-09T00:00:00Z",
+			wantEnd:   "2025-03-10T00:00:00Z",
+		},
+		{
+			// the clocks are moved forward, the day lasts 23 hours
+			name:      "spring forward",
+			params:    thirdPartyGetQueryParams{StartDate: "2025-03-09", EndDate: "2025-03-09"},
+			loc:       newYork,
+			wantStart: "2025-03-09T05:00:00Z",
+			wantEnd:   "2025-03-10T04:00:00Z",
+		},
+		{
+			// the clocks are moved back, the day lasts 25 hours
+			name:      "fall back",
+			params:    thirdPartyGetQueryParams{StartDate: "2025-11-02", EndDate: "2025-11-02"},
+			loc:       newYork,
+			wantStart: "2025-11-02T04:00:00Z",
+			wantEnd:   "2025-11-03T05:00:00Z",
+		},
+		{
+			// midnight is skipped, the day starts at 01:00 local time
+			name:      "skipped midnight",
+			params:    thirdPartyGetQueryParams{StartDate: "2025-09-07", EndDate: "2025-09-06"},
+			loc:       santiago,
+			wantStart: "2025-09-07T04:00:00Z",
+			wantEnd:   "2025-09-07T04:00:00Z",
+		},
+		{
+			name:      "mixed",
+			params:    thirdPartyGetQueryParams{StartDate: "2025-11-01", EndDate: "2025-11-02T12:00:00Z"},
+			loc:       newYork,
+			wantStart: "2025-11-01T04:00:00Z",
+			wantEnd:   "2025-11-02T12:00:00Z",
+		},
+		{
+			name:   "open range",
+			params: thirdPartyGetQueryParams{},
+			loc:    newYork,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.params.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			filter, err := tt.params.ToFilter(tt.loc)
+			if err != nil {
+				t.Fatalf("ToFilter() error = %v", err)
+			}
+
+			if got := formatUTC(filter.StartDate); got != tt.wantStart {
+				t.Errorf("StartDate = %s, want %s", got, tt.wantStart)
+			}
+			if got := formatUTC(filter.EndDate); got != tt.wantEnd {
+				t.Errorf("EndDate = %s, want %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestThirdPartyGetQueryParams_Invalid(t *testing.T) {
+	for _, params := range []thirdPartyGetQueryParams{
+		{StartDate: "2025-13-01"},
+		{EndDate: "09.03.2025"},
+		{StartDate: "2025-03-09T10:00:00"},
+	} {
+		if err := params.Validate(); err == nil {
+			t.Errorf("Validate(%+v) error = nil", params)
+		}
+	}
+
+	params := thirdPartyGetQueryParams{StartDate: "2025-03-10", EndDate: "2025-03-09T12:00:00Z"}
+	if _, err := params.ToFilter(time.UTC); err == nil {
+		t.Error("ToFilter() error = nil for reversed range")
+	}
+}
+
+func TestThirdPartyGetQueryParams_HasDates(t *testing.T) {
+	if (&thirdPartyGetQueryParams{StartDate: "2025-03-09T10:00:00Z"}).HasDates() {
+		t.Error("HasDates() = true for date-time")
+	}
+	if !(&thirdPartyGetQueryParams{EndDate: "2025-03-09"}).HasDates() {
+		t.Error("HasDates() = false for date")
+	}
+}
+
+func formatUTC(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func TestThirdPartyGetQueryParams_PhoneNumber(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "+15551234567", want: "+15551234567"},
+		{value: " 15551234567", want: "+15551234567"},
+		{value: "89123456789", want: "89123456789"},
+		{value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		params := thirdPartyGetQueryParams{PhoneNumber: tt.value}
+
+		filter, err := params.ToFilter(time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filter.PhoneNumber != tt.want {
+			t.Errorf("PhoneNumber of %q = %q, want %q", tt.value, filter.PhoneNumber, tt.want)
+		}
+	}
+}
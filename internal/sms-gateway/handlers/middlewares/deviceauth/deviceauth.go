@@ -17,6 +17,7 @@ const LocalsDevice = "device"
 // If the header is valid, the middleware will authorize the device and store the
 // device in the request's Locals under the key LocalsDevice. If the header is
 // invalid, the middleware will call c.Next() and continue with the request.
+// The suspended device is rejected with 403 Forbidden.
 func New(authSvc *auth.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get authorization header
@@ -33,6 +34,9 @@ func New(authSvc *auth.Service) fiber.Handler {
 		if errors.Is(err, devices.ErrNotFound) {
 			return c.Next()
 		}
+		if errors.Is(err, devices.ErrSuspended) {
+			return fiber.NewError(fiber.StatusForbidden, err.Error())
+		}
 		if err != nil {
 			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
 		}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var limitedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "sms",
+	Subsystem: "http",
+	Name:      "rate_limited_total",
+	Help:      "Total number of requests rejected by the rate limiter",
+}, []string{"group"})
+
+// Config of the rate limiter of an API group.
+type Config struct {
+	// Max is the number of requests per window for a single client, zero
+	// disables the limiter.
+	Max int
+	// Window is the period of the sliding window.
+	Window time.Duration
+}
+
+// New returns a middleware that limits the requests of every client of the
+// API group with a sliding window. The client is identified by the key
+// function. Rejected requests are answered with 429 and counted per group.
+//
+// If Max is not positive, the middleware does nothing.
+func New(group string, config Config, key func(c *fiber.Ctx) string) fiber.Handler {
+	if config.Max <= 0 {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return limiter.New(limiter.Config{
+		Max:          config.Max,
+		Expiration:   config.Window,
+		KeyGenerator: key,
+		LimitReached: func(c *fiber.Ctx) error {
+			limitedCounter.WithLabelValues(group).Inc()
+			return fiber.NewError(fiber.StatusTooManyRequests, "Too many requests")
+		},
+		LimiterMiddleware: limiter.SlidingWindow{},
+	})
+}
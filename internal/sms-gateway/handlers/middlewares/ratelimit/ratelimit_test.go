@@ -0,0 +1,63 @@
+package ratelimit_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNew(t *testing.T) {
+	app := fiber.New()
+	app.Use(ratelimit.New("test", ratelimit.Config{Max: 2, Window: time.Minute}, func(c *fiber.Ctx) string {
+		return c.Get("X-Client")
+	}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	request := func(client string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Client", client)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	for i := range 2 {
+		if code := request("a"); code != fiber.StatusNoContent {
+			t.Fatalf("request %d: expected 204, got %d", i, code)
+		}
+	}
+	if code := request("a"); code != fiber.StatusTooManyRequests {
+		t.Errorf("expected 429 after the limit, got %d", code)
+	}
+	if code := request("b"); code != fiber.StatusNoContent {
+		t.Errorf("expected other clients to be unaffected, got %d", code)
+	}
+}
+
+func TestNew_Disabled(t *testing.T) {
+	app := fiber.New()
+	app.Use(ratelimit.New("test", ratelimit.Config{}, func(c *fiber.Ctx) string {
+		return "client"
+	}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	for i := range 5 {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusNoContent {
+			t.Fatalf("request %d: expected 204, got %d", i, resp.StatusCode)
+		}
+	}
+}
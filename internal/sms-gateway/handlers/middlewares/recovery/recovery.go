@@ -0,0 +1,65 @@
+package recovery
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var panicsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "sms",
+	Subsystem: "http",
+	Name:      "panics_total",
+	Help:      "Total number of recovered panics in HTTP handlers",
+}, []string{"route"})
+
+type errorResponse struct {
+	// Error message
+	Message string `json:"message"`
+	// Request ID to look up the logs
+	RequestID string `json:"requestId"`
+}
+
+// New returns a middleware that converts panics of the next handlers into 500
+// responses with the request ID. The panic is logged with the stack trace and
+// counted per route. The request ID is taken from the "X-Request-ID" header
+// or generated, and returned in the same header.
+func New(logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			requestID := c.Get(fiber.HeaderXRequestID)
+			if requestID == "" {
+				requestID = uuid.NewString()
+			}
+
+			route := c.Route().Path
+			panicsCounter.WithLabelValues(route).Inc()
+
+			logger.Error("Panic in handler",
+				zap.String("request_id", requestID),
+				zap.String("method", c.Method()),
+				zap.String("route", route),
+				zap.String("panic", fmt.Sprint(r)),
+				zap.ByteString("stack", debug.Stack()),
+			)
+
+			c.Set(fiber.HeaderXRequestID, requestID)
+			err = c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+				Message:   "Internal server error",
+				RequestID: requestID,
+			})
+		}()
+
+		return c.Next()
+	}
+}
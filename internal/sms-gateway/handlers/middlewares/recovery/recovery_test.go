@@ -0,0 +1,109 @@
+package recovery
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestApp(logger *zap.Logger) *fiber.App {
+	app := fiber.New()
+	app.Use(New(logger))
+	app.Get("/panic/:id", func(c *fiber.Ctx) error {
+		panic("boom")
+	})
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	return app
+}
+
+func TestNew_Panic(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	app := newTestApp(zap.New(core))
+	before := testutil.ToFloat64(panicsCounter.WithLabelValues("/panic/:id"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/panic/1", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", resp.StatusCode)
+	}
+
+	body := errorResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("can't decode response: %v", err)
+	}
+	if body.RequestID == "" {
+		t.Error("expected request ID in the response")
+	}
+	if header := resp.Header.Get(fiber.HeaderXRequestID); header != body.RequestID {
+		t.Errorf("expected request ID header %q, got %q", body.RequestID, header)
+	}
+
+	if got := testutil.ToFloat64(panicsCounter.WithLabelValues("/panic/:id")) - before; got != 1 {
+		t.Errorf("expected 1 panic counted for the route, got %v", got)
+	}
+
+	entries := logs.FilterMessage("Panic in handler").AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["request_id"] != body.RequestID {
+		t.Errorf("expected logged request ID %q, got %v", body.RequestID, fields["request_id"])
+	}
+	if fields["panic"] != "boom" {
+		t.Errorf("expected logged panic %q, got %v", "boom", fields["panic"])
+	}
+	if stack, _ := fields["stack"].(string); stack == "" {
+		t.Error("expected logged stack trace")
+	}
+}
+
+func TestNew_RequestID(t *testing.T) {
+	app := newTestApp(zap.NewNop())
+
+	req := httptest.NewRequest("GET", "/panic/1", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "request-1")
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	body := errorResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("can't decode response: %v", err)
+	}
+	if body.RequestID != "request-1" {
+		t.Errorf("expected request ID %q, got %q", "request-1", body.RequestID)
+	}
+	if header := resp.Header.Get(fiber.HeaderXRequestID); header != "request-1" {
+		t.Errorf("expected request ID header %q, got %q", "request-1", header)
+	}
+}
+
+func TestNew_NoPanic(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	app := newTestApp(zap.New(core))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/ok", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Errorf("expected 204, got %d", resp.StatusCode)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("expected no log entries, got %d", logs.Len())
+	}
+}
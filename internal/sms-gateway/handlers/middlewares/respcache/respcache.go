@@ -0,0 +1,123 @@
+package respcache
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderCache reports whether the response was served from the cache.
+const HeaderCache = "X-Cache"
+
+// skippedHeaders are response headers that are not stored in the cache.
+var skippedHeaders = map[string]struct{}{
+	fiber.HeaderContentLength:   {},
+	fiber.HeaderContentEncoding: {},
+	fiber.HeaderDate:            {},
+	fiber.HeaderSetCookie:       {},
+	fiber.HeaderVary:            {},
+	HeaderCache:                 {},
+}
+
+type entry struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
+
+// mutatingMethods are the methods that invalidate the cached responses of the
+// group.
+var mutatingMethods = map[string]struct{}{
+	fiber.MethodPost:   {},
+	fiber.MethodPut:    {},
+	fiber.MethodPatch:  {},
+	fiber.MethodDelete: {},
+}
+
+// New returns a middleware that caches successful GET responses of the route
+// group for the given TTL. Responses are cached per user and URL including the
+// query string, so a cached response is never served to another user. Requests
+// with "Cache-Control: no-cache" bypass the cache and refresh it. A successful
+// POST, PUT, PATCH or DELETE request of the user to the group invalidates the
+// user's cached responses of the group, the changes made otherwise, e.g. by
+// the devices, are visible once the TTL runs out.
+//
+// If the TTL is not positive, the middleware does nothing.
+func New(storage cache.Cache, group string, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if ttl <= 0 {
+			return c.Next()
+		}
+
+		tag := userauth.GetUser(c).ID + ":" + group
+
+		if _, ok := mutatingMethods[c.Method()]; ok {
+			if err := c.Next(); err != nil {
+				return err
+			}
+
+			if c.Response().StatusCode() < fiber.StatusBadRequest {
+				// the stale responses are removed before the response is sent
+				_ = storage.InvalidateTag(c.Context(), tag)
+			}
+
+			return nil
+		}
+
+		if c.Method() != fiber.MethodGet {
+			return c.Next()
+		}
+
+		key := userauth.GetUser(c).ID + ":" + c.OriginalURL()
+
+		if !strings.Contains(c.Get(fiber.HeaderCacheControl), "no-cache") {
+			if raw, err := storage.GetBytes(c.Context(), key); err == nil {
+				var e entry
+				if err := json.Unmarshal(raw, &e); err == nil {
+					for k, v := range e.Headers {
+						c.Set(k, v)
+					}
+					c.Set(HeaderCache, "HIT")
+					return c.Status(e.Status).Send(e.Body)
+				}
+			}
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		c.Set(HeaderCache, "MISS")
+
+		res := c.Response()
+		if res.StatusCode() != fiber.StatusOK {
+			return nil
+		}
+
+		e := entry{
+			Status:  res.StatusCode(),
+			Headers: map[string]string{},
+			Body:    res.Body(),
+		}
+		res.Header.VisitAll(func(k, v []byte) {
+			if _, ok := skippedHeaders[string(k)]; ok {
+				return
+			}
+			e.Headers[string(k)] = string(v)
+		})
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil
+		}
+
+		// caching is best-effort, failures must not break the response
+		_ = storage.SetBytes(c.Context(), key, data, cache.WithTTL(ttl), cache.WithTags(tag))
+
+		return nil
+	}
+}
@@ -0,0 +1,219 @@
+package respcache_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/respcache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestApp returns the app with the cached handler counting its calls. The
+// user is taken from the X-User header the way the auth middleware sets it.
+// The handlers of the /other group share the cache with the root group.
+func newTestApp(ttl time.Duration, status int) (*fiber.App, *int) {
+	calls := 0
+	handler := func(c *fiber.Ctx) error {
+		calls++
+		c.Set("X-Call", strconv.Itoa(calls))
+		return c.Status(status).SendString(c.Get("X-User") + ":" + c.Query("q") + ":" + strconv.Itoa(calls))
+	}
+	storage := cache.NewMemory(0)
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", models.User{ID: c.Get("X-User")})
+		return c.Next()
+	})
+	app.Group("/other", respcache.New(storage, "other", ttl)).All("/", handler)
+	app.Group("/", respcache.New(storage, "root", ttl)).All("/", handler)
+
+	return app, &calls
+}
+
+type testResponse struct {
+	status int
+	cache  string
+	call   string
+	body   string
+}
+
+func request(t *testing.T, app *fiber.App, method, user, url string, headers ...string) testResponse {
+	t.Helper()
+
+	req := httptest.NewRequest(method, url, nil)
+	req.Header.Set("X-User", user)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("can't read body: %v", err)
+	}
+
+	return testResponse{
+		status: resp.StatusCode,
+		cache:  resp.Header.Get(respcache.HeaderCache),
+		call:   resp.Header.Get("X-Call"),
+		body:   string(body),
+	}
+}
+
+func TestNew_HitMiss(t *testing.T) {
+	app, calls := newTestApp(time.Minute, fiber.StatusOK)
+
+	first := request(t, app, "GET", "user", "/?q=1")
+	if first.cache != "MISS" || first.body != "user:1:1" {
+		t.Fatalf("first response = %+v, want MISS with body user:1:1", first)
+	}
+
+	second := request(t, app, "GET", "user", "/?q=1")
+	if second.cache != "HIT" || second.body != first.body || second.status != fiber.StatusOK {
+		t.Errorf("second response = %+v, want HIT with body %s", second, first.body)
+	}
+	if second.call != "1" {
+		t.Errorf("cached header X-Call = %q, want 1", second.call)
+	}
+	if *calls != 1 {
+		t.Errorf("handler calls = %d, want 1", *calls)
+	}
+}
+
+func TestNew_Vary(t *testing.T) {
+	app, calls := newTestApp(time.Minute, fiber.StatusOK)
+
+	_ = request(t, app, "GET", "user", "/?q=1")
+
+	if res := request(t, app, "GET", "other", "/?q=1"); res.cache != "MISS" || res.body != "other:1:2" {
+		t.Errorf("other user response = %+v, want MISS with own body", res)
+	}
+	if res := request(t, app, "GET", "user", "/?q=2"); res.cache != "MISS" || res.body != "user:2:3" {
+		t.Errorf("other query response = %+v, want MISS with own body", res)
+	}
+	if res := request(t, app, "GET", "user", "/?q=1"); res.cache != "HIT" || res.body != "user:1:1" {
+		t.Errorf("repeated response = %+v, want HIT with body user:1:1", res)
+	}
+	if *calls != 3 {
+		t.Errorf("handler calls = %d, want 3", *calls)
+	}
+}
+
+func TestNew_NoCache(t *testing.T) {
+	app, _ := newTestApp(time.Minute, fiber.StatusOK)
+
+	_ = request(t, app, "GET", "user", "/")
+
+	refreshed := request(t, app, "GET", "user", "/", fiber.HeaderCacheControl, "no-cache")
+	if refreshed.cache != "MISS" || refreshed.body != "user::2" {
+		t.Errorf("no-cache response = %+v, want MISS with body user::2", refreshed)
+	}
+	if res := request(t, app, "GET", "user", "/"); res.cache != "HIT" || res.body != refreshed.body {
+		t.Errorf("response after refresh = %+v, want HIT with body %s", res, refreshed.body)
+	}
+}
+
+func TestNew_NotCached(t *testing.T) {
+	t.Run("error status", func(t *testing.T) {
+		app, calls := newTestApp(time.Minute, fiber.StatusNotFound)
+
+		for range 2 {
+			if res := request(t, app, "GET", "user", "/"); res.cache != "MISS" || res.status != fiber.StatusNotFound {
+				t.Errorf("response = %+v, want MISS with 404", res)
+			}
+		}
+		if *calls != 2 {
+			t.Errorf("handler calls = %d, want 2", *calls)
+		}
+	})
+
+	t.Run("post", func(t *testing.T) {
+		app, calls := newTestApp(time.Minute, fiber.StatusOK)
+
+		for range 2 {
+			if res := request(t, app, "POST", "user", "/"); res.cache != "" {
+				t.Errorf("X-Cache = %q, want none", res.cache)
+			}
+		}
+		if *calls != 2 {
+			t.Errorf("handler calls = %d, want 2", *calls)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		app, calls := newTestApp(0, fiber.StatusOK)
+
+		for range 2 {
+			if res := request(t, app, "GET", "user", "/"); res.cache != "" {
+				t.Errorf("X-Cache = %q, want none", res.cache)
+			}
+		}
+		if *calls != 2 {
+			t.Errorf("handler calls = %d, want 2", *calls)
+		}
+	})
+}
+
+func TestNew_Invalidate(t *testing.T) {
+	app, _ := newTestApp(time.Minute, fiber.StatusOK)
+
+	_ = request(t, app, "GET", "user", "/?q=1")
+	_ = request(t, app, "GET", "user", "/?q=2")
+	_ = request(t, app, "GET", "other", "/?q=1")
+	_ = request(t, app, "GET", "user", "/other/")
+
+	if res := request(t, app, "PATCH", "user", "/"); res.cache != "" {
+		t.Errorf("X-Cache of write = %q, want none", res.cache)
+	}
+
+	for _, url := range []string{"/?q=1", "/?q=2"} {
+		if res := request(t, app, "GET", "user", url); res.cache != "MISS" {
+			t.Errorf("response of %s after write = %+v, want MISS", url, res)
+		}
+	}
+	if res := request(t, app, "GET", "other", "/?q=1"); res.cache != "HIT" {
+		t.Errorf("other user response after write = %+v, want HIT", res)
+	}
+	if res := request(t, app, "GET", "user", "/other/"); res.cache != "HIT" {
+		t.Errorf("other group response after write = %+v, want HIT", res)
+	}
+}
+
+func TestNew_InvalidateFailedWrite(t *testing.T) {
+	calls := 0
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", models.User{ID: c.Get("X-User")})
+		return c.Next()
+	})
+	app.Use(respcache.New(cache.NewMemory(0), "root", time.Minute))
+	app.Get("/", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendString(strconv.Itoa(calls))
+	})
+	app.Patch("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusBadRequest)
+	})
+	app.Delete("/", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusNotFound)
+	})
+
+	_ = request(t, app, "GET", "user", "/")
+
+	// the rejected writes don't change the resources
+	_ = request(t, app, "PATCH", "user", "/")
+	_ = request(t, app, "DELETE", "user", "/")
+
+	if res := request(t, app, "GET", "user", "/"); res.cache != "HIT" || res.body != "1" {
+		t.Errorf("response = %+v, want HIT with body 1", res)
+	}
+}
@@ -6,11 +6,15 @@ import (
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/enrollments"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/utils"
 )
 
-const localsUser = "user"
+const (
+	localsUser       = "user"
+	localsEnrollment = "enrollment"
+)
 
 // NewBasic returns a middleware that will check if the request contains a valid
 // "Authorization" header in the form of "Basic <base64 encoded username:password>".
@@ -83,6 +87,35 @@ func NewCode(authSvc *auth.Service) fiber.Handler {
 	}
 }
 
+// NewEnrollment returns a middleware that will check if the request contains a
+// valid "Authorization" header in the form of "Enrollment <enrollment token>".
+// If the header is valid, the middleware will store the owner of the token and
+// the enrollment in the request's Locals. The token is redeemed on
+// registration, so a failed request doesn't use it up. If the header
+// is invalid, the middleware will call c.Next() and continue with the request.
+func NewEnrollment(authSvc *auth.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		auth := c.Get(fiber.HeaderAuthorization)
+
+		if len(auth) <= 11 || !strings.EqualFold(auth[:11], "enrollment ") {
+			return c.Next()
+		}
+
+		// Get the token
+		token := auth[11:]
+
+		user, enrollment, err := authSvc.AuthorizeEnrollment(token)
+		if err != nil {
+			return fiber.ErrUnauthorized
+		}
+
+		c.Locals(localsUser, user)
+		c.Locals(localsEnrollment, enrollment)
+
+		return c.Next()
+	}
+}
+
 // HasUser checks if a user is present in the Locals of the given context.
 // It returns true if the Locals contain a user under the key LocalsUser,
 // otherwise returns false.
@@ -99,6 +132,12 @@ func GetUser(c *fiber.Ctx) models.User {
 	return c.Locals(localsUser).(models.User)
 }
 
+// GetEnrollment returns the enrollment the user was authorized with, if any.
+func GetEnrollment(c *fiber.Ctx) (enrollments.Enrollment, bool) {
+	enrollment, ok := c.Locals(localsEnrollment).(enrollments.Enrollment)
+	return enrollment, ok
+}
+
 // UserRequired is a middleware that ensures a user is present in the request's Locals.
 // If a user is not found, it returns an unauthorized error, otherwise it passes control
 // to the next handler in the stack.
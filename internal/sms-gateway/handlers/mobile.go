@@ -1,21 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/converters"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/keys"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/messages"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/deviceauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/ratelimit"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/settings"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/webhooks"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/crashes"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devicelogs"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/enrollments"
+	appevents "github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/pings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sims"
 	"github.com/capcom6/go-helpers/anys"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -28,28 +38,42 @@ import (
 type mobileHandlerParams struct {
 	fx.In
 
+	Config Config
+
 	Logger    *zap.Logger
 	Validator *validator.Validate
 
-	AuthSvc    *auth.Service
-	DevicesSvc *devices.Service
+	AuthSvc       *auth.Service
+	CrashesSvc    *crashes.Service
+	DevicesSvc    *devices.Service
+	PingsSvc      *pings.Service
+	DeviceLogsSvc *devicelogs.Service
+	SimsSvc       *sims.Service
 
 	MessagesCtrl *messages.MobileController
 	WebhooksCtrl *webhooks.MobileController
 	SettingsCtrl *settings.MobileController
 	EventsCtrl   *events.MobileController
+	KeysCtrl     *keys.MobileController
 }
 
 type mobileHandler struct {
 	base.Handler
 
-	authSvc    *auth.Service
-	devicesSvc *devices.Service
+	config Config
+
+	authSvc       *auth.Service
+	crashesSvc    *crashes.Service
+	devicesSvc    *devices.Service
+	pingsSvc      *pings.Service
+	deviceLogsSvc *devicelogs.Service
+	simsSvc       *sims.Service
 
 	messagesCtrl *messages.MobileController
 	webhooksCtrl *webhooks.MobileController
 	settingsCtrl *settings.MobileController
 	eventsCtrl   *events.MobileController
+	keysCtrl     *keys.MobileController
 
 	idGen func() string
 }
@@ -76,17 +100,19 @@ func (h *mobileHandler) getDevice(device models.Device, c *fiber.Ctx) error {
 }
 
 //	@Summary		Register device
-//	@Description	Registers new device for new or existing user. Returns user credentials only for new users
+//	@Description	Registers new device for new or existing user. Returns user credentials only for new users. Devices registered with an enrollment token get its name, tags, group and settings
 //	@Security		ApiAuth
 //	@Security		UserCode
 //	@Security		ServerKey
+//	@Security		EnrollmentToken
 //	@Tags			Device
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		smsgateway.MobileRegisterRequest	true	"Device registration request"
 //	@Success		201		{object}	smsgateway.MobileRegisterResponse	"Device registered"
 //	@Failure		400		{object}	smsgateway.ErrorResponse			"Invalid request"
-//	@Failure		401		{object}	smsgateway.ErrorResponse			"Unauthorized (private mode only)"
+//	@Failure		401		{object}	smsgateway.ErrorResponse			"Unauthorized (private mode or invalid enrollment token)"
+//	@Failure		403		{object}	smsgateway.ErrorResponse			"Forbidden (sandbox credentials)"
 //	@Failure		429		{object}	smsgateway.ErrorResponse			"Too many requests"
 //	@Failure		500		{object}	smsgateway.ErrorResponse			"Internal server error"
 //	@Router			/mobile/v1/device [post]
@@ -108,6 +134,10 @@ func (h *mobileHandler) postDevice(c *fiber.Ctx) (err error) {
 	if userauth.HasUser(c) {
 		user = userauth.GetUser(c)
 		login = user.ID
+
+		if user.Sandbox {
+			return fiber.NewError(fiber.StatusForbidden, "Sandbox credentials can't be used to register devices")
+		}
 	} else {
 		id := h.idGen()
 		login = strings.ToUpper(id[:6])
@@ -119,7 +149,16 @@ func (h *mobileHandler) postDevice(c *fiber.Ctx) (err error) {
 		}
 	}
 
-	device, err := h.authSvc.RegisterDevice(user, req.Name, req.PushToken)
+	var device models.Device
+	if enrollment, ok := userauth.GetEnrollment(c); ok {
+		device, err = h.authSvc.RegisterEnrolledDevice(user, enrollment, req.Name, req.PushToken)
+		if errors.Is(err, enrollments.ErrInvalidToken) {
+			// the last use is redeemed concurrently or the token is revoked
+			return fiber.ErrUnauthorized
+		}
+	} else {
+		device, err = h.authSvc.RegisterDevice(user, req.Name, req.PushToken)
+	}
 	if err != nil {
 		return fmt.Errorf("can't register device: %w", err)
 	}
@@ -133,12 +172,21 @@ func (h *mobileHandler) postDevice(c *fiber.Ctx) (err error) {
 		})
 }
 
+type mobileUpdateRequest struct {
+	smsgateway.MobileUpdateRequest
+
+	// Device name
+	Name *string `json:"name,omitempty" validate:"omitempty,max=128" example:"Android Phone"`
+	// Version of the mobile app
+	AppVersion *string `json:"appVersion,omitempty" validate:"omitempty,max=32" example:"1.40.0"`
+}
+
 //	@Summary		Update device
-//	@Description	Updates push token for device
+//	@Description	Updates push token, name and app version of the device
 //	@Security		MobileToken
 //	@Tags			Device
 //	@Accept			json
-//	@Param			request	body	smsgateway.MobileUpdateRequest	true	"Device update request"
+//	@Param			request	body	mobileUpdateRequest			true	"Device update request"
 //	@Success		204		"Successfully updated"
 //	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
 //	@Failure		403		{object}	smsgateway.ErrorResponse	"Forbidden (wrong device ID)"
@@ -147,7 +195,7 @@ func (h *mobileHandler) postDevice(c *fiber.Ctx) (err error) {
 //
 // Update device
 func (h *mobileHandler) patchDevice(device models.Device, c *fiber.Ctx) error {
-	req := smsgateway.MobileUpdateRequest{}
+	req := mobileUpdateRequest{}
 
 	if err := h.BodyParserValidator(c, &req); err != nil {
 		return err
@@ -157,10 +205,293 @@ func (h *mobileHandler) patchDevice(device models.Device, c *fiber.Ctx) error {
 		return fiber.ErrForbidden
 	}
 
-	if err := h.devicesSvc.UpdatePushToken(req.Id, req.PushToken); err != nil {
+	update := devices.DeviceUpdate{
+		Name:       req.Name,
+		AppVersion: req.AppVersion,
+	}
+	if req.PushToken != "" {
+		update.PushToken = &req.PushToken
+	}
+	if err := h.devicesSvc.Update(device, update); err != nil {
+		return fmt.Errorf("can't update device: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type mobileSubscriptionsRequest struct {
+	// Event types to deliver to the device, empty to receive all events
+	Events []string `json:"events" example:"MessageEnqueued"`
+}
+
+func (r *mobileSubscriptionsRequest) Validate() error {
+	for _, event := range r.Events {
+		if !appevents.IsType(event) {
+			return fmt.Errorf("unknown event type: %s", event)
+		}
+	}
+
+	return nil
+}
+
+type mobileSubscriptionsResponse struct {
+	// Event types delivered to the device, empty if the device receives all events
+	Events []string `json:"events" example:"MessageEnqueued"`
+}
+
+//	@Summary		Get event subscriptions
+//	@Description	Returns event types delivered to the device via push or SSE
+//	@Security		MobileToken
+//	@Tags			Device
+//	@Produce		json
+//	@Success		200	{object}	mobileSubscriptionsResponse	"Event subscriptions"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/device/subscriptions [get]
+//
+// Get event subscriptions
+func (h *mobileHandler) getSubscriptions(device models.Device, c *fiber.Ctx) error {
+	subscriptions := device.EventSubscriptions
+	if subscriptions == nil {
+		subscriptions = []string{}
+	}
+
+	return c.JSON(mobileSubscriptionsResponse{Events: subscriptions})
+}
+
+//	@Summary		Update event subscriptions
+//	@Description	Sets event types delivered to the device via push or SSE. Other events are not sent to the device, an empty list restores delivery of all events
+//	@Security		MobileToken
+//	@Tags			Device
+//	@Accept			json
+//	@Param			request	body	mobileSubscriptionsRequest	true	"Event subscriptions"
+//	@Success		204		"Successfully updated"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/device/subscriptions [put]
+//
+// Update event subscriptions
+func (h *mobileHandler) putSubscriptions(device models.Device, c *fiber.Ctx) error {
+	req := mobileSubscriptionsRequest{}
+
+	if err := h.BodyParserValidator(c, &req); err != nil {
 		return err
 	}
 
+	if err := h.devicesSvc.UpdateEventSubscriptions(device, req.Events); err != nil {
+		return fmt.Errorf("can't update event subscriptions: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type mobilePingRequest struct {
+	// Device time when the ping was sent
+	SentAt time.Time `json:"sentAt" validate:"required" example:"2025-10-16T12:00:00.000Z"`
+	// Round-trip time of the previous ping in milliseconds, measured by the device
+	LastRTTMs *uint32 `json:"lastRttMs,omitempty" example:"120"`
+}
+
+type mobilePingResponse struct {
+	// Device time when the ping was sent, as received
+	SentAt time.Time `json:"sentAt" example:"2025-10-16T12:00:00.000Z"`
+	// Server time when the ping was received
+	ReceivedAt time.Time `json:"receivedAt" example:"2025-10-16T12:00:00.060Z"`
+	// Estimated clock skew of the device in milliseconds, positive if the device clock is ahead
+	ClockSkewMs int64 `json:"clockSkewMs" example:"-15"`
+}
+
+//	@Summary		Ping server
+//	@Description	Measures round-trip time and clock skew. The device calculates the round-trip time from the response and reports it in the next ping
+//	@Security		MobileToken
+//	@Tags			Device
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		mobilePingRequest			true	"Ping request"
+//	@Success		200		{object}	mobilePingResponse			"Ping response"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/ping [post]
+//
+// Ping server
+func (h *mobileHandler) postPing(device models.Device, c *fiber.Ctx) error {
+	receivedAt := time.Now()
+
+	req := mobilePingRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	var rtt *time.Duration
+	if req.LastRTTMs != nil {
+		rtt = anys.AsPointer(time.Duration(*req.LastRTTMs) * time.Millisecond)
+	}
+
+	sample, err := h.pingsSvc.Record(c.Context(), device.ID, req.SentAt, receivedAt, rtt)
+	if err != nil {
+		return fmt.Errorf("can't record ping: %w", err)
+	}
+
+	return c.JSON(mobilePingResponse{
+		SentAt:      req.SentAt,
+		ReceivedAt:  receivedAt,
+		ClockSkewMs: sample.Skew.Milliseconds(),
+	})
+}
+
+type mobileLogEntry struct {
+	// Log priority
+	Priority string `json:"priority" validate:"max=16" example:"ERROR"`
+	// Module that produced the entry
+	Module string `json:"module" validate:"max=64" example:"messages"`
+	// Log message
+	Message string `json:"message" validate:"required,max=4096" example:"Can't send message"`
+	// Additional context
+	Context map[string]string `json:"context,omitempty" validate:"max=32"`
+	// Time when the entry was created on the device
+	CreatedAt time.Time `json:"createdAt" validate:"required" example:"2025-10-16T12:00:00.000Z"`
+}
+
+type mobileLogsRequest struct {
+	// Batch of log entries
+	Entries []mobileLogEntry `json:"entries" validate:"required,min=1,max=500,dive"`
+}
+
+//	@Summary		Upload logs
+//	@Description	Uploads a batch of recent log entries for remote debugging. Entries are kept for 72 hours, up to 1000 per device
+//	@Security		MobileToken
+//	@Tags			Device, Logs
+//	@Accept			json
+//	@Param			request	body	mobileLogsRequest	true	"Log entries"
+//	@Success		204		"Logs uploaded"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/logs [post]
+//
+// Upload logs
+func (h *mobileHandler) postLogs(device models.Device, c *fiber.Ctx) error {
+	req := mobileLogsRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	entries := make([]devicelogs.Entry, 0, len(req.Entries))
+	for _, e := range req.Entries {
+		entries = append(entries, devicelogs.Entry(e))
+	}
+
+	if err := h.deviceLogsSvc.Append(c.Context(), device.ID, entries); err != nil {
+		return fmt.Errorf("can't store logs: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+type mobileCrashRequest struct {
+	// Failure type
+	Kind crashes.Kind `json:"kind" validate:"required,oneof=crash anr" example:"crash" swaggertype:"string" enums:"crash,anr"`
+	// App version
+	AppVersion string `json:"appVersion" validate:"required,max=32" example:"1.40.0"`
+	// Android version
+	AndroidVersion string `json:"androidVersion" validate:"max=32" example:"14"`
+	// Exception class
+	Exception string `json:"exception" validate:"required" example:"java.lang.IllegalStateException"`
+	// Exception message
+	Message string `json:"message" example:"Can't send message"`
+	// Stack trace
+	StackTrace string `json:"stackTrace" validate:"required,max=65535" example:"java.lang.IllegalStateException: Can't send message\n\tat ..."`
+	// Time of the failure
+	OccurredAt time.Time `json:"occurredAt" validate:"required" example:"2025-10-16T12:00:00.000Z"`
+}
+
+//	@Summary		Report crash
+//	@Description	Submits a crash or ANR report of the app. Reports are deduplicated by the stack trace
+//	@Security		MobileToken
+//	@Tags			Device
+//	@Accept			json
+//	@Param			request	body	mobileCrashRequest	true	"Crash report"
+//	@Success		202		"Report accepted"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/crashes [post]
+//
+// Report crash
+func (h *mobileHandler) postCrash(device models.Device, c *fiber.Ctx) error {
+	req := mobileCrashRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	report := crashes.Report{
+		DeviceID:       device.ID,
+		Kind:           req.Kind,
+		AppVersion:     req.AppVersion,
+		AndroidVersion: req.AndroidVersion,
+		Exception:      req.Exception,
+		Message:        req.Message,
+		StackTrace:     req.StackTrace,
+		OccurredAt:     req.OccurredAt,
+	}
+
+	if err := h.crashesSvc.Submit(report); err != nil {
+		return fmt.Errorf("can't submit crash report: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
+type mobileSIMReport struct {
+	// SIM card number
+	SimNumber uint8 `json:"simNumber" validate:"required,min=1,max=3" example:"1"`
+	// Carrier name
+	Operator *string `json:"operator,omitempty" validate:"omitempty,max=64" example:"Vodafone"`
+	// Account balance
+	Balance *float64 `json:"balance,omitempty" example:"12.5"`
+	// Balance currency
+	Currency *string `json:"currency,omitempty" validate:"omitempty,max=8" example:"EUR"`
+	// Plan name
+	Plan *string `json:"plan,omitempty" validate:"omitempty,max=128" example:"Unlimited SMS"`
+	// Plan or balance expiration time
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" example:"2025-11-01T00:00:00Z"`
+}
+
+type mobileSIMsRequest struct {
+	// Balance and plan data of the SIM cards
+	SIMs []mobileSIMReport `json:"sims" validate:"required,min=1,max=3,dive"`
+}
+
+//	@Summary		Report SIM balance
+//	@Description	Stores the balance and plan data parsed by the app from carrier USSD or SMS responses. SIMs with the balance below the `sims.min_balance` setting are excluded from routing
+//	@Security		MobileToken
+//	@Tags			Device
+//	@Accept			json
+//	@Param			request	body	mobileSIMsRequest	true	"SIM reports"
+//	@Success		204		"Reports stored"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/mobile/v1/sims [post]
+//
+// Report SIM balance
+func (h *mobileHandler) postSims(device models.Device, c *fiber.Ctx) error {
+	req := mobileSIMsRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	reports := make([]sims.Report, len(req.SIMs))
+	for i, r := range req.SIMs {
+		reports[i] = sims.Report(r)
+	}
+
+	if err := h.simsSvc.Report(device, reports); err != nil {
+		return fmt.Errorf("can't report SIMs: %w", err)
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -222,6 +553,7 @@ func (h *mobileHandler) Register(router fiber.Router) {
 	router.Post("/device",
 		userauth.NewBasic(h.authSvc),
 		userauth.NewCode(h.authSvc),
+		userauth.NewEnrollment(h.authSvc),
 		keyauth.New(keyauth.Config{
 			Next: func(c *fiber.Ctx) bool {
 				// Skip server key authorization in the following cases:
@@ -249,9 +581,20 @@ func (h *mobileHandler) Register(router fiber.Router) {
 
 	router.Get("/device", deviceauth.WithDevice(h.getDevice))
 
-	router.Use(deviceauth.DeviceRequired())
+	router.Use(
+		deviceauth.DeviceRequired(),
+		ratelimit.New("mobile", h.config.MobileRateLimit, func(c *fiber.Ctx) string {
+			return deviceauth.GetDevice(c).ID
+		}),
+	)
 
 	router.Patch("/device", deviceauth.WithDevice(h.patchDevice))
+	router.Get("/device/subscriptions", deviceauth.WithDevice(h.getSubscriptions))
+	router.Put("/device/subscriptions", deviceauth.WithDevice(h.putSubscriptions))
+	router.Post("/ping", deviceauth.WithDevice(h.postPing))
+	router.Post("/logs", deviceauth.WithDevice(h.postLogs))
+	router.Post("/crashes", deviceauth.WithDevice(h.postCrash))
+	router.Post("/sims", deviceauth.WithDevice(h.postSims))
 
 	// Should be under `userauth.NewBasic` protection instead of `deviceauth`
 	router.Patch("/user/password", deviceauth.WithDevice(h.changePassword))
@@ -261,6 +604,7 @@ func (h *mobileHandler) Register(router fiber.Router) {
 	h.webhooksCtrl.Register(router.Group("/webhooks"))
 	h.settingsCtrl.Register(router.Group("/settings"))
 	h.eventsCtrl.Register(router.Group("/events"))
+	h.keysCtrl.Register(router.Group("/keys"))
 }
 
 func newMobileHandler(params mobileHandlerParams) *mobileHandler {
@@ -268,13 +612,19 @@ func newMobileHandler(params mobileHandlerParams) *mobileHandler {
 
 	return &mobileHandler{
 		Handler: base.Handler{Logger: params.Logger, Validator: params.Validator},
+		config:  params.Config,
 		authSvc: params.AuthSvc,
 
-		messagesCtrl: params.MessagesCtrl,
-		devicesSvc:   params.DevicesSvc,
-		webhooksCtrl: params.WebhooksCtrl,
-		settingsCtrl: params.SettingsCtrl,
-		eventsCtrl:   params.EventsCtrl,
+		messagesCtrl:  params.MessagesCtrl,
+		crashesSvc:    params.CrashesSvc,
+		devicesSvc:    params.DevicesSvc,
+		pingsSvc:      params.PingsSvc,
+		deviceLogsSvc: params.DeviceLogsSvc,
+		simsSvc:       params.SimsSvc,
+		webhooksCtrl:  params.WebhooksCtrl,
+		settingsCtrl:  params.SettingsCtrl,
+		eventsCtrl:    params.EventsCtrl,
+		keysCtrl:      params.KeysCtrl,
 
 		idGen: idGen,
 	}
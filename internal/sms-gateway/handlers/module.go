@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/autoreplies"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/devices"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/keys"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/logs"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/otp"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/privacy"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/sandbox"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/settings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/stats"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/users"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/webhooks"
 	"github.com/capcom6/go-infra-fx/http"
 	"go.uber.org/fx"
@@ -22,6 +30,7 @@ var Module = fx.Module(
 		http.AsApiHandler(newThirdPartyHandler),
 		http.AsApiHandler(newMobileHandler),
 		http.AsApiHandler(newUpstreamHandler),
+		http.AsApiHandler(newAdminHandler),
 	),
 	fx.Provide(
 		newHealthHandler,
@@ -33,7 +42,18 @@ var Module = fx.Module(
 		settings.NewThirdPartyController,
 		settings.NewMobileController,
 		logs.NewThirdPartyController,
+		otp.NewThirdPartyController,
+		stats.NewThirdPartyController,
+		sandbox.NewThirdPartyController,
 		events.NewMobileController,
+		keys.NewThirdPartyController,
+		keys.NewMobileController,
+		users.NewThirdPartyController,
+		privacy.NewThirdPartyController,
+		autoreplies.NewThirdPartyController,
 		fx.Private,
 	),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("responses", cache.WithEvictable())
+	}, fx.Private),
 )
@@ -0,0 +1,218 @@
+package otp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/otp"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type thirdPartyControllerParams struct {
+	fx.In
+
+	OTPSvc      *otp.Service
+	DevicesSvc  *devices.Service
+	MessagesSvc *messages.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type ThirdPartyController struct {
+	base.Handler
+
+	otpSvc      *otp.Service
+	devicesSvc  *devices.Service
+	messagesSvc *messages.Service
+}
+
+type sendRequest struct {
+	// Recipient phone number
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=128" example:"+79990001234"`
+	// Device ID, random active device is used if empty
+	DeviceID string `json:"deviceId,omitempty" validate:"omitempty,nanoid" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Message template, `{code}` is replaced with the generated code
+	Template string `json:"template,omitempty" validate:"omitempty,max=1024" example:"Your verification code is {code}"`
+	// Number of digits in the code
+	Length int `json:"length,omitempty" validate:"omitempty,min=4,max=10" example:"6" default:"6"`
+	// Code validity period in seconds
+	TTL int `json:"ttl,omitempty" validate:"omitempty,min=30,max=3600" example:"300" default:"300"`
+	// SIM card number
+	SimNumber *uint8 `json:"simNumber,omitempty" validate:"omitempty,min=1,max=3" example:"1"`
+}
+
+type sendResponse struct {
+	// Code ID to verify the code with
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Code expiration time
+	ExpiresAt time.Time `json:"expiresAt" example:"2025-10-16T12:05:00Z"`
+	// Message ID
+	MessageID string `json:"messageId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Device ID
+	DeviceID string `json:"deviceId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+}
+
+type verifyRequest struct {
+	// Code ID
+	ID string `json:"id" validate:"required,nanoid" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Code provided by the recipient
+	Code string `json:"code" validate:"required,numeric,max=10" example:"123456"`
+}
+
+type verifyResponse struct {
+	// Code is valid
+	Valid bool `json:"valid" example:"true"`
+}
+
+type thirdPartySendQueryParams struct {
+	SkipPhoneValidation bool `query:"skipPhoneValidation"`
+}
+
+//	@Summary		Send code
+//	@Description	Generates a numeric code and sends it in a message with bypass priority. Only the hash of the code is stored until it expires.
+//	@Security		ApiAuth
+//	@Tags			User, OTP
+//	@Accept			json
+//	@Produce		json
+//	@Param			skipPhoneValidation	query		bool						false	"Skip phone validation"
+//	@Param			request				body		sendRequest					true	"Send code request"
+//	@Success		202					{object}	sendResponse				"Code sent"
+//	@Failure		400					{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401					{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500					{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/otp [post]
+//
+// Send code
+func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
+	var params thirdPartySendQueryParams
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	req := sendRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	device, err := h.selectDevice(user, req.DeviceID)
+	if err != nil {
+		return err
+	}
+
+	code, err := h.otpSvc.Send(c.Context(), device, otp.Request{
+		PhoneNumber:         req.PhoneNumber,
+		Template:            req.Template,
+		Length:              req.Length,
+		TTL:                 time.Duration(req.TTL) * time.Second,
+		SimNumber:           req.SimNumber,
+		SkipPhoneValidation: params.SkipPhoneValidation,
+	})
+	if err != nil {
+		var errValidation messages.ErrValidation
+		var errContentPolicy messages.ErrContentPolicy
+		if errors.Is(err, otp.ErrInvalidTemplate) || errors.As(err, &errValidation) || errors.As(err, &errContentPolicy) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+
+		return fmt.Errorf("can't send code: %w", err)
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(sendResponse{
+		ID:        code.ID,
+		ExpiresAt: code.ExpiresAt,
+		MessageID: code.Message.ID,
+		DeviceID:  code.Message.DeviceID,
+	})
+}
+
+//	@Summary		Verify code
+//	@Description	Checks the code provided by the recipient. A valid code can be used only once; the code is dropped after 5 failed attempts.
+//	@Security		ApiAuth
+//	@Tags			User, OTP
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		verifyRequest				true	"Verify code request"
+//	@Success		200		{object}	verifyResponse				"Verification result"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	smsgateway.ErrorResponse	"Code not found or expired"
+//	@Failure		429		{object}	smsgateway.ErrorResponse	"Too many attempts"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/otp/verify [post]
+//
+// Verify code
+func (h *ThirdPartyController) postVerify(user models.User, c *fiber.Ctx) error {
+	req := verifyRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	valid, err := h.otpSvc.Verify(c.Context(), user.ID, req.ID, req.Code)
+	if errors.Is(err, otp.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if errors.Is(err, otp.ErrTooManyAttempts) {
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't verify code: %w", err)
+	}
+
+	return c.JSON(verifyResponse{Valid: valid})
+}
+
+func (h *ThirdPartyController) selectDevice(user models.User, deviceID string) (models.Device, error) {
+	if deviceID != "" {
+		device, err := h.devicesSvc.Get(user.ID, devices.WithID(deviceID))
+		if errors.Is(err, devices.ErrNotFound) {
+			return device, fiber.NewError(fiber.StatusBadRequest, "No active device with such ID found")
+		}
+		if err != nil {
+			return device, fmt.Errorf("can't get device: %w", err)
+		}
+		return device, nil
+	}
+
+	items, err := h.devicesSvc.Select(user.ID)
+	if err != nil {
+		return models.Device{}, fmt.Errorf("can't select devices: %w", err)
+	}
+	if len(items) == 0 {
+		return models.Device{}, fiber.NewError(fiber.StatusBadRequest, "No active devices found")
+	}
+
+	device, err := slices.Random(h.messagesSvc.FilterHealthy(items))
+	if err != nil {
+		return models.Device{}, fmt.Errorf("can't get random device: %w", err)
+	}
+
+	return device, nil
+}
+
+func (h *ThirdPartyController) Register(router fiber.Router) {
+	router.Post("", userauth.WithUser(h.post))
+	router.Post("verify", userauth.WithUser(h.postVerify))
+}
+
+func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
+	return &ThirdPartyController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("otp"),
+			Validator: params.Validator,
+		},
+		otpSvc:      params.OTPSvc,
+		devicesSvc:  params.DevicesSvc,
+		messagesSvc: params.MessagesSvc,
+	}
+}
@@ -0,0 +1,133 @@
+package privacy
+
+import (
+	"fmt"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/privacy"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type thirdPartyControllerParams struct {
+	fx.In
+
+	PrivacySvc *privacy.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type ThirdPartyController struct {
+	base.Handler
+
+	privacySvc *privacy.Service
+}
+
+//	@Summary		Export data of phone number
+//	@Description	Returns the outgoing messages to the phone number and the incoming messages from it uploaded by the devices for the inbox exports, to answer the data subject access request. Both lists are paged with the same limit and offset, `X-Total-Count` is the larger of the totals. The request is recorded in the audit trail with the first page.
+//	@Security		ApiAuth
+//	@Tags			User, Privacy
+//	@Produce		json
+//	@Param			phoneNumber	query		string						true	"Phone number of the data subject"
+//	@Param			limit		query		int							false	"Pagination limit"	default(50)	min(1)	max(100)
+//	@Param			offset		query		int							false	"Pagination offset"	default(0)
+//	@Success		200			{object}	exportResponse				"Data of the phone number"
+//	@Header			200			{integer}	X-Total-Count				"Larger of the totals of outgoing and incoming messages"
+//	@Header			200			{string}	Link						"Links to the first, prev, next and last pages"
+//	@Failure		400			{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401			{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500			{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/privacy/export [get]
+//
+// Export data of phone number
+func (h *ThirdPartyController) getExport(user models.User, c *fiber.Ctx) error {
+	params := exportQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	limit := params.limit()
+	report, err := h.privacySvc.Export(c.Context(), user.ID, params.PhoneNumber, limit, params.Offset)
+	if err != nil {
+		return fmt.Errorf("can't export data: %w", err)
+	}
+
+	base.SetPagination(c, max(report.MessagesTotal, report.InboxTotal), params.Offset, limit)
+	return c.JSON(reportToDTO(report))
+}
+
+//	@Summary		Erase data of phone number
+//	@Description	Removes the phone number and the content from the outgoing messages, deletes the pending ones and their short links, drops the incoming messages uploaded for the inbox exports and the device log entries mentioning the number, and erases the copies relayed to the upstream instance, to answer the data subject erasure request. The upstream account is shared, so the relayed copies of the other users to the number are erased too. The erased messages are kept in the stats. The request is recorded in the audit trail. The erasure may be repeated on failure.
+//	@Security		ApiAuth
+//	@Tags			User, Privacy
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		eraseRequest				true	"Erase request"
+//	@Success		200		{object}	eraseResponse				"Number of erased messages"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/privacy/erase [post]
+//
+// Erase data of phone number
+func (h *ThirdPartyController) postErase(user models.User, c *fiber.Ctx) error {
+	req := eraseRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	result, err := h.privacySvc.Erase(c.Context(), user.ID, req.PhoneNumber)
+	if err != nil {
+		return fmt.Errorf("can't erase data: %w", err)
+	}
+
+	return c.JSON(eraseResponse{
+		Messages:         result.Messages,
+		InboxMessages:    result.InboxMessages,
+		Links:            result.Links,
+		LogEntries:       result.LogEntries,
+		UpstreamMessages: result.UpstreamMessages,
+	})
+}
+
+//	@Summary		List data subject requests
+//	@Description	Returns the latest 100 export and erase requests of the user, newest first. The phone numbers are kept as hashes only.
+//	@Security		ApiAuth
+//	@Tags			User, Privacy
+//	@Produce		json
+//	@Success		200	{object}	[]requestResponse			"Audit trail"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/privacy/requests [get]
+//
+// List data subject requests
+func (h *ThirdPartyController) getRequests(user models.User, c *fiber.Ctx) error {
+	items, err := h.privacySvc.Requests(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(slices.Map(items, requestToDTO))
+}
+
+func (h *ThirdPartyController) Register(router fiber.Router) {
+	router.Get("export", userauth.WithUser(h.getExport))
+	router.Post("erase", userauth.WithUser(h.postErase))
+	router.Get("requests", userauth.WithUser(h.getRequests))
+}
+
+func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
+	return &ThirdPartyController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("privacy"),
+			Validator: params.Validator,
+		},
+		privacySvc: params.PrivacySvc,
+	}
+}
@@ -0,0 +1,137 @@
+package privacy
+
+import (
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/converters"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/privacy"
+	"github.com/capcom6/go-helpers/slices"
+)
+
+type exportQueryParams struct {
+	// Phone number of the data subject
+	PhoneNumber string `query:"phoneNumber" validate:"required,max=128"`
+	Limit       int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Offset      int    `query:"offset" validate:"omitempty,min=0"`
+}
+
+// limit returns the page size, 50 by default.
+func (p exportQueryParams) limit() int {
+	if p.Limit > 0 {
+		return p.Limit
+	}
+
+	return 50
+}
+
+type eraseRequest struct {
+	// Phone number of the data subject
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=128" example:"+79990001234"`
+}
+
+type outgoingMessage struct {
+	smsgateway.MobileMessage
+
+	// Processing state with the recipients states
+	State *smsgateway.MessageState `json:"state,omitempty"`
+}
+
+type incomingMessage struct {
+	// Message ID on the device
+	ID string `json:"id" example:"1234"`
+	// Message type: sms, data or mms
+	Type string `json:"type" example:"sms"`
+	// Sender phone number
+	PhoneNumber string `json:"phoneNumber" example:"+79990001234"`
+	// Message text
+	Text string `json:"text" example:"Hello World!"`
+	// SIM card number
+	SimNumber *uint8 `json:"simNumber,omitempty" example:"1"`
+	// Time when the message was received
+	ReceivedAt time.Time `json:"receivedAt" example:"2025-10-16T12:00:00Z"`
+}
+
+type exportResponse struct {
+	// Outgoing messages to the phone number, the content of hashed messages is omitted
+	Messages []outgoingMessage `json:"messages"`
+	// Total number of outgoing messages
+	MessagesTotal int64 `json:"messagesTotal" example:"10"`
+	// Incoming messages from the phone number uploaded by the devices for the inbox exports
+	Inbox []incomingMessage `json:"inbox"`
+	// Total number of incoming messages
+	InboxTotal int64 `json:"inboxTotal" example:"2"`
+}
+
+type eraseResponse struct {
+	// Number of erased outgoing messages
+	Messages int64 `json:"messages" example:"10"`
+	// Number of erased incoming messages
+	InboxMessages int64 `json:"inboxMessages" example:"2"`
+	// Number of removed short links of the erased messages
+	Links int64 `json:"links" example:"1"`
+	// Number of removed device log entries mentioning the phone number
+	LogEntries int64 `json:"logEntries" example:"3"`
+	// Number of erased messages relayed to the upstream instance
+	UpstreamMessages int64 `json:"upstreamMessages" example:"0"`
+}
+
+type requestResponse struct {
+	// Request ID
+	ID uint64 `json:"id" example:"1"`
+	// Request type
+	Type privacy.RequestType `json:"type" example:"erase" enums:"export,erase"`
+	// SHA-256 of the phone number in the international format
+	PhoneNumberHash string `json:"phoneNumberHash" example:"8d2b7b3c5a2a0f7d6a4f1c1e0b9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e"`
+	// Number of exported or erased outgoing messages
+	Messages int64 `json:"messages" example:"10"`
+	// Number of exported or erased incoming messages
+	InboxMessages int64 `json:"inboxMessages" example:"2"`
+	// Time of the request
+	CreatedAt time.Time `json:"createdAt" example:"2025-11-09T12:00:00Z"`
+}
+
+func reportToDTO(report privacy.Report) exportResponse {
+	return exportResponse{
+		Messages:      slices.Map(report.Messages, outgoingMessageToDTO),
+		MessagesTotal: report.MessagesTotal,
+		Inbox:         slices.Map(report.Inbox, incomingMessageToDTO),
+		InboxTotal:    report.InboxTotal,
+	}
+}
+
+func outgoingMessageToDTO(m messages.MessageOut) outgoingMessage {
+	out := outgoingMessage{
+		MobileMessage: converters.MessageToMobileDTO(m),
+	}
+	if m.State != nil {
+		state := converters.MessageStateToDTO(*m.State)
+		out.State = &state
+	}
+
+	return out
+}
+
+func incomingMessageToDTO(m exports.Message) incomingMessage {
+	return incomingMessage{
+		ID:          m.ID,
+		Type:        m.Type,
+		PhoneNumber: m.PhoneNumber,
+		Text:        m.Text,
+		SimNumber:   m.SimNumber,
+		ReceivedAt:  m.ReceivedAt,
+	}
+}
+
+func requestToDTO(r privacy.Request) requestResponse {
+	return requestResponse{
+		ID:              r.ID,
+		Type:            r.Type,
+		PhoneNumberHash: r.PhoneNumberHash,
+		Messages:        r.Messages,
+		InboxMessages:   r.InboxMessages,
+		CreatedAt:       r.CreatedAt,
+	}
+}
@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"path"
 	"strings"
 
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/recovery"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/openapi"
 	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
 )
 
 type rootHandler struct {
@@ -13,9 +19,18 @@ type rootHandler struct {
 
 	healthHandler  *healthHandler
 	openapiHandler *openapi.Handler
+
+	linksSvc   *links.Service
+	exportsSvc *exports.Service
+
+	logger *zap.Logger
 }
 
 func (h *rootHandler) Register(app *fiber.App) {
+	// the root handlers are registered before the API ones, so the panics of
+	// all the routes are recovered here
+	app.Use(recovery.New(h.logger))
+
 	if h.config.PublicPath != "/api" {
 		app.Use(func(c *fiber.Ctx) error {
 			err := c.Next()
@@ -25,6 +40,10 @@ func (h *rootHandler) Register(app *fiber.App) {
 				c.Set(fiber.HeaderLocation, path.Join(h.config.PublicPath, after))
 			}
 
+			if link := c.GetRespHeader(fiber.HeaderLink); link != "" {
+				c.Set(fiber.HeaderLink, strings.ReplaceAll(link, "</api/", "<"+strings.TrimSuffix(h.config.PublicPath, "/")+"/"))
+			}
+
 			return err
 		})
 	}
@@ -32,6 +51,49 @@ func (h *rootHandler) Register(app *fiber.App) {
 	h.healthHandler.Register(app)
 
 	h.registerOpenAPI(app)
+
+	if h.linksSvc.Enabled() {
+		app.Get("/r/:token", h.redirect)
+	}
+
+	if h.exportsSvc.ArtifactsEnabled() {
+		app.Get("/exports/:id", h.download)
+	}
+}
+
+// redirect counts the click on the short link and redirects to the original URL.
+func (h *rootHandler) redirect(c *fiber.Ctx) error {
+	url, err := h.linksSvc.Resolve(c.Params("token"))
+	if errors.Is(err, links.ErrNotFound) {
+		return fiber.ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("can't resolve link: %w", err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+// download sends the export artifact requested by the signed link.
+func (h *rootHandler) download(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	content, err := h.exportsSvc.Download(c.Context(), id, c.Query("expires"), c.Query("signature"))
+	switch {
+	case errors.Is(err, exports.ErrInvalidSignature):
+		return fiber.ErrForbidden
+	case errors.Is(err, exports.ErrLinkExpired):
+		return fiber.ErrGone
+	case errors.Is(err, exports.ErrNotFound):
+		return fiber.ErrNotFound
+	case err != nil:
+		return fmt.Errorf("can't download export: %w", err)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Attachment("export-" + id + ".json")
+	return c.Send(content)
 }
 
 func (h *rootHandler) registerOpenAPI(router fiber.Router) {
@@ -49,11 +111,16 @@ func (h *rootHandler) registerOpenAPI(router fiber.Router) {
 	h.openapiHandler.Register(router.Group("/api/docs"), h.config.PublicHost, h.config.PublicPath)
 }
 
-func newRootHandler(cfg Config, healthHandler *healthHandler, openapiHandler *openapi.Handler) *rootHandler {
+func newRootHandler(cfg Config, healthHandler *healthHandler, openapiHandler *openapi.Handler, linksSvc *links.Service, exportsSvc *exports.Service, logger *zap.Logger) *rootHandler {
 	return &rootHandler{
 		config: cfg,
 
 		healthHandler:  healthHandler,
 		openapiHandler: openapiHandler,
+
+		linksSvc:   linksSvc,
+		exportsSvc: exportsSvc,
+
+		logger: logger,
 	}
 }
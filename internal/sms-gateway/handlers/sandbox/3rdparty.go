@@ -0,0 +1,104 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sandbox"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jaevor/go-nanoid"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// loginPrefix distinguishes the sandbox logins from the regular ones.
+const loginPrefix = "SANDBOX-"
+
+type thirdPartyControllerParams struct {
+	fx.In
+
+	SandboxSvc *sandbox.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type ThirdPartyController struct {
+	base.Handler
+
+	sandboxSvc *sandbox.Service
+
+	idGen func() string
+}
+
+type accountResponse struct {
+	// Sandbox user login
+	Login string `json:"login" example:"SANDBOX-PYDMBQ"`
+	// Sandbox user password
+	Password string `json:"password" example:"zzxymyxmwed8fzy"`
+	// ID of the virtual device
+	DeviceID string `json:"deviceId" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Recipients with this phone number ending always fail
+	FailingSuffix string `json:"failingSuffix" example:"0000"`
+}
+
+//	@Summary		Create sandbox account
+//	@Description	Creates test credentials with a single virtual device. Messages sent with these credentials are accepted and move through simulated states, webhooks are delivered by the server, but nothing reaches a real device. Recipients ending with `failingSuffix` always fail.
+//	@Description	The number of accounts a user can create is limited by the `sandbox.max_accounts` per `sandbox.accounts_window_hours` server config.
+//	@Security		ApiAuth
+//	@Tags			User, Sandbox
+//	@Produce		json
+//	@Success		201	{object}	accountResponse				"Sandbox account created"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		403	{object}	smsgateway.ErrorResponse	"Forbidden (sandbox credentials)"
+//	@Failure		429	{object}	smsgateway.ErrorResponse	"Too many sandbox accounts"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/sandbox [post]
+//
+// Create sandbox account
+func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
+	if user.Sandbox {
+		return fiber.NewError(fiber.StatusForbidden, "Sandbox credentials can't be used to create sandbox accounts")
+	}
+
+	id := h.idGen()
+	login := loginPrefix + strings.ToUpper(id[:6])
+	password := strings.ToLower(id[7:])
+
+	device, err := h.sandboxSvc.CreateAccount(c.Context(), user, login, password)
+	if errors.Is(err, sandbox.ErrTooManyAccounts) {
+		return fiber.NewError(fiber.StatusTooManyRequests, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't create sandbox account: %w", err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(accountResponse{
+		Login:         login,
+		Password:      password,
+		DeviceID:      device.ID,
+		FailingSuffix: sandbox.FailingSuffix,
+	})
+}
+
+func (h *ThirdPartyController) Register(router fiber.Router) {
+	router.Post("", userauth.WithUser(h.post))
+}
+
+func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
+	idGen, _ := nanoid.Standard(21)
+
+	return &ThirdPartyController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("sandbox"),
+			Validator: params.Validator,
+		},
+		sandboxSvc: params.SandboxSvc,
+		idGen:      idGen,
+	}
+}
@@ -1,7 +1,9 @@
 package settings
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
@@ -9,12 +11,18 @@ import (
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
+	"github.com/capcom6/go-helpers/slices"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+)
+
 type thirdPartyControllerParams struct {
 	fx.In
 
@@ -87,10 +95,12 @@ func (h *ThirdPartyController) put(user models.User, c *fiber.Ctx) error {
 }
 
 //	@Summary		Partially update settings
-//	@Description	Partially updates settings for a specific user
+//	@Description	Partially updates settings for a specific user. Supports `application/merge-patch+json` (RFC 7386, `null` removes a key) and `application/json-patch+json` (RFC 6902) in addition to plain JSON merge
 //	@Security		ApiAuth
 //	@Tags			User, Settings
 //	@Accept			json
+//	@Accept			application/merge-patch+json
+//	@Accept			application/json-patch+json
 //	@Produce		json
 //	@Param			request	body		smsgateway.DeviceSettings	true	"Settings"
 //	@Success		200		{object}	object						"Settings updated"
@@ -101,6 +111,13 @@ func (h *ThirdPartyController) put(user models.User, c *fiber.Ctx) error {
 //
 // Partially update settings
 func (h *ThirdPartyController) patch(user models.User, c *fiber.Ctx) error {
+	switch mediaType(c) {
+	case mimeMergePatch:
+		return h.mergePatch(user, c)
+	case mimeJSONPatch:
+		return h.jsonPatch(user, c)
+	}
+
 	if err := h.BodyParserValidator(c, &smsgateway.DeviceSettings{}); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid settings format: %v", err))
 	}
@@ -119,10 +136,239 @@ func (h *ThirdPartyController) patch(user models.User, c *fiber.Ctx) error {
 	return c.JSON(updated)
 }
 
+func (h *ThirdPartyController) mergePatch(user models.User, c *fiber.Ctx) error {
+	patch := make(map[string]any, 8)
+
+	if err := c.BodyParser(&patch); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Failed to parse request body: %v", err))
+	}
+
+	updated, err := h.settingsSvc.MergePatchSettings(user.ID, patch)
+	if err != nil {
+		return h.patchError(err)
+	}
+
+	return c.JSON(updated)
+}
+
+func (h *ThirdPartyController) jsonPatch(user models.User, c *fiber.Ctx) error {
+	ops := []settings.PatchOperation{}
+
+	if err := c.BodyParser(&ops); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Failed to parse request body: %v", err))
+	}
+
+	updated, err := h.settingsSvc.JSONPatchSettings(user.ID, ops)
+	if err != nil {
+		return h.patchError(err)
+	}
+
+	return c.JSON(updated)
+}
+
+func (h *ThirdPartyController) patchError(err error) error {
+	var errValidation settings.ErrValidation
+	if errors.As(err, &errValidation) {
+		return fiber.NewError(fiber.StatusBadRequest, errValidation.Error())
+	}
+
+	return fmt.Errorf("can't update settings: %w", err)
+}
+
+//	@Summary		List settings profiles
+//	@Description	Returns named settings profiles of the user with the devices and groups they are assigned to
+//	@Security		ApiAuth
+//	@Tags			User, Settings
+//	@Produce		json
+//	@Success		200	{object}	[]profileResponse			"Settings profiles"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/settings/profiles [get]
+//
+// List settings profiles
+func (h *ThirdPartyController) getProfiles(user models.User, c *fiber.Ctx) error {
+	profiles, err := h.settingsSvc.SelectProfiles(user.ID)
+	if err != nil {
+		return fmt.Errorf("can't select settings profiles: %w", err)
+	}
+
+	return c.JSON(slices.Map(profiles, profileToDTO))
+}
+
+//	@Summary		Create settings profile
+//	@Description	Creates a named settings profile. Assigned to devices or groups, the profile is applied on top of the user's settings. Sensitive settings are not applied per device and are ignored
+//	@Security		ApiAuth
+//	@Tags			User, Settings
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		profileRequest				true	"Settings profile"
+//	@Success		201		{object}	profileResponse				"Settings profile"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		409		{object}	smsgateway.ErrorResponse	"Profile with the same name exists"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/settings/profiles [post]
+//
+// Create settings profile
+func (h *ThirdPartyController) postProfile(user models.User, c *fiber.Ctx) error {
+	req := profileRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	profile, err := h.settingsSvc.CreateProfile(user.ID, req.toDomain())
+	if err != nil {
+		return h.profileError(err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(profileToDTO(profile))
+}
+
+//	@Summary		Get settings profile
+//	@Description	Returns the settings profile with the devices and groups it is assigned to
+//	@Security		ApiAuth
+//	@Tags			User, Settings
+//	@Produce		json
+//	@Param			id	path		string						true	"Profile ID"
+//	@Success		200	{object}	profileResponse				"Settings profile"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Profile not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/settings/profiles/{id} [get]
+//
+// Get settings profile
+func (h *ThirdPartyController) getProfile(user models.User, c *fiber.Ctx) error {
+	profile, err := h.settingsSvc.GetProfile(user.ID, c.Params("id"))
+	if err != nil {
+		return h.profileError(err)
+	}
+
+	return c.JSON(profileToDTO(profile))
+}
+
+//	@Summary		Replace settings profile
+//	@Description	Replaces the name and the settings of the profile. The devices the profile is applied to are notified
+//	@Security		ApiAuth
+//	@Tags			User, Settings
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string						true	"Profile ID"
+//	@Param			request	body		profileRequest				true	"Settings profile"
+//	@Success		200		{object}	profileResponse				"Settings profile"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	smsgateway.ErrorResponse	"Profile not found"
+//	@Failure		409		{object}	smsgateway.ErrorResponse	"Profile with the same name exists"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/settings/profiles/{id} [put]
+//
+// Replace settings profile
+func (h *ThirdPartyController) putProfile(user models.User, c *fiber.Ctx) error {
+	req := profileRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	profile, err := h.settingsSvc.UpdateProfile(user.ID, c.Params("id"), req.toDomain())
+	if err != nil {
+		return h.profileError(err)
+	}
+
+	return c.JSON(profileToDTO(profile))
+}
+
+//	@Summary		Delete settings profile
+//	@Description	Deletes the settings profile. The devices the profile was applied to are notified
+//	@Security		ApiAuth
+//	@Tags			User, Settings
+//	@Param			id	path	string	true	"Profile ID"
+//	@Success		204	"Successfully deleted"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Profile not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/settings/profiles/{id} [delete]
+//
+// Delete settings profile
+func (h *ThirdPartyController) deleteProfile(user models.User, c *fiber.Ctx) error {
+	if err := h.settingsSvc.DeleteProfile(user.ID, c.Params("id")); err != nil {
+		return h.profileError(err)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+//	@Summary		Assign settings profile
+//	@Description	Replaces the devices and groups the profile is assigned to. A device or a group has at most one profile, so the ones assigned to other profiles are moved to this one. The device profile takes precedence over the group profile. The devices are notified
+//	@Security		ApiAuth
+//	@Tags			User, Settings
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string						true	"Profile ID"
+//	@Param			request	body		profileAssignmentsRequest	true	"Assignments"
+//	@Success		200		{object}	profileResponse				"Settings profile"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404		{object}	smsgateway.ErrorResponse	"Profile not found"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/settings/profiles/{id}/assignments [put]
+//
+// Assign settings profile
+func (h *ThirdPartyController) putProfileAssignments(user models.User, c *fiber.Ctx) error {
+	req := profileAssignmentsRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	if len(req.Devices) > 0 {
+		items, err := h.devicesSvc.Select(user.ID)
+		if err != nil {
+			return fmt.Errorf("can't select devices: %w", err)
+		}
+
+		known := make(map[string]struct{}, len(items))
+		for _, device := range items {
+			known[device.ID] = struct{}{}
+		}
+		for _, id := range req.Devices {
+			if _, ok := known[id]; !ok {
+				return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Device %s not found", id))
+			}
+		}
+	}
+
+	profile, err := h.settingsSvc.AssignProfile(user.ID, c.Params("id"), req.Devices, req.Groups)
+	if err != nil {
+		return h.profileError(err)
+	}
+
+	return c.JSON(profileToDTO(profile))
+}
+
+func (h *ThirdPartyController) profileError(err error) error {
+	var errValidation settings.ErrValidation
+	switch {
+	case errors.As(err, &errValidation):
+		return fiber.NewError(fiber.StatusBadRequest, errValidation.Error())
+	case errors.Is(err, settings.ErrProfileNotFound):
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	case errors.Is(err, settings.ErrProfileExists):
+		return fiber.NewError(fiber.StatusConflict, err.Error())
+	}
+
+	return fmt.Errorf("can't process settings profile: %w", err)
+}
+
 func (h *ThirdPartyController) Register(app fiber.Router) {
 	app.Get("", userauth.WithUser(h.get))
 	app.Patch("", userauth.WithUser(h.patch))
 	app.Put("", userauth.WithUser(h.put))
+
+	app.Get("profiles", userauth.WithUser(h.getProfiles))
+	app.Post("profiles", userauth.WithUser(h.postProfile))
+	app.Get("profiles/:id", userauth.WithUser(h.getProfile))
+	app.Put("profiles/:id", userauth.WithUser(h.putProfile))
+	app.Delete("profiles/:id", userauth.WithUser(h.deleteProfile))
+	app.Put("profiles/:id/assignments", userauth.WithUser(h.putProfileAssignments))
 }
 
 func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
@@ -135,3 +381,9 @@ func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyContr
 		settingsSvc: params.SettingsSvc,
 	}
 }
+
+// mediaType returns the request content type without parameters.
+func mediaType(c *fiber.Ctx) string {
+	ctype, _, _ := strings.Cut(c.Get(fiber.HeaderContentType), ";")
+	return strings.ToLower(strings.TrimSpace(ctype))
+}
@@ -0,0 +1,59 @@
+package settings
+
+import (
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
+)
+
+type profileRequest struct {
+	// Profile name, unique for the user
+	Name string `json:"name" validate:"required,max=64" example:"Warehouse"`
+	// Settings applied on top of the user's settings, sensitive settings are ignored
+	Settings map[string]any `json:"settings"`
+}
+
+func (r profileRequest) toDomain() settings.ProfileIn {
+	return settings.ProfileIn{
+		Name:     r.Name,
+		Settings: r.Settings,
+	}
+}
+
+type profileAssignmentsRequest struct {
+	// IDs of the devices the profile is assigned to
+	Devices []string `json:"devices" validate:"max=1000,dive,nanoid" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Groups of the devices the profile is assigned to
+	Groups []string `json:"groups" validate:"max=100,dive,required,max=64" example:"berlin"`
+}
+
+type profileResponse struct {
+	// Profile ID
+	ID string `json:"id" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Profile name
+	Name string `json:"name" example:"Warehouse"`
+	// Settings applied on top of the user's settings
+	Settings map[string]any `json:"settings"`
+	// IDs of the devices the profile is assigned to
+	Devices []string `json:"devices" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Groups of the devices the profile is assigned to
+	Groups []string `json:"groups" example:"berlin"`
+	// Created at
+	CreatedAt time.Time `json:"createdAt" example:"2025-10-16T12:00:00Z"`
+	// Updated at
+	UpdatedAt time.Time `json:"updatedAt" example:"2025-10-16T12:00:00Z"`
+}
+
+func profileToDTO(p settings.Profile) profileResponse {
+	deviceIDs, groups := p.Targets()
+
+	return profileResponse{
+		ID:        p.ID,
+		Name:      p.Name,
+		Settings:  p.Settings,
+		Devices:   deviceIDs,
+		Groups:    groups,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
@@ -30,7 +30,7 @@ type MobileController struct {
 }
 
 //	@Summary		Get settings
-//	@Description	Returns settings for a device
+//	@Description	Returns settings for a device, with the settings profiles of the device applied
 //	@Security		MobileToken
 //	@Tags			Device, Settings
 //	@Produce		json
@@ -41,7 +41,7 @@ type MobileController struct {
 //
 // Get settings
 func (h *MobileController) get(device models.Device, c *fiber.Ctx) error {
-	settings, err := h.settingsSvc.GetSettings(device.UserID, false)
+	settings, err := h.settingsSvc.GetDeviceSettings(device)
 	if err != nil {
 		return fmt.Errorf("can't get settings for device %s (user ID: %s): %w", device.ID, device.UserID, err)
 	}
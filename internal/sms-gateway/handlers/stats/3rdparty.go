@@ -0,0 +1,211 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/stats"
+	"github.com/capcom6/go-helpers/slices"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// defaultRangeDays is the number of days returned if `from` is not set.
+const defaultRangeDays = 30
+
+type thirdPartyControllerParams struct {
+	fx.In
+
+	StatsSvc *stats.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type ThirdPartyController struct {
+	base.Handler
+
+	statsSvc *stats.Service
+}
+
+type dailyQueryParams struct {
+	From string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To   string `query:"to"   validate:"omitempty,datetime=2006-01-02"`
+}
+
+func (p *dailyQueryParams) Range() (time.Time, time.Time) {
+	to := time.Now().UTC()
+	if p.To != "" {
+		to, _ = time.Parse(time.DateOnly, p.To)
+	}
+
+	from := to.AddDate(0, 0, -(defaultRangeDays - 1))
+	if p.From != "" {
+		from, _ = time.Parse(time.DateOnly, p.From)
+	}
+
+	return from, to
+}
+
+type messagesStats struct {
+	// Number of messages created during the day
+	Total uint64 `json:"total" example:"10"`
+	// Number of messages in Pending state
+	Pending uint64 `json:"pending" example:"0"`
+	// Number of messages in Processed state
+	Processed uint64 `json:"processed" example:"1"`
+	// Number of messages in Sent state
+	Sent uint64 `json:"sent" example:"2"`
+	// Number of messages in Delivered state
+	Delivered uint64 `json:"delivered" example:"6"`
+	// Number of messages in Failed state
+	Failed uint64 `json:"failed" example:"1"`
+}
+
+type webhooksStats struct {
+	// Number of successfully delivered webhooks
+	Delivered uint64 `json:"delivered" example:"3"`
+	// Number of failed webhooks
+	Failed uint64 `json:"failed" example:"1"`
+	// Share of successfully delivered webhooks, omitted if there were no deliveries
+	SuccessRate *float64 `json:"successRate,omitempty" example:"0.75"`
+}
+
+type dailyStats struct {
+	// Day in UTC
+	Date string `json:"date" example:"2025-10-16"`
+	// Messages created during the day by state
+	Messages messagesStats `json:"messages"`
+	// Number of devices seen during the day
+	ActiveDevices uint64 `json:"activeDevices" example:"2"`
+	// Webhooks delivered by the server, the webhooks delivered by the devices directly aren't counted
+	ServerWebhooks webhooksStats `json:"serverWebhooks"`
+}
+
+//	@Summary		Get daily stats
+//	@Description	Returns daily aggregates of messages by state, active devices and webhook deliveries by the server. The webhooks delivered by the devices directly aren't reported to the server and aren't counted. Days without activity are omitted. The range is limited to 366 days.
+//	@Security		ApiAuth
+//	@Tags			User, Stats
+//	@Produce		json
+//	@Param			from	query		string						false	"First day of the range, 29 days before `to` by default"	Format(date)
+//	@Param			to		query		string						false	"Last day of the range, today by default"					Format(date)
+//	@Success		200		{object}	[]dailyStats				"Daily stats"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/stats/daily [get]
+//
+// Get daily stats
+func (h *ThirdPartyController) getDaily(user models.User, c *fiber.Ctx) error {
+	params := dailyQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	from, to := params.Range()
+	items, err := h.statsSvc.SelectDaily(user.ID, from, to)
+	if errors.Is(err, stats.ErrInvalidRange) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't select stats: %w", err)
+	}
+
+	return c.JSON(slices.Map(items, dailyStatsToDTO))
+}
+
+type countryStats struct {
+	// Day in UTC
+	Date string `json:"date" example:"2025-10-16"`
+	// Detected country of the recipients, ISO 3166-1 alpha-2, empty if unknown
+	Country string `json:"country" example:"DE"`
+	// Number of recipients
+	Total uint64 `json:"total" example:"10"`
+	// Number of recipients in Delivered state
+	Delivered uint64 `json:"delivered" example:"8"`
+	// Number of recipients in Failed state
+	Failed uint64 `json:"failed" example:"1"`
+}
+
+//	@Summary		Get daily stats by country
+//	@Description	Returns daily aggregates of recipients grouped by the country detected from the phone number. Days without activity are omitted. The range is limited to 366 days.
+//	@Security		ApiAuth
+//	@Tags			User, Stats
+//	@Produce		json
+//	@Param			from	query		string						false	"First day of the range, 29 days before `to` by default"	Format(date)
+//	@Param			to		query		string						false	"Last day of the range, today by default"					Format(date)
+//	@Success		200		{object}	[]countryStats				"Daily stats by country"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/stats/daily/countries [get]
+//
+// Get daily stats by country
+func (h *ThirdPartyController) getDailyCountries(user models.User, c *fiber.Ctx) error {
+	params := dailyQueryParams{}
+	if err := h.QueryParserValidator(c, &params); err != nil {
+		return err
+	}
+
+	from, to := params.Range()
+	items, err := h.statsSvc.SelectDailyByCountry(user.ID, from, to)
+	if errors.Is(err, stats.ErrInvalidRange) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't select stats: %w", err)
+	}
+
+	return c.JSON(slices.Map(items, countryStatsToDTO))
+}
+
+func countryStatsToDTO(s stats.DailyCountryStats) countryStats {
+	return countryStats{
+		Date:      s.Date.Format(time.DateOnly),
+		Country:   s.Country,
+		Total:     s.RecipientsTotal,
+		Delivered: s.RecipientsDelivered,
+		Failed:    s.RecipientsFailed,
+	}
+}
+
+func dailyStatsToDTO(s stats.DailyStats) dailyStats {
+	return dailyStats{
+		Date: s.Date.Format(time.DateOnly),
+		Messages: messagesStats{
+			Total:     s.MessagesTotal,
+			Pending:   s.MessagesPending,
+			Processed: s.MessagesProcessed,
+			Sent:      s.MessagesSent,
+			Delivered: s.MessagesDelivered,
+			Failed:    s.MessagesFailed,
+		},
+		ActiveDevices: s.ActiveDevices,
+		ServerWebhooks: webhooksStats{
+			Delivered:   s.WebhooksDelivered,
+			Failed:      s.WebhooksFailed,
+			SuccessRate: s.WebhooksSuccessRate(),
+		},
+	}
+}
+
+func (h *ThirdPartyController) Register(router fiber.Router) {
+	router.Get("daily", userauth.WithUser(h.getDaily))
+	router.Get("daily/countries", userauth.WithUser(h.getDailyCountries))
+}
+
+func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
+	return &ThirdPartyController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("stats"),
+			Validator: params.Validator,
+		},
+		statsSvc: params.StatsSvc,
+	}
+}
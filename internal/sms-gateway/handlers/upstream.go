@@ -1,19 +1,60 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"fmt"
 	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push"
+	pushtypes "github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
 	"github.com/capcom6/go-helpers/anys"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/keyauth"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	// upstreamMaxBatchSize is the max number of notifications in a single push request.
+	upstreamMaxBatchSize = 500
+	// upstreamDefaultRateLimit is the default number of requests per minute per instance.
+	upstreamDefaultRateLimit = 5
+)
+
+type upstreamPushRejection struct {
+	// Device push token
+	Token string `json:"token" example:"eTxx88nfSla87gZuJcW5mS"`
+	// Rejection reason
+	Reason string `json:"reason" example:"unregistered"`
+}
+
+type upstreamPushResponse struct {
+	// Number of accepted notifications
+	Accepted int `json:"accepted" example:"1"`
+	// Rejected notifications
+	Rejected []upstreamPushRejection `json:"rejected"`
+}
+
+type upstreamResultsRequest struct {
+	// Device push tokens
+	Tokens []string `json:"tokens" validate:"required,min=1,max=500,dive,required" example:"eTxx88nfSla87gZuJcW5mS"`
+}
+
+type upstreamPushResult struct {
+	// Device push token
+	Token string `json:"token" example:"eTxx88nfSla87gZuJcW5mS"`
+	// Delivery status: pending, sent, failed, blacklisted or unknown
+	Status string `json:"status" example:"sent"`
+	// Failure reason
+	Reason string `json:"reason,omitempty" example:"unregistered"`
+	// Time of the last status change
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" example:"2025-10-16T12:00:00Z"`
+}
+
 type upstreamHandler struct {
 	base.Handler
 
@@ -40,15 +81,17 @@ func newUpstreamHandler(params upstreamHandlerParams) *upstreamHandler {
 }
 
 //	@Summary		Send push notifications
-//	@Description	Enqueues notifications for sending to devices
+//	@Description	Enqueues notifications for sending to devices. Notifications for blacklisted tokens are rejected
+//	@Security		UpstreamKey
 //	@Tags			Upstream
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body	smsgateway.UpstreamPushRequest	true	"Push request"
-//	@Success		202		"Notification enqueued"
-//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
-//	@Failure		429		{object}	smsgateway.ErrorResponse	"Too many requests"
-//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Param			request	body		smsgateway.UpstreamPushRequest	true	"Push request"
+//	@Success		202		{object}	upstreamPushResponse			"Notification enqueued"
+//	@Failure		400		{object}	smsgateway.ErrorResponse		"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse		"Unauthorized"
+//	@Failure		429		{object}	smsgateway.ErrorResponse		"Too many requests"
+//	@Failure		500		{object}	smsgateway.ErrorResponse		"Internal server error"
 //	@Router			/upstream/v1/push [post]
 //
 // Send push notifications
@@ -63,11 +106,20 @@ func (h *upstreamHandler) postPush(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "Empty request")
 	}
 
+	if len(req) > upstreamMaxBatchSize {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Too many notifications, max %d per request", upstreamMaxBatchSize))
+	}
+
 	for _, v := range req {
 		if err := h.ValidateStruct(v); err != nil {
 			return err
 		}
+	}
 
+	res := upstreamPushResponse{
+		Rejected: []upstreamPushRejection{},
+	}
+	for _, v := range req {
 		event := push.Event{
 			Type: anys.ZeroDefault(v.Event, smsgateway.PushMessageEnqueued),
 			Data: v.Data,
@@ -75,18 +127,82 @@ func (h *upstreamHandler) postPush(c *fiber.Ctx) error {
 
 		if err := h.pushSvc.Enqueue(v.Token, event); err != nil {
 			h.Logger.Error("Can't push message", zap.Error(err))
+			res.Rejected = append(res.Rejected, upstreamPushRejection{Token: v.Token, Reason: string(pushtypes.ErrorReasonInternal)})
+			continue
+		}
+		h.pushSvc.Claim(upstreamCaller(c), v.Token)
+
+		if result, ok := h.pushSvc.Results([]string{v.Token})[v.Token]; ok && result.Status == push.DeliveryStatusBlacklisted {
+			res.Rejected = append(res.Rejected, upstreamPushRejection{Token: v.Token, Reason: string(result.Reason)})
+			continue
 		}
+
+		res.Accepted++
 	}
 
-	return c.SendStatus(fiber.StatusAccepted)
+	return c.Status(fiber.StatusAccepted).JSON(res)
+}
+
+//	@Summary		Get delivery results
+//	@Description	Returns the outcome of the last notification for each token pushed by the caller, identified by the instance key or by IP for anonymous access. Other tokens are unknown. Results are kept for an hour
+//	@Security		UpstreamKey
+//	@Tags			Upstream
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		upstreamResultsRequest		true	"Results request"
+//	@Success		200		{object}	[]upstreamPushResult		"Delivery results"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		429		{object}	smsgateway.ErrorResponse	"Too many requests"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/upstream/v1/push/results [post]
+//
+// Get delivery results
+func (h *upstreamHandler) postResults(c *fiber.Ctx) error {
+	req := upstreamResultsRequest{}
+
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	results := h.pushSvc.ResultsOf(upstreamCaller(c), req.Tokens)
+
+	res := make([]upstreamPushResult, 0, len(req.Tokens))
+	for _, token := range req.Tokens {
+		result, ok := results[token]
+		if !ok {
+			res = append(res, upstreamPushResult{Token: token, Status: "unknown"})
+			continue
+		}
+
+		res = append(res, upstreamPushResult{
+			Token:     token,
+			Status:    string(result.Status),
+			Reason:    string(result.Reason),
+			UpdatedAt: &result.UpdatedAt,
+		})
+	}
+
+	return c.JSON(res)
+}
+
+// authorize validates the instance key if upstream keys are configured.
+func (h *upstreamHandler) authorize(c *fiber.Ctx, key string) (bool, error) {
+	for _, k := range h.config.UpstreamKeys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return true, nil
+		}
+	}
+
+	return false, keyauth.ErrMissingOrMalformedAPIKey
 }
 
 // Register registers upstream handlers with the given router.
 //
 // If upstream is disabled in the configuration, this function does nothing.
 //
-// It registers a single handler for sending push notifications to
-// /upstream/v1/push, with a rate limiter of 5 requests per minute.
+// Requests are authenticated with instance keys when they are configured and
+// rate limited per instance key or per IP for anonymous access.
 func (h *upstreamHandler) Register(router fiber.Router) {
 	if !h.config.UpstreamEnabled {
 		return
@@ -94,9 +210,38 @@ func (h *upstreamHandler) Register(router fiber.Router) {
 
 	router = router.Group("/upstream/v1")
 
-	router.Post("/push", limiter.New(limiter.Config{
-		Max:               5,
+	router.Use(keyauth.New(keyauth.Config{
+		Next: func(c *fiber.Ctx) bool {
+			return len(h.config.UpstreamKeys) == 0
+		},
+		Validator: h.authorize,
+	}))
+
+	router.Post("/push", h.newLimiter(), h.postPush)
+	router.Post("/push/results", h.newLimiter(), h.postResults)
+}
+
+// newLimiter creates a rate limiter keyed by the instance key or by IP for
+// anonymous access.
+func (h *upstreamHandler) newLimiter() fiber.Handler {
+	rateLimit := h.config.UpstreamRateLimit
+	if rateLimit <= 0 {
+		rateLimit = upstreamDefaultRateLimit
+	}
+
+	return limiter.New(limiter.Config{
+		Max:               rateLimit,
 		Expiration:        60 * time.Second,
+		KeyGenerator:      upstreamCaller,
 		LimiterMiddleware: limiter.SlidingWindow{},
-	}), h.postPush)
+	})
+}
+
+// upstreamCaller identifies the caller by the instance key or by IP for
+// anonymous access.
+func upstreamCaller(c *fiber.Ctx) string {
+	if key, ok := c.Locals("token").(string); ok && key != "" {
+		return "key:" + key
+	}
+	return c.IP()
 }
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUpstreamCaller(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		if key := c.Get("X-Key"); key != "" {
+			c.Locals("token", key)
+		}
+		return c.SendString(upstreamCaller(c))
+	})
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "instance key", key: "secret", want: "key:secret"},
+		{name: "anonymous", want: "0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.key != "" {
+				req.Header.Set("X-Key", tt.key)
+			}
+
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != tt.want {
+				t.Errorf("upstreamCaller() = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
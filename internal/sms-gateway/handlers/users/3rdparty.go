@@ -0,0 +1,117 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type thirdPartyControllerParams struct {
+	fx.In
+
+	AuthSvc *auth.Service
+
+	Validator *validator.Validate
+	Logger    *zap.Logger
+}
+
+type ThirdPartyController struct {
+	base.Handler
+
+	authSvc *auth.Service
+}
+
+type userResponse struct {
+	// User ID
+	ID string `json:"id" example:"RZ2FJK"`
+	// IANA time zone the date-only filters are interpreted in
+	Timezone string `json:"timezone" example:"Europe/Berlin"`
+}
+
+type userPatchRequest struct {
+	// IANA time zone the date-only filters are interpreted in
+	Timezone *string `json:"timezone,omitempty" validate:"omitempty,timezone" example:"Europe/Berlin"`
+}
+
+//	@Summary		Get user
+//	@Description	Returns the account of the user
+//	@Security		ApiAuth
+//	@Tags			User
+//	@Produce		json
+//	@Success		200	{object}	userResponse				"User"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/user [get]
+//
+// Get user
+func (h *ThirdPartyController) get(user models.User, c *fiber.Ctx) error {
+	current, err := h.authSvc.GetUser(user.ID)
+	if err != nil {
+		return fmt.Errorf("can't get user: %w", err)
+	}
+
+	return c.JSON(userToDTO(current))
+}
+
+//	@Summary		Update user
+//	@Description	Updates the account of the user, only the provided fields are changed
+//	@Security		ApiAuth
+//	@Tags			User
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		userPatchRequest			true	"User update"
+//	@Success		200		{object}	userResponse				"User"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/user [patch]
+//
+// Update user
+func (h *ThirdPartyController) patch(user models.User, c *fiber.Ctx) error {
+	req := userPatchRequest{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	if req.Timezone != nil {
+		err := h.authSvc.SetTimezone(user.ID, *req.Timezone)
+		if errors.Is(err, auth.ErrInvalidTimezone) {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		if err != nil {
+			return fmt.Errorf("can't set timezone: %w", err)
+		}
+	}
+
+	return h.get(user, c)
+}
+
+func (h *ThirdPartyController) Register(router fiber.Router) {
+	router.Get("", userauth.WithUser(h.get))
+	router.Patch("", userauth.WithUser(h.patch))
+}
+
+func userToDTO(user models.User) userResponse {
+	return userResponse{
+		ID:       user.ID,
+		Timezone: user.Timezone,
+	}
+}
+
+func NewThirdPartyController(params thirdPartyControllerParams) *ThirdPartyController {
+	return &ThirdPartyController{
+		Handler: base.Handler{
+			Logger:    params.Logger.Named("users"),
+			Validator: params.Validator,
+		},
+		authSvc: params.AuthSvc,
+	}
+}
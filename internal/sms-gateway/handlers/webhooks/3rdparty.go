@@ -1,9 +1,9 @@
 package webhooks
 
 import (
+	"errors"
 	"fmt"
 
-	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/userauth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
@@ -34,7 +34,7 @@ type ThirdPartyController struct {
 //	@Security		ApiAuth
 //	@Tags			User, Webhooks
 //	@Produce		json
-//	@Success		200	{object}	[]smsgateway.Webhook		"Webhook list"
+//	@Success		200	{object}	[]webhooks.WebhookDTO		"Webhook list"
 //	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
 //	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
 //	@Router			/3rdparty/v1/webhooks [get]
@@ -50,13 +50,13 @@ func (h *ThirdPartyController) get(user models.User, c *fiber.Ctx) error {
 }
 
 //	@Summary		Register webhook
-//	@Description	Registers webhook. If webhook with same ID already exists, it will be replaced
+//	@Description	Registers webhook. If webhook with same ID already exists, it will be replaced. If `verify` is set, the server sends a `webhook:verification` request with a `challenge` to the URL and registers the webhook only if the endpoint responds with the challenge as a plain text body or as the `challenge` field of a JSON object. If `batch` is set, the state change events are combined per message (or for the whole webhook with `groupBy: none`) into a single request with the `events` array in the payload, sent when the window ends or `size` events are collected. Only the events delivered by the server are batched, the devices deliver their events one by one. If `filter` is set for `sms:received`, the server delivers only the incoming messages from the listed devices, senders and with the keywords, the devices report the messages to the server instead of delivering them. The filtered webhooks don't fire for the devices with the app versions which don't report the incoming messages. If `format` is `flat`, the payload is sent as a single level object; the server flattens the events it delivers, including the filtered `sms:received` ones, while the webhooks delivered by the devices are flattened only by the app versions which support it
 //	@Security		ApiAuth
 //	@Tags			User, Webhooks
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		smsgateway.Webhook			true	"Webhook"
-//	@Success		201		{object}	smsgateway.Webhook			"Created"
+//	@Param			request	body		webhooks.WebhookDTO			true	"Webhook"
+//	@Success		201		{object}	webhooks.WebhookDTO			"Created"
 //	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
 //	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
 //	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
@@ -64,13 +64,14 @@ func (h *ThirdPartyController) get(user models.User, c *fiber.Ctx) error {
 //
 // Register webhook
 func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
-	dto := smsgateway.Webhook{}
+	dto := webhooks.WebhookDTO{}
 
 	if err := h.BodyParserValidator(c, &dto); err != nil {
 		return err
 	}
 
-	if err := h.webhooksSvc.Replace(user.ID, dto); err != nil {
+	webhook, err := h.webhooksSvc.Replace(c.Context(), user.ID, dto)
+	if err != nil {
 		if webhooks.IsValidationError(err) {
 			return fiber.NewError(fiber.StatusBadRequest, err.Error())
 		}
@@ -78,7 +79,7 @@ func (h *ThirdPartyController) post(user models.User, c *fiber.Ctx) error {
 		return fmt.Errorf("can't write webhook: %w", err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(dto)
+	return c.Status(fiber.StatusCreated).JSON(webhook)
 }
 
 //	@Summary		Delete webhook
@@ -103,9 +104,35 @@ func (h *ThirdPartyController) delete(user models.User, c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
+//	@Summary		Get webhook circuit state
+//	@Description	Returns the circuit breaker state of the webhook. The server holds the events of the webhook while the circuit is open, i.e. after 5 consecutive failed deliveries, and delivers a single event to check the endpoint when `nextRetryAt` comes. The circuit is closed on success and the held events are delivered, otherwise it's opened again for twice as long, up to an hour. Up to 100 events are held, the oldest ones are dropped and counted as failed. The circuits apply to the events delivered by the server only, the webhooks delivered by the devices are reported as `unknown`. The state is kept per server instance
+//	@Security		ApiAuth
+//	@Tags			User, Webhooks
+//	@Produce		json
+//	@Param			id	path		string						true	"Webhook ID"
+//	@Success		200	{object}	webhooks.Circuit			"Circuit state"
+//	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		404	{object}	smsgateway.ErrorResponse	"Webhook not found"
+//	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Router			/3rdparty/v1/webhooks/{id}/circuit [get]
+//
+// Get webhook circuit state
+func (h *ThirdPartyController) getCircuit(user models.User, c *fiber.Ctx) error {
+	circuit, err := h.webhooksSvc.GetCircuit(user.ID, c.Params("id"))
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return fmt.Errorf("can't get webhook circuit: %w", err)
+	}
+
+	return c.JSON(circuit)
+}
+
 func (h *ThirdPartyController) Register(router fiber.Router) {
 	router.Get("", userauth.WithUser(h.get))
 	router.Post("", userauth.WithUser(h.post))
+	router.Get("/:id/circuit", userauth.WithUser(h.getCircuit))
 	router.Delete("/:id", userauth.WithUser(h.delete))
 }
 
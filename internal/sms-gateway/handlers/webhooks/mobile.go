@@ -1,12 +1,14 @@
 package webhooks
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/base"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/handlers/middlewares/deviceauth"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -17,7 +19,8 @@ type mobileControllerParams struct {
 
 	WebhooksServices *webhooks.Service
 
-	Logger *zap.Logger
+	Validator *validator.Validate
+	Logger    *zap.Logger
 }
 
 type MobileController struct {
@@ -27,18 +30,23 @@ type MobileController struct {
 }
 
 //	@Summary		List webhooks
-//	@Description	Returns list of registered webhooks for device
+//	@Description	Returns list of registered webhooks for device. The filtered `sms:received` webhooks are delivered by the server and aren't listed
 //	@Security		MobileToken
 //	@Tags			Device, Webhooks
 //	@Produce		json
-//	@Success		200	{object}	[]smsgateway.Webhook		"Webhook list"
+//	@Success		200	{object}	[]webhooks.WebhookDTO		"Webhook list"
 //	@Failure		401	{object}	smsgateway.ErrorResponse	"Unauthorized"
 //	@Failure		500	{object}	smsgateway.ErrorResponse	"Internal server error"
 //	@Router			/mobile/v1/webhooks [get]
 //
 // List webhooks
 func (h *MobileController) get(device models.Device, c *fiber.Ctx) error {
-	items, err := h.webhooksSvc.Select(device.UserID, webhooks.WithDeviceID(device.ID, false))
+	items, err := h.webhooksSvc.Select(
+		device.UserID,
+		webhooks.WithDeviceID(device.ID, false),
+		webhooks.WithoutEvents(webhooks.ServerEvents...),
+		webhooks.WithFiltered(false),
+	)
 	if err != nil {
 		return fmt.Errorf("can't select webhooks: %w", err)
 	}
@@ -46,14 +54,47 @@ func (h *MobileController) get(device models.Device, c *fiber.Ctx) error {
 	return c.JSON(items)
 }
 
+//	@Summary		Report incoming message
+//	@Description	Reports the SMS received by the device, so the server delivers it to the filtered `sms:received` webhooks and applies the auto-reply rules of the user. The webhooks without filter are delivered by the device. The repeated report of the same `messageId` is accepted without delivery
+//	@Security		MobileToken
+//	@Tags			Device, Webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		webhooks.IncomingMessage	true	"Incoming message"
+//	@Success		202		{object}	object						"Accepted"
+//	@Failure		400		{object}	smsgateway.ErrorResponse	"Invalid request"
+//	@Failure		401		{object}	smsgateway.ErrorResponse	"Unauthorized"
+//	@Failure		500		{object}	smsgateway.ErrorResponse	"Internal server error"
+//	@Failure		503		{object}	smsgateway.ErrorResponse	"Too many incoming messages, retry later"
+//	@Router			/mobile/v1/webhooks/received [post]
+//
+// Report incoming message
+func (h *MobileController) postReceived(device models.Device, c *fiber.Ctx) error {
+	req := webhooks.IncomingMessage{}
+	if err := h.BodyParserValidator(c, &req); err != nil {
+		return err
+	}
+
+	if err := h.webhooksSvc.Receive(c.Context(), device, req); err != nil {
+		if errors.Is(err, webhooks.ErrReceiveBusy) {
+			return fiber.NewError(fiber.StatusServiceUnavailable, err.Error())
+		}
+		return fmt.Errorf("can't receive message: %w", err)
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
+
 func (h *MobileController) Register(router fiber.Router) {
 	router.Get("", deviceauth.WithDevice(h.get))
+	router.Post("/received", deviceauth.WithDevice(h.postReceived))
 }
 
 func NewMobileController(params mobileControllerParams) *MobileController {
 	return &MobileController{
 		Handler: base.Handler{
-			Logger: params.Logger.Named("webhooks"),
+			Logger:    params.Logger.Named("webhooks"),
+			Validator: params.Validator,
 		},
 		webhooksSvc: params.WebhooksServices,
 	}
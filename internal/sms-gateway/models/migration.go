@@ -2,6 +2,10 @@ package models
 
 import (
 	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -9,6 +13,31 @@ import (
 //go:embed migrations
 var migrations embed.FS
 
+// LatestMigration returns the version of the newest embedded migration for
+// the dialect.
+func LatestMigration(dialect string) (int64, error) {
+	entries, err := fs.ReadDir(migrations, "migrations/"+dialect)
+	if err != nil {
+		return 0, fmt.Errorf("can't read migrations: %w", err)
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		latest = max(latest, version)
+	}
+
+	return latest, nil
+}
+
 func Migrate(db *gorm.DB) error {
 	return db.AutoMigrate(&User{}, &Device{})
 }
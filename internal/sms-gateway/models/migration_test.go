@@ -0,0 +1,22 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+func TestLatestMigration(t *testing.T) {
+	latest, err := models.LatestMigration("mysql")
+	if err != nil {
+		t.Fatalf("LatestMigration() error = %v", err)
+	}
+
+	if latest < 20221007133357 {
+		t.Errorf("LatestMigration() = %d, expected at least the initial migration", latest)
+	}
+
+	if _, err := models.LatestMigration("unknown"); err == nil {
+		t.Error("LatestMigration() expected error for unknown dialect")
+	}
+}
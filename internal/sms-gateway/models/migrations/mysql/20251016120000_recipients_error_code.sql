@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `message_recipients`
+ADD `error_code` varchar(32) NULL;
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `message_recipients` DROP `error_code`;
+-- +goose StatementEnd
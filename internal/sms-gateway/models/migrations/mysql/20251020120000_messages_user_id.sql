@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `messages`
+ADD `user_id` varchar(32) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE `messages` m
+    JOIN `devices` d ON m.`device_id` = d.`id`
+SET m.`user_id` = d.`user_id`;
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `messages` DROP `user_id`;
+-- +goose StatementEnd
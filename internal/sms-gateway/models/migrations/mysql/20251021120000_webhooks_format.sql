@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `webhooks`
+ADD `format` varchar(16) NOT NULL DEFAULT 'default';
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `webhooks` DROP `format`;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `message_states`
+ADD `device_updated_at` datetime(3) NULL,
+ADD `received_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `message_states`
+DROP `received_at`,
+DROP `device_updated_at`;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `devices`
+ADD `event_subscriptions` json NULL;
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `devices` DROP `event_subscriptions`;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `webhooks`
+ADD `verified_at` datetime(3) NULL;
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `webhooks` DROP `verified_at`;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `crash_reports` (
+    `id` BIGINT UNSIGNED AUTO_INCREMENT,
+    `stack_hash` char(64) NOT NULL,
+    `app_version` varchar(32) NOT NULL,
+    `kind` varchar(8) NOT NULL,
+    `exception` varchar(256) NOT NULL,
+    `message` varchar(1024) NOT NULL,
+    `stack_trace` text NOT NULL,
+    `count` bigint unsigned NOT NULL DEFAULT 1,
+    `last_device_id` varchar(21) NOT NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_crash_reports_hash_version` (`stack_hash`, `app_version`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `crash_reports`;
+-- +goose StatementEnd
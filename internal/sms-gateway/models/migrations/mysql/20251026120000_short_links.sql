@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `short_links` (
+    `id` BIGINT UNSIGNED AUTO_INCREMENT,
+    `token` char(10) NOT NULL,
+    `user_id` varchar(32) NOT NULL,
+    `message_id` varchar(36) NOT NULL,
+    `url` varchar(2048) NOT NULL,
+    `clicks` bigint unsigned NOT NULL DEFAULT 0,
+    `first_click_at` datetime(3) NULL,
+    `last_click_at` datetime(3) NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_short_links_token` (`token`),
+    INDEX `idx_short_links_user_message` (`user_id`, `message_id`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `short_links`;
+-- +goose StatementEnd
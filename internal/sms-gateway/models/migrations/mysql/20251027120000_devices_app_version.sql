@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `devices`
+ADD `app_version` varchar(32) NULL;
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `devices` DROP `app_version`;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `stats_daily` (
+    `user_id` varchar(32) NOT NULL,
+    `date` date NOT NULL,
+    `messages_total` bigint unsigned NOT NULL DEFAULT 0,
+    `messages_pending` bigint unsigned NOT NULL DEFAULT 0,
+    `messages_processed` bigint unsigned NOT NULL DEFAULT 0,
+    `messages_sent` bigint unsigned NOT NULL DEFAULT 0,
+    `messages_delivered` bigint unsigned NOT NULL DEFAULT 0,
+    `messages_failed` bigint unsigned NOT NULL DEFAULT 0,
+    `active_devices` bigint unsigned NOT NULL DEFAULT 0,
+    `webhooks_delivered` bigint unsigned NOT NULL DEFAULT 0,
+    `webhooks_failed` bigint unsigned NOT NULL DEFAULT 0,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`user_id`, `date`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `stats_daily`;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `device_sims` (
+    `id` BIGINT UNSIGNED AUTO_INCREMENT,
+    `device_id` char(21) NOT NULL,
+    `sim_number` tinyint(1) unsigned NOT NULL,
+    `user_id` varchar(32) NOT NULL,
+    `operator` varchar(64) NULL,
+    `balance` decimal(12, 2) NULL,
+    `currency` varchar(8) NULL,
+    `plan` varchar(128) NULL,
+    `expires_at` datetime(3) NULL,
+    `reported_at` datetime(3) NOT NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_device_sims_device_sim` (`device_id`, `sim_number`),
+    INDEX `idx_device_sims_user` (`user_id`),
+    CONSTRAINT `fk_device_sims_device` FOREIGN KEY (`device_id`) REFERENCES `devices` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `device_sims`;
+-- +goose StatementEnd
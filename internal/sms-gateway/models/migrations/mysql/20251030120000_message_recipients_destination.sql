@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `message_recipients`
+ADD `country` char(2) NULL,
+ADD `carrier` varchar(64) NULL;
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `message_recipients`
+DROP `carrier`,
+DROP `country`;
+-- +goose StatementEnd
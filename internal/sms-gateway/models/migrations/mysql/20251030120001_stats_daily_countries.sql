@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `stats_daily_countries` (
+    `user_id` varchar(32) NOT NULL,
+    `date` date NOT NULL,
+    `country` char(2) NOT NULL,
+    `recipients_total` bigint unsigned NOT NULL DEFAULT 0,
+    `recipients_delivered` bigint unsigned NOT NULL DEFAULT 0,
+    `recipients_failed` bigint unsigned NOT NULL DEFAULT 0,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`user_id`, `date`, `country`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `stats_daily_countries`;
+-- +goose StatementEnd
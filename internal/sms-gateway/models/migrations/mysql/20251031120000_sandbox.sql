@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `users`
+ADD `sandbox` tinyint(1) unsigned NOT NULL DEFAULT false;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `devices`
+ADD `sandbox` tinyint(1) unsigned NOT NULL DEFAULT false;
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `devices` DROP `sandbox`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `users` DROP `sandbox`;
+-- +goose StatementEnd
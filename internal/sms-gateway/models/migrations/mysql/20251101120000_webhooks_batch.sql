@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `webhooks`
+ADD `batch_window` int unsigned NOT NULL DEFAULT 0,
+ADD `batch_size` int unsigned NOT NULL DEFAULT 0,
+ADD `batch_group_by` varchar(16) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `webhooks`
+DROP `batch_group_by`,
+DROP `batch_size`,
+DROP `batch_window`;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `relayed_messages` (
+    `id` BIGINT UNSIGNED AUTO_INCREMENT,
+    `user_id` varchar(32) NOT NULL,
+    `device_id` char(21) NOT NULL,
+    `message_id` varchar(36) NOT NULL,
+    `upstream_id` varchar(36) NOT NULL,
+    `state` varchar(16) NOT NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_relayed_messages_device_message` (`device_id`, `message_id`),
+    INDEX `idx_relayed_messages_upstream` (`upstream_id`),
+    INDEX `idx_relayed_messages_state` (`state`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `relayed_messages`;
+-- +goose StatementEnd
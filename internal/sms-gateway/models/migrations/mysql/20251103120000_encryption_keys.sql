@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `messages`
+ADD `encryption_algorithm` varchar(32) NULL,
+ADD `encryption_key_id` varchar(64) NULL;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE `encryption_keys` (
+    `id` BIGINT UNSIGNED AUTO_INCREMENT,
+    `user_id` varchar(32) NOT NULL,
+    `key_id` varchar(64) NOT NULL,
+    `device_id` char(21) NOT NULL,
+    `algorithm` varchar(32) NOT NULL,
+    `material` text NOT NULL,
+    `revoked_at` datetime(3) NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_encryption_keys_user_key` (`user_id`, `key_id`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `encryption_keys`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `messages`
+DROP `encryption_key_id`,
+DROP `encryption_algorithm`;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `devices`
+ADD `tags` json NULL,
+ADD `group_name` varchar(64) NULL,
+ADD `settings` json NULL;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE `device_enrollments` (
+    `id` char(21) NOT NULL,
+    `user_id` varchar(32) NOT NULL,
+    `token_hash` char(64) NOT NULL,
+    `name` varchar(128) NULL,
+    `tags` json NULL,
+    `group_name` varchar(64) NULL,
+    `settings` json NULL,
+    `max_uses` int unsigned NOT NULL DEFAULT 0,
+    `uses` int unsigned NOT NULL DEFAULT 0,
+    `expires_at` datetime(3) NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_device_enrollments_token_hash` (`token_hash`),
+    INDEX `idx_device_enrollments_user_id` (`user_id`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `device_enrollments`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE `devices`
+DROP `settings`,
+DROP `group_name`,
+DROP `tags`;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `settings_profiles` (
+    `id` char(21) NOT NULL,
+    `user_id` varchar(32) NOT NULL,
+    `name` varchar(64) NOT NULL,
+    `settings` json NOT NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_settings_profiles_user_name` (`user_id`, `name`)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE `settings_profile_assignments` (
+    `user_id` varchar(32) NOT NULL,
+    `target_type` enum('device', 'group') NOT NULL,
+    `target` varchar(64) NOT NULL,
+    `profile_id` char(21) NOT NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    `updated_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`user_id`, `target_type`, `target`),
+    INDEX `idx_settings_profile_assignments_profile_id` (`profile_id`),
+    CONSTRAINT `fk_settings_profiles_assignments` FOREIGN KEY (`profile_id`) REFERENCES `settings_profiles` (`id`) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `settings_profile_assignments`;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE `settings_profiles`;
+-- +goose StatementEnd
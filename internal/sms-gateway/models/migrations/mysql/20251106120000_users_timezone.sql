@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE `users`
+ADD `timezone` varchar(64) NOT NULL DEFAULT 'UTC';
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE `users` DROP `timezone`;
+-- +goose StatementEnd
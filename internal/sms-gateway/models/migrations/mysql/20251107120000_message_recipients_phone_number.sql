@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX `idx_message_recipients_phone_number` ON `message_recipients` (`phone_number`);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX `idx_message_recipients_phone_number` ON `message_recipients`;
+-- +goose StatementEnd
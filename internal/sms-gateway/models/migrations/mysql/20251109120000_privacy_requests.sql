@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `privacy_requests` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `user_id` varchar(32) NOT NULL,
+    `type` varchar(8) NOT NULL,
+    `phone_number_hash` char(64) NOT NULL,
+    `messages` bigint NOT NULL DEFAULT 0,
+    `inbox_messages` bigint NOT NULL DEFAULT 0,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    INDEX `idx_privacy_requests_user_id` (`user_id`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `privacy_requests`;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX `idx_messages_updated_at` ON `messages` (`updated_at`);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX `idx_messages_updated_at` ON `messages`;
+-- +goose StatementEnd
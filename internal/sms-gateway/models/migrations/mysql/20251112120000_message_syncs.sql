@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE `message_syncs` (
+    `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+    `device_id` char(21) NOT NULL,
+    `seq` bigint unsigned NOT NULL,
+    `changes` json NOT NULL,
+    `created_at` datetime(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `unq_message_syncs_device_seq` (`device_id`, `seq`),
+    INDEX `idx_message_syncs_created_at` (`created_at`)
+);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE `message_syncs`;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX `idx_message_states_received_at` ON `message_states` (`received_at`);
+-- +goose StatementEnd
+---
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX `idx_message_states_received_at` ON `message_states`;
+-- +goose StatementEnd
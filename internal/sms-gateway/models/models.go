@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 )
 
@@ -19,14 +20,61 @@ type User struct {
 	PasswordHash string   `gorm:"not null;type:varchar(72)"`
 	Devices      []Device `gorm:"-,foreignKey:UserID;constraint:OnDelete:CASCADE"`
 
+	// Sandbox marks test credentials, messages of such users never reach a
+	// real device.
+	Sandbox bool `gorm:"not null;default:false"`
+
+	// Timezone is the IANA name of the time zone the date-only filters of the
+	// user are interpreted in.
+	Timezone string `gorm:"not null;type:varchar(64);default:'UTC'"`
+
 	SoftDeletableModel
 }
 
+// Location returns the time zone of the user, UTC if it's unknown.
+func (u User) Location() *time.Location {
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
 type Device struct {
 	ID        string  `gorm:"primaryKey;type:char(21)"`
 	Name      *string `gorm:"type:varchar(128)"`
 	AuthToken string  `gorm:"not null;uniqueIndex;type:char(21)"`
 	PushToken *string `gorm:"type:varchar(256)"`
+	// PushDegradedAt is the time the push token was found rejected by the
+	// push provider. Events are delivered by SSE and polling until the device
+	// registers a new token.
+	PushDegradedAt *time.Time `gorm:"type:datetime(3)"`
+	// HasPushToken marks the device read from the token cache, which doesn't
+	// hold the push token, as having one.
+	HasPushToken bool `gorm:"-"`
+
+	// AppVersion is the version of the mobile app reported by the device.
+	AppVersion *string `gorm:"type:varchar(32)"`
+
+	// EventSubscriptions is the list of event types delivered to the device,
+	// all events are delivered if it's empty.
+	EventSubscriptions []string `gorm:"type:json;serializer:json"`
+
+	// Sandbox marks the virtual device of the sandbox user, messages enqueued
+	// to it are simulated by the server.
+	Sandbox bool `gorm:"not null;default:false"`
+
+	// SuspendedAt is the time the device was suspended by the user. The
+	// suspended device isn't authorized until it's resumed.
+	SuspendedAt *time.Time `gorm:"type:datetime(3)"`
+
+	// Tags, Group and Settings are pre-assigned by the enrollment token the
+	// device is registered with. Settings override the user's settings for
+	// the device.
+	Tags     []string       `gorm:"type:json;serializer:json"`
+	Group    *string        `gorm:"column:group_name;type:varchar(64)"`
+	Settings map[string]any `gorm:"type:json;serializer:json"`
 
 	LastSeen time.Time `gorm:"not null;autocreatetime:false;default:CURRENT_TIMESTAMP(3);index:idx_devices_last_seen"`
 
@@ -35,6 +83,13 @@ type Device struct {
 	SoftDeletableModel
 }
 
+// CanPush reports whether the events can be delivered to the device by push
+// notifications.
+func (d *Device) CanPush() bool {
+	hasPushToken := d.HasPushToken || d.PushToken != nil && *d.PushToken != ""
+	return hasPushToken && d.PushDegradedAt == nil
+}
+
 func (d *Device) IsEmpty() bool {
 	if d == nil {
 		return true
@@ -42,3 +97,13 @@ func (d *Device) IsEmpty() bool {
 
 	return d.ID == ""
 }
+
+// IsSubscribed returns true if events of the given type should be delivered
+// to the device.
+func (d *Device) IsSubscribed(eventType string) bool {
+	if len(d.EventSubscriptions) == 0 {
+		return true
+	}
+
+	return slices.Contains(d.EventSubscriptions, eventType)
+}
@@ -2,6 +2,7 @@ package models_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 )
@@ -41,3 +42,84 @@ func TestDevice_IsEmpty(t *testing.T) {
 		})
 	}
 }
+
+func TestDevice_IsSubscribed(t *testing.T) {
+	tests := []struct {
+		name      string
+		d         *models.Device
+		eventType string
+		want      bool
+	}{
+		{
+			name:      "no subscriptions",
+			d:         &models.Device{},
+			eventType: "SettingsUpdated",
+			want:      true,
+		},
+		{
+			name: "subscribed",
+			d: &models.Device{
+				EventSubscriptions: []string{"MessageEnqueued", "SettingsUpdated"},
+			},
+			eventType: "SettingsUpdated",
+			want:      true,
+		},
+		{
+			name: "not subscribed",
+			d: &models.Device{
+				EventSubscriptions: []string{"MessageEnqueued"},
+			},
+			eventType: "SettingsUpdated",
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.IsSubscribed(tt.eventType); got != tt.want {
+				t.Errorf("IsSubscribed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDevice_CanPush(t *testing.T) {
+	token := "token"
+	empty := ""
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		d    *models.Device
+		want bool
+	}{
+		{
+			name: "no token",
+			d:    &models.Device{},
+			want: false,
+		},
+		{
+			name: "empty token",
+			d:    &models.Device{PushToken: &empty},
+			want: false,
+		},
+		{
+			name: "token",
+			d:    &models.Device{PushToken: &token},
+			want: true,
+		},
+		{
+			name: "degraded token",
+			d:    &models.Device{PushToken: &token, PushDegradedAt: &now},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.CanPush(); got != tt.want {
+				t.Errorf("CanPush() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package alerts
+
+import "time"
+
+type Config struct {
+	// Interval is the period of the analysis, 0 to disable.
+	Interval time.Duration
+	// Window is the recent period compared against the baseline.
+	Window time.Duration
+	// Baseline is the period before the window used to calculate the usual
+	// failure rate.
+	Baseline time.Duration
+	// MinFailures is the minimal number of failed messages in the window to
+	// raise the alert, so single failures of low-volume devices are ignored.
+	MinFailures uint
+	// Factor is the ratio between the window and baseline failure rates
+	// considered a spike.
+	Factor float64
+}
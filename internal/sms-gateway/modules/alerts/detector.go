@@ -0,0 +1,110 @@
+package alerts
+
+import "math"
+
+// Scope is the level of the aggregation the spike is detected on.
+type Scope string
+
+const (
+	ScopeDevice Scope = "device"
+	ScopeUser   Scope = "user"
+)
+
+// minBaselineRate is the failure rate assumed when the baseline is lower or
+// has no messages, so the first failures after a quiet period aren't
+// reported as infinite spikes.
+const minBaselineRate = 0.05
+
+// counters is the number of messages in final states and the failed ones.
+type counters struct {
+	UserID   string
+	DeviceID string
+	Total    uint64
+	Failed   uint64
+}
+
+func (c counters) rate() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+
+	return float64(c.Failed) / float64(c.Total)
+}
+
+// Spike describes the detected failure rate spike.
+type Spike struct {
+	Scope    Scope
+	UserID   string
+	DeviceID string
+
+	Total        uint64
+	Failed       uint64
+	FailureRate  float64
+	BaselineRate float64
+}
+
+// detect compares the window counters with the baseline ones per device and
+// per user and returns the spikes.
+func detect(window, baseline []counters, minFailures uint, factor float64) []Spike {
+	baselineByDevice := map[string]counters{}
+	baselineByUser := map[string]counters{}
+	for _, c := range baseline {
+		baselineByDevice[c.DeviceID] = c
+		baselineByUser[c.UserID] = sum(baselineByUser[c.UserID], c)
+	}
+
+	windowByUser := map[string]counters{}
+	userOrder := []string{}
+
+	spikes := []Spike{}
+	for _, c := range window {
+		if _, ok := windowByUser[c.UserID]; !ok {
+			userOrder = append(userOrder, c.UserID)
+		}
+		windowByUser[c.UserID] = sum(windowByUser[c.UserID], c)
+
+		if spike, ok := check(ScopeDevice, c, baselineByDevice[c.DeviceID], minFailures, factor); ok {
+			spikes = append(spikes, spike)
+		}
+	}
+
+	for _, userID := range userOrder {
+		c := windowByUser[userID]
+		c.DeviceID = ""
+		if spike, ok := check(ScopeUser, c, baselineByUser[userID], minFailures, factor); ok {
+			spikes = append(spikes, spike)
+		}
+	}
+
+	return spikes
+}
+
+func check(scope Scope, window, baseline counters, minFailures uint, factor float64) (Spike, bool) {
+	if window.Failed < uint64(minFailures) {
+		return Spike{}, false
+	}
+
+	baselineRate := baseline.rate()
+	rate := window.rate()
+	if rate < math.Max(baselineRate, minBaselineRate)*factor {
+		return Spike{}, false
+	}
+
+	return Spike{
+		Scope:        scope,
+		UserID:       window.UserID,
+		DeviceID:     window.DeviceID,
+		Total:        window.Total,
+		Failed:       window.Failed,
+		FailureRate:  rate,
+		BaselineRate: baselineRate,
+	}, true
+}
+
+func sum(a, b counters) counters {
+	return counters{
+		UserID: b.UserID,
+		Total:  a.Total + b.Total,
+		Failed: a.Failed + b.Failed,
+	}
+}
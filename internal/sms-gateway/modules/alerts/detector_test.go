@@ -0,0 +1,65 @@
+package alerts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   []counters
+		baseline []counters
+		want     []Spike
+	}{
+		{
+			name:   "too few failures",
+			window: []counters{{UserID: "u1", DeviceID: "d1", Total: 4, Failed: 4}},
+			want:   []Spike{},
+		},
+		{
+			name:     "usual failure rate",
+			window:   []counters{{UserID: "u1", DeviceID: "d1", Total: 100, Failed: 20}},
+			baseline: []counters{{UserID: "u1", DeviceID: "d1", Total: 1000, Failed: 150}},
+			want:     []Spike{},
+		},
+		{
+			name:     "device spike",
+			window:   []counters{{UserID: "u1", DeviceID: "d1", Total: 20, Failed: 10}, {UserID: "u1", DeviceID: "d2", Total: 80, Failed: 0}},
+			baseline: []counters{{UserID: "u1", DeviceID: "d1", Total: 1000, Failed: 10}},
+			want: []Spike{
+				{Scope: ScopeDevice, UserID: "u1", DeviceID: "d1", Total: 20, Failed: 10, FailureRate: 0.5, BaselineRate: 0.01},
+			},
+		},
+		{
+			name:   "device and user spike without baseline",
+			window: []counters{{UserID: "u1", DeviceID: "d1", Total: 10, Failed: 5}},
+			want: []Spike{
+				{Scope: ScopeDevice, UserID: "u1", DeviceID: "d1", Total: 10, Failed: 5, FailureRate: 0.5},
+				{Scope: ScopeUser, UserID: "u1", Total: 10, Failed: 5, FailureRate: 0.5},
+			},
+		},
+		{
+			name: "user spike across devices",
+			window: []counters{
+				{UserID: "u1", DeviceID: "d1", Total: 10, Failed: 3},
+				{UserID: "u1", DeviceID: "d2", Total: 10, Failed: 3},
+			},
+			baseline: []counters{
+				{UserID: "u1", DeviceID: "d1", Total: 100, Failed: 5},
+				{UserID: "u1", DeviceID: "d2", Total: 100, Failed: 5},
+			},
+			want: []Spike{
+				{Scope: ScopeUser, UserID: "u1", Total: 20, Failed: 6, FailureRate: 0.3, BaselineRate: 0.05},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detect(tt.window, tt.baseline, 5, 3); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detect() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
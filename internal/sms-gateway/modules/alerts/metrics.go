@@ -0,0 +1,31 @@
+package alerts
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	MetricAlertsTotal = "total"
+
+	LabelScope = "scope"
+)
+
+type metrics struct {
+	alertsCounter *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		alertsCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "alerts",
+			Name:      MetricAlertsTotal,
+			Help:      "Total number of delivery degradation alerts by scope",
+		}, []string{LabelScope}),
+	}
+}
+
+func (m *metrics) IncAlert(scope Scope) {
+	m.alertsCounter.WithLabelValues(string(scope)).Inc()
+}
@@ -0,0 +1,35 @@
+package alerts
+
+import (
+	"context"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"alerts",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("alerts")
+	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("alerts")
+	}, fx.Private),
+	fx.Provide(newRepository, fx.Private),
+	fx.Provide(newMetrics, fx.Private),
+	fx.Provide(NewService),
+	fx.Invoke(func(lc fx.Lifecycle, svc *Service) {
+		ctx, cancel := context.WithCancel(context.Background())
+		lc.Append(fx.Hook{
+			OnStart: func(_ context.Context) error {
+				go svc.Run(ctx)
+				return nil
+			},
+			OnStop: func(_ context.Context) error {
+				cancel()
+				return nil
+			},
+		})
+	}),
+)
@@ -0,0 +1,37 @@
+package alerts
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Counters returns the number of messages which reached the final state in
+// the period and the failed ones, grouped by device. The time of the state is
+// the time the server received it, so the messages changed later for other
+// reasons, e.g. hashed, aren't counted again.
+func (r *repository) Counters(ctx context.Context, since, until time.Time) ([]counters, error) {
+	items := []counters{}
+	err := r.db.WithContext(ctx).
+		Table("message_states AS s").
+		Select("m.user_id, m.device_id, COUNT(*) AS total, SUM(s.state = 'Failed') AS failed").
+		Joins("JOIN messages AS m ON m.id = s.message_id AND m.state = s.state").
+		Where("m.user_id <> '' AND s.state IN ('Sent', 'Delivered', 'Failed') AND s.received_at >= ? AND s.received_at < ?", since, until).
+		Group("m.user_id, m.device_id").
+		Order("m.user_id, m.device_id").
+		Scan(&items).
+		Error
+
+	return items, err
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
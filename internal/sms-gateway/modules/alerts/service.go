@@ -0,0 +1,138 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	Counters *repository
+	Cache    cache.Cache
+
+	WebhooksSvc *webhooks.Service
+
+	Metrics *metrics
+	Logger  *zap.Logger
+}
+
+type Service struct {
+	config Config
+
+	counters *repository
+	cache    cache.Cache
+
+	webhooksSvc *webhooks.Service
+
+	metrics *metrics
+	logger  *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		config: params.Config,
+
+		counters: params.Counters,
+		cache:    params.Cache,
+
+		webhooksSvc: params.WebhooksSvc,
+
+		metrics: params.Metrics,
+		logger:  params.Logger,
+	}
+}
+
+// Run periodically compares the failure rate of the recent window with the
+// baseline and emits alerts for the spikes.
+func (s *Service) Run(ctx context.Context) {
+	if s.config.Interval <= 0 || s.config.Window <= 0 {
+		s.logger.Info("Delivery alerts are disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.analyze(ctx); err != nil {
+				s.logger.Error("Can't analyze delivery failures", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (s *Service) analyze(ctx context.Context) error {
+	now := time.Now()
+	windowStart := now.Add(-s.config.Window)
+
+	window, err := s.counters.Counters(ctx, windowStart, now)
+	if err != nil {
+		return err
+	}
+	if len(window) == 0 {
+		return nil
+	}
+
+	baseline, err := s.counters.Counters(ctx, windowStart.Add(-s.config.Baseline), windowStart)
+	if err != nil {
+		return err
+	}
+
+	for _, spike := range detect(window, baseline, s.config.MinFailures, s.config.Factor) {
+		s.raise(ctx, spike)
+	}
+
+	return nil
+}
+
+// raise emits the alert unless it was already raised during the window, so
+// the ongoing degradation is reported once per window across instances.
+func (s *Service) raise(ctx context.Context, spike Spike) {
+	key := string(spike.Scope) + ":" + spike.UserID + ":" + spike.DeviceID
+	err := s.cache.SetOrFail(ctx, key, time.Now().UTC().Format(time.RFC3339), cache.WithTTL(s.config.Window))
+	if errors.Is(err, cache.ErrKeyExists) {
+		return
+	}
+	if err != nil {
+		s.logger.Error("Can't store alert", zap.String("key", key), zap.Error(err))
+		return
+	}
+
+	s.logger.Warn("Delivery degraded",
+		zap.String("scope", string(spike.Scope)),
+		zap.String("user_id", spike.UserID),
+		zap.String("device_id", spike.DeviceID),
+		zap.Uint64("failed", spike.Failed),
+		zap.Uint64("total", spike.Total),
+		zap.Float64("failure_rate", spike.FailureRate),
+		zap.Float64("baseline_rate", spike.BaselineRate),
+	)
+	s.metrics.IncAlert(spike.Scope)
+
+	var deviceID *string
+	if spike.Scope == ScopeDevice {
+		deviceID = &spike.DeviceID
+	}
+
+	s.webhooksSvc.Emit(spike.UserID, deviceID, webhooks.EventDeliveryDegraded, map[string]any{
+		"scope":         spike.Scope,
+		"failed":        spike.Failed,
+		"total":         spike.Total,
+		"failureRate":   spike.FailureRate,
+		"baselineRate":  spike.BaselineRate,
+		"windowSeconds": int(s.config.Window.Seconds()),
+	})
+}
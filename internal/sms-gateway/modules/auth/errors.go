@@ -0,0 +1,7 @@
+package auth
+
+import "errors"
+
+var (
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
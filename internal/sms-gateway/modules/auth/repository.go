@@ -32,6 +32,11 @@ func (r *repository) Insert(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
+// UpdateTimezone sets the time zone of the user.
+func (r *repository) UpdateTimezone(userID string, timezone string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("timezone", timezone).Error
+}
+
 func (r *repository) UpdatePassword(userID string, passwordHash string) error {
 	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", passwordHash).Error
 }
@@ -7,12 +7,15 @@ import (
 	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/enrollments"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/online"
 	"github.com/android-sms-gateway/server/pkg/crypto"
+	"github.com/capcom6/go-helpers/anys"
 	"github.com/capcom6/go-helpers/cache"
 	"github.com/jaevor/go-nanoid"
 	"go.uber.org/fx"
@@ -29,9 +32,10 @@ type Params struct {
 
 	Config Config
 
-	Users      *repository
-	DevicesSvc *devices.Service
-	OnlineSvc  online.Service
+	Users          *repository
+	DevicesSvc     *devices.Service
+	EnrollmentsSvc *enrollments.Service
+	OnlineSvc      online.Service
 
 	Logger *zap.Logger
 }
@@ -43,8 +47,9 @@ type Service struct {
 	codesCache *cache.Cache[string]
 	usersCache *cache.Cache[models.User]
 
-	devicesSvc *devices.Service
-	onlineSvc  online.Service
+	devicesSvc     *devices.Service
+	enrollmentsSvc *enrollments.Service
+	onlineSvc      online.Service
 
 	logger *zap.Logger
 
@@ -55,12 +60,13 @@ func New(params Params) *Service {
 	idgen, _ := nanoid.Standard(21)
 
 	return &Service{
-		config:     params.Config,
-		users:      params.Users,
-		devicesSvc: params.DevicesSvc,
-		onlineSvc:  params.OnlineSvc,
-		logger:     params.Logger,
-		idgen:      idgen,
+		config:         params.Config,
+		users:          params.Users,
+		devicesSvc:     params.DevicesSvc,
+		enrollmentsSvc: params.EnrollmentsSvc,
+		onlineSvc:      params.OnlineSvc,
+		logger:         params.Logger,
+		idgen:          idgen,
 
 		codesCache: cache.New[string](cache.Config{}),
 		usersCache: cache.New[models.User](cache.Config{TTL: 1 * time.Hour}),
@@ -112,6 +118,32 @@ func (s *Service) RegisterUser(login, password string) (models.User, error) {
 	return user, nil
 }
 
+// RegisterSandbox creates the sandbox user with a single virtual device.
+// Messages of the sandbox user are accepted and processed as usual, but never
+// reach a real device.
+func (s *Service) RegisterSandbox(login, password string) (models.User, models.Device, error) {
+	user := models.User{
+		ID:      login,
+		Sandbox: true,
+	}
+
+	var err error
+	if user.PasswordHash, err = crypto.MakeBCryptHash(password); err != nil {
+		return user, models.Device{}, fmt.Errorf("can't hash password: %w", err)
+	}
+
+	if err = s.users.Insert(&user); err != nil {
+		return user, models.Device{}, fmt.Errorf("can't create user")
+	}
+
+	device := models.Device{
+		Name:    anys.AsPointer(sandboxDeviceName),
+		Sandbox: true,
+	}
+
+	return user, device, s.devicesSvc.Insert(user.ID, &device)
+}
+
 func (s *Service) RegisterDevice(user models.User, name, pushToken *string) (models.Device, error) {
 	device := models.Device{
 		Name:      name,
@@ -121,6 +153,31 @@ func (s *Service) RegisterDevice(user models.User, name, pushToken *string) (mod
 	return device, s.devicesSvc.Insert(user.ID, &device)
 }
 
+// RegisterEnrolledDevice redeems the enrollment and registers the device
+// with its metadata. The use of the enrollment is counted only if the device
+// is registered. The name of the enrollment takes precedence over the one
+// reported by the device.
+func (s *Service) RegisterEnrolledDevice(user models.User, enrollment enrollments.Enrollment, name, pushToken *string) (models.Device, error) {
+	device := models.Device{}
+	_, err := s.enrollmentsSvc.Redeem(enrollment.ID, func(enrollment enrollments.Enrollment) error {
+		if enrollment.Name != nil {
+			name = enrollment.Name
+		}
+
+		device = models.Device{
+			Name:      name,
+			PushToken: pushToken,
+			Tags:      enrollment.Tags,
+			Group:     enrollment.Group,
+			Settings:  enrollment.Settings,
+		}
+
+		return s.devicesSvc.Insert(user.ID, &device)
+	})
+
+	return device, err
+}
+
 func (s *Service) IsPublic() bool {
 	return s.config.Mode == ModePublic
 }
@@ -142,6 +199,9 @@ func (s *Service) AuthorizeDevice(token string) (models.Device, error) {
 	if err != nil {
 		return device, err
 	}
+	if device.SuspendedAt != nil {
+		return models.Device{}, devices.ErrSuspended
+	}
 
 	go func(id string) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -194,6 +254,22 @@ func (s *Service) AuthorizeUserByCode(code string) (models.User, error) {
 	return user, nil
 }
 
+// AuthorizeEnrollment checks the enrollment token and returns its owner. The
+// token is redeemed by RegisterEnrolledDevice.
+func (s *Service) AuthorizeEnrollment(token string) (models.User, enrollments.Enrollment, error) {
+	enrollment, err := s.enrollmentsSvc.Authorize(token)
+	if err != nil {
+		return models.User{}, enrollment, err
+	}
+
+	user, err := s.users.GetByID(enrollment.UserID)
+	if err != nil {
+		return models.User{}, enrollment, err
+	}
+
+	return user, enrollment, nil
+}
+
 func (s *Service) ChangePassword(userID string, currentPassword string, newPassword string) error {
 	user, err := s.users.GetByLogin(userID)
 	if err != nil {
@@ -223,6 +299,29 @@ func (s *Service) ChangePassword(userID string, currentPassword string, newPassw
 	return nil
 }
 
+// GetUser returns the user by ID. Unlike the authorization methods it always
+// reads the stored user, so the recent changes are visible.
+func (s *Service) GetUser(userID string) (models.User, error) {
+	return s.users.GetByID(userID)
+}
+
+// SetTimezone sets the time zone the date-only filters of the user are
+// interpreted in, an IANA name like "Europe/Berlin".
+func (s *Service) SetTimezone(userID string, timezone string) error {
+	if timezone == "" || strings.EqualFold(timezone, "local") {
+		return fmt.Errorf("%w: %q", ErrInvalidTimezone, timezone)
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidTimezone, timezone)
+	}
+
+	if err := s.users.UpdateTimezone(userID, timezone); err != nil {
+		return fmt.Errorf("failed to update timezone: %w", err)
+	}
+
+	return nil
+}
+
 // Run starts a ticker that triggers the clean function every hour.
 // It runs indefinitely until the provided context is canceled.
 func (s *Service) Run(ctx context.Context) {
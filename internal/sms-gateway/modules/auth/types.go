@@ -4,6 +4,8 @@ import "time"
 
 const codeTTL = 5 * time.Minute
 
+const sandboxDeviceName = "Sandbox"
+
 type Mode string
 
 const (
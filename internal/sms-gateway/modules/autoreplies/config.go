@@ -0,0 +1,15 @@
+package autoreplies
+
+import "time"
+
+type Config struct {
+	// MaxPerSender is the max number of replies to a single sender in the
+	// window, so two auto-responders can't reply to each other endlessly. Zero
+	// disables the limit.
+	MaxPerSender uint
+	// MaxPerUser is the max number of replies of the user in the window, zero
+	// disables the limit.
+	MaxPerUser uint
+	// Window is the period of the limits.
+	Window time.Duration
+}
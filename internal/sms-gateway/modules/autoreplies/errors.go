@@ -0,0 +1,8 @@
+package autoreplies
+
+import "errors"
+
+var (
+	ErrNotFound    = errors.New("rule not found")
+	ErrInvalidRule = errors.New("invalid rule")
+)
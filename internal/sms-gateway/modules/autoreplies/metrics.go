@@ -0,0 +1,35 @@
+package autoreplies
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	MetricRepliesTotal = "replies_total"
+
+	LabelResult = "result"
+
+	ResultSent    = "sent"
+	ResultLimited = "limited"
+	ResultFailed  = "failed"
+)
+
+type metrics struct {
+	repliesCounter *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		repliesCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "autoreplies",
+			Name:      MetricRepliesTotal,
+			Help:      "Total number of the incoming messages matched by auto-reply rules by result",
+		}, []string{LabelResult}),
+	}
+}
+
+func (m *metrics) IncReply(result string) {
+	m.repliesCounter.WithLabelValues(result).Inc()
+}
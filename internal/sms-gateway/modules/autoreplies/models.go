@@ -0,0 +1,55 @@
+package autoreplies
+
+import (
+	"fmt"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"gorm.io/gorm"
+)
+
+// MatchType defines how the pattern of the rule is matched against the text
+// of the incoming message.
+type MatchType string
+
+const (
+	// MatchKeyword matches the pattern as a case-insensitive whole word.
+	MatchKeyword MatchType = "keyword"
+	// MatchRegex matches the pattern as a regular expression.
+	MatchRegex MatchType = "regex"
+)
+
+func (t MatchType) IsValid() bool {
+	return t == MatchKeyword || t == MatchRegex
+}
+
+// Rule replies to the incoming messages of the user matching the pattern
+// from the device which received the message.
+type Rule struct {
+	ID       uint64  `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	ExtID    string  `gorm:"not null;type:char(21);uniqueIndex:unq_auto_reply_rules_user_extid,priority:2"`
+	UserID   string  `gorm:"not null;type:varchar(32);uniqueIndex:unq_auto_reply_rules_user_extid,priority:1"`
+	DeviceID *string `gorm:"type:char(21);index:idx_auto_reply_rules_device"`
+
+	MatchType MatchType `gorm:"not null;type:varchar(16)"`
+	Pattern   string    `gorm:"not null;type:varchar(256)"`
+	Reply     string    `gorm:"not null;type:text"`
+	// CooldownSeconds is the min interval between the replies of the rule to
+	// the same sender
+	CooldownSeconds uint `gorm:"not null;type:int unsigned;default:0"`
+
+	User   models.User    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Device *models.Device `gorm:"foreignKey:DeviceID;constraint:OnDelete:CASCADE"`
+
+	models.TimedModel
+}
+
+func (Rule) TableName() string {
+	return "auto_reply_rules"
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Rule{}); err != nil {
+		return fmt.Errorf("auto_reply_rules migration failed: %w", err)
+	}
+	return nil
+}
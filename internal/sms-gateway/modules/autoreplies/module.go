@@ -0,0 +1,29 @@
+package autoreplies
+
+import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"github.com/capcom6/go-infra-fx/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"autoreplies",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("autoreplies")
+	}),
+	fx.Provide(newRepository, fx.Private),
+	fx.Provide(newMetrics, fx.Private),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("autoreplies")
+	}, fx.Private),
+	fx.Provide(NewService),
+	fx.Invoke(func(webhooksSvc *webhooks.Service, svc *Service) {
+		webhooksSvc.OnReceived(svc.reply)
+	}),
+)
+
+func init() {
+	db.RegisterMigration(Migrate)
+}
@@ -0,0 +1,63 @@
+package autoreplies
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Select returns the rules of the user in the order of creation.
+func (r *repository) Select(userID string) ([]Rule, error) {
+	items := []Rule{}
+	err := r.db.
+		Where("user_id = ?", userID).
+		Order("id").
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+// Get returns the rule of the user.
+func (r *repository) Get(userID, extID string) (Rule, error) {
+	rule := Rule{}
+	err := r.db.
+		Where("user_id = ? AND ext_id = ?", userID, extID).
+		Take(&rule).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return rule, ErrNotFound
+	}
+
+	return rule, err
+}
+
+// Insert stores the rule.
+func (r *repository) Insert(rule *Rule) error {
+	return r.db.Omit("User", "Device").Create(rule).Error
+}
+
+// Delete removes the rule of the user.
+func (r *repository) Delete(userID, extID string) error {
+	res := r.db.
+		Where("user_id = ? AND ext_id = ?", userID, extID).
+		Delete(&Rule{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,136 @@
+package autoreplies
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+)
+
+const (
+	// PhoneNumberPlaceholder is replaced with the sender of the incoming
+	// message in the reply.
+	PhoneNumberPlaceholder = "{phoneNumber}"
+	// MessagePlaceholder is replaced with the text of the incoming message in
+	// the reply.
+	MessagePlaceholder = "{message}"
+
+	// maxGroups is the number of the regular expression groups available in
+	// the reply as {1}...{9}.
+	maxGroups = 9
+)
+
+// senderPattern matches the senders which can receive the reply, the
+// alphanumeric senders and short codes can't.
+var senderPattern = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+
+// compile returns the regular expression of the rule pattern.
+func compile(matchType MatchType, pattern string) (*regexp.Regexp, error) {
+	switch matchType {
+	case MatchKeyword:
+		keyword := strings.TrimSpace(pattern)
+		if keyword == "" {
+			return nil, fmt.Errorf("%w: empty keyword", ErrInvalidRule)
+		}
+		return regexp.MustCompile(`(?i)(?:^|[^\pL\pN])` + regexp.QuoteMeta(keyword) + `(?:[^\pL\pN]|$)`), nil
+	case MatchRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidRule, err)
+		}
+		return re, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown match type %q", ErrInvalidRule, matchType)
+	}
+}
+
+// maxPatterns is the number of the compiled patterns kept, the cache is
+// dropped when it's full.
+const maxPatterns = 1024
+
+// patterns keeps the compiled rule patterns, so the rules aren't compiled for
+// each incoming message.
+type patterns struct {
+	mu    sync.RWMutex
+	items map[patternKey]*regexp.Regexp
+}
+
+type patternKey struct {
+	matchType MatchType
+	pattern   string
+}
+
+func newPatterns() *patterns {
+	return &patterns{items: make(map[patternKey]*regexp.Regexp)}
+}
+
+// compile returns the compiled expression of the pattern.
+func (p *patterns) compile(matchType MatchType, pattern string) (*regexp.Regexp, error) {
+	key := patternKey{matchType: matchType, pattern: pattern}
+
+	p.mu.RLock()
+	re, ok := p.items[key]
+	p.mu.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := compile(matchType, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if len(p.items) >= maxPatterns {
+		clear(p.items)
+	}
+	p.items[key] = re
+	p.mu.Unlock()
+
+	return re, nil
+}
+
+// match returns the reply of the rule to the message, false if the message
+// doesn't match the rule.
+func (p *patterns) match(rule Rule, deviceID string, message webhooks.IncomingMessage) (string, bool) {
+	if rule.DeviceID != nil && *rule.DeviceID != deviceID {
+		return "", false
+	}
+
+	re, err := p.compile(rule.MatchType, rule.Pattern)
+	if err != nil {
+		// the stored rules are validated
+		return "", false
+	}
+
+	groups := re.FindStringSubmatch(message.Message)
+	if groups == nil {
+		return "", false
+	}
+	if rule.MatchType == MatchKeyword {
+		// the keyword expression has no groups of the user
+		groups = nil
+	}
+
+	return render(rule.Reply, message, groups), true
+}
+
+// render replaces the placeholders of the reply template.
+func render(template string, message webhooks.IncomingMessage, groups []string) string {
+	pairs := []string{
+		PhoneNumberPlaceholder, message.PhoneNumber,
+		MessagePlaceholder, message.Message,
+	}
+	for i := 1; i <= maxGroups; i++ {
+		value := ""
+		if i < len(groups) {
+			value = groups[i]
+		}
+		pairs = append(pairs, "{"+strconv.Itoa(i)+"}", value)
+	}
+
+	return strings.NewReplacer(pairs...).Replace(template)
+}
@@ -0,0 +1,152 @@
+package autoreplies
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+)
+
+func TestMatch(t *testing.T) {
+	device := "device"
+	other := "other"
+
+	tests := []struct {
+		name      string
+		rule      Rule
+		text      string
+		want      string
+		wantMatch bool
+	}{
+		{
+			name:      "keyword",
+			rule:      Rule{MatchType: MatchKeyword, Pattern: "HELP", Reply: "Hi {phoneNumber}, call us"},
+			text:      "help me please",
+			want:      "Hi +79990001234, call us",
+			wantMatch: true,
+		},
+		{
+			name: "keyword within word",
+			rule: Rule{MatchType: MatchKeyword, Pattern: "help", Reply: "reply"},
+			text: "helpful",
+		},
+		{
+			name:      "keyword with punctuation",
+			rule:      Rule{MatchType: MatchKeyword, Pattern: "stop", Reply: "Unsubscribed"},
+			text:      "Please, STOP!",
+			want:      "Unsubscribed",
+			wantMatch: true,
+		},
+		{
+			name:      "regex groups",
+			rule:      Rule{MatchType: MatchRegex, Pattern: `^ORDER (\d+)$`, Reply: "Order {1} is on the way{2}"},
+			text:      "ORDER 42",
+			want:      "Order 42 is on the way",
+			wantMatch: true,
+		},
+		{
+			name: "regex mismatch",
+			rule: Rule{MatchType: MatchRegex, Pattern: `^ORDER (\d+)$`, Reply: "reply"},
+			text: "order 42",
+		},
+		{
+			name:      "message placeholder",
+			rule:      Rule{MatchType: MatchRegex, Pattern: `.`, Reply: "Got: {message}"},
+			text:      "ping",
+			want:      "Got: ping",
+			wantMatch: true,
+		},
+		{
+			name:      "device",
+			rule:      Rule{DeviceID: &device, MatchType: MatchKeyword, Pattern: "help", Reply: "reply"},
+			text:      "help",
+			want:      "reply",
+			wantMatch: true,
+		},
+		{
+			name: "other device",
+			rule: Rule{DeviceID: &other, MatchType: MatchKeyword, Pattern: "help", Reply: "reply"},
+			text: "help",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := newPatterns().match(tt.rule, device, webhooks.IncomingMessage{PhoneNumber: "+79990001234", Message: tt.text})
+			if ok != tt.wantMatch || got != tt.want {
+				t.Errorf("match() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	p := newPatterns()
+
+	first, err := p.compile(MatchRegex, `^\d+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.compile(MatchRegex, `^\d+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("compile() of the same pattern returned another expression")
+	}
+
+	keyword, err := p.compile(MatchKeyword, `^\d+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyword == first {
+		t.Error("compile() of another match type returned the same expression")
+	}
+
+	if _, err := p.compile(MatchRegex, `(`); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("compile() of invalid pattern error = %v, want ErrInvalidRule", err)
+	}
+	if len(p.items) != 2 {
+		t.Errorf("compiled patterns = %d, want 2", len(p.items))
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name      string
+		matchType MatchType
+		pattern   string
+		wantErr   bool
+	}{
+		{name: "keyword", matchType: MatchKeyword, pattern: "a.b"},
+		{name: "empty keyword", matchType: MatchKeyword, pattern: "  ", wantErr: true},
+		{name: "regex", matchType: MatchRegex, pattern: `^\d+$`},
+		{name: "invalid regex", matchType: MatchRegex, pattern: `(`, wantErr: true},
+		{name: "unknown type", matchType: "glob", pattern: "*", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compile(tt.matchType, tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidRule) {
+				t.Errorf("compile() error = %v, want ErrInvalidRule", err)
+			}
+		})
+	}
+}
+
+func TestSenderPattern(t *testing.T) {
+	for sender, want := range map[string]bool{
+		"+79990001234": true,
+		"79990001234":  true,
+		"Google":       false,
+		"900":          false,
+	} {
+		if got := senderPattern.MatchString(sender); got != want {
+			t.Errorf("senderPattern.MatchString(%q) = %v, want %v", sender, got, want)
+		}
+	}
+}
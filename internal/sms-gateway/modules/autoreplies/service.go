@@ -0,0 +1,292 @@
+package autoreplies
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// replyTimeout limits the evaluation of the rules and the enqueueing of
+	// the reply.
+	replyTimeout = 10 * time.Second
+	// repliedTTL is how long the replied incoming message is remembered, so
+	// the message reported again isn't replied twice.
+	repliedTTL = 24 * time.Hour
+)
+
+// RuleIn is the rule created by the user.
+type RuleIn struct {
+	// DeviceID limits the rule to the messages received by the device, all
+	// devices of the user if nil
+	DeviceID  *string
+	MatchType MatchType
+	Pattern   string
+	// Reply is the template of the reply with the placeholders
+	Reply    string
+	Cooldown time.Duration
+}
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	Rules *repository
+	Cache cache.Cache
+	IDGen db.IDGen
+
+	DevicesSvc  *devices.Service
+	MessagesSvc *messages.Service
+
+	Metrics *metrics
+	Logger  *zap.Logger
+}
+
+// Service replies to the incoming messages reported by the devices according
+// to the rules of the user.
+type Service struct {
+	config Config
+
+	rules    *repository
+	patterns *patterns
+	cache    cache.Cache
+	idGen    db.IDGen
+
+	devicesSvc  *devices.Service
+	messagesSvc *messages.Service
+
+	metrics *metrics
+	logger  *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		config: params.Config,
+
+		rules:    params.Rules,
+		patterns: newPatterns(),
+		cache:    params.Cache,
+		idGen:    params.IDGen,
+
+		devicesSvc:  params.DevicesSvc,
+		messagesSvc: params.MessagesSvc,
+
+		metrics: params.Metrics,
+		logger:  params.Logger,
+	}
+}
+
+// Select returns the rules of the user in the order of evaluation.
+func (s *Service) Select(userID string) ([]Rule, error) {
+	items, err := s.rules.Select(userID)
+	if err != nil {
+		return nil, fmt.Errorf("can't select rules: %w", err)
+	}
+
+	return items, nil
+}
+
+// Create validates and stores the rule, it's evaluated after the existing
+// rules of the user.
+func (s *Service) Create(userID string, in RuleIn) (Rule, error) {
+	if _, err := compile(in.MatchType, in.Pattern); err != nil {
+		return Rule{}, err
+	}
+	if strings.TrimSpace(in.Reply) == "" {
+		return Rule{}, fmt.Errorf("%w: empty reply", ErrInvalidRule)
+	}
+
+	if in.DeviceID != nil {
+		ok, err := s.devicesSvc.Exists(userID, devices.WithID(*in.DeviceID))
+		if err != nil {
+			return Rule{}, fmt.Errorf("can't select devices: %w", err)
+		}
+		if !ok {
+			return Rule{}, fmt.Errorf("%w: device %s not found", ErrInvalidRule, *in.DeviceID)
+		}
+	}
+
+	rule := Rule{
+		ExtID:           s.idGen(),
+		UserID:          userID,
+		DeviceID:        in.DeviceID,
+		MatchType:       in.MatchType,
+		Pattern:         in.Pattern,
+		Reply:           in.Reply,
+		CooldownSeconds: uint(in.Cooldown.Seconds()),
+	}
+	if err := s.rules.Insert(&rule); err != nil {
+		return rule, fmt.Errorf("can't store rule: %w", err)
+	}
+
+	return s.rules.Get(userID, rule.ExtID)
+}
+
+// Delete removes the rule of the user.
+func (s *Service) Delete(userID, id string) error {
+	if err := s.rules.Delete(userID, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("can't delete rule: %w", err)
+	}
+
+	return nil
+}
+
+// reply enqueues the reply of the first matching rule of the user to the
+// sender from the device which received the message. It's called by the
+// bounded workers of the incoming messages, so the reply is enqueued
+// synchronously.
+func (s *Service) reply(device models.Device, message webhooks.IncomingMessage) {
+	// the alphanumeric senders can't receive the reply
+	if !senderPattern.MatchString(message.PhoneNumber) {
+		return
+	}
+
+	logger := s.logger.With(
+		zap.String("user_id", device.UserID),
+		zap.String("device_id", device.ID),
+	)
+
+	rules, err := s.rules.Select(device.UserID)
+	if err != nil {
+		logger.Error("Can't select rules", zap.Error(err))
+		return
+	}
+
+	var (
+		rule Rule
+		text string
+		ok   bool
+	)
+	for _, rule = range rules {
+		if text, ok = s.patterns.match(rule, device.ID, message); ok {
+			break
+		}
+	}
+	if !ok {
+		return
+	}
+
+	logger = logger.With(zap.String("rule_id", rule.ExtID))
+
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	first, err := s.claim(ctx, device.ID, message.ID)
+	if err != nil {
+		s.metrics.IncReply(ResultFailed)
+		logger.Error("Can't check replied messages", zap.Error(err))
+		return
+	}
+	if !first {
+		logger.Info("Message is already replied", zap.String("message_id", message.ID))
+		return
+	}
+
+	allowed, err := s.allow(ctx, rule, message.PhoneNumber)
+	if err != nil {
+		s.metrics.IncReply(ResultFailed)
+		logger.Error("Can't check reply limits", zap.Error(err))
+		return
+	}
+	if !allowed {
+		s.metrics.IncReply(ResultLimited)
+		logger.Info("Reply is limited")
+		return
+	}
+
+	if _, err := s.messagesSvc.Enqueue(device, messages.MessageIn{
+		ID: replyID(message.ID),
+		TextContent: &messages.TextMessageContent{
+			Text: text,
+		},
+		PhoneNumbers: []string{message.PhoneNumber},
+		SimNumber:    message.SimNumber,
+	}, messages.EnqueueOptions{}); errors.Is(err, messages.ErrMessageAlreadyExists) {
+		logger.Info("Message is already replied", zap.String("message_id", message.ID))
+		return
+	} else if err != nil {
+		s.metrics.IncReply(ResultFailed)
+		logger.Error("Can't enqueue reply", zap.Error(err))
+		return
+	}
+
+	s.metrics.IncReply(ResultSent)
+}
+
+// claim reports whether the incoming message of the device is replied for the
+// first time.
+func (s *Service) claim(ctx context.Context, deviceID, messageID string) (bool, error) {
+	err := s.cache.SetOrFail(ctx, "replied:"+deviceID+":"+messageID, "1", cache.WithTTL(repliedTTL))
+	if errors.Is(err, cache.ErrKeyExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("can't mark message as replied: %w", err)
+	}
+
+	return true, nil
+}
+
+// replyID is the ID of the reply to the incoming message, so the repeated
+// reply to the message is rejected as the duplicate even after the replied
+// message is forgotten.
+func replyID(messageID string) string {
+	hash := sha256.Sum256([]byte("reply:" + messageID))
+	return hex.EncodeToString(hash[:])[:32]
+}
+
+// allow checks the cooldown of the rule and the limits of the sender and the
+// user. The reply loops between auto-responders are stopped by the sender
+// limit.
+func (s *Service) allow(ctx context.Context, rule Rule, sender string) (bool, error) {
+	if rule.CooldownSeconds > 0 {
+		err := s.cache.SetOrFail(ctx, "cooldown:"+rule.ExtID+":"+sender, "1",
+			cache.WithTTL(time.Duration(rule.CooldownSeconds)*time.Second))
+		if errors.Is(err, cache.ErrKeyExists) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("can't set cooldown: %w", err)
+		}
+	}
+
+	limits := []struct {
+		key string
+		max uint
+	}{
+		{key: "sender:" + rule.UserID + ":" + sender, max: s.config.MaxPerSender},
+		{key: "user:" + rule.UserID, max: s.config.MaxPerUser},
+	}
+	for _, limit := range limits {
+		if limit.max == 0 {
+			continue
+		}
+
+		count, err := s.cache.Increment(ctx, limit.key, 1, cache.WithTTL(s.config.Window))
+		if err != nil {
+			return false, fmt.Errorf("can't increment counter: %w", err)
+		}
+		if count > int64(limit.max) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,111 @@
+package autoreplies
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestService_Allow(t *testing.T) {
+	ctx := context.Background()
+	first, second := "+79990001234", "+79990005678"
+
+	tests := []struct {
+		name   string
+		config Config
+		rule   Rule
+		// replies are the senders of the messages in order
+		replies []string
+		want    []bool
+	}{
+		{
+			name:    "cooldown",
+			rule:    Rule{ExtID: "rule", UserID: "user", CooldownSeconds: 60},
+			replies: []string{first, first, second, second},
+			want:    []bool{true, false, true, false},
+		},
+		{
+			name:    "per sender",
+			config:  Config{MaxPerSender: 2, Window: time.Minute},
+			rule:    Rule{ExtID: "rule", UserID: "user"},
+			replies: []string{first, first, first, second},
+			want:    []bool{true, true, false, true},
+		},
+		{
+			name:    "per user",
+			config:  Config{MaxPerUser: 3, Window: time.Minute},
+			rule:    Rule{ExtID: "rule", UserID: "user"},
+			replies: []string{first, second, first, second},
+			want:    []bool{true, true, true, false},
+		},
+		{
+			name:    "no limits",
+			rule:    Rule{ExtID: "rule", UserID: "user"},
+			replies: []string{first, first, first},
+			want:    []bool{true, true, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{config: tt.config, cache: cache.NewMemory(0)}
+
+			for i, sender := range tt.replies {
+				got, err := s.allow(ctx, tt.rule, sender)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want[i] {
+					t.Errorf("allow() of reply %d to %s = %v, want %v", i+1, sender, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestService_AllowOtherUser(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{config: Config{MaxPerSender: 1, MaxPerUser: 1, Window: time.Minute}, cache: cache.NewMemory(0)}
+
+	for _, userID := range []string{"user", "other"} {
+		allowed, err := s.allow(ctx, Rule{ExtID: "rule-" + userID, UserID: userID}, "+79990001234")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !allowed {
+			t.Errorf("allow() of %s = false, the limits are per user", userID)
+		}
+	}
+}
+
+func TestService_Claim(t *testing.T) {
+	ctx := context.Background()
+	s := &Service{cache: cache.NewMemory(0)}
+
+	for _, tt := range []struct {
+		deviceID, messageID string
+		want                bool
+	}{
+		{"device", "message", true},
+		{"device", "message", false},
+		{"device", "another", true},
+		{"other", "message", true},
+	} {
+		got, err := s.claim(ctx, tt.deviceID, tt.messageID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("claim(%s, %s) = %v, want %v", tt.deviceID, tt.messageID, got, tt.want)
+		}
+	}
+
+	if replyID("message") != replyID("message") || replyID("message") == replyID("another") {
+		t.Error("replyID() isn't stable per message")
+	}
+	if n := len(replyID("message")); n > 36 {
+		t.Errorf("len(replyID()) = %d, exceeds the message ID column", n)
+	}
+}
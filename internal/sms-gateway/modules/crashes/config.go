@@ -0,0 +1,7 @@
+package crashes
+
+type Config struct {
+	// SentryDSN is the Sentry project DSN to forward reports to, empty to
+	// disable forwarding.
+	SentryDSN string
+}
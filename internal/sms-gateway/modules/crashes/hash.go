@@ -0,0 +1,40 @@
+package crashes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+var (
+	frameLineRegexp   = regexp.MustCompile(`:\d+\)`)
+	hexAddressRegexp  = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	lambdaIndexRegexp = regexp.MustCompile(`\$\d+`)
+)
+
+// stackHash returns the fingerprint of the stack trace. Only the exception
+// type and the stack frames are used, with line numbers, addresses and
+// synthetic class indexes removed, so the same failure produces the same hash
+// regardless of the message or minor build differences.
+func stackHash(exception, stackTrace string) string {
+	frames := []string{strings.TrimSpace(exception)}
+	for _, line := range strings.Split(stackTrace, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "at ") {
+			continue
+		}
+
+		line = frameLineRegexp.ReplaceAllString(line, ")")
+		line = hexAddressRegexp.ReplaceAllString(line, "0x")
+		line = lambdaIndexRegexp.ReplaceAllString(line, "$")
+		frames = append(frames, line)
+	}
+
+	if len(frames) == 1 {
+		frames = append(frames, strings.TrimSpace(stackTrace))
+	}
+
+	hash := sha256.Sum256([]byte(strings.Join(frames, "\n")))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,29 @@
+package crashes
+
+import "testing"
+
+func TestStackHash(t *testing.T) {
+	trace := `java.lang.IllegalStateException: message 1
+	at me.capcom.smsgateway.modules.messages.MessagesService.send(MessagesService.kt:120)
+	at me.capcom.smsgateway.modules.messages.MessagesService$send$1.invoke(MessagesService.kt:88)`
+	sameFailure := `java.lang.IllegalStateException: another message
+	at me.capcom.smsgateway.modules.messages.MessagesService.send(MessagesService.kt:125)
+	at me.capcom.smsgateway.modules.messages.MessagesService$send$2.invoke(MessagesService.kt:90)`
+	otherFailure := `java.lang.IllegalStateException: message 1
+	at me.capcom.smsgateway.modules.webhooks.WebhooksService.emit(WebhooksService.kt:42)`
+
+	hash := stackHash("java.lang.IllegalStateException", trace)
+	if len(hash) != 64 {
+		t.Fatalf("stackHash() length = %d, want 64", len(hash))
+	}
+
+	if got := stackHash("java.lang.IllegalStateException", sameFailure); got != hash {
+		t.Errorf("stackHash() of the same failure = %s, want %s", got, hash)
+	}
+	if got := stackHash("java.lang.IllegalStateException", otherFailure); got == hash {
+		t.Errorf("stackHash() of other failure must differ")
+	}
+	if got := stackHash("java.lang.RuntimeException", trace); got == hash {
+		t.Errorf("stackHash() of other exception must differ")
+	}
+}
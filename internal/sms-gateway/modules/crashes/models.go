@@ -0,0 +1,49 @@
+package crashes
+
+import (
+	"fmt"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"gorm.io/gorm"
+)
+
+// Kind is the type of the failure.
+type Kind string
+
+const (
+	KindCrash Kind = "crash"
+	KindANR   Kind = "anr"
+)
+
+// CrashReport is a unique failure of the app version. Repeated reports with
+// the same stack hash increment the counter.
+type CrashReport struct {
+	ID         uint64 `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	StackHash  string `gorm:"not null;type:char(64);uniqueIndex:unq_crash_reports_hash_version,priority:1"`
+	AppVersion string `gorm:"not null;type:varchar(32);uniqueIndex:unq_crash_reports_hash_version,priority:2"`
+
+	Kind       Kind   `gorm:"not null;type:varchar(8)"`
+	Exception  string `gorm:"not null;type:varchar(256)"`
+	Message    string `gorm:"not null;type:varchar(1024)"`
+	StackTrace string `gorm:"not null;type:text"`
+
+	Count        uint64 `gorm:"not null;default:1"`
+	LastDeviceID string `gorm:"not null;type:varchar(21)"`
+
+	models.TimedModel
+}
+
+// VersionStats is the number of failures of the app version.
+type VersionStats struct {
+	AppVersion string
+	Kind       Kind
+	Unique     int64
+	Total      int64
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&CrashReport{}); err != nil {
+		return fmt.Errorf("crash_reports migration failed: %w", err)
+	}
+	return nil
+}
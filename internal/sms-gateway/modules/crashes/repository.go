@@ -0,0 +1,62 @@
+package crashes
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Upsert inserts the report or increments the counter of the existing one
+// with the same stack hash and app version.
+func (r *repository) Upsert(report *CrashReport) error {
+	return r.db.
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]any{
+				"count":          gorm.Expr("count + 1"),
+				"message":        report.Message,
+				"last_device_id": report.LastDeviceID,
+			}),
+		}).
+		Create(report).
+		Error
+}
+
+// Stats returns the number of unique and total failures per app version.
+func (r *repository) Stats() ([]VersionStats, error) {
+	stats := []VersionStats{}
+	err := r.db.
+		Model(&CrashReport{}).
+		Select("app_version, kind, COUNT(*) AS `unique`, SUM(count) AS total").
+		Group("app_version, kind").
+		Order("app_version DESC, kind").
+		Scan(&stats).
+		Error
+
+	return stats, err
+}
+
+// Select returns the most frequent reports, optionally of the app version.
+func (r *repository) Select(appVersion string, limit int) ([]CrashReport, error) {
+	query := r.db.Model(&CrashReport{})
+	if appVersion != "" {
+		query = query.Where("app_version = ?", appVersion)
+	}
+
+	reports := []CrashReport{}
+	err := query.
+		Order("count DESC, updated_at DESC").
+		Limit(limit).
+		Find(&reports).
+		Error
+
+	return reports, err
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
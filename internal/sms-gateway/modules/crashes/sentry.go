@@ -0,0 +1,133 @@
+package crashes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const sentryTimeout = 10 * time.Second
+
+// sentryForwarder sends reports to Sentry using the store API, so no SDK is
+// required.
+type sentryForwarder struct {
+	endpoint string
+	auth     string
+
+	client *http.Client
+}
+
+func newSentryForwarder(dsn string) (*sentryForwarder, error) {
+	if dsn == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse sentry dsn: %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("sentry dsn has no public key")
+	}
+
+	prefix, projectID := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if projectID == "" {
+		return nil, errors.New("sentry dsn has no project id")
+	}
+
+	endpoint := url.URL{
+		Scheme: u.Scheme,
+		Host:   u.Host,
+		Path:   path.Join(prefix, "api", projectID, "store") + "/",
+	}
+
+	return &sentryForwarder{
+		endpoint: endpoint.String(),
+		auth: fmt.Sprintf(
+			"Sentry sentry_version=7, sentry_client=android-sms-gateway/1.0, sentry_key=%s",
+			u.User.Username(),
+		),
+		client: &http.Client{Timeout: sentryTimeout},
+	}, nil
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sentryEvent struct {
+	EventID   string `json:"event_id"`
+	Timestamp string `json:"timestamp"`
+	Level     string `json:"level"`
+	Platform  string `json:"platform"`
+	Release   string `json:"release"`
+	Exception struct {
+		Values []sentryException `json:"values"`
+	} `json:"exception"`
+	Fingerprint []string          `json:"fingerprint"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]any    `json:"extra"`
+}
+
+func (f *sentryForwarder) Forward(ctx context.Context, report Report, hash string) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("can't generate event id: %w", err)
+	}
+
+	event := sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   report.OccurredAt.UTC().Format(time.RFC3339),
+		Level:       "fatal",
+		Platform:    "java",
+		Release:     report.AppVersion,
+		Fingerprint: []string{hash},
+		Tags: map[string]string{
+			"kind":            string(report.Kind),
+			"device_id":       report.DeviceID,
+			"android_version": report.AndroidVersion,
+		},
+		Extra: map[string]any{
+			"stacktrace": report.StackTrace,
+		},
+	}
+	event.Exception.Values = []sentryException{{Type: report.Exception, Value: report.Message}}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("can't marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", f.auth)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't send event: %w", err)
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
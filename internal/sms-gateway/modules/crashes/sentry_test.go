@@ -0,0 +1,63 @@
+package crashes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSentryForwarder(t *testing.T) {
+	f, err := newSentryForwarder("https://public@o1.ingest.sentry.io/prefix/42")
+	if err != nil {
+		t.Fatalf("newSentryForwarder() error = %v", err)
+	}
+	if want := "https://o1.ingest.sentry.io/prefix/api/42/store/"; f.endpoint != want {
+		t.Errorf("endpoint = %s, want %s", f.endpoint, want)
+	}
+	if !strings.Contains(f.auth, "sentry_key=public") {
+		t.Errorf("auth = %s, want sentry_key=public", f.auth)
+	}
+
+	if f, err := newSentryForwarder(""); f != nil || err != nil {
+		t.Errorf("newSentryForwarder(\"\") = %v, %v, want nil, nil", f, err)
+	}
+	if _, err := newSentryForwarder("https://o1.ingest.sentry.io/42"); err == nil {
+		t.Error("newSentryForwarder() without key expected error")
+	}
+}
+
+func TestSentryForwarder_Forward(t *testing.T) {
+	var event sentryEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/1/store/" || !strings.Contains(r.Header.Get("X-Sentry-Auth"), "sentry_key=key") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&event)
+	}))
+	defer server.Close()
+
+	f, err := newSentryForwarder(strings.Replace(server.URL, "://", "://key@", 1) + "/1")
+	if err != nil {
+		t.Fatalf("newSentryForwarder() error = %v", err)
+	}
+
+	report := Report{
+		Kind:       KindANR,
+		AppVersion: "1.40.0",
+		Exception:  "ANR",
+		Message:    "Input dispatching timed out",
+		OccurredAt: time.Now(),
+	}
+	if err := f.Forward(context.Background(), report, "hash"); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+
+	if event.Release != "1.40.0" || event.Tags["kind"] != "anr" || len(event.Fingerprint) != 1 || event.Fingerprint[0] != "hash" {
+		t.Errorf("Forward() sent %+v", event)
+	}
+}
@@ -0,0 +1,151 @@
+package crashes
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	maxExceptionLength = 256
+	maxMessageLength   = 1024
+	// maxStackTraceSize is the size of the TEXT column in bytes.
+	maxStackTraceSize = 65535
+
+	// sentryWorkers is the max number of the reports forwarded concurrently,
+	// the reports beyond it aren't forwarded.
+	sentryWorkers = 4
+)
+
+// Report is a crash or ANR report sent by the device.
+type Report struct {
+	DeviceID       string
+	Kind           Kind
+	AppVersion     string
+	AndroidVersion string
+	Exception      string
+	Message        string
+	StackTrace     string
+	OccurredAt     time.Time
+}
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	Reports *repository
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	reports *repository
+	sentry  *sentryForwarder
+	// forwarding limits the reports forwarded concurrently
+	forwarding chan struct{}
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) (*Service, error) {
+	sentry, err := newSentryForwarder(params.Config.SentryDSN)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		reports: params.Reports,
+		sentry:  sentry,
+
+		forwarding: make(chan struct{}, sentryWorkers),
+
+		logger: params.Logger.Named("service"),
+	}, nil
+}
+
+// Submit stores the report deduplicated by the stack hash and asynchronously
+// forwards it to Sentry if configured. The report isn't forwarded if the max
+// number of the reports is being forwarded.
+func (s *Service) Submit(report Report) error {
+	hash := stackHash(report.Exception, report.StackTrace)
+
+	model := CrashReport{
+		StackHash:    hash,
+		AppVersion:   report.AppVersion,
+		Kind:         report.Kind,
+		Exception:    truncate(report.Exception, maxExceptionLength),
+		Message:      truncate(report.Message, maxMessageLength),
+		StackTrace:   truncateBytes(report.StackTrace, maxStackTraceSize),
+		Count:        1,
+		LastDeviceID: report.DeviceID,
+	}
+
+	if err := s.reports.Upsert(&model); err != nil {
+		return fmt.Errorf("can't store report: %w", err)
+	}
+
+	if s.sentry != nil {
+		s.forward(report, hash)
+	}
+
+	return nil
+}
+
+// forward sends the report to Sentry in the background.
+func (s *Service) forward(report Report, hash string) {
+	select {
+	case s.forwarding <- struct{}{}:
+	default:
+		s.logger.Warn("Too many reports are forwarded to Sentry, the report is skipped", zap.String("stack_hash", hash))
+		return
+	}
+
+	go func() {
+		defer func() { <-s.forwarding }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), sentryTimeout)
+		defer cancel()
+
+		if err := s.sentry.Forward(ctx, report, hash); err != nil {
+			s.logger.Warn("Can't forward report to Sentry", zap.String("stack_hash", hash), zap.Error(err))
+		}
+	}()
+}
+
+// Stats returns the number of failures per app version.
+func (s *Service) Stats() ([]VersionStats, error) {
+	return s.reports.Stats()
+}
+
+// Top returns the most frequent failures, optionally of the app version.
+func (s *Service) Top(appVersion string, limit int) ([]CrashReport, error) {
+	return s.reports.Select(appVersion, limit)
+}
+
+// truncate cuts s to at most n runes.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+
+	return string(runes[:n])
+}
+
+// truncateBytes cuts s to at most n bytes on a rune boundary.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,45 @@
+package crashes
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{name: "short", s: "trace", n: 8, want: "trace"},
+		{name: "ascii", s: "trace", n: 3, want: "tra"},
+		// "я" is 2 bytes, the cut inside it drops the whole rune
+		{name: "multibyte boundary", s: "яяя", n: 4, want: "яя"},
+		{name: "multibyte middle", s: "яяя", n: 3, want: "я"},
+		// "😀" is 4 bytes
+		{name: "emoji", s: "a😀", n: 4, want: "a"},
+		{name: "zero", s: "я", n: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateBytes(tt.s, tt.n); got != tt.want {
+				t.Errorf("truncateBytes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateBytes_StackTrace(t *testing.T) {
+	// the trace of the max length in runes exceeds the column in bytes
+	trace := strings.Repeat("я", maxStackTraceSize)
+
+	got := truncateBytes(trace, maxStackTraceSize)
+	if len(got) > maxStackTraceSize {
+		t.Errorf("size = %d, want at most %d", len(got), maxStackTraceSize)
+	}
+	if !utf8.ValidString(got) {
+		t.Error("truncated trace isn't valid UTF-8")
+	}
+}
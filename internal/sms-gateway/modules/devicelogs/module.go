@@ -0,0 +1,18 @@
+package devicelogs
+
+import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"devicelogs",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("devicelogs")
+	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("devicelogs")
+	}, fx.Private),
+	fx.Provide(NewService),
+)
@@ -0,0 +1,158 @@
+package devicelogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxEntries is the number of recent entries kept per device.
+	maxEntries = 1000
+	// retention is the max age of the kept entries.
+	retention = 72 * time.Hour
+	// maxAppendAttempts is the number of attempts to store the batch if the
+	// entries of the device are created or expire concurrently.
+	maxAppendAttempts = 3
+)
+
+// errUnchanged aborts the update of the entries which are left intact.
+var errUnchanged = errors.New("unchanged")
+
+type Service struct {
+	cache *cache.Typed[[]Entry]
+
+	logger *zap.Logger
+}
+
+func NewService(c cache.Cache, logger *zap.Logger) *Service {
+	return &Service{
+		cache: cache.NewTyped[[]Entry](c),
+
+		logger: logger,
+	}
+}
+
+// Append stores the batch of entries uploaded by the device. Only the most
+// recent maxEntries entries not older than retention are kept. The batch is
+// appended atomically, so the concurrent uploads don't overwrite each other,
+// and the stored entries are left intact if they can't be loaded.
+func (s *Service) Append(ctx context.Context, deviceID string, batch []Entry) error {
+	threshold := time.Now().Add(-retention)
+	for range maxAppendAttempts {
+		_, err := s.cache.Update(ctx, deviceID, func(entries []Entry) ([]Entry, error) {
+			return trim(append(entries, batch...), threshold), nil
+		}, cache.WithTTL(retention))
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			err = s.cache.SetOrFail(ctx, deviceID, trim(slices.Clone(batch), threshold), cache.WithTTL(retention))
+			if errors.Is(err, cache.ErrKeyExists) {
+				// the first batch is stored concurrently
+				continue
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("can't store logs: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("can't store logs: %w", cache.ErrKeyExists)
+}
+
+// Select returns entries of the device created in the range, oldest first.
+// Zero bounds are not applied.
+func (s *Service) Select(ctx context.Context, deviceID string, from, to time.Time) ([]Entry, error) {
+	entries, err := s.load(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if !from.IsZero() && e.CreatedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && e.CreatedAt.After(to) {
+			continue
+		}
+		result = append(result, e)
+	}
+
+	return result, nil
+}
+
+// ErasePhoneNumbers removes the entries of the devices mentioning any of the
+// phone numbers in the message or the context and returns the number of the
+// removed entries.
+func (s *Service) ErasePhoneNumbers(ctx context.Context, deviceIDs []string, phoneNumbers []string) (int, error) {
+	removed := 0
+	for _, deviceID := range deviceIDs {
+		// the erasure doesn't extend the retention of the entries
+		ttl, err := s.cache.GetTTL(ctx, deviceID)
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return removed, fmt.Errorf("can't get logs lifetime: %w", err)
+		}
+
+		count := 0
+		_, err = s.cache.Update(ctx, deviceID, func(entries []Entry) ([]Entry, error) {
+			count = len(entries)
+			entries = slices.DeleteFunc(entries, func(e Entry) bool {
+				return e.mentions(phoneNumbers)
+			})
+			count -= len(entries)
+			if count == 0 {
+				return nil, errUnchanged
+			}
+
+			return entries, nil
+		}, cache.WithTTL(ttl))
+		switch {
+		case errors.Is(err, errUnchanged), errors.Is(err, cache.ErrKeyNotFound):
+			continue
+		case err != nil:
+			return removed, fmt.Errorf("can't store logs: %w", err)
+		}
+		removed += count
+	}
+
+	return removed, nil
+}
+
+func (s *Service) load(ctx context.Context, deviceID string) ([]Entry, error) {
+	entries, err := s.cache.Get(ctx, deviceID)
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get logs: %w", err)
+	}
+
+	return entries, nil
+}
+
+// trim sorts the entries by creation time and drops the ones created before
+// the threshold or exceeding maxEntries.
+func trim(entries []Entry, threshold time.Time) []Entry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	start := sort.Search(len(entries), func(i int) bool {
+		return !entries[i].CreatedAt.Before(threshold)
+	})
+	if len(entries)-start > maxEntries {
+		start = len(entries) - maxEntries
+	}
+
+	return entries[start:]
+}
@@ -0,0 +1,138 @@
+package devicelogs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func TestService_Append(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(cache.NewMemory(0), zap.NewNop())
+	now := time.Now()
+
+	batch := []Entry{
+		{Module: "messages", Message: "old", CreatedAt: now.Add(-retention - time.Minute)},
+		{Module: "messages", Message: "second", CreatedAt: now.Add(-time.Minute)},
+		{Module: "messages", Message: "first", CreatedAt: now.Add(-time.Hour)},
+	}
+	if err := svc.Append(ctx, "device", batch); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	entries, err := svc.Select(ctx, "device", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].Message != "first" || entries[1].Message != "second" {
+		t.Errorf("Select() = %+v, want first and second", entries)
+	}
+
+	entries, err = svc.Select(ctx, "device", now.Add(-30*time.Minute), time.Time{})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "second" {
+		t.Errorf("Select(from) = %+v, want second", entries)
+	}
+
+	many := make([]Entry, maxEntries+10)
+	for i := range many {
+		many[i] = Entry{Message: "bulk", CreatedAt: now.Add(time.Duration(i) * time.Millisecond)}
+	}
+	if err := svc.Append(ctx, "device", many); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	entries, err = svc.Select(ctx, "device", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(entries) != maxEntries {
+		t.Errorf("len(Select()) = %d, want %d", len(entries), maxEntries)
+	}
+	if !entries[len(entries)-1].CreatedAt.Equal(many[len(many)-1].CreatedAt) {
+		t.Errorf("the newest entry must be kept")
+	}
+
+	other, err := svc.Select(ctx, "other", time.Time{}, time.Time{})
+	if err != nil || len(other) != 0 {
+		t.Errorf("Select(other) = %v, %v, want empty", other, err)
+	}
+}
+
+func TestService_AppendConcurrent(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(cache.NewMemory(0), zap.NewNop())
+
+	const batches = 5
+	wg := sync.WaitGroup{}
+	for range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svc.Append(ctx, "device", []Entry{{Message: "entry", CreatedAt: time.Now()}}); err != nil {
+				t.Errorf("Append() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := svc.Select(ctx, "device", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(entries) != batches {
+		t.Errorf("len(Select()) = %d, want %d", len(entries), batches)
+	}
+}
+
+func TestService_AppendLoadError(t *testing.T) {
+	ctx := context.Background()
+	storage := cache.NewMemory(0)
+	svc := NewService(storage, zap.NewNop())
+
+	if err := storage.Set(ctx, "device", "not a list"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if err := svc.Append(ctx, "device", []Entry{{Message: "entry", CreatedAt: time.Now()}}); !errors.Is(err, cache.ErrInvalidValue) {
+		t.Errorf("Append() error = %v, want %v", err, cache.ErrInvalidValue)
+	}
+	if value, _ := storage.Get(ctx, "device"); value != "not a list" {
+		t.Errorf("stored logs = %q, must be left intact", value)
+	}
+}
+
+func TestService_ErasePhoneNumbers(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(cache.NewMemory(0), zap.NewNop())
+	now := time.Now()
+
+	batch := []Entry{
+		{Module: "messages", Message: "sent to +79990001234", CreatedAt: now},
+		{Module: "messages", Message: "received", Context: map[string]string{"sender": "+79990001234"}, CreatedAt: now},
+		{Module: "messages", Message: "sent to +79990005678", CreatedAt: now},
+	}
+	if err := svc.Append(ctx, "device", batch); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	removed, err := svc.ErasePhoneNumbers(ctx, []string{"device", "missing"}, []string{"+79990001234"})
+	if err != nil || removed != 2 {
+		t.Fatalf("ErasePhoneNumbers() = %d, %v, want 2", removed, err)
+	}
+
+	entries, err := svc.Select(ctx, "device", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "sent to +79990005678" {
+		t.Errorf("Select() after erasure = %+v, want the other number only", entries)
+	}
+}
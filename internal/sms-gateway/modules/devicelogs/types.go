@@ -0,0 +1,32 @@
+package devicelogs
+
+import (
+	"strings"
+	"time"
+)
+
+// Entry is a single log record uploaded by the device.
+type Entry struct {
+	Priority  string            `json:"priority"`
+	Module    string            `json:"module"`
+	Message   string            `json:"message"`
+	Context   map[string]string `json:"context,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// mentions returns true if the message or the context of the entry contains
+// any of the phone numbers.
+func (e Entry) mentions(phoneNumbers []string) bool {
+	for _, phoneNumber := range phoneNumbers {
+		if strings.Contains(e.Message, phoneNumber) {
+			return true
+		}
+		for _, v := range e.Context {
+			if strings.Contains(v, phoneNumber) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
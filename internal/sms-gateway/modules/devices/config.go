@@ -4,4 +4,10 @@ import "time"
 
 type Config struct {
 	UnusedLifetime time.Duration
+	// TokenCacheTTL is how long the device resolved by the auth token is
+	// cached, zero disables caching.
+	TokenCacheTTL time.Duration
+	// OnlineWindow is the period since the last activity during which the
+	// device is considered online.
+	OnlineWindow time.Duration
 }
@@ -4,4 +4,6 @@ import "errors"
 
 var (
 	ErrInvalidUser = errors.New("invalid user")
+	// ErrSuspended is returned when the suspended device is authorized.
+	ErrSuspended = errors.New("device is suspended")
 )
@@ -0,0 +1,53 @@
+package devices
+
+import (
+	"sync"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+// LifecycleEventType is the type of the device lifecycle change.
+type LifecycleEventType string
+
+const (
+	LifecycleRegistered        LifecycleEventType = "device:registered"
+	LifecycleRenamed           LifecycleEventType = "device:renamed"
+	LifecycleSuspended         LifecycleEventType = "device:suspended"
+	LifecycleResumed           LifecycleEventType = "device:resumed"
+	LifecycleDeleted           LifecycleEventType = "device:deleted"
+	LifecycleTokenRotated      LifecycleEventType = "device:token-rotated"
+	LifecycleAppVersionChanged LifecycleEventType = "device:app-version-changed"
+)
+
+// LifecycleEvent describes a change of the device. Previous contains the
+// value replaced by the change, if any.
+type LifecycleEvent struct {
+	Type     LifecycleEventType
+	Device   models.Device
+	Previous *string
+}
+
+// LifecycleHandler is called synchronously after the change is stored, so it
+// must not block.
+type LifecycleHandler func(event LifecycleEvent)
+
+type lifecycleHandlers struct {
+	mux      sync.RWMutex
+	handlers []LifecycleHandler
+}
+
+func (l *lifecycleHandlers) add(handler LifecycleHandler) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	l.handlers = append(l.handlers, handler)
+}
+
+func (l *lifecycleHandlers) emit(event LifecycleEvent) {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+
+	for _, h := range l.handlers {
+		h(event)
+	}
+}
@@ -0,0 +1,134 @@
+package devices
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+// SortField is the field the list of the devices is sorted by.
+type SortField string
+
+const (
+	SortByName      SortField = "name"
+	SortByLastSeen  SortField = "lastSeen"
+	SortByCreatedAt SortField = "createdAt"
+)
+
+var sortColumns = map[SortField]string{
+	SortByName:      "name",
+	SortByLastSeen:  "last_seen",
+	SortByCreatedAt: "created_at",
+}
+
+// ListQuery selects a page of the devices of the user.
+type ListQuery struct {
+	Filters []SelectFilter
+
+	// Online selects the online or the offline devices, all if nil.
+	Online *bool
+	// MinAppVersion and MaxAppVersion bound the app version inclusively,
+	// empty for no bound. The devices without a version don't match any
+	// bound.
+	MinAppVersion string
+	MaxAppVersion string
+
+	// SortBy defaults to the creation time.
+	SortBy SortField
+	Desc   bool
+
+	// Limit is the page size, zero for all the devices.
+	Limit  int
+	Offset int
+}
+
+// List returns the page of the devices of the user and the total number of
+// the matching devices. The filters, the version range and the page are
+// applied by the database.
+func (s *Service) List(userID string, query ListQuery) ([]models.Device, int64, error) {
+	column, ok := sortColumns[query.SortBy]
+	if !ok {
+		column = sortColumns[SortByCreatedAt]
+	}
+
+	filters := append(slices.Clip(query.Filters), WithUserID(userID))
+	if query.Online != nil {
+		if *query.Online {
+			filters = append(filters, ActiveWithin(s.config.OnlineWindow))
+		} else {
+			filters = append(filters, InactiveFor(s.config.OnlineWindow))
+		}
+	}
+	if query.MinAppVersion != "" || query.MaxAppVersion != "" {
+		filters = append(filters, withAppVersionRange(query.MinAppVersion, query.MaxAppVersion))
+	}
+
+	items, err := s.devices.Select(append(slices.Clip(filters), orderBy(column, query.Desc), page(query.Limit, query.Offset))...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// the total is known without counting unless the page is full or empty
+	// beyond the first one
+	if (query.Limit <= 0 || len(items) < query.Limit) && (len(items) > 0 || query.Offset == 0) {
+		return items, int64(query.Offset + len(items)), nil
+	}
+
+	total, err := s.devices.Count(filters...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+const (
+	// versionPartLimit bounds each part of the version code, the greater
+	// parts are clamped.
+	versionPartLimit = 1_000_000
+	// versionCodeParts is the number of the version parts in the code, the
+	// rest are ignored.
+	versionCodeParts = 3
+)
+
+// versionCode encodes the dot-separated numeric version like "1.40.2" as a
+// number with the same order, so the versions are compared by the database.
+// The missing parts are zeros, the pre-release and build suffixes and the
+// parts after the patch are ignored.
+func versionCode(version string) int64 {
+	version = strings.TrimPrefix(version, "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, _, _ = strings.Cut(version, "-")
+
+	fields := strings.Split(version, ".")
+	code := int64(0)
+	for i := range versionCodeParts {
+		part := 0
+		if i < len(fields) {
+			part, _ = strconv.Atoi(fields[i])
+		}
+		code = code*versionPartLimit + int64(min(max(part, 0), versionPartLimit-1))
+	}
+
+	return code
+}
+
+// versionCodeSQL is the SQL expression of the versionCode of the column.
+func versionCodeSQL(column string) string {
+	version := fmt.Sprintf("SUBSTRING_INDEX(SUBSTRING_INDEX(TRIM(LEADING 'v' FROM %s), '+', 1), '-', 1)", column)
+	dots := fmt.Sprintf("(LENGTH(%[1]s) - LENGTH(REPLACE(%[1]s, '.', '')))", version)
+
+	code := "0"
+	for i := range versionCodeParts {
+		part := fmt.Sprintf(
+			"LEAST(CAST(IF(%s >= %d, SUBSTRING_INDEX(SUBSTRING_INDEX(%s, '.', %d), '.', -1), '0') AS UNSIGNED), %d)",
+			dots, i, version, i+1, versionPartLimit-1,
+		)
+		code = fmt.Sprintf("(%s * %d + %s)", code, versionPartLimit, part)
+	}
+
+	return code
+}
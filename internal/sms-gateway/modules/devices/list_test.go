@@ -0,0 +1,49 @@
+package devices
+
+import (
+	"cmp"
+	"strings"
+	"testing"
+)
+
+func TestVersionCode(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.40.2", "1.40.2", 0},
+		{"1.9.0", "1.10.0", -1},
+		{"1.40.10", "1.40.9", 1},
+		{"1.40", "1.40.0", 0},
+		{"1.40.1", "1.40", 1},
+		{"v2.0.0", "1.99.99", 1},
+		{"1.40.0-beta.1", "1.40.0", 0},
+		{"1.40.0+123", "1.40.0", 0},
+		{"1.40.0.5", "1.40.0", 0},
+		{"1.2000000.0", "1.999999.0", 0},
+	}
+
+	for _, tt := range tests {
+		if got := cmp.Compare(versionCode(tt.a), versionCode(tt.b)); got != tt.want {
+			t.Errorf("compare(versionCode(%q), versionCode(%q)) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersionCodeSQL(t *testing.T) {
+	expr := versionCodeSQL("app_version")
+
+	for _, part := range []string{
+		"TRIM(LEADING 'v' FROM app_version)",
+		"SUBSTRING_INDEX(SUBSTRING_INDEX(",
+		"'.', 3), '.', -1)",
+		"999999)",
+	} {
+		if !strings.Contains(expr, part) {
+			t.Errorf("versionCodeSQL() = %s, want to contain %s", expr, part)
+		}
+	}
+	if strings.Count(expr, "(") != strings.Count(expr, ")") {
+		t.Errorf("versionCodeSQL() has unbalanced parentheses: %s", expr)
+	}
+}
@@ -0,0 +1,59 @@
+package devices
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	MetricLastSeenDevicesTotal   = "last_seen_devices_total"
+	MetricLastSeenUpdateDuration = "last_seen_update_duration_seconds"
+
+	LabelStatus = "status"
+
+	// StatusUpdated is the device with the stored last seen time moved forward.
+	StatusUpdated = "updated"
+	// StatusSkipped is the removed device or the device with a newer stored
+	// last seen time.
+	StatusSkipped = "skipped"
+	// StatusFailed is the device of the failed update.
+	StatusFailed = "failed"
+)
+
+type metrics struct {
+	lastSeenDevices  *prometheus.CounterVec
+	lastSeenDuration prometheus.Histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		lastSeenDevices: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "devices",
+			Name:      MetricLastSeenDevicesTotal,
+			Help:      "Total number of devices in the last seen updates by status",
+		}, []string{LabelStatus}),
+		lastSeenDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "sms",
+			Subsystem: "devices",
+			Name:      MetricLastSeenUpdateDuration,
+			Help:      "Duration of a single bulk update of the last seen time",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+		}),
+	}
+}
+
+// ObserveLastSeenUpdate records the bulk update of the chunk of devices.
+func (m *metrics) ObserveLastSeenUpdate(duration time.Duration, devices int, updated int64, err error) {
+	m.lastSeenDuration.Observe(duration.Seconds())
+
+	if err != nil {
+		m.lastSeenDevices.WithLabelValues(StatusFailed).Add(float64(devices))
+		return
+	}
+
+	m.lastSeenDevices.WithLabelValues(StatusUpdated).Add(float64(updated))
+	m.lastSeenDevices.WithLabelValues(StatusSkipped).Add(float64(int64(devices) - updated))
+}
@@ -1,6 +1,7 @@
 package devices
 
 import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/cleaner"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -22,6 +23,10 @@ var Module = fx.Module(
 		newDevicesRepository,
 		fx.Private,
 	),
+	fx.Provide(newMetrics, fx.Private),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("devices")
+	}, fx.Private),
 	fx.Provide(func(p ServiceParams) FxResult {
 		svc := NewService(p)
 		return FxResult{
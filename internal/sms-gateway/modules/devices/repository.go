@@ -3,6 +3,9 @@ package devices
 import (
 	"context"
 	"errors"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
@@ -30,6 +33,17 @@ func (r *repository) Select(filter ...SelectFilter) ([]models.Device, error) {
 	return devices, f.apply(r.db).Find(&devices).Error
 }
 
+// Count returns the number of the devices with the given filters.
+func (r *repository) Count(filter ...SelectFilter) (int64, error) {
+	if len(filter) == 0 {
+		return 0, ErrInvalidFilter
+	}
+
+	total := int64(0)
+
+	return total, newFilter(filter...).apply(r.db.Model(&models.Device{})).Count(&total).Error
+}
+
 // Exists checks if there exists a device with the given filters.
 //
 // If the device does not exist, it returns false and nil error. If there is an
@@ -67,24 +81,49 @@ func (r *repository) Insert(device *models.Device) error {
 	return r.db.Create(device).Error
 }
 
-func (r *repository) UpdatePushToken(id, token string) error {
-	return r.db.Model(&models.Device{}).Where("id = ?", id).Update("push_token", token).Error
+func (r *repository) Update(id string, fields map[string]any) error {
+	return r.db.Model(&models.Device{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *repository) UpdateEventSubscriptions(id string, events []string) error {
+	return r.db.Model(&models.Device{ID: id}).Select("EventSubscriptions").Updates(&models.Device{EventSubscriptions: events}).Error
 }
 
-func (r *repository) SetLastSeen(ctx context.Context, id string, lastSeen time.Time) error {
-	if lastSeen.IsZero() {
-		return nil // ignore zero timestamps
+// SetLastSeen updates the last seen time of the devices with a single
+// statement and returns the number of updated devices. The stored time only
+// moves forward, so the concurrent updates of the same devices are safe.
+func (r *repository) SetLastSeen(ctx context.Context, batch map[string]time.Time) (int64, error) {
+	if len(batch) == 0 {
+		return 0, nil
 	}
+
+	// the same order of the rows for all the updates
+	ids := slices.Sorted(maps.Keys(batch))
+	args := make([]any, 0, len(ids)*2)
+	for _, id := range ids {
+		args = append(args, id, batch[id])
+	}
+
 	res := r.db.WithContext(ctx).
 		Model(&models.Device{}).
-		Where("id = ? AND last_seen < ?", id, lastSeen).
-		UpdateColumn("last_seen", lastSeen)
-	if res.Error != nil {
-		return res.Error
-	}
+		Where("id IN ?", ids).
+		UpdateColumn("last_seen", gorm.Expr(
+			"GREATEST(last_seen, CASE id"+strings.Repeat(" WHEN ? THEN ?", len(ids))+" END)",
+			args...,
+		))
 
-	// RowsAffected==0 => not found or stale timestamp; treat as no-op.
-	return nil
+	// a missing device or a stale timestamp isn't updated and isn't counted
+	return res.RowsAffected, res.Error
+}
+
+// SetPushDegraded marks the push token of the device as degraded unless the
+// device has already registered another one.
+func (r *repository) SetPushDegraded(id, pushToken string, at time.Time) error {
+	return r.db.
+		Model(&models.Device{}).
+		Where("id = ? AND push_token = ? AND push_degraded_at IS NULL", id, pushToken).
+		UpdateColumn("push_degraded_at", at).
+		Error
 }
 
 func (r *repository) Remove(filter ...SelectFilter) error {
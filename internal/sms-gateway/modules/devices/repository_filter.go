@@ -1,6 +1,7 @@
 package devices
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -20,6 +21,13 @@ func WithToken(token string) SelectFilter {
 	}
 }
 
+// WithPushToken selects the devices with the push token.
+func WithPushToken(token string) SelectFilter {
+	return func(f *selectFilter) {
+		f.pushToken = &token
+	}
+}
+
 func WithUserID(userID string) SelectFilter {
 	return func(f *selectFilter) {
 		f.userID = &userID
@@ -32,11 +40,88 @@ func ActiveWithin(duration time.Duration) SelectFilter {
 	}
 }
 
+// WithNameContains selects the devices with the name containing the
+// substring.
+func WithNameContains(substr string) SelectFilter {
+	return func(f *selectFilter) {
+		f.nameContains = &substr
+	}
+}
+
+// WithTag selects the devices with the tag.
+func WithTag(tag string) SelectFilter {
+	return func(f *selectFilter) {
+		f.tag = &tag
+	}
+}
+
+// InactiveFor selects the devices without activity within the duration.
+func InactiveFor(duration time.Duration) SelectFilter {
+	return func(f *selectFilter) {
+		f.inactiveFor = duration
+	}
+}
+
+// LastSeenAfter selects the devices last seen after the time.
+func LastSeenAfter(t time.Time) SelectFilter {
+	return func(f *selectFilter) {
+		f.lastSeenAfter = t
+	}
+}
+
+// LastSeenBefore selects the devices last seen before the time.
+func LastSeenBefore(t time.Time) SelectFilter {
+	return func(f *selectFilter) {
+		f.lastSeenBefore = t
+	}
+}
+
+// withAppVersionRange selects the devices with the app version within the
+// inclusive range, empty for no bound. The devices without a version don't
+// match any bound.
+func withAppVersionRange(minVersion, maxVersion string) SelectFilter {
+	return func(f *selectFilter) {
+		f.minAppVersion = minVersion
+		f.maxAppVersion = maxVersion
+	}
+}
+
+// page limits the devices to the page, zero limit for all the devices.
+func page(limit, offset int) SelectFilter {
+	return func(f *selectFilter) {
+		f.limit = limit
+		f.offset = offset
+	}
+}
+
+// orderBy sorts the devices by the column, the ID breaks the ties.
+func orderBy(column string, desc bool) SelectFilter {
+	return func(f *selectFilter) {
+		f.orderBy = column
+		f.desc = desc
+	}
+}
+
 type selectFilter struct {
 	id           *string
 	userID       *string
 	token        *string
+	pushToken    *string
 	activeWithin time.Duration
+
+	nameContains   *string
+	tag            *string
+	inactiveFor    time.Duration
+	lastSeenAfter  time.Time
+	lastSeenBefore time.Time
+	minAppVersion  string
+	maxAppVersion  string
+
+	orderBy string
+	desc    bool
+
+	limit  int
+	offset int
 }
 
 func newFilter(filters ...SelectFilter) *selectFilter {
@@ -58,11 +143,61 @@ func (f *selectFilter) apply(query *gorm.DB) *gorm.DB {
 	if f.token != nil {
 		query = query.Where("auth_token = ?", *f.token)
 	}
+	if f.pushToken != nil {
+		query = query.Where("push_token = ?", *f.pushToken)
+	}
 	if f.userID != nil {
 		query = query.Where("user_id = ?", *f.userID)
 	}
 	if f.activeWithin != 0 {
 		query = query.Where("last_seen > ?", time.Now().Add(-f.activeWithin))
 	}
+	if f.nameContains != nil {
+		query = query.Where("name LIKE ?", "%"+escapeLike(*f.nameContains)+"%")
+	}
+	if f.tag != nil {
+		query = query.Where("JSON_CONTAINS(tags, JSON_QUOTE(?))", *f.tag)
+	}
+	if f.inactiveFor != 0 {
+		query = query.Where("last_seen <= ?", time.Now().Add(-f.inactiveFor))
+	}
+	if !f.lastSeenAfter.IsZero() {
+		query = query.Where("last_seen > ?", f.lastSeenAfter)
+	}
+	if !f.lastSeenBefore.IsZero() {
+		query = query.Where("last_seen < ?", f.lastSeenBefore)
+	}
+	if f.minAppVersion != "" || f.maxAppVersion != "" {
+		query = query.Where("app_version IS NOT NULL AND app_version <> ''")
+	}
+	if f.minAppVersion != "" {
+		query = query.Where(appVersionCode+" >= ?", versionCode(f.minAppVersion))
+	}
+	if f.maxAppVersion != "" {
+		query = query.Where(appVersionCode+" <= ?", versionCode(f.maxAppVersion))
+	}
+	if f.orderBy != "" {
+		direction := " ASC"
+		if f.desc {
+			direction = " DESC"
+		}
+		query = query.Order(f.orderBy + direction).Order("id" + direction)
+	}
+	if f.limit > 0 {
+		query = query.Limit(f.limit)
+	}
+	if f.offset > 0 {
+		query = query.Offset(f.offset)
+	}
 	return query
 }
+
+// appVersionCode is the SQL expression of the numeric code of the app version.
+var appVersionCode = versionCodeSQL("app_version")
+
+// escapeLike escapes the wildcards of the LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,120 @@
+//go:build cgo
+
+package devices
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDriver is SQLite with the MySQL GREATEST function used by SetLastSeen.
+const testDriver = "sqlite3_devices"
+
+func init() {
+	sql.Register(testDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// the times are stored as text in the same format and zone, so
+			// they are ordered as strings
+			return conn.RegisterFunc("greatest", func(a, b string) string {
+				return max(a, b)
+			}, true)
+		},
+	})
+}
+
+// newTestRepository returns the repository backed by the in-memory database
+// with the devices seen at the given times.
+func newTestRepository(t *testing.T, lastSeen map[string]time.Time) *repository {
+	t.Helper()
+
+	db, err := gorm.Open(
+		&sqlite.Dialector{DriverName: testDriver, DSN: ":memory:"},
+		&gorm.Config{Logger: logger.Discard},
+	)
+	if err != nil {
+		t.Fatalf("can't open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("can't get database: %v", err)
+	}
+	// every connection opens its own in-memory database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err := db.Exec("CREATE TABLE devices (id TEXT PRIMARY KEY, last_seen DATETIME NOT NULL)").Error; err != nil {
+		t.Fatalf("can't create table: %v", err)
+	}
+	for id, at := range lastSeen {
+		if err := db.Exec("INSERT INTO devices (id, last_seen) VALUES (?, ?)", id, at).Error; err != nil {
+			t.Fatalf("can't insert device: %v", err)
+		}
+	}
+
+	return &repository{db: db}
+}
+
+func (r *repository) lastSeen(t *testing.T, id string) time.Time {
+	t.Helper()
+
+	var at time.Time
+	if err := r.db.Raw("SELECT last_seen FROM devices WHERE id = ?", id).Row().Scan(&at); err != nil {
+		t.Fatalf("can't select last seen of %s: %v", id, err)
+	}
+
+	return at
+}
+
+func TestRepository_SetLastSeen(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r := newTestRepository(t, map[string]time.Time{
+		"device-1": base,
+		"device-2": base,
+		"device-3": base,
+	})
+
+	// the devices are matched by the ids, a missing device isn't counted
+	updated, err := r.SetLastSeen(ctx, map[string]time.Time{
+		"device-1": base.Add(time.Minute),
+		"device-2": base.Add(2 * time.Minute),
+		"missing":  base.Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("SetLastSeen() error = %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("SetLastSeen() = %d, want 2", updated)
+	}
+
+	// the batches flushed out of order never move the time back
+	if _, err := r.SetLastSeen(ctx, map[string]time.Time{
+		"device-1": base.Add(-time.Minute),
+		"device-2": base.Add(time.Minute),
+		"device-3": base.Add(3 * time.Minute),
+	}); err != nil {
+		t.Fatalf("SetLastSeen() error = %v", err)
+	}
+
+	for id, want := range map[string]time.Time{
+		"device-1": base.Add(time.Minute),
+		"device-2": base.Add(2 * time.Minute),
+		"device-3": base.Add(3 * time.Minute),
+	} {
+		if got := r.lastSeen(t, id); !got.Equal(want) {
+			t.Errorf("last seen of %s = %s, want %s", id, got, want)
+		}
+	}
+
+	if updated, err := r.SetLastSeen(ctx, nil); err != nil || updated != 0 {
+		t.Errorf("SetLastSeen() of empty batch = %d, %v, want 0", updated, err)
+	}
+}
@@ -2,40 +2,55 @@ package devices
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
-	"github.com/capcom6/go-helpers/cache"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/capcom6/go-helpers/anys"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+// lastSeenChunkSize is the maximum number of devices in a single last seen
+// update.
+const lastSeenChunkSize = 200
+
 type ServiceParams struct {
 	fx.In
 
 	Config Config
 
-	Devices *repository
+	Devices     *repository
+	TokensCache cache.Cache
 
 	IDGen db.IDGen
 
-	Logger *zap.Logger
+	Metrics *metrics
+	Logger  *zap.Logger
 }
 
 type Service struct {
 	config Config
 
 	devices     *repository
-	tokensCache *cache.Cache[models.Device]
+	tokensCache *cache.Typed[cachedDevice]
 
 	idGen db.IDGen
 
-	logger *zap.Logger
+	lifecycle lifecycleHandlers
+
+	metrics *metrics
+	logger  *zap.Logger
+}
+
+// OnLifecycle registers the handler called after the device is registered,
+// updated or removed.
+func (s *Service) OnLifecycle(handler LifecycleHandler) {
+	s.lifecycle.add(handler)
 }
 
 func (s *Service) Insert(userID string, device *models.Device) error {
@@ -43,7 +58,13 @@ func (s *Service) Insert(userID string, device *models.Device) error {
 	device.AuthToken = s.idGen()
 	device.UserID = userID
 
-	return s.devices.Insert(device)
+	if err := s.devices.Insert(device); err != nil {
+		return err
+	}
+
+	s.lifecycle.emit(LifecycleEvent{Type: LifecycleRegistered, Device: *device})
+
+	return nil
 }
 
 // Select returns a list of devices for a specific user that match the provided filters.
@@ -53,6 +74,12 @@ func (s *Service) Select(userID string, filter ...SelectFilter) ([]models.Device
 	return s.devices.Select(filter...)
 }
 
+// SelectByPushToken returns the devices of any user registered with the push
+// token.
+func (s *Service) SelectByPushToken(token string) ([]models.Device, error) {
+	return s.devices.Select(WithPushToken(token))
+}
+
 // Exists checks if there exists a device that matches the provided filters.
 //
 // If the device does not exist, it returns false and nil error. If there is an
@@ -77,19 +104,24 @@ func (s *Service) Get(userID string, filter ...SelectFilter) (models.Device, err
 // GetByToken returns a device by token.
 //
 // This method is used to retrieve a device by its auth token. If the device
-// does not exist, it returns ErrNotFound.
+// does not exist, it returns ErrNotFound. The push token of the cached device
+// isn't returned, the HasPushToken mark is set instead.
 func (s *Service) GetByToken(token string) (models.Device, error) {
-	hash := sha256.Sum256([]byte(token))
-	cacheKey := hex.EncodeToString(hash[:])
+	ctx := context.Background()
+	cacheKey := tokenCacheKey(token)
+
+	cached, err := s.tokensCache.Get(ctx, cacheKey)
+	if err == nil {
+		return cached.toModel(token), nil
+	}
 
-	device, err := s.tokensCache.Get(cacheKey)
+	device, err := s.devices.Get(WithToken(token))
 	if err != nil {
-		device, err = s.devices.Get(WithToken(token))
-		if err != nil {
-			return device, fmt.Errorf("can't get device: %w", err)
-		}
+		return device, fmt.Errorf("can't get device: %w", err)
+	}
 
-		if err := s.tokensCache.Set(cacheKey, device); err != nil {
+	if s.config.TokenCacheTTL > 0 {
+		if err := s.tokensCache.Set(ctx, cacheKey, newCachedDevice(device), cache.WithTTL(s.config.TokenCacheTTL)); err != nil {
 			s.logger.Error("can't cache device", zap.Error(err))
 		}
 	}
@@ -97,25 +129,127 @@ func (s *Service) GetByToken(token string) (models.Device, error) {
 	return device, nil
 }
 
-func (s *Service) UpdatePushToken(deviceId string, token string) error {
-	return s.devices.UpdatePushToken(deviceId, token)
+// DeviceUpdate contains the device fields to update, nil fields are left
+// unchanged.
+type DeviceUpdate struct {
+	Name       *string
+	PushToken  *string
+	AppVersion *string
 }
 
-func (s *Service) SetLastSeen(ctx context.Context, batch map[string]time.Time) error {
-	if len(batch) == 0 {
+// Update stores the changed fields of the device and emits the corresponding
+// lifecycle events. Fields that are nil or equal to the current values are
+// ignored, except the push token of the push-degraded device: registering it
+// again clears the mark and counts as the token rotation. The values are
+// compared with the stored device, as the cached one has no push token.
+func (s *Service) Update(device models.Device, update DeviceUpdate) error {
+	device, err := s.devices.Get(WithID(device.ID))
+	if err != nil {
+		return fmt.Errorf("can't get device: %w", err)
+	}
+
+	fields := map[string]any{}
+	events := []LifecycleEvent{}
+
+	if update.Name != nil && !equalPtr(device.Name, *update.Name) {
+		fields["name"] = *update.Name
+		events = append(events, LifecycleEvent{Type: LifecycleRenamed, Previous: device.Name})
+		device.Name = update.Name
+	}
+	if update.PushToken != nil && (!equalPtr(device.PushToken, *update.PushToken) || device.PushDegradedAt != nil) {
+		fields["push_token"] = *update.PushToken
+		fields["push_degraded_at"] = nil
+		events = append(events, LifecycleEvent{Type: LifecycleTokenRotated})
+		device.PushToken = update.PushToken
+		device.PushDegradedAt = nil
+	}
+	if update.AppVersion != nil && !equalPtr(device.AppVersion, *update.AppVersion) {
+		fields["app_version"] = *update.AppVersion
+		events = append(events, LifecycleEvent{Type: LifecycleAppVersionChanged, Previous: device.AppVersion})
+		device.AppVersion = update.AppVersion
+	}
+
+	if len(fields) == 0 {
 		return nil
 	}
 
-	var multiErr error
+	if err := s.devices.Update(device.ID, fields); err != nil {
+		return err
+	}
+
+	s.invalidateToken(device)
+
+	for _, event := range events {
+		event.Device = device
+		s.lifecycle.emit(event)
+	}
+
+	return nil
+}
+
+// SetPushDegraded marks the push token of the device as rejected by the push
+// provider, so the events are delivered by SSE and polling. The mark is
+// cleared when the device registers a token, even the same one.
+func (s *Service) SetPushDegraded(device models.Device) error {
+	if device.PushToken == nil || device.PushDegradedAt != nil {
+		return nil
+	}
+
+	if err := s.devices.SetPushDegraded(device.ID, *device.PushToken, time.Now()); err != nil {
+		return err
+	}
+
+	s.invalidateToken(device)
+
+	return nil
+}
+
+// UpdateEventSubscriptions sets the event types delivered to the device. An
+// empty list subscribes the device to all events.
+func (s *Service) UpdateEventSubscriptions(device models.Device, events []string) error {
+	if len(events) == 0 {
+		events = nil
+	}
+
+	if err := s.devices.UpdateEventSubscriptions(device.ID, events); err != nil {
+		return err
+	}
+
+	s.invalidateToken(device)
+
+	return nil
+}
+
+// SetLastSeen stores the last seen time of the devices with bulk updates of
+// up to lastSeenChunkSize devices, so a large batch doesn't hold the table
+// with a single statement. Zero timestamps are ignored.
+func (s *Service) SetLastSeen(ctx context.Context, batch map[string]time.Time) error {
+	ids := make([]string, 0, len(batch))
 	for deviceID, lastSeen := range batch {
+		if !lastSeen.IsZero() {
+			ids = append(ids, deviceID)
+		}
+	}
+	slices.Sort(ids)
+
+	var multiErr error
+	for chunk := range slices.Chunk(ids, lastSeenChunkSize) {
 		if err := ctx.Err(); err != nil {
 			return errors.Join(err, multiErr)
 		}
-		if err := s.devices.SetLastSeen(ctx, deviceID, lastSeen); err != nil {
-			multiErr = errors.Join(multiErr, fmt.Errorf("device %s: %w", deviceID, err))
+
+		items := make(map[string]time.Time, len(chunk))
+		for _, deviceID := range chunk {
+			items[deviceID] = batch[deviceID]
+		}
+
+		start := time.Now()
+		updated, err := s.devices.SetLastSeen(ctx, items)
+		s.metrics.ObserveLastSeenUpdate(time.Since(start), len(chunk), updated, err)
+		if err != nil {
+			multiErr = errors.Join(multiErr, fmt.Errorf("chunk of %d devices: %w", len(chunk), err))
 			s.logger.Error("can't set last seen",
-				zap.String("device_id", deviceID),
-				zap.Time("last_seen", lastSeen),
+				zap.Int("count", len(chunk)),
 				zap.Error(err),
 			)
 		}
@@ -123,6 +257,52 @@ func (s *Service) SetLastSeen(ctx context.Context, batch map[string]time.Time) e
 	return multiErr
 }
 
+// Suspend suspends the user's device, so it isn't authorized until it's
+// resumed. Suspending the suspended device is a no-op.
+func (s *Service) Suspend(userID, id string) error {
+	return s.setSuspended(userID, id, true)
+}
+
+// Resume resumes the user's suspended device. Resuming the active device is
+// a no-op.
+func (s *Service) Resume(userID, id string) error {
+	return s.setSuspended(userID, id, false)
+}
+
+func (s *Service) setSuspended(userID, id string, suspended bool) error {
+	device, err := s.Get(userID, WithID(id))
+	if err != nil {
+		return err
+	}
+
+	if (device.SuspendedAt != nil) == suspended {
+		return nil
+	}
+
+	event := LifecycleEvent{Type: LifecycleResumed}
+	device.SuspendedAt = nil
+	if suspended {
+		event.Type = LifecycleSuspended
+		device.SuspendedAt = anys.AsPointer(time.Now())
+	}
+
+	if err := s.devices.Update(device.ID, map[string]any{"suspended_at": device.SuspendedAt}); err != nil {
+		return err
+	}
+
+	s.invalidateToken(device)
+
+	event.Device = device
+	s.lifecycle.emit(event)
+
+	return nil
+}
+
+// IsOnline reports whether the device was active within the online window.
+func (s *Service) IsOnline(device models.Device) bool {
+	return device.LastSeen.After(time.Now().Add(-s.config.OnlineWindow))
+}
+
 // Remove removes devices for a specific user that match the provided filters.
 // It ensures that the filter includes the user's ID.
 func (s *Service) Remove(userID string, filter ...SelectFilter) error {
@@ -133,18 +313,23 @@ func (s *Service) Remove(userID string, filter ...SelectFilter) error {
 		return err
 	}
 
-	hash := sha256.Sum256([]byte(device.AuthToken))
-	cacheKey := hex.EncodeToString(hash[:])
-
-	if err := s.tokensCache.Delete(cacheKey); err != nil {
-		s.logger.Error("can't invalidate token cache",
-			zap.String("device_id", device.ID),
-			zap.String("cache_key", cacheKey),
-			zap.Error(err),
-		)
+	if err := s.devices.Remove(filter...); err != nil {
+		return err
 	}
 
-	return s.devices.Remove(filter...)
+	s.invalidateToken(device)
+
+	s.lifecycle.emit(LifecycleEvent{Type: LifecycleDeleted, Device: device})
+
+	return nil
+}
+
+// invalidateToken drops the cached device, so the next request of the device
+// reads the current state.
+func (s *Service) invalidateToken(device models.Device) {
+	if err := s.tokensCache.Delete(context.Background(), tokenCacheKey(device.AuthToken)); err != nil {
+		s.logger.Error("can't invalidate token cache", zap.String("device_id", device.ID), zap.Error(err))
+	}
 }
 
 func (s *Service) Clean(ctx context.Context) error {
@@ -158,8 +343,13 @@ func NewService(params ServiceParams) *Service {
 	return &Service{
 		config:      params.Config,
 		devices:     params.Devices,
-		tokensCache: cache.New[models.Device](cache.Config{TTL: 10 * time.Minute}),
+		tokensCache: cache.NewTyped[cachedDevice](params.TokensCache),
 		idGen:       params.IDGen,
+		metrics:     params.Metrics,
 		logger:      params.Logger.Named("service"),
 	}
 }
+
+func equalPtr(current *string, value string) bool {
+	return current != nil && *current == value
+}
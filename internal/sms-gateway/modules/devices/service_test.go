@@ -0,0 +1,34 @@
+package devices
+
+import (
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+func TestService_IsOnline(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		window   time.Duration
+		lastSeen time.Time
+		want     bool
+	}{
+		{name: "Within window", window: 15 * time.Minute, lastSeen: now.Add(-10 * time.Minute), want: true},
+		{name: "Beyond window", window: 15 * time.Minute, lastSeen: now.Add(-20 * time.Minute), want: false},
+		{name: "Custom window", window: 5 * time.Minute, lastSeen: now.Add(-10 * time.Minute), want: false},
+		{name: "Longer window", window: time.Hour, lastSeen: now.Add(-30 * time.Minute), want: true},
+		{name: "Never seen", window: 15 * time.Minute, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{config: Config{OnlineWindow: tt.window}}
+
+			if got := s.IsOnline(models.Device{LastSeen: tt.lastSeen}); got != tt.want {
+				t.Errorf("IsOnline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
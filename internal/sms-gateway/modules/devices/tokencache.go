@@ -0,0 +1,83 @@
+package devices
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+// cachedDevice is the device cached by its auth token. It holds the ID and
+// the non-secret fields only: the auth token is the cache key and the push
+// token is replaced by the mark of its presence.
+type cachedDevice struct {
+	ID                 string         `json:"id"`
+	Name               *string        `json:"name,omitempty"`
+	HasPushToken       bool           `json:"hasPushToken"`
+	PushDegradedAt     *time.Time     `json:"pushDegradedAt,omitempty"`
+	AppVersion         *string        `json:"appVersion,omitempty"`
+	EventSubscriptions []string       `json:"eventSubscriptions,omitempty"`
+	Sandbox            bool           `json:"sandbox"`
+	SuspendedAt        *time.Time     `json:"suspendedAt,omitempty"`
+	Tags               []string       `json:"tags,omitempty"`
+	Group              *string        `json:"group,omitempty"`
+	Settings           map[string]any `json:"settings,omitempty"`
+	LastSeen           time.Time      `json:"lastSeen"`
+	UserID             string         `json:"userId"`
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"updatedAt"`
+}
+
+func newCachedDevice(device models.Device) cachedDevice {
+	return cachedDevice{
+		ID:                 device.ID,
+		Name:               device.Name,
+		HasPushToken:       device.PushToken != nil && *device.PushToken != "",
+		PushDegradedAt:     device.PushDegradedAt,
+		AppVersion:         device.AppVersion,
+		EventSubscriptions: device.EventSubscriptions,
+		Sandbox:            device.Sandbox,
+		SuspendedAt:        device.SuspendedAt,
+		Tags:               device.Tags,
+		Group:              device.Group,
+		Settings:           device.Settings,
+		LastSeen:           device.LastSeen,
+		UserID:             device.UserID,
+		CreatedAt:          device.CreatedAt,
+		UpdatedAt:          device.UpdatedAt,
+	}
+}
+
+// toModel returns the device authorized by the token. The push token is left
+// empty, the HasPushToken mark is set instead.
+func (d cachedDevice) toModel(token string) models.Device {
+	return models.Device{
+		ID:                 d.ID,
+		Name:               d.Name,
+		AuthToken:          token,
+		HasPushToken:       d.HasPushToken,
+		PushDegradedAt:     d.PushDegradedAt,
+		AppVersion:         d.AppVersion,
+		EventSubscriptions: d.EventSubscriptions,
+		Sandbox:            d.Sandbox,
+		SuspendedAt:        d.SuspendedAt,
+		Tags:               d.Tags,
+		Group:              d.Group,
+		Settings:           d.Settings,
+		LastSeen:           d.LastSeen,
+		UserID:             d.UserID,
+		SoftDeletableModel: models.SoftDeletableModel{
+			TimedModel: models.TimedModel{
+				CreatedAt: d.CreatedAt,
+				UpdatedAt: d.UpdatedAt,
+			},
+		},
+	}
+}
+
+// tokenCacheKey doesn't expose the token to the cache backend.
+func tokenCacheKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
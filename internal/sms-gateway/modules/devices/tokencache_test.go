@@ -0,0 +1,62 @@
+package devices
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/capcom6/go-helpers/anys"
+)
+
+func TestCachedDevice(t *testing.T) {
+	device := models.Device{
+		ID:        "device-id",
+		Name:      anys.AsPointer("Phone"),
+		AuthToken: "auth-token-secret",
+		PushToken: anys.AsPointer("push-token-secret"),
+		Tags:      []string{"office"},
+		LastSeen:  time.Date(2025, 11, 1, 12, 0, 0, 0, time.UTC),
+		UserID:    "user-id",
+	}
+
+	data, err := json.Marshal(newCachedDevice(device))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("cached device contains credentials: %s", data)
+	}
+
+	cached := cachedDevice{}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := cached.toModel(device.AuthToken)
+	if restored.ID != device.ID || restored.UserID != device.UserID || restored.AuthToken != device.AuthToken {
+		t.Errorf("restored = %+v", restored)
+	}
+	if restored.PushToken != nil {
+		t.Errorf("push token = %q, want nil", *restored.PushToken)
+	}
+	if !restored.CanPush() {
+		t.Error("restored device can't push")
+	}
+
+	device.PushDegradedAt = anys.AsPointer(time.Now())
+	if restored := newCachedDevice(device).toModel(device.AuthToken); restored.CanPush() {
+		t.Error("degraded device can push")
+	}
+
+	device.SuspendedAt = anys.AsPointer(time.Now())
+	if restored := newCachedDevice(device).toModel(device.AuthToken); restored.SuspendedAt == nil {
+		t.Error("suspended device isn't marked")
+	}
+
+	device.PushToken = nil
+	if restored := newCachedDevice(device).toModel(device.AuthToken); restored.HasPushToken {
+		t.Error("device without push token is marked")
+	}
+}
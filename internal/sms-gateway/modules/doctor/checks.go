@@ -0,0 +1,188 @@
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/health"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/fcm"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// egressProbeURL responds with 204 and is used to check the outbound
+	// connectivity.
+	egressProbeURL = "https://www.gstatic.com/generate_204"
+
+	// maxClockSkew is the clock difference reported as a warning.
+	maxClockSkew = 30 * time.Second
+)
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name    string
+	Status  health.Status
+	Message string
+}
+
+func pass(name, format string, args ...any) Result {
+	return Result{Name: name, Status: health.StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(name, format string, args ...any) Result {
+	return Result{Name: name, Status: health.StatusWarn, Message: fmt.Sprintf(format, args...)}
+}
+
+func fail(name string, err error) Result {
+	return Result{Name: name, Status: health.StatusFail, Message: err.Error()}
+}
+
+func checkDatabase(ctx context.Context, db *sql.DB, dialect string) []Result {
+	if err := db.PingContext(ctx); err != nil {
+		return []Result{fail("database", fmt.Errorf("can't connect: %w", err))}
+	}
+
+	results := []Result{pass("database", "connected")}
+
+	latest, err := models.LatestMigration(dialect)
+	if err != nil {
+		return append(results, fail("migrations", err))
+	}
+
+	var applied sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT MAX(version_id) FROM goose_db_version").Scan(&applied); err != nil {
+		return append(results, fail("migrations", fmt.Errorf("can't read applied version: %w", err)))
+	}
+
+	if applied.Int64 < latest {
+		return append(results, fail("migrations", fmt.Errorf("applied version %d is behind %d, run db:migrate", applied.Int64, latest)))
+	}
+
+	return append(results, pass("migrations", "up to date (%d)", applied.Int64))
+}
+
+func checkCache(ctx context.Context, factory cache.Factory) Result {
+	c, err := factory.New("doctor")
+	if err != nil {
+		return fail("cache", fmt.Errorf("can't create cache: %w", err))
+	}
+
+	key := fmt.Sprintf("probe:%d", time.Now().UnixNano())
+	if err := c.Set(ctx, key, "ok"); err != nil {
+		return fail("cache", err)
+	}
+	defer func() {
+		_ = c.Delete(ctx, key)
+	}()
+
+	value, err := c.Get(ctx, key)
+	if err != nil {
+		return fail("cache", err)
+	}
+	if value != "ok" {
+		return fail("cache", errors.New("read value doesn't match the written one"))
+	}
+
+	return pass("cache", "read and write succeeded")
+}
+
+func checkFCM(ctx context.Context, config push.Config, egressSvc *egress.Service, logger *zap.Logger) Result {
+	if config.Mode != push.ModeFCM {
+		return pass("fcm", "not used in %s mode", config.Mode)
+	}
+
+	client, err := fcm.New(config.ClientOptions, egressSvc.Transport(egress.DestinationFCM), logger)
+	if err != nil {
+		return fail("fcm", err)
+	}
+
+	if err := client.Open(ctx); err != nil {
+		return fail("fcm", err)
+	}
+	defer func() {
+		_ = client.Close(ctx)
+	}()
+
+	if err := client.Check(ctx); err != nil {
+		return fail("fcm", err)
+	}
+
+	return pass("fcm", "credentials accepted")
+}
+
+func checkStorage(ctx context.Context, storageSvc *storage.Service) Result {
+	if !storageSvc.Enabled() {
+		return pass("storage", "not configured")
+	}
+
+	key := fmt.Sprintf("doctor/probe-%d", time.Now().UnixNano())
+	if err := storageSvc.Put(ctx, key, []byte("ok"), "text/plain"); err != nil {
+		return fail("storage", err)
+	}
+	defer func() {
+		_ = storageSvc.Delete(ctx, key)
+	}()
+
+	value, err := storageSvc.Get(ctx, key)
+	if err != nil {
+		return fail("storage", err)
+	}
+	if string(value) != "ok" {
+		return fail("storage", errors.New("read object doesn't match the written one"))
+	}
+
+	return pass("storage", "read and write succeeded")
+}
+
+// checkEgress requests the probe URL through the webhooks proxy and returns
+// the server time from the response.
+func checkEgress(ctx context.Context, egressSvc *egress.Service, timeout time.Duration) (Result, time.Time) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, egressProbeURL, nil)
+	if err != nil {
+		return fail("egress", err), time.Time{}
+	}
+
+	res, err := egressSvc.Client(egress.DestinationWebhooks, timeout).Do(req)
+	if err != nil {
+		return fail("egress", fmt.Errorf("can't reach %s: %w", egressProbeURL, err)), time.Time{}
+	}
+	_ = res.Body.Close()
+
+	serverTime, _ := http.ParseTime(res.Header.Get("Date"))
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return warn("egress", "%s responded with %s", egressProbeURL, res.Status), serverTime
+	}
+
+	return pass("egress", "%s is reachable", egressProbeURL), serverTime
+}
+
+// checkClock compares the local clock with the database and the remote server
+// clocks. Zero remote time is skipped.
+func checkClock(ctx context.Context, db *sql.DB, remote time.Time) Result {
+	var dbUnix int64
+	if err := db.QueryRowContext(ctx, "SELECT UNIX_TIMESTAMP()").Scan(&dbUnix); err != nil {
+		return warn("clock", "can't read database time: %s", err)
+	}
+
+	if skew := time.Since(time.Unix(dbUnix, 0)); skew.Abs() > maxClockSkew {
+		return warn("clock", "local clock differs from the database by %s", skew.Round(time.Second))
+	}
+
+	if !remote.IsZero() {
+		if skew := time.Since(remote); skew.Abs() > maxClockSkew {
+			return warn("clock", "local clock differs from the internet time by %s", skew.Round(time.Second))
+		}
+	}
+
+	return pass("clock", "in sync within %s", maxClockSkew)
+}
@@ -0,0 +1,77 @@
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/health"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/storage"
+	"github.com/capcom6/go-infra-fx/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const checkTimeout = 10 * time.Second
+
+type runParams struct {
+	fx.In
+
+	DB       *sql.DB
+	DBConfig db.Config
+
+	CacheFactory cache.Factory
+	PushConfig   push.Config
+	EgressSvc    *egress.Service
+	StorageSvc   *storage.Service
+
+	Shutdowner fx.Shutdowner
+	Logger     *zap.Logger
+}
+
+// run performs the self-test and prints the report. The exit code is 1 if
+// any check fails.
+func run(params runParams) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*checkTimeout)
+	defer cancel()
+
+	results := checkDatabase(ctx, params.DB, string(params.DBConfig.Dialect))
+	results = append(results, checkCache(ctx, params.CacheFactory))
+	results = append(results, checkFCM(ctx, params.PushConfig, params.EgressSvc, params.Logger))
+	results = append(results, checkStorage(ctx, params.StorageSvc))
+
+	egressResult, remoteTime := checkEgress(ctx, params.EgressSvc, checkTimeout)
+	results = append(results, egressResult, checkClock(ctx, params.DB, remoteTime))
+
+	failed := report(os.Stdout, results)
+
+	var opts []fx.ShutdownOption
+	if failed {
+		opts = append(opts, fx.ExitCode(1))
+	}
+	if err := params.Shutdowner.Shutdown(opts...); err != nil {
+		params.Logger.Error("Failed to shutdown", zap.Error(err))
+	}
+}
+
+// report prints the results and returns true if any check failed.
+func report(w io.Writer, results []Result) bool {
+	failed := false
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		fmt.Fprintf(tw, "[%s]\t%s\t%s\n", strings.ToUpper(string(r.Status)), r.Name, r.Message)
+		failed = failed || r.Status == health.StatusFail
+	}
+	_ = tw.Flush()
+
+	return failed
+}
@@ -0,0 +1,28 @@
+package doctor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/health"
+)
+
+func TestReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+
+	failed := report(buf, []Result{
+		{Name: "database", Status: health.StatusPass, Message: "connected"},
+		{Name: "clock", Status: health.StatusWarn, Message: "skewed"},
+	})
+	if failed {
+		t.Error("report() = true, want false without failed checks")
+	}
+	if !strings.Contains(buf.String(), "[PASS]  database  connected") {
+		t.Errorf("unexpected report:\n%s", buf.String())
+	}
+
+	if !report(&bytes.Buffer{}, []Result{{Name: "cache", Status: health.StatusFail, Message: "down"}}) {
+		t.Error("report() = false, want true with a failed check")
+	}
+}
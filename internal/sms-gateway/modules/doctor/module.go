@@ -0,0 +1,18 @@
+package doctor
+
+import (
+	"github.com/capcom6/go-infra-fx/cli"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"doctor",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("doctor")
+	}),
+)
+
+func init() {
+	cli.Register("doctor", run)
+}
@@ -0,0 +1,26 @@
+package egress
+
+// Destination is the group of outbound requests that may use its own proxy.
+type Destination string
+
+const (
+	DestinationFCM      Destination = "fcm"
+	DestinationWebhooks Destination = "webhooks"
+	DestinationUpstream Destination = "upstream"
+	DestinationStorage  Destination = "storage"
+	DestinationPush     Destination = "push"
+)
+
+// Direct disables the proxy for the destination.
+const Direct = "direct"
+
+type Config struct {
+	// Proxy is the URL of the proxy for all outbound requests, e.g.
+	// "http://proxy:3128" or "socks5://proxy:1080". If empty, the proxy is
+	// taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+	// variables.
+	Proxy string
+	// Overrides replace the proxy for the specific destinations, Direct
+	// connects without a proxy.
+	Overrides map[Destination]string
+}
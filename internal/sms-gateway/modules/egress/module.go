@@ -0,0 +1,12 @@
+package egress
+
+import (
+	"go.uber.org/fx"
+)
+
+var Module = fx.Module(
+	"egress",
+	fx.Provide(
+		NewService,
+	),
+)
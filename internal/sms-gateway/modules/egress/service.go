@@ -0,0 +1,95 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrInvalidProxy = errors.New("invalid proxy")
+
+type proxyFunc func(*http.Request) (*url.URL, error)
+
+// Service resolves the proxy of outbound requests.
+type Service struct {
+	proxy     proxyFunc
+	overrides map[Destination]proxyFunc
+}
+
+func NewService(config Config) (*Service, error) {
+	proxy, err := newProxyFunc(config.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[Destination]proxyFunc, len(config.Overrides))
+	for dest, value := range config.Overrides {
+		if value == "" {
+			continue
+		}
+
+		override, err := newProxyFunc(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dest, err)
+		}
+		overrides[dest] = override
+	}
+
+	return &Service{
+		proxy:     proxy,
+		overrides: overrides,
+	}, nil
+}
+
+// Proxy returns the proxy function of the destination for http.Transport.
+func (s *Service) Proxy(dest Destination) func(*http.Request) (*url.URL, error) {
+	if override, ok := s.overrides[dest]; ok {
+		return override
+	}
+
+	return s.proxy
+}
+
+// Transport returns a copy of the default transport that uses the proxy of
+// the destination.
+func (s *Service) Transport(dest Destination) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = s.Proxy(dest)
+
+	return transport
+}
+
+// Client returns the HTTP client that uses the proxy of the destination.
+func (s *Service) Client(dest Destination, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: s.Transport(dest),
+	}
+}
+
+func newProxyFunc(value string) (proxyFunc, error) {
+	switch value {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case Direct:
+		return nil, nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProxy, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("%w: unsupported scheme %q", ErrInvalidProxy, u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: no host", ErrInvalidProxy)
+	}
+
+	return http.ProxyURL(u), nil
+}
@@ -0,0 +1,63 @@
+package egress_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+)
+
+func TestService_Proxy(t *testing.T) {
+	svc, err := egress.NewService(egress.Config{
+		Proxy: "http://proxy:3128",
+		Overrides: map[egress.Destination]string{
+			egress.DestinationFCM:      "socks5://socks:1080",
+			egress.DestinationWebhooks: egress.Direct,
+			egress.DestinationUpstream: "",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+
+	tests := []struct {
+		dest egress.Destination
+		want string
+	}{
+		{egress.DestinationFCM, "socks5://socks:1080"},
+		{egress.DestinationWebhooks, ""},
+		{egress.DestinationUpstream, "http://proxy:3128"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.dest), func(t *testing.T) {
+			proxy := svc.Proxy(tt.dest)
+			if tt.want == "" {
+				if proxy != nil {
+					t.Errorf("Proxy(%s) expected direct connection", tt.dest)
+				}
+				return
+			}
+
+			u, err := proxy(req)
+			if err != nil {
+				t.Fatalf("proxy() error = %v", err)
+			}
+			if u.String() != tt.want {
+				t.Errorf("proxy() = %s, want %s", u, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewService_InvalidProxy(t *testing.T) {
+	for _, value := range []string{"ftp://proxy:21", "http://", "::"} {
+		_, err := egress.NewService(egress.Config{Proxy: value})
+		if !errors.Is(err, egress.ErrInvalidProxy) {
+			t.Errorf("NewService(%q) error = %v, want %v", value, err, egress.ErrInvalidProxy)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package email
+
+import "time"
+
+type Config struct {
+	// Listen is the address of the SMTP listener, empty to disable.
+	Listen string
+	// Domain is the domain of recipient addresses, empty to accept any.
+	Domain string
+	// AllowedSenders limits envelope senders, empty to accept any
+	// authenticated sender.
+	AllowedSenders []string
+
+	// TLSCertFile and TLSKeyFile are the certificate and the key of the
+	// listener. AUTH is accepted only over TLS.
+	TLSCertFile string
+	TLSKeyFile  string
+	// ImplicitTLS makes the listener accept only TLS connections, otherwise
+	// TLS is started with STARTTLS.
+	ImplicitTLS bool
+	// AllowInsecureAuth accepts AUTH over plaintext connections, e.g. behind
+	// a TLS-terminating proxy or on a private network.
+	AllowInsecureAuth bool
+
+	// MaxAuthFailures is the number of failed AUTH attempts from an IP
+	// within AuthFailuresWindow after which the IP is blocked until the
+	// window ends, zero to disable.
+	MaxAuthFailures    int
+	AuthFailuresWindow time.Duration
+
+	MaxMessageSize int
+	MaxRecipients  int
+	Timeout        time.Duration
+}
@@ -0,0 +1,9 @@
+package email
+
+import "errors"
+
+var (
+	ErrInvalidRecipient = errors.New("invalid recipient address")
+	ErrSenderNotAllowed = errors.New("sender is not allowed")
+	ErrNoContent        = errors.New("no message content")
+)
@@ -0,0 +1,67 @@
+package email
+
+import (
+	"sync"
+	"time"
+)
+
+// authLimiter blocks the IPs with too many failed authentication attempts
+// within the window, so the API credentials can't be brute-forced over SMTP.
+type authLimiter struct {
+	max    int
+	window time.Duration
+
+	mu       sync.Mutex
+	failures map[string]authFailures
+}
+
+type authFailures struct {
+	count   int
+	resetAt time.Time
+}
+
+func newAuthLimiter(max int, window time.Duration) *authLimiter {
+	return &authLimiter{
+		max:      max,
+		window:   window,
+		failures: make(map[string]authFailures),
+	}
+}
+
+// Allow reports whether the IP may attempt to authenticate. It's always true
+// if the limit isn't set.
+func (l *authLimiter) Allow(ip string) bool {
+	if l.max <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.failures[ip]
+	return !ok || f.count < l.max || time.Now().After(f.resetAt)
+}
+
+// Fail records the failed attempt of the IP.
+func (l *authLimiter) Fail(ip string) {
+	if l.max <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for k, f := range l.failures {
+		if now.After(f.resetAt) {
+			delete(l.failures, k)
+		}
+	}
+
+	f, ok := l.failures[ip]
+	if !ok {
+		f.resetAt = now.Add(l.window)
+	}
+	f.count++
+	l.failures[ip] = f
+}
@@ -0,0 +1,26 @@
+package email
+
+import (
+	"context"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"email",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("email")
+	}),
+	fx.Provide(NewService),
+	fx.Invoke(func(lc fx.Lifecycle, svc *Service) {
+		lc.Append(fx.Hook{
+			OnStart: func(_ context.Context) error {
+				return svc.Start()
+			},
+			OnStop: func(ctx context.Context) error {
+				return svc.Close(ctx)
+			},
+		})
+	}),
+)
@@ -0,0 +1,167 @@
+package email
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"unicode"
+)
+
+// signatureSeparator is the standard email signature delimiter.
+const signatureSeparator = "\n-- \n"
+
+// parseRecipient extracts the phone number from the recipient address. The
+// local part of the address is the phone number, e.g. +79990001234@sms.example.com.
+func parseRecipient(address, domain string) (string, error) {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidRecipient, err)
+	}
+
+	local, host, ok := strings.Cut(addr.Address, "@")
+	if !ok || local == "" {
+		return "", ErrInvalidRecipient
+	}
+
+	if domain != "" && !strings.EqualFold(host, domain) {
+		return "", fmt.Errorf("%w: unknown domain %s", ErrInvalidRecipient, host)
+	}
+
+	phone := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '(' || r == ')' {
+			return -1
+		}
+		return r
+	}, local)
+
+	digits := strings.TrimPrefix(phone, "+")
+	if digits == "" || strings.IndexFunc(digits, func(r rune) bool { return !unicode.IsDigit(r) }) >= 0 {
+		return "", fmt.Errorf("%w: %s is not a phone number", ErrInvalidRecipient, local)
+	}
+
+	return phone, nil
+}
+
+// parseSender returns the bare address of the envelope sender.
+func parseSender(address string) (string, error) {
+	if address == "" {
+		return "", nil
+	}
+
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(addr.Address), nil
+}
+
+// extractText returns the text of the message. The body is used as the text;
+// the subject is used only if the body is empty. Signatures are stripped.
+func extractText(r io.Reader) (string, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return "", fmt.Errorf("can't parse message: %w", err)
+	}
+
+	body, err := readPlainText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return "", err
+	}
+
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	if idx := strings.Index("\n"+body, signatureSeparator); idx >= 0 {
+		body = body[:max(idx-1, 0)]
+	}
+	body = strings.TrimSpace(body)
+
+	if body != "" {
+		return body, nil
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		return "", fmt.Errorf("can't decode subject: %w", err)
+	}
+
+	if subject = strings.TrimSpace(subject); subject != "" {
+		return subject, nil
+	}
+
+	return "", ErrNoContent
+}
+
+func readPlainText(contentType, encoding string, r io.Reader) (string, error) {
+	mediaType, params := "text/plain", map[string]string{}
+	if contentType != "" {
+		var err error
+		if mediaType, params, err = mime.ParseMediaType(contentType); err != nil {
+			return "", fmt.Errorf("can't parse content type: %w", err)
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return "", nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("can't read multipart body: %w", err)
+			}
+
+			text, err := readPlainText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			if text != "" {
+				return text, nil
+			}
+		}
+	}
+
+	if mediaType != "text/plain" {
+		return "", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding, newlineStripper{r})
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("can't read body: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// newlineStripper removes line breaks from base64 encoded content.
+type newlineStripper struct {
+	r io.Reader
+}
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	for {
+		count, err := n.r.Read(p)
+		j := 0
+		for _, b := range p[:count] {
+			if b != '\r' && b != '\n' {
+				p[j] = b
+				j++
+			}
+		}
+		if j > 0 || err != nil {
+			return j, err
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseRecipient(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		domain  string
+		want    string
+		wantErr bool
+	}{
+		{name: "International", address: "+79990001234@sms.example.com", domain: "sms.example.com", want: "+79990001234"},
+		{name: "With name", address: `"John" <79990001234@sms.example.com>`, domain: "sms.example.com", want: "79990001234"},
+		{name: "Separators", address: "+7-999-000.12.34@sms.example.com", want: "+79990001234"},
+		{name: "Any domain", address: "79990001234@other.com", want: "79990001234"},
+		{name: "Domain case", address: "79990001234@SMS.example.com", domain: "sms.example.com", want: "79990001234"},
+		{name: "Wrong domain", address: "79990001234@other.com", domain: "sms.example.com", wantErr: true},
+		{name: "Not a phone", address: "john@sms.example.com", wantErr: true},
+		{name: "Invalid address", address: "not an address", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRecipient(tt.address, tt.domain)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRecipient) {
+					t.Fatalf("parseRecipient() error = %v, want ErrInvalidRecipient", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRecipient() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseRecipient() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractText(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "Plain body",
+			message: "Subject: Ignored\r\n\r\nHello, world!\r\n",
+			want:    "Hello, world!",
+		},
+		{
+			name:    "Subject only",
+			message: "Subject: =?UTF-8?B?0J/RgNC40LLQtdGC?=\r\n\r\n\r\n",
+			want:    "Привет",
+		},
+		{
+			name:    "Signature",
+			message: "Subject: Test\r\n\r\nCode: 1234\r\n-- \r\nACME Corp\r\n",
+			want:    "Code: 1234",
+		},
+		{
+			name:    "Quoted printable",
+			message: "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nCaf=C3=A9 is open\r\n",
+			want:    "Café is open",
+		},
+		{
+			name: "Multipart",
+			message: "Content-Type: multipart/alternative; boundary=b1\r\n\r\n" +
+				"--b1\r\nContent-Type: text/html\r\n\r\n<p>Hello</p>\r\n" +
+				"--b1\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: base64\r\n\r\nSGVs\r\nbG8=\r\n" +
+				"--b1--\r\n",
+			want: "Hello",
+		},
+		{
+			name:    "No content",
+			message: "Subject: \r\n\r\n",
+			wantErr: ErrNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractText(strings.NewReader(tt.message))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("extractText() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractText() unexpected error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("extractText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/capcom6/go-helpers/slices"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	AuthSvc     *auth.Service
+	DevicesSvc  *devices.Service
+	MessagesSvc *messages.Service
+
+	Logger *zap.Logger
+}
+
+// Service converts emails received by the SMTP listener to messages.
+type Service struct {
+	config Config
+
+	authSvc     *auth.Service
+	devicesSvc  *devices.Service
+	messagesSvc *messages.Service
+
+	server *smtpServer
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	s := &Service{
+		config: params.Config,
+
+		authSvc:     params.AuthSvc,
+		devicesSvc:  params.DevicesSvc,
+		messagesSvc: params.MessagesSvc,
+
+		logger: params.Logger,
+	}
+
+	return s
+}
+
+// Enabled returns true if the SMTP listener is configured.
+func (s *Service) Enabled() bool {
+	return s.config.Listen != ""
+}
+
+// Start starts the SMTP listener in the background.
+func (s *Service) Start() error {
+	if !s.Enabled() {
+		s.logger.Info("Email ingestion is disabled")
+		return nil
+	}
+
+	var tlsConfig *tls.Config
+	if s.config.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.config.TLSCertFile, s.config.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("can't load SMTP certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	} else if s.config.ImplicitTLS || !s.config.AllowInsecureAuth {
+		return errors.New("SMTP listener requires a TLS certificate unless insecure authentication is allowed")
+	}
+
+	l, err := net.Listen("tcp", s.config.Listen)
+	if err != nil {
+		return fmt.Errorf("can't listen on %s: %w", s.config.Listen, err)
+	}
+	if s.config.ImplicitTLS {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
+	s.server = newSMTPServer(s.config, tlsConfig, s, s.logger)
+
+	s.logger.Info("Starting SMTP listener...", zap.String("listen", s.config.Listen), zap.Bool("tls", tlsConfig != nil))
+	go func() {
+		if err := s.server.Serve(l); err != nil {
+			s.logger.Error("SMTP listener failed", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// Close stops the SMTP listener.
+func (s *Service) Close(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+
+	return s.server.Close(ctx)
+}
+
+// Authorize checks the SMTP credentials, they are the same as for the API.
+func (s *Service) Authorize(username, password string) (models.User, error) {
+	return s.authSvc.AuthorizeUser(username, password)
+}
+
+// Submit enqueues the message to a random device of the user.
+func (s *Service) Submit(_ context.Context, user models.User, sender string, phoneNumbers []string, text string) (string, error) {
+	userDevices, err := s.devicesSvc.Select(user.ID)
+	if err != nil {
+		return "", fmt.Errorf("can't select devices: %w", err)
+	}
+
+	if len(userDevices) < 1 {
+		return "", errors.New("no devices found")
+	}
+
+	device, err := slices.Random(s.messagesSvc.FilterHealthy(userDevices))
+	if err != nil {
+		return "", fmt.Errorf("can't get random device: %w", err)
+	}
+
+	state, err := s.messagesSvc.Enqueue(device, messages.MessageIn{
+		TextContent:  &messages.TextMessageContent{Text: text},
+		PhoneNumbers: phoneNumbers,
+	}, messages.EnqueueOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Info("Message submitted via email",
+		zap.String("user_id", user.ID),
+		zap.String("device_id", device.ID),
+		zap.String("sender", sender),
+		zap.String("message_id", state.ID),
+	)
+
+	return state.ID, nil
+}
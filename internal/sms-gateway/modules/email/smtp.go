@@ -0,0 +1,219 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"go.uber.org/zap"
+)
+
+var errTooManyAuthFailures = &smtp.SMTPError{
+	Code:         454,
+	EnhancedCode: smtp.EnhancedCode{4, 7, 0},
+	Message:      "Too many failed authentication attempts, try again later",
+}
+
+// backend authorizes senders and submits messages received by the SMTP server.
+type backend interface {
+	Authorize(username, password string) (models.User, error)
+	Submit(ctx context.Context, user models.User, sender string, phoneNumbers []string, text string) (string, error)
+}
+
+// smtpServer accepts messages from the authenticated senders. AUTH is
+// advertised and accepted only over TLS, either STARTTLS or implicit, unless
+// the insecure authentication is allowed explicitly.
+type smtpServer struct {
+	config  Config
+	backend backend
+	limiter *authLimiter
+	logger  *zap.Logger
+
+	server *smtp.Server
+}
+
+func newSMTPServer(config Config, tlsConfig *tls.Config, backend backend, logger *zap.Logger) *smtpServer {
+	s := &smtpServer{
+		config:  config,
+		backend: backend,
+		limiter: newAuthLimiter(config.MaxAuthFailures, config.AuthFailuresWindow),
+		logger:  logger,
+	}
+
+	server := smtp.NewServer(s)
+	server.Domain = config.Domain
+	if server.Domain == "" {
+		server.Domain = "localhost"
+	}
+	server.TLSConfig = tlsConfig
+	server.AllowInsecureAuth = config.AllowInsecureAuth
+	server.MaxMessageBytes = int64(config.MaxMessageSize)
+	server.MaxRecipients = config.MaxRecipients
+	server.ReadTimeout = config.Timeout
+	server.WriteTimeout = config.Timeout
+	server.ErrorLog = zap.NewStdLog(logger)
+	s.server = server
+
+	return s
+}
+
+// Serve accepts connections until the server is closed.
+func (s *smtpServer) Serve(l net.Listener) error {
+	if err := s.server.Serve(l); err != nil && !errors.Is(err, smtp.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// Close stops accepting connections and waits for active sessions, the
+// sessions left when the context is done are closed.
+func (s *smtpServer) Close(ctx context.Context) error {
+	err := s.server.Shutdown(ctx)
+	if errors.Is(err, smtp.ErrServerClosed) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		_ = s.server.Close()
+	}
+
+	return err
+}
+
+// NewSession implements smtp.Backend.
+func (s *smtpServer) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	ip := c.Conn().RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	return &smtpSession{
+		server: s,
+		ip:     ip,
+		logger: s.logger.With(zap.String("remote_ip", ip)),
+	}, nil
+}
+
+type smtpSession struct {
+	server *smtpServer
+	ip     string
+	logger *zap.Logger
+
+	user       *models.User
+	sender     *string
+	recipients []string
+}
+
+// AuthMechanisms implements smtp.AuthSession.
+func (s *smtpSession) AuthMechanisms() []string {
+	return []string{sasl.Plain}
+}
+
+// Auth implements smtp.AuthSession.
+func (s *smtpSession) Auth(mech string) (sasl.Server, error) {
+	if mech != sasl.Plain {
+		return nil, smtp.ErrAuthUnknownMechanism
+	}
+
+	return sasl.NewPlainServer(func(_, username, password string) error {
+		if !s.server.limiter.Allow(s.ip) {
+			s.logger.Warn("Authentication throttled", zap.String("username", username))
+			return errTooManyAuthFailures
+		}
+
+		user, err := s.server.backend.Authorize(username, password)
+		if err != nil {
+			s.server.limiter.Fail(s.ip)
+			s.logger.Info("Authentication failed", zap.String("username", username), zap.Error(err))
+			return smtp.ErrAuthFailed
+		}
+
+		s.user = &user
+		return nil
+	}), nil
+}
+
+// Mail implements smtp.Session.
+func (s *smtpSession) Mail(from string, _ *smtp.MailOptions) error {
+	if s.user == nil {
+		return smtp.ErrAuthRequired
+	}
+
+	sender, err := parseSender(from)
+	if err != nil {
+		return &smtp.SMTPError{Code: 553, EnhancedCode: smtp.EnhancedCode{5, 1, 7}, Message: "Invalid sender address"}
+	}
+
+	if !s.server.isSenderAllowed(sender) {
+		return &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 7, 1}, Message: ErrSenderNotAllowed.Error()}
+	}
+
+	s.sender = &sender
+	return nil
+}
+
+// Rcpt implements smtp.Session.
+func (s *smtpSession) Rcpt(to string, _ *smtp.RcptOptions) error {
+	phone, err := parseRecipient(to, s.server.config.Domain)
+	if err != nil {
+		return &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 1, 1}, Message: err.Error()}
+	}
+
+	s.recipients = append(s.recipients, phone)
+	return nil
+}
+
+// Data implements smtp.Session.
+func (s *smtpSession) Data(r io.Reader) error {
+	text, err := extractText(r)
+	if err != nil {
+		// the size limit error is reported as is
+		var smtpErr *smtp.SMTPError
+		if errors.As(err, &smtpErr) {
+			return smtpErr
+		}
+		return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: err.Error()}
+	}
+
+	id, err := s.server.backend.Submit(context.Background(), *s.user, *s.sender, s.recipients, text)
+	if err != nil {
+		s.logger.Info("Can't submit message", zap.String("user_id", s.user.ID), zap.Error(err))
+		return &smtp.SMTPError{Code: 554, EnhancedCode: smtp.EnhancedCode{5, 6, 0}, Message: err.Error()}
+	}
+
+	s.logger.Debug("Message queued", zap.String("message_id", id))
+	return nil
+}
+
+// Reset implements smtp.Session.
+func (s *smtpSession) Reset() {
+	s.sender = nil
+	s.recipients = nil
+}
+
+// Logout implements smtp.Session.
+func (s *smtpSession) Logout() error {
+	return nil
+}
+
+func (s *smtpServer) isSenderAllowed(sender string) bool {
+	if len(s.config.AllowedSenders) == 0 {
+		return true
+	}
+
+	_, domain, _ := strings.Cut(sender, "@")
+	for _, allowed := range s.config.AllowedSenders {
+		allowed = strings.ToLower(allowed)
+		if allowed == sender || allowed == "@"+domain {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,328 @@
+package email
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"go.uber.org/zap"
+)
+
+type submission struct {
+	user         models.User
+	sender       string
+	phoneNumbers []string
+	text         string
+}
+
+type fakeBackend struct {
+	mu          sync.Mutex
+	authorized  int
+	submissions []submission
+}
+
+func (b *fakeBackend) Authorize(username, password string) (models.User, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.authorized++
+	if username != "user" || password != "secret" {
+		return models.User{}, errors.New("invalid credentials")
+	}
+	return models.User{ID: username}, nil
+}
+
+func (b *fakeBackend) Submit(_ context.Context, user models.User, sender string, phoneNumbers []string, text string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.submissions = append(b.submissions, submission{user, sender, phoneNumbers, text})
+	return "msg-1", nil
+}
+
+// testCertificate returns a self-signed certificate for 127.0.0.1 and the
+// pool trusting it.
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
+
+type testServer struct {
+	addr    string
+	backend *fakeBackend
+	roots   *x509.CertPool
+}
+
+// startTestServer starts the server with STARTTLS, or accepting only TLS
+// connections with implicit TLS.
+func startTestServer(t *testing.T, config Config) testServer {
+	t.Helper()
+
+	cert, roots := testCertificate(t)
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	if config.ImplicitTLS {
+		l = tls.NewListener(l, tlsConfig)
+	}
+
+	backend := &fakeBackend{}
+	server := newSMTPServer(config, tlsConfig, backend, zap.NewNop())
+	go func() {
+		_ = server.Serve(l)
+	}()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Close(ctx)
+	})
+
+	return testServer{addr: l.Addr().String(), backend: backend, roots: roots}
+}
+
+// sendMail is like smtp.SendMail, but trusts the test certificate and
+// doesn't fall back to plaintext.
+func (s testServer) sendMail(t *testing.T, password, from string, to []string, msg []byte, implicitTLS bool) error {
+	t.Helper()
+
+	tlsConfig := &tls.Config{RootCAs: s.roots, ServerName: "127.0.0.1"}
+
+	var (
+		c   *smtp.Client
+		err error
+	)
+	if implicitTLS {
+		conn, dialErr := tls.Dial("tcp", s.addr, tlsConfig)
+		if dialErr != nil {
+			return dialErr
+		}
+		c, err = smtp.NewClient(conn, "127.0.0.1")
+	} else {
+		c, err = smtp.Dial(s.addr)
+		if err == nil {
+			err = c.StartTLS(tlsConfig)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Auth(smtp.PlainAuth("", "user", password, "127.0.0.1")); err != nil {
+		return err
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func TestSMTPServer_Submit(t *testing.T) {
+	server := startTestServer(t, Config{Domain: "sms.example.com", MaxMessageSize: 1024})
+	backend := server.backend
+
+	err := server.sendMail(t, "secret", "app@example.com",
+		[]string{"+79990001234@sms.example.com", "+79990005678@sms.example.com"},
+		[]byte("Subject: Alert\r\n\r\nDisk is full\r\n"),
+		false,
+	)
+	if err != nil {
+		t.Fatalf("sendMail() error = %v", err)
+	}
+
+	if len(backend.submissions) != 1 {
+		t.Fatalf("submissions = %d, want 1", len(backend.submissions))
+	}
+
+	got := backend.submissions[0]
+	if got.user.ID != "user" || got.sender != "app@example.com" || got.text != "Disk is full" {
+		t.Errorf("submission = %+v", got)
+	}
+	if len(got.phoneNumbers) != 2 || got.phoneNumbers[0] != "+79990001234" || got.phoneNumbers[1] != "+79990005678" {
+		t.Errorf("phone numbers = %v", got.phoneNumbers)
+	}
+}
+
+func TestSMTPServer_Rejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		password string
+		from     string
+		to       string
+	}{
+		{name: "Invalid credentials", password: "wrong", from: "app@example.com", to: "79990001234@sms.example.com"},
+		{name: "Sender not allowed", config: Config{AllowedSenders: []string{"@corp.example.com"}}, password: "secret", from: "app@example.com", to: "79990001234@sms.example.com"},
+		{name: "Invalid recipient", config: Config{Domain: "sms.example.com"}, password: "secret", from: "app@example.com", to: "79990001234@other.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := startTestServer(t, tt.config)
+
+			err := server.sendMail(t, tt.password, tt.from, []string{tt.to}, []byte("Subject: Test\r\n\r\nText\r\n"), false)
+			if err == nil {
+				t.Fatal("sendMail() error = nil, want error")
+			}
+			if len(server.backend.submissions) != 0 {
+				t.Errorf("submissions = %d, want 0", len(server.backend.submissions))
+			}
+		})
+	}
+}
+
+func TestSMTPServer_AllowedSenderDomain(t *testing.T) {
+	server := startTestServer(t, Config{AllowedSenders: []string{"@corp.example.com"}})
+
+	err := server.sendMail(t, "secret", "Monitor@Corp.Example.com", []string{"79990001234@sms.example.com"}, []byte("\r\nText\r\n"), false)
+	if err != nil {
+		t.Fatalf("sendMail() error = %v", err)
+	}
+	if len(server.backend.submissions) != 1 {
+		t.Errorf("submissions = %d, want 1", len(server.backend.submissions))
+	}
+}
+
+func TestSMTPServer_ImplicitTLS(t *testing.T) {
+	server := startTestServer(t, Config{ImplicitTLS: true})
+
+	err := server.sendMail(t, "secret", "app@example.com", []string{"79990001234@sms.example.com"}, []byte("\r\nText\r\n"), true)
+	if err != nil {
+		t.Fatalf("sendMail() error = %v", err)
+	}
+	if len(server.backend.submissions) != 1 {
+		t.Errorf("submissions = %d, want 1", len(server.backend.submissions))
+	}
+}
+
+func TestSMTPServer_AuthRequiresTLS(t *testing.T) {
+	server := startTestServer(t, Config{})
+
+	c, err := smtp.Dial(server.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.Hello("localhost"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.Extension("AUTH"); ok {
+		t.Error("AUTH is advertised without TLS")
+	}
+	if ok, _ := c.Extension("STARTTLS"); !ok {
+		t.Error("STARTTLS isn't advertised")
+	}
+
+	// net/smtp refuses PLAIN over plaintext on its own, so it's sent as is
+	id, err := c.Text.Cmd("AUTH PLAIN AHVzZXIAc2VjcmV0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Text.StartResponse(id)
+	code, _, _ := c.Text.ReadResponse(0)
+	c.Text.EndResponse(id)
+	if code != 523 {
+		t.Errorf("AUTH over plaintext code = %d, want 523", code)
+	}
+	if server.backend.authorized != 0 {
+		t.Errorf("credentials checked %d times over plaintext", server.backend.authorized)
+	}
+}
+
+func TestSMTPServer_AuthThrottling(t *testing.T) {
+	server := startTestServer(t, Config{MaxAuthFailures: 2, AuthFailuresWindow: time.Minute})
+
+	for range 2 {
+		if err := server.sendMail(t, "wrong", "app@example.com", []string{"79990001234@sms.example.com"}, []byte("\r\nText\r\n"), false); err == nil {
+			t.Fatal("sendMail() error = nil, want error")
+		}
+	}
+
+	// the IP is blocked even with the valid credentials
+	err := server.sendMail(t, "secret", "app@example.com", []string{"79990001234@sms.example.com"}, []byte("\r\nText\r\n"), false)
+	if err == nil || !strings.Contains(err.Error(), "454") {
+		t.Fatalf("sendMail() error = %v, want 454", err)
+	}
+	if server.backend.authorized != 2 {
+		t.Errorf("credentials checked %d times, want 2", server.backend.authorized)
+	}
+}
+
+func TestAuthLimiter_Window(t *testing.T) {
+	l := newAuthLimiter(1, 10*time.Millisecond)
+
+	l.Fail("10.0.0.1")
+	if l.Allow("10.0.0.1") {
+		t.Error("Allow() = true after the limit")
+	}
+	if !l.Allow("10.0.0.2") {
+		t.Error("Allow() = false for another IP")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !l.Allow("10.0.0.1") {
+		t.Error("Allow() = false after the window")
+	}
+}
@@ -0,0 +1,8 @@
+package enrollments
+
+import "errors"
+
+var (
+	ErrNotFound     = errors.New("enrollment not found")
+	ErrInvalidToken = errors.New("invalid, expired or exhausted enrollment token")
+)
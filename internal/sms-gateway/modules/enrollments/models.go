@@ -0,0 +1,45 @@
+package enrollments
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"gorm.io/gorm"
+)
+
+// Enrollment is the token that registers devices of the user with the
+// pre-assigned metadata, so a fleet is provisioned without further API calls.
+// Only the hash of the token is stored.
+type Enrollment struct {
+	ID        string `gorm:"primaryKey;type:char(21)"`
+	UserID    string `gorm:"not null;type:varchar(32);index:idx_device_enrollments_user_id"`
+	TokenHash string `gorm:"not null;type:char(64);uniqueIndex:unq_device_enrollments_token_hash"`
+
+	Name  *string  `gorm:"type:varchar(128)"`
+	Tags  []string `gorm:"type:json;serializer:json"`
+	Group *string  `gorm:"column:group_name;type:varchar(64)"`
+	// Settings are stored as the settings of the registered devices, so they
+	// are applied on top of the profiles assigned to the group and to the
+	// device.
+	Settings map[string]any `gorm:"type:json;serializer:json"`
+
+	// MaxUses is the number of devices the token registers, zero for
+	// unlimited.
+	MaxUses   uint32     `gorm:"not null;default:0"`
+	Uses      uint32     `gorm:"not null;default:0"`
+	ExpiresAt *time.Time `gorm:"type:datetime(3)"`
+
+	models.TimedModel
+}
+
+func (Enrollment) TableName() string {
+	return "device_enrollments"
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Enrollment{}); err != nil {
+		return fmt.Errorf("device_enrollments migration failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package enrollments
+
+import (
+	"github.com/capcom6/go-infra-fx/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"enrollments",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("enrollments")
+	}),
+	fx.Provide(
+		newRepository,
+		fx.Private,
+	),
+	fx.Provide(
+		NewService,
+	),
+)
+
+func init() {
+	db.RegisterMigration(Migrate)
+}
@@ -0,0 +1,104 @@
+package enrollments
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Insert stores the enrollment and reads back the timestamps set by the
+// database.
+func (r *repository) Insert(enrollment *Enrollment) error {
+	if err := r.db.Create(enrollment).Error; err != nil {
+		return err
+	}
+
+	return r.db.Where("id = ?", enrollment.ID).Take(enrollment).Error
+}
+
+// Select returns the enrollments of the user, newest first.
+func (r *repository) Select(userID string) ([]Enrollment, error) {
+	items := []Enrollment{}
+	err := r.db.
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+// Delete removes the enrollment of the user.
+func (r *repository) Delete(userID, id string) error {
+	res := r.db.
+		Where("user_id = ? AND id = ?", userID, id).
+		Delete(&Enrollment{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetValid returns the enrollment with the token hash if it's not expired and
+// has uses left.
+func (r *repository) GetValid(tokenHash string, now time.Time) (Enrollment, error) {
+	enrollment := Enrollment{}
+	err := r.db.
+		Where("token_hash = ?", tokenHash).
+		Where("max_uses = 0 OR uses < max_uses").
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Take(&enrollment).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return enrollment, ErrInvalidToken
+	}
+
+	return enrollment, err
+}
+
+// Redeem counts the use of the valid enrollment and calls register with it
+// in the same transaction. The use is rolled back if register fails. The
+// check and the increment are atomic and the enrollment stays locked until
+// register returns, so the limit of uses holds for concurrent registrations.
+func (r *repository) Redeem(id string, now time.Time, register func(Enrollment) error) (Enrollment, error) {
+	enrollment := Enrollment{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Enrollment{}).
+			Where("id = ?", id).
+			Where("max_uses = 0 OR uses < max_uses").
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Update("uses", gorm.Expr("uses + 1"))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidToken
+		}
+
+		if err := tx.Where("id = ?", id).Take(&enrollment).Error; err != nil {
+			return err
+		}
+
+		return register(enrollment)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return enrollment, ErrInvalidToken
+	}
+
+	return enrollment, err
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,135 @@
+package enrollments
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// EnrollmentIn is the metadata assigned to the devices registered with the
+// token.
+type EnrollmentIn struct {
+	Name     *string
+	Tags     []string
+	Group    *string
+	Settings map[string]any
+
+	MaxUses   uint32
+	ExpiresAt *time.Time
+}
+
+type ServiceParams struct {
+	fx.In
+
+	Enrollments *repository
+
+	SettingsSvc *settings.Service
+
+	IDGen db.IDGen
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	enrollments *repository
+
+	settingsSvc *settings.Service
+
+	idGen db.IDGen
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		enrollments: params.Enrollments,
+
+		settingsSvc: params.SettingsSvc,
+
+		idGen: params.IDGen,
+
+		logger: params.Logger.Named("service"),
+	}
+}
+
+// Create issues the enrollment token of the user. The token is returned only
+// once, the enrollment keeps its hash.
+func (s *Service) Create(userID string, in EnrollmentIn) (Enrollment, string, error) {
+	profile, err := s.settingsSvc.FilterProfile(in.Settings)
+	if err != nil {
+		return Enrollment{}, "", err
+	}
+
+	token := s.idGen()
+	enrollment := Enrollment{
+		ID:        s.idGen(),
+		UserID:    userID,
+		TokenHash: hashToken(token),
+
+		Name:     in.Name,
+		Tags:     in.Tags,
+		Group:    in.Group,
+		Settings: profile,
+
+		MaxUses:   in.MaxUses,
+		ExpiresAt: in.ExpiresAt,
+	}
+
+	if err := s.enrollments.Insert(&enrollment); err != nil {
+		return enrollment, "", fmt.Errorf("can't store enrollment: %w", err)
+	}
+
+	return enrollment, token, nil
+}
+
+// Select returns the enrollments of the user, newest first.
+func (s *Service) Select(userID string) ([]Enrollment, error) {
+	return s.enrollments.Select(userID)
+}
+
+// Delete revokes the enrollment token. Devices already registered with it
+// keep their metadata.
+func (s *Service) Delete(userID, id string) error {
+	return s.enrollments.Delete(userID, id)
+}
+
+// Authorize returns the enrollment of the token without counting a use, the
+// use is counted by Redeem on registration.
+//
+// If the token is unknown, expired or has no uses left, it returns
+// ErrInvalidToken.
+func (s *Service) Authorize(token string) (Enrollment, error) {
+	return s.enrollments.GetValid(hashToken(token), time.Now())
+}
+
+// Redeem counts the registration with the enrollment and calls register with
+// its current state within the same transaction, so a failed registration
+// doesn't use up the token.
+//
+// If the enrollment is removed, expired or has no uses left, it returns
+// ErrInvalidToken.
+func (s *Service) Redeem(id string, register func(Enrollment) error) (Enrollment, error) {
+	enrollment, err := s.enrollments.Redeem(id, time.Now(), register)
+	if err != nil {
+		return enrollment, err
+	}
+
+	s.logger.Info("Enrollment token redeemed",
+		zap.String("enrollment_id", enrollment.ID),
+		zap.String("user_id", enrollment.UserID),
+		zap.Uint32("uses", enrollment.Uses),
+	)
+
+	return enrollment, nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
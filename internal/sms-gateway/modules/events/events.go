@@ -1,11 +1,31 @@
 package events
 
 import (
+	"slices"
 	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 )
 
+// PushEncryptionKeysUpdated notifies the devices that the encryption keys of
+// the user were published or revoked.
+const PushEncryptionKeysUpdated smsgateway.PushEventType = "EncryptionKeysUpdated"
+
+// Types are the event types delivered to the devices.
+var Types = []smsgateway.PushEventType{
+	smsgateway.PushMessageEnqueued,
+	smsgateway.PushWebhooksUpdated,
+	smsgateway.PushMessagesExportRequested,
+	smsgateway.PushSettingsUpdated,
+	PushEncryptionKeysUpdated,
+}
+
+// IsType reports whether the value is one of the event types delivered to the
+// devices.
+func IsType(value string) bool {
+	return slices.Contains(Types, smsgateway.PushEventType(value))
+}
+
 func NewMessageEnqueuedEvent() *Event {
 	return NewEvent(smsgateway.PushMessageEnqueued, nil)
 }
@@ -24,6 +44,23 @@ func NewMessagesExportRequestedEvent(since, until time.Time) *Event {
 	)
 }
 
+// NewMessagesExportUploadRequestedEvent asks the device to upload the messages
+// to the server export with the given ID instead of triggering webhooks.
+func NewMessagesExportUploadRequestedEvent(exportID string, since, until time.Time) *Event {
+	return NewEvent(
+		smsgateway.PushMessagesExportRequested,
+		map[string]string{
+			"exportId": exportID,
+			"since":    since.Format(time.RFC3339),
+			"until":    until.Format(time.RFC3339),
+		},
+	)
+}
+
 func NewSettingsUpdatedEvent() *Event {
 	return NewEvent(smsgateway.PushSettingsUpdated, nil)
 }
+
+func NewEncryptionKeysUpdatedEvent() *Event {
+	return NewEvent(PushEncryptionKeysUpdated, nil)
+}
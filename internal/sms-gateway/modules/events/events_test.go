@@ -0,0 +1,18 @@
+package events
+
+import "testing"
+
+func TestIsType(t *testing.T) {
+	for _, eventType := range Types {
+		if !IsType(string(eventType)) {
+			t.Errorf("IsType(%q) = false, want true", eventType)
+		}
+	}
+
+	if !IsType(string(PushEncryptionKeysUpdated)) {
+		t.Errorf("IsType(%q) = false, want true", PushEncryptionKeysUpdated)
+	}
+	if IsType("Unknown") {
+		t.Error("IsType(\"Unknown\") = true, want false")
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+)
+
+// listeners keeps in-process subscribers waiting for events of a device,
+// e.g. long-polling requests.
+type listeners struct {
+	mu    sync.Mutex
+	items map[string]map[*listener]struct{}
+}
+
+type listener struct {
+	eventType smsgateway.PushEventType
+	ch        chan struct{}
+}
+
+func newListeners() *listeners {
+	return &listeners{
+		items: make(map[string]map[*listener]struct{}),
+	}
+}
+
+func (l *listeners) add(deviceID string, eventType smsgateway.PushEventType) (*listener, func()) {
+	item := &listener{
+		eventType: eventType,
+		ch:        make(chan struct{}, 1),
+	}
+
+	l.mu.Lock()
+	if l.items[deviceID] == nil {
+		l.items[deviceID] = make(map[*listener]struct{})
+	}
+	l.items[deviceID][item] = struct{}{}
+	l.mu.Unlock()
+
+	return item, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		delete(l.items[deviceID], item)
+		if len(l.items[deviceID]) == 0 {
+			delete(l.items, deviceID)
+		}
+	}
+}
+
+func (l *listeners) notify(deviceID string, eventType smsgateway.PushEventType) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for item := range l.items[deviceID] {
+		if item.eventType != eventType {
+			continue
+		}
+
+		select {
+		case item.ch <- struct{}{}:
+		default:
+			// already signaled
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+)
+
+func TestListeners(t *testing.T) {
+	l := newListeners()
+
+	enqueued, cancelEnqueued := l.add("device", smsgateway.PushMessageEnqueued)
+	webhooks, cancelWebhooks := l.add("device", smsgateway.PushWebhooksUpdated)
+	other, cancelOther := l.add("other", smsgateway.PushMessageEnqueued)
+	defer cancelWebhooks()
+	defer cancelOther()
+
+	l.notify("device", smsgateway.PushMessageEnqueued)
+	l.notify("device", smsgateway.PushMessageEnqueued)
+
+	select {
+	case <-enqueued.ch:
+	default:
+		t.Fatal("expected listener to be signaled")
+	}
+	select {
+	case <-enqueued.ch:
+		t.Fatal("expected a single pending signal")
+	default:
+	}
+	select {
+	case <-webhooks.ch:
+		t.Fatal("expected listener of another event type not to be signaled")
+	case <-other.ch:
+		t.Fatal("expected listener of another device not to be signaled")
+	default:
+	}
+
+	cancelEnqueued()
+	l.notify("device", smsgateway.PushMessageEnqueued)
+	select {
+	case <-enqueued.ch:
+		t.Fatal("expected cancelled listener not to be signaled")
+	default:
+	}
+
+	cancelWebhooks()
+	cancelOther()
+	if len(l.items) != 0 {
+		t.Fatalf("expected no listeners, got %d devices", len(l.items))
+	}
+}
@@ -10,6 +10,10 @@ const (
 	MetricEnqueuedTotal = "enqueued_total"
 	MetricSentTotal     = "sent_total"
 	MetricFailedTotal   = "failed_total"
+	MetricSkippedTotal  = "skipped_total"
+
+	MetricPushFallbackTotal = "push_fallback_total"
+	MetricPushDegradedTotal = "push_degraded_total"
 
 	LabelEvent        = "event"
 	LabelDeliveryType = "delivery_type"
@@ -17,6 +21,7 @@ const (
 
 	DeliveryTypePush    = "push"
 	DeliveryTypeSSE     = "sse"
+	DeliveryTypeMQTT    = "mqtt"
 	DeliveryTypeUnknown = "unknown"
 
 	FailureReasonQueueFull      = "queue_full"
@@ -28,6 +33,10 @@ type metrics struct {
 	enqueuedCounter *prometheus.CounterVec
 	sentCounter     *prometheus.CounterVec
 	failedCounter   *prometheus.CounterVec
+	skippedCounter  *prometheus.CounterVec
+
+	pushFallbackCounter *prometheus.CounterVec
+	pushDegradedCounter prometheus.Counter
 }
 
 // newMetrics creates and initializes all events metrics
@@ -51,6 +60,24 @@ func newMetrics() *metrics {
 			Name:      MetricFailedTotal,
 			Help:      "Total number of failed notifications",
 		}, []string{LabelEvent, LabelDeliveryType, LabelReason}),
+		skippedCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "events",
+			Name:      MetricSkippedTotal,
+			Help:      "Total number of notifications skipped due to device subscriptions",
+		}, []string{LabelEvent}),
+		pushFallbackCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "events",
+			Name:      MetricPushFallbackTotal,
+			Help:      "Total number of events sent by SSE to devices with degraded push tokens",
+		}, []string{LabelEvent}),
+		pushDegradedCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "events",
+			Name:      MetricPushDegradedTotal,
+			Help:      "Total number of devices marked as push-degraded",
+		}),
 	}
 }
 
@@ -68,3 +95,18 @@ func (m *metrics) IncrementSent(eventType string, deliveryType string) {
 func (m *metrics) IncrementFailed(eventType string, deliveryType string, reason string) {
 	m.failedCounter.WithLabelValues(eventType, deliveryType, reason).Inc()
 }
+
+// IncrementSkipped increments the skipped counter for the given event type
+func (m *metrics) IncrementSkipped(eventType string) {
+	m.skippedCounter.WithLabelValues(eventType).Inc()
+}
+
+// IncrementPushFallback increments the push fallback counter for the given event type
+func (m *metrics) IncrementPushFallback(eventType string) {
+	m.pushFallbackCounter.WithLabelValues(eventType).Inc()
+}
+
+// IncrementPushDegraded increments the push-degraded devices counter
+func (m *metrics) IncrementPushDegraded() {
+	m.pushDegradedCounter.Inc()
+}
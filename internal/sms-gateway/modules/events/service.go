@@ -3,8 +3,12 @@ package events
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/mqtt"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sse"
 	"go.uber.org/zap"
@@ -15,26 +19,77 @@ type Service struct {
 
 	sseSvc  *sse.Service
 	pushSvc *push.Service
+	mqttSvc *mqtt.Service
 
-	queue chan eventWrapper
+	queue     chan eventWrapper
+	listeners *listeners
 
 	metrics *metrics
 
 	logger *zap.Logger
 }
 
-func NewService(devicesSvc *devices.Service, sseSvc *sse.Service, pushSvc *push.Service, metrics *metrics, logger *zap.Logger) *Service {
-	return &Service{
+func NewService(devicesSvc *devices.Service, sseSvc *sse.Service, pushSvc *push.Service, mqttSvc *mqtt.Service, metrics *metrics, logger *zap.Logger) *Service {
+	svc := &Service{
 		deviceSvc: devicesSvc,
 		sseSvc:    sseSvc,
 		pushSvc:   pushSvc,
+		mqttSvc:   mqttSvc,
 
 		metrics: metrics,
 
-		queue: make(chan eventWrapper, 128),
+		queue:     make(chan eventWrapper, 128),
+		listeners: newListeners(),
 
 		logger: logger,
 	}
+
+	// the devices without push receive the events via the SSE broker, so the
+	// long-polling requests served by other instances are woken up too
+	sseSvc.Observe(svc.listeners.notify)
+
+	pushSvc.OnBlacklisted(svc.onPushBlacklisted)
+	devicesSvc.OnLifecycle(svc.onDeviceEvent)
+
+	return svc
+}
+
+// onPushBlacklisted redelivers the event not delivered to the blacklisted
+// push token by SSE. The devices with the token are marked as push-degraded
+// by the redelivery.
+func (s *Service) onPushBlacklisted(token string, event push.Event) {
+	items, err := s.deviceSvc.SelectByPushToken(token)
+	if err != nil {
+		s.logger.Error("Failed to select devices by push token", zap.Error(err))
+		return
+	}
+
+	for _, device := range items {
+		wrapper := eventWrapper{
+			UserID:     device.UserID,
+			DeviceID:   &device.ID,
+			Event:      NewEvent(event.Type, event.Data),
+			pushFailed: true,
+		}
+
+		select {
+		case s.queue <- wrapper:
+			s.metrics.IncrementEnqueued(string(event.Type))
+		default:
+			s.metrics.IncrementFailed(string(event.Type), DeliveryTypeSSE, FailureReasonQueueFull)
+			s.logger.Error("Failed to enqueue push fallback, event queue is full", zap.String("device_id", device.ID))
+		}
+	}
+}
+
+// onDeviceEvent resumes the notifications for the push token registered
+// again by the device.
+func (s *Service) onDeviceEvent(event devices.LifecycleEvent) {
+	if event.Type != devices.LifecycleTokenRotated || event.Device.PushToken == nil {
+		return
+	}
+
+	s.pushSvc.Unblacklist(*event.Device.PushToken)
 }
 
 func (s *Service) Notify(userID string, deviceID *string, event *Event) error {
@@ -56,6 +111,17 @@ func (s *Service) Notify(userID string, deviceID *string, event *Event) error {
 	return nil
 }
 
+// Listen subscribes to events of the given type for the device. The returned
+// channel is signaled when such an event is processed; repeated events are
+// coalesced until the channel is read. With the SSE broker, the events of the
+// devices without push are observed regardless of the instance which sent
+// them, otherwise only events sent by this instance are observed. The
+// returned function must be called to unsubscribe.
+func (s *Service) Listen(deviceID string, eventType smsgateway.PushEventType) (<-chan struct{}, func()) {
+	item, cancel := s.listeners.add(deviceID, eventType)
+	return item.ch, cancel
+}
+
 func (s *Service) Run(ctx context.Context) {
 	for {
 		select {
@@ -86,9 +152,43 @@ func (s *Service) processEvent(wrapper eventWrapper) {
 		return
 	}
 
+	now := time.Now()
+
 	// Process each device
 	for _, device := range devices {
-		if device.PushToken != nil && *device.PushToken != "" {
+		if wrapper.pushFailed {
+			// the event is already processed, only its push has failed
+			s.degradePush(device)
+			device.PushDegradedAt = &now
+		} else {
+			s.listeners.notify(device.ID, wrapper.Event.eventType)
+
+			// virtual devices of sandbox users have no connection to notify
+			if device.Sandbox {
+				continue
+			}
+
+			if !device.IsSubscribed(string(wrapper.Event.eventType)) {
+				s.metrics.IncrementSkipped(string(wrapper.Event.eventType))
+				continue
+			}
+
+			// MQTT is an additional channel, the server doesn't know whether the
+			// device is subscribed to its topic
+			if s.mqttSvc.Enabled() {
+				if err := s.mqttSvc.Publish(device.ID, mqtt.Event{
+					Type: wrapper.Event.eventType,
+					Data: wrapper.Event.data,
+				}); err != nil {
+					s.logger.Error("Failed to publish MQTT notification", zap.String("user_id", wrapper.UserID), zap.String("device_id", device.ID), zap.Error(err))
+					s.metrics.IncrementFailed(string(wrapper.Event.eventType), DeliveryTypeMQTT, FailureReasonProviderFailed)
+				} else {
+					s.metrics.IncrementSent(string(wrapper.Event.eventType), DeliveryTypeMQTT)
+				}
+			}
+		}
+
+		if device.CanPush() {
 			// Device has push token, use push service
 			if err := s.pushSvc.Enqueue(*device.PushToken, push.Event{
 				Type: wrapper.Event.eventType,
@@ -102,10 +202,16 @@ func (s *Service) processEvent(wrapper eventWrapper) {
 			continue
 		}
 
-		// No push token, use SSE service
+		if device.PushDegradedAt != nil {
+			s.metrics.IncrementPushFallback(string(wrapper.Event.eventType))
+		}
+
+		// No working push token, use SSE service, the device also polls
+		// more often without push
 		if err := s.sseSvc.Send(device.ID, sse.Event{
-			Type: wrapper.Event.eventType,
-			Data: wrapper.Event.data,
+			Type:    wrapper.Event.eventType,
+			Data:    wrapper.Event.data,
+			TraceID: wrapper.Event.traceID,
 		}); err != nil {
 			s.logger.Error("Failed to send SSE notification", zap.String("user_id", wrapper.UserID), zap.String("device_id", device.ID), zap.Error(err))
 			s.metrics.IncrementFailed(string(wrapper.Event.eventType), DeliveryTypeSSE, FailureReasonProviderFailed)
@@ -114,3 +220,19 @@ func (s *Service) processEvent(wrapper eventWrapper) {
 		}
 	}
 }
+
+// degradePush marks the device with the blacklisted push token as
+// push-degraded, so the next events are delivered by SSE.
+func (s *Service) degradePush(device models.Device) {
+	if device.PushDegradedAt != nil {
+		return
+	}
+
+	s.logger.Warn("Push token is blacklisted, falling back to SSE", zap.String("user_id", device.UserID), zap.String("device_id", device.ID))
+	if err := s.deviceSvc.SetPushDegraded(device); err != nil {
+		s.logger.Error("Failed to mark device as push-degraded", zap.String("device_id", device.ID), zap.Error(err))
+		return
+	}
+
+	s.metrics.IncrementPushDegraded()
+}
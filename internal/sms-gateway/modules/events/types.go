@@ -7,6 +7,8 @@ import (
 type Event struct {
 	eventType smsgateway.PushEventType
 	data      map[string]string
+	// traceID is the trace ID of the request which caused the event
+	traceID string
 }
 
 func NewEvent(eventType smsgateway.PushEventType, data map[string]string) *Event {
@@ -16,8 +18,19 @@ func NewEvent(eventType smsgateway.PushEventType, data map[string]string) *Event
 	}
 }
 
+// WithTraceID links the event to the trace of the request which caused it.
+// Only the SSE delivery is traced.
+func (e *Event) WithTraceID(traceID string) *Event {
+	e.traceID = traceID
+	return e
+}
+
 type eventWrapper struct {
 	UserID   string
 	DeviceID *string
 	Event    *Event
+
+	// pushFailed is set for the event not delivered because the push token
+	// of the device is blacklisted, so it's delivered by SSE only
+	pushFailed bool
 }
@@ -0,0 +1,116 @@
+package exports
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	exportPrefix   = "export:"
+	artifactPrefix = "artifact:"
+	// indexPrefix is the prefix of the artifacts index of the user.
+	indexPrefix   = "artifacts:"
+	contentPrefix = "artifact-content:"
+	// objectPrefix is the prefix of the artifacts in the object storage.
+	objectPrefix = "exports/"
+)
+
+// Artifact is the downloadable file with the messages of the completed
+// export.
+type Artifact struct {
+	ExportID string `json:"exportId"`
+	UserID   string `json:"userId"`
+	DeviceID string `json:"deviceId"`
+
+	// Count is the number of exported messages.
+	Count int `json:"count"`
+	// Size is the size of the content in bytes.
+	Size int `json:"size"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// indexEntry is the artifact in the artifacts index of the user.
+type indexEntry struct {
+	ExportID  string    `json:"exportId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CompletedEvent describes the export completed by the device. Artifact and
+// URL are set only if artifacts are enabled.
+type CompletedEvent struct {
+	Export   Export
+	Artifact *Artifact
+	URL      string
+}
+
+// CompletedHandler is called synchronously after the export is completed, so
+// it must not block.
+type CompletedHandler func(event CompletedEvent)
+
+type completedHandlers struct {
+	mux      sync.RWMutex
+	handlers []CompletedHandler
+}
+
+func (c *completedHandlers) add(handler CompletedHandler) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.handlers = append(c.handlers, handler)
+}
+
+func (c *completedHandlers) emit(event CompletedEvent) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	for _, h := range c.handlers {
+		h(event)
+	}
+}
+
+// signer signs the download links of artifacts.
+type signer struct {
+	key []byte
+}
+
+// URL returns the link to download the artifact of the export until expires.
+func (s signer) URL(baseURL, exportID string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", exp)
+	query.Set("signature", s.sign(exportID, exp))
+
+	return baseURL + "/exports/" + url.PathEscape(exportID) + "?" + query.Encode()
+}
+
+// Verify checks the signature of the download link.
+func (s signer) Verify(exportID, expires, signature string, now time.Time) error {
+	if !hmac.Equal([]byte(s.sign(exportID, expires)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > exp {
+		return ErrLinkExpired
+	}
+
+	return nil
+}
+
+func (s signer) sign(exportID, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(exportID + ":" + expires))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
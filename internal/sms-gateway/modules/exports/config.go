@@ -0,0 +1,26 @@
+package exports
+
+import "time"
+
+type Config struct {
+	// BaseURL is the public URL of the gateway artifact download links are
+	// built on, e.g. "https://sms.example.com". Empty → artifacts are not
+	// stored.
+	BaseURL string
+	// SigningKey is the secret download links are signed with. Empty → a
+	// random key is generated on start, so links don't survive restarts. It's
+	// required with multiple instances.
+	SigningKey string
+	// MultiInstance is set if the gateway runs multiple instances, so the
+	// artifacts and the download links must be valid on every instance.
+	MultiInstance bool
+	// SharedCache is set if the cache backend is shared by the instances.
+	// Artifacts are kept in the cache, so it's required with multiple
+	// instances.
+	SharedCache bool
+	// MaxSize is the max size of the exported messages in bytes, 0 for
+	// unlimited.
+	MaxSize int64
+	// ArtifactTTL is the lifetime of the artifact and its download links.
+	ArtifactTTL time.Duration
+}
@@ -0,0 +1,15 @@
+package exports
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("export not found")
+	ErrCompleted     = errors.New("export is already completed")
+	ErrLimitExceeded = errors.New("export messages limit exceeded")
+
+	ErrInvalidSignature = errors.New("invalid download link signature")
+	ErrLinkExpired      = errors.New("download link expired")
+
+	ErrSigningKeyRequired  = errors.New("signing key of download links is required with multiple instances")
+	ErrSharedCacheRequired = errors.New("shared cache is required for artifacts with multiple instances")
+)
@@ -0,0 +1,36 @@
+package exports
+
+import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/cleaner"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type FxResult struct {
+	fx.Out
+
+	Service   *Service
+	AsCleaner cleaner.Cleanable `group:"cleaners"`
+}
+
+var Module = fx.Module(
+	"exports",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("exports")
+	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("exports")
+	}, fx.Private),
+	fx.Provide(func(p ServiceParams) (FxResult, error) {
+		svc, err := NewService(p)
+		if err != nil {
+			return FxResult{}, err
+		}
+
+		return FxResult{
+			Service:   svc,
+			AsCleaner: svc,
+		}, nil
+	}),
+)
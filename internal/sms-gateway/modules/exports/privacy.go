@@ -0,0 +1,94 @@
+package exports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+// SelectByPhoneNumbers returns the uploaded messages of the user from any of
+// the phone numbers.
+func (s *Service) SelectByPhoneNumbers(ctx context.Context, userID string, phoneNumbers []string) ([]Message, error) {
+	exports, err := s.userExports(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []Message{}
+	for _, export := range exports {
+		for _, message := range export.Messages {
+			if slices.Contains(phoneNumbers, message.PhoneNumber) {
+				messages = append(messages, message)
+			}
+		}
+	}
+
+	return messages, nil
+}
+
+// ErasePhoneNumbers removes the uploaded messages of the user from the phone
+// numbers and returns the number of the removed messages. The artifacts of
+// the affected exports contain the messages, so they are removed too.
+func (s *Service) ErasePhoneNumbers(ctx context.Context, userID string, phoneNumbers []string) (int, error) {
+	exports, err := s.userExports(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, export := range exports {
+		count := len(export.Messages)
+		export.Messages = slices.DeleteFunc(export.Messages, func(m Message) bool {
+			return slices.Contains(phoneNumbers, m.PhoneNumber)
+		})
+		if len(export.Messages) == count {
+			continue
+		}
+
+		data, err := json.Marshal(export.Messages)
+		if err != nil {
+			return removed, fmt.Errorf("can't marshal messages: %w", err)
+		}
+		export.Size = len(data)
+
+		// the erasure doesn't extend the lifetime of the export
+		if err := s.cache.Set(ctx, exportKey(userID, export.ID), export, cache.WithValidUntil(export.UpdatedAt.Add(exportTTL))); err != nil {
+			return removed, fmt.Errorf("can't store export: %w", err)
+		}
+		removed += count - len(export.Messages)
+
+		if err := s.DeleteArtifact(ctx, userID, export.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+// userExports returns the non-expired exports of the user.
+func (s *Service) userExports(ctx context.Context, userID string) ([]Export, error) {
+	keys, err := s.cache.Keys(ctx, cache.EscapePattern(exportKey(userID, ""))+"*")
+	if err != nil {
+		return nil, fmt.Errorf("can't list exports: %w", err)
+	}
+
+	exports := make([]Export, 0, len(keys))
+	for _, key := range keys {
+		export, err := s.load(ctx, userID, strings.TrimPrefix(key, exportKey(userID, "")))
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		exports = append(exports, export)
+	}
+
+	return exports, nil
+}
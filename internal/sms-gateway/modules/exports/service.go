@@ -0,0 +1,509 @@
+package exports
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/storage"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxMessages is the max number of messages in a single export.
+	maxMessages = 10_000
+	// exportTTL is the time after the last update when the export is dropped.
+	exportTTL = 24 * time.Hour
+	// maxIndexAttempts is the max number of attempts to update the artifacts
+	// index of the user changed concurrently.
+	maxIndexAttempts = 5
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	Cache      cache.Cache
+	EventsSvc  *events.Service
+	StorageSvc *storage.Service
+	IDGen      db.IDGen
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	config Config
+
+	cache     *cache.Typed[Export]
+	artifacts *cache.Typed[Artifact]
+	index     *cache.Typed[[]indexEntry]
+	contents  cache.Cache
+	eventsSvc *events.Service
+	idGen     db.IDGen
+
+	// storage keeps the artifacts content if the object storage is enabled,
+	// otherwise it's kept in the cache
+	storage *storage.Service
+
+	signer    signer
+	completed completedHandlers
+
+	logger *zap.Logger
+}
+
+// NewService returns the exports service. It fails if artifacts are enabled
+// with multiple instances, but the download links or the artifacts aren't
+// valid on every instance.
+func NewService(params ServiceParams) (*Service, error) {
+	config := params.Config
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.ArtifactTTL <= 0 {
+		config.ArtifactTTL = exportTTL
+	}
+
+	logger := params.Logger.Named("service")
+
+	storageEnabled := params.StorageSvc != nil && params.StorageSvc.Enabled()
+	if config.MultiInstance && (config.BaseURL != "" || storageEnabled) {
+		if !config.SharedCache {
+			return nil, ErrSharedCacheRequired
+		}
+		if config.BaseURL != "" && config.SigningKey == "" {
+			return nil, ErrSigningKeyRequired
+		}
+	}
+
+	key := []byte(config.SigningKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+		if config.BaseURL != "" {
+			logger.Warn("Signing key of export download links is not set, links won't survive restarts")
+		}
+	}
+
+	s := &Service{
+		config: config,
+
+		cache:     cache.NewTyped[Export](params.Cache),
+		artifacts: cache.NewTyped[Artifact](params.Cache),
+		index:     cache.NewTyped[[]indexEntry](params.Cache),
+		contents:  params.Cache,
+		eventsSvc: params.EventsSvc,
+		idGen:     params.IDGen,
+
+		signer: signer{key: key},
+
+		logger: logger,
+	}
+
+	if storageEnabled {
+		s.storage = params.StorageSvc
+	}
+
+	return s, nil
+}
+
+// ArtifactsEnabled returns true if the base URL of download links or the
+// object storage is configured.
+func (s *Service) ArtifactsEnabled() bool {
+	return s.config.BaseURL != "" || s.storage != nil
+}
+
+// OnCompleted registers the handler called after the device uploads the
+// final batch of the export.
+func (s *Service) OnCompleted(handler CompletedHandler) {
+	s.completed.add(handler)
+}
+
+// Request creates the export and asks the device to upload the messages
+// received in the period.
+func (s *Service) Request(ctx context.Context, device models.Device, since, until time.Time) (Export, error) {
+	now := time.Now()
+	export := Export{
+		ID:        s.idGen(),
+		UserID:    device.UserID,
+		DeviceID:  device.ID,
+		Since:     since,
+		Until:     until,
+		Status:    StatusRequested,
+		Messages:  []Message{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.save(ctx, export); err != nil {
+		return export, err
+	}
+
+	event := events.NewMessagesExportUploadRequestedEvent(export.ID, since, until)
+	if err := s.eventsSvc.Notify(device.UserID, &device.ID, event); err != nil {
+		return export, fmt.Errorf("can't notify device: %w", err)
+	}
+
+	return export, nil
+}
+
+// Get returns the export of the user.
+func (s *Service) Get(ctx context.Context, userID, id string) (Export, error) {
+	return s.load(ctx, userID, id)
+}
+
+// Upload appends the batch of messages uploaded by the device. The export is
+// completed with the final batch. The batch is appended atomically, so the
+// concurrent uploads don't overwrite each other.
+func (s *Service) Upload(ctx context.Context, device models.Device, id string, messages []Message, final bool) (Export, error) {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return Export{}, fmt.Errorf("can't marshal messages: %w", err)
+	}
+
+	var event CompletedEvent
+	export, err := s.cache.Update(ctx, exportKey(device.UserID, id), func(export Export) (Export, error) {
+		if export.DeviceID != device.ID {
+			return Export{}, ErrNotFound
+		}
+
+		if export.Status == StatusCompleted {
+			return export, ErrCompleted
+		}
+
+		if len(export.Messages)+len(messages) > maxMessages {
+			return export, ErrLimitExceeded
+		}
+
+		if s.config.MaxSize > 0 && int64(export.Size+len(data)) > s.config.MaxSize {
+			return export, ErrLimitExceeded
+		}
+
+		export.Messages = append(export.Messages, messages...)
+		export.Size += len(data)
+		export.Status = StatusUploading
+		export.UpdatedAt = time.Now()
+
+		if !final {
+			return export, nil
+		}
+
+		export.Status = StatusCompleted
+
+		event = CompletedEvent{Export: export}
+		if s.ArtifactsEnabled() {
+			// the artifact is stored again if the export has changed
+			// concurrently, so it holds all the batches
+			artifact, err := s.storeArtifact(ctx, export)
+			if err != nil {
+				return export, err
+			}
+
+			event.Artifact = &artifact
+			event.URL = s.DownloadURL(artifact)
+		}
+
+		return export, nil
+	}, cache.WithTTL(exportTTL))
+	switch {
+	case errors.Is(err, cache.ErrKeyNotFound):
+		return Export{}, ErrNotFound
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrCompleted), errors.Is(err, ErrLimitExceeded):
+		return export, err
+	case err != nil:
+		return export, fmt.Errorf("can't upload export: %w", err)
+	}
+
+	if final {
+		s.completed.emit(event)
+	}
+
+	return export, nil
+}
+
+// Artifacts returns the non-expired artifacts of the user, the most recent
+// first. The artifacts are listed by the index of the user, so the artifacts
+// of the other users aren't loaded.
+func (s *Service) Artifacts(ctx context.Context, userID string) ([]Artifact, error) {
+	entries, err := s.index.Get(ctx, indexPrefix+userID)
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return []Artifact{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get artifacts index: %w", err)
+	}
+
+	artifacts := make([]Artifact, 0, len(entries))
+	for _, entry := range entries {
+		artifact, err := s.loadArtifact(ctx, entry.ExportID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		artifacts = append(artifacts, artifact)
+	}
+
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].CreatedAt.After(artifacts[j].CreatedAt)
+	})
+
+	return artifacts, nil
+}
+
+// DeleteArtifact removes the artifact of the user's export.
+func (s *Service) DeleteArtifact(ctx context.Context, userID, exportID string) error {
+	artifact, err := s.loadArtifact(ctx, exportID)
+	if err != nil {
+		return err
+	}
+
+	if artifact.UserID != userID {
+		return ErrNotFound
+	}
+
+	if err := s.deleteContent(ctx, exportID); err != nil {
+		return err
+	}
+	if err := s.artifacts.Delete(ctx, artifactPrefix+exportID); err != nil {
+		return fmt.Errorf("can't delete artifact: %w", err)
+	}
+
+	return s.updateIndex(ctx, userID, func(entries []indexEntry) []indexEntry {
+		return slices.DeleteFunc(entries, func(e indexEntry) bool { return e.ExportID == exportID })
+	})
+}
+
+// DownloadURL returns the signed link to download the artifact, valid until
+// the artifact expires. Artifacts in the object storage are downloaded from it
+// directly, bypassing the server.
+//
+// It returns an empty string if no link can be issued.
+func (s *Service) DownloadURL(artifact Artifact) string {
+	if s.storage != nil {
+		ttl := min(time.Until(artifact.ExpiresAt), storage.MaxPresignTTL)
+		link, err := s.storage.PresignGet(objectKey(artifact.ExportID), ttl, artifact.ExportID+".json")
+		if err == nil {
+			return link
+		}
+		s.logger.Warn("Can't presign artifact link", zap.String("export_id", artifact.ExportID), zap.Error(err))
+	}
+
+	if s.config.BaseURL == "" {
+		return ""
+	}
+
+	return s.signer.URL(s.config.BaseURL, artifact.ExportID, artifact.ExpiresAt)
+}
+
+// Download returns the content of the artifact requested by the signed
+// download link.
+func (s *Service) Download(ctx context.Context, exportID, expires, signature string) ([]byte, error) {
+	if err := s.signer.Verify(exportID, expires, signature, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if s.storage == nil {
+		content, err := s.contents.GetBytes(ctx, contentPrefix+exportID)
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't get artifact content: %w", err)
+		}
+
+		return content, nil
+	}
+
+	// the object may outlive the artifact until it's removed
+	if _, err := s.loadArtifact(ctx, exportID); err != nil {
+		return nil, err
+	}
+
+	content, err := s.storage.Get(ctx, objectKey(exportID))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get artifact content: %w", err)
+	}
+
+	return content, nil
+}
+
+// storeArtifact stores the messages of the completed export for download.
+func (s *Service) storeArtifact(ctx context.Context, export Export) (Artifact, error) {
+	content, err := json.Marshal(export.Messages)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("can't marshal artifact: %w", err)
+	}
+
+	now := time.Now()
+	artifact := Artifact{
+		ExportID:  export.ID,
+		UserID:    export.UserID,
+		DeviceID:  export.DeviceID,
+		Count:     len(export.Messages),
+		Size:      len(content),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.config.ArtifactTTL),
+	}
+
+	if s.storage != nil {
+		err = s.storage.Put(ctx, objectKey(export.ID), content, "application/json")
+	} else {
+		err = s.contents.SetBytes(ctx, contentPrefix+export.ID, content, cache.WithValidUntil(artifact.ExpiresAt))
+	}
+	if err != nil {
+		return artifact, fmt.Errorf("can't store artifact content: %w", err)
+	}
+	if err := s.artifacts.Set(ctx, artifactPrefix+export.ID, artifact, cache.WithValidUntil(artifact.ExpiresAt)); err != nil {
+		return artifact, fmt.Errorf("can't store artifact: %w", err)
+	}
+
+	err = s.updateIndex(ctx, export.UserID, func(entries []indexEntry) []indexEntry {
+		entries = slices.DeleteFunc(entries, func(e indexEntry) bool { return e.ExportID == export.ID })
+		return append(entries, indexEntry{ExportID: export.ID, ExpiresAt: artifact.ExpiresAt})
+	})
+
+	return artifact, err
+}
+
+// updateIndex atomically updates the artifacts index of the user. The expired
+// entries are dropped. The index outlives the artifacts it lists, as every
+// artifact expires within the TTL.
+func (s *Service) updateIndex(ctx context.Context, userID string, update func([]indexEntry) []indexEntry) error {
+	key := indexPrefix + userID
+	apply := func(entries []indexEntry) []indexEntry {
+		now := time.Now()
+		return slices.DeleteFunc(update(entries), func(e indexEntry) bool { return e.ExpiresAt.Before(now) })
+	}
+
+	for range maxIndexAttempts {
+		current, err := s.index.Get(ctx, key)
+		switch {
+		case errors.Is(err, cache.ErrKeyNotFound):
+			entries := apply(nil)
+			if len(entries) == 0 {
+				return nil
+			}
+
+			err = s.index.SetOrFail(ctx, key, entries, cache.WithTTL(s.config.ArtifactTTL))
+			if errors.Is(err, cache.ErrKeyExists) {
+				// the index is created concurrently
+				continue
+			}
+		case err == nil:
+			err = s.index.CompareAndSwap(ctx, key, current, apply(slices.Clone(current)), cache.WithTTL(s.config.ArtifactTTL))
+			if errors.Is(err, cache.ErrValueMismatch) || errors.Is(err, cache.ErrKeyNotFound) {
+				// the index is updated concurrently
+				continue
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("can't update artifacts index: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("can't update artifacts index: %w", cache.ErrValueMismatch)
+}
+
+func (s *Service) loadArtifact(ctx context.Context, exportID string) (Artifact, error) {
+	artifact, err := s.artifacts.Get(ctx, artifactPrefix+exportID)
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return Artifact{}, ErrNotFound
+	}
+	if err != nil {
+		return Artifact{}, fmt.Errorf("can't get artifact: %w", err)
+	}
+
+	return artifact, nil
+}
+
+func (s *Service) load(ctx context.Context, userID, id string) (Export, error) {
+	export, err := s.cache.Get(ctx, exportKey(userID, id))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return Export{}, ErrNotFound
+	}
+	if err != nil {
+		return Export{}, fmt.Errorf("can't get export: %w", err)
+	}
+
+	return export, nil
+}
+
+func (s *Service) save(ctx context.Context, export Export) error {
+	if err := s.cache.Set(ctx, exportKey(export.UserID, export.ID), export, cache.WithTTL(exportTTL)); err != nil {
+		return fmt.Errorf("can't store export: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) deleteContent(ctx context.Context, exportID string) error {
+	var err error
+	if s.storage != nil {
+		err = s.storage.Delete(ctx, objectKey(exportID))
+	} else {
+		err = s.contents.Delete(ctx, contentPrefix+exportID)
+	}
+	if err != nil {
+		return fmt.Errorf("can't delete artifact content: %w", err)
+	}
+
+	return nil
+}
+
+// Clean removes the objects of the expired artifacts from the storage. The
+// objects don't expire with the artifacts, so they are swept periodically.
+func (s *Service) Clean(ctx context.Context) error {
+	if s.storage == nil {
+		return nil
+	}
+
+	objects, err := s.storage.List(ctx, objectPrefix)
+	if err != nil {
+		return fmt.Errorf("can't list artifacts: %w", err)
+	}
+
+	until := time.Now().Add(-s.config.ArtifactTTL)
+	removed := 0
+	for _, object := range objects {
+		if object.LastModified.After(until) {
+			continue
+		}
+
+		if err := s.storage.Delete(ctx, object.Key); err != nil {
+			return fmt.Errorf("can't delete artifact: %w", err)
+		}
+		removed++
+	}
+
+	s.logger.Info("Cleaned expired artifacts", zap.Int("count", removed))
+
+	return nil
+}
+
+// exportKey returns the cache key of the export, the exports are keyed by
+// user so the exports of the user are listed without loading the others.
+func exportKey(userID, id string) string {
+	return exportPrefix + userID + ":" + id
+}
+
+// objectKey returns the key of the artifact content in the object storage.
+func objectKey(exportID string) string {
+	return objectPrefix + exportID + ".json"
+}
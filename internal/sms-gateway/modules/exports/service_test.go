@@ -0,0 +1,408 @@
+package exports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/storage"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func TestService_Upload(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{cache: cache.NewTyped[Export](cache.NewMemory(0)), logger: zap.NewNop()}
+
+	device := models.Device{ID: "device", UserID: "user"}
+	if err := svc.save(ctx, Export{ID: "export", UserID: "user", DeviceID: "device", Status: StatusRequested}); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	batch := []Message{{ID: "1", Type: "sms", PhoneNumber: "+79990001234", Text: "Hello", ReceivedAt: time.Now()}}
+
+	if _, err := svc.Upload(ctx, models.Device{ID: "other"}, "export", batch, false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Upload() from other device error = %v, want %v", err, ErrNotFound)
+	}
+
+	export, err := svc.Upload(ctx, device, "export", batch, false)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if export.Status != StatusUploading || len(export.Messages) != 1 {
+		t.Errorf("Upload() = %s with %d messages, want %s with 1", export.Status, len(export.Messages), StatusUploading)
+	}
+
+	if _, err := svc.Upload(ctx, device, "export", batch, true); err != nil {
+		t.Fatalf("Upload() final error = %v", err)
+	}
+
+	export, err = svc.Get(ctx, "user", "export")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if export.Status != StatusCompleted || len(export.Messages) != 2 {
+		t.Errorf("Get() = %s with %d messages, want %s with 2", export.Status, len(export.Messages), StatusCompleted)
+	}
+
+	if _, err := svc.Upload(ctx, device, "export", batch, true); !errors.Is(err, ErrCompleted) {
+		t.Errorf("Upload() after completion error = %v, want %v", err, ErrCompleted)
+	}
+	if _, err := svc.Get(ctx, "other", "export"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() by other user error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestService_UploadConcurrent(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{cache: cache.NewTyped[Export](cache.NewMemory(0)), logger: zap.NewNop()}
+
+	device := models.Device{ID: "device", UserID: "user"}
+	if err := svc.save(ctx, Export{ID: "export", UserID: "user", DeviceID: "device", Status: StatusRequested}); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	const batches = 5
+	wg := sync.WaitGroup{}
+	for i := range batches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			batch := []Message{{ID: fmt.Sprint(i), Type: "sms", PhoneNumber: "+79990001234", Text: "Hello"}}
+			if _, err := svc.Upload(ctx, device, "export", batch, false); err != nil {
+				t.Errorf("Upload() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	export, err := svc.Get(ctx, "user", "export")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(export.Messages) != batches {
+		t.Errorf("Get() = %d messages, want %d", len(export.Messages), batches)
+	}
+}
+
+func TestService_UploadLimit(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{cache: cache.NewTyped[Export](cache.NewMemory(0)), logger: zap.NewNop()}
+
+	device := models.Device{ID: "device", UserID: "user"}
+	if err := svc.save(ctx, Export{ID: "export", UserID: "user", DeviceID: "device", Status: StatusRequested}); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	if _, err := svc.Upload(ctx, device, "export", make([]Message, maxMessages+1), false); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Upload() error = %v, want %v", err, ErrLimitExceeded)
+	}
+}
+
+func TestService_UploadSizeLimit(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{config: Config{MaxSize: 100}, cache: cache.NewTyped[Export](cache.NewMemory(0)), logger: zap.NewNop()}
+
+	device := models.Device{ID: "device", UserID: "user"}
+	if err := svc.save(ctx, Export{ID: "export", UserID: "user", DeviceID: "device", Status: StatusRequested}); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	batch := []Message{{ID: "1", Type: "sms", PhoneNumber: "+79990001234", Text: strings.Repeat("a", 100)}}
+	if _, err := svc.Upload(ctx, device, "export", batch, false); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Upload() error = %v, want %v", err, ErrLimitExceeded)
+	}
+}
+
+func TestService_Artifacts(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(ServiceParams{
+		Config: Config{BaseURL: "https://sms.example.com/", SigningKey: "secret", ArtifactTTL: time.Hour},
+		Cache:  cache.NewMemory(0),
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var completed CompletedEvent
+	svc.OnCompleted(func(event CompletedEvent) {
+		completed = event
+	})
+
+	device := models.Device{ID: "device", UserID: "user"}
+	if err := svc.save(ctx, Export{ID: "export", UserID: "user", DeviceID: "device", Status: StatusRequested}); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	batch := []Message{{ID: "1", Type: "sms", PhoneNumber: "+79990001234", Text: "Hello", ReceivedAt: time.Now()}}
+	if _, err := svc.Upload(ctx, device, "export", batch, true); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	if completed.Artifact == nil || completed.Artifact.Count != 1 {
+		t.Fatalf("OnCompleted() artifact = %+v, want 1 message", completed.Artifact)
+	}
+
+	link, err := url.Parse(completed.URL)
+	if err != nil {
+		t.Fatalf("OnCompleted() url = %q: %v", completed.URL, err)
+	}
+	if link.Host != "sms.example.com" || link.Path != "/exports/export" {
+		t.Errorf("OnCompleted() url = %q, want https://sms.example.com/exports/export", completed.URL)
+	}
+
+	query := link.Query()
+	content, err := svc.Download(ctx, "export", query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if len(content) != completed.Artifact.Size {
+		t.Errorf("Download() size = %d, want %d", len(content), completed.Artifact.Size)
+	}
+
+	if _, err := svc.Download(ctx, "other", query.Get("expires"), query.Get("signature")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Download() with other export error = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	artifacts, err := svc.Artifacts(ctx, "user")
+	if err != nil || len(artifacts) != 1 {
+		t.Fatalf("Artifacts() = %v, %v, want 1 artifact", artifacts, err)
+	}
+	if artifacts, _ := svc.Artifacts(ctx, "other"); len(artifacts) != 0 {
+		t.Errorf("Artifacts() of other user = %v, want none", artifacts)
+	}
+
+	if err := svc.DeleteArtifact(ctx, "other", "export"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteArtifact() by other user error = %v, want %v", err, ErrNotFound)
+	}
+	if err := svc.DeleteArtifact(ctx, "user", "export"); err != nil {
+		t.Fatalf("DeleteArtifact() error = %v", err)
+	}
+	if _, err := svc.Download(ctx, "export", query.Get("expires"), query.Get("signature")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Download() after delete error = %v, want %v", err, ErrNotFound)
+	}
+	if entries, err := svc.index.Get(ctx, indexPrefix+"user"); err != nil || len(entries) != 0 {
+		t.Errorf("index after delete = %v, %v, want empty", entries, err)
+	}
+}
+
+func TestService_DownloadURLStorage(t *testing.T) {
+	egressSvc, _ := egress.NewService(egress.Config{})
+	storageSvc, err := storage.NewService(storage.ServiceParams{
+		Config:    storage.Config{Endpoint: "http://minio:9000", Bucket: "bucket", PathStyle: true},
+		EgressSvc: egressSvc,
+		Logger:    zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewService(ServiceParams{
+		Cache:      cache.NewMemory(0),
+		StorageSvc: storageSvc,
+		Logger:     zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !svc.ArtifactsEnabled() {
+		t.Fatal("ArtifactsEnabled() = false with the object storage")
+	}
+
+	link, err := url.Parse(svc.DownloadURL(Artifact{ExportID: "export", ExpiresAt: time.Now().Add(time.Hour)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Host != "minio:9000" || link.Path != "/bucket/exports/export.json" || link.Query().Get("X-Amz-Signature") == "" {
+		t.Errorf("DownloadURL() = %s, want the presigned object link", link)
+	}
+
+	if got := svc.DownloadURL(Artifact{ExportID: "export", ExpiresAt: time.Now().Add(-time.Minute)}); got != "" {
+		t.Errorf("DownloadURL() of expired artifact = %q, want empty", got)
+	}
+}
+
+func TestService_Clean(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	fresh := time.Now().UTC().Format(time.RFC3339)
+
+	deleted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			fmt.Fprintf(w, "<ListBucketResult>"+
+				"<Contents><Key>exports/old.json</Key><LastModified>%s</LastModified></Contents>"+
+				"<Contents><Key>exports/fresh.json</Key><LastModified>%s</LastModified></Contents>"+
+				"<IsTruncated>false</IsTruncated></ListBucketResult>", old, fresh)
+		case http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	egressSvc, _ := egress.NewService(egress.Config{})
+	storageSvc, err := storage.NewService(storage.ServiceParams{
+		Config:    storage.Config{Endpoint: server.URL, Bucket: "bucket", PathStyle: true},
+		EgressSvc: egressSvc,
+		Logger:    zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewService(ServiceParams{
+		Config:     Config{ArtifactTTL: time.Hour},
+		Cache:      cache.NewMemory(0),
+		StorageSvc: storageSvc,
+		Logger:     zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.Clean(context.Background()); err != nil {
+		t.Fatalf("Clean() error = %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/bucket/exports/old.json" {
+		t.Errorf("Clean() deleted %v, want the expired artifact only", deleted)
+	}
+}
+
+func TestSigner_Expired(t *testing.T) {
+	s := signer{key: []byte("secret")}
+
+	expires := time.Now().Add(-time.Minute)
+	link, _ := url.Parse(s.URL("", "export", expires))
+	query := link.Query()
+
+	if err := s.Verify("export", query.Get("expires"), query.Get("signature"), time.Now()); !errors.Is(err, ErrLinkExpired) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrLinkExpired)
+	}
+}
+
+func TestService_ErasePhoneNumbers(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(ServiceParams{
+		Config: Config{BaseURL: "https://sms.example.com/", SigningKey: "secret", ArtifactTTL: time.Hour},
+		Cache:  cache.NewMemory(0),
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	device := models.Device{ID: "device", UserID: "user"}
+	for _, id := range []string{"export", "other"} {
+		if err := svc.save(ctx, Export{ID: id, UserID: "user", DeviceID: "device", Status: StatusRequested, UpdatedAt: time.Now()}); err != nil {
+			t.Fatalf("save() error = %v", err)
+		}
+	}
+
+	batch := []Message{
+		{ID: "1", Type: "sms", PhoneNumber: "+79990001234", Text: "Hello", ReceivedAt: time.Now()},
+		{ID: "2", Type: "sms", PhoneNumber: "+79990005678", Text: "Hi", ReceivedAt: time.Now()},
+	}
+	if _, err := svc.Upload(ctx, device, "export", batch, true); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if _, err := svc.Upload(ctx, device, "other", batch[1:], false); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	phoneNumbers := []string{"+79990001234"}
+	if messages, err := svc.SelectByPhoneNumbers(ctx, "user", phoneNumbers); err != nil || len(messages) != 1 {
+		t.Fatalf("SelectByPhoneNumbers() = %v, %v, want 1 message", messages, err)
+	}
+	if messages, _ := svc.SelectByPhoneNumbers(ctx, "other", phoneNumbers); len(messages) != 0 {
+		t.Errorf("SelectByPhoneNumbers() of other user = %v, want none", messages)
+	}
+
+	removed, err := svc.ErasePhoneNumbers(ctx, "user", phoneNumbers)
+	if err != nil || removed != 1 {
+		t.Fatalf("ErasePhoneNumbers() = %d, %v, want 1", removed, err)
+	}
+
+	if messages, _ := svc.SelectByPhoneNumbers(ctx, "user", phoneNumbers); len(messages) != 0 {
+		t.Errorf("SelectByPhoneNumbers() after erasure = %v, want none", messages)
+	}
+	if export, _ := svc.Get(ctx, "user", "export"); len(export.Messages) != 1 {
+		t.Errorf("Get() after erasure = %d messages, want 1", len(export.Messages))
+	}
+	if artifacts, _ := svc.Artifacts(ctx, "user"); len(artifacts) != 0 {
+		t.Errorf("Artifacts() after erasure = %v, want none", artifacts)
+	}
+}
+
+func TestService_ArtifactsIndex(t *testing.T) {
+	ctx := context.Background()
+	svc, err := NewService(ServiceParams{
+		Config: Config{BaseURL: "https://sms.example.com", SigningKey: "secret", ArtifactTTL: time.Hour},
+		Cache:  cache.NewMemory(0),
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, export := range []Export{
+		{ID: "first", UserID: "user", DeviceID: "device"},
+		{ID: "second", UserID: "user", DeviceID: "device"},
+		{ID: "other", UserID: "other", DeviceID: "device"},
+	} {
+		if _, err := svc.storeArtifact(ctx, export); err != nil {
+			t.Fatalf("storeArtifact() error = %v", err)
+		}
+	}
+
+	// the artifact dropped from the cache is skipped
+	if err := svc.artifacts.Delete(ctx, artifactPrefix+"first"); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := svc.Artifacts(ctx, "user")
+	if err != nil {
+		t.Fatalf("Artifacts() error = %v", err)
+	}
+	if len(artifacts) != 1 || artifacts[0].ExportID != "second" {
+		t.Errorf("Artifacts() = %+v, want second only", artifacts)
+	}
+
+	entries, err := svc.index.Get(ctx, indexPrefix+"user")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("index = %v, %v, want 2 entries", entries, err)
+	}
+}
+
+func TestNewService_MultiInstance(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   error
+	}{
+		{name: "artifacts disabled", config: Config{MultiInstance: true}},
+		{name: "local cache", config: Config{MultiInstance: true, BaseURL: "https://sms.example.com", SigningKey: "secret"}, want: ErrSharedCacheRequired},
+		{name: "no signing key", config: Config{MultiInstance: true, SharedCache: true, BaseURL: "https://sms.example.com"}, want: ErrSigningKeyRequired},
+		{name: "configured", config: Config{MultiInstance: true, SharedCache: true, BaseURL: "https://sms.example.com", SigningKey: "secret"}},
+		{name: "single instance", config: Config{BaseURL: "https://sms.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewService(ServiceParams{Config: tt.config, Cache: cache.NewMemory(0), Logger: zap.NewNop()})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("NewService() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package exports
+
+import "time"
+
+// Status is the state of the export.
+type Status string
+
+const (
+	// StatusRequested means the device was asked to export messages.
+	StatusRequested Status = "requested"
+	// StatusUploading means the device has uploaded some of the messages.
+	StatusUploading Status = "uploading"
+	// StatusCompleted means all the messages were uploaded.
+	StatusCompleted Status = "completed"
+)
+
+// Message is an incoming message exported by the device.
+type Message struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	PhoneNumber string    `json:"phoneNumber"`
+	Text        string    `json:"text"`
+	SimNumber   *uint8    `json:"simNumber,omitempty"`
+	ReceivedAt  time.Time `json:"receivedAt"`
+}
+
+// Export is a request for the device to deliver the messages history.
+type Export struct {
+	ID       string `json:"id"`
+	UserID   string `json:"userId"`
+	DeviceID string `json:"deviceId"`
+
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+
+	Status   Status    `json:"status"`
+	Messages []Message `json:"messages"`
+	// Size is the size of the uploaded messages in bytes.
+	Size int `json:"size"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
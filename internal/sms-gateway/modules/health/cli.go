@@ -1,10 +1,13 @@
 package health
 
 import (
+	"context"
 	"io"
+	"net"
 	httpclient "net/http"
 	"time"
 
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/listener"
 	"github.com/capcom6/go-infra-fx/http"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -15,7 +18,17 @@ func testHealth(shutdowner fx.Shutdowner, logger *zap.Logger, config http.Config
 		Timeout: 1 * time.Second,
 	}
 
-	res, err := client.Get("http://" + config.Listen + "/health")
+	host := config.Listen
+	if path, ok := listener.SocketPath(config.Listen); ok {
+		host = "unix"
+		client.Transport = &httpclient.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		}
+	}
+
+	res, err := client.Get("http://" + host + "/health")
 	if err != nil {
 		logger.Error("Failed to send request", zap.Error(err))
 		if err := shutdowner.Shutdown(fx.ExitCode(1)); err != nil {
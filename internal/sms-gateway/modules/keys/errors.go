@@ -0,0 +1,12 @@
+package keys
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("key not found")
+	ErrExists   = errors.New("key with such ID already exists")
+	ErrRevoked  = errors.New("key is revoked")
+	// ErrAlgorithmMismatch is returned when the key is used with another
+	// algorithm than the registered one.
+	ErrAlgorithmMismatch = errors.New("algorithm doesn't match the key")
+)
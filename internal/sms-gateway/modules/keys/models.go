@@ -0,0 +1,56 @@
+package keys
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"gorm.io/gorm"
+)
+
+// Key is the client-side encryption key published by a device of the user.
+// The material is opaque to the server: it's a public key or a key wrapped
+// by the app, so the plaintext is never exposed.
+type Key struct {
+	ID        uint64 `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	UserID    string `gorm:"not null;type:varchar(32);uniqueIndex:unq_encryption_keys_user_key,priority:1"`
+	KeyID     string `gorm:"not null;type:varchar(64);uniqueIndex:unq_encryption_keys_user_key,priority:2"`
+	DeviceID  string `gorm:"not null;type:char(21)"`
+	Algorithm string `gorm:"not null;type:varchar(32)"`
+	Material  string `gorm:"not null;type:text"`
+
+	RevokedAt *time.Time `gorm:"type:datetime(3)"`
+
+	models.TimedModel
+}
+
+func (Key) TableName() string {
+	return "encryption_keys"
+}
+
+// Revoked returns true if the key must not be used for new messages.
+func (k Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Usable returns an error if the key must not be used for new messages
+// encrypted with the algorithm. Algorithms are compared case-insensitively.
+func (k Key) Usable(algorithm string) error {
+	if k.Revoked() {
+		return ErrRevoked
+	}
+
+	if !strings.EqualFold(k.Algorithm, algorithm) {
+		return ErrAlgorithmMismatch
+	}
+
+	return nil
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Key{}); err != nil {
+		return fmt.Errorf("encryption_keys migration failed: %w", err)
+	}
+	return nil
+}
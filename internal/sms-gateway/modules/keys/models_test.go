@@ -0,0 +1,60 @@
+package keys
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKey_Usable(t *testing.T) {
+	revokedAt := time.Now()
+
+	tests := []struct {
+		name      string
+		key       Key
+		algorithm string
+		want      error
+	}{
+		{
+			name:      "active",
+			key:       Key{Algorithm: "AES-256-GCM"},
+			algorithm: "AES-256-GCM",
+		},
+		{
+			name:      "case-insensitive algorithm",
+			key:       Key{Algorithm: "AES-256-GCM"},
+			algorithm: "aes-256-gcm",
+		},
+		{
+			name:      "another algorithm",
+			key:       Key{Algorithm: "AES-256-GCM"},
+			algorithm: "chacha20-poly1305",
+			want:      ErrAlgorithmMismatch,
+		},
+		{
+			name:      "revoked",
+			key:       Key{Algorithm: "AES-256-GCM", RevokedAt: &revokedAt},
+			algorithm: "AES-256-GCM",
+			want:      ErrRevoked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.key.Usable(tt.algorithm); !errors.Is(err, tt.want) {
+				t.Errorf("Usable() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_Revoked(t *testing.T) {
+	if (Key{}).Revoked() {
+		t.Error("key without revocation time is revoked")
+	}
+
+	now := time.Now()
+	if !(Key{RevokedAt: &now}).Revoked() {
+		t.Error("key with revocation time isn't revoked")
+	}
+}
@@ -0,0 +1,70 @@
+package keys
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Insert stores the key.
+func (r *repository) Insert(key *Key) error {
+	err := r.db.Create(key).Error
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrExists
+	}
+
+	return err
+}
+
+// Select returns the keys of the user, newest first.
+func (r *repository) Select(userID string, withRevoked bool) ([]Key, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if !withRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	items := []Key{}
+	err := query.
+		Order("id DESC").
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+// Get returns the key of the user.
+func (r *repository) Get(userID, keyID string) (Key, error) {
+	key := Key{}
+	err := r.db.
+		Where("user_id = ? AND key_id = ?", userID, keyID).
+		Take(&key).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, ErrNotFound
+	}
+
+	return key, err
+}
+
+// Revoke marks the key of the user as revoked. Already revoked keys are not
+// changed.
+func (r *repository) Revoke(userID, keyID string, at time.Time) error {
+	return r.db.Model(&Key{}).
+		Where("user_id = ? AND key_id = ? AND revoked_at IS NULL", userID, keyID).
+		Update("revoked_at", at).
+		Error
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,131 @@
+package keys
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// KeyIn is the key published by the device.
+type KeyIn struct {
+	// KeyID is the identifier chosen by the app, referenced by the messages
+	KeyID string
+	// Algorithm is the encryption algorithm the key is used with
+	Algorithm string
+	// Material is the public or wrapped key
+	Material string
+}
+
+type ServiceParams struct {
+	fx.In
+
+	Keys *repository
+
+	EventsSvc *events.Service
+
+	Logger *zap.Logger
+}
+
+// Service is the registry of the client-side encryption keys, so the devices
+// of the user can rotate and agree on keys without exposing the plaintext to
+// the server.
+type Service struct {
+	keys *repository
+
+	eventsSvc *events.Service
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		keys: params.Keys,
+
+		eventsSvc: params.EventsSvc,
+
+		logger: params.Logger,
+	}
+}
+
+// Register publishes the key of the device and notifies the other devices of
+// the user.
+func (s *Service) Register(device models.Device, in KeyIn) (Key, error) {
+	key := Key{
+		UserID:    device.UserID,
+		KeyID:     in.KeyID,
+		DeviceID:  device.ID,
+		Algorithm: in.Algorithm,
+		Material:  in.Material,
+	}
+
+	if err := s.keys.Insert(&key); err != nil {
+		if errors.Is(err, ErrExists) {
+			return key, err
+		}
+		return key, fmt.Errorf("can't store key: %w", err)
+	}
+
+	s.notify(device.UserID)
+
+	return s.keys.Get(device.UserID, in.KeyID)
+}
+
+// Select returns the keys of the user, newest first.
+func (s *Service) Select(userID string, withRevoked bool) ([]Key, error) {
+	items, err := s.keys.Select(userID, withRevoked)
+	if err != nil {
+		return nil, fmt.Errorf("can't select keys: %w", err)
+	}
+
+	return items, nil
+}
+
+// Get returns the key of the user.
+func (s *Service) Get(userID, keyID string) (Key, error) {
+	return s.keys.Get(userID, keyID)
+}
+
+// Active returns the key of the user that can be used for new messages
+// encrypted with the algorithm. It returns ErrRevoked if the key is revoked
+// and ErrAlgorithmMismatch if the key is registered for another algorithm.
+func (s *Service) Active(userID, keyID, algorithm string) (Key, error) {
+	key, err := s.keys.Get(userID, keyID)
+	if err != nil {
+		return key, err
+	}
+
+	return key, key.Usable(algorithm)
+}
+
+// Revoke marks the key as not usable for new messages and notifies the
+// devices of the user. The key is kept, so messages encrypted with it can
+// still be decrypted.
+func (s *Service) Revoke(userID, keyID string) error {
+	key, err := s.keys.Get(userID, keyID)
+	if err != nil {
+		return err
+	}
+
+	if key.Revoked() {
+		return nil
+	}
+
+	if err := s.keys.Revoke(userID, keyID, time.Now()); err != nil {
+		return fmt.Errorf("can't revoke key: %w", err)
+	}
+
+	s.notify(userID)
+
+	return nil
+}
+
+func (s *Service) notify(userID string) {
+	if err := s.eventsSvc.Notify(userID, nil, events.NewEncryptionKeysUpdatedEvent()); err != nil {
+		s.logger.Warn("Can't notify devices", zap.String("user_id", userID), zap.Error(err))
+	}
+}
@@ -0,0 +1,11 @@
+package links
+
+import "time"
+
+type Config struct {
+	// BaseURL is the public URL of the gateway short links are built on, e.g.
+	// "https://sms.example.com". Empty → link tracking is disabled.
+	BaseURL string
+	// TTL is the lifetime of the short links, zero to keep them forever.
+	TTL time.Duration
+}
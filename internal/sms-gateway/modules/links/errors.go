@@ -0,0 +1,7 @@
+package links
+
+import "errors"
+
+var (
+	ErrNotFound = errors.New("link not found")
+)
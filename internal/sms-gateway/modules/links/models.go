@@ -0,0 +1,32 @@
+package links
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"gorm.io/gorm"
+)
+
+// ShortLink is a URL of an outgoing message replaced with a gateway-hosted
+// short link to count clicks.
+type ShortLink struct {
+	ID        uint64 `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	Token     string `gorm:"not null;type:char(10);uniqueIndex:unq_short_links_token"`
+	UserID    string `gorm:"not null;type:varchar(32);index:idx_short_links_user_message,priority:1"`
+	MessageID string `gorm:"not null;type:varchar(36);index:idx_short_links_user_message,priority:2"`
+	URL       string `gorm:"not null;type:varchar(2048)"`
+
+	Clicks       uint64     `gorm:"not null;default:0"`
+	FirstClickAt *time.Time `gorm:"type:datetime(3)"`
+	LastClickAt  *time.Time `gorm:"type:datetime(3)"`
+
+	models.TimedModel
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&ShortLink{}); err != nil {
+		return fmt.Errorf("short_links migration failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package links
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Click registers the click on the link created after createdAfter and
+// returns the link.
+func (r *repository) Click(token string, at, createdAfter time.Time) (ShortLink, error) {
+	link := ShortLink{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&ShortLink{}).
+			Where("token = ? AND created_at >= ?", token, createdAfter).
+			Updates(map[string]any{
+				"clicks":         gorm.Expr("clicks + 1"),
+				"first_click_at": gorm.Expr("COALESCE(first_click_at, ?)", at),
+				"last_click_at":  at,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return tx.Where("token = ?", token).Take(&link).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return link, ErrNotFound
+	}
+
+	return link, err
+}
+
+// SelectByMessage returns the links of the message of the user.
+func (r *repository) SelectByMessage(userID, messageID string) ([]ShortLink, error) {
+	links := []ShortLink{}
+	err := r.db.
+		Where("user_id = ? AND message_id = ?", userID, messageID).
+		Order("id").
+		Find(&links).
+		Error
+
+	return links, err
+}
+
+// DeleteByMessages removes the links of the messages of the user and returns
+// the number of the removed links.
+func (r *repository) DeleteByMessages(ctx context.Context, userID string, messageIDs []string) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("user_id = ? AND message_id IN ?", userID, messageIDs).
+		Delete(&ShortLink{})
+
+	return res.RowsAffected, res.Error
+}
+
+// removeExpired removes the links created before createdAfter and returns
+// the number of the removed links.
+func (r *repository) removeExpired(ctx context.Context, createdAfter time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("created_at < ?", createdAfter).
+		Delete(&ShortLink{})
+
+	return res.RowsAffected, res.Error
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,58 @@
+package links
+
+import (
+	"crypto/rand"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	tokenLength   = 10
+	tokenAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	// maxURLLength is the max length of the URL to shorten, longer URLs are
+	// kept as is.
+	maxURLLength = 2048
+)
+
+var urlRegexp = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"]+`)
+
+// rewriteURLs replaces http(s) URLs in the text with the result of replace.
+// Trailing punctuation is not considered a part of the URL.
+func rewriteURLs(text string, replace func(url string) string) string {
+	return urlRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		url := strings.TrimRight(match, ".,;:!?)]}'")
+		if len(url) > maxURLLength {
+			return match
+		}
+
+		return replace(url) + match[len(url):]
+	})
+}
+
+// isRedirectable reports whether the short link may redirect to the URL: only
+// absolute http(s) URLs are allowed.
+func isRedirectable(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// generateToken returns a random token of the short link.
+func generateToken() (string, error) {
+	token := make([]byte, tokenLength)
+	max := big.NewInt(int64(len(tokenAlphabet)))
+	for i := range token {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		token[i] = tokenAlphabet[n.Int64()]
+	}
+
+	return string(token), nil
+}
@@ -0,0 +1,83 @@
+package links
+
+import "testing"
+
+func TestRewriteURLs(t *testing.T) {
+	replace := func(url string) string {
+		return "<" + url + ">"
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "no urls",
+			text: "Hello, World!",
+			want: "Hello, World!",
+		},
+		{
+			name: "single url",
+			text: "Visit https://example.com/path?q=1 now",
+			want: "Visit <https://example.com/path?q=1> now",
+		},
+		{
+			name: "trailing punctuation",
+			text: "See http://example.com/a. Or (https://example.com/b)!",
+			want: "See <http://example.com/a>. Or (<https://example.com/b>)!",
+		},
+		{
+			name: "without scheme",
+			text: "Visit example.com",
+			want: "Visit example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteURLs(tt.text, replace); got != tt.want {
+				t.Errorf("rewriteURLs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateToken(t *testing.T) {
+	a, err := generateToken()
+	if err != nil {
+		t.Fatalf("generateToken() error = %v", err)
+	}
+	b, err := generateToken()
+	if err != nil {
+		t.Fatalf("generateToken() error = %v", err)
+	}
+
+	if len(a) != tokenLength || a == b {
+		t.Errorf("generateToken() = %q, %q", a, b)
+	}
+}
+
+func TestIsRedirectable(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://example.com/path?q=1", want: true},
+		{url: "HTTP://example.com", want: true},
+		{url: "javascript:alert(1)", want: false},
+		{url: "data:text/html,<script>", want: false},
+		{url: "ftp://example.com/file", want: false},
+		{url: "https:///path", want: false},
+		{url: "/relative/path", want: false},
+		{url: "https://exa mple.com/%zz", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := isRedirectable(tt.url); got != tt.want {
+				t.Errorf("isRedirectable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package links
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	Links *repository
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	baseURL string
+	ttl     time.Duration
+
+	links *repository
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		baseURL: strings.TrimRight(params.Config.BaseURL, "/"),
+		ttl:     params.Config.TTL,
+
+		links: params.Links,
+
+		logger: params.Logger.Named("service"),
+	}
+}
+
+// Enabled returns true if the base URL of short links is configured.
+func (s *Service) Enabled() bool {
+	return s.baseURL != ""
+}
+
+// Shorten replaces URLs in the text of the message with short links and
+// returns the resulting text with the links. The links aren't stored, they
+// must be stored with the message, so they aren't left without it.
+func (s *Service) Shorten(userID, messageID, text string) (string, []ShortLink, error) {
+	if !s.Enabled() {
+		return text, nil, nil
+	}
+
+	links := []ShortLink{}
+	var tokenErr error
+	result := rewriteURLs(text, func(url string) string {
+		if !isRedirectable(url) {
+			return url
+		}
+
+		token, err := generateToken()
+		if err != nil {
+			tokenErr = err
+			return url
+		}
+
+		links = append(links, ShortLink{
+			Token:     token,
+			UserID:    userID,
+			MessageID: messageID,
+			URL:       url,
+		})
+
+		return s.shortURL(token)
+	})
+	if tokenErr != nil {
+		return text, nil, fmt.Errorf("can't generate token: %w", tokenErr)
+	}
+
+	return result, links, nil
+}
+
+// Resolve registers the click on the short link and returns the original URL.
+// The expired links and the ones to URLs which aren't redirected to are not
+// found.
+func (s *Service) Resolve(token string) (string, error) {
+	now := time.Now()
+
+	link, err := s.links.Click(token, now, s.createdAfter(now))
+	if err != nil {
+		return "", err
+	}
+
+	if !isRedirectable(link.URL) {
+		return "", ErrNotFound
+	}
+
+	return link.URL, nil
+}
+
+// SelectByMessage returns short links of the message with click statistics.
+func (s *Service) SelectByMessage(userID, messageID string) ([]ShortLink, error) {
+	links, err := s.links.SelectByMessage(userID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("can't select links: %w", err)
+	}
+
+	return links, nil
+}
+
+// DeleteByMessages removes the links of the messages of the user, so the
+// original URLs aren't kept after the messages are erased. It returns the
+// number of the removed links.
+func (s *Service) DeleteByMessages(ctx context.Context, userID string, messageIDs []string) (int64, error) {
+	if len(messageIDs) == 0 {
+		return 0, nil
+	}
+
+	n, err := s.links.DeleteByMessages(ctx, userID, messageIDs)
+	if err != nil {
+		return n, fmt.Errorf("can't delete links: %w", err)
+	}
+
+	return n, nil
+}
+
+// Clean removes the expired links.
+func (s *Service) Clean(ctx context.Context) error {
+	if s.ttl == 0 {
+		return nil
+	}
+
+	n, err := s.links.removeExpired(ctx, s.createdAfter(time.Now()))
+
+	s.logger.Info("Cleaned expired links", zap.Int64("count", n))
+	return err
+}
+
+// createdAfter returns the creation time of the oldest link that isn't
+// expired, zero if the links don't expire.
+func (s *Service) createdAfter(now time.Time) time.Time {
+	if s.ttl == 0 {
+		return time.Time{}
+	}
+
+	return now.Add(-s.ttl)
+}
+
+// ShortURL returns the short URL of the link.
+func (s *Service) ShortURL(link ShortLink) string {
+	return s.shortURL(link.Token)
+}
+
+func (s *Service) shortURL(token string) string {
+	return s.baseURL + "/r/" + token
+}
@@ -0,0 +1,11 @@
+package listener
+
+import "os"
+
+type Config struct {
+	// Listen is the listen address of the HTTP server, "unix://<path>" for a
+	// unix domain socket.
+	Listen string
+	// SocketMode is the permissions of the unix domain socket.
+	SocketMode os.FileMode
+}
@@ -0,0 +1,114 @@
+package listener
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+const unixScheme = "unix://"
+
+// SocketPath returns the path of the unix domain socket if the listen
+// address has the "unix://" scheme.
+func SocketPath(listen string) (string, bool) {
+	path, ok := strings.CutPrefix(listen, unixScheme)
+	if !ok || path == "" {
+		return "", false
+	}
+
+	return path, true
+}
+
+// peerIP is the remote IP of the unix socket peers reported by the server.
+const peerIP = "0.0.0.0"
+
+// TrustedProxies returns the trusted proxies with the unix socket peers added
+// if the listen address is a unix domain socket. The socket is reachable by
+// the local reverse proxy only, so its X-Forwarded-For header is honored.
+func TrustedProxies(listen string, proxies []string) []string {
+	if _, ok := SocketPath(listen); !ok || len(proxies) == 0 {
+		// no proxies means that the header is honored for all clients
+		return proxies
+	}
+
+	return append(slices.Clone(proxies), peerIP)
+}
+
+// Listener serves the HTTP application on a unix domain socket. It's
+// registered as a root handler to get the application.
+type Listener struct {
+	config Config
+	app    *fiber.App
+
+	logger *zap.Logger
+}
+
+func New(config Config, logger *zap.Logger) *Listener {
+	return &Listener{
+		config: config,
+		logger: logger,
+	}
+}
+
+// Register implements the root handler.
+func (l *Listener) Register(app *fiber.App) {
+	l.app = app
+}
+
+// Enabled reports whether the listen address is a unix domain socket.
+func (l *Listener) Enabled() bool {
+	_, ok := SocketPath(l.config.Listen)
+	return ok
+}
+
+// Start listens on the unix domain socket and serves the application until
+// it's shut down. A stale socket left by the previous run is removed.
+func (l *Listener) Start() error {
+	path, ok := SocketPath(l.config.Listen)
+	if !ok {
+		return fmt.Errorf("not a unix socket address: %s", l.config.Listen)
+	}
+	if l.app == nil {
+		return errors.New("application is not registered")
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("can't remove stale socket: %w", err)
+		}
+	}
+
+	// the socket is created aside and moved in place with the permissions
+	// set, so it's never accessible with the default ones
+	tmp := path + ".tmp"
+	_ = os.Remove(tmp)
+
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return fmt.Errorf("can't listen on %s: %w", path, err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+
+	if err := os.Chmod(tmp, l.config.SocketMode); err != nil {
+		_ = ln.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("can't set socket permissions: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = ln.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("can't move socket in place: %w", err)
+	}
+	defer os.Remove(path)
+
+	l.logger.Info("Listening on unix socket", zap.String("path", path), zap.Stringer("mode", l.config.SocketMode))
+
+	return l.app.Listener(ln)
+}
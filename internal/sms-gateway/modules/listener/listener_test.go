@@ -0,0 +1,107 @@
+package listener_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/listener"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func TestSocketPath(t *testing.T) {
+	tests := []struct {
+		listen string
+		want   string
+		wantOK bool
+	}{
+		{"unix:///var/run/asg.sock", "/var/run/asg.sock", true},
+		{"unix://asg.sock", "asg.sock", true},
+		{"unix://", "", false},
+		{":3000", "", false},
+		{"127.0.0.1:3000", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listen, func(t *testing.T) {
+			got, ok := listener.SocketPath(tt.listen)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("SocketPath(%q) = %q, %v, want %q, %v", tt.listen, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		listen  string
+		proxies []string
+		want    []string
+	}{
+		{"tcp", ":3000", []string{"10.0.0.1"}, []string{"10.0.0.1"}},
+		{"unix", "unix:///var/run/asg.sock", []string{"10.0.0.1"}, []string{"10.0.0.1", "0.0.0.0"}},
+		{"unix without proxies", "unix:///var/run/asg.sock", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listener.TrustedProxies(tt.listen, tt.proxies); !slices.Equal(got, tt.want) {
+				t.Errorf("TrustedProxies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListener_Start(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "asg.sock")
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	l := listener.New(listener.Config{Listen: "unix://" + path, SocketMode: 0o600}, zap.NewNop())
+	l.Register(app)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Start()
+	}()
+
+	var info os.FileInfo
+	for range 100 {
+		var err error
+		if info, err = os.Stat(path); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if info == nil {
+		t.Fatal("socket is not created")
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("socket mode = %o, want %o", mode, 0o600)
+	}
+
+	// the server may not be running yet right after the socket is created
+	var err error
+	for range 100 {
+		if err = app.Shutdown(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket must be removed on shutdown, stat error = %v", err)
+	}
+}
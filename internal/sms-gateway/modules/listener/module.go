@@ -0,0 +1,22 @@
+package listener
+
+import (
+	"github.com/capcom6/go-infra-fx/http"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"listener",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("listener")
+	}),
+	fx.Provide(
+		New,
+	),
+	fx.Provide(
+		http.AsRootHandler(func(l *Listener) *Listener {
+			return l
+		}),
+	),
+)
@@ -0,0 +1,53 @@
+package messages
+
+import (
+	"time"
+)
+
+// maxPriorityBurst limits the burst budget, so the fair queue can't be
+// bypassed by most of the messages of the period.
+const maxPriorityBurst = 100
+
+// priorityBurstPeriods maps settings values to budget periods. The values
+// match the ones of the `limit_period` setting.
+var priorityBurstPeriods = map[string]time.Duration{
+	"PerMinute": time.Minute,
+	"PerHour":   time.Hour,
+	"PerDay":    24 * time.Hour,
+}
+
+// PriorityBurst is the budget of messages with priority at or above
+// fairQueueBypassPriority per period. Such messages bypass the sending limits
+// of the device, so the budget protects the account from abuse while keeping
+// authentication codes ahead of bulk campaigns. Messages beyond the budget are
+// sent with regular priority.
+type PriorityBurst struct {
+	Value  int
+	Period time.Duration
+}
+
+// Enabled returns true if the budget is limited.
+func (b PriorityBurst) Enabled() bool {
+	return b.Value > 0 && b.Period > 0
+}
+
+// priorityBurstFromSettings reads the budget from the "messages" settings
+// group. A missing or invalid budget means unlimited bypass.
+func priorityBurstFromSettings(settings map[string]any) PriorityBurst {
+	group, ok := settings["messages"].(map[string]any)
+	if !ok {
+		return PriorityBurst{}
+	}
+
+	value, ok := group["priority_burst_value"].(float64)
+	if !ok || value < 1 {
+		return PriorityBurst{}
+	}
+
+	period, _ := group["priority_burst_period"].(string)
+
+	return PriorityBurst{
+		Value:  min(int(value), maxPriorityBurst),
+		Period: priorityBurstPeriods[period],
+	}
+}
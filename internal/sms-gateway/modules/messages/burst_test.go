@@ -0,0 +1,101 @@
+package messages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func TestPriorityBurstFromSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		want     PriorityBurst
+	}{
+		{
+			name:     "no settings",
+			settings: nil,
+			want:     PriorityBurst{},
+		},
+		{
+			name: "budget",
+			settings: map[string]any{
+				"messages": map[string]any{"priority_burst_value": 10.0, "priority_burst_period": "PerHour"},
+			},
+			want: PriorityBurst{Value: 10, Period: time.Hour},
+		},
+		{
+			name: "capped",
+			settings: map[string]any{
+				"messages": map[string]any{"priority_burst_value": 1000.0, "priority_burst_period": "PerDay"},
+			},
+			want: PriorityBurst{Value: maxPriorityBurst, Period: 24 * time.Hour},
+		},
+		{
+			name: "disabled period",
+			settings: map[string]any{
+				"messages": map[string]any{"priority_burst_value": 10.0, "priority_burst_period": "Disabled"},
+			},
+			want: PriorityBurst{Value: 10},
+		},
+		{
+			name: "invalid value",
+			settings: map[string]any{
+				"messages": map[string]any{"priority_burst_value": "10", "priority_burst_period": "PerHour"},
+			},
+			want: PriorityBurst{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := priorityBurstFromSettings(tt.settings)
+			if got != tt.want {
+				t.Errorf("priorityBurstFromSettings() = %+v, want %+v", got, tt.want)
+			}
+			if got.Enabled() != (tt.want.Value > 0 && tt.want.Period > 0) {
+				t.Errorf("Enabled() = %v", got.Enabled())
+			}
+		})
+	}
+}
+
+func TestService_acquirePriorityBurst(t *testing.T) {
+	s := &Service{
+		cache:  cache.NewMemory(0),
+		logger: zap.NewNop(),
+	}
+	burst := PriorityBurst{Value: 2, Period: time.Hour}
+
+	first, ok := s.acquirePriorityBurst(burst, "user")
+	if !ok || first.key == "" {
+		t.Fatalf("acquirePriorityBurst() = %+v, %v, want a slot", first, ok)
+	}
+	if _, ok := s.acquirePriorityBurst(burst, "user"); !ok {
+		t.Fatal("acquirePriorityBurst() of the second slot = false, want true")
+	}
+	if slot, ok := s.acquirePriorityBurst(burst, "user"); ok || slot.key != "" {
+		t.Fatalf("acquirePriorityBurst() over the budget = %+v, %v, want no slot", slot, ok)
+	}
+
+	// the budget of other users isn't shared
+	if _, ok := s.acquirePriorityBurst(burst, "other"); !ok {
+		t.Error("acquirePriorityBurst() of another user = false, want true")
+	}
+
+	// the released slot is taken again
+	s.release("", first)
+	if _, ok := s.acquirePriorityBurst(burst, "user"); !ok {
+		t.Error("acquirePriorityBurst() after release = false, want true")
+	}
+	if _, ok := s.acquirePriorityBurst(burst, "user"); ok {
+		t.Error("acquirePriorityBurst() over the budget after release = true, want false")
+	}
+
+	// unlimited budget doesn't take slots
+	if slot, ok := s.acquirePriorityBurst(PriorityBurst{}, "user"); !ok || slot.key != "" {
+		t.Errorf("acquirePriorityBurst() unlimited = %+v, %v, want empty slot", slot, ok)
+	}
+}
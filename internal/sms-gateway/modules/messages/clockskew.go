@@ -0,0 +1,39 @@
+package messages
+
+import "time"
+
+// ClockSkewPolicy defines how state timestamps out of the valid range are handled.
+type ClockSkewPolicy string
+
+const (
+	// ClockSkewPolicyClamp moves out of range timestamps to the nearest valid time.
+	ClockSkewPolicyClamp ClockSkewPolicy = "clamp"
+	// ClockSkewPolicyReject rejects state updates with out of range timestamps.
+	ClockSkewPolicyReject ClockSkewPolicy = "reject"
+)
+
+type ClockSkewConfig struct {
+	// Tolerance is the allowed deviation of device timestamps, 0 to accept
+	// any timestamp.
+	Tolerance time.Duration
+	Policy    ClockSkewPolicy
+}
+
+// normalize checks the device reported time of the state. The time is valid
+// within [createdAt - Tolerance, now + Tolerance]. It returns the time to
+// store and false if the device time is out of range.
+func (c ClockSkewConfig) normalize(deviceTime, createdAt, now time.Time) (time.Time, bool) {
+	if c.Tolerance <= 0 {
+		return deviceTime, true
+	}
+
+	if deviceTime.After(now.Add(c.Tolerance)) {
+		return now, false
+	}
+
+	if !createdAt.IsZero() && deviceTime.Before(createdAt.Add(-c.Tolerance)) {
+		return createdAt, false
+	}
+
+	return deviceTime, true
+}
@@ -0,0 +1,35 @@
+package messages
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockSkewConfig_normalize(t *testing.T) {
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	createdAt := now.Add(-time.Hour)
+	config := ClockSkewConfig{Tolerance: 5 * time.Minute}
+
+	tests := []struct {
+		name       string
+		config     ClockSkewConfig
+		deviceTime time.Time
+		want       time.Time
+		wantOk     bool
+	}{
+		{name: "In range", config: config, deviceTime: now.Add(-time.Minute), want: now.Add(-time.Minute), wantOk: true},
+		{name: "Slightly ahead", config: config, deviceTime: now.Add(time.Minute), want: now.Add(time.Minute), wantOk: true},
+		{name: "Far in future", config: config, deviceTime: now.Add(time.Hour), want: now, wantOk: false},
+		{name: "Before creation", config: config, deviceTime: createdAt.Add(-time.Hour), want: createdAt, wantOk: false},
+		{name: "Disabled", config: ClockSkewConfig{}, deviceTime: now.Add(24 * time.Hour), want: now.Add(24 * time.Hour), wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.config.normalize(tt.deviceTime, createdAt, now)
+			if !got.Equal(tt.want) || ok != tt.wantOk {
+				t.Errorf("normalize() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
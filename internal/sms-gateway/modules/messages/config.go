@@ -4,4 +4,26 @@ import "time"
 
 type Config struct {
 	ProcessedLifetime time.Duration
+	// ProcessedTimeout is the time after which a message in the Processed
+	// state without further updates is considered stuck.
+	ProcessedTimeout time.Duration
+
+	ContentPolicy ContentPolicyConfig
+	Polling       PollingConfig
+	ClockSkew     ClockSkewConfig
+	Dedup         DedupConfig
+	TTL           TTLConfig
+	Health        HealthConfig
+	SendWindows   SendWindowsConfig
+}
+
+// PollingConfig controls the polling hints returned to devices.
+type PollingConfig struct {
+	// ActiveInterval is used when the device has just received messages.
+	ActiveInterval time.Duration
+	// IdleInterval is used when the queue is empty and push is unavailable.
+	IdleInterval time.Duration
+	// IdlePushInterval is used when the queue is empty and the device will be
+	// woken up by a push notification.
+	IdlePushInterval time.Duration
 }
@@ -0,0 +1,177 @@
+package messages
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ContentPolicyMode defines how content policy violations are handled.
+type ContentPolicyMode string
+
+const (
+	// ContentPolicyModeOff disables content checks.
+	ContentPolicyModeOff ContentPolicyMode = "off"
+	// ContentPolicyModeWarn reports violations but accepts the message.
+	ContentPolicyModeWarn ContentPolicyMode = "warn"
+	// ContentPolicyModeBlock rejects messages with violations.
+	ContentPolicyModeBlock ContentPolicyMode = "block"
+)
+
+const (
+	ContentRuleURLShortener = "url_shortener"
+	ContentRuleBannedWord   = "banned_word"
+	ContentRuleAllCaps      = "all_caps"
+)
+
+// minUppercaseLetters is the minimal number of letters for the all-caps rule
+// to be applied, so short texts like "OK" are not reported.
+const minUppercaseLetters = 10
+
+type ContentPolicyConfig struct {
+	Mode ContentPolicyMode
+
+	URLShorteners     []string
+	BannedWords       []string
+	MaxUppercaseRatio float64
+}
+
+// ContentWarning describes a single content policy violation.
+type ContentWarning struct {
+	Rule    string
+	Message string
+}
+
+type contentPolicy struct {
+	config ContentPolicyConfig
+
+	urlRegexp *regexp.Regexp
+}
+
+func newContentPolicy(config ContentPolicyConfig) *contentPolicy {
+	if config.Mode == "" {
+		config.Mode = ContentPolicyModeOff
+	}
+
+	return &contentPolicy{
+		config:    config,
+		urlRegexp: regexp.MustCompile(`(?i)(?:https?://)?(?:www\.)?([a-z0-9-]+(?:\.[a-z0-9-]+)+)(?:/\S*)?`),
+	}
+}
+
+// Enabled returns true if the policy is not turned off.
+func (p *contentPolicy) Enabled() bool {
+	return p.config.Mode != ContentPolicyModeOff
+}
+
+// Blocking returns true if violations must reject the message.
+func (p *contentPolicy) Blocking() bool {
+	return p.config.Mode == ContentPolicyModeBlock
+}
+
+// Check returns the list of violations found in the text.
+func (p *contentPolicy) Check(text string) []ContentWarning {
+	if !p.Enabled() || text == "" {
+		return nil
+	}
+
+	warnings := []ContentWarning{}
+	warnings = append(warnings, p.checkURLShorteners(text)...)
+	warnings = append(warnings, p.checkBannedWords(text)...)
+	warnings = append(warnings, p.checkUppercase(text)...)
+
+	return warnings
+}
+
+func (p *contentPolicy) checkURLShorteners(text string) []ContentWarning {
+	if len(p.config.URLShorteners) == 0 {
+		return nil
+	}
+
+	warnings := []ContentWarning{}
+	for _, match := range p.urlRegexp.FindAllStringSubmatch(text, -1) {
+		host := strings.ToLower(match[1])
+		for _, shortener := range p.config.URLShorteners {
+			shortener = strings.ToLower(shortener)
+			if host == shortener || strings.HasSuffix(host, "."+shortener) {
+				warnings = append(warnings, ContentWarning{
+					Rule:    ContentRuleURLShortener,
+					Message: fmt.Sprintf("URL shortener `%s` is often filtered by carriers", shortener),
+				})
+				break
+			}
+		}
+	}
+
+	return warnings
+}
+
+func (p *contentPolicy) checkBannedWords(text string) []ContentWarning {
+	if len(p.config.BannedWords) == 0 {
+		return nil
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	index := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		index[w] = struct{}{}
+	}
+
+	warnings := []ContentWarning{}
+	for _, banned := range p.config.BannedWords {
+		banned = strings.ToLower(strings.TrimSpace(banned))
+		if banned == "" {
+			continue
+		}
+
+		found := false
+		if strings.ContainsFunc(banned, unicode.IsSpace) {
+			found = strings.Contains(strings.ToLower(text), banned)
+		} else {
+			_, found = index[banned]
+		}
+
+		if found {
+			warnings = append(warnings, ContentWarning{
+				Rule:    ContentRuleBannedWord,
+				Message: fmt.Sprintf("text contains banned word `%s`", banned),
+			})
+		}
+	}
+
+	return warnings
+}
+
+func (p *contentPolicy) checkUppercase(text string) []ContentWarning {
+	if p.config.MaxUppercaseRatio <= 0 {
+		return nil
+	}
+
+	letters, upper := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+
+	if letters < minUppercaseLetters {
+		return nil
+	}
+
+	ratio := float64(upper) / float64(letters)
+	if ratio <= p.config.MaxUppercaseRatio {
+		return nil
+	}
+
+	return []ContentWarning{{
+		Rule:    ContentRuleAllCaps,
+		Message: fmt.Sprintf("uppercase letters ratio %.2f exceeds %.2f", ratio, p.config.MaxUppercaseRatio),
+	}}
+}
@@ -0,0 +1,96 @@
+package messages
+
+import (
+	"testing"
+)
+
+func TestContentPolicy_Check(t *testing.T) {
+	config := ContentPolicyConfig{
+		Mode:              ContentPolicyModeWarn,
+		URLShorteners:     []string{"bit.ly"},
+		BannedWords:       []string{"casino", "free money"},
+		MaxUppercaseRatio: 0.7,
+	}
+
+	tests := []struct {
+		name   string
+		config ContentPolicyConfig
+		text   string
+		want   []string
+	}{
+		{
+			name:   "Clean text",
+			config: config,
+			text:   "Your code is 123456",
+			want:   []string{},
+		},
+		{
+			name:   "URL shortener",
+			config: config,
+			text:   "Check https://bit.ly/abc now",
+			want:   []string{ContentRuleURLShortener},
+		},
+		{
+			name:   "URL shortener without scheme",
+			config: config,
+			text:   "Visit bit.ly/abc",
+			want:   []string{ContentRuleURLShortener},
+		},
+		{
+			name:   "Similar domain",
+			config: config,
+			text:   "Visit https://notbit.ly/abc",
+			want:   []string{},
+		},
+		{
+			name:   "Banned word",
+			config: config,
+			text:   "Best Casino in town",
+			want:   []string{ContentRuleBannedWord},
+		},
+		{
+			name:   "Banned word as part of another word",
+			config: config,
+			text:   "casinos are closed",
+			want:   []string{},
+		},
+		{
+			name:   "Banned phrase",
+			config: config,
+			text:   "Get free money today",
+			want:   []string{ContentRuleBannedWord},
+		},
+		{
+			name:   "All caps",
+			config: config,
+			text:   "THIS IS A VERY IMPORTANT MESSAGE",
+			want:   []string{ContentRuleAllCaps},
+		},
+		{
+			name:   "Short all caps",
+			config: config,
+			text:   "OK",
+			want:   []string{},
+		},
+		{
+			name:   "Disabled",
+			config: ContentPolicyConfig{Mode: ContentPolicyModeOff, BannedWords: []string{"casino"}},
+			text:   "casino",
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newContentPolicy(tt.config).Check(tt.text)
+			if len(got) != len(tt.want) {
+				t.Fatalf("contentPolicy.Check() = %v, want rules %v", got, tt.want)
+			}
+			for i, w := range got {
+				if w.Rule != tt.want[i] {
+					t.Errorf("contentPolicy.Check()[%d].Rule = %s, want %s", i, w.Rule, tt.want[i])
+				}
+			}
+		})
+	}
+}
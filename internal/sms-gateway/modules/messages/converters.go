@@ -42,6 +42,12 @@ func messageToDomain(input Message) (MessageOut, error) {
 		},
 		CreatedAt: input.CreatedAt,
 	}
+	if input.EncryptionAlgorithm != nil && input.EncryptionKeyID != nil {
+		out.Encryption = &EncryptionInfo{
+			Algorithm: *input.EncryptionAlgorithm,
+			KeyID:     *input.EncryptionKeyID,
+		}
+	}
 	if len(input.States) > 0 || input.DeviceID != "" {
         state := modelToMessageState(input)
         out.State = &state
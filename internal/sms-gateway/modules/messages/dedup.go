@@ -0,0 +1,91 @@
+package messages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	// dedupAttempts is the number of lookups of the original message, which
+	// may be not stored yet by the concurrent request.
+	dedupAttempts = 5
+	// dedupRetryDelay is the delay between the lookups of the original
+	// message.
+	dedupRetryDelay = 100 * time.Millisecond
+)
+
+// DedupStrategy defines which messages are considered duplicates.
+type DedupStrategy string
+
+const (
+	// DedupStrategyOff disables duplicate protection.
+	DedupStrategyOff DedupStrategy = "off"
+	// DedupStrategyContent treats messages with the same content as duplicates.
+	DedupStrategyContent DedupStrategy = "content"
+	// DedupStrategyContentRecipient treats messages with the same content and
+	// recipients as duplicates.
+	DedupStrategyContentRecipient DedupStrategy = "content_recipient"
+)
+
+// DedupConfig controls the duplicate protection. Users can override it in the
+// "dedup" settings group.
+type DedupConfig struct {
+	// Window is the period after enqueueing during which an equal message is
+	// considered a duplicate.
+	Window   time.Duration
+	Strategy DedupStrategy
+}
+
+// Enabled returns true if duplicates should be detected.
+func (c DedupConfig) Enabled() bool {
+	return c.Window > 0 && (c.Strategy == DedupStrategyContent || c.Strategy == DedupStrategyContentRecipient)
+}
+
+// withUserSettings returns the config overridden by the user settings.
+// Invalid values are ignored.
+func (c DedupConfig) withUserSettings(settings map[string]any) DedupConfig {
+	group, ok := settings["dedup"].(map[string]any)
+	if !ok {
+		return c
+	}
+
+	if window, ok := group["window_seconds"].(float64); ok && window >= 0 {
+		c.Window = time.Duration(window) * time.Second
+	}
+
+	if strategy, ok := group["strategy"].(string); ok {
+		switch DedupStrategy(strategy) {
+		case DedupStrategyOff, DedupStrategyContent, DedupStrategyContentRecipient:
+			c.Strategy = DedupStrategy(strategy)
+		}
+	}
+
+	return c
+}
+
+// dedupKey returns the key identifying duplicates of the message of the user.
+func dedupKey(strategy DedupStrategy, userID string, message Message, phoneNumbers []string) string {
+	h := sha256.New()
+
+	h.Write([]byte(userID))
+	h.Write([]byte{0})
+	h.Write([]byte(message.Type))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatBool(message.IsEncrypted)))
+	h.Write([]byte{0})
+	h.Write([]byte(message.Content))
+
+	if strategy == DedupStrategyContentRecipient {
+		recipients := slices.Clone(phoneNumbers)
+		slices.Sort(recipients)
+		for _, phone := range recipients {
+			h.Write([]byte{0})
+			h.Write([]byte(phone))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
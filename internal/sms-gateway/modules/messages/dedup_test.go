@@ -0,0 +1,92 @@
+package messages
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupConfig_withUserSettings(t *testing.T) {
+	def := DedupConfig{Window: time.Minute, Strategy: DedupStrategyContentRecipient}
+
+	tests := []struct {
+		name     string
+		settings map[string]any
+		want     DedupConfig
+	}{
+		{
+			name:     "no settings",
+			settings: nil,
+			want:     def,
+		},
+		{
+			name: "override",
+			settings: map[string]any{
+				"dedup": map[string]any{"window_seconds": float64(300), "strategy": "content"},
+			},
+			want: DedupConfig{Window: 5 * time.Minute, Strategy: DedupStrategyContent},
+		},
+		{
+			name: "disabled",
+			settings: map[string]any{
+				"dedup": map[string]any{"strategy": "off"},
+			},
+			want: DedupConfig{Window: time.Minute, Strategy: DedupStrategyOff},
+		},
+		{
+			name: "invalid values",
+			settings: map[string]any{
+				"dedup": map[string]any{"window_seconds": "300", "strategy": "unknown"},
+			},
+			want: def,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := def.withUserSettings(tt.settings)
+			if got != tt.want {
+				t.Errorf("withUserSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupConfig_Enabled(t *testing.T) {
+	if (DedupConfig{Window: time.Minute, Strategy: DedupStrategyOff}).Enabled() {
+		t.Error("expected dedup to be disabled by strategy")
+	}
+	if (DedupConfig{Window: 0, Strategy: DedupStrategyContent}).Enabled() {
+		t.Error("expected dedup to be disabled by window")
+	}
+	if !(DedupConfig{Window: time.Minute, Strategy: DedupStrategyContent}).Enabled() {
+		t.Error("expected dedup to be enabled")
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	message := Message{Type: MessageTypeText, Content: `{"text":"Hello"}`}
+
+	a := dedupKey(DedupStrategyContentRecipient, "user", message, []string{"+79990001234", "+79990001235"})
+	b := dedupKey(DedupStrategyContentRecipient, "user", message, []string{"+79990001235", "+79990001234"})
+	if a != b {
+		t.Error("expected the key to not depend on the recipients order")
+	}
+
+	if a == dedupKey(DedupStrategyContentRecipient, "user", message, []string{"+79990001234"}) {
+		t.Error("expected the key to depend on the recipients")
+	}
+
+	c := dedupKey(DedupStrategyContent, "user", message, []string{"+79990001234"})
+	d := dedupKey(DedupStrategyContent, "user", message, []string{"+79990001235"})
+	if c != d {
+		t.Error("expected the key to not depend on the recipients")
+	}
+
+	if c == dedupKey(DedupStrategyContent, "other", message, nil) {
+		t.Error("expected the key to depend on the user")
+	}
+
+	if c == dedupKey(DedupStrategyContent, "user", Message{Type: MessageTypeText, Content: `{"text":"Bye"}`}, nil) {
+		t.Error("expected the key to depend on the content")
+	}
+}
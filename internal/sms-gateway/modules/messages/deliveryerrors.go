@@ -0,0 +1,140 @@
+package messages
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DeliveryErrorCode is a stable, device-independent classification of the
+// recipient error reported by the mobile app.
+type DeliveryErrorCode string
+
+const (
+	DeliveryErrorGenericFailure  DeliveryErrorCode = "generic_failure"
+	DeliveryErrorRadioOff        DeliveryErrorCode = "radio_off"
+	DeliveryErrorNoService       DeliveryErrorCode = "no_service"
+	DeliveryErrorNullPDU         DeliveryErrorCode = "null_pdu"
+	DeliveryErrorLimitExceeded   DeliveryErrorCode = "limit_exceeded"
+	DeliveryErrorNotAllowed      DeliveryErrorCode = "not_allowed"
+	DeliveryErrorInvalidAddress  DeliveryErrorCode = "invalid_address"
+	DeliveryErrorInvalidFormat   DeliveryErrorCode = "invalid_format"
+	DeliveryErrorNetworkRejected DeliveryErrorCode = "network_rejected"
+	DeliveryErrorNetworkError    DeliveryErrorCode = "network_error"
+	DeliveryErrorModemError      DeliveryErrorCode = "modem_error"
+	DeliveryErrorSystemError     DeliveryErrorCode = "system_error"
+	DeliveryErrorCancelled       DeliveryErrorCode = "cancelled"
+	DeliveryErrorTTLExpired      DeliveryErrorCode = "ttl_expired"
+	DeliveryErrorTimeout         DeliveryErrorCode = "processing_timeout"
+	DeliveryErrorUnknown         DeliveryErrorCode = "unknown"
+)
+
+// androidResultCodes maps android.telephony.SmsManager result codes to stable error codes.
+var androidResultCodes = map[int]DeliveryErrorCode{
+	1:  DeliveryErrorGenericFailure,  // RESULT_ERROR_GENERIC_FAILURE
+	2:  DeliveryErrorRadioOff,        // RESULT_ERROR_RADIO_OFF
+	3:  DeliveryErrorNullPDU,         // RESULT_ERROR_NULL_PDU
+	4:  DeliveryErrorNoService,       // RESULT_ERROR_NO_SERVICE
+	5:  DeliveryErrorLimitExceeded,   // RESULT_ERROR_LIMIT_EXCEEDED
+	6:  DeliveryErrorNotAllowed,      // RESULT_ERROR_FDN_CHECK_FAILURE
+	7:  DeliveryErrorNotAllowed,      // RESULT_ERROR_SHORT_CODE_NOT_ALLOWED
+	8:  DeliveryErrorNotAllowed,      // RESULT_ERROR_SHORT_CODE_NEVER_ALLOWED
+	9:  DeliveryErrorRadioOff,        // RESULT_RADIO_NOT_AVAILABLE
+	10: DeliveryErrorNetworkRejected, // RESULT_NETWORK_REJECT
+	11: DeliveryErrorInvalidFormat,   // RESULT_INVALID_ARGUMENTS
+	12: DeliveryErrorSystemError,     // RESULT_INVALID_STATE
+	13: DeliveryErrorSystemError,     // RESULT_NO_MEMORY
+	14: DeliveryErrorInvalidFormat,   // RESULT_INVALID_SMS_FORMAT
+	15: DeliveryErrorSystemError,     // RESULT_SYSTEM_ERROR
+	16: DeliveryErrorModemError,      // RESULT_MODEM_ERROR
+	17: DeliveryErrorNetworkError,    // RESULT_NETWORK_ERROR
+	18: DeliveryErrorInvalidFormat,   // RESULT_ENCODING_ERROR
+	19: DeliveryErrorInvalidAddress,  // RESULT_INVALID_SMSC_ADDRESS
+	20: DeliveryErrorNotAllowed,      // RESULT_OPERATION_NOT_ALLOWED
+	21: DeliveryErrorSystemError,     // RESULT_INTERNAL_ERROR
+	22: DeliveryErrorSystemError,     // RESULT_NO_RESOURCES
+	23: DeliveryErrorCancelled,       // RESULT_CANCELLED
+	24: DeliveryErrorNotAllowed,      // RESULT_REQUEST_NOT_SUPPORTED
+	29: DeliveryErrorNotAllowed,      // RESULT_SMS_BLOCKED_DURING_EMERGENCY
+	30: DeliveryErrorNetworkError,    // RESULT_SMS_SEND_RETRY_FAILED
+}
+
+// deliveryErrorKeywords maps substrings of textual error reports to stable
+// error codes. The order matters: more specific keywords go first.
+var deliveryErrorKeywords = []struct {
+	keyword string
+	code    DeliveryErrorCode
+}{
+	{"ttl expired", DeliveryErrorTTLExpired},
+	{"processing timeout", DeliveryErrorTimeout},
+	{"generic_failure", DeliveryErrorGenericFailure},
+	{"generic failure", DeliveryErrorGenericFailure},
+	{"radio_off", DeliveryErrorRadioOff},
+	{"radio off", DeliveryErrorRadioOff},
+	{"radio_not_available", DeliveryErrorRadioOff},
+	{"airplane", DeliveryErrorRadioOff},
+	{"no_service", DeliveryErrorNoService},
+	{"no service", DeliveryErrorNoService},
+	{"null_pdu", DeliveryErrorNullPDU},
+	{"null pdu", DeliveryErrorNullPDU},
+	{"limit_exceeded", DeliveryErrorLimitExceeded},
+	{"limit exceeded", DeliveryErrorLimitExceeded},
+	{"fdn_check_failure", DeliveryErrorNotAllowed},
+	{"short_code", DeliveryErrorNotAllowed},
+	{"not_allowed", DeliveryErrorNotAllowed},
+	{"not allowed", DeliveryErrorNotAllowed},
+	{"invalid_smsc_address", DeliveryErrorInvalidAddress},
+	{"invalid address", DeliveryErrorInvalidAddress},
+	{"invalid phone", DeliveryErrorInvalidAddress},
+	{"network_reject", DeliveryErrorNetworkRejected},
+	{"network reject", DeliveryErrorNetworkRejected},
+	{"network_error", DeliveryErrorNetworkError},
+	{"network error", DeliveryErrorNetworkError},
+	{"modem_error", DeliveryErrorModemError},
+	{"modem error", DeliveryErrorModemError},
+	{"encoding_error", DeliveryErrorInvalidFormat},
+	{"invalid_sms_format", DeliveryErrorInvalidFormat},
+	{"invalid_arguments", DeliveryErrorInvalidFormat},
+	{"cancelled", DeliveryErrorCancelled},
+	{"canceled", DeliveryErrorCancelled},
+}
+
+var deliveryErrorNumberRegexp = regexp.MustCompile(`(?i)(?:code|result|error)\D{0,3}(\d{1,3})\b`)
+
+// NormalizeDeliveryError maps the raw recipient error reported by the device
+// to a stable error code. It returns nil if there is no error.
+func NormalizeDeliveryError(raw *string) *DeliveryErrorCode {
+	if raw == nil {
+		return nil
+	}
+
+	text := strings.ToLower(strings.TrimSpace(*raw))
+	if text == "" {
+		return nil
+	}
+
+	code := DeliveryErrorUnknown
+
+	if n, err := strconv.Atoi(text); err == nil {
+		if c, ok := androidResultCodes[n]; ok {
+			code = c
+		}
+		return &code
+	}
+
+	for _, k := range deliveryErrorKeywords {
+		if strings.Contains(text, k.keyword) {
+			code = k.code
+			return &code
+		}
+	}
+
+	if m := deliveryErrorNumberRegexp.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if c, ok := androidResultCodes[n]; ok {
+			code = c
+		}
+	}
+
+	return &code
+}
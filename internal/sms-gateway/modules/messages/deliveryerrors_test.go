@@ -0,0 +1,38 @@
+package messages
+
+import "testing"
+
+func TestNormalizeDeliveryError(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  *string
+		want *DeliveryErrorCode
+	}{
+		{name: "No error", raw: nil, want: nil},
+		{name: "Empty error", raw: ptr("  "), want: nil},
+		{name: "Numeric code", raw: ptr("4"), want: ptr(DeliveryErrorNoService)},
+		{name: "Unknown numeric code", raw: ptr("999"), want: ptr(DeliveryErrorUnknown)},
+		{name: "Constant name", raw: ptr("Send result: RESULT_ERROR_RADIO_OFF"), want: ptr(DeliveryErrorRadioOff)},
+		{name: "Human readable", raw: ptr("Generic failure"), want: ptr(DeliveryErrorGenericFailure)},
+		{name: "Code in text", raw: ptr("Sending error code: 17"), want: ptr(DeliveryErrorNetworkError)},
+		{name: "TTL expired", raw: ptr(ErrorTTLExpired), want: ptr(DeliveryErrorTTLExpired)},
+		{name: "processing timeout", raw: ptr(ErrorProcessingTimeout), want: ptr(DeliveryErrorTimeout)},
+		{name: "Unrecognized", raw: ptr("something went wrong"), want: ptr(DeliveryErrorUnknown)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizeDeliveryError(tt.raw)
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("NormalizeDeliveryError() = %v, want %v", got, tt.want)
+			}
+			if got != nil && *got != *tt.want {
+				t.Errorf("NormalizeDeliveryError() = %s, want %s", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
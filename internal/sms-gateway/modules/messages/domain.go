@@ -1,11 +1,21 @@
 package messages
 
 import (
+	"errors"
 	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 )
 
+// EncryptionInfo is the metadata of the client-side encryption of the
+// message. The server stores it as is to let the device decrypt the message.
+type EncryptionInfo struct {
+	// Algorithm is the encryption algorithm
+	Algorithm string
+	// KeyID is the ID of the key in the user's key registry
+	KeyID string
+}
+
 type MessageIn struct {
 	ID string
 
@@ -14,6 +24,7 @@ type MessageIn struct {
 
 	PhoneNumbers []string
 	IsEncrypted  bool
+	Encryption   *EncryptionInfo
 
 	SimNumber          *uint8
 	WithDeliveryReport *bool
@@ -47,6 +58,46 @@ type MessageStateOut struct {
 	IsHashed bool
 	// Encrypted
 	IsEncrypted bool
+	// Normalized recipients errors by phone number
+	ErrorCodes map[string]DeliveryErrorCode
+	// Detected recipients destinations by phone number
+	Destinations map[string]RecipientInfo
+	// ID of the original message if the enqueued message was a duplicate
+	DuplicateOf string
 
 	MessageStateIn
 }
+
+// StateUpdateResult is the result of a single update of the batch.
+type StateUpdateResult struct {
+	// Index of the update in the batch
+	Index int
+	// Message ID
+	ID string
+	// Error of the update
+	Err error
+}
+
+// Retryable returns true if the update may succeed on retry.
+func (r StateUpdateResult) Retryable() bool {
+	var errValidation ErrValidation
+	return !errors.Is(r.Err, ErrMessageNotFound) && !errors.As(r.Err, &errValidation)
+}
+
+type MessagePreview struct {
+	// Normalized phone numbers
+	PhoneNumbers []string
+	// Content policy warnings
+	Warnings []ContentWarning
+	// Message would be rejected by the content policy
+	Blocked bool
+}
+
+type QueueReconciliation struct {
+	// Number of messages held locally by the device
+	LocalPending int
+	// Number of pending messages on the server
+	ServerPending int64
+	// Number of stuck messages moved back to the Pending state
+	Requeued int64
+}
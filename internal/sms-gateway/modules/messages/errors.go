@@ -1,7 +1,49 @@
 package messages
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/capcom6/go-helpers/slices"
+)
+
+// ErrSimulatorBusy is returned when the message to the sandbox device is
+// rejected because too many messages are waiting for the simulation.
+var ErrSimulatorBusy = errors.New("too many sandbox messages are pending, try again later")
+
 type ErrValidation string
 
 func (e ErrValidation) Error() string {
 	return string(e)
 }
+
+// ErrContentPolicy is returned when a message is rejected by the content policy.
+type ErrContentPolicy struct {
+	Warnings []ContentWarning
+}
+
+func (e ErrContentPolicy) Error() string {
+	return "content policy violation: " + strings.Join(
+		slices.Map(e.Warnings, func(w ContentWarning) string { return w.Message }),
+		"; ",
+	)
+}
+
+// ErrSendWindow is returned when the message is enqueued outside the send
+// window of the recipient country.
+type ErrSendWindow struct {
+	Country string
+	Window  SendWindow
+	// OpensAt is the next opening of the window in the local time of the
+	// recipient.
+	OpensAt time.Time
+}
+
+func (e ErrSendWindow) Error() string {
+	return fmt.Sprintf(
+		"outside the send window %s of %s, the window opens at %s",
+		e.Window, e.Country, e.OpensAt.Format(time.RFC3339),
+	)
+}
@@ -0,0 +1,89 @@
+package messages
+
+import (
+	"math"
+	"sort"
+)
+
+// fairQueueWeightStep is the priority difference that doubles the share of a
+// flow in the weighted fair queue.
+const fairQueueWeightStep = 16
+
+// fairQueueBypassPriority is the priority at which messages are scheduled
+// before any other flow regardless of fairness.
+const fairQueueBypassPriority = 100
+
+// pendingFlow is a sequence of pending messages of the same user and priority
+// in the order they should be sent.
+type pendingFlow struct {
+	UserID   string
+	Priority int8
+	IDs      []uint64
+}
+
+// fairQueueWeight returns the share of the flow with the given priority. The
+// weight doubles every fairQueueWeightStep priority points, so higher priority
+// is preferred but lower priority flows still make progress.
+func fairQueueWeight(priority int8) float64 {
+	return math.Exp2(float64(priority) / fairQueueWeightStep)
+}
+
+// scheduleFair selects up to limit messages from the flows using weighted fair
+// queuing. Each flow gets a share proportional to its weight, so a large flow
+// of one user can't starve flows of other users or lower priorities. Order
+// inside a flow is preserved. Flows at or above fairQueueBypassPriority are
+// drained first.
+func scheduleFair(flows []pendingFlow, limit int) []uint64 {
+	type item struct {
+		id       uint64
+		priority int8
+		finish   float64
+		seq      int
+	}
+
+	items := []item{}
+	seq := 0
+	for _, flow := range flows {
+		step := 1 / fairQueueWeight(flow.Priority)
+		for i, id := range flow.IDs {
+			items = append(items, item{
+				id:       id,
+				priority: flow.Priority,
+				finish:   float64(i+1) * step,
+				seq:      seq,
+			})
+			seq++
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+
+		aBypass, bBypass := a.priority >= fairQueueBypassPriority, b.priority >= fairQueueBypassPriority
+		if aBypass != bBypass {
+			return aBypass
+		}
+		if aBypass && a.priority != b.priority {
+			return a.priority > b.priority
+		}
+
+		if a.finish != b.finish {
+			return a.finish < b.finish
+		}
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		return a.seq < b.seq
+	})
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	ids := make([]uint64, len(items))
+	for i, it := range items {
+		ids[i] = it.id
+	}
+
+	return ids
+}
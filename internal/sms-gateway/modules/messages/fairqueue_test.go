@@ -0,0 +1,89 @@
+package messages
+
+import "testing"
+
+func flowIDs(from, count uint64) []uint64 {
+	ids := make([]uint64, count)
+	for i := range ids {
+		ids[i] = from + uint64(i)
+	}
+	return ids
+}
+
+func countFrom(ids []uint64, from, to uint64) int {
+	n := 0
+	for _, id := range ids {
+		if id >= from && id < to {
+			n++
+		}
+	}
+	return n
+}
+
+func TestScheduleFair_SingleFlowKeepsOrder(t *testing.T) {
+	ids := scheduleFair([]pendingFlow{{UserID: "a", IDs: []uint64{5, 3, 9, 1}}}, 3)
+
+	want := []uint64{5, 3, 9}
+	if len(ids) != len(want) {
+		t.Fatalf("scheduleFair() = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("scheduleFair() = %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestScheduleFair_BulkUserDoesNotStarveOthers(t *testing.T) {
+	flows := []pendingFlow{
+		{UserID: "bulk", IDs: flowIDs(1, 1000)},
+		{UserID: "urgent", IDs: flowIDs(5000, 5)},
+	}
+
+	ids := scheduleFair(flows, 10)
+
+	if got := countFrom(ids, 5000, 5005); got != 5 {
+		t.Errorf("urgent messages selected = %d, want 5 (%v)", got, ids)
+	}
+	if got := countFrom(ids, 1, 1001); got != 5 {
+		t.Errorf("bulk messages selected = %d, want 5 (%v)", got, ids)
+	}
+}
+
+func TestScheduleFair_LowPriorityIsNotStarved(t *testing.T) {
+	flows := []pendingFlow{
+		{UserID: "a", Priority: 0, IDs: flowIDs(1, 1000)},
+		{UserID: "a", Priority: -fairQueueWeightStep, IDs: flowIDs(5000, 1000)},
+	}
+
+	ids := scheduleFair(flows, 90)
+
+	high, low := countFrom(ids, 1, 1001), countFrom(ids, 5000, 6000)
+	if low == 0 {
+		t.Fatalf("low priority flow is starved: %v", ids)
+	}
+	if high != 2*low {
+		t.Errorf("high/low = %d/%d, want 2:1 share", high, low)
+	}
+}
+
+func TestScheduleFair_BypassPriorityFirst(t *testing.T) {
+	flows := []pendingFlow{
+		{UserID: "a", Priority: 0, IDs: flowIDs(1, 10)},
+		{UserID: "b", Priority: fairQueueBypassPriority, IDs: flowIDs(100, 3)},
+	}
+
+	ids := scheduleFair(flows, 5)
+
+	for i, id := range ids[:3] {
+		if id != uint64(100+i) {
+			t.Fatalf("scheduleFair() = %v, want bypass messages first", ids)
+		}
+	}
+}
+
+func TestScheduleFair_Empty(t *testing.T) {
+	if ids := scheduleFair(nil, 10); len(ids) != 0 {
+		t.Errorf("scheduleFair() = %v, want empty", ids)
+	}
+}
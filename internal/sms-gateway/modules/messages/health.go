@@ -0,0 +1,219 @@
+package messages
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// HealthConfig controls the exclusion of devices with a high recent failure
+// rate from the device selection.
+type HealthConfig struct {
+	// Interval is the period of the failure rates evaluation, 0 to disable.
+	Interval time.Duration
+	// Window is the recent period used to calculate the failure rate.
+	Window time.Duration
+	// MinSamples is the minimal number of recipients in the final state
+	// within the window to judge the device, so a single failure of a
+	// low-volume device doesn't drain it.
+	MinSamples uint
+	// FailureThreshold is the failure rate at which the device becomes
+	// unhealthy.
+	FailureThreshold float64
+	// RecoveryThreshold is the failure rate at which the unhealthy device
+	// becomes healthy again. It is lower than FailureThreshold, so the device
+	// doesn't flap around the threshold. It only applies while the drained
+	// device still gets messages, i.e. the ones addressed to it explicitly or
+	// sent when all of the user's devices are drained. Otherwise the device
+	// is restored once its samples age out of the window below MinSamples.
+	RecoveryThreshold float64
+}
+
+// Enabled reports whether the devices health is evaluated.
+func (c HealthConfig) Enabled() bool {
+	return c.Interval > 0 && c.Window > 0 && c.FailureThreshold > 0
+}
+
+// deviceOutcomes is the number of recipients of a single device which
+// reached the final state and the failed ones.
+type deviceOutcomes struct {
+	DeviceID string
+	Total    uint64
+	Failed   uint64
+}
+
+// deviceHealth holds the set of unhealthy devices and applies the
+// thresholds with hysteresis.
+type deviceHealth struct {
+	config HealthConfig
+
+	unhealthy map[string]struct{}
+	mux       sync.RWMutex
+}
+
+func newDeviceHealth(config HealthConfig) *deviceHealth {
+	return &deviceHealth{
+		config:    config,
+		unhealthy: map[string]struct{}{},
+	}
+}
+
+// Update replaces the failure rates with the new evaluation and returns the
+// devices which changed the state. A device without enough samples within
+// the window is considered healthy, so a drained device gets another chance
+// after the window passes.
+func (h *deviceHealth) Update(items []deviceOutcomes) (drained, restored []string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	next := make(map[string]struct{}, len(h.unhealthy))
+	for _, item := range items {
+		if item.Total == 0 || item.Total < uint64(h.config.MinSamples) {
+			continue
+		}
+
+		rate := float64(item.Failed) / float64(item.Total)
+		_, wasUnhealthy := h.unhealthy[item.DeviceID]
+
+		switch {
+		case !wasUnhealthy && rate >= h.config.FailureThreshold:
+			drained = append(drained, item.DeviceID)
+			next[item.DeviceID] = struct{}{}
+		case wasUnhealthy && rate > h.config.RecoveryThreshold:
+			next[item.DeviceID] = struct{}{}
+		}
+	}
+
+	for deviceID := range h.unhealthy {
+		if _, ok := next[deviceID]; !ok {
+			restored = append(restored, deviceID)
+		}
+	}
+
+	h.unhealthy = next
+
+	return drained, restored
+}
+
+// Healthy reports whether the device isn't drained.
+func (h *deviceHealth) Healthy(deviceID string) bool {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	_, ok := h.unhealthy[deviceID]
+	return !ok
+}
+
+// Count returns the number of unhealthy devices.
+func (h *deviceHealth) Count() int {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+
+	return len(h.unhealthy)
+}
+
+// Filter returns the healthy devices. If all of the devices are unhealthy,
+// they are returned as is, so the messages are still sent.
+func (h *deviceHealth) Filter(devices []models.Device) []models.Device {
+	healthy := make([]models.Device, 0, len(devices))
+	for _, device := range devices {
+		if h.Healthy(device.ID) {
+			healthy = append(healthy, device)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return devices
+	}
+
+	return healthy
+}
+
+type HealthTaskParams struct {
+	fx.In
+
+	Messages *repository
+	Config   Config
+	Metrics  *metrics
+	Logger   *zap.Logger
+}
+
+// HealthTask periodically evaluates the failure rates of the devices, so the
+// devices with e.g. a blocked SIM are excluded from the selection. The rates
+// are calculated from the shared database, so all instances come to the
+// same decision.
+type HealthTask struct {
+	Messages *repository
+	Config   HealthConfig
+	Metrics  *metrics
+	Logger   *zap.Logger
+
+	health *deviceHealth
+}
+
+func (t *HealthTask) Run(ctx context.Context) {
+	if !t.Config.Enabled() {
+		t.Logger.Info("Devices health evaluation is disabled")
+		return
+	}
+
+	t.Logger.Info("Starting devices health task...")
+	ticker := time.NewTicker(t.Config.Interval)
+	defer ticker.Stop()
+
+	t.process(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.Logger.Info("Stopping devices health task...")
+			return
+		case <-ticker.C:
+			t.process(ctx)
+		}
+	}
+}
+
+// Filter returns the healthy devices, see deviceHealth.Filter.
+func (t *HealthTask) Filter(devices []models.Device) []models.Device {
+	if !t.Config.Enabled() {
+		return devices
+	}
+
+	return t.health.Filter(devices)
+}
+
+func (t *HealthTask) process(ctx context.Context) {
+	items, err := t.Messages.DeviceOutcomes(ctx, time.Now().Add(-t.Config.Window))
+	if err != nil {
+		t.Logger.Error("Can't get devices outcomes", zap.Error(err))
+		return
+	}
+
+	drained, restored := t.health.Update(items)
+	for _, deviceID := range drained {
+		t.Logger.Warn("Device drained due to high failure rate", zap.String("device_id", deviceID))
+	}
+	for _, deviceID := range restored {
+		t.Logger.Info("Device restored", zap.String("device_id", deviceID))
+	}
+
+	t.Metrics.AddHealthTransitions(HealthTransitionDrained, len(drained))
+	t.Metrics.AddHealthTransitions(HealthTransitionRestored, len(restored))
+	t.Metrics.SetUnhealthyDevices(t.health.Count())
+}
+
+func NewHealthTask(params HealthTaskParams) *HealthTask {
+	return &HealthTask{
+		Messages: params.Messages,
+		Config:   params.Config.Health,
+		Metrics:  params.Metrics,
+		Logger:   params.Logger,
+
+		health: newDeviceHealth(params.Config.Health),
+	}
+}
@@ -0,0 +1,59 @@
+package messages
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+func TestDeviceHealth_Update(t *testing.T) {
+	health := newDeviceHealth(HealthConfig{MinSamples: 10, FailureThreshold: 0.5, RecoveryThreshold: 0.2})
+
+	steps := []struct {
+		name         string
+		items        []deviceOutcomes
+		wantDrained  []string
+		wantRestored []string
+		wantHealthy  bool
+	}{
+		{name: "Not enough samples", items: []deviceOutcomes{{DeviceID: "a", Total: 5, Failed: 5}}, wantHealthy: true},
+		{name: "Below threshold", items: []deviceOutcomes{{DeviceID: "a", Total: 10, Failed: 4}}, wantHealthy: true},
+		{name: "Above threshold", items: []deviceOutcomes{{DeviceID: "a", Total: 10, Failed: 5}}, wantDrained: []string{"a"}, wantHealthy: false},
+		{name: "Between thresholds", items: []deviceOutcomes{{DeviceID: "a", Total: 10, Failed: 3}}, wantHealthy: false},
+		{name: "Below recovery threshold", items: []deviceOutcomes{{DeviceID: "a", Total: 10, Failed: 2}}, wantRestored: []string{"a"}, wantHealthy: true},
+		{name: "Drained again", items: []deviceOutcomes{{DeviceID: "a", Total: 20, Failed: 20}}, wantDrained: []string{"a"}, wantHealthy: false},
+		{name: "Window passed", items: nil, wantRestored: []string{"a"}, wantHealthy: true},
+	}
+
+	for _, step := range steps {
+		drained, restored := health.Update(step.items)
+		if !slices.Equal(drained, step.wantDrained) {
+			t.Errorf("%s: drained = %v, want %v", step.name, drained, step.wantDrained)
+		}
+		if !slices.Equal(restored, step.wantRestored) {
+			t.Errorf("%s: restored = %v, want %v", step.name, restored, step.wantRestored)
+		}
+		if got := health.Healthy("a"); got != step.wantHealthy {
+			t.Errorf("%s: Healthy() = %v, want %v", step.name, got, step.wantHealthy)
+		}
+	}
+}
+
+func TestDeviceHealth_Filter(t *testing.T) {
+	health := newDeviceHealth(HealthConfig{MinSamples: 1, FailureThreshold: 0.5, RecoveryThreshold: 0.2})
+	health.Update([]deviceOutcomes{
+		{DeviceID: "a", Total: 10, Failed: 10},
+		{DeviceID: "b", Total: 10, Failed: 0},
+	})
+
+	devices := []models.Device{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	if got := health.Filter(devices); len(got) != 2 || got[0].ID != "b" || got[1].ID != "c" {
+		t.Errorf("Filter() = %v, want devices b and c", got)
+	}
+
+	unhealthy := []models.Device{{ID: "a"}}
+	if got := health.Filter(unhealthy); len(got) != 1 || got[0].ID != "a" {
+		t.Errorf("Filter() = %v, want all devices when none is healthy", got)
+	}
+}
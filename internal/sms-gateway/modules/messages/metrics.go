@@ -0,0 +1,110 @@
+package messages
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metric constants
+const (
+	MetricMessagesTotal           = "total"
+	MetricDeviceQueueDepth        = "device_queue_depth"
+	MetricStuckTotal              = "stuck_total"
+	MetricPriorityDowngradedTotal = "priority_downgraded_total"
+	MetricUnhealthyDevices        = "unhealthy_devices"
+	MetricHealthTransitionsTotal  = "health_transitions_total"
+
+	LabelState      = "state"
+	LabelAction     = "action"
+	LabelTransition = "transition"
+
+	StuckActionRequeued = "requeued"
+	StuckActionFailed   = "failed"
+
+	HealthTransitionDrained  = "drained"
+	HealthTransitionRestored = "restored"
+)
+
+// metrics contains all Prometheus metrics for the messages module
+type metrics struct {
+	messagesCounter           *prometheus.CounterVec
+	deviceQueueDepth          prometheus.Histogram
+	stuckCounter              *prometheus.CounterVec
+	priorityDowngradedCounter prometheus.Counter
+	unhealthyDevices          prometheus.Gauge
+	healthTransitionsCounter  *prometheus.CounterVec
+}
+
+// newMetrics creates and initializes all messages metrics
+func newMetrics() *metrics {
+	return &metrics{
+		messagesCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "messages",
+			Name:      MetricMessagesTotal,
+			Help:      "Total number of messages by state",
+		}, []string{LabelState}),
+		deviceQueueDepth: promauto.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "sms",
+			Subsystem: "messages",
+			Name:      MetricDeviceQueueDepth,
+			Help:      "Number of messages held locally by devices as reported by devices",
+			Buckets:   []float64{0, 1, 5, 10, 25, 50, 100, 250, 500, 1000},
+		}),
+		stuckCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "messages",
+			Name:      MetricStuckTotal,
+			Help:      "Total number of messages stuck in Processed state by recovery action",
+		}, []string{LabelAction}),
+		priorityDowngradedCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "messages",
+			Name:      MetricPriorityDowngradedTotal,
+			Help:      "Total number of high-priority messages sent with regular priority due to exhausted burst budget",
+		}),
+		unhealthyDevices: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "sms",
+			Subsystem: "messages",
+			Name:      MetricUnhealthyDevices,
+			Help:      "Number of devices excluded from selection due to high failure rate",
+		}),
+		healthTransitionsCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "messages",
+			Name:      MetricHealthTransitionsTotal,
+			Help:      "Total number of device health transitions",
+		}, []string{LabelTransition}),
+	}
+}
+
+// IncrementState increments the messages counter for the given state
+func (m *metrics) IncrementState(state ProcessingState) {
+	m.messagesCounter.WithLabelValues(string(state)).Inc()
+}
+
+// ObserveDeviceQueueDepth observes the queue depth reported by a device
+func (m *metrics) ObserveDeviceQueueDepth(depth int) {
+	m.deviceQueueDepth.Observe(float64(depth))
+}
+
+// AddStuck increments the stuck messages counter for the given action
+func (m *metrics) AddStuck(action string, count int64) {
+	m.stuckCounter.WithLabelValues(action).Add(float64(count))
+}
+
+// IncrementPriorityDowngraded increments the counter of high-priority messages
+// beyond the burst budget
+func (m *metrics) IncrementPriorityDowngraded() {
+	m.priorityDowngradedCounter.Inc()
+}
+
+// SetUnhealthyDevices sets the number of devices excluded from selection
+func (m *metrics) SetUnhealthyDevices(count int) {
+	m.unhealthyDevices.Set(float64(count))
+}
+
+// AddHealthTransitions increments the device health transitions counter
+func (m *metrics) AddHealthTransitions(transition string, count int) {
+	m.healthTransitionsCounter.WithLabelValues(transition).Add(float64(count))
+}
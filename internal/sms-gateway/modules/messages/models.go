@@ -35,6 +35,7 @@ type DataMessageContent struct {
 type Message struct {
 	ID                 uint64          `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
 	DeviceID           string          `gorm:"not null;type:char(21);uniqueIndex:unq_messages_id_device,priority:2;index:idx_messages_device_state"`
+	UserID             string          `gorm:"not null;type:varchar(32);default:''"`
 	ExtID              string          `gorm:"not null;type:varchar(36);uniqueIndex:unq_messages_id_device,priority:1"`
 	Type               MessageType     `gorm:"not null;type:enum('Text','Data');default:Text"`
 	Content            string          `gorm:"not null;type:text"`
@@ -47,6 +48,9 @@ type Message struct {
 	IsHashed    bool `gorm:"not null;type:tinyint(1) unsigned;default:0"`
 	IsEncrypted bool `gorm:"not null;type:tinyint(1) unsigned;default:0"`
 
+	EncryptionAlgorithm *string `gorm:"type:varchar(32)"`
+	EncryptionKeyID     *string `gorm:"type:varchar(64)"`
+
 	Device     models.Device      `gorm:"foreignKey:DeviceID;constraint:OnDelete:CASCADE"`
 	Recipients []MessageRecipient `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
 	States     []MessageState     `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE"`
@@ -109,11 +113,21 @@ func (m *Message) GetDataContent() (*DataMessageContent, error) {
 }
 
 type MessageRecipient struct {
-	ID          uint64          `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
-	MessageID   uint64          `gorm:"uniqueIndex:unq_message_recipients_message_id_phone_number,priority:1;type:BIGINT UNSIGNED"`
-	PhoneNumber string          `gorm:"uniqueIndex:unq_message_recipients_message_id_phone_number,priority:2;type:varchar(128)"`
-	State       ProcessingState `gorm:"not null;type:enum('Pending','Sent','Processed','Delivered','Failed');default:Pending"`
-	Error       *string         `gorm:"type:varchar(256)"`
+	ID          uint64             `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	MessageID   uint64             `gorm:"uniqueIndex:unq_message_recipients_message_id_phone_number,priority:1;type:BIGINT UNSIGNED"`
+	PhoneNumber string             `gorm:"uniqueIndex:unq_message_recipients_message_id_phone_number,priority:2;index:idx_message_recipients_phone_number;type:varchar(128)"`
+	State       ProcessingState    `gorm:"not null;type:enum('Pending','Sent','Processed','Delivered','Failed');default:Pending"`
+	Error       *string            `gorm:"type:varchar(256)"`
+	ErrorCode   *DeliveryErrorCode `gorm:"type:varchar(32)"`
+	Country     *string            `gorm:"type:char(2)"`
+	Carrier     *string            `gorm:"type:varchar(64)"`
+}
+
+// stuckMessages is the stuck messages of a single device.
+type stuckMessages struct {
+	DeviceID string
+	UserID   string
+	Messages []Message
 }
 
 type MessageState struct {
@@ -121,8 +135,29 @@ type MessageState struct {
 	MessageID uint64          `gorm:"not null;type:BIGINT UNSIGNED;uniqueIndex:unq_message_states_message_id_state,priority:1"`
 	State     ProcessingState `gorm:"not null;type:enum('Pending','Sent','Processed','Delivered','Failed');uniqueIndex:unq_message_states_message_id_state,priority:2"`
 	UpdatedAt time.Time       `gorm:"<-:create;not null;autoupdatetime:false"`
+	// DeviceUpdatedAt is the time reported by the device before clock skew checks.
+	DeviceUpdatedAt *time.Time `gorm:"<-:create;type:datetime(3)"`
+	// ReceivedAt is the server time of the state update.
+	ReceivedAt time.Time `gorm:"->;not null;autocreatetime:false;default:CURRENT_TIMESTAMP(3);index:idx_message_states_received_at"`
+}
+
+// MessageSync is the entry of the changelog of the message states synced by
+// the device. The sequence number of the last entry is the sync token.
+type MessageSync struct {
+	ID       uint64       `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	DeviceID string       `gorm:"not null;type:char(21);uniqueIndex:unq_message_syncs_device_seq,priority:1"`
+	Seq      uint64       `gorm:"not null;type:BIGINT UNSIGNED;uniqueIndex:unq_message_syncs_device_seq,priority:2"`
+	Changes  []syncChange `gorm:"not null;type:json;serializer:json"`
+
+	CreatedAt time.Time `gorm:"->;not null;autocreatetime:false;default:CURRENT_TIMESTAMP(3);index:idx_message_syncs_created_at"`
+}
+
+// syncChange is the message state applied by the sync.
+type syncChange struct {
+	ID    string          `json:"id"`
+	State ProcessingState `json:"state"`
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&Message{}, &MessageRecipient{}, &MessageState{})
+	return db.AutoMigrate(&Message{}, &MessageRecipient{}, &MessageState{}, &MessageSync{})
 }
@@ -1,6 +1,7 @@
 package messages
 
 import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/cleaner"
 	"github.com/capcom6/go-infra-fx/db"
 	"go.uber.org/fx"
@@ -29,8 +30,14 @@ var Module = fx.Module(
 			AsCleaner: svc,
 		}
 	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("messages")
+	}, fx.Private),
 	fx.Provide(newRepository),
+	fx.Provide(newMetrics, fx.Private),
 	fx.Provide(NewHashingTask, fx.Private),
+	fx.Provide(NewStuckTask, fx.Private),
+	fx.Provide(NewHealthTask, fx.Private),
 )
 
 func init() {
@@ -0,0 +1,47 @@
+package messages
+
+import (
+	"context"
+	"fmt"
+)
+
+// SelectByPhoneNumber returns the page of the messages of the user to the
+// recipient, both plain and hashed, and the total number of them. The content
+// of the hashed messages isn't stored, so it's omitted.
+func (s *Service) SelectByPhoneNumber(userID, phoneNumber string, limit, offset int) ([]MessageOut, int64, error) {
+	items, total, err := s.messages.Select(
+		MessagesSelectFilter{UserID: userID, PhoneNumber: phoneNumber},
+		MessagesSelectOptions{WithRecipients: true, WithStates: true, WithContent: true, Limit: limit, Offset: offset},
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can't select messages: %w", err)
+	}
+
+	out := make([]MessageOut, 0, len(items))
+	for _, item := range items {
+		if item.IsHashed {
+			// the content is replaced with its hash
+			item.Type, item.Content = "", ""
+		}
+
+		message, err := messageToDomain(item)
+		if err != nil {
+			return nil, 0, fmt.Errorf("can't convert message %s: %w", item.ExtID, err)
+		}
+		out = append(out, message)
+	}
+
+	return out, total, nil
+}
+
+// ErasePhoneNumber removes the recipient and the content from the messages of
+// the user and returns the IDs of the affected messages. The pending messages
+// are deleted.
+func (s *Service) ErasePhoneNumber(ctx context.Context, userID, phoneNumber string) ([]string, error) {
+	ids, err := s.messages.ErasePhoneNumber(ctx, userID, phoneNumberVariants(phoneNumber))
+	if err != nil {
+		return nil, fmt.Errorf("can't erase messages: %w", err)
+	}
+
+	return ids, nil
+}
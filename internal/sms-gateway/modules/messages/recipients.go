@@ -0,0 +1,126 @@
+package messages
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/nyaruka/phonenumbers"
+)
+
+// unknownRegion is the region code returned for numbers not assigned to any
+// country.
+const unknownRegion = "ZZ"
+
+// RecipientInfo is the destination of the recipient detected from the number
+// plan data.
+type RecipientInfo struct {
+	// Country is the ISO 3166-1 alpha-2 code of the country.
+	Country string
+	// Carrier is the carrier the number range was originally allocated to,
+	// empty if unknown. Ported numbers aren't detected.
+	Carrier string
+}
+
+// DetectRecipient returns the country and the carrier of the phone number in
+// the international format. It returns false if the country can't be
+// detected, e.g. for encrypted or non-validated numbers.
+func DetectRecipient(phoneNumber string) (RecipientInfo, bool) {
+	phone, err := phonenumbers.Parse(phoneNumber, "")
+	if err != nil {
+		return RecipientInfo{}, false
+	}
+
+	region := phonenumbers.GetRegionCodeForNumber(phone)
+	if region == "" || region == unknownRegion {
+		return RecipientInfo{}, false
+	}
+
+	carrier, _ := phonenumbers.GetCarrierForNumber(phone, "en")
+
+	return RecipientInfo{Country: region, Carrier: carrier}, true
+}
+
+// countryRoutesFromSettings returns the device IDs assigned to countries in
+// the `routing.countries` user setting, e.g. {"DE": ["PyDmBQZZXYmyxMwED8Fzy"]}.
+func countryRoutesFromSettings(settings map[string]any) map[string][]string {
+	group, _ := settings["routing"].(map[string]any)
+	countries, _ := group["countries"].(map[string]any)
+
+	routes := make(map[string][]string, len(countries))
+	for country, ids := range countries {
+		items, _ := ids.([]any)
+		for _, id := range items {
+			if id, ok := id.(string); ok {
+				routes[strings.ToUpper(country)] = append(routes[strings.ToUpper(country)], id)
+			}
+		}
+	}
+
+	return routes
+}
+
+// routeByCountry narrows the devices to the ones assigned to the country of
+// the recipients. The devices are returned unchanged if the recipients belong
+// to different or unknown countries or none of the devices is assigned.
+func routeByCountry(routes map[string][]string, phoneNumbers []string, devices []models.Device) []models.Device {
+	if len(routes) == 0 || len(phoneNumbers) == 0 {
+		return devices
+	}
+
+	country := ""
+	for _, phoneNumber := range phoneNumbers {
+		if normalized, err := cleanPhoneNumber(phoneNumber); err == nil {
+			phoneNumber = normalized
+		}
+
+		info, ok := DetectRecipient(phoneNumber)
+		if !ok || (country != "" && country != info.Country) {
+			return devices
+		}
+		country = info.Country
+	}
+
+	ids, ok := routes[country]
+	if !ok {
+		return devices
+	}
+
+	routed := []models.Device{}
+	for _, d := range devices {
+		if slices.Contains(ids, d.ID) {
+			routed = append(routed, d)
+		}
+	}
+	if len(routed) == 0 {
+		return devices
+	}
+
+	return routed
+}
+
+// hashPhoneNumber returns the hash the phone number is replaced with when the
+// message is hashed. It must match the SQL expression of HashProcessed.
+func hashPhoneNumber(phoneNumber string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(phoneNumber)))[:16]
+}
+
+// phoneNumberVariants returns the values the recipient may be stored as: the
+// number as is, in the international format and their hashes.
+func phoneNumberVariants(phoneNumber string) []string {
+	plain := []string{phoneNumber}
+	if phone, err := phonenumbers.Parse(phoneNumber, "RU"); err == nil {
+		if normalized := phonenumbers.Format(phone, phonenumbers.E164); normalized != phoneNumber {
+			plain = append(plain, normalized)
+		}
+	}
+
+	variants := slices.Clone(plain)
+	for _, v := range plain {
+		variants = append(variants, hashPhoneNumber(v))
+	}
+
+	return variants
+}
@@ -0,0 +1,98 @@
+package messages
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+func TestDetectRecipient(t *testing.T) {
+	tests := []struct {
+		name        string
+		phoneNumber string
+		wantCountry string
+		wantOK      bool
+	}{
+		{name: "Russia", phoneNumber: "+79123456789", wantCountry: "RU", wantOK: true},
+		{name: "Germany", phoneNumber: "+4915123456789", wantCountry: "DE", wantOK: true},
+		{name: "United States", phoneNumber: "+12015550123", wantCountry: "US", wantOK: true},
+		{name: "national format", phoneNumber: "89123456789", wantOK: false},
+		{name: "encrypted", phoneNumber: "U2FsdGVkX1+abc", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DetectRecipient(tt.phoneNumber)
+			if ok != tt.wantOK {
+				t.Fatalf("DetectRecipient() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got.Country != tt.wantCountry {
+				t.Errorf("DetectRecipient() country = %q, want %q", got.Country, tt.wantCountry)
+			}
+		})
+	}
+}
+
+func TestRouteByCountry(t *testing.T) {
+	devices := []models.Device{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	routes := map[string][]string{"DE": {"b"}, "RU": {"x"}}
+
+	tests := []struct {
+		name         string
+		phoneNumbers []string
+		want         []string
+	}{
+		{name: "routed", phoneNumbers: []string{"+4915123456789"}, want: []string{"b"}},
+		{name: "no route", phoneNumbers: []string{"+12015550123"}, want: []string{"a", "b", "c"}},
+		{name: "unknown device", phoneNumbers: []string{"+79123456789"}, want: []string{"a", "b", "c"}},
+		{name: "mixed countries", phoneNumbers: []string{"+4915123456789", "+79123456789"}, want: []string{"a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := routeByCountry(routes, tt.phoneNumbers, devices)
+			if len(got) != len(tt.want) {
+				t.Fatalf("routeByCountry() = %v, want %v", got, tt.want)
+			}
+			for i, d := range got {
+				if d.ID != tt.want[i] {
+					t.Errorf("routeByCountry()[%d] = %q, want %q", i, d.ID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPhoneNumberVariants(t *testing.T) {
+	tests := []struct {
+		name        string
+		phoneNumber string
+		want        []string
+	}{
+		{
+			name:        "international format",
+			phoneNumber: "+79123456789",
+			want:        []string{"+79123456789", "694e8de862e4f319"},
+		},
+		{
+			name:        "national format",
+			phoneNumber: "89123456789",
+			want:        []string{"89123456789", "+79123456789", "ed9eda091e5fc3b5", "694e8de862e4f319"},
+		},
+		{
+			name:        "not a number",
+			phoneNumber: "customer",
+			want:        []string{"customer", hashPhoneNumber("customer")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := phoneNumberVariants(tt.phoneNumber)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("phoneNumberVariants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
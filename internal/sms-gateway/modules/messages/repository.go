@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
 	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -17,6 +20,7 @@ const maxPendingBatch = 100
 
 var ErrMessageNotFound = gorm.ErrRecordNotFound
 var ErrMessageAlreadyExists = errors.New("duplicate id")
+var errSyncExists = errors.New("sync already exists")
 var ErrMultipleMessagesFound = errors.New("multiple messages found")
 
 type repository struct {
@@ -56,6 +60,17 @@ func (r *repository) Select(filter MessagesSelectFilter, options MessagesSelectO
 		query = query.Where("messages.device_id = ?", filter.DeviceID)
 	}
 
+	// Apply recipient filter
+	if filter.PhoneNumber != "" {
+		query = query.Where(
+			"messages.id IN (?)",
+			r.db.
+				Model(&MessageRecipient{}).
+				Select("message_id").
+				Where("phone_number IN ?", phoneNumberVariants(filter.PhoneNumber)),
+		)
+	}
+
 	// Get total count
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -96,17 +111,109 @@ func (r *repository) Select(filter MessagesSelectFilter, options MessagesSelectO
 	return messages, total, nil
 }
 
-func (r *repository) SelectPending(deviceID string, order MessagesOrder) ([]Message, error) {
-	messages, _, err := r.Select(MessagesSelectFilter{
-		DeviceID: deviceID,
-		State:    ProcessingStatePending,
-	}, MessagesSelectOptions{
-		WithRecipients: true,
-		Limit:          maxPendingBatch,
-		OrderBy:        order,
+// SelectPending returns a batch of pending messages for the device and the
+// total number of pending messages. Messages of different users and priorities are interleaved with weighted
+// fair queuing, see scheduleFair.
+func (r *repository) SelectPending(deviceID string, order MessagesOrder) ([]Message, int64, error) {
+	groups := []struct {
+		UserID   string
+		Priority int8
+		Count    int64
+	}{}
+	if err := r.db.Model(&Message{}).
+		Select("user_id, priority, COUNT(*) AS count").
+		Where("device_id = ? AND state = ?", deviceID, ProcessingStatePending).
+		Group("user_id, priority").
+		Find(&groups).Error; err != nil {
+		return nil, 0, fmt.Errorf("can't count pending messages: %w", err)
+	}
+
+	var total int64
+	for _, g := range groups {
+		total += g.Count
+	}
+
+	if len(groups) == 0 {
+		return []Message{}, 0, nil
+	}
+
+	idOrder := "id DESC"
+	if order == MessagesOrderFIFO {
+		idOrder = "id ASC"
+	}
+
+	// the heads of all flows are selected at once, up to maxPendingBatch
+	// messages per flow
+	heads := []struct {
+		ID       uint64
+		UserID   string
+		Priority int8
+	}{}
+	if err := r.db.
+		Table("(?) AS pending",
+			r.db.Model(&Message{}).
+				Select("id, user_id, priority, ROW_NUMBER() OVER (PARTITION BY user_id, priority ORDER BY "+idOrder+") AS pos").
+				Where("device_id = ? AND state = ?", deviceID, ProcessingStatePending),
+		).
+		Select("id, user_id, priority").
+		Where("pos <= ?", maxPendingBatch).
+		Order(idOrder).
+		Find(&heads).Error; err != nil {
+		return nil, 0, fmt.Errorf("can't select pending messages: %w", err)
+	}
+
+	type flowKey struct {
+		userID   string
+		priority int8
+	}
+	flows := make([]pendingFlow, len(groups))
+	flowIndex := make(map[flowKey]int, len(groups))
+	for i, g := range groups {
+		flows[i] = pendingFlow{UserID: g.UserID, Priority: g.Priority}
+		flowIndex[flowKey{g.UserID, g.Priority}] = i
+	}
+	for _, h := range heads {
+		if i, ok := flowIndex[flowKey{h.UserID, h.Priority}]; ok {
+			flows[i].IDs = append(flows[i].IDs, h.ID)
+		}
+	}
+
+	ids := scheduleFair(flows, maxPendingBatch)
+	if len(ids) == 0 {
+		return []Message{}, total, nil
+	}
+
+	selected := make([]Message, 0, len(ids))
+	if err := r.db.
+		Where("id IN ?", ids).
+		Find(&selected).Error; err != nil {
+		return nil, 0, fmt.Errorf("can't select pending messages: %w", err)
+	}
+
+	recipients := []MessageRecipient{}
+	if err := r.db.
+		Where("message_id IN ?", ids).
+		Order("id").
+		Find(&recipients).Error; err != nil {
+		return nil, 0, fmt.Errorf("can't select pending recipients: %w", err)
+	}
+	byMessage := make(map[uint64][]MessageRecipient, len(ids))
+	for _, v := range recipients {
+		byMessage[v.MessageID] = append(byMessage[v.MessageID], v)
+	}
+	for i := range selected {
+		selected[i].Recipients = byMessage[selected[i].ID]
+	}
+
+	positions := make(map[uint64]int, len(ids))
+	for i, id := range ids {
+		positions[id] = i
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return positions[selected[i].ID] < positions[selected[j].ID]
 	})
 
-	return messages, err
+	return selected, total, nil
 }
 
 func (r *repository) Get(filter MessagesSelectFilter, options MessagesSelectOptions) (Message, error) {
@@ -126,16 +233,27 @@ func (r *repository) Get(filter MessagesSelectFilter, options MessagesSelectOpti
 	return messages[0], nil
 }
 
-func (r *repository) Insert(message *Message) error {
-	err := r.db.Omit("Device").Create(message).Error
-	if err == nil {
-		return nil
-	}
+// Insert stores the message with its short links in a single transaction.
+func (r *repository) Insert(message *Message, shortLinks []links.ShortLink) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Device").Create(message).Error; err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+				return ErrMessageAlreadyExists
+			}
+			return err
+		}
 
-	if mysqlErr := err.(*mysql.MySQLError); mysqlErr != nil && mysqlErr.Number == 1062 {
-		return ErrMessageAlreadyExists
-	}
-	return err
+		if len(shortLinks) == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&shortLinks).Error; err != nil {
+			return fmt.Errorf("can't store links: %w", err)
+		}
+
+		return nil
+	})
 }
 
 func (r *repository) UpdateState(message *Message) error {
@@ -154,7 +272,7 @@ func (r *repository) UpdateState(message *Message) error {
 		}
 
 		for _, v := range message.Recipients {
-			if err := tx.Model(&v).Where("message_id = ?", message.ID).Select("State", "Error").Updates(&v).Error; err != nil {
+			if err := tx.Model(&v).Where("message_id = ?", message.ID).Select("State", "Error", "ErrorCode").Updates(&v).Error; err != nil {
 				return err
 			}
 		}
@@ -163,11 +281,16 @@ func (r *repository) UpdateState(message *Message) error {
 	})
 }
 
-func (r *repository) HashProcessed(ids []uint64) error {
+// HashProcessed hashes the content and the recipients of the messages in the
+// final states. The messages in the Processed state keep the content for the
+// redelivery until processedBefore, so the stuck ones are hashed after it.
+// The update time of the messages is kept, so the hashing doesn't count as
+// a recent outcome of the device.
+func (r *repository) HashProcessed(ids []uint64, processedBefore time.Time) error {
 	rawSQL := "UPDATE `messages` `m`, `message_recipients` `r`\n" +
-		"SET `m`.`is_hashed` = true, `m`.`content` = SHA2(COALESCE(JSON_VALUE(`content`, '$.text'), JSON_VALUE(`content`, '$.data')), 256), `r`.`phone_number` = LEFT(SHA2(phone_number, 256), 16)\n" +
-		"WHERE `m`.`id` = `r`.`message_id` AND `m`.`is_hashed` = false AND `m`.`is_encrypted` = false AND `m`.`state` <> 'Pending'"
-	params := []interface{}{}
+		"SET `m`.`updated_at` = `m`.`updated_at`, `m`.`is_hashed` = true, `m`.`content` = SHA2(COALESCE(JSON_VALUE(`content`, '$.text'), JSON_VALUE(`content`, '$.data')), 256), `r`.`phone_number` = LEFT(SHA2(phone_number, 256), 16)\n" +
+		"WHERE `m`.`id` = `r`.`message_id` AND `m`.`is_hashed` = false AND `m`.`is_encrypted` = false AND (`m`.`state` NOT IN ('Pending', 'Processed') OR (`m`.`state` = 'Processed' AND `m`.`updated_at` < ?))"
+	params := []interface{}{processedBefore}
 	if len(ids) > 0 {
 		rawSQL += " AND `m`.`id` IN (?)"
 		params = append(params, ids)
@@ -190,6 +313,218 @@ func (r *repository) HashProcessed(ids []uint64) error {
 	})
 }
 
+// CountPending returns the number of pending messages for the device.
+func (r *repository) CountPending(ctx context.Context, deviceID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&Message{}).
+		Where("device_id = ? AND state = ?", deviceID, ProcessingStatePending).
+		Count(&count).Error
+
+	return count, err
+}
+
+// CountPendingByDevices returns the number of pending messages grouped by
+// device. Devices without pending messages are omitted.
+func (r *repository) CountPendingByDevices(ctx context.Context, deviceIDs []string) (map[string]int64, error) {
+	rows := []struct {
+		DeviceID string
+		Count    int64
+	}{}
+	err := r.db.WithContext(ctx).
+		Model(&Message{}).
+		Select("device_id, COUNT(*) AS count").
+		Where("device_id IN ? AND state = ?", deviceIDs, ProcessingStatePending).
+		Group("device_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.DeviceID] = row.Count
+	}
+
+	return counts, nil
+}
+
+// SelectPendingSandbox returns the sandbox devices with pending messages.
+func (r *repository) SelectPendingSandbox(ctx context.Context) ([]models.Device, error) {
+	devices := []models.Device{}
+	err := r.db.WithContext(ctx).
+		Where("sandbox = ? AND id IN (?)", true,
+			r.db.Model(&Message{}).
+				Select("device_id").
+				Where("state = ?", ProcessingStatePending),
+		).
+		Find(&devices).Error
+
+	return devices, err
+}
+
+// DeviceOutcomes returns the number of recipients which reached the final
+// state since the given time and the failed ones, grouped by device. The
+// messages are looked up by the idx_messages_updated_at index.
+func (r *repository) DeviceOutcomes(ctx context.Context, since time.Time) ([]deviceOutcomes, error) {
+	items := []deviceOutcomes{}
+	err := r.db.WithContext(ctx).
+		Table("message_recipients").
+		Select("messages.device_id, COUNT(*) AS total, SUM(message_recipients.state = 'Failed') AS failed").
+		Joins("JOIN messages ON messages.id = message_recipients.message_id").
+		Where("messages.deleted_at IS NULL AND messages.updated_at >= ? AND message_recipients.state IN ?", since, []ProcessingState{ProcessingStateSent, ProcessingStateDelivered, ProcessingStateFailed}).
+		Group("messages.device_id").
+		Scan(&items).
+		Error
+
+	return items, err
+}
+
+// resolveStuck moves messages stuck in the Processed state since the given
+// time to the target state. If deviceID is empty, messages of all devices are
+// processed. The messages are locked, so concurrent instances don't resolve
+// the same ones twice. It returns the affected messages grouped by device,
+// with the recipients moved to the target state.
+func (r *repository) resolveStuck(ctx context.Context, deviceID string, processedBefore time.Time, target ProcessingState, reason *string) ([]stuckMessages, error) {
+	affected := map[string]*stuckMessages{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&Message{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("state = ? AND updated_at < ?", ProcessingStateProcessed, processedBefore)
+		if target == ProcessingStatePending {
+			// content of hashed messages is lost, so they can't be sent again
+			query = query.Where("is_hashed = ?", false)
+		}
+		if deviceID != "" {
+			query = query.Where("device_id = ?", deviceID)
+		}
+
+		stuck := []Message{}
+		if err := query.Find(&stuck).Error; err != nil {
+			return err
+		}
+
+		if len(stuck) == 0 {
+			return nil
+		}
+
+		ids := make([]uint64, len(stuck))
+		deviceIDs := make([]string, 0, len(stuck))
+		for i, m := range stuck {
+			ids[i] = m.ID
+			if _, ok := affected[m.DeviceID]; !ok {
+				affected[m.DeviceID] = &stuckMessages{DeviceID: m.DeviceID}
+				deviceIDs = append(deviceIDs, m.DeviceID)
+			}
+		}
+
+		devices := []models.Device{}
+		if err := tx.Select("id", "user_id").Where("id IN ?", deviceIDs).Find(&devices).Error; err != nil {
+			return err
+		}
+		for _, d := range devices {
+			affected[d.ID].UserID = d.UserID
+		}
+
+		recipients := []MessageRecipient{}
+		if err := tx.Where("message_id IN ? AND state IN ?", ids, []ProcessingState{ProcessingStatePending, ProcessingStateProcessed}).
+			Find(&recipients).Error; err != nil {
+			return err
+		}
+		byMessage := map[uint64][]MessageRecipient{}
+		for _, v := range recipients {
+			byMessage[v.MessageID] = append(byMessage[v.MessageID], v)
+		}
+
+		now := time.Now()
+		for _, m := range stuck {
+			m.State = target
+			m.Recipients = byMessage[m.ID]
+			affected[m.DeviceID].Messages = append(affected[m.DeviceID].Messages, m)
+
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&MessageState{MessageID: m.ID, State: target, UpdatedAt: now}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&Message{}).
+			Where("id IN ?", ids).
+			Update("state", target).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&MessageRecipient{}).
+			Where("message_id IN ? AND state IN ?", ids, []ProcessingState{ProcessingStatePending, ProcessingStateProcessed}).
+			Updates(map[string]any{"state": target, "error": reason, "error_code": NormalizeDeliveryError(reason)}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]stuckMessages, 0, len(affected))
+	for _, v := range affected {
+		result = append(result, *v)
+	}
+
+	return result, nil
+}
+
+// SelectSyncs returns the sequence numbers of the first and the last stored
+// syncs of the device and the syncs after the given one in order. The
+// numbers are zero if the device has no syncs.
+func (r *repository) SelectSyncs(ctx context.Context, deviceID string, after uint64) (uint64, uint64, []MessageSync, error) {
+	bounds := struct {
+		First uint64
+		Last  uint64
+	}{}
+	if err := r.db.WithContext(ctx).
+		Model(&MessageSync{}).
+		Select("COALESCE(MIN(seq), 0) AS first, COALESCE(MAX(seq), 0) AS last").
+		Where("device_id = ?", deviceID).
+		Scan(&bounds).Error; err != nil {
+		return 0, 0, nil, err
+	}
+
+	syncs := []MessageSync{}
+	if after >= bounds.Last {
+		return bounds.First, bounds.Last, syncs, nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("device_id = ? AND seq > ?", deviceID, after).
+		Order("seq").
+		Find(&syncs).Error
+
+	return bounds.First, bounds.Last, syncs, err
+}
+
+// InsertSync stores the sync of the device. It returns errSyncExists if the
+// sync with the sequence number is stored by the concurrent request.
+func (r *repository) InsertSync(ctx context.Context, sync *MessageSync) error {
+	err := r.db.WithContext(ctx).Create(sync).Error
+	if mysqlErr := (*mysql.MySQLError)(nil); errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return errSyncExists
+	}
+
+	return err
+}
+
+// removeSyncs removes the syncs older than the given time. The last sync of
+// every device is kept, so the token of an idle device stays valid.
+func (r *repository) removeSyncs(ctx context.Context, until time.Time) (int64, error) {
+	res := r.db.
+		WithContext(ctx).
+		Exec(
+			"DELETE s FROM message_syncs s "+
+				"JOIN (SELECT device_id, MAX(seq) AS seq FROM message_syncs GROUP BY device_id) l ON l.device_id = s.device_id "+
+				"WHERE s.created_at < ? AND s.seq < l.seq",
+			until,
+		)
+	return res.RowsAffected, res.Error
+}
+
 // removeProcessed removes messages older than the given time that are not in
 // the Pending state.
 //
@@ -204,6 +539,80 @@ func (r *repository) removeProcessed(ctx context.Context, until time.Time) (int6
 	return res.RowsAffected, res.Error
 }
 
+// ErasePhoneNumber removes the recipient from the messages of the user and
+// returns the IDs of the affected messages. The pending messages aren't
+// sent yet, so they are deleted. The content of the other messages is cleared
+// and the recipient is replaced with a random hash, so the messages are kept
+// in the stats without the personal data.
+func (r *repository) ErasePhoneNumber(ctx context.Context, userID string, phoneNumbers []string) ([]string, error) {
+	items := []struct {
+		ID    uint64
+		ExtID string
+	}{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&Message{}).
+			Select("messages.id, messages.ext_id").
+			Joins("JOIN devices ON devices.id = messages.device_id").
+			Where("devices.user_id = ?", userID).
+			Where(
+				"messages.id IN (?)",
+				tx.Model(&MessageRecipient{}).Select("message_id").Where("phone_number IN ?", phoneNumbers),
+			).
+			Find(&items).
+			Error
+		if err != nil {
+			return fmt.Errorf("can't select messages: %w", err)
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		ids := make([]uint64, 0, len(items))
+		for _, item := range items {
+			ids = append(ids, item.ID)
+		}
+
+		if err := tx.
+			Where("id IN ? AND state = ?", ids, ProcessingStatePending).
+			Delete(&Message{}).
+			Error; err != nil {
+			return fmt.Errorf("can't delete pending messages: %w", err)
+		}
+
+		if err := tx.
+			Model(&MessageRecipient{}).
+			Where("message_id IN ? AND phone_number IN ?", ids, phoneNumbers).
+			Updates(map[string]any{
+				"phone_number": gorm.Expr("LEFT(SHA2(UUID(), 256), 16)"),
+				"error":        nil,
+			}).
+			Error; err != nil {
+			return fmt.Errorf("can't erase recipients: %w", err)
+		}
+
+		return tx.
+			Model(&Message{}).
+			Where("id IN ?", ids).
+			Updates(map[string]any{
+				"content":   "",
+				"is_hashed": true,
+			}).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	extIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		extIDs = append(extIDs, item.ExtID)
+	}
+
+	return extIDs, nil
+}
+
 func newRepository(db *gorm.DB) *repository {
 	return &repository{
 		db: db,
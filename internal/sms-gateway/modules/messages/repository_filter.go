@@ -20,6 +20,9 @@ type MessagesSelectFilter struct {
 	StartDate time.Time
 	EndDate   time.Time
 	State     ProcessingState
+	// PhoneNumber selects the messages to the recipient, both plain and
+	// hashed. Recipients of encrypted messages can't be matched.
+	PhoneNumber string
 }
 
 type MessagesSelectOptions struct {
@@ -0,0 +1,261 @@
+//go:build cgo
+
+package messages
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testSchema is the SQLite version of the tables used by the queue
+// reconciliation. SQLite skips the row locks of the repository.
+var testSchema = []string{
+	`CREATE TABLE devices (id TEXT PRIMARY KEY, user_id TEXT NOT NULL)`,
+	`CREATE TABLE messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		device_id TEXT NOT NULL,
+		user_id TEXT NOT NULL DEFAULT '',
+		ext_id TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'Text',
+		content TEXT NOT NULL DEFAULT '',
+		state TEXT NOT NULL DEFAULT 'Pending',
+		valid_until DATETIME,
+		sim_number INTEGER,
+		with_delivery_report INTEGER NOT NULL DEFAULT 0,
+		priority INTEGER NOT NULL DEFAULT 0,
+		is_hashed INTEGER NOT NULL DEFAULT 0,
+		is_encrypted INTEGER NOT NULL DEFAULT 0,
+		encryption_algorithm TEXT,
+		encryption_key_id TEXT,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		deleted_at DATETIME
+	)`,
+	`CREATE TABLE message_recipients (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id INTEGER NOT NULL,
+		phone_number TEXT NOT NULL,
+		state TEXT NOT NULL DEFAULT 'Pending',
+		error TEXT,
+		error_code TEXT,
+		country TEXT,
+		carrier TEXT,
+		UNIQUE (message_id, phone_number)
+	)`,
+	`CREATE TABLE message_states (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id INTEGER NOT NULL,
+		state TEXT NOT NULL,
+		updated_at DATETIME NOT NULL,
+		device_updated_at DATETIME,
+		received_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (message_id, state)
+	)`,
+}
+
+// The times are stored as text, so they are compared as strings. The stale
+// and the fresh messages are years away from now, so the comparison doesn't
+// depend on the local time zone.
+var (
+	staleTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	freshTime = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// testMessage is the message inserted by newTestRepository.
+type testMessage struct {
+	extID     string
+	deviceID  string
+	state     ProcessingState
+	updatedAt time.Time
+	hashed    bool
+}
+
+// newTestRepository returns the repository backed by the in-memory database
+// with the devices of the user and the messages with a single recipient each.
+func newTestRepository(t *testing.T, messages ...testMessage) *repository {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("can't open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("can't get database: %v", err)
+	}
+	// every connection opens its own in-memory database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	for _, stmt := range testSchema {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("can't create table: %v", err)
+		}
+	}
+	for _, id := range []string{"device-1", "device-2"} {
+		if err := db.Exec("INSERT INTO devices (id, user_id) VALUES (?, ?)", id, "user").Error; err != nil {
+			t.Fatalf("can't insert device: %v", err)
+		}
+	}
+	for _, m := range messages {
+		res := db.Exec(
+			"INSERT INTO messages (device_id, user_id, ext_id, state, is_hashed, updated_at) VALUES (?, ?, ?, ?, ?, ?)",
+			m.deviceID, "user", m.extID, m.state, m.hashed, m.updatedAt,
+		)
+		if res.Error != nil {
+			t.Fatalf("can't insert message: %v", res.Error)
+		}
+		if err := db.Exec(
+			"INSERT INTO message_recipients (message_id, phone_number, state) SELECT id, ?, state FROM messages WHERE ext_id = ?",
+			"+79990001234", m.extID,
+		).Error; err != nil {
+			t.Fatalf("can't insert recipient: %v", err)
+		}
+	}
+
+	return newRepository(db)
+}
+
+// state returns the state of the message and of its recipient.
+func (r *repository) state(t *testing.T, extID string) (ProcessingState, ProcessingState) {
+	t.Helper()
+
+	var message, recipient ProcessingState
+	err := r.db.Raw(
+		"SELECT m.state, r.state FROM messages m JOIN message_recipients r ON r.message_id = m.id WHERE m.ext_id = ?",
+		extID,
+	).Row().Scan(&message, &recipient)
+	if err != nil {
+		t.Fatalf("can't select state of %s: %v", extID, err)
+	}
+
+	return message, recipient
+}
+
+func TestRepository_ResolveStuckRequeue(t *testing.T) {
+	r := newTestRepository(t,
+		testMessage{extID: "stuck", deviceID: "device-1", state: ProcessingStateProcessed, updatedAt: staleTime},
+		testMessage{extID: "fresh", deviceID: "device-1", state: ProcessingStateProcessed, updatedAt: freshTime},
+		testMessage{extID: "hashed", deviceID: "device-1", state: ProcessingStateProcessed, updatedAt: staleTime, hashed: true},
+		testMessage{extID: "sent", deviceID: "device-1", state: ProcessingStateSent, updatedAt: staleTime},
+		testMessage{extID: "failed", deviceID: "device-1", state: ProcessingStateFailed, updatedAt: staleTime},
+		testMessage{extID: "other", deviceID: "device-2", state: ProcessingStateProcessed, updatedAt: staleTime},
+	)
+
+	affected, err := r.resolveStuck(context.Background(), "device-1", time.Now(), ProcessingStatePending, nil)
+	if err != nil {
+		t.Fatalf("resolveStuck() error = %v", err)
+	}
+
+	if len(affected) != 1 || affected[0].DeviceID != "device-1" || affected[0].UserID != "user" {
+		t.Fatalf("resolveStuck() = %+v, want the messages of device-1 of user", affected)
+	}
+	if len(affected[0].Messages) != 1 || affected[0].Messages[0].ExtID != "stuck" {
+		t.Fatalf("requeued messages = %+v, want the stuck one only", affected[0].Messages)
+	}
+	if recipients := affected[0].Messages[0].Recipients; len(recipients) != 1 {
+		t.Errorf("recipients of the requeued message = %d, want 1", len(recipients))
+	}
+
+	tests := map[string]ProcessingState{
+		"stuck":  ProcessingStatePending,
+		"fresh":  ProcessingStateProcessed,
+		"hashed": ProcessingStateProcessed,
+		"sent":   ProcessingStateSent,
+		"failed": ProcessingStateFailed,
+		"other":  ProcessingStateProcessed,
+	}
+	for extID, want := range tests {
+		message, recipient := r.state(t, extID)
+		if message != want || recipient != want {
+			t.Errorf("state of %s = %s, recipient %s, want %s", extID, message, recipient, want)
+		}
+	}
+
+	var states int64
+	if err := r.db.Table("message_states").Where("state = ?", ProcessingStatePending).Count(&states).Error; err != nil {
+		t.Fatal(err)
+	}
+	if states != 1 {
+		t.Errorf("pending state records = %d, want 1", states)
+	}
+}
+
+func TestRepository_CountPending(t *testing.T) {
+	r := newTestRepository(t,
+		testMessage{extID: "pending-1", deviceID: "device-1", state: ProcessingStatePending, updatedAt: staleTime},
+		testMessage{extID: "pending-2", deviceID: "device-1", state: ProcessingStatePending, updatedAt: freshTime},
+		testMessage{extID: "processed", deviceID: "device-1", state: ProcessingStateProcessed, updatedAt: staleTime},
+		testMessage{extID: "other", deviceID: "device-2", state: ProcessingStatePending, updatedAt: staleTime},
+	)
+
+	count, err := r.CountPending(context.Background(), "device-1")
+	if err != nil {
+		t.Fatalf("CountPending() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("CountPending() = %d, want 2", count)
+	}
+}
+
+// testMetrics are shared by the tests, the metrics are registered once.
+var testMetrics = newMetrics()
+
+func TestService_ReconcileQueue(t *testing.T) {
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		localPending int
+		want         QueueReconciliation
+	}{
+		{
+			name:         "Empty local queue",
+			timeout:      time.Minute,
+			localPending: 0,
+			want:         QueueReconciliation{LocalPending: 0, ServerPending: 2, Requeued: 1},
+		},
+		{
+			name:         "Messages held locally",
+			timeout:      time.Minute,
+			localPending: 3,
+			want:         QueueReconciliation{LocalPending: 3, ServerPending: 1, Requeued: 0},
+		},
+		{
+			name:         "Timeout disabled",
+			timeout:      0,
+			localPending: 0,
+			want:         QueueReconciliation{LocalPending: 0, ServerPending: 1, Requeued: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				config: Config{ProcessedTimeout: tt.timeout},
+				messages: newTestRepository(t,
+					testMessage{extID: "pending", deviceID: "device-1", state: ProcessingStatePending, updatedAt: freshTime},
+					testMessage{extID: "stuck", deviceID: "device-1", state: ProcessingStateProcessed, updatedAt: staleTime},
+					testMessage{extID: "hashed", deviceID: "device-1", state: ProcessingStateProcessed, updatedAt: staleTime, hashed: true},
+					testMessage{extID: "other", deviceID: "device-2", state: ProcessingStatePending, updatedAt: freshTime},
+				),
+				metrics: testMetrics,
+				logger:  zap.NewNop(),
+			}
+
+			got, err := s.ReconcileQueue(context.Background(), models.Device{ID: "device-1"}, tt.localPending)
+			if err != nil {
+				t.Fatalf("ReconcileQueue() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReconcileQueue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// SendWindow is the range of the local time of day when the messages may be
+// sent to the recipients of a country. The window wraps around midnight if
+// it ends before the start, e.g. 21:00-08:00.
+type SendWindow struct {
+	// Start is the opening time since midnight.
+	Start time.Duration
+	// End is the closing time since midnight.
+	End time.Duration
+}
+
+// ParseSendWindow parses the window in the "HH:MM-HH:MM" format.
+func ParseSendWindow(value string) (SendWindow, error) {
+	start, end, ok := strings.Cut(value, "-")
+	if !ok {
+		return SendWindow{}, fmt.Errorf("invalid send window %q: expected HH:MM-HH:MM", value)
+	}
+
+	window := SendWindow{}
+	for _, v := range []struct {
+		value  string
+		target *time.Duration
+	}{
+		{value: start, target: &window.Start},
+		{value: end, target: &window.End},
+	} {
+		t, err := time.Parse("15:04", strings.TrimSpace(v.value))
+		if err != nil {
+			return SendWindow{}, fmt.Errorf("invalid send window %q: %w", value, err)
+		}
+		*v.target = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	if window.Start == window.End {
+		return SendWindow{}, fmt.Errorf("invalid send window %q: empty range", value)
+	}
+
+	return window, nil
+}
+
+func (w SendWindow) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+
+	return format(w.Start) + "-" + format(w.End)
+}
+
+// Contains reports whether the wall clock time of t is within the window.
+func (w SendWindow) Contains(t time.Time) bool {
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	if w.Start < w.End {
+		return clock >= w.Start && clock < w.End
+	}
+
+	return clock >= w.Start || clock < w.End
+}
+
+// opensAt returns the next opening of the window after t in the location of t.
+func (w SendWindow) opensAt(t time.Time) time.Time {
+	return nextClock(w.Start, t)
+}
+
+// closesAt returns the next closing of the window after t in the location of
+// t.
+func (w SendWindow) closesAt(t time.Time) time.Time {
+	return nextClock(w.End, t)
+}
+
+// within reports whether the window lies within the other one, i.e. it only
+// narrows the other window.
+func (w SendWindow) within(other SendWindow) bool {
+	day := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for clock := time.Duration(0); clock < 24*time.Hour; clock += time.Minute {
+		t := day.Add(clock)
+		if w.Contains(t) && !other.Contains(t) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// nextClock returns the next time of day after t in the location of t.
+func nextClock(clock time.Duration, t time.Time) time.Time {
+	hour, minute := int(clock.Hours()), int(clock.Minutes())%60
+
+	next := time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location())
+	if !next.After(t) {
+		next = time.Date(t.Year(), t.Month(), t.Day()+1, hour, minute, 0, 0, t.Location())
+	}
+
+	return next
+}
+
+// SendWindowsConfig restricts the time of day when the messages are sent to
+// the recipients of the countries, in the local time of the recipient. Users
+// can narrow it in the "send_windows" settings group. The messages with
+// the priority at or above the bypass threshold, e.g. one-time codes, aren't
+// restricted.
+type SendWindowsConfig struct {
+	// Countries are the windows by the ISO 3166-1 alpha-2 country code.
+	Countries map[string]SendWindow
+}
+
+// withUserSettings returns the config overridden by the `send_windows.countries`
+// user setting, e.g. {"FR": "08:00-20:00"}. The user's window may only narrow
+// the window of the server config, so the windows beyond it, empty values
+// and invalid values are ignored.
+func (c SendWindowsConfig) withUserSettings(settings map[string]any) SendWindowsConfig {
+	group, _ := settings["send_windows"].(map[string]any)
+	countries, ok := group["countries"].(map[string]any)
+	if !ok {
+		return c
+	}
+
+	merged := make(map[string]SendWindow, len(c.Countries)+len(countries))
+	for country, window := range c.Countries {
+		merged[country] = window
+	}
+
+	for country, value := range countries {
+		value, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		country = strings.ToUpper(country)
+		window, err := ParseSendWindow(value)
+		if err != nil {
+			continue
+		}
+
+		if floor, ok := c.Countries[country]; ok && !window.within(floor) {
+			continue
+		}
+		merged[country] = window
+	}
+
+	return SendWindowsConfig{Countries: merged}
+}
+
+// check returns ErrSendWindow if any of the recipients is outside the window
+// of its country at the time. Otherwise it returns the earliest closing of the
+// windows of the recipients, zero if none of them is restricted. The
+// recipients of the unknown countries or time zones aren't restricted.
+func (c SendWindowsConfig) check(phoneNumbers []string, now time.Time) (time.Time, error) {
+	closesAt := time.Time{}
+	if len(c.Countries) == 0 {
+		return closesAt, nil
+	}
+
+	for _, phoneNumber := range phoneNumbers {
+		info, ok := DetectRecipient(phoneNumber)
+		if !ok {
+			continue
+		}
+
+		closes, err := c.checkRecipient(info.Country, recipientLocations(phoneNumber), now)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if !closes.IsZero() && (closesAt.IsZero() || closes.Before(closesAt)) {
+			closesAt = closes
+		}
+	}
+
+	return closesAt, nil
+}
+
+// checkRecipient checks the window of the country in each of the time zones
+// of the recipient, the numbers of some countries span several zones. It
+// returns the earliest closing of the window in these zones.
+func (c SendWindowsConfig) checkRecipient(country string, locations []*time.Location, now time.Time) (time.Time, error) {
+	closesAt := time.Time{}
+
+	window, ok := c.Countries[country]
+	if !ok {
+		return closesAt, nil
+	}
+
+	for _, loc := range locations {
+		local := now.In(loc)
+		if !window.Contains(local) {
+			return time.Time{}, ErrSendWindow{
+				Country: country,
+				Window:  window,
+				OpensAt: window.opensAt(local),
+			}
+		}
+
+		if closes := window.closesAt(local); closesAt.IsZero() || closes.Before(closesAt) {
+			closesAt = closes
+		}
+	}
+
+	return closesAt, nil
+}
+
+// recipientLocations returns the time zones of the phone number in the
+// international format, empty if unknown.
+func recipientLocations(phoneNumber string) []*time.Location {
+	phone, err := phonenumbers.Parse(phoneNumber, "")
+	if err != nil {
+		return nil
+	}
+
+	zones, err := phonenumbers.GetTimezonesForNumber(phone)
+	if err != nil {
+		return nil
+	}
+
+	locations := make([]*time.Location, 0, len(zones))
+	for _, zone := range zones {
+		// the unknown zone isn't in the database
+		if loc, err := time.LoadLocation(zone); err == nil {
+			locations = append(locations, loc)
+		}
+	}
+
+	return locations
+}
@@ -0,0 +1,186 @@
+package messages
+
+import (
+	"errors"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestParseSendWindow(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    SendWindow
+		wantErr bool
+	}{
+		{value: "09:00-20:30", want: SendWindow{Start: 9 * time.Hour, End: 20*time.Hour + 30*time.Minute}},
+		{value: "21:00 - 08:00", want: SendWindow{Start: 21 * time.Hour, End: 8 * time.Hour}},
+		{value: "09:00", wantErr: true},
+		{value: "9am-8pm", wantErr: true},
+		{value: "25:00-08:00", wantErr: true},
+		{value: "09:00-09:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSendWindow(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSendWindow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSendWindow() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (SendWindow{Start: 9 * time.Hour, End: 20*time.Hour + 30*time.Minute}).String(); got != "09:00-20:30" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestSendWindow_Contains(t *testing.T) {
+	day := SendWindow{Start: 9 * time.Hour, End: 20 * time.Hour}
+	night := SendWindow{Start: 21 * time.Hour, End: 8 * time.Hour}
+
+	tests := []struct {
+		clock     string
+		day       bool
+		overnight bool
+	}{
+		{clock: "08:59:59", day: false, overnight: false},
+		{clock: "09:00:00", day: true, overnight: false},
+		{clock: "19:59:59", day: true, overnight: false},
+		{clock: "20:00:00", day: false, overnight: false},
+		{clock: "23:30:00", day: false, overnight: true},
+		{clock: "07:59:00", day: false, overnight: true},
+	}
+
+	for _, tt := range tests {
+		now, err := time.Parse("2006-01-02 15:04:05", "2025-11-03 "+tt.clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := day.Contains(now); got != tt.day {
+			t.Errorf("%s: day.Contains() = %v, want %v", tt.clock, got, tt.day)
+		}
+		if got := night.Contains(now); got != tt.overnight {
+			t.Errorf("%s: night.Contains() = %v, want %v", tt.clock, got, tt.overnight)
+		}
+	}
+}
+
+func TestSendWindowsConfig_withUserSettings(t *testing.T) {
+	def := SendWindowsConfig{Countries: map[string]SendWindow{
+		"FR": {Start: 8 * time.Hour, End: 20 * time.Hour},
+		"DE": {Start: 9 * time.Hour, End: 18 * time.Hour},
+		"IT": {Start: 8 * time.Hour, End: 20 * time.Hour},
+	}}
+
+	got := def.withUserSettings(map[string]any{
+		"send_windows": map[string]any{
+			"countries": map[string]any{
+				"fr": "10:00-19:00",
+				"DE": "",
+				"IT": "07:00-21:00",
+				"ES": "09:00-21:00",
+				"US": "invalid",
+				"GB": float64(9),
+			},
+		},
+	})
+
+	// the server windows may only be narrowed
+	want := map[string]SendWindow{
+		"FR": {Start: 10 * time.Hour, End: 19 * time.Hour},
+		"DE": {Start: 9 * time.Hour, End: 18 * time.Hour},
+		"IT": {Start: 8 * time.Hour, End: 20 * time.Hour},
+		"ES": {Start: 9 * time.Hour, End: 21 * time.Hour},
+	}
+	if !maps.Equal(got.Countries, want) {
+		t.Errorf("withUserSettings() = %+v, want %+v", got.Countries, want)
+	}
+	if len(def.Countries) != 3 {
+		t.Error("server config is modified")
+	}
+
+	if got := def.withUserSettings(nil); !maps.Equal(got.Countries, def.Countries) {
+		t.Errorf("withUserSettings() without settings = %+v", got.Countries)
+	}
+}
+
+func TestSendWindow_within(t *testing.T) {
+	tests := []struct {
+		window, other string
+		want          bool
+	}{
+		{window: "10:00-19:00", other: "08:00-20:00", want: true},
+		{window: "08:00-20:00", other: "08:00-20:00", want: true},
+		{window: "07:00-20:00", other: "08:00-20:00", want: false},
+		{window: "22:00-06:00", other: "21:00-08:00", want: true},
+		{window: "21:00-08:00", other: "22:00-06:00", want: false},
+		{window: "09:00-12:00", other: "21:00-08:00", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.window+" in "+tt.other, func(t *testing.T) {
+			window, _ := ParseSendWindow(tt.window)
+			other, _ := ParseSendWindow(tt.other)
+			if got := window.within(other); got != tt.want {
+				t.Errorf("within() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendWindowsConfig_checkRecipient(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	config := SendWindowsConfig{Countries: map[string]SendWindow{
+		"FR": {Start: 8 * time.Hour, End: 20 * time.Hour},
+		"US": {Start: 9 * time.Hour, End: 21 * time.Hour},
+	}}
+
+	// 07:00 in Paris, 01:00 in New York
+	now := time.Date(2025, 11, 3, 6, 0, 0, 0, time.UTC)
+
+	_, err = config.checkRecipient("FR", []*time.Location{paris}, now)
+	errSendWindow := ErrSendWindow{}
+	if !errors.As(err, &errSendWindow) {
+		t.Fatalf("checkRecipient() error = %v, want ErrSendWindow", err)
+	}
+	if want := time.Date(2025, 11, 3, 8, 0, 0, 0, paris); !errSendWindow.OpensAt.Equal(want) {
+		t.Errorf("OpensAt = %s, want %s", errSendWindow.OpensAt, want)
+	}
+
+	// 09:00 in Paris
+	closesAt, err := config.checkRecipient("FR", []*time.Location{paris}, now.Add(2*time.Hour))
+	if err != nil {
+		t.Errorf("checkRecipient() within the window error = %v", err)
+	}
+	if want := time.Date(2025, 11, 3, 20, 0, 0, 0, paris); !closesAt.Equal(want) {
+		t.Errorf("checkRecipient() closes at %s, want %s", closesAt, want)
+	}
+
+	// the window is closed in one of the zones, 21:00 in Paris and 15:00 in New York
+	evening := time.Date(2025, 11, 3, 20, 0, 0, 0, time.UTC)
+	if _, err := config.checkRecipient("US", []*time.Location{newYork, paris}, evening); err == nil {
+		t.Error("checkRecipient() in the closed zone error = nil")
+	} else if errors.As(err, &errSendWindow) && !errSendWindow.OpensAt.Equal(time.Date(2025, 11, 4, 9, 0, 0, 0, paris)) {
+		t.Errorf("OpensAt = %s, want the next day", errSendWindow.OpensAt)
+	}
+
+	if closesAt, err := config.checkRecipient("DE", []*time.Location{paris}, now); err != nil || !closesAt.IsZero() {
+		t.Errorf("checkRecipient() without window = %s, %v", closesAt, err)
+	}
+	if closesAt, err := config.checkRecipient("FR", nil, now); err != nil || !closesAt.IsZero() {
+		t.Errorf("checkRecipient() without zones = %s, %v", closesAt, err)
+	}
+}
@@ -2,9 +2,10 @@ package messages
 
 import (
 	"context"
-	"crypto/sha256"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,22 +13,49 @@ import (
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sims"
+	"github.com/android-sms-gateway/server/pkg/cache"
 	"github.com/capcom6/go-helpers/anys"
 	"github.com/capcom6/go-helpers/slices"
 	"github.com/nyaruka/phonenumbers"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
-	"golang.org/x/exp/maps"
 )
 
 const (
-	ErrorTTLExpired = "TTL expired"
+	ErrorTTLExpired        = "TTL expired"
+	ErrorProcessingTimeout = "Processing timeout"
 )
 
+// Simulator processes the messages enqueued to sandbox devices instead of
+// the real ones. The messages stay Pending until they're simulated, so the
+// simulator picks them up from the database after a restart.
+type Simulator interface {
+	// Admit reports whether one more message of the sandbox device can be
+	// accepted for the simulation.
+	Admit(device models.Device) bool
+	// Simulate schedules the processing of the pending messages of the
+	// sandbox device.
+	Simulate(device models.Device)
+}
+
+// Relayer forwards the messages which can't be served by the devices of the
+// user to the upstream instance.
+type Relayer interface {
+	Relay(device models.Device, message MessageOut)
+}
+
 type EnqueueOptions struct {
 	SkipPhoneValidation bool
+	// TrackLinks replaces URLs in the text with short links counting clicks
+	TrackLinks bool
+	// Relay forwards the message to the upstream instance instead of the device
+	Relay bool
+	// TraceID is the trace ID of the request, the notification of the device
+	// is linked to it
+	TraceID string
 }
 
 type ServiceParams struct {
@@ -39,10 +67,18 @@ type ServiceParams struct {
 
 	Messages    *repository
 	HashingTask *HashingTask
+	StuckTask   *StuckTask
+	HealthTask  *HealthTask
 
-	EventsSvc *events.Service
+	EventsSvc   *events.Service
+	SettingsSvc *settings.Service
+	LinksSvc    *links.Service
+	SimsSvc     *sims.Service
 
-	Logger *zap.Logger
+	Cache cache.Cache
+
+	Metrics *metrics
+	Logger  *zap.Logger
 }
 
 type Service struct {
@@ -50,59 +86,120 @@ type Service struct {
 
 	messages    *repository
 	hashingTask *HashingTask
+	stuckTask   *StuckTask
+	healthTask  *HealthTask
+
+	eventsSvc   *events.Service
+	settingsSvc *settings.Service
+	linksSvc    *links.Service
+	simsSvc     *sims.Service
+
+	cache cache.Cache
 
-	eventsSvc *events.Service
+	contentPolicy *contentPolicy
 
-	logger *zap.Logger
+	simulator Simulator
+	relayer   Relayer
 
-	messagesCounter *prometheus.CounterVec
+	metrics *metrics
+	logger  *zap.Logger
 
 	idgen func() string
 }
 
 func NewService(params ServiceParams) *Service {
-	messagesCounter := promauto.NewCounterVec(prometheus.CounterOpts{
-		Namespace: "sms",
-		Subsystem: "messages",
-		Name:      "total",
-		Help:      "Total number of messages by state",
-	}, []string{"state"})
-
 	return &Service{
 		config: params.Config,
 
 		messages:    params.Messages,
 		hashingTask: params.HashingTask,
+		stuckTask:   params.StuckTask,
+		healthTask:  params.HealthTask,
+
+		eventsSvc:   params.EventsSvc,
+		settingsSvc: params.SettingsSvc,
+		linksSvc:    params.LinksSvc,
+		simsSvc:     params.SimsSvc,
 
-		eventsSvc: params.EventsSvc,
+		cache: params.Cache,
 
-		logger: params.Logger.Named("Service"),
+		contentPolicy: newContentPolicy(params.Config.ContentPolicy),
 
-		messagesCounter: messagesCounter,
+		metrics: params.Metrics,
+		logger:  params.Logger.Named("Service"),
 
 		idgen: params.IDGen,
 	}
 }
 
+// SetSimulator sets the processor of the messages enqueued to sandbox
+// devices.
+func (s *Service) SetSimulator(simulator Simulator) {
+	s.simulator = simulator
+}
+
+// SetRelayer sets the forwarder of the messages to the upstream instance.
+func (s *Service) SetRelayer(relayer Relayer) {
+	s.relayer = relayer
+}
+
 func (s *Service) RunBackgroundTasks(ctx context.Context, wg *sync.WaitGroup) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		s.hashingTask.Run(ctx)
 	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.stuckTask.Run(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.healthTask.Run(ctx)
+	}()
 }
 
-func (s *Service) SelectPending(deviceID string, order MessagesOrder) ([]MessageOut, error) {
+func (s *Service) SelectPending(deviceID string, order MessagesOrder) ([]MessageOut, int64, error) {
 	if order == "" {
 		order = MessagesOrderLIFO
 	}
 
-	messages, err := s.messages.SelectPending(deviceID, order)
+	messages, total, err := s.messages.SelectPending(deviceID, order)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out, err := slices.MapOrError(messages, messageToDomain)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return slices.MapOrError(messages, messageToDomain)
+	return out, total, nil
+}
+
+// NextPollIn returns the recommended delay before the device polls for
+// pending messages again. It takes into account the number of messages left
+// in the queue after the current batch and the availability of push
+// notifications for the device.
+func (s *Service) NextPollIn(device models.Device, selected int, total int64) time.Duration {
+	if total > int64(selected) {
+		// more messages are waiting, poll immediately
+		return 0
+	}
+
+	if selected > 0 {
+		return s.config.Polling.ActiveInterval
+	}
+
+	if device.CanPush() {
+		return s.config.Polling.IdlePushInterval
+	}
+
+	return s.config.Polling.IdleInterval
 }
 
 func (s *Service) UpdateState(deviceID string, message MessageStateIn) error {
@@ -115,14 +212,34 @@ func (s *Service) UpdateState(deviceID string, message MessageStateIn) error {
 		message.State = ProcessingStateProcessed
 	}
 
-	existing.State = message.State
-	existing.States = slices.Map(maps.Keys(message.States), func(key string) MessageState {
-		return MessageState{
-			MessageID: existing.ID,
-			State:     ProcessingState(key),
-			UpdatedAt: message.States[key],
+	now := time.Now()
+	states := make([]MessageState, 0, len(message.States))
+	for key, deviceTime := range message.States {
+		updatedAt, ok := s.config.ClockSkew.normalize(deviceTime, existing.CreatedAt, now)
+		if !ok {
+			if s.config.ClockSkew.Policy == ClockSkewPolicyReject {
+				return ErrValidation(fmt.Sprintf("time %s of state %s is out of the allowed range", deviceTime.Format(time.RFC3339), key))
+			}
+
+			s.logger.Warn("State time is out of range, clamping",
+				zap.String("device_id", deviceID),
+				zap.String("message_id", message.ID),
+				zap.String("state", key),
+				zap.Time("device_time", deviceTime),
+				zap.Time("stored_time", updatedAt),
+			)
 		}
-	})
+
+		states = append(states, MessageState{
+			MessageID:       existing.ID,
+			State:           ProcessingState(key),
+			UpdatedAt:       updatedAt,
+			DeviceUpdatedAt: anys.AsPointer(deviceTime),
+		})
+	}
+
+	existing.State = message.State
+	existing.States = states
 	existing.Recipients = s.recipientsStateToModel(message.Recipients, existing.IsHashed)
 
 	if err := s.messages.UpdateState(&existing); err != nil {
@@ -131,11 +248,26 @@ func (s *Service) UpdateState(deviceID string, message MessageStateIn) error {
 
 	s.hashingTask.Enqueue(existing.ID)
 
-	s.messagesCounter.WithLabelValues(string(existing.State)).Inc()
+	s.metrics.IncrementState(existing.State)
 
 	return nil
 }
 
+// UpdateStates applies the batch of state updates reported by the device.
+// Each update is applied atomically and independently, so a failed update
+// doesn't affect the others and can be safely retried. It returns the
+// results of the failed updates only.
+func (s *Service) UpdateStates(deviceID string, updates []MessageStateIn) []StateUpdateResult {
+	failed := []StateUpdateResult{}
+	for i, update := range updates {
+		if err := s.UpdateState(deviceID, update); err != nil {
+			failed = append(failed, StateUpdateResult{Index: i, ID: update.ID, Err: err})
+		}
+	}
+
+	return failed
+}
+
 func (s *Service) SelectStates(user models.User, filter MessagesSelectFilter, options MessagesSelectOptions) ([]MessageStateOut, int64, error) {
 	filter.UserID = user.ID
 
@@ -188,17 +320,12 @@ func (s *Service) Enqueue(device models.Device, message MessageIn, opts EnqueueO
 		},
 	}
 
-	var phone string
-	var err error
-	for i, v := range message.PhoneNumbers {
-		if message.IsEncrypted || opts.SkipPhoneValidation {
-			phone = v
-		} else {
-			if phone, err = cleanPhoneNumber(v); err != nil {
-				return state, fmt.Errorf("can't use phone in row %d: %w", i+1, err)
-			}
-		}
+	phoneNumbers, err := s.normalizePhoneNumbers(message, opts)
+	if err != nil {
+		return state, err
+	}
 
+	for i, phone := range phoneNumbers {
 		message.PhoneNumbers[i] = phone
 
 		state.Recipients[i] = smsgateway.RecipientState{
@@ -207,9 +334,30 @@ func (s *Service) Enqueue(device models.Device, message MessageIn, opts EnqueueO
 		}
 	}
 
-	validUntil := message.ValidUntil
-	if message.TTL != nil && *message.TTL > 0 {
-		validUntil = anys.AsPointer(time.Now().Add(time.Duration(*message.TTL) * time.Second))
+	if warnings := s.CheckContent(message); len(warnings) > 0 && s.contentPolicy.Blocking() {
+		return state, ErrContentPolicy{Warnings: warnings}
+	}
+
+	if message.Encryption != nil && !message.IsEncrypted {
+		return state, ErrValidation("encryption metadata is allowed only for encrypted messages")
+	}
+
+	if opts.TrackLinks {
+		if message.TextContent == nil || message.IsEncrypted {
+			return state, ErrValidation("links can be tracked only in plain text messages")
+		}
+		if !s.linksSvc.Enabled() {
+			return state, ErrValidation("link tracking is disabled on the server")
+		}
+	}
+
+	if device.Sandbox && s.simulator != nil && !s.simulator.Admit(device) {
+		return state, ErrSimulatorBusy
+	}
+
+	validUntil, err := s.config.TTL.validUntil(message, time.Now())
+	if err != nil {
+		return state, err
 	}
 
 	msg := Message{
@@ -218,6 +366,7 @@ func (s *Service) Enqueue(device models.Device, message MessageIn, opts EnqueueO
 		IsEncrypted: message.IsEncrypted,
 
 		DeviceID: device.ID,
+		UserID:   device.UserID,
 
 		SimNumber:          message.SimNumber,
 		WithDeliveryReport: anys.OrDefault(message.WithDeliveryReport, true),
@@ -225,6 +374,10 @@ func (s *Service) Enqueue(device models.Device, message MessageIn, opts EnqueueO
 		Priority:   int8(message.Priority),
 		ValidUntil: validUntil,
 	}
+	if message.Encryption != nil {
+		msg.EncryptionAlgorithm = &message.Encryption.Algorithm
+		msg.EncryptionKeyID = &message.Encryption.KeyID
+	}
 
 	if message.TextContent != nil {
 		if err := msg.SetTextContent(*message.TextContent); err != nil {
@@ -243,21 +396,346 @@ func (s *Service) Enqueue(device models.Device, message MessageIn, opts EnqueueO
 	}
 	state.ID = msg.ExtID
 
-	if err := s.messages.Insert(&msg); err != nil {
+	userSettings, err := s.settingsSvc.GetSettings(device.UserID, true)
+	if err != nil {
+		s.logger.Warn("can't get user settings", zap.String("user_id", device.UserID), zap.Error(err))
+	}
+
+	if int(msg.Priority) < fairQueueBypassPriority {
+		closesAt, err := s.config.SendWindows.withUserSettings(userSettings).check(phoneNumbers, time.Now())
+		if err != nil {
+			return state, err
+		}
+
+		// the message isn't sent after the window closes, e.g. by a device
+		// which is offline till then
+		if !closesAt.IsZero() && (msg.ValidUntil == nil || closesAt.Before(*msg.ValidUntil)) {
+			msg.ValidUntil = &closesAt
+		}
+	}
+
+	msg.SimNumber, err = s.simsSvc.Route(device, msg.SimNumber, userSettings)
+	if errors.Is(err, sims.ErrPaused) {
+		return state, ErrValidation(err.Error())
+	}
+	if err != nil {
+		return state, fmt.Errorf("can't route message: %w", err)
+	}
+
+	dedupKey, original := s.findDuplicate(s.config.Dedup.withUserSettings(userSettings), device, msg, phoneNumbers)
+	if original != nil {
+		s.logger.Info("duplicate message skipped", zap.String("user_id", device.UserID), zap.String("message_id", msg.ExtID), zap.String("duplicate_of", original.ID))
+		original.DuplicateOf = original.ID
+		return *original, nil
+	}
+
+	burstSlot := prioritySlot{}
+	if int(msg.Priority) >= fairQueueBypassPriority {
+		var ok bool
+		burstSlot, ok = s.acquirePriorityBurst(priorityBurstFromSettings(userSettings), device.UserID)
+		if !ok {
+			s.logger.Info("priority burst budget exceeded", zap.String("user_id", device.UserID), zap.String("message_id", msg.ExtID))
+			s.metrics.IncrementPriorityDowngraded()
+			msg.Priority = fairQueueBypassPriority - 1
+		}
+	}
+
+	var shortLinks []links.ShortLink
+	if opts.TrackLinks {
+		var text string
+		text, shortLinks, err = s.linksSvc.Shorten(device.UserID, msg.ExtID, message.TextContent.Text)
+		if err == nil {
+			err = msg.SetTextContent(TextMessageContent{Text: text})
+		}
+		if err != nil {
+			s.release(dedupKey, burstSlot)
+			return state, fmt.Errorf("can't track links: %w", err)
+		}
+	}
+
+	// relayed messages are processed by the upstream instance, so the device
+	// doesn't receive them
+	relay := opts.Relay && s.relayer != nil && !device.Sandbox
+	if relay {
+		msg.State = ProcessingStateProcessed
+		state.State = ProcessingStateProcessed
+	}
+
+	if err := s.messages.Insert(&msg, shortLinks); err != nil {
+		s.release(dedupKey, burstSlot)
 		return state, err
 	}
 
-	s.messagesCounter.WithLabelValues(string(state.State)).Inc()
+	s.metrics.IncrementState(state.State)
 
-	go func(userID, deviceID string) {
-		if err := s.eventsSvc.Notify(userID, &deviceID, events.NewMessageEnqueuedEvent()); err != nil {
+	if device.Sandbox {
+		if s.simulator != nil {
+			s.simulator.Simulate(device)
+		}
+		return state, nil
+	}
+
+	if relay {
+		out, err := messageToDomain(msg)
+		if err != nil {
+			return state, fmt.Errorf("can't relay message: %w", err)
+		}
+		s.relayer.Relay(device, out)
+		return state, nil
+	}
+
+	go func(userID, deviceID, traceID string) {
+		if err := s.eventsSvc.Notify(userID, &deviceID, events.NewMessageEnqueuedEvent().WithTraceID(traceID)); err != nil {
 			s.logger.Error("can't notify device", zap.Error(err), zap.String("user_id", userID), zap.String("device_id", deviceID))
 		}
-	}(device.UserID, device.ID)
+	}(device.UserID, device.ID, opts.TraceID)
 
 	return state, nil
 }
 
+// findDuplicate registers the message in the dedup window of the user. If an
+// equal message was enqueued within the window, it returns the state of that
+// message. The original message registered by a concurrent request may be not
+// stored yet, so it's looked up several times; if it's still missing, it was
+// deleted and the message takes its place in the window. The returned key
+// must be released if the message is not stored; it is empty if the dedup is
+// disabled.
+func (s *Service) findDuplicate(config DedupConfig, device models.Device, msg Message, phoneNumbers []string) (string, *MessageStateOut) {
+	ctx := context.Background()
+
+	if !config.Enabled() {
+		return "", nil
+	}
+
+	logger := s.logger.With(zap.String("user_id", device.UserID))
+	key := dedupKey(config.Strategy, device.UserID, msg, phoneNumbers)
+	value := msg.DeviceID + "/" + msg.ExtID
+
+	for attempt := range dedupAttempts {
+		if attempt > 0 {
+			time.Sleep(dedupRetryDelay)
+		}
+
+		err := s.cache.SetOrFail(ctx, key, value, cache.WithTTL(config.Window))
+		if err == nil {
+			return key, nil
+		}
+		if !errors.Is(err, cache.ErrKeyExists) {
+			logger.Warn("can't register message for dedup", zap.Error(err))
+			return "", nil
+		}
+
+		registered, err := s.cache.Get(ctx, key)
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			// the window has just expired or the original message wasn't
+			// stored
+			continue
+		}
+		if err != nil {
+			logger.Warn("can't get message registered for dedup", zap.Error(err))
+			return "", nil
+		}
+
+		deviceID, extID, _ := strings.Cut(registered, "/")
+		original, err := s.messages.Get(
+			MessagesSelectFilter{ExtID: extID, DeviceID: deviceID, UserID: device.UserID},
+			MessagesSelectOptions{WithRecipients: true, WithDevice: true, WithStates: true},
+		)
+		if err == nil {
+			state := modelToMessageState(original)
+			return "", &state
+		}
+		if !errors.Is(err, ErrMessageNotFound) {
+			logger.Warn("can't get original message", zap.Error(err))
+			return "", nil
+		}
+	}
+
+	if err := s.cache.Set(ctx, key, value, cache.WithTTL(config.Window)); err != nil {
+		logger.Warn("can't register message for dedup", zap.Error(err))
+		return "", nil
+	}
+
+	return key, nil
+}
+
+// prioritySlot is the slot of the priority burst budget taken by the message.
+type prioritySlot struct {
+	// key of the counter of the period, empty if no slot is taken
+	key        string
+	validUntil time.Time
+}
+
+// acquirePriorityBurst takes a slot of the priority burst budget of the user
+// in the current period. The slots are counted by a single cache counter per
+// period, so concurrent requests can't exceed the budget. It returns the slot,
+// which must be released if the message is not stored, and false if the
+// budget is exhausted. Unlimited budget always succeeds with an empty slot.
+func (s *Service) acquirePriorityBurst(burst PriorityBurst, userID string) (prioritySlot, bool) {
+	if !burst.Enabled() {
+		return prioritySlot{}, true
+	}
+
+	window := time.Now().Truncate(burst.Period)
+	slot := prioritySlot{
+		key:        "burst:" + userID + ":" + strconv.FormatInt(window.Unix(), 10),
+		validUntil: window.Add(burst.Period),
+	}
+
+	count, err := s.cache.Increment(context.Background(), slot.key, 1, cache.WithValidUntil(slot.validUntil))
+	if err != nil {
+		// don't delay the message because of the cache failure
+		s.logger.Warn("can't acquire priority burst slot", zap.String("user_id", userID), zap.Error(err))
+		return prioritySlot{}, true
+	}
+	if count > int64(burst.Value) {
+		// the exceeding request doesn't hold a slot, so the released ones
+		// can be taken again
+		s.releasePriorityBurst(slot)
+		return prioritySlot{}, false
+	}
+
+	return slot, true
+}
+
+// releasePriorityBurst returns the slot taken by acquirePriorityBurst. The
+// expiration is set in case the counter has expired meanwhile.
+func (s *Service) releasePriorityBurst(slot prioritySlot) {
+	if _, err := s.cache.Decrement(context.Background(), slot.key, 1, cache.WithValidUntil(slot.validUntil)); err != nil {
+		s.logger.Warn("can't release priority burst slot", zap.String("key", slot.key), zap.Error(err))
+	}
+}
+
+// release removes the dedup key and returns the priority burst slot
+// registered for a message that wasn't stored. Empty keys are skipped.
+func (s *Service) release(dedupKey string, burstSlot prioritySlot) {
+	if dedupKey != "" {
+		if err := s.cache.Delete(context.Background(), dedupKey); err != nil {
+			s.logger.Warn("can't release cache key", zap.String("key", dedupKey), zap.Error(err))
+		}
+	}
+	if burstSlot.key != "" {
+		s.releasePriorityBurst(burstSlot)
+	}
+}
+
+// Preview validates the message without enqueueing it. It returns normalized
+// phone numbers and content policy warnings.
+func (s *Service) Preview(message MessageIn, opts EnqueueOptions) (MessagePreview, error) {
+	phoneNumbers, err := s.normalizePhoneNumbers(message, opts)
+	if err != nil {
+		return MessagePreview{}, err
+	}
+
+	if message.TextContent == nil && message.DataContent == nil {
+		return MessagePreview{}, ErrValidation("no text or data content")
+	}
+
+	if _, err := s.config.TTL.validUntil(message, time.Now()); err != nil {
+		return MessagePreview{}, err
+	}
+
+	warnings := s.CheckContent(message)
+
+	return MessagePreview{
+		PhoneNumbers: phoneNumbers,
+		Warnings:     warnings,
+		Blocked:      len(warnings) > 0 && s.contentPolicy.Blocking(),
+	}, nil
+}
+
+// ReconcileQueue handles the local queue depth reported by the device. If the
+// device holds no messages locally, messages stuck in the Processed state
+// longer than the configured timeout are returned to the Pending state so they
+// will be delivered to the device again.
+func (s *Service) ReconcileQueue(ctx context.Context, device models.Device, localPending int) (QueueReconciliation, error) {
+	s.metrics.ObserveDeviceQueueDepth(localPending)
+
+	result := QueueReconciliation{
+		LocalPending: localPending,
+	}
+
+	if localPending == 0 && s.config.ProcessedTimeout > 0 {
+		affected, err := s.messages.resolveStuck(ctx, device.ID, time.Now().Add(-s.config.ProcessedTimeout), ProcessingStatePending, nil)
+		if err != nil {
+			return result, fmt.Errorf("can't requeue stuck messages: %w", err)
+		}
+
+		for _, v := range affected {
+			result.Requeued += int64(len(v.Messages))
+		}
+		if result.Requeued > 0 {
+			s.metrics.AddStuck(StuckActionRequeued, result.Requeued)
+			s.logger.Info("Requeued stuck messages",
+				zap.String("device_id", device.ID),
+				zap.Int64("count", result.Requeued),
+			)
+		}
+	}
+
+	pending, err := s.messages.CountPending(ctx, device.ID)
+	if err != nil {
+		return result, fmt.Errorf("can't count pending messages: %w", err)
+	}
+	result.ServerPending = pending
+
+	return result, nil
+}
+
+// CountPending returns the number of pending messages of the devices, keyed
+// by device ID. Devices without pending messages are omitted.
+func (s *Service) CountPending(ctx context.Context, deviceIDs []string) (map[string]int64, error) {
+	if len(deviceIDs) == 0 {
+		return map[string]int64{}, nil
+	}
+
+	counts, err := s.messages.CountPendingByDevices(ctx, deviceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("can't count pending messages: %w", err)
+	}
+
+	return counts, nil
+}
+
+// SelectPendingSandbox returns the sandbox devices with pending messages.
+func (s *Service) SelectPendingSandbox(ctx context.Context) ([]models.Device, error) {
+	devices, err := s.messages.SelectPendingSandbox(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't select sandbox devices: %w", err)
+	}
+
+	return devices, nil
+}
+
+// CheckContent checks the message text against the content policy and returns
+// found violations. Encrypted and data messages are not checked.
+func (s *Service) CheckContent(message MessageIn) []ContentWarning {
+	if message.IsEncrypted || message.TextContent == nil {
+		return nil
+	}
+
+	return s.contentPolicy.Check(message.TextContent.Text)
+}
+
+// RouteByCountry narrows the devices to the ones assigned to the country of
+// the recipients in the `routing.countries` user setting.
+func (s *Service) RouteByCountry(userID string, phoneNumbers []string, devices []models.Device) []models.Device {
+	userSettings, err := s.settingsSvc.GetSettings(userID, true)
+	if err != nil {
+		s.logger.Warn("can't get user settings", zap.String("user_id", userID), zap.Error(err))
+		return devices
+	}
+
+	return routeByCountry(countryRoutesFromSettings(userSettings), phoneNumbers, devices)
+}
+
+// FilterHealthy excludes the devices with a high recent failure rate, e.g.
+// because of a blocked SIM. If none of the devices is healthy, they are
+// returned as is.
+func (s *Service) FilterHealthy(devices []models.Device) []models.Device {
+	return s.healthTask.Filter(devices)
+}
+
 func (s *Service) ExportInbox(device models.Device, since, until time.Time) error {
 	event := events.NewMessagesExportRequestedEvent(since, until)
 
@@ -269,11 +747,34 @@ func (s *Service) Clean(ctx context.Context) error {
 	n, err := s.messages.removeProcessed(ctx, time.Now().Add(-s.config.ProcessedLifetime))
 
 	s.logger.Info("Cleaned processed messages", zap.Int64("count", n))
-	return err
+
+	syncs, syncsErr := s.messages.removeSyncs(ctx, time.Now().Add(-syncLogLifetime))
+
+	s.logger.Info("Cleaned message syncs", zap.Int64("count", syncs))
+	return errors.Join(err, syncsErr)
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 
+func (s *Service) normalizePhoneNumbers(message MessageIn, opts EnqueueOptions) ([]string, error) {
+	output := make([]string, len(message.PhoneNumbers))
+
+	for i, v := range message.PhoneNumbers {
+		if message.IsEncrypted || opts.SkipPhoneValidation {
+			output[i] = v
+			continue
+		}
+
+		phone, err := cleanPhoneNumber(v)
+		if err != nil {
+			return nil, fmt.Errorf("can't use phone in row %d: %w", i+1, err)
+		}
+		output[i] = phone
+	}
+
+	return output, nil
+}
+
 func (s *Service) recipientsToModel(input []string) []MessageRecipient {
 	output := make([]MessageRecipient, len(input))
 
@@ -281,6 +782,12 @@ func (s *Service) recipientsToModel(input []string) []MessageRecipient {
 		output[i] = MessageRecipient{
 			PhoneNumber: v,
 		}
+		if info, ok := DetectRecipient(v); ok {
+			output[i].Country = &info.Country
+			if info.Carrier != "" {
+				output[i].Carrier = &info.Carrier
+			}
+		}
 	}
 
 	return output
@@ -301,13 +808,14 @@ func (s *Service) recipientsStateToModel(input []smsgateway.RecipientState, hash
 		}
 
 		if hash {
-			phoneNumber = fmt.Sprintf("%x", sha256.Sum256([]byte(phoneNumber)))[:16]
+			phoneNumber = hashPhoneNumber(phoneNumber)
 		}
 
 		output[i] = MessageRecipient{
 			PhoneNumber: phoneNumber,
 			State:       ProcessingState(v.State),
 			Error:       v.Error,
+			ErrorCode:   NormalizeDeliveryError(v.Error),
 		}
 	}
 
@@ -322,10 +830,27 @@ func modelToMessageState(input Message) MessageStateOut {
 				states[string(s.State)] = s.UpdatedAt
 			}
 		}
+	errorCodes := make(map[string]DeliveryErrorCode)
+	destinations := make(map[string]RecipientInfo)
+	for _, r := range input.Recipients {
+		if r.ErrorCode != nil {
+			errorCodes[r.PhoneNumber] = *r.ErrorCode
+		}
+		if r.Country != nil {
+			destinations[r.PhoneNumber] = RecipientInfo{
+				Country: *r.Country,
+				Carrier: anys.OrDefault(r.Carrier, ""),
+			}
+		}
+	}
+
 	return MessageStateOut{
 		DeviceID:    input.DeviceID,
 		IsHashed:    input.IsHashed,
 		IsEncrypted: input.IsEncrypted,
+		ErrorCodes:  errorCodes,
+
+		Destinations: destinations,
 
 		MessageStateIn: MessageStateIn{
 			ID:         input.ExtID,
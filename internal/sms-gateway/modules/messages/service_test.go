@@ -1,10 +1,14 @@
 package messages
 
 import (
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 )
 
 func TestService_recipientsStateToModel(t *testing.T) {
@@ -156,3 +160,61 @@ func TestCleanPhoneNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestStateUpdateResult_Retryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "not found", err: fmt.Errorf("can't get: %w", ErrMessageNotFound), want: false},
+		{name: "validation", err: ErrValidation("time is out of the allowed range"), want: false},
+		{name: "database", err: errors.New("connection refused"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (StateUpdateResult{Err: tt.err}).Retryable(); got != tt.want {
+				t.Errorf("Retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_NextPollIn(t *testing.T) {
+	token := "token"
+	degradedAt := time.Now()
+
+	s := &Service{
+		config: Config{
+			Polling: PollingConfig{
+				ActiveInterval:   5 * time.Second,
+				IdleInterval:     time.Minute,
+				IdlePushInterval: 15 * time.Minute,
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		device   models.Device
+		selected int
+		total    int64
+		want     time.Duration
+	}{
+		{name: "Pending backlog", device: models.Device{PushToken: &token}, selected: 2, total: 5, want: 0},
+		{name: "Recently active", device: models.Device{PushToken: &token}, selected: 2, total: 2, want: 5 * time.Second},
+		{name: "Idle with push", device: models.Device{PushToken: &token}, selected: 0, total: 0, want: 15 * time.Minute},
+		{name: "Idle with cached push token", device: models.Device{HasPushToken: true}, selected: 0, total: 0, want: 15 * time.Minute},
+		{name: "Idle with degraded push", device: models.Device{PushToken: &token, PushDegradedAt: &degradedAt}, selected: 0, total: 0, want: time.Minute},
+		{name: "Idle without push", device: models.Device{}, selected: 0, total: 0, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.NextPollIn(tt.device, tt.selected, tt.total); got != tt.want {
+				t.Errorf("NextPollIn() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
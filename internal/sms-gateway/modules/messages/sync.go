@@ -0,0 +1,184 @@
+package messages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// syncLogLifetime is how long the syncs of the device are kept. The last sync
+// of every device is kept longer, so only the devices which lost the syncs
+// after their token are asked to resync.
+const syncLogLifetime = 24 * time.Hour
+
+// StateSync is the result of the delta sync of message states.
+type StateSync struct {
+	// Token acknowledges the applied changes, the device submits the next
+	// changelog since it.
+	Token string
+	// Resync is set if the token of the device doesn't match the server
+	// changelog, so the device should resubmit the states of its recent
+	// messages.
+	Resync bool
+	// Failed updates, indexes refer to the submitted changelog.
+	Failed []StateUpdateResult
+}
+
+// SyncStates applies the changelog of message states submitted by the device
+// since the token of the previous sync. The token is the sequence number of
+// the last sync stored by the server, so the changes already applied by the
+// syncs after the token of the device aren't applied again. Only the last
+// change of every message is applied. The token is advanced unless a
+// retryable update has failed, so the device keeps the changes since the
+// returned token until the next sync.
+func (s *Service) SyncStates(ctx context.Context, deviceID, token string, changes []MessageStateIn) (StateSync, error) {
+	seq, valid := parseSyncToken(token)
+
+	first, last, syncs, err := s.messages.SelectSyncs(ctx, deviceID, seq)
+	if err != nil {
+		return StateSync{}, fmt.Errorf("can't select syncs: %w", err)
+	}
+
+	plan := planSync(seq, valid, first, last, syncs, changes)
+
+	failed := s.UpdateStates(deviceID, plan.changes)
+	for i := range failed {
+		failed[i].Index = plan.indexes[failed[i].Index]
+	}
+
+	result := StateSync{
+		Token:  formatSyncToken(last),
+		Resync: plan.resync,
+		Failed: failed,
+	}
+
+	if slices.ContainsFunc(failed, StateUpdateResult.Retryable) {
+		if !plan.resync {
+			result.Token = formatSyncToken(seq)
+		}
+		return result, nil
+	}
+
+	applied := make([]syncChange, 0, len(plan.changes))
+	for i, change := range plan.changes {
+		if slices.ContainsFunc(failed, func(r StateUpdateResult) bool { return r.Index == plan.indexes[i] }) {
+			continue
+		}
+		applied = append(applied, syncChange{ID: change.ID, State: change.State})
+	}
+
+	if len(applied) == 0 && last > 0 {
+		return result, nil
+	}
+
+	err = s.messages.InsertSync(ctx, &MessageSync{DeviceID: deviceID, Seq: last + 1, Changes: applied})
+	switch {
+	case errors.Is(err, errSyncExists):
+		// a concurrent sync of the device has advanced the changelog
+		result.Resync = true
+		return result, nil
+	case err != nil:
+		return result, fmt.Errorf("can't insert sync: %w", err)
+	}
+
+	result.Token = formatSyncToken(last + 1)
+
+	return result, nil
+}
+
+// syncPlan is the part of the submitted changelog to apply.
+type syncPlan struct {
+	changes []MessageStateIn
+	// indexes of the changes in the submitted changelog
+	indexes []int
+	resync  bool
+}
+
+// planSync selects the changes to apply from the changelog submitted since
+// the sync seq. The first and last are the sequence numbers of the stored
+// syncs, and syncs are the stored syncs after seq. The changes with the same
+// state as applied by the syncs after seq are skipped. The device should
+// resync if its token is invalid, is ahead of the stored changelog or
+// precedes the retained syncs, or if it has no token while the server has.
+func planSync(seq uint64, valid bool, first, last uint64, syncs []MessageSync, changes []MessageStateIn) syncPlan {
+	resync := !valid ||
+		seq > last ||
+		(seq > 0 && seq+1 < first) ||
+		(seq == 0 && last > 0)
+
+	applied := map[syncChange]struct{}{}
+	if !resync {
+		for _, sync := range syncs {
+			for _, change := range sync.Changes {
+				applied[change] = struct{}{}
+			}
+		}
+	}
+
+	compacted, indexes := compactChanges(changes)
+
+	plan := syncPlan{
+		changes: make([]MessageStateIn, 0, len(compacted)),
+		indexes: make([]int, 0, len(compacted)),
+		resync:  resync,
+	}
+	for i, change := range compacted {
+		if _, ok := applied[syncChange{ID: change.ID, State: change.State}]; ok {
+			continue
+		}
+
+		plan.changes = append(plan.changes, change)
+		plan.indexes = append(plan.indexes, indexes[i])
+	}
+
+	return plan
+}
+
+// compactChanges keeps the last change of every message. It returns the
+// indexes of the kept changes in the original changelog.
+func compactChanges(changes []MessageStateIn) ([]MessageStateIn, []int) {
+	last := make(map[string]int, len(changes))
+	for i, change := range changes {
+		last[change.ID] = i
+	}
+
+	compacted := make([]MessageStateIn, 0, len(last))
+	indexes := make([]int, 0, len(last))
+	for i, change := range changes {
+		if last[change.ID] != i {
+			continue
+		}
+
+		compacted = append(compacted, change)
+		indexes = append(indexes, i)
+	}
+
+	return compacted, indexes
+}
+
+// parseSyncToken returns the sequence number of the token, the empty token is
+// the one of the first sync. It returns false if the token isn't a sequence
+// number, e.g. the token issued before the changelog.
+func parseSyncToken(token string) (uint64, bool) {
+	if token == "" {
+		return 0, true
+	}
+
+	seq, err := strconv.ParseUint(token, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return seq, true
+}
+
+func formatSyncToken(seq uint64) string {
+	if seq == 0 {
+		return ""
+	}
+
+	return strconv.FormatUint(seq, 10)
+}
@@ -0,0 +1,108 @@
+package messages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompactChanges(t *testing.T) {
+	changes := []MessageStateIn{
+		{ID: "a", State: ProcessingStateProcessed},
+		{ID: "b", State: ProcessingStateProcessed},
+		{ID: "a", State: ProcessingStateSent},
+		{ID: "c", State: ProcessingStateSent},
+		{ID: "a", State: ProcessingStateDelivered},
+	}
+
+	compacted, indexes := compactChanges(changes)
+
+	wantIDs := []string{"b", "c", "a"}
+	gotIDs := make([]string, 0, len(compacted))
+	for _, change := range compacted {
+		gotIDs = append(gotIDs, change.ID)
+	}
+	if !reflect.DeepEqual(gotIDs, wantIDs) {
+		t.Errorf("compactChanges() ids = %v, want %v", gotIDs, wantIDs)
+	}
+	if !reflect.DeepEqual(indexes, []int{1, 3, 4}) {
+		t.Errorf("compactChanges() indexes = %v, want [1 3 4]", indexes)
+	}
+	if compacted[2].State != ProcessingStateDelivered {
+		t.Errorf("compactChanges() kept state %s, want the last one", compacted[2].State)
+	}
+}
+
+func TestPlanSync(t *testing.T) {
+	changes := []MessageStateIn{
+		{ID: "a", State: ProcessingStateSent},
+		{ID: "b", State: ProcessingStateSent},
+		{ID: "a", State: ProcessingStateDelivered},
+	}
+	syncs := []MessageSync{
+		{Seq: 3, Changes: []syncChange{{ID: "a", State: ProcessingStateDelivered}}},
+		{Seq: 4, Changes: []syncChange{{ID: "b", State: ProcessingStateProcessed}}},
+	}
+
+	tests := []struct {
+		name        string
+		seq         uint64
+		valid       bool
+		first, last uint64
+		syncs       []MessageSync
+		wantIDs     []string
+		wantIndexes []int
+		wantResync  bool
+	}{
+		{name: "first sync", valid: true, wantIDs: []string{"b", "a"}, wantIndexes: []int{1, 2}},
+		{name: "up to date", seq: 4, valid: true, first: 1, last: 4, wantIDs: []string{"b", "a"}, wantIndexes: []int{1, 2}},
+		{name: "behind", seq: 2, valid: true, first: 1, last: 4, syncs: syncs, wantIDs: []string{"b"}, wantIndexes: []int{1}},
+		{name: "invalid token", valid: false, first: 1, last: 4, syncs: syncs, wantIDs: []string{"b", "a"}, wantIndexes: []int{1, 2}, wantResync: true},
+		{name: "no token", valid: true, first: 1, last: 4, syncs: syncs, wantIDs: []string{"b", "a"}, wantIndexes: []int{1, 2}, wantResync: true},
+		{name: "ahead", seq: 5, valid: true, first: 1, last: 4, wantIDs: []string{"b", "a"}, wantIndexes: []int{1, 2}, wantResync: true},
+		{name: "removed", seq: 1, valid: true, first: 3, last: 4, syncs: syncs, wantIDs: []string{"b", "a"}, wantIndexes: []int{1, 2}, wantResync: true},
+		{name: "oldest retained", seq: 2, valid: true, first: 3, last: 4, syncs: syncs, wantIDs: []string{"b"}, wantIndexes: []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planSync(tt.seq, tt.valid, tt.first, tt.last, tt.syncs, changes)
+
+			gotIDs := make([]string, 0, len(plan.changes))
+			for _, change := range plan.changes {
+				gotIDs = append(gotIDs, change.ID)
+			}
+			if !reflect.DeepEqual(gotIDs, tt.wantIDs) {
+				t.Errorf("planSync() ids = %v, want %v", gotIDs, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(plan.indexes, tt.wantIndexes) {
+				t.Errorf("planSync() indexes = %v, want %v", plan.indexes, tt.wantIndexes)
+			}
+			if plan.resync != tt.wantResync {
+				t.Errorf("planSync() resync = %v, want %v", plan.resync, tt.wantResync)
+			}
+		})
+	}
+}
+
+func TestSyncToken(t *testing.T) {
+	tests := []struct {
+		token     string
+		wantSeq   uint64
+		wantValid bool
+	}{
+		{token: "", wantSeq: 0, wantValid: true},
+		{token: "42", wantSeq: 42, wantValid: true},
+		{token: "Sy6vQ4cTq2Xh0mWn9Lb3p", wantSeq: 0, wantValid: false},
+		{token: "-1", wantSeq: 0, wantValid: false},
+	}
+
+	for _, tt := range tests {
+		seq, valid := parseSyncToken(tt.token)
+		if seq != tt.wantSeq || valid != tt.wantValid {
+			t.Errorf("parseSyncToken(%q) = %d, %v, want %d, %v", tt.token, seq, valid, tt.wantSeq, tt.wantValid)
+		}
+		if valid && formatSyncToken(seq) != tt.token {
+			t.Errorf("formatSyncToken(%d) = %q, want %q", seq, formatSyncToken(seq), tt.token)
+		}
+	}
+}
@@ -2,16 +2,29 @@ package messages
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"github.com/capcom6/go-helpers/anys"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 	"golang.org/x/exp/maps"
 )
 
+// processedSweepInterval is the period of hashing of the messages stuck in
+// the Processed state, they aren't enqueued for hashing by the updates.
+const processedSweepInterval = 5 * time.Minute
+
 type HashingTaskConfig struct {
 	Interval time.Duration
+	// ProcessedTimeout is the time after which the messages in the Processed
+	// state are hashed, so the stuck ones may be requeued before. Zero hashes
+	// them right away.
+	ProcessedTimeout time.Duration
 }
 
 type HashingTaskParams struct {
@@ -35,11 +48,11 @@ func (t *HashingTask) Run(ctx context.Context) {
 	t.Logger.Info("Starting hashing task...")
 	ticker := time.NewTicker(t.Config.Interval)
 	defer ticker.Stop()
+	sweepTicker := time.NewTicker(processedSweepInterval)
+	defer sweepTicker.Stop()
 
 	t.Logger.Info("Initial hashing...")
-	if err := t.Messages.HashProcessed([]uint64{}); err != nil {
-		t.Logger.Error("Can't hash messages", zap.Error(err))
-	}
+	t.sweep()
 	t.Logger.Info("Initial hashing...Done")
 
 	for {
@@ -49,10 +62,20 @@ func (t *HashingTask) Run(ctx context.Context) {
 			return
 		case <-ticker.C:
 			t.process()
+		case <-sweepTicker.C:
+			t.sweep()
 		}
 	}
 }
 
+// sweep hashes all the messages in the final states and the ones stuck in the
+// Processed state.
+func (t *HashingTask) sweep() {
+	if err := t.Messages.HashProcessed([]uint64{}, time.Now().Add(-t.Config.ProcessedTimeout)); err != nil {
+		t.Logger.Error("Can't hash messages", zap.Error(err))
+	}
+}
+
 // Enqueue adds a message ID to the processing queue to be hashed in the next batch
 func (t *HashingTask) Enqueue(id uint64) {
 	t.mux.Lock()
@@ -73,7 +96,7 @@ func (t *HashingTask) process() {
 	}
 
 	t.Logger.Debug("Hashing messages...")
-	if err := t.Messages.HashProcessed(ids); err != nil {
+	if err := t.Messages.HashProcessed(ids, time.Now().Add(-t.Config.ProcessedTimeout)); err != nil {
 		t.Logger.Error("Can't hash messages", zap.Error(err))
 	}
 }
@@ -86,3 +109,139 @@ func NewHashingTask(params HashingTaskParams) *HashingTask {
 		queue:    map[uint64]struct{}{},
 	}
 }
+
+type StuckAction string
+
+const (
+	// StuckActionRequeue moves stuck messages back to the Pending state.
+	StuckActionRequeue StuckAction = "requeue"
+	// StuckActionFail marks stuck messages as Failed.
+	StuckActionFail StuckAction = "fail"
+)
+
+// ParseStuckAction parses the recovery action of the stuck messages, the empty
+// value defaults to requeue.
+func ParseStuckAction(value string) (StuckAction, error) {
+	switch action := StuckAction(value); action {
+	case "":
+		return StuckActionRequeue, nil
+	case StuckActionRequeue, StuckActionFail:
+		return action, nil
+	default:
+		return "", fmt.Errorf("unknown stuck action %q: expected requeue or fail", value)
+	}
+}
+
+type StuckTaskConfig struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	Action   StuckAction
+}
+
+type StuckTaskParams struct {
+	fx.In
+
+	Messages    *repository
+	Config      StuckTaskConfig
+	EventsSvc   *events.Service
+	WebhooksSvc *webhooks.Service
+	Metrics     *metrics
+	Logger      *zap.Logger
+}
+
+// StuckTask periodically recovers messages that were moved to the Processed
+// state but never updated by the device, e.g. because of the app crash.
+type StuckTask struct {
+	Messages    *repository
+	Config      StuckTaskConfig
+	EventsSvc   *events.Service
+	WebhooksSvc *webhooks.Service
+	Metrics     *metrics
+	Logger      *zap.Logger
+}
+
+func (t *StuckTask) Run(ctx context.Context) {
+	if t.Config.Interval <= 0 || t.Config.Timeout <= 0 {
+		t.Logger.Info("Stuck messages recovery is disabled")
+		return
+	}
+
+	t.Logger.Info("Starting stuck messages recovery task...")
+	ticker := time.NewTicker(t.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.Logger.Info("Stopping stuck messages recovery task...")
+			return
+		case <-ticker.C:
+			t.process(ctx)
+		}
+	}
+}
+
+func (t *StuckTask) process(ctx context.Context) {
+	target, reason, action := ProcessingStatePending, (*string)(nil), StuckActionRequeued
+	if t.Config.Action == StuckActionFail {
+		target, reason, action = ProcessingStateFailed, anys.AsPointer(ErrorProcessingTimeout), StuckActionFailed
+	}
+
+	affected, err := t.Messages.resolveStuck(ctx, "", time.Now().Add(-t.Config.Timeout), target, reason)
+	if err != nil {
+		t.Logger.Error("Can't recover stuck messages", zap.Error(err))
+		return
+	}
+
+	for _, v := range affected {
+		count := int64(len(v.Messages))
+		t.Metrics.AddStuck(action, count)
+		t.Logger.Info("Recovered stuck messages",
+			zap.String("device_id", v.DeviceID),
+			zap.String("action", action),
+			zap.Int64("count", count),
+		)
+
+		if target == ProcessingStateFailed {
+			t.emitFailed(v, *reason)
+			continue
+		}
+
+		if err := t.EventsSvc.Notify(v.UserID, &v.DeviceID, events.NewMessageEnqueuedEvent()); err != nil {
+			t.Logger.Error("Can't notify device", zap.String("device_id", v.DeviceID), zap.Error(err))
+		}
+	}
+}
+
+// emitFailed sends the sms:failed webhooks for the recipients of the failed
+// messages, as the device would. The recipients of the hashed messages are
+// unknown, so no webhooks are sent for them.
+func (t *StuckTask) emitFailed(stuck stuckMessages, reason string) {
+	now := time.Now()
+	for _, m := range stuck.Messages {
+		if m.IsHashed {
+			continue
+		}
+
+		for _, r := range m.Recipients {
+			t.WebhooksSvc.Emit(stuck.UserID, &stuck.DeviceID, smsgateway.WebhookEventSmsFailed, map[string]any{
+				"messageId":   m.ExtID,
+				"phoneNumber": r.PhoneNumber,
+				"failedAt":    now,
+				"reason":      reason,
+				"errorCode":   NormalizeDeliveryError(&reason),
+			})
+		}
+	}
+}
+
+func NewStuckTask(params StuckTaskParams) *StuckTask {
+	return &StuckTask{
+		Messages:    params.Messages,
+		Config:      params.Config,
+		EventsSvc:   params.EventsSvc,
+		WebhooksSvc: params.WebhooksSvc,
+		Metrics:     params.Metrics,
+		Logger:      params.Logger,
+	}
+}
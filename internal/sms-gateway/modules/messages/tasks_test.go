@@ -0,0 +1,29 @@
+package messages
+
+import "testing"
+
+func TestParseStuckAction(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    StuckAction
+		wantErr bool
+	}{
+		{value: "", want: StuckActionRequeue},
+		{value: "requeue", want: StuckActionRequeue},
+		{value: "fail", want: StuckActionFail},
+		{value: "drop", wantErr: true},
+		{value: "Fail", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseStuckAction(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStuckAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseStuckAction() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
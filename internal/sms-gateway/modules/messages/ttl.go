@@ -0,0 +1,51 @@
+package messages
+
+import (
+	"fmt"
+	"time"
+)
+
+// TTLConfig limits the lifetime of the messages, so the messages aren't sent
+// after the limit even if the clients don't set the TTL.
+type TTLConfig struct {
+	// Default is the TTL of the messages without TTL and valid until time, 0
+	// to use Max.
+	Default time.Duration
+	// Max is the max TTL of the messages, 0 for unlimited.
+	Max time.Duration
+}
+
+// validUntil returns the expiration time of the message. The default is
+// applied to the messages without TTL and valid until time, and it's
+// capped by the max. It returns ErrValidation if the message lives longer
+// than the max.
+func (c TTLConfig) validUntil(message MessageIn, now time.Time) (*time.Time, error) {
+	if message.TTL != nil && *message.TTL > 0 {
+		ttl := time.Duration(*message.TTL) * time.Second
+		if c.Max > 0 && ttl > c.Max {
+			return nil, ErrValidation(fmt.Sprintf("ttl exceeds the max of %d seconds", int64(c.Max.Seconds())))
+		}
+
+		validUntil := now.Add(ttl)
+		return &validUntil, nil
+	}
+
+	if message.ValidUntil != nil {
+		if c.Max > 0 && message.ValidUntil.After(now.Add(c.Max)) {
+			return nil, ErrValidation(fmt.Sprintf("validUntil exceeds the max ttl of %d seconds", int64(c.Max.Seconds())))
+		}
+
+		return message.ValidUntil, nil
+	}
+
+	ttl := c.Default
+	if c.Max > 0 && (ttl <= 0 || ttl > c.Max) {
+		ttl = c.Max
+	}
+	if ttl <= 0 {
+		return nil, nil
+	}
+
+	validUntil := now.Add(ttl)
+	return &validUntil, nil
+}
@@ -0,0 +1,53 @@
+package messages
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTTLConfig_validUntil(t *testing.T) {
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	ttl := func(seconds uint64) *uint64 { return &seconds }
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		config  TTLConfig
+		message MessageIn
+		want    *time.Time
+		wantErr bool
+	}{
+		{name: "Unlimited", config: TTLConfig{}, message: MessageIn{}, want: nil},
+		{name: "TTL", config: TTLConfig{Max: time.Hour}, message: MessageIn{TTL: ttl(600)}, want: at(10 * time.Minute)},
+		{name: "TTL above max", config: TTLConfig{Max: time.Hour}, message: MessageIn{TTL: ttl(7200)}, wantErr: true},
+		{name: "Valid until", config: TTLConfig{Max: time.Hour}, message: MessageIn{ValidUntil: at(time.Minute)}, want: at(time.Minute)},
+		{name: "Valid until above max", config: TTLConfig{Max: time.Hour}, message: MessageIn{ValidUntil: at(2 * time.Hour)}, wantErr: true},
+		{name: "Default", config: TTLConfig{Default: time.Hour}, message: MessageIn{}, want: at(time.Hour)},
+		{name: "Default above max", config: TTLConfig{Default: 2 * time.Hour, Max: time.Hour}, message: MessageIn{}, want: at(time.Hour)},
+		{name: "Max without default", config: TTLConfig{Max: time.Hour}, message: MessageIn{}, want: at(time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.validUntil(tt.message, now)
+			if tt.wantErr {
+				var errValidation ErrValidation
+				if !errors.As(err, &errValidation) {
+					t.Fatalf("validUntil() error = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validUntil() error = %v", err)
+			}
+
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("validUntil() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Native histogram settings shared by the application histograms. Classic
+// buckets are kept for scrapers without native histograms support.
+const (
+	NativeHistogramBucketFactor     = 1.1
+	NativeHistogramMaxBucketNumber  = 160
+	NativeHistogramMinResetDuration = time.Hour
+)
+
+const (
+	// HeaderTraceParent is the W3C Trace Context header.
+	HeaderTraceParent = "traceparent"
+
+	// LabelTraceID is the exemplar label with the trace ID.
+	LabelTraceID = "trace_id"
+)
+
+var traceParentRegexp = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+// ParseTraceParent returns the trace ID from the W3C traceparent header value.
+func ParseTraceParent(value string) (string, bool) {
+	m := traceParentRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(value)))
+	if m == nil || strings.Trim(m[1], "0") == "" {
+		return "", false
+	}
+
+	return m[1], true
+}
+
+// TraceID returns the trace ID of the request or an empty string.
+func TraceID(c *fiber.Ctx) string {
+	traceID, _ := ParseTraceParent(c.Get(HeaderTraceParent))
+	return traceID
+}
+
+// ObserveWithExemplar records the value and links it to the trace if the
+// trace ID is known.
+func ObserveWithExemplar(o prometheus.Observer, value float64, traceID string) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && traceID != "" {
+		eo.ObserveWithExemplar(value, prometheus.Labels{LabelTraceID: traceID})
+		return
+	}
+
+	o.Observe(value)
+}
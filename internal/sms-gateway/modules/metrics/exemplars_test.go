@@ -0,0 +1,27 @@
+package metrics
+
+import "testing"
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   string
+		wantOk bool
+	}{
+		{name: "Valid", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", want: "4bf92f3577b34da6a3ce929d0e0e4736", wantOk: true},
+		{name: "Upper case", value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", want: "4bf92f3577b34da6a3ce929d0e0e4736", wantOk: true},
+		{name: "Zero trace ID", value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "Empty", value: ""},
+		{name: "Malformed", value: "00-4bf92f35-00f067aa0ba902b7-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseTraceParent(tt.value)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("ParseTraceParent() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
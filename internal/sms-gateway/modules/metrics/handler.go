@@ -3,6 +3,9 @@ package metrics
 import (
 	"github.com/ansrivas/fiberprometheus/v2"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type HttpHandler struct {
@@ -10,7 +13,12 @@ type HttpHandler struct {
 
 func (h *HttpHandler) Register(app *fiber.App) {
 	promhandler := fiberprometheus.New("")
-	promhandler.RegisterAt(app, "/metrics")
+
+	// OpenMetrics format is required for exemplars, native histograms are
+	// served with protobuf negotiation
+	app.Get("/metrics", adaptor.HTTPHandler(promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})))
 
 	app.Use(promhandler.Middleware)
 }
@@ -0,0 +1,215 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTopic   = "sms-gateway/" + DeviceIDPlaceholder + "/events"
+	defaultTimeout = 5 * time.Second
+	keepAlive      = 60 * time.Second
+
+	// queueSize is the number of the events waiting for publishing.
+	queueSize = 1024
+	// workers is the number of the events published concurrently.
+	workers = 4
+	// disconnectQuiesce is the time to finish the work on close, in ms.
+	disconnectQuiesce = 250
+)
+
+var (
+	// ErrQueueFull is returned when the event can't be queued for publishing.
+	ErrQueueFull = errors.New("mqtt queue is full")
+
+	errPublishTimeout = errors.New("publish timed out")
+)
+
+type Event struct {
+	Type smsgateway.PushEventType `json:"event"`
+	Data map[string]string        `json:"data"`
+}
+
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// Service publishes the device events to the MQTT broker, so the devices
+// subscribed to their topics receive them. The events are queued and
+// published in the background, so a slow broker doesn't block the callers.
+// The connection is re-established after a failure.
+type Service struct {
+	config Config
+	broker string
+
+	client paho.Client
+	queue  chan message
+
+	logger *zap.Logger
+}
+
+func NewService(config Config, logger *zap.Logger) (*Service, error) {
+	s := &Service{
+		config: config,
+		logger: logger,
+	}
+
+	if !config.Enabled() {
+		return s, nil
+	}
+
+	u, err := url.Parse(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse mqtt url: %w", err)
+	}
+
+	scheme, port := "tcp", "1883"
+	switch u.Scheme {
+	case "mqtt", "tcp":
+	case "mqtts", "ssl":
+		scheme, port = "ssl", "8883"
+	default:
+		return nil, fmt.Errorf("invalid mqtt scheme: %s", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	s.broker = scheme + "://" + net.JoinHostPort(u.Hostname(), port)
+
+	if s.config.Topic == "" {
+		s.config.Topic = defaultTopic
+	}
+	if s.config.QoS > 1 {
+		s.config.QoS = 1
+	}
+	if s.config.ClientID == "" {
+		s.config.ClientID = "sms-gateway-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+	}
+	if s.config.Timeout <= 0 {
+		s.config.Timeout = defaultTimeout
+	}
+
+	password, _ := u.User.Password()
+	options := paho.NewClientOptions().
+		AddBroker(s.broker).
+		SetClientID(s.config.ClientID).
+		SetUsername(u.User.Username()).
+		SetPassword(password).
+		SetKeepAlive(keepAlive).
+		SetConnectTimeout(s.config.Timeout).
+		SetWriteTimeout(s.config.Timeout).
+		SetCleanSession(true).
+		SetOrderMatters(false).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(func(paho.Client) {
+			logger.Info("Connected to MQTT broker", zap.String("broker", s.broker), zap.String("client_id", s.config.ClientID))
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logger.Warn("MQTT connection lost, reconnecting", zap.Error(err))
+		})
+
+	s.client = paho.NewClient(options)
+	s.queue = make(chan message, queueSize)
+
+	return s, nil
+}
+
+// Enabled reports whether the events are published.
+func (s *Service) Enabled() bool {
+	return s.config.Enabled()
+}
+
+// Publish queues the event for the topic of the device. It is a no-op if the
+// publishing is disabled. It returns ErrQueueFull if the broker doesn't keep
+// up with the events.
+func (s *Service) Publish(deviceID string, event Event) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("can't marshal event: %w", err)
+	}
+
+	select {
+	case s.queue <- message{topic: strings.ReplaceAll(s.config.Topic, DeviceIDPlaceholder, deviceID), payload: payload}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run connects to the broker and publishes the queued events until the
+// context is canceled.
+func (s *Service) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	// with the connect retry the token is done only after the connection is
+	// established, the events are kept in the queue meanwhile: the clean
+	// session drops the ones stored by the client before the first connection
+	select {
+	case <-ctx.Done():
+		return
+	case <-s.client.Connect().Done():
+	}
+
+	wg := sync.WaitGroup{}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// Close disconnects from the broker.
+func (s *Service) Close() error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	s.client.Disconnect(disconnectQuiesce)
+
+	return nil
+}
+
+func (s *Service) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-s.queue:
+			s.publish(m)
+		}
+	}
+}
+
+func (s *Service) publish(m message) {
+	token := s.client.Publish(m.topic, s.config.QoS, false, m.payload)
+	if !token.WaitTimeout(s.config.Timeout) {
+		s.logger.Error("Failed to publish MQTT event", zap.String("topic", m.topic), zap.Error(errPublishTimeout))
+		return
+	}
+	if err := token.Error(); err != nil {
+		s.logger.Error("Failed to publish MQTT event", zap.String("topic", m.topic), zap.Error(err))
+	}
+}
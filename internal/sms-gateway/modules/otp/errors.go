@@ -0,0 +1,9 @@
+package otp
+
+import "errors"
+
+var (
+	ErrNotFound        = errors.New("code not found or expired")
+	ErrTooManyAttempts = errors.New("too many verification attempts")
+	ErrInvalidTemplate = errors.New("template must contain the {code} placeholder")
+)
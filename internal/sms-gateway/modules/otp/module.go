@@ -0,0 +1,18 @@
+package otp
+
+import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"otp",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("otp")
+	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("otp")
+	}, fx.Private),
+	fx.Provide(NewService),
+)
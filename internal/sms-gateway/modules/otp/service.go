@@ -0,0 +1,198 @@
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// maxAttempts is the number of failed verifications after which the code is
+// dropped.
+const maxAttempts = 5
+
+type ServiceParams struct {
+	fx.In
+
+	Cache       cache.Cache
+	MessagesSvc *messages.Service
+	IDGen       db.IDGen
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	cache       cache.Cache
+	messagesSvc *messages.Service
+	idGen       db.IDGen
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		cache:       params.Cache,
+		messagesSvc: params.MessagesSvc,
+		idGen:       params.IDGen,
+
+		logger: params.Logger.Named("service"),
+	}
+}
+
+// Send generates a code, stores its hash and enqueues the message with the
+// code to the device. OTP messages are sent with the bypass priority.
+func (s *Service) Send(ctx context.Context, device models.Device, req Request) (OTP, error) {
+	if req.Template == "" {
+		req.Template = DefaultTemplate
+	}
+	if !strings.Contains(req.Template, CodePlaceholder) {
+		return OTP{}, ErrInvalidTemplate
+	}
+	if req.Length == 0 {
+		req.Length = DefaultLength
+	}
+	if req.TTL == 0 {
+		req.TTL = DefaultTTL
+	}
+
+	code, err := generateCode(req.Length)
+	if err != nil {
+		return OTP{}, fmt.Errorf("can't generate code: %w", err)
+	}
+
+	id := s.idGen()
+	expiresAt := time.Now().Add(req.TTL)
+
+	if err := s.save(ctx, device.UserID, id, record{Hash: hashCode(id, code), ExpiresAt: expiresAt}); err != nil {
+		return OTP{}, err
+	}
+
+	ttl := uint64(req.TTL.Seconds())
+	state, err := s.messagesSvc.Enqueue(
+		device,
+		messages.MessageIn{
+			TextContent: &messages.TextMessageContent{
+				Text: strings.ReplaceAll(req.Template, CodePlaceholder, code),
+			},
+			PhoneNumbers: []string{req.PhoneNumber},
+			SimNumber:    req.SimNumber,
+			TTL:          &ttl,
+			Priority:     smsgateway.PriorityBypassThreshold,
+		},
+		messages.EnqueueOptions{SkipPhoneValidation: req.SkipPhoneValidation},
+	)
+	if err != nil {
+		if delErr := s.cache.Delete(ctx, key(device.UserID, id)); delErr != nil {
+			s.logger.Warn("can't delete code", zap.String("id", id), zap.Error(delErr))
+		}
+		return OTP{}, fmt.Errorf("can't enqueue message: %w", err)
+	}
+
+	return OTP{
+		ID:        id,
+		ExpiresAt: expiresAt,
+		Message:   state,
+	}, nil
+}
+
+// Verify checks the code. A matched code is dropped, so it can be used only
+// once. After maxAttempts checks the code is dropped too. The attempts are
+// counted and the code is consumed atomically, so concurrent checks can't
+// exceed the limit or redeem the code twice.
+func (s *Service) Verify(ctx context.Context, userID, id, code string) (bool, error) {
+	raw, err := s.cache.Get(ctx, key(userID, id))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("can't get code: %w", err)
+	}
+
+	rec := record{}
+	if err := json.Unmarshal([]byte(raw), &rec); err != nil {
+		return false, fmt.Errorf("can't unmarshal code: %w", err)
+	}
+
+	// the counter expires with the code
+	attempts, err := s.cache.Increment(ctx, attemptsKey(userID, id), 1, cache.WithValidUntil(rec.ExpiresAt))
+	if err != nil {
+		return false, fmt.Errorf("can't count attempts: %w", err)
+	}
+	if attempts > maxAttempts {
+		return false, ErrTooManyAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(rec.Hash), []byte(hashCode(id, code))) == 1 {
+		// only one of the concurrent checks gets the code
+		if _, err := s.cache.GetAndDelete(ctx, key(userID, id)); errors.Is(err, cache.ErrKeyNotFound) {
+			return false, ErrNotFound
+		} else if err != nil {
+			return false, fmt.Errorf("can't delete code: %w", err)
+		}
+		return true, nil
+	}
+
+	if attempts == maxAttempts {
+		if err := s.cache.Delete(ctx, key(userID, id)); err != nil {
+			return false, fmt.Errorf("can't delete code: %w", err)
+		}
+		return false, ErrTooManyAttempts
+	}
+
+	return false, nil
+}
+
+func (s *Service) save(ctx context.Context, userID, id string, rec record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("can't marshal code: %w", err)
+	}
+
+	if err := s.cache.Set(ctx, key(userID, id), string(data), cache.WithValidUntil(rec.ExpiresAt)); err != nil {
+		return fmt.Errorf("can't store code: %w", err)
+	}
+
+	return nil
+}
+
+func key(userID, id string) string {
+	return userID + ":" + id
+}
+
+func attemptsKey(userID, id string) string {
+	return key(userID, id) + ":attempts"
+}
+
+// generateCode returns a random numeric code of the given length.
+func generateCode(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+
+	return string(code), nil
+}
+
+func hashCode(id, code string) string {
+	h := sha256.Sum256([]byte(id + ":" + code))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,144 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func TestService_Verify(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{cache: cache.NewMemory(0), logger: zap.NewNop()}
+
+	rec := record{Hash: hashCode("otp", "123456"), ExpiresAt: time.Now().Add(time.Minute)}
+	if err := svc.save(ctx, "user", "otp", rec); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	if _, err := svc.Verify(ctx, "other", "otp", "123456"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Verify() by other user error = %v, want %v", err, ErrNotFound)
+	}
+
+	valid, err := svc.Verify(ctx, "user", "otp", "000000")
+	if err != nil || valid {
+		t.Errorf("Verify() with wrong code = %v, %v, want false, nil", valid, err)
+	}
+
+	valid, err = svc.Verify(ctx, "user", "otp", "123456")
+	if err != nil || !valid {
+		t.Errorf("Verify() = %v, %v, want true, nil", valid, err)
+	}
+
+	if _, err := svc.Verify(ctx, "user", "otp", "123456"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Verify() of used code error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestService_Verify_TooManyAttempts(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{cache: cache.NewMemory(0), logger: zap.NewNop()}
+
+	rec := record{Hash: hashCode("otp", "123456"), ExpiresAt: time.Now().Add(time.Minute)}
+	if err := svc.save(ctx, "user", "otp", rec); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	for i := 1; i < maxAttempts; i++ {
+		if _, err := svc.Verify(ctx, "user", "otp", "000000"); err != nil {
+			t.Fatalf("Verify() attempt %d error = %v", i, err)
+		}
+	}
+
+	if _, err := svc.Verify(ctx, "user", "otp", "000000"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrTooManyAttempts)
+	}
+
+	if _, err := svc.Verify(ctx, "user", "otp", "123456"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Verify() after lockout error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestService_Verify_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{cache: cache.NewMemory(0), logger: zap.NewNop()}
+
+	rec := record{Hash: hashCode("otp", "123456"), ExpiresAt: time.Now().Add(time.Minute)}
+	if err := svc.save(ctx, "user", "otp", rec); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	var (
+		wg       sync.WaitGroup
+		verified atomic.Int32
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if valid, _ := svc.Verify(ctx, "user", "otp", "123456"); valid {
+				verified.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := verified.Load(); n != 1 {
+		t.Errorf("code redeemed %d times, want 1", n)
+	}
+}
+
+func TestService_Verify_ConcurrentAttempts(t *testing.T) {
+	ctx := context.Background()
+	svc := &Service{cache: cache.NewMemory(0), logger: zap.NewNop()}
+
+	rec := record{Hash: hashCode("otp", "123456"), ExpiresAt: time.Now().Add(time.Minute)}
+	if err := svc.save(ctx, "user", "otp", rec); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		checked atomic.Int32
+	)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.Verify(ctx, "user", "otp", "000000"); err == nil || !errors.Is(err, ErrTooManyAttempts) && !errors.Is(err, ErrNotFound) {
+				checked.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := checked.Load(); n > maxAttempts-1 {
+		t.Errorf("checked %d codes, want at most %d", n, maxAttempts-1)
+	}
+	if _, err := svc.Verify(ctx, "user", "otp", "123456"); !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("Verify() after lockout error = %v", err)
+	}
+}
+
+func TestGenerateCode(t *testing.T) {
+	for _, length := range []int{4, 6, 10} {
+		code, err := generateCode(length)
+		if err != nil {
+			t.Fatalf("generateCode() error = %v", err)
+		}
+		if len(code) != length {
+			t.Errorf("generateCode(%d) = %q", length, code)
+		}
+		for _, c := range code {
+			if c < '0' || c > '9' {
+				t.Errorf("generateCode(%d) = %q contains non-digit", length, code)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package otp
+
+import (
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+)
+
+const (
+	// CodePlaceholder is replaced with the generated code in the template.
+	CodePlaceholder = "{code}"
+
+	DefaultTemplate = "Your verification code is " + CodePlaceholder
+	DefaultLength   = 6
+	DefaultTTL      = 5 * time.Minute
+)
+
+// Request describes the code to generate and send.
+type Request struct {
+	PhoneNumber string
+	// Template of the message text with the CodePlaceholder
+	Template string
+	// Length is the number of digits in the code
+	Length int
+	// TTL is the validity period of the code
+	TTL       time.Duration
+	SimNumber *uint8
+
+	SkipPhoneValidation bool
+}
+
+// OTP is the sent code.
+type OTP struct {
+	ID        string
+	ExpiresAt time.Time
+
+	Message messages.MessageStateOut
+}
+
+// record is the cached state of the code. The code itself is not stored, the
+// verification attempts are counted under a separate key.
+type record struct {
+	Hash      string    `json:"hash"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
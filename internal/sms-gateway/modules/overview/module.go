@@ -0,0 +1,19 @@
+package overview
+
+import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"overview",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("overview")
+	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("overview", cache.WithEvictable())
+	}, fx.Private),
+	fx.Provide(newRepository, fx.Private),
+	fx.Provide(NewService),
+)
@@ -0,0 +1,73 @@
+package overview
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// CountUsers returns the number of registered users.
+func (r *repository) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Where("deleted_at IS NULL").
+		Count(&count).
+		Error
+
+	return count, err
+}
+
+// CountDevices returns the number of devices seen since onlineSince and the
+// others.
+func (r *repository) CountDevices(ctx context.Context, onlineSince time.Time) (Devices, error) {
+	var row struct {
+		Total  int64
+		Online int64
+	}
+	err := r.db.WithContext(ctx).
+		Table("devices").
+		Select("COUNT(*) AS total, COALESCE(SUM(last_seen >= ?), 0) AS online", onlineSince).
+		Where("deleted_at IS NULL").
+		Scan(&row).
+		Error
+
+	return Devices{Online: row.Online, Offline: row.Total - row.Online}, err
+}
+
+// CountMessages returns the number of pending and processed messages.
+func (r *repository) CountMessages(ctx context.Context) (Messages, error) {
+	var row Messages
+	err := r.db.WithContext(ctx).
+		Table("messages").
+		Select("COALESCE(SUM(state = 'Pending'), 0) AS pending, COALESCE(SUM(state = 'Processed'), 0) AS processed").
+		Where("state IN ('Pending', 'Processed') AND deleted_at IS NULL").
+		Scan(&row).
+		Error
+
+	return row, err
+}
+
+// CountWebhooks returns the number of webhook deliveries of the day.
+func (r *repository) CountWebhooks(ctx context.Context, day time.Time) (Webhooks, error) {
+	var row Webhooks
+	err := r.db.WithContext(ctx).
+		Table("stats_daily").
+		Select("COALESCE(SUM(webhooks_delivered), 0) AS delivered, COALESCE(SUM(webhooks_failed), 0) AS failed").
+		Where("date = ?", day).
+		Scan(&row).
+		Error
+
+	return row, err
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,106 @@
+package overview
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// cacheTTL limits the database load of frequently refreshed dashboards.
+	cacheTTL = 30 * time.Second
+	// cacheKey of the computed overview.
+	cacheKey = "overview"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	DevicesConfig devices.Config
+
+	Overview *repository
+	Cache    cache.Cache
+
+	WebhooksSvc *webhooks.Service
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	devicesConfig devices.Config
+
+	overview *repository
+	cache    *cache.Typed[Overview]
+
+	webhooksSvc *webhooks.Service
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		devicesConfig: params.DevicesConfig,
+
+		overview: params.Overview,
+		cache:    cache.NewTyped[Overview](params.Cache),
+
+		webhooksSvc: params.WebhooksSvc,
+
+		logger: params.Logger,
+	}
+}
+
+// Get returns the system overview. Database totals are computed at most once
+// per cacheTTL, the in-memory queues are always current.
+func (s *Service) Get(ctx context.Context) (Overview, error) {
+	overview, err := s.cache.GetOrSet(ctx, cacheKey, func() (Overview, error) {
+		return s.compute(ctx)
+	}, cache.WithTTL(cacheTTL))
+	if err != nil {
+		return Overview{}, err
+	}
+
+	overview.Queues.WebhookBatches = s.webhooksSvc.PendingBatches()
+
+	return overview, nil
+}
+
+func (s *Service) compute(ctx context.Context) (Overview, error) {
+	now := time.Now()
+
+	users, err := s.overview.CountUsers(ctx)
+	if err != nil {
+		return Overview{}, fmt.Errorf("can't count users: %w", err)
+	}
+
+	deviceCounts, err := s.overview.CountDevices(ctx, now.Add(-s.devicesConfig.OnlineWindow))
+	if err != nil {
+		return Overview{}, fmt.Errorf("can't count devices: %w", err)
+	}
+
+	messages, err := s.overview.CountMessages(ctx)
+	if err != nil {
+		return Overview{}, fmt.Errorf("can't count messages: %w", err)
+	}
+
+	y, m, d := now.UTC().Date()
+	deliveries, err := s.overview.CountWebhooks(ctx, time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		return Overview{}, fmt.Errorf("can't count webhooks: %w", err)
+	}
+
+	return Overview{
+		Users:    users,
+		Devices:  deviceCounts,
+		Messages: messages,
+		Webhooks: deliveries,
+
+		GeneratedAt: now,
+	}, nil
+}
@@ -0,0 +1,54 @@
+package overview
+
+import "time"
+
+// Overview is the summary of the system state for operator dashboards.
+type Overview struct {
+	Users    int64
+	Devices  Devices
+	Messages Messages
+	Queues   Queues
+	Webhooks Webhooks
+
+	GeneratedAt time.Time
+}
+
+// Devices counts the registered devices by their last activity.
+type Devices struct {
+	Online  int64
+	Offline int64
+}
+
+// Messages counts the messages not yet sent by the devices.
+type Messages struct {
+	// Pending messages wait for the device to fetch them.
+	Pending int64
+	// Processed messages are fetched by the device, but not sent yet.
+	Processed int64
+}
+
+// Queues are the in-memory queues of the server instance. Only the webhook
+// batches are reported, the depths of the other queues aren't tracked.
+type Queues struct {
+	// WebhookBatches is the number of webhook batches waiting to be sent.
+	WebhookBatches int
+}
+
+// Webhooks counts the webhook deliveries by the server since the start of the
+// day (UTC). The webhooks delivered by the devices directly aren't reported
+// to the server, so they aren't counted.
+type Webhooks struct {
+	Delivered int64
+	Failed    int64
+}
+
+// FailureRate returns the share of failed deliveries, zero if nothing was
+// sent.
+func (w Webhooks) FailureRate() float64 {
+	total := w.Delivered + w.Failed
+	if total == 0 {
+		return 0
+	}
+
+	return float64(w.Failed) / float64(total)
+}
@@ -0,0 +1,24 @@
+package overview
+
+import "testing"
+
+func TestWebhooks_FailureRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		webhooks Webhooks
+		want     float64
+	}{
+		{"empty", Webhooks{}, 0},
+		{"all delivered", Webhooks{Delivered: 10}, 0},
+		{"some failed", Webhooks{Delivered: 3, Failed: 1}, 0.25},
+		{"all failed", Webhooks{Failed: 2}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.webhooks.FailureRate(); got != tt.want {
+				t.Errorf("FailureRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
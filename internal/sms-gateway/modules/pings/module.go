@@ -0,0 +1,18 @@
+package pings
+
+import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"pings",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("pings")
+	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("pings")
+	}, fx.Private),
+	fx.Provide(NewService),
+)
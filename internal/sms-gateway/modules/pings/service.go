@@ -0,0 +1,114 @@
+package pings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSamples is the number of recent samples kept per device.
+	maxSamples = 20
+	// samplesTTL is the time after the last ping when samples are dropped.
+	samplesTTL = 24 * time.Hour
+)
+
+type Service struct {
+	cache *cache.Typed[[]Sample]
+
+	logger *zap.Logger
+}
+
+func NewService(c cache.Cache, logger *zap.Logger) *Service {
+	return &Service{
+		cache: cache.NewTyped[[]Sample](c),
+
+		logger: logger,
+	}
+}
+
+// Record stores the ping of the device. The clock skew is estimated from the
+// device send time, the server receive time and the previous RTT reported by
+// the device.
+func (s *Service) Record(ctx context.Context, deviceID string, sentAt, receivedAt time.Time, rtt *time.Duration) (Sample, error) {
+	sample := Sample{
+		ReceivedAt: receivedAt,
+		RTT:        rtt,
+		Skew:       sentAt.Sub(receivedAt),
+	}
+	if rtt != nil {
+		// the request takes about a half of the round trip
+		sample.Skew += *rtt / 2
+	}
+
+	samples, err := s.Samples(ctx, deviceID)
+	if err != nil {
+		s.logger.Warn("Can't load ping samples", zap.String("device_id", deviceID), zap.Error(err))
+		samples = nil
+	}
+
+	samples = append(samples, sample)
+	if len(samples) > maxSamples {
+		samples = samples[len(samples)-maxSamples:]
+	}
+
+	if err := s.cache.Set(ctx, deviceID, samples, cache.WithTTL(samplesTTL)); err != nil {
+		return sample, fmt.Errorf("can't store samples: %w", err)
+	}
+
+	return sample, nil
+}
+
+// Samples returns recent samples of the device, oldest first.
+func (s *Service) Samples(ctx context.Context, deviceID string) ([]Sample, error) {
+	samples, err := s.cache.Get(ctx, deviceID)
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return []Sample{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get samples: %w", err)
+	}
+
+	return samples, nil
+}
+
+// Summarize calculates statistics of the samples.
+func Summarize(samples []Sample) Stats {
+	stats := Stats{Count: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	var (
+		totalSkew time.Duration
+		totalRTT  time.Duration
+		rttCount  int
+	)
+	for _, sample := range samples {
+		totalSkew += sample.Skew
+
+		if sample.RTT == nil {
+			continue
+		}
+
+		rtt := *sample.RTT
+		stats.LastRTT = &rtt
+		if stats.MaxRTT == nil || rtt > *stats.MaxRTT {
+			stats.MaxRTT = &rtt
+		}
+		totalRTT += rtt
+		rttCount++
+	}
+
+	stats.AvgSkew = totalSkew / time.Duration(len(samples))
+	if rttCount > 0 {
+		avg := totalRTT / time.Duration(rttCount)
+		stats.AvgRTT = &avg
+	}
+
+	return stats
+}
@@ -0,0 +1,77 @@
+package pings
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
+
+func TestService_Record(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(cache.NewMemory(0), zap.NewNop())
+
+	now := time.Now()
+
+	// device clock is 2 seconds ahead, request takes 100ms of 200ms round trip
+	sample, err := svc.Record(ctx, "device", now.Add(2*time.Second-100*time.Millisecond), now, durationPtr(200*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if sample.Skew != 2*time.Second {
+		t.Errorf("Skew = %v, want %v", sample.Skew, 2*time.Second)
+	}
+
+	for i := 0; i < maxSamples+5; i++ {
+		if _, err := svc.Record(ctx, "device", now, now, nil); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	samples, err := svc.Samples(ctx, "device")
+	if err != nil {
+		t.Fatalf("Samples() error = %v", err)
+	}
+	if len(samples) != maxSamples {
+		t.Errorf("len(Samples()) = %d, want %d", len(samples), maxSamples)
+	}
+
+	other, err := svc.Samples(ctx, "other")
+	if err != nil || len(other) != 0 {
+		t.Errorf("Samples(other) = %v, %v, want empty", other, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stats := Summarize([]Sample{
+		{RTT: durationPtr(100 * time.Millisecond), Skew: time.Second},
+		{Skew: 3 * time.Second},
+		{RTT: durationPtr(300 * time.Millisecond), Skew: 2 * time.Second},
+	})
+
+	if stats.Count != 3 {
+		t.Errorf("Count = %d, want 3", stats.Count)
+	}
+	if stats.AvgRTT == nil || *stats.AvgRTT != 200*time.Millisecond {
+		t.Errorf("AvgRTT = %v, want 200ms", stats.AvgRTT)
+	}
+	if stats.MaxRTT == nil || *stats.MaxRTT != 300*time.Millisecond {
+		t.Errorf("MaxRTT = %v, want 300ms", stats.MaxRTT)
+	}
+	if stats.LastRTT == nil || *stats.LastRTT != 300*time.Millisecond {
+		t.Errorf("LastRTT = %v, want 300ms", stats.LastRTT)
+	}
+	if stats.AvgSkew != 2*time.Second {
+		t.Errorf("AvgSkew = %v, want 2s", stats.AvgSkew)
+	}
+
+	if empty := Summarize(nil); empty.Count != 0 || empty.AvgRTT != nil {
+		t.Errorf("Summarize(nil) = %+v, want empty", empty)
+	}
+}
@@ -0,0 +1,24 @@
+package pings
+
+import "time"
+
+// Sample is a single ping measurement.
+type Sample struct {
+	// ReceivedAt is the server time of the ping.
+	ReceivedAt time.Time `json:"receivedAt"`
+	// RTT is the round-trip time of the previous ping as measured by the
+	// device, nil if unknown.
+	RTT *time.Duration `json:"rtt,omitempty"`
+	// Skew is the estimated difference between the device and server clocks,
+	// positive if the device clock is ahead.
+	Skew time.Duration `json:"skew"`
+}
+
+// Stats summarizes recent samples of a device.
+type Stats struct {
+	Count   int
+	LastRTT *time.Duration
+	AvgRTT  *time.Duration
+	MaxRTT  *time.Duration
+	AvgSkew time.Duration
+}
@@ -0,0 +1,43 @@
+package privacy
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RequestType is the type of the data subject request.
+type RequestType string
+
+const (
+	RequestTypeExport RequestType = "export"
+	RequestTypeErase  RequestType = "erase"
+)
+
+// Request is the audit record of the data subject request. The phone number
+// isn't kept, only its hash, so the record survives the erasure.
+type Request struct {
+	ID              uint64      `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	UserID          string      `gorm:"not null;type:varchar(32);index:idx_privacy_requests_user_id"`
+	Type            RequestType `gorm:"not null;type:varchar(8)"`
+	PhoneNumberHash string      `gorm:"not null;type:char(64)"`
+
+	// Messages is the number of the exported or erased outgoing messages.
+	Messages int64 `gorm:"not null;default:0"`
+	// InboxMessages is the number of the exported or erased incoming messages.
+	InboxMessages int64 `gorm:"not null;default:0"`
+
+	CreatedAt time.Time `gorm:"->;not null;autocreatetime:false;default:CURRENT_TIMESTAMP(3)"`
+}
+
+func (Request) TableName() string {
+	return "privacy_requests"
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Request{}); err != nil {
+		return fmt.Errorf("privacy_requests migration failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package privacy
+
+import (
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Insert stores the audit record and reads back the creation time set by the
+// database.
+func (r *repository) Insert(request *Request) error {
+	if err := r.db.Create(request).Error; err != nil {
+		return err
+	}
+
+	return r.db.Where("id = ?", request.ID).Take(request).Error
+}
+
+// Select returns the latest audit records of the user, newest first.
+func (r *repository) Select(userID string, limit int) ([]Request, error) {
+	items := []Request{}
+	err := r.db.
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,226 @@
+package privacy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devicelogs"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/links"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/relay"
+	"github.com/nyaruka/phonenumbers"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// maxRequests is the number of the latest audit records returned.
+const maxRequests = 100
+
+// Report is the page of the data of the user related to the phone number.
+type Report struct {
+	// Messages are the outgoing messages to the phone number.
+	Messages      []messages.MessageOut
+	MessagesTotal int64
+	// Inbox is the incoming messages from the phone number uploaded by the
+	// devices for the exports.
+	Inbox      []exports.Message
+	InboxTotal int64
+}
+
+// Erasure is the number of the erased records.
+type Erasure struct {
+	Messages         int64
+	InboxMessages    int64
+	Links            int64
+	LogEntries       int64
+	UpstreamMessages int64
+}
+
+type ServiceParams struct {
+	fx.In
+
+	Requests *repository
+
+	MessagesSvc   *messages.Service
+	ExportsSvc    *exports.Service
+	LinksSvc      *links.Service
+	DevicesSvc    *devices.Service
+	DeviceLogsSvc *devicelogs.Service
+	RelaySvc      *relay.Service
+
+	Logger *zap.Logger
+}
+
+// Service handles the data subject requests: it collects or erases the data
+// of the user related to a phone number and keeps the audit trail of the
+// requests.
+type Service struct {
+	requests *repository
+
+	messagesSvc   *messages.Service
+	exportsSvc    *exports.Service
+	linksSvc      *links.Service
+	devicesSvc    *devices.Service
+	deviceLogsSvc *devicelogs.Service
+	relaySvc      *relay.Service
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		requests: params.Requests,
+
+		messagesSvc:   params.MessagesSvc,
+		exportsSvc:    params.ExportsSvc,
+		linksSvc:      params.LinksSvc,
+		devicesSvc:    params.DevicesSvc,
+		deviceLogsSvc: params.DeviceLogsSvc,
+		relaySvc:      params.RelaySvc,
+
+		logger: params.Logger.Named("service"),
+	}
+}
+
+// Export returns the page of the data of the user related to the phone
+// number, both lists are paged with the same limit and offset. The request is
+// audited with the first page.
+func (s *Service) Export(ctx context.Context, userID, phoneNumber string, limit, offset int) (Report, error) {
+	outgoing, total, err := s.messagesSvc.SelectByPhoneNumber(userID, phoneNumber, limit, offset)
+	if err != nil {
+		return Report{}, err
+	}
+
+	inbox, err := s.exportsSvc.SelectByPhoneNumbers(ctx, userID, phoneNumberForms(phoneNumber))
+	if err != nil {
+		return Report{}, fmt.Errorf("can't select inbox messages: %w", err)
+	}
+
+	report := Report{
+		Messages:      outgoing,
+		MessagesTotal: total,
+		Inbox:         inbox[min(offset, len(inbox)):min(offset+limit, len(inbox))],
+		InboxTotal:    int64(len(inbox)),
+	}
+
+	// the data isn't returned without the audit record
+	if offset == 0 {
+		if err := s.audit(userID, RequestTypeExport, phoneNumber, report.MessagesTotal, report.InboxTotal); err != nil {
+			return Report{}, err
+		}
+	}
+
+	return report, nil
+}
+
+// Erase removes the phone number and the content from the messages of the
+// user, the short links of the messages, the device log entries mentioning
+// the number and the relayed copies of the messages, and drops the incoming
+// messages from it. The erasure is idempotent, so it may be repeated on
+// failure.
+func (s *Service) Erase(ctx context.Context, userID, phoneNumber string) (Erasure, error) {
+	result := Erasure{}
+	forms := phoneNumberForms(phoneNumber)
+
+	ids, err := s.messagesSvc.ErasePhoneNumber(ctx, userID, phoneNumber)
+	if err != nil {
+		return result, err
+	}
+	result.Messages = int64(len(ids))
+
+	if result.Links, err = s.linksSvc.DeleteByMessages(ctx, userID, ids); err != nil {
+		return result, err
+	}
+
+	removed, err := s.exportsSvc.ErasePhoneNumbers(ctx, userID, forms)
+	result.InboxMessages = int64(removed)
+	if err != nil {
+		return result, fmt.Errorf("can't erase inbox messages: %w", err)
+	}
+
+	devices, err := s.devicesSvc.Select(userID)
+	if err != nil {
+		return result, fmt.Errorf("can't select devices: %w", err)
+	}
+	deviceIDs := make([]string, 0, len(devices))
+	for _, device := range devices {
+		deviceIDs = append(deviceIDs, device.ID)
+	}
+	removed, err = s.deviceLogsSvc.ErasePhoneNumbers(ctx, deviceIDs, forms)
+	result.LogEntries = int64(removed)
+	if err != nil {
+		return result, fmt.Errorf("can't erase device logs: %w", err)
+	}
+
+	if result.UpstreamMessages, err = s.relaySvc.ErasePhoneNumber(ctx, phoneNumber); err != nil {
+		return result, err
+	}
+
+	if err := s.audit(userID, RequestTypeErase, phoneNumber, result.Messages, result.InboxMessages); err != nil {
+		return result, err
+	}
+
+	s.logger.Info("Data of phone number erased",
+		zap.String("user_id", userID),
+		zap.Int64("messages", result.Messages),
+		zap.Int64("inbox_messages", result.InboxMessages),
+		zap.Int64("links", result.Links),
+		zap.Int64("log_entries", result.LogEntries),
+		zap.Int64("upstream_messages", result.UpstreamMessages),
+	)
+
+	return result, nil
+}
+
+// Requests returns the latest audit records of the user, newest first.
+func (s *Service) Requests(userID string) ([]Request, error) {
+	items, err := s.requests.Select(userID, maxRequests)
+	if err != nil {
+		return nil, fmt.Errorf("can't select requests: %w", err)
+	}
+
+	return items, nil
+}
+
+func (s *Service) audit(userID string, requestType RequestType, phoneNumber string, outgoing, inbox int64) error {
+	request := Request{
+		UserID:          userID,
+		Type:            requestType,
+		PhoneNumberHash: hashPhoneNumber(phoneNumber),
+		Messages:        outgoing,
+		InboxMessages:   inbox,
+	}
+
+	if err := s.requests.Insert(&request); err != nil {
+		return fmt.Errorf("can't store audit record: %w", err)
+	}
+
+	return nil
+}
+
+// phoneNumberForms returns the phone number as is and in the international
+// format, the incoming messages keep the sender as reported by the device.
+func phoneNumberForms(phoneNumber string) []string {
+	forms := []string{phoneNumber}
+	if phone, err := phonenumbers.Parse(phoneNumber, ""); err == nil {
+		if normalized := phonenumbers.Format(phone, phonenumbers.E164); normalized != phoneNumber {
+			forms = append(forms, normalized)
+		}
+	}
+
+	return forms
+}
+
+// hashPhoneNumber returns the hash of the phone number in the international
+// format, so the audit records of the same number match regardless of the
+// format of the request.
+func hashPhoneNumber(phoneNumber string) string {
+	forms := phoneNumberForms(phoneNumber)
+	hash := sha256.Sum256([]byte(forms[len(forms)-1]))
+
+	return hex.EncodeToString(hash[:])
+}
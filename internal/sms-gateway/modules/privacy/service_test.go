@@ -0,0 +1,35 @@
+package privacy
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPhoneNumberForms(t *testing.T) {
+	tests := []struct {
+		name        string
+		phoneNumber string
+		want        []string
+	}{
+		{name: "international", phoneNumber: "+79990001234", want: []string{"+79990001234"}},
+		{name: "formatted", phoneNumber: "+7 999 000-12-34", want: []string{"+7 999 000-12-34", "+79990001234"}},
+		{name: "without country", phoneNumber: "89990001234", want: []string{"89990001234"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := phoneNumberForms(tt.phoneNumber); !slices.Equal(got, tt.want) {
+				t.Errorf("phoneNumberForms(%q) = %v, want %v", tt.phoneNumber, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPhoneNumber(t *testing.T) {
+	if hashPhoneNumber("+7 999 000-12-34") != hashPhoneNumber("+79990001234") {
+		t.Error("hashPhoneNumber() differs for the formats of the same number")
+	}
+	if hashPhoneNumber("+79990001234") == hashPhoneNumber("+79990005678") {
+		t.Error("hashPhoneNumber() matches for different numbers")
+	}
+}
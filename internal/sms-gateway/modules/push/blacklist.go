@@ -0,0 +1,48 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	pkgcache "github.com/android-sms-gateway/server/pkg/cache"
+	"gorm.io/gorm"
+)
+
+// blacklistCacheName is the name of the cache of the blacklisted tokens.
+const blacklistCacheName = "push-blacklist"
+
+// newBlacklistPreloader restores the tokens blacklisted within the timeout, so
+// a restart doesn't resume the notifications to the rejected tokens. The
+// tokens are found by the push degradation mark of the devices.
+func newBlacklistPreloader(db *gorm.DB) cache.Preloader {
+	return cache.NewPreloader(blacklistCacheName, func(ctx context.Context, c cache.Cache) error {
+		return preloadBlacklist(ctx, db, c, time.Now())
+	})
+}
+
+func preloadBlacklist(ctx context.Context, db *gorm.DB, c cache.Cache, now time.Time) error {
+	devices := []models.Device{}
+	if err := db.WithContext(ctx).
+		Select("push_token", "push_degraded_at").
+		Where("push_token IS NOT NULL AND push_degraded_at > ?", now.Add(-blacklistTimeout)).
+		Find(&devices).
+		Error; err != nil {
+		return fmt.Errorf("can't select degraded devices: %w", err)
+	}
+
+	for _, device := range devices {
+		if device.PushToken == nil || device.PushDegradedAt == nil {
+			continue
+		}
+
+		validUntil := device.PushDegradedAt.Add(blacklistTimeout)
+		if err := c.Set(ctx, *device.PushToken, "", pkgcache.WithValidUntil(validUntil)); err != nil {
+			return fmt.Errorf("can't blacklist token: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -5,4 +5,5 @@ import "time"
 const (
 	maxRetries       = 3
 	blacklistTimeout = 15 * time.Minute
+	resultsTimeout   = time.Hour
 )
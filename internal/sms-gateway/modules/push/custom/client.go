@@ -0,0 +1,262 @@
+package custom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
+)
+
+// DefaultTemplate is the request body used when the template isn't
+// configured.
+const DefaultTemplate = `{"token":{{json .Token}},"event":{{json .Event}},"data":{{json .Data}}}`
+
+const (
+	defaultMethod      = http.MethodPost
+	defaultContentType = "application/json"
+)
+
+// maxConcurrency is the max number of the requests sent in parallel.
+const maxConcurrency = 8
+
+// notification is the data available in the URL and body templates.
+type notification struct {
+	// Token is the push token registered by the device, e.g. the topic of
+	// the notification service
+	Token string
+	Event string
+	Data  map[string]string
+}
+
+// Client sends each notification as a separate request to the configured
+// URL, so the deployments without access to FCM can integrate with their own
+// notification systems, e.g. ntfy, Gotify or MQTT bridges.
+//
+// The options are:
+//   - url: the request URL, a template with the notification fields, e.g.
+//     "https://ntfy.example.com/{{.Token}}", required. The fields are
+//     path-escaped and the rendered URL must keep the scheme and the host.
+//   - method: the request method, POST by default
+//   - template: the request body template, DefaultTemplate by default
+//   - content_type: the request content type, application/json by default
+//   - auth: the Authorization header value, e.g. "Bearer <token>"
+//
+// The templates use text/template with the json function to encode values.
+type Client struct {
+	method      string
+	contentType string
+	auth        string
+
+	url    *template.Template
+	origin string
+	body   *template.Template
+
+	transport http.RoundTripper
+
+	client *http.Client
+	mux    sync.Mutex
+}
+
+func New(options map[string]string, transport http.RoundTripper) (*Client, error) {
+	if options["url"] == "" {
+		return nil, errors.New("push url is required")
+	}
+
+	urlTemplate, err := parseTemplate("url", options["url"])
+	if err != nil {
+		return nil, err
+	}
+	probe, err := renderURL(urlTemplate, notification{Token: "token", Event: "event"})
+	if err != nil {
+		return nil, err
+	}
+	if probe.Scheme != "http" && probe.Scheme != "https" || probe.Host == "" {
+		return nil, errors.New("push url must be an absolute http(s) URL")
+	}
+
+	body := options["template"]
+	if body == "" {
+		body = DefaultTemplate
+	}
+	bodyTemplate, err := parseTemplate("template", body)
+	if err != nil {
+		return nil, err
+	}
+
+	method := strings.ToUpper(options["method"])
+	if method == "" {
+		method = defaultMethod
+	}
+
+	contentType := options["content_type"]
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+
+	return &Client{
+		method:      method,
+		contentType: contentType,
+		auth:        options["auth"],
+
+		url:    urlTemplate,
+		origin: origin(probe),
+		body:   bodyTemplate,
+
+		transport: transport,
+	}, nil
+}
+
+func (c *Client) Open(ctx context.Context) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.client != nil {
+		return nil
+	}
+
+	c.client = &http.Client{Transport: c.transport}
+
+	return nil
+}
+
+func (c *Client) Send(ctx context.Context, messages map[string]types.Event) (map[string]error, error) {
+	errs := map[string]error{}
+	errsMux := sync.Mutex{}
+
+	sem := make(chan struct{}, maxConcurrency)
+	wg := sync.WaitGroup{}
+	for token, event := range messages {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(n notification) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := c.sendOne(ctx, n); err != nil {
+				errsMux.Lock()
+				errs[n.Token] = err
+				errsMux.Unlock()
+			}
+		}(notification{Token: token, Event: string(event.Type), Data: event.Data})
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return nil, nil
+	}
+
+	return errs, nil
+}
+
+func (c *Client) sendOne(ctx context.Context, n notification) error {
+	// the token is set by the device, so it must not leave the configured
+	// host or the path segment
+	if n.Token == "." || n.Token == ".." {
+		return types.NewSendError(types.ErrorReasonInvalidArgument, errors.New("invalid token"))
+	}
+	target, err := renderURL(c.url, n)
+	if err != nil {
+		return types.NewSendError(types.ErrorReasonInvalidArgument, err)
+	}
+	if origin(target) != c.origin {
+		return types.NewSendError(types.ErrorReasonInvalidArgument, fmt.Errorf("url host doesn't match %s", c.origin))
+	}
+
+	body := bytes.Buffer{}
+	if err := c.body.Execute(&body, n); err != nil {
+		return types.NewSendError(types.ErrorReasonInvalidArgument, fmt.Errorf("can't render body: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, c.method, target.String(), &body)
+	if err != nil {
+		return types.NewSendError(types.ErrorReasonInvalidArgument, fmt.Errorf("can't create request: %w", err))
+	}
+
+	req.Header.Set("Content-Type", c.contentType)
+	req.Header.Set("User-Agent", "android-sms-gateway/1.x (server; golang)")
+	if c.auth != "" {
+		req.Header.Set("Authorization", c.auth)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return types.NewSendError(types.ErrorReasonUnavailable, fmt.Errorf("can't send request: %w", err))
+	}
+
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 400 {
+		return types.NewSendError(types.ReasonOfStatus(resp.StatusCode), fmt.Errorf("unexpected status code: %d", resp.StatusCode))
+	}
+
+	return nil
+}
+
+func (c *Client) Close(ctx context.Context) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	c.client = nil
+
+	return nil
+}
+
+// renderURL renders the URL template with the path-escaped notification
+// fields.
+func renderURL(t *template.Template, n notification) (*url.URL, error) {
+	escaped := notification{
+		Token: url.PathEscape(n.Token),
+		Event: url.PathEscape(n.Event),
+		Data:  make(map[string]string, len(n.Data)),
+	}
+	for k, v := range n.Data {
+		escaped.Data[k] = url.PathEscape(v)
+	}
+
+	buf := bytes.Buffer{}
+	if err := t.Execute(&buf, escaped); err != nil {
+		return nil, fmt.Errorf("can't render url: %w", err)
+	}
+
+	u, err := url.Parse(buf.String())
+	if err != nil {
+		return nil, fmt.Errorf("can't parse url: %w", err)
+	}
+
+	return u, nil
+}
+
+func origin(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{"json": toJSON}).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse %s template: %w", name, err)
+	}
+
+	return t, nil
+}
+
+func toJSON(v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
@@ -0,0 +1,140 @@
+package custom
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
+)
+
+type request struct {
+	method string
+	path   string
+	auth   string
+	body   string
+}
+
+func TestClient_Send(t *testing.T) {
+	mu := sync.Mutex{}
+	requests := map[string]request{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		requests[r.URL.Path] = request{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization"), body: string(body)}
+		mu.Unlock()
+
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		options map[string]string
+		want    request
+	}{
+		{
+			name:    "Default",
+			options: map[string]string{"url": server.URL + "/{{.Token}}"},
+			want: request{
+				method: http.MethodPost,
+				path:   "/topic",
+				body:   `{"token":"topic","event":"MessageEnqueued","data":{"id":"1"}}`,
+			},
+		},
+		{
+			name: "Template",
+			options: map[string]string{
+				"url":      server.URL + "/{{.Token}}",
+				"method":   "put",
+				"template": `{{.Event}} {{index .Data "id"}}`,
+				"auth":     "Bearer secret",
+			},
+			want: request{
+				method: http.MethodPut,
+				path:   "/topic",
+				auth:   "Bearer secret",
+				body:   `MessageEnqueued 1`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.options, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Open(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close(context.Background())
+
+			errs, err := c.Send(context.Background(), map[string]types.Event{
+				"topic":   {Type: smsgateway.PushMessageEnqueued, Data: map[string]string{"id": "1"}},
+				"gone":    {Type: smsgateway.PushMessageEnqueued},
+				"../x?y=": {Type: smsgateway.PushMessageEnqueued},
+				"..":      {Type: smsgateway.PushMessageEnqueued},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the endpoint responding 410 isn't the evidence of the token rot
+			if len(errs) != 2 || types.ReasonOf(errs["gone"]) != types.ErrorReasonUnavailable ||
+				types.ReasonOf(errs[".."]) != types.ErrorReasonInvalidArgument {
+				t.Errorf("errs = %v, want unavailable gone token and invalid dot token", errs)
+			}
+
+			mu.Lock()
+			got := requests["/topic"]
+			_, escaped := requests["/../x?y="]
+			mu.Unlock()
+			if !escaped {
+				t.Error("token with path and query isn't escaped")
+			}
+			if got != tt.want {
+				t.Errorf("request = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(map[string]string{}, nil); err == nil {
+		t.Error("New() without url error = nil")
+	}
+	if _, err := New(map[string]string{"url": "http://localhost/{{.Token"}, nil); err == nil {
+		t.Error("New() with invalid url template error = nil")
+	}
+	if _, err := New(map[string]string{"url": "{{.Token}}"}, nil); err == nil {
+		t.Error("New() with relative url error = nil")
+	}
+}
+
+func TestClient_SendHost(t *testing.T) {
+	c, err := New(map[string]string{"url": "https://{{.Token}}.example.com/"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := c.Send(context.Background(), map[string]types.Event{
+		"other": {Type: smsgateway.PushMessageEnqueued},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types.ReasonOf(errs["other"]) != types.ErrorReasonInvalidArgument {
+		t.Errorf("errs = %v, want invalid argument for other host", errs)
+	}
+}
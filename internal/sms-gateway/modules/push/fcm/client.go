@@ -1,29 +1,71 @@
 package fcm
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
+	"time"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 )
 
+const (
+	// credentialsCheckInterval is how often the credentials file is checked
+	// for changes.
+	credentialsCheckInterval = 30 * time.Second
+	// reloadTimeout limits the re-initialization of the messaging client.
+	reloadTimeout = 30 * time.Second
+)
+
+// checkTopic is the topic of the dry-run message sent by Check.
+const checkTopic = "doctor"
+
+var messagingScopes = []string{
+	"https://www.googleapis.com/auth/cloud-platform",
+	"https://www.googleapis.com/auth/firebase.messaging",
+}
+
 type Client struct {
-	options map[string]string
+	options   map[string]string
+	transport http.RoundTripper
+	logger    *zap.Logger
 
-	client *messaging.Client
-	mux    sync.Mutex
+	client      *messaging.Client
+	credentials []byte
+	mux         sync.RWMutex
+
+	checkInterval time.Duration
+	stop          chan struct{}
+	wg            sync.WaitGroup
 }
 
-func New(options map[string]string) (*Client, error) {
+// New creates the FCM client. If transport is not nil, it's used for the
+// token and messaging requests.
+func New(options map[string]string, transport http.RoundTripper, logger *zap.Logger) (*Client, error) {
 	return &Client{
-		options: options,
+		options:   options,
+		transport: transport,
+		logger:    logger,
+
+		checkInterval: credentialsCheckInterval,
 	}, nil
 }
 
+// Open initializes the messaging client. If the credentials are read from a
+// file, the file is watched for changes and re-read on SIGHUP; the client is
+// replaced on change without interrupting sends in progress.
 func (c *Client) Open(ctx context.Context) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
@@ -32,27 +74,42 @@ func (c *Client) Open(ctx context.Context) error {
 		return nil
 	}
 
-	creds := c.options["credentials"]
-	if creds == "" {
-		return fmt.Errorf("no credentials provided")
+	creds, err := c.loadCredentials()
+	if err != nil {
+		return err
 	}
 
-	opt := option.WithCredentialsJSON([]byte(creds))
-
-	app, err := firebase.NewApp(ctx, nil, opt)
+	client, err := newMessagingClient(ctx, creds, c.transport)
 	if err != nil {
-		return fmt.Errorf("can't create firebase app: %w", err)
+		return err
 	}
 
-	c.client, err = app.Messaging(ctx)
-	if err != nil {
-		return fmt.Errorf("can't create firebase messaging client: %w", err)
+	c.client = client
+	c.credentials = creds
+
+	if path := c.options["credentials_file"]; path != "" {
+		// the signal is subscribed before Open returns, so SIGHUP never
+		// terminates the process once the client is opened
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+
+		c.stop = make(chan struct{})
+		c.wg.Add(1)
+		go c.watch(path, hup)
 	}
 
 	return nil
 }
 
 func (c *Client) Send(ctx context.Context, messages map[string]types.Event) (map[string]error, error) {
+	c.mux.RLock()
+	client := c.client
+	c.mux.RUnlock()
+
+	if client == nil {
+		return nil, errors.New("client is not opened")
+	}
+
 	errs := make(map[string]error, len(messages))
 	for address, payload := range messages {
 		eventMap, err := eventToMap(payload)
@@ -61,7 +118,7 @@ func (c *Client) Send(ctx context.Context, messages map[string]types.Event) (map
 			continue
 		}
 
-		_, err = c.client.Send(ctx, &messaging.Message{
+		_, err = client.Send(ctx, &messaging.Message{
 			Data: eventMap,
 			Android: &messaging.AndroidConfig{
 				Priority: "high",
@@ -70,15 +127,155 @@ func (c *Client) Send(ctx context.Context, messages map[string]types.Event) (map
 		})
 
 		if err != nil {
-			errs[address] = fmt.Errorf("can't send message to %s: %w", address, err)
+			errs[address] = types.NewSendError(classifyError(err), fmt.Errorf("can't send message to %s: %w", address, err))
 		}
 	}
 
 	return errs, nil
 }
 
+// Check validates the credentials by sending a dry-run message to a topic, so
+// no device token is required.
+func (c *Client) Check(ctx context.Context) error {
+	c.mux.RLock()
+	client := c.client
+	c.mux.RUnlock()
+
+	if client == nil {
+		return errors.New("client is not opened")
+	}
+
+	if _, err := client.SendDryRun(ctx, &messaging.Message{Topic: checkTopic}); err != nil {
+		return fmt.Errorf("can't send dry-run message: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) Close(ctx context.Context) error {
+	if c.stop != nil {
+		close(c.stop)
+		c.wg.Wait()
+		c.stop = nil
+	}
+
+	c.mux.Lock()
 	c.client = nil
+	c.mux.Unlock()
 
 	return nil
 }
+
+// Reload re-reads the credentials and replaces the messaging client if they
+// have changed. The current client is kept on error.
+func (c *Client) Reload(ctx context.Context) (bool, error) {
+	creds, err := c.loadCredentials()
+	if err != nil {
+		return false, err
+	}
+
+	c.mux.RLock()
+	unchanged := bytes.Equal(creds, c.credentials)
+	c.mux.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	client, err := newMessagingClient(ctx, creds, c.transport)
+	if err != nil {
+		return false, err
+	}
+
+	c.mux.Lock()
+	c.client = client
+	c.credentials = creds
+	c.mux.Unlock()
+
+	return true, nil
+}
+
+func (c *Client) watch(path string, hup chan os.Signal) {
+	defer c.wg.Done()
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(c.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-hup:
+			c.reload(path)
+		case <-ticker.C:
+			c.reload(path)
+		}
+	}
+}
+
+func (c *Client) reload(path string) {
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+
+	reloaded, err := c.Reload(ctx)
+	if err != nil {
+		c.logger.Error("Can't reload FCM credentials", zap.String("path", path), zap.Error(err))
+		return
+	}
+
+	if reloaded {
+		c.logger.Info("FCM credentials reloaded", zap.String("path", path))
+	}
+}
+
+func (c *Client) loadCredentials() ([]byte, error) {
+	var creds []byte
+	if path := c.options["credentials_file"]; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("can't read credentials file: %w", err)
+		}
+		creds = bytes.TrimSpace(data)
+	} else {
+		creds = []byte(c.options["credentials"])
+	}
+
+	if len(creds) == 0 {
+		return nil, fmt.Errorf("no credentials provided")
+	}
+
+	return creds, nil
+}
+
+func newMessagingClient(ctx context.Context, creds []byte, transport http.RoundTripper) (*messaging.Client, error) {
+	var (
+		config *firebase.Config
+		opts   = []option.ClientOption{option.WithCredentialsJSON(creds)}
+	)
+
+	if transport != nil {
+		// the token source keeps the context for the token refresh requests,
+		// so it must outlive the initialization
+		ctx := context.WithValue(context.WithoutCancel(ctx), oauth2.HTTPClient, &http.Client{Transport: transport})
+
+		credentials, err := google.CredentialsFromJSON(ctx, creds, messagingScopes...)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse credentials: %w", err)
+		}
+
+		config = &firebase.Config{ProjectID: credentials.ProjectID}
+		opts = []option.ClientOption{option.WithHTTPClient(oauth2.NewClient(ctx, credentials.TokenSource))}
+	}
+
+	app, err := firebase.NewApp(ctx, config, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("can't create firebase app: %w", err)
+	}
+
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't create firebase messaging client: %w", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,180 @@
+package fcm
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// serviceAccount returns the service account credentials of the project with
+// a freshly generated key. The messaging client is created without requests,
+// so the credentials don't have to be real.
+func serviceAccount(t *testing.T, projectID string) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("can't generate key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("can't marshal key: %v", err)
+	}
+
+	creds, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     projectID,
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "sms-gateway@" + projectID + ".iam.gserviceaccount.com",
+		"token_uri":      "https://oauth2.googleapis.com/token",
+	})
+	if err != nil {
+		t.Fatalf("can't marshal credentials: %v", err)
+	}
+
+	return creds
+}
+
+func writeCredentials(t *testing.T, path string, creds []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, creds, 0o600); err != nil {
+		t.Fatalf("can't write credentials: %v", err)
+	}
+}
+
+// openClient opens the client reading the credentials from the file.
+func openClient(t *testing.T, creds []byte, interval time.Duration) (*Client, string, *observer.ObservedLogs) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	writeCredentials(t, path, creds)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	c, err := New(map[string]string{"credentials_file": path}, nil, zap.New(core))
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	c.checkInterval = interval
+
+	if err := c.Open(context.Background()); err != nil {
+		t.Fatalf("can't open client: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	return c, path, logs
+}
+
+func (c *Client) current() ([]byte, *messaging.Client) {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+
+	return c.credentials, c.client
+}
+
+// waitReloaded waits for the credentials to be reloaded by the watcher.
+func waitReloaded(t *testing.T, c *Client, logs *observer.ObservedLogs, creds []byte) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage("FCM credentials reloaded").Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("credentials were not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if current, _ := c.current(); string(current) != string(creds) {
+		t.Errorf("credentials were not replaced")
+	}
+}
+
+func TestClient_Reload(t *testing.T) {
+	c, path, _ := openClient(t, serviceAccount(t, "project-1"), time.Hour)
+	ctx := context.Background()
+
+	if reloaded, err := c.Reload(ctx); err != nil || reloaded {
+		t.Fatalf("Reload() = %v, %v; want false for unchanged credentials", reloaded, err)
+	}
+
+	creds := serviceAccount(t, "project-2")
+	writeCredentials(t, path, creds)
+	_, before := c.current()
+
+	if reloaded, err := c.Reload(ctx); err != nil || !reloaded {
+		t.Fatalf("Reload() = %v, %v; want true for changed credentials", reloaded, err)
+	}
+	current, client := c.current()
+	if string(current) != string(creds) {
+		t.Error("credentials were not replaced")
+	}
+	if client == before {
+		t.Error("messaging client was not replaced")
+	}
+
+	writeCredentials(t, path, []byte(" \n"))
+	if reloaded, err := c.Reload(ctx); err == nil || reloaded {
+		t.Fatalf("Reload() = %v, %v; want error for empty credentials", reloaded, err)
+	}
+	if kept, keptClient := c.current(); string(kept) != string(creds) || keptClient != client {
+		t.Error("client was not kept on error")
+	}
+}
+
+func TestClient_WatchFile(t *testing.T) {
+	c, path, logs := openClient(t, serviceAccount(t, "project-1"), 10*time.Millisecond)
+
+	creds := serviceAccount(t, "project-2")
+	writeCredentials(t, path, creds)
+
+	waitReloaded(t, c, logs, creds)
+}
+
+func TestClient_WatchFileError(t *testing.T) {
+	creds := serviceAccount(t, "project-1")
+	c, path, logs := openClient(t, creds, 10*time.Millisecond)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("can't remove credentials: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for logs.FilterMessage("Can't reload FCM credentials").Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("reload error was not logged")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if current, client := c.current(); string(current) != string(creds) || client == nil {
+		t.Error("client was not kept on error")
+	}
+}
+
+func TestClient_WatchSIGHUP(t *testing.T) {
+	// the file is not polled during the test, only the signal reloads it
+	c, path, logs := openClient(t, serviceAccount(t, "project-1"), time.Hour)
+
+	creds := serviceAccount(t, "project-2")
+	writeCredentials(t, path, creds)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("can't send SIGHUP: %v", err)
+	}
+
+	waitReloaded(t, c, logs, creds)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"firebase.google.com/go/v4/messaging"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
 )
 
@@ -18,3 +19,23 @@ func eventToMap(event types.Event) (map[string]string, error) {
 		"data":  string(json),
 	}, nil
 }
+
+// classifyError maps FCM error codes to push error reasons.
+func classifyError(err error) types.ErrorReason {
+	switch {
+	case messaging.IsQuotaExceeded(err):
+		return types.ErrorReasonQuota
+	case messaging.IsUnregistered(err), messaging.IsSenderIDMismatch(err):
+		return types.ErrorReasonUnregistered
+	case messaging.IsUnavailable(err):
+		return types.ErrorReasonUnavailable
+	case messaging.IsInvalidArgument(err):
+		return types.ErrorReasonInvalidArgument
+	case messaging.IsThirdPartyAuthError(err):
+		return types.ErrorReasonAuth
+	case messaging.IsInternal(err):
+		return types.ErrorReasonInternal
+	default:
+		return types.ErrorReasonUnknown
+	}
+}
@@ -0,0 +1,120 @@
+package fcm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
+	"go.uber.org/zap"
+)
+
+// roundTripFunc is the transport answering the requests without the network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			// the delay is longer than the retries wait, so 503 isn't retried
+			"Retry-After": {"3600"},
+		},
+		Body:    io.NopCloser(strings.NewReader(body)),
+		Request: req,
+	}
+}
+
+// fcmError is the error response of FCM v1 API with the FCM error code.
+func fcmError(status int, code string) string {
+	return fmt.Sprintf(
+		`{"error":{"code":%d,"message":"failed","status":"%s","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"%s"}]}}`,
+		status, code, code,
+	)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		token  string
+		status int
+		body   string
+		want   types.ErrorReason
+	}{
+		{token: "quota", status: http.StatusTooManyRequests, body: fcmError(http.StatusTooManyRequests, "QUOTA_EXCEEDED"), want: types.ErrorReasonQuota},
+		{token: "unregistered", status: http.StatusNotFound, body: fcmError(http.StatusNotFound, "UNREGISTERED"), want: types.ErrorReasonUnregistered},
+		{token: "mismatch", status: http.StatusForbidden, body: fcmError(http.StatusForbidden, "SENDER_ID_MISMATCH"), want: types.ErrorReasonUnregistered},
+		{token: "unavailable", status: http.StatusServiceUnavailable, body: fcmError(http.StatusServiceUnavailable, "UNAVAILABLE"), want: types.ErrorReasonUnavailable},
+		{token: "invalid", status: http.StatusBadRequest, body: fcmError(http.StatusBadRequest, "INVALID_ARGUMENT"), want: types.ErrorReasonInvalidArgument},
+		{token: "auth", status: http.StatusUnauthorized, body: fcmError(http.StatusUnauthorized, "THIRD_PARTY_AUTH_ERROR"), want: types.ErrorReasonAuth},
+		{token: "internal", status: http.StatusInternalServerError, body: fcmError(http.StatusInternalServerError, "INTERNAL"), want: types.ErrorReasonInternal},
+		// the status of the request isn't the evidence of the token rot
+		{token: "not-found", status: http.StatusNotFound, body: `{"error":{"code":404,"message":"not found","status":"NOT_FOUND"}}`, want: types.ErrorReasonUnknown},
+		{token: "not-json", status: http.StatusBadGateway, body: "bad gateway", want: types.ErrorReasonUnknown},
+	}
+
+	byToken := make(map[string]int, len(tests))
+	messages := make(map[string]types.Event, len(tests))
+	for i, tt := range tests {
+		byToken[tt.token] = i
+		messages[tt.token] = types.Event{Type: "MessageEnqueued"}
+	}
+
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "oauth2.googleapis.com" {
+			return jsonResponse(req, http.StatusOK, `{"access_token":"token","token_type":"Bearer","expires_in":3600}`), nil
+		}
+
+		var payload struct {
+			Message struct {
+				Token string `json:"token"`
+			} `json:"message"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		i, ok := byToken[payload.Message.Token]
+		if !ok {
+			return nil, fmt.Errorf("unexpected token %q", payload.Message.Token)
+		}
+
+		return jsonResponse(req, tests[i].status, tests[i].body), nil
+	})
+
+	c, err := New(map[string]string{"credentials": string(serviceAccount(t, "project-1"))}, transport, zap.NewNop())
+	if err != nil {
+		t.Fatalf("can't create client: %v", err)
+	}
+	if err := c.Open(context.Background()); err != nil {
+		t.Fatalf("can't open client: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	errs, err := c.Send(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if errs[tt.token] == nil {
+				t.Fatal("Send() error = nil, want error")
+			}
+			if got := types.ReasonOf(errs[tt.token]); got != tt.want {
+				t.Errorf("reason = %s, want %s (%v)", got, tt.want, errs[tt.token])
+			}
+		})
+	}
+
+	if got := classifyError(errors.New("failed")); got != types.ErrorReasonUnknown {
+		t.Errorf("classifyError() of other error = %s, want %s", got, types.ErrorReasonUnknown)
+	}
+}
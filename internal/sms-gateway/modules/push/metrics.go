@@ -1,6 +1,7 @@
 package push
 
 import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -53,8 +54,8 @@ func newMetrics() *metrics {
 			Namespace: "sms",
 			Subsystem: "push",
 			Name:      "errors_total",
-			Help:      "Total number of errors",
-		}, []string{}),
+			Help:      "Total number of errors by reason",
+		}, []string{"reason"}),
 	}
 }
 
@@ -70,6 +71,6 @@ func (m *metrics) IncBlacklist(operation BlacklistOperation) {
 	m.blacklistCounter.WithLabelValues(string(operation)).Inc()
 }
 
-func (m *metrics) IncError(v int) {
-	m.errorsCounter.WithLabelValues().Add(float64(v))
+func (m *metrics) IncError(reason types.ErrorReason, v int) {
+	m.errorsCounter.WithLabelValues(string(reason)).Add(float64(v))
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/custom"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/fcm"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/upstream"
 	"go.uber.org/fx"
@@ -16,13 +19,19 @@ var Module = fx.Module(
 		return log.Named("push")
 	}),
 	fx.Provide(newMetrics, fx.Private),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New(blacklistCacheName)
+	}, fx.Private),
+	fx.Provide(cache.AsPreloader(newBlacklistPreloader)),
 	fx.Provide(
-		func(cfg Config, lc fx.Lifecycle) (c client, err error) {
+		func(cfg Config, egressSvc *egress.Service, lc fx.Lifecycle, logger *zap.Logger) (c client, err error) {
 			switch cfg.Mode {
 			case ModeFCM:
-				c, err = fcm.New(cfg.ClientOptions)
+				c, err = fcm.New(cfg.ClientOptions, egressSvc.Transport(egress.DestinationFCM), logger)
 			case ModeUpstream:
-				c, err = upstream.New(cfg.ClientOptions)
+				c, err = upstream.New(cfg.ClientOptions, egressSvc.Transport(egress.DestinationUpstream))
+			case ModeCustom:
+				c, err = custom.New(cfg.ClientOptions, egressSvc.Transport(egress.DestinationPush))
 			default:
 				return nil, errors.New("invalid push mode")
 			}
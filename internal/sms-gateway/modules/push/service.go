@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
+	pkgcache "github.com/android-sms-gateway/server/pkg/cache"
 	"github.com/capcom6/go-helpers/cache"
 	"github.com/capcom6/go-helpers/maps"
 
@@ -27,8 +28,9 @@ type Params struct {
 
 	Config Config
 
-	Client  client
-	Metrics *metrics
+	Client    client
+	Blacklist pkgcache.Cache
+	Metrics   *metrics
 
 	Logger *zap.Logger
 }
@@ -40,7 +42,12 @@ type Service struct {
 	metrics *metrics
 
 	cache     *cache.Cache[eventWrapper]
-	blacklist *cache.Cache[struct{}]
+	blacklist pkgcache.Cache
+	results   *cache.Cache[DeliveryResult]
+	// owners maps the tokens to the callers which requested the notifications
+	owners *cache.Cache[string]
+
+	blacklisted blacklistedHandlers
 
 	logger *zap.Logger
 }
@@ -59,9 +66,13 @@ func New(params Params) *Service {
 		client:  params.Client,
 		metrics: params.Metrics,
 
-		cache: cache.New[eventWrapper](cache.Config{}),
-		blacklist: cache.New[struct{}](cache.Config{
-			TTL: blacklistTimeout,
+		cache:     cache.New[eventWrapper](cache.Config{}),
+		blacklist: params.Blacklist,
+		results: cache.New[DeliveryResult](cache.Config{
+			TTL: resultsTimeout,
+		}),
+		owners: cache.New[string](cache.Config{
+			TTL: resultsTimeout,
 		}),
 
 		logger: params.Logger,
@@ -83,11 +94,19 @@ func (s *Service) Run(ctx context.Context) {
 	}
 }
 
+// OnBlacklisted registers the handler called with the event which isn't
+// delivered because the token is blacklisted, so it's delivered by other means.
+func (s *Service) OnBlacklisted(handler BlacklistedHandler) {
+	s.blacklisted.add(handler)
+}
+
 // Enqueue adds the data to the cache and immediately sends all messages if the debounce is 0.
 func (s *Service) Enqueue(token string, event types.Event) error {
-	if _, err := s.blacklist.Get(token); err == nil {
+	if s.IsBlacklisted(token) {
 		s.metrics.IncBlacklist(BlacklistOperationSkipped)
+		s.setResult(token, DeliveryStatusBlacklisted, types.ErrorReasonUnregistered)
 		s.logger.Debug("Skipping blacklisted token", zap.String("token", token))
+		s.blacklisted.emit(token, event)
 		return nil
 	}
 
@@ -102,10 +121,73 @@ func (s *Service) Enqueue(token string, event types.Event) error {
 	}
 
 	s.metrics.IncEnqueued(string(event.Type))
+	s.setResult(token, DeliveryStatusPending, "")
 
 	return nil
 }
 
+// Unblacklist resumes the notifications for the token registered again by the
+// device.
+func (s *Service) Unblacklist(token string) {
+	if err := s.blacklist.Delete(context.Background(), token); err != nil {
+		s.logger.Warn("Can't remove from blacklist", zap.String("token", token), zap.Error(err))
+	}
+}
+
+// IsBlacklisted reports whether the notifications for the token are skipped
+// after the push provider has repeatedly rejected it.
+func (s *Service) IsBlacklisted(token string) bool {
+	_, err := s.blacklist.Get(context.Background(), token)
+	return err == nil
+}
+
+// Results returns the outcome of the last notification for each of the known
+// tokens. Results are kept for an hour.
+func (s *Service) Results(tokens []string) map[string]DeliveryResult {
+	results := make(map[string]DeliveryResult, len(tokens))
+	for _, token := range tokens {
+		if result, err := s.results.Get(token); err == nil {
+			results[token] = result
+		}
+	}
+
+	return results
+}
+
+// Claim records the owner as the caller which requested the notification to
+// the token, so only the owner gets its results by ResultsOf. The last owner
+// of the token is kept.
+func (s *Service) Claim(owner, token string) {
+	if err := s.owners.Set(token, owner); err != nil {
+		s.logger.Warn("Can't store token owner", zap.String("token", token), zap.Error(err))
+	}
+}
+
+// ResultsOf is like Results, but returns the results of the tokens claimed by
+// the owner only.
+func (s *Service) ResultsOf(owner string, tokens []string) map[string]DeliveryResult {
+	owned := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if claimed, err := s.owners.Get(token); err == nil && claimed == owner {
+			owned = append(owned, token)
+		}
+	}
+
+	return s.Results(owned)
+}
+
+func (s *Service) setResult(token string, status DeliveryStatus, reason types.ErrorReason) {
+	result := DeliveryResult{
+		Status:    status,
+		Reason:    reason,
+		UpdatedAt: time.Now(),
+	}
+
+	if err := s.results.Set(token, result); err != nil {
+		s.logger.Warn("Can't store delivery result", zap.String("token", token), zap.Error(err))
+	}
+}
+
 // sendAll sends messages to all targets from the cache after initializing the service.
 func (s *Service) sendAll(ctx context.Context) {
 	targets := s.cache.Drain()
@@ -122,35 +204,49 @@ func (s *Service) sendAll(ctx context.Context) {
 	defer cancel()
 
 	errs, err := s.client.Send(ctx, messages)
-	if len(errs) == 0 && err == nil {
-		s.logger.Info("Messages sent successfully", zap.Int("count", len(messages)))
-		return
-	}
-
 	if err != nil {
-		s.metrics.IncError(len(messages))
+		reason := types.ReasonOf(err)
+		for token := range messages {
+			s.setResult(token, DeliveryStatusFailed, reason)
+		}
+
+		s.metrics.IncError(reason, len(messages))
 		s.logger.Error("Can't send messages", zap.Error(err))
 		return
 	}
 
-	s.metrics.IncError(len(errs))
+	for token := range messages {
+		if _, ok := errs[token]; !ok {
+			s.setResult(token, DeliveryStatusSent, "")
+		}
+	}
+
+	if len(errs) == 0 {
+		s.logger.Info("Messages sent successfully", zap.Int("count", len(messages)))
+		return
+	}
 
 	for token, sendErr := range errs {
-		s.logger.Error("Can't send message", zap.Error(sendErr), zap.String("token", token))
+		reason := types.ReasonOf(sendErr)
+		s.metrics.IncError(reason, 1)
+		s.setResult(token, DeliveryStatusFailed, reason)
+		s.logger.Error("Can't send message", zap.Error(sendErr), zap.String("token", token), zap.String("reason", string(reason)))
 
 		wrapper := targets[token]
 		wrapper.retries++
 
 		if wrapper.retries >= maxRetries {
-			if err := s.blacklist.Set(token, struct{}{}); err != nil {
+			if err := s.blacklist.Set(context.Background(), token, "", pkgcache.WithTTL(blacklistTimeout)); err != nil {
 				s.logger.Warn("Can't add to blacklist", zap.String("token", token), zap.Error(err))
 			}
 
+			s.setResult(token, DeliveryStatusBlacklisted, reason)
 			s.metrics.IncBlacklist(BlacklistOperationAdded)
 			s.metrics.IncRetry(RetryOutcomeMaxAttempts)
 			s.logger.Warn("Retries exceeded, blacklisting token",
 				zap.String("token", token),
 				zap.Duration("ttl", blacklistTimeout))
+			s.blacklisted.emit(token, *wrapper.event)
 			continue
 		}
 
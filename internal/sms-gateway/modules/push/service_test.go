@@ -0,0 +1,95 @@
+package push
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+// testMetrics are shared by the tests, as the metrics are registered once.
+var testMetrics = sync.OnceValue(newMetrics)
+
+func TestService_Blacklisted(t *testing.T) {
+	svc := New(Params{
+		Blacklist: cache.NewMemory(0),
+		Metrics:   testMetrics(),
+		Logger:    zap.NewNop(),
+	})
+
+	var redelivered []Event
+	svc.OnBlacklisted(func(token string, event Event) {
+		if token != "token" {
+			t.Errorf("OnBlacklisted() token = %q, want %q", token, "token")
+		}
+		redelivered = append(redelivered, event)
+	})
+
+	event := Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{"id": "1"}}
+
+	if err := svc.blacklist.Set(context.Background(), "token", ""); err != nil {
+		t.Fatal(err)
+	}
+	if !svc.IsBlacklisted("token") {
+		t.Fatal("IsBlacklisted() = false, want true")
+	}
+
+	if err := svc.Enqueue("token", event); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if len(redelivered) != 1 || redelivered[0].Type != event.Type {
+		t.Errorf("OnBlacklisted() events = %v, want the skipped event", redelivered)
+	}
+	if result := svc.Results([]string{"token"})["token"]; result.Status != DeliveryStatusBlacklisted {
+		t.Errorf("Results() status = %s, want %s", result.Status, DeliveryStatusBlacklisted)
+	}
+
+	svc.Unblacklist("token")
+	if svc.IsBlacklisted("token") {
+		t.Error("IsBlacklisted() after Unblacklist() = true, want false")
+	}
+
+	if err := svc.Enqueue("token", event); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if len(redelivered) != 1 {
+		t.Errorf("OnBlacklisted() called %d times, want once", len(redelivered))
+	}
+}
+
+func TestService_ResultsOf(t *testing.T) {
+	svc := New(Params{
+		Blacklist: cache.NewMemory(0),
+		Metrics:   testMetrics(),
+		Logger:    zap.NewNop(),
+	})
+
+	event := Event{Type: smsgateway.PushMessageEnqueued}
+	for _, token := range []string{"own", "foreign"} {
+		if err := svc.Enqueue(token, event); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	svc.Claim("key:a", "own")
+	svc.Claim("key:b", "foreign")
+
+	results := svc.ResultsOf("key:a", []string{"own", "foreign", "missing"})
+	if len(results) != 1 {
+		t.Fatalf("ResultsOf() = %v, want the own token only", results)
+	}
+	if result, ok := results["own"]; !ok || result.Status != DeliveryStatusPending {
+		t.Errorf("ResultsOf() own = %+v, %v, want %s", result, ok, DeliveryStatusPending)
+	}
+
+	// the token pushed by another caller changes the owner
+	svc.Claim("key:b", "own")
+	if results := svc.ResultsOf("key:a", []string{"own"}); len(results) != 0 {
+		t.Errorf("ResultsOf() after another claim = %v, want none", results)
+	}
+	if results := svc.ResultsOf("key:b", []string{"own", "foreign"}); len(results) != 2 {
+		t.Errorf("ResultsOf() of the new owner = %v, want both tokens", results)
+	}
+}
@@ -2,6 +2,8 @@ package push
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
 )
@@ -11,10 +13,54 @@ type Mode string
 const (
 	ModeFCM      Mode = "fcm"
 	ModeUpstream Mode = "upstream"
+	// ModeCustom sends the notifications to the configured HTTP endpoint.
+	ModeCustom Mode = "custom"
 )
 
 type Event = types.Event
 
+// DeliveryStatus is the state of the last notification enqueued for a token.
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending     DeliveryStatus = "pending"
+	DeliveryStatusSent        DeliveryStatus = "sent"
+	DeliveryStatusFailed      DeliveryStatus = "failed"
+	DeliveryStatusBlacklisted DeliveryStatus = "blacklisted"
+)
+
+// DeliveryResult is the outcome of the last notification enqueued for a token.
+type DeliveryResult struct {
+	Status    DeliveryStatus
+	Reason    types.ErrorReason
+	UpdatedAt time.Time
+}
+
+// BlacklistedHandler is called synchronously with the event which isn't
+// delivered because the token is blacklisted, so it must not block.
+type BlacklistedHandler func(token string, event Event)
+
+type blacklistedHandlers struct {
+	mux      sync.RWMutex
+	handlers []BlacklistedHandler
+}
+
+func (b *blacklistedHandlers) add(handler BlacklistedHandler) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *blacklistedHandlers) emit(token string, event Event) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	for _, h := range b.handlers {
+		h(token, event)
+	}
+}
+
 type client interface {
 	Open(ctx context.Context) error
 	Send(ctx context.Context, messages map[string]types.Event) (map[string]error, error)
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorReason is the classified cause of a push send failure.
+type ErrorReason string
+
+const (
+	// ErrorReasonQuota means the provider rate limit or quota is exceeded.
+	ErrorReasonQuota ErrorReason = "quota_exceeded"
+	// ErrorReasonUnregistered means the token is no longer valid.
+	ErrorReasonUnregistered ErrorReason = "unregistered"
+	// ErrorReasonUnavailable means the provider is temporarily unavailable.
+	ErrorReasonUnavailable ErrorReason = "unavailable"
+	// ErrorReasonInvalidArgument means the request or token is malformed.
+	ErrorReasonInvalidArgument ErrorReason = "invalid_argument"
+	// ErrorReasonAuth means the server credentials were rejected.
+	ErrorReasonAuth ErrorReason = "auth"
+	// ErrorReasonInternal means an internal provider error.
+	ErrorReasonInternal ErrorReason = "internal"
+	// ErrorReasonUnknown is used for unclassified errors.
+	ErrorReasonUnknown ErrorReason = "unknown"
+)
+
+// SendError is a push send failure with the classified reason.
+type SendError struct {
+	Reason ErrorReason
+	Err    error
+}
+
+func NewSendError(reason ErrorReason, err error) *SendError {
+	return &SendError{Reason: reason, Err: err}
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// ReasonOf returns the classified reason of the error.
+func ReasonOf(err error) ErrorReason {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.Reason
+	}
+
+	return ErrorReasonUnknown
+}
+
+// ReasonOfStatus maps the response status of an HTTP provider to the reason.
+// The status is of the whole request, so 404 and 410 mean the endpoint is
+// missing or moved rather than the tokens are, and they aren't reported as
+// unregistered; the rejected tokens are reported by the provider per token.
+func ReasonOfStatus(status int) ErrorReason {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrorReasonQuota
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorReasonAuth
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return ErrorReasonInvalidArgument
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return ErrorReasonUnavailable
+	case status >= http.StatusInternalServerError:
+		return ErrorReasonUnavailable
+	default:
+		return ErrorReasonUnknown
+	}
+}
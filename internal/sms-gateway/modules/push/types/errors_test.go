@@ -0,0 +1,56 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestReasonOf(t *testing.T) {
+	sendErr := NewSendError(ErrorReasonQuota, errors.New("quota"))
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorReason
+	}{
+		{name: "Send error", err: sendErr, want: ErrorReasonQuota},
+		{name: "Wrapped send error", err: fmt.Errorf("can't send: %w", sendErr), want: ErrorReasonQuota},
+		{name: "Other error", err: errors.New("failed"), want: ErrorReasonUnknown},
+		{name: "No error", err: nil, want: ErrorReasonUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReasonOf(tt.err); got != tt.want {
+				t.Errorf("ReasonOf() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReasonOfStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   ErrorReason
+	}{
+		{status: http.StatusTooManyRequests, want: ErrorReasonQuota},
+		{status: http.StatusUnauthorized, want: ErrorReasonAuth},
+		{status: http.StatusForbidden, want: ErrorReasonAuth},
+		{status: http.StatusBadRequest, want: ErrorReasonInvalidArgument},
+		{status: http.StatusUnprocessableEntity, want: ErrorReasonInvalidArgument},
+		{status: http.StatusNotFound, want: ErrorReasonUnavailable},
+		{status: http.StatusGone, want: ErrorReasonUnavailable},
+		{status: http.StatusInternalServerError, want: ErrorReasonUnavailable},
+		{status: http.StatusServiceUnavailable, want: ErrorReasonUnavailable},
+		{status: http.StatusConflict, want: ErrorReasonUnknown},
+		{status: http.StatusMovedPermanently, want: ErrorReasonUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := ReasonOfStatus(tt.status); got != tt.want {
+			t.Errorf("ReasonOfStatus(%d) = %s, want %s", tt.status, got, tt.want)
+		}
+	}
+}
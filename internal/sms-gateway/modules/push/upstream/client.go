@@ -11,21 +11,35 @@ import (
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/push/types"
-	"github.com/capcom6/go-helpers/maps"
 )
 
 const BASE_URL = "https://api.sms-gate.app/upstream/v1"
 
+// maxBatchSize is the max number of notifications sent in a single request.
+const maxBatchSize = 100
+
+type pushRejection struct {
+	Token  string `json:"token"`
+	Reason string `json:"reason"`
+}
+
+type pushResponse struct {
+	Accepted int             `json:"accepted"`
+	Rejected []pushRejection `json:"rejected"`
+}
+
 type Client struct {
-	options map[string]string
+	options   map[string]string
+	transport http.RoundTripper
 
 	client *http.Client
 	mux    sync.Mutex
 }
 
-func New(options map[string]string) (*Client, error) {
+func New(options map[string]string, transport http.RoundTripper) (*Client, error) {
 	return &Client{
-		options: options,
+		options:   options,
+		transport: transport,
 	}, nil
 }
 
@@ -37,7 +51,7 @@ func (c *Client) Open(ctx context.Context) error {
 		return nil
 	}
 
-	c.client = &http.Client{}
+	c.client = &http.Client{Transport: c.transport}
 
 	return nil
 }
@@ -53,7 +67,29 @@ func (c *Client) Send(ctx context.Context, messages map[string]types.Event) (map
 		})
 	}
 
-	payloadBytes, err := json.Marshal(payload)
+	errs := map[string]error{}
+	for start := 0; start < len(payload); start += maxBatchSize {
+		batch := payload[start:min(start+maxBatchSize, len(payload))]
+
+		batchErrs, err := c.sendBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for token, err := range batchErrs {
+			errs[token] = err
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, nil
+	}
+
+	return errs, nil
+}
+
+func (c *Client) sendBatch(ctx context.Context, batch smsgateway.UpstreamPushRequest) (map[string]error, error) {
+	payloadBytes, err := json.Marshal(batch)
 
 	if err != nil {
 		return nil, fmt.Errorf("can't marshal payload: %w", err)
@@ -66,10 +102,13 @@ func (c *Client) Send(ctx context.Context, messages map[string]types.Event) (map
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "android-sms-gateway/1.x (server; golang)")
+	if key := c.options["key"]; key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return c.mapErrors(messages, fmt.Errorf("can't send request: %w", err)), nil
+		return c.mapErrors(batch, types.NewSendError(types.ErrorReasonUnavailable, fmt.Errorf("can't send request: %w", err))), nil
 	}
 
 	defer func() {
@@ -78,16 +117,30 @@ func (c *Client) Send(ctx context.Context, messages map[string]types.Event) (map
 	}()
 
 	if resp.StatusCode >= 400 {
-		return c.mapErrors(messages, fmt.Errorf("unexpected status code: %d", resp.StatusCode)), nil
+		return c.mapErrors(batch, types.NewSendError(types.ReasonOfStatus(resp.StatusCode), fmt.Errorf("unexpected status code: %d", resp.StatusCode))), nil
+	}
+
+	// Older gateways respond without body
+	res := pushResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, nil
+	}
+
+	errs := make(map[string]error, len(res.Rejected))
+	for _, r := range res.Rejected {
+		errs[r.Token] = types.NewSendError(types.ErrorReason(r.Reason), fmt.Errorf("rejected by upstream: %s", r.Reason))
 	}
 
-	return nil, nil
+	return errs, nil
 }
 
-func (c *Client) mapErrors(messages map[string]types.Event, err error) map[string]error {
-	return maps.MapValues(messages, func(e types.Event) error {
-		return err
-	})
+func (c *Client) mapErrors(batch smsgateway.UpstreamPushRequest, err error) map[string]error {
+	errs := make(map[string]error, len(batch))
+	for _, n := range batch {
+		errs[n.Token] = err
+	}
+
+	return errs
 }
 
 func (c *Client) Close(ctx context.Context) error {
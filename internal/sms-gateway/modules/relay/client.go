@@ -0,0 +1,133 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+)
+
+// pageSize is the max number of messages returned by the upstream list
+// request.
+const pageSize = 100
+
+// upstreamError is the error response of the upstream instance.
+type upstreamError struct {
+	Status  int
+	Message string
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("upstream responded with status %d: %s", e.Status, e.Message)
+}
+
+// client calls the 3rd-party API of the upstream instance.
+type client struct {
+	baseURL  string
+	login    string
+	password string
+
+	client *http.Client
+}
+
+func newClient(config Config, transport http.RoundTripper) *client {
+	return &client{
+		baseURL:  strings.TrimRight(config.URL, "/"),
+		login:    config.Login,
+		password: config.Password,
+
+		client: &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}
+}
+
+// Send enqueues the message on the upstream instance and returns its
+// upstream state.
+func (c *client) Send(ctx context.Context, message smsgateway.Message) (smsgateway.GetMessageResponse, error) {
+	res := smsgateway.GetMessageResponse{}
+	err := c.do(ctx, http.MethodPost, "/messages", nil, message, &res)
+
+	return res, err
+}
+
+// List returns the page of the upstream messages created since the given
+// time.
+func (c *client) List(ctx context.Context, since time.Time, offset int) ([]smsgateway.GetMessageResponse, error) {
+	query := url.Values{}
+	query.Set("from", since.UTC().Format(time.RFC3339))
+	query.Set("limit", strconv.Itoa(pageSize))
+	query.Set("offset", strconv.Itoa(offset))
+
+	res := []smsgateway.GetMessageResponse{}
+	err := c.do(ctx, http.MethodGet, "/messages", query, nil, &res)
+
+	return res, err
+}
+
+// Erase erases the data of the phone number on the upstream instance and
+// returns the number of the erased upstream messages.
+func (c *client) Erase(ctx context.Context, phoneNumber string) (int64, error) {
+	res := struct {
+		Messages int64 `json:"messages"`
+	}{}
+	err := c.do(ctx, http.MethodPost, "/privacy/erase", nil, map[string]string{"phoneNumber": phoneNumber}, &res)
+
+	return res.Messages, err
+}
+
+func (c *client) do(ctx context.Context, method, path string, query url.Values, payload, result any) error {
+	var body io.Reader
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("can't marshal payload: %w", err)
+		}
+		body = bytes.NewReader(payloadBytes)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "android-sms-gateway/1.x (server; golang)")
+	req.SetBasicAuth(c.login, c.password)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't send request: %w", err)
+	}
+
+	defer func() {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= 400 {
+		errResp := smsgateway.ErrorResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return &upstreamError{Status: resp.StatusCode, Message: errResp.Message}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("can't decode response: %w", err)
+	}
+
+	return nil
+}
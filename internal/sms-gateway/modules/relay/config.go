@@ -0,0 +1,27 @@
+package relay
+
+import "time"
+
+type Config struct {
+	// URL is the 3rd-party API URL of the upstream instance, e.g.
+	// "https://api.sms-gate.app/3rdparty/v1". Empty → relaying is disabled.
+	URL string
+	// Login and Password are the credentials of the account on the upstream
+	// instance.
+	Login    string
+	Password string
+
+	// OfflineAfter is the period without activity after which the device is
+	// considered offline.
+	OfflineAfter time.Duration
+	// SyncInterval is the period of the upstream states polling.
+	SyncInterval time.Duration
+	// TrackPeriod is the period after relaying during which the upstream
+	// state of the message is polled.
+	TrackPeriod time.Duration
+}
+
+// Enabled returns true if the upstream instance is configured.
+func (c Config) Enabled() bool {
+	return c.URL != ""
+}
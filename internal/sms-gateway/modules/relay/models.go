@@ -0,0 +1,34 @@
+package relay
+
+import (
+	"fmt"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"gorm.io/gorm"
+)
+
+// stateForwarding is the state of the relayed message claimed for
+// forwarding, the queued messages are Pending.
+const stateForwarding messages.ProcessingState = "Forwarding"
+
+// RelayedMessage maps the local message forwarded to the upstream instance
+// to the upstream message. It's stored before the forwarding, so the queued
+// messages survive the restarts.
+type RelayedMessage struct {
+	ID         uint64                   `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	UserID     string                   `gorm:"not null;type:varchar(32)"`
+	DeviceID   string                   `gorm:"not null;type:char(21);uniqueIndex:unq_relayed_messages_device_message,priority:1"`
+	MessageID  string                   `gorm:"not null;type:varchar(36);uniqueIndex:unq_relayed_messages_device_message,priority:2"`
+	UpstreamID string                   `gorm:"not null;type:varchar(36);index:idx_relayed_messages_upstream"`
+	State      messages.ProcessingState `gorm:"not null;type:varchar(16);index:idx_relayed_messages_state"`
+
+	models.TimedModel
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&RelayedMessage{}); err != nil {
+		return fmt.Errorf("relayed_messages migration failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package relay
+
+import (
+	"context"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/cleaner"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/capcom6/go-infra-fx/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type FxResult struct {
+	fx.Out
+
+	Service   *Service
+	AsCleaner cleaner.Cleanable `group:"cleaners"`
+}
+
+var Module = fx.Module(
+	"relay",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("relay")
+	}),
+	fx.Provide(
+		newRepository,
+		fx.Private,
+	),
+	fx.Provide(func(p ServiceParams) FxResult {
+		svc := NewService(p)
+		return FxResult{
+			Service:   svc,
+			AsCleaner: svc,
+		}
+	}),
+	fx.Invoke(func(lc fx.Lifecycle, messagesSvc *messages.Service, svc *Service) {
+		messagesSvc.SetRelayer(svc)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		lc.Append(fx.Hook{
+			OnStart: func(_ context.Context) error {
+				go svc.Run(ctx)
+				return nil
+			},
+			OnStop: func(_ context.Context) error {
+				cancel()
+				return nil
+			},
+		})
+	}),
+)
+
+func init() {
+	db.RegisterMigration(Migrate)
+}
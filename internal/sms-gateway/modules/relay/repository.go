@@ -0,0 +1,83 @@
+package relay
+
+import (
+	"context"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"gorm.io/gorm"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Insert stores the mapping of the relayed message.
+func (r *repository) Insert(ctx context.Context, message *RelayedMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+// SelectQueued returns the messages waiting for forwarding and the ones whose
+// forwarding was claimed before the given time, but not completed.
+func (r *repository) SelectQueued(ctx context.Context, staleBefore time.Time, limit int) ([]RelayedMessage, error) {
+	items := []RelayedMessage{}
+	err := r.db.WithContext(ctx).
+		Where("state = ? OR (state = ? AND updated_at < ?)", messages.ProcessingStatePending, stateForwarding, staleBefore).
+		Order("id").
+		Limit(limit).
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+// Claim marks the queued message as being forwarded. It returns false if the
+// message has been claimed by another instance.
+func (r *repository) Claim(ctx context.Context, id uint64, staleBefore time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&RelayedMessage{}).
+		Where("id = ? AND (state = ? OR (state = ? AND updated_at < ?))", id, messages.ProcessingStatePending, stateForwarding, staleBefore).
+		Updates(map[string]any{
+			"state":      stateForwarding,
+			"updated_at": gorm.Expr("CURRENT_TIMESTAMP(3)"),
+		})
+
+	return res.RowsAffected == 1, res.Error
+}
+
+// SelectTracked returns the relayed messages created since the given time
+// which are not in a final state yet.
+func (r *repository) SelectTracked(ctx context.Context, since time.Time, limit int) ([]RelayedMessage, error) {
+	items := []RelayedMessage{}
+	err := r.db.WithContext(ctx).
+		Where("state NOT IN ? AND created_at >= ?", []messages.ProcessingState{messages.ProcessingStateDelivered, messages.ProcessingStateFailed}, since).
+		Order("id").
+		Limit(limit).
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+// UpdateState stores the last applied upstream state of the relayed message.
+func (r *repository) UpdateState(ctx context.Context, id uint64, state messages.ProcessingState) error {
+	return r.db.WithContext(ctx).
+		Model(&RelayedMessage{}).
+		Where("id = ?", id).
+		Update("state", state).
+		Error
+}
+
+// Clean removes the mappings created before the given time.
+func (r *repository) Clean(ctx context.Context, until time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("created_at < ?", until).
+		Delete(&RelayedMessage{})
+	return res.RowsAffected, res.Error
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,376 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// forwardBatchSize is the max number of queued messages forwarded at
+	// once.
+	forwardBatchSize = 100
+	// forwardLease is the time after which the message claimed for forwarding
+	// by the stopped instance is forwarded again.
+	forwardLease = 2 * time.Minute
+	// syncBatchSize is the max number of relayed messages tracked by a single
+	// sync.
+	syncBatchSize = 500
+	// syncMargin extends the upstream list period to compensate the clock
+	// difference between the instances.
+	syncMargin = 5 * time.Minute
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	Relayed *repository
+
+	MessagesSvc *messages.Service
+	WebhooksSvc *webhooks.Service
+	EgressSvc   *egress.Service
+
+	Logger *zap.Logger
+}
+
+// Service forwards the messages which can't be served by the devices of the
+// user to the upstream instance and relays the upstream states back to the
+// local messages.
+type Service struct {
+	config Config
+
+	relayed *repository
+	client  *client
+
+	messagesSvc *messages.Service
+	webhooksSvc *webhooks.Service
+
+	// queued wakes up the forwarding of the queued messages
+	queued chan struct{}
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		config: params.Config,
+
+		relayed: params.Relayed,
+		client:  newClient(params.Config, params.EgressSvc.Transport(egress.DestinationUpstream)),
+
+		messagesSvc: params.MessagesSvc,
+		webhooksSvc: params.WebhooksSvc,
+
+		queued: make(chan struct{}, 1),
+
+		logger: params.Logger,
+	}
+}
+
+// ShouldRelay returns true if relaying is enabled and none of the devices
+// was online within the configured period, so the message should be
+// forwarded to the upstream instance.
+func (s *Service) ShouldRelay(devices []models.Device) bool {
+	if !s.config.Enabled() || len(devices) == 0 {
+		return false
+	}
+
+	return allOffline(devices, time.Now().Add(-s.config.OfflineAfter))
+}
+
+// Relay stores the message enqueued to the offline device in the queue of
+// the forwarding, it's failed if it can't be stored.
+func (s *Service) Relay(device models.Device, message messages.MessageOut) {
+	if err := s.relayed.Insert(context.Background(), &RelayedMessage{
+		UserID:     device.UserID,
+		DeviceID:   device.ID,
+		MessageID:  message.ID,
+		UpstreamID: upstreamID(device.ID, message.ID),
+		State:      messages.ProcessingStatePending,
+	}); err != nil {
+		s.logger.Error("Can't queue relayed message",
+			zap.String("device_id", device.ID),
+			zap.String("message_id", message.ID),
+			zap.Error(err),
+		)
+		s.fail(device, message, "Relay failed: can't queue message")
+		return
+	}
+
+	s.wake()
+}
+
+// wake starts the forwarding of the queued messages.
+func (s *Service) wake() {
+	select {
+	case s.queued <- struct{}{}:
+	default:
+	}
+}
+
+// ErasePhoneNumber erases the relayed copies of the messages to the phone
+// number on the upstream instance and returns the number of the erased
+// upstream messages. The upstream account is shared by the users, so the
+// copies relayed for the other users to the number are erased too.
+func (s *Service) ErasePhoneNumber(ctx context.Context, phoneNumber string) (int64, error) {
+	if !s.config.Enabled() {
+		return 0, nil
+	}
+
+	n, err := s.client.Erase(ctx, phoneNumber)
+	if err != nil {
+		return 0, fmt.Errorf("can't erase upstream messages: %w", err)
+	}
+
+	return n, nil
+}
+
+// Run forwards the scheduled messages and polls the upstream states until the
+// context is canceled.
+func (s *Service) Run(ctx context.Context) {
+	if !s.config.Enabled() {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.SyncInterval)
+	defer ticker.Stop()
+
+	// the messages queued before the restart
+	s.wake()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.queued:
+			s.forwardQueued(ctx)
+		case <-ticker.C:
+			s.forwardQueued(ctx)
+			if err := s.sync(ctx); err != nil {
+				s.logger.Error("Can't sync relayed messages", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Clean removes the mappings of the messages which are not tracked anymore.
+func (s *Service) Clean(ctx context.Context) error {
+	n, err := s.relayed.Clean(ctx, time.Now().Add(-s.config.TrackPeriod))
+
+	s.logger.Info("Cleaned relayed messages", zap.Int64("count", n))
+	return err
+}
+
+// forwardQueued forwards the batch of the queued messages, the next batch is
+// forwarded after it.
+func (s *Service) forwardQueued(ctx context.Context) {
+	staleBefore := time.Now().Add(-forwardLease)
+
+	queued, err := s.relayed.SelectQueued(ctx, staleBefore, forwardBatchSize)
+	if err != nil {
+		s.logger.Error("Can't select queued messages", zap.Error(err))
+		return
+	}
+
+	for _, m := range queued {
+		ok, err := s.relayed.Claim(ctx, m.ID, staleBefore)
+		if err != nil {
+			s.logger.Error("Can't claim queued message", zap.String("message_id", m.MessageID), zap.Error(err))
+			return
+		}
+		if ok {
+			s.forward(ctx, m)
+		}
+	}
+
+	if len(queued) == forwardBatchSize {
+		s.wake()
+	}
+}
+
+// forward sends the claimed message to the upstream instance. The upstream ID
+// is passed as the message ID, so the repeated forwarding is rejected as the
+// duplicate.
+func (s *Service) forward(ctx context.Context, m RelayedMessage) {
+	logger := s.logger.With(
+		zap.String("device_id", m.DeviceID),
+		zap.String("message_id", m.MessageID),
+		zap.String("upstream_id", m.UpstreamID),
+	)
+
+	message, err := s.messagesSvc.GetMessage(models.User{ID: m.UserID}, m.MessageID)
+	if errors.Is(err, messages.ErrMessageNotFound) {
+		logger.Warn("Relayed message is not found")
+		s.complete(ctx, logger, m, messages.ProcessingStateFailed)
+		return
+	}
+	if err != nil {
+		// the claim expires, so the message is forwarded later
+		logger.Error("Can't get relayed message", zap.Error(err))
+		return
+	}
+
+	req := messageToUpstream(message)
+	req.ID = m.UpstreamID
+
+	_, err = s.client.Send(ctx, req)
+	if upstreamErr := (*upstreamError)(nil); errors.As(err, &upstreamErr) && upstreamErr.Status == http.StatusConflict {
+		// forwarded before the restart
+		err = nil
+	}
+	if err != nil {
+		logger.Error("Can't relay message", zap.Error(err))
+		s.fail(models.Device{ID: m.DeviceID, UserID: m.UserID}, message, fmt.Sprintf("Relay failed: %s", err.Error()))
+		s.complete(ctx, logger, m, messages.ProcessingStateFailed)
+		return
+	}
+
+	s.complete(ctx, logger, m, messages.ProcessingStateProcessed)
+	logger.Info("Message relayed")
+}
+
+// complete stores the state of the forwarded message. The Processed messages
+// are tracked until the final upstream state.
+func (s *Service) complete(ctx context.Context, logger *zap.Logger, m RelayedMessage, state messages.ProcessingState) {
+	if err := s.relayed.UpdateState(ctx, m.ID, state); err != nil {
+		logger.Error("Can't store relayed message state", zap.Error(err))
+	}
+}
+
+// fail marks all recipients of the message that can't be relayed as failed.
+func (s *Service) fail(device models.Device, message messages.MessageOut, reason string) {
+	now := time.Now()
+	update := messages.MessageStateIn{
+		ID:         message.ID,
+		State:      messages.ProcessingStateFailed,
+		Recipients: make([]smsgateway.RecipientState, len(message.PhoneNumbers)),
+		States:     map[string]time.Time{string(messages.ProcessingStateFailed): now},
+	}
+	for i, phoneNumber := range message.PhoneNumbers {
+		update.Recipients[i] = smsgateway.RecipientState{
+			PhoneNumber: phoneNumber,
+			State:       smsgateway.ProcessingStateFailed,
+			Error:       &reason,
+		}
+	}
+
+	if err := s.messagesSvc.UpdateState(device.ID, update); err != nil {
+		s.logger.Error("Can't fail relayed message", zap.String("device_id", device.ID), zap.String("message_id", message.ID), zap.Error(err))
+		return
+	}
+
+	for _, r := range update.Recipients {
+		s.webhooksSvc.Emit(device.UserID, &device.ID, smsgateway.WebhookEventSmsFailed, map[string]any{
+			"messageId":   message.ID,
+			"phoneNumber": r.PhoneNumber,
+			"failedAt":    now,
+			"reason":      reason,
+			"errorCode":   messages.NormalizeDeliveryError(&reason),
+		})
+	}
+}
+
+// sync polls the upstream states of the tracked messages and applies the
+// changed ones.
+func (s *Service) sync(ctx context.Context) error {
+	tracked, err := s.relayed.SelectTracked(ctx, time.Now().Add(-s.config.TrackPeriod), syncBatchSize)
+	if err != nil {
+		return fmt.Errorf("can't select tracked messages: %w", err)
+	}
+	if len(tracked) == 0 {
+		return nil
+	}
+
+	// the same upstream message is returned for duplicates
+	pending := make(map[string][]RelayedMessage, len(tracked))
+	since := tracked[0].CreatedAt
+	for _, m := range tracked {
+		pending[m.UpstreamID] = append(pending[m.UpstreamID], m)
+		if m.CreatedAt.Before(since) {
+			since = m.CreatedAt
+		}
+	}
+	since = since.Add(-syncMargin)
+
+	for offset := 0; len(pending) > 0; offset += pageSize {
+		page, err := s.client.List(ctx, since, offset)
+		if err != nil {
+			return fmt.Errorf("can't list upstream messages: %w", err)
+		}
+
+		for _, upstream := range page {
+			for _, m := range pending[upstream.ID] {
+				s.apply(ctx, m, upstream)
+			}
+			delete(pending, upstream.ID)
+		}
+
+		if len(page) < pageSize {
+			break
+		}
+	}
+
+	return nil
+}
+
+// apply updates the local message with the upstream state and delivers the
+// webhooks of the changed recipients.
+func (s *Service) apply(ctx context.Context, m RelayedMessage, upstream smsgateway.GetMessageResponse) {
+	logger := s.logger.With(
+		zap.String("device_id", m.DeviceID),
+		zap.String("message_id", m.MessageID),
+		zap.String("upstream_id", upstream.ID),
+	)
+
+	state := messages.ProcessingState(upstream.State)
+	if state == messages.ProcessingStatePending {
+		state = messages.ProcessingStateProcessed
+	}
+	if state == m.State {
+		return
+	}
+
+	local, err := s.messagesSvc.GetState(models.User{ID: m.UserID}, m.MessageID)
+	if err != nil {
+		logger.Warn("Can't get relayed message", zap.Error(err))
+		return
+	}
+
+	update := messages.MessageStateIn{
+		ID:     m.MessageID,
+		State:  state,
+		States: upstream.States,
+	}
+
+	events := []event{}
+	// phone numbers of the hashed upstream messages are lost
+	if !upstream.IsHashed {
+		update.Recipients = upstream.Recipients
+		events = recipientEvents(local, upstream)
+	}
+
+	if err := s.messagesSvc.UpdateState(m.DeviceID, update); err != nil {
+		logger.Error("Can't update relayed message", zap.Error(err))
+		return
+	}
+
+	if err := s.relayed.UpdateState(ctx, m.ID, state); err != nil {
+		logger.Error("Can't store relayed message state", zap.Error(err))
+	}
+
+	for _, e := range events {
+		s.webhooksSvc.Emit(m.UserID, &m.DeviceID, e.Type, e.Payload)
+	}
+}
@@ -0,0 +1,128 @@
+package relay
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+)
+
+// event is the webhook event of the relayed state change.
+type event struct {
+	Type    smsgateway.WebhookEvent
+	Payload map[string]any
+}
+
+// allOffline returns true if none of the devices was seen since the given
+// time. Sandbox devices are never offline as their messages are simulated.
+func allOffline(devices []models.Device, since time.Time) bool {
+	for _, d := range devices {
+		if d.Sandbox || d.LastSeen.After(since) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// messageToUpstream builds the upstream request of the message. The ID and
+// the SIM number are not passed as they are local to this instance.
+func messageToUpstream(message messages.MessageOut) smsgateway.Message {
+	req := smsgateway.Message{
+		PhoneNumbers:       message.PhoneNumbers,
+		IsEncrypted:        message.IsEncrypted,
+		WithDeliveryReport: message.WithDeliveryReport,
+		ValidUntil:         message.ValidUntil,
+		Priority:           message.Priority,
+	}
+
+	if message.TextContent != nil {
+		req.TextMessage = &smsgateway.TextMessage{Text: message.TextContent.Text}
+	} else if message.DataContent != nil {
+		req.DataMessage = &smsgateway.DataMessage{Data: message.DataContent.Data, Port: message.DataContent.Port}
+	}
+
+	return req
+}
+
+// upstreamID returns the ID of the relayed message on the upstream instance.
+// The upstream account is shared by the users, so the ID is unique across the
+// devices.
+func upstreamID(deviceID, messageID string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(deviceID+":"+messageID)))[:32]
+}
+
+// recipientEvents returns the webhook events of the recipients whose upstream
+// state differs from the local one. The local phone numbers may be already
+// hashed.
+func recipientEvents(local messages.MessageStateOut, upstream smsgateway.GetMessageResponse) []event {
+	prev := make(map[string]smsgateway.ProcessingState, len(local.Recipients))
+	for _, r := range local.Recipients {
+		prev[r.PhoneNumber] = r.State
+	}
+
+	events := []event{}
+	for _, r := range upstream.Recipients {
+		key := r.PhoneNumber
+		if local.IsHashed {
+			key = hashPhoneNumber(key)
+		}
+
+		was := prev[key]
+		if was == r.State {
+			continue
+		}
+
+		switch r.State {
+		case smsgateway.ProcessingStateSent, smsgateway.ProcessingStateFailed:
+			events = append(events, newEvent(r.State, local.ID, r, upstream.States))
+		case smsgateway.ProcessingStateDelivered:
+			// the Sent state may be missed between the polls
+			if was != smsgateway.ProcessingStateSent {
+				events = append(events, newEvent(smsgateway.ProcessingStateSent, local.ID, r, upstream.States))
+			}
+			events = append(events, newEvent(r.State, local.ID, r, upstream.States))
+		}
+	}
+
+	return events
+}
+
+// newEvent builds the event of the recipient state with the payload layout of
+// the mobile app. The time of the event is taken from the upstream states
+// history.
+func newEvent(state smsgateway.ProcessingState, messageID string, r smsgateway.RecipientState, states map[string]time.Time) event {
+	eventType, timeField := smsgateway.WebhookEventSmsSent, "sentAt"
+	switch state {
+	case smsgateway.ProcessingStateDelivered:
+		eventType, timeField = smsgateway.WebhookEventSmsDelivered, "deliveredAt"
+	case smsgateway.ProcessingStateFailed:
+		eventType, timeField = smsgateway.WebhookEventSmsFailed, "failedAt"
+	}
+
+	at, ok := states[string(state)]
+	if !ok {
+		at = time.Now()
+	}
+
+	payload := map[string]any{
+		"messageId":   messageID,
+		"phoneNumber": r.PhoneNumber,
+		timeField:     at,
+	}
+	if eventType == smsgateway.WebhookEventSmsFailed && r.Error != nil {
+		payload["reason"] = *r.Error
+		payload["errorCode"] = messages.NormalizeDeliveryError(r.Error)
+	}
+
+	return event{Type: eventType, Payload: payload}
+}
+
+// hashPhoneNumber returns the phone number the way it's stored in the hashed
+// messages.
+func hashPhoneNumber(phoneNumber string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(phoneNumber)))[:16]
+}
@@ -0,0 +1,161 @@
+package relay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+)
+
+func TestAllOffline(t *testing.T) {
+	now := time.Now()
+	since := now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		devices []models.Device
+		want    bool
+	}{
+		{
+			name:    "all offline",
+			devices: []models.Device{{LastSeen: now.Add(-2 * time.Hour)}, {LastSeen: now.Add(-3 * time.Hour)}},
+			want:    true,
+		},
+		{
+			name:    "one online",
+			devices: []models.Device{{LastSeen: now.Add(-2 * time.Hour)}, {LastSeen: now}},
+			want:    false,
+		},
+		{
+			name:    "sandbox",
+			devices: []models.Device{{LastSeen: now.Add(-2 * time.Hour), Sandbox: true}},
+			want:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allOffline(tt.devices, since); got != tt.want {
+				t.Errorf("allOffline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageToUpstream(t *testing.T) {
+	sim := uint8(2)
+	message := messages.MessageOut{
+		MessageIn: messages.MessageIn{
+			ID:           "local-id",
+			TextContent:  &messages.TextMessageContent{Text: "Hello"},
+			PhoneNumbers: []string{"+79990001234"},
+			SimNumber:    &sim,
+		},
+	}
+
+	req := messageToUpstream(message)
+	if req.ID != "" {
+		t.Errorf("ID = %q, want empty", req.ID)
+	}
+	if req.SimNumber != nil {
+		t.Errorf("SimNumber = %v, want nil", *req.SimNumber)
+	}
+	if req.TextMessage == nil || req.TextMessage.Text != "Hello" {
+		t.Errorf("TextMessage = %+v, want Hello", req.TextMessage)
+	}
+	if len(req.PhoneNumbers) != 1 || req.PhoneNumbers[0] != "+79990001234" {
+		t.Errorf("PhoneNumbers = %v", req.PhoneNumbers)
+	}
+}
+
+func TestRecipientEvents(t *testing.T) {
+	sentAt := time.Date(2025, 11, 2, 12, 0, 0, 0, time.UTC)
+	reason := "RESULT_ERROR_NO_SERVICE"
+
+	local := messages.MessageStateOut{
+		MessageStateIn: messages.MessageStateIn{
+			ID: "local-id",
+			Recipients: []smsgateway.RecipientState{
+				{PhoneNumber: "+79990000001", State: smsgateway.ProcessingStatePending},
+				{PhoneNumber: "+79990000002", State: smsgateway.ProcessingStatePending},
+				{PhoneNumber: "+79990000003", State: smsgateway.ProcessingStateSent},
+			},
+		},
+	}
+	upstream := smsgateway.GetMessageResponse{
+		ID:    "upstream-id",
+		State: smsgateway.ProcessingStateDelivered,
+		Recipients: []smsgateway.RecipientState{
+			{PhoneNumber: "+79990000001", State: smsgateway.ProcessingStateDelivered},
+			{PhoneNumber: "+79990000002", State: smsgateway.ProcessingStateFailed, Error: &reason},
+			{PhoneNumber: "+79990000003", State: smsgateway.ProcessingStateSent},
+		},
+		States: map[string]time.Time{string(smsgateway.ProcessingStateSent): sentAt},
+	}
+
+	events := recipientEvents(local, upstream)
+
+	want := []smsgateway.WebhookEvent{
+		smsgateway.WebhookEventSmsSent,
+		smsgateway.WebhookEventSmsDelivered,
+		smsgateway.WebhookEventSmsFailed,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("len(events) = %d, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Errorf("events[%d].Type = %s, want %s", i, e.Type, want[i])
+		}
+		if e.Payload["messageId"] != "local-id" {
+			t.Errorf("events[%d] messageId = %v, want local-id", i, e.Payload["messageId"])
+		}
+	}
+	if events[0].Payload["sentAt"] != sentAt {
+		t.Errorf("sentAt = %v, want %v", events[0].Payload["sentAt"], sentAt)
+	}
+	if events[2].Payload["reason"] != reason {
+		t.Errorf("reason = %v, want %s", events[2].Payload["reason"], reason)
+	}
+	if code, _ := events[2].Payload["errorCode"].(*messages.DeliveryErrorCode); code == nil || *code != messages.DeliveryErrorNoService {
+		t.Errorf("errorCode = %v, want %s", events[2].Payload["errorCode"], messages.DeliveryErrorNoService)
+	}
+}
+
+func TestRecipientEvents_Hashed(t *testing.T) {
+	local := messages.MessageStateOut{
+		IsHashed: true,
+		MessageStateIn: messages.MessageStateIn{
+			ID: "local-id",
+			Recipients: []smsgateway.RecipientState{
+				{PhoneNumber: hashPhoneNumber("+79990000001"), State: smsgateway.ProcessingStateSent},
+			},
+		},
+	}
+	upstream := smsgateway.GetMessageResponse{
+		Recipients: []smsgateway.RecipientState{
+			{PhoneNumber: "+79990000001", State: smsgateway.ProcessingStateDelivered},
+		},
+	}
+
+	events := recipientEvents(local, upstream)
+	if len(events) != 1 || events[0].Type != smsgateway.WebhookEventSmsDelivered {
+		t.Errorf("events = %+v, want single delivered event", events)
+	}
+}
+
+func TestUpstreamID(t *testing.T) {
+	id := upstreamID("device", "message")
+
+	if len(id) > 36 {
+		t.Errorf("len(upstreamID()) = %d, exceeds the message ID limit", len(id))
+	}
+	if upstreamID("device", "message") != id {
+		t.Error("upstreamID() isn't stable")
+	}
+	if upstreamID("other", "message") == id {
+		t.Error("upstreamID() of another device is the same")
+	}
+}
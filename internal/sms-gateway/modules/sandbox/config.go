@@ -0,0 +1,42 @@
+package sandbox
+
+import "time"
+
+type Config struct {
+	// StepDelay is the pause between the simulated state transitions of the
+	// sandbox accounts.
+	StepDelay time.Duration
+	// Workers is the number of the sandbox devices simulated concurrently.
+	Workers int
+	// MaxPending is the number of the pending messages of a sandbox device
+	// after which new messages are rejected, 0 for unlimited.
+	MaxPending int
+
+	// MaxAccounts is the number of the sandbox accounts a user can create
+	// within the AccountsWindow, 0 for unlimited.
+	MaxAccounts    int
+	AccountsWindow time.Duration
+
+	VirtualDevice VirtualDeviceConfig
+}
+
+// VirtualDeviceConfig configures the built-in device which pulls the pending
+// messages like the mobile app and reports simulated outcomes.
+type VirtualDeviceConfig struct {
+	Enabled bool
+
+	// Login and Password are the credentials of the device owner, the user is
+	// created if it doesn't exist.
+	Login    string
+	Password string
+
+	// PollInterval is the period of pending messages polling in addition to
+	// the notifications.
+	PollInterval time.Duration
+	// Latency is the pause before the Sent and Delivered states.
+	Latency time.Duration
+	// FailedRate is the share of the recipients failed on sending.
+	FailedRate float64
+	// DeliveredRate is the share of the sent recipients that are delivered.
+	DeliveredRate float64
+}
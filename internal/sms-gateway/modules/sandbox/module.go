@@ -0,0 +1,36 @@
+package sandbox
+
+import (
+	"context"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"sandbox",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("sandbox")
+	}),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("sandbox")
+	}, fx.Private),
+	fx.Provide(NewService),
+	fx.Invoke(func(lc fx.Lifecycle, messagesSvc *messages.Service, svc *Service) {
+		messagesSvc.SetSimulator(svc)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		lc.Append(fx.Hook{
+			OnStart: func(_ context.Context) error {
+				go svc.Run(ctx)
+				return nil
+			},
+			OnStop: func(_ context.Context) error {
+				cancel()
+				return nil
+			},
+		})
+	}),
+)
@@ -0,0 +1,293 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/auth"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/sse"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/webhooks"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// queueSize is the number of the sandbox devices waiting for the simulation,
+// the devices which don't fit are picked up by the next sweep.
+const queueSize = 1024
+
+// sweepInterval is the period of the lookup of the sandbox devices with
+// pending messages, so the messages missed by the queue and the ones left
+// after a restart are simulated.
+const sweepInterval = time.Minute
+
+// ErrTooManyAccounts is returned when the user has created the maximum number
+// of the sandbox accounts within the window.
+var ErrTooManyAccounts = errors.New("too many sandbox accounts, try again later")
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	AuthSvc     *auth.Service
+	DevicesSvc  *devices.Service
+	MessagesSvc *messages.Service
+	WebhooksSvc *webhooks.Service
+	SseSvc      *sse.Service
+
+	Cache cache.Cache
+
+	Logger *zap.Logger
+}
+
+// Service simulates the processing of the messages enqueued to sandbox
+// devices and runs the virtual device: it moves the messages through the
+// states and delivers the webhooks as a real device would.
+type Service struct {
+	config Config
+
+	authSvc     *auth.Service
+	devicesSvc  *devices.Service
+	messagesSvc *messages.Service
+	webhooksSvc *webhooks.Service
+	sseSvc      *sse.Service
+
+	cache cache.Cache
+
+	queue chan models.Device
+	// scheduled holds the devices queued or being simulated, the value is set
+	// if new messages arrived during the simulation
+	scheduled map[string]bool
+	mu        sync.Mutex
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		config: params.Config,
+
+		authSvc:     params.AuthSvc,
+		devicesSvc:  params.DevicesSvc,
+		messagesSvc: params.MessagesSvc,
+		webhooksSvc: params.WebhooksSvc,
+		sseSvc:      params.SseSvc,
+
+		cache: params.Cache,
+
+		queue:     make(chan models.Device, queueSize),
+		scheduled: make(map[string]bool),
+
+		logger: params.Logger,
+	}
+}
+
+// CreateAccount registers the sandbox user with the virtual device on behalf
+// of the owner. It returns ErrTooManyAccounts if the owner has reached the
+// limit of the accounts within the window.
+func (s *Service) CreateAccount(ctx context.Context, owner models.User, login, password string) (models.Device, error) {
+	if err := s.countAccount(ctx, owner.ID); err != nil {
+		return models.Device{}, err
+	}
+
+	_, device, err := s.authSvc.RegisterSandbox(login, password)
+	if err != nil {
+		return models.Device{}, fmt.Errorf("can't register sandbox user: %w", err)
+	}
+
+	s.logger.Info("Sandbox account created", zap.String("owner_id", owner.ID), zap.String("device_id", device.ID))
+
+	return device, nil
+}
+
+// countAccount counts the new account of the owner within the window.
+func (s *Service) countAccount(ctx context.Context, ownerID string) error {
+	if s.config.MaxAccounts <= 0 {
+		return nil
+	}
+
+	count, err := s.cache.Increment(ctx, "accounts:"+ownerID, 1, cache.WithTTL(s.config.AccountsWindow))
+	if err != nil {
+		return fmt.Errorf("can't count sandbox accounts: %w", err)
+	}
+	if count > int64(s.config.MaxAccounts) {
+		return ErrTooManyAccounts
+	}
+
+	return nil
+}
+
+// Admit reports whether the sandbox device has room for one more pending
+// message.
+func (s *Service) Admit(device models.Device) bool {
+	if s.config.MaxPending <= 0 {
+		return true
+	}
+
+	counts, err := s.messagesSvc.CountPending(context.Background(), []string{device.ID})
+	if err != nil {
+		// the message is stored anyway, so it's simulated later
+		s.logger.Warn("Can't count pending messages", zap.String("device_id", device.ID), zap.Error(err))
+		return true
+	}
+
+	return counts[device.ID] < int64(s.config.MaxPending)
+}
+
+// Simulate schedules the simulation of the pending messages of the sandbox
+// device.
+func (s *Service) Simulate(device models.Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.scheduled[device.ID]; ok {
+		s.scheduled[device.ID] = true
+		return
+	}
+
+	select {
+	case s.queue <- device:
+		s.scheduled[device.ID] = false
+	default:
+		s.logger.Warn("Simulation queue is full, the device is left for the next sweep", zap.String("device_id", device.ID))
+	}
+}
+
+// Run starts the simulations and the virtual device until the context is
+// canceled.
+func (s *Service) Run(ctx context.Context) {
+	if s.config.VirtualDevice.Enabled {
+		go s.runVirtualDevice(ctx)
+	}
+
+	workers := max(s.config.Workers, 1)
+	wg := sync.WaitGroup{}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx)
+		}()
+	}
+	defer wg.Wait()
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep schedules the sandbox devices with pending messages.
+func (s *Service) sweep(ctx context.Context) {
+	items, err := s.messagesSvc.SelectPendingSandbox(ctx)
+	if err != nil {
+		s.logger.Error("Can't select sandbox devices", zap.Error(err))
+		return
+	}
+
+	for _, device := range items {
+		s.Simulate(device)
+	}
+}
+
+// work simulates the queued devices one by one, a device is simulated by a
+// single worker at a time.
+func (s *Service) work(ctx context.Context) {
+	sandbox := outcomes{DeliveredRate: 1}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case device := <-s.queue:
+			for {
+				more, err := s.pullPending(ctx, device, sandbox, s.config.StepDelay)
+				if err != nil {
+					s.logger.Error("Can't simulate pending messages", zap.String("device_id", device.ID), zap.Error(err))
+				}
+
+				s.mu.Lock()
+				again := (more || s.scheduled[device.ID]) && err == nil && ctx.Err() == nil
+				if again {
+					s.scheduled[device.ID] = false
+				} else {
+					delete(s.scheduled, device.ID)
+				}
+				s.mu.Unlock()
+
+				if !again {
+					break
+				}
+			}
+		}
+	}
+}
+
+// simulated is the message being simulated.
+type simulated struct {
+	state              messages.MessageStateIn
+	withDeliveryReport bool
+}
+
+// process moves the messages through the Sent and Delivered states with the
+// delay before each transition.
+func (s *Service) process(ctx context.Context, device models.Device, batch []simulated, o outcomes, delay time.Duration) {
+	logger := s.logger.With(zap.String("device_id", device.ID))
+
+	steps := []func(messages.MessageStateIn, time.Time, outcomes) (messages.MessageStateIn, []event){
+		sendStep,
+		deliverStep,
+	}
+
+	for _, step := range steps {
+		if len(batch) == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		now := time.Now()
+
+		// the device is "seen" like a real one, so it isn't removed as unused
+		if err := s.devicesSvc.SetLastSeen(ctx, map[string]time.Time{device.ID: now}); err != nil {
+			logger.Warn("Can't update last seen time", zap.Error(err))
+		}
+
+		next := batch[:0]
+		for _, message := range batch {
+			state, events := step(message.state, now, o)
+
+			if err := s.messagesSvc.UpdateState(device.ID, state); err != nil {
+				logger.Error("Can't update simulated message state", zap.String("message_id", state.ID), zap.Error(err))
+				continue
+			}
+
+			for _, e := range events {
+				s.webhooksSvc.Emit(device.UserID, &device.ID, e.Type, e.Payload)
+			}
+
+			if state.State == messages.ProcessingStateSent && message.withDeliveryReport {
+				next = append(next, simulated{state: state, withDeliveryReport: true})
+			}
+		}
+		batch = next
+	}
+}
@@ -0,0 +1,68 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func TestService_countAccount(t *testing.T) {
+	s := &Service{
+		config: Config{MaxAccounts: 2, AccountsWindow: time.Hour},
+		cache:  cache.NewMemory(0),
+	}
+	ctx := context.Background()
+
+	for i := range 2 {
+		if err := s.countAccount(ctx, "user"); err != nil {
+			t.Fatalf("account %d: %v", i+1, err)
+		}
+	}
+
+	if err := s.countAccount(ctx, "user"); !errors.Is(err, ErrTooManyAccounts) {
+		t.Errorf("err = %v, want %v", err, ErrTooManyAccounts)
+	}
+	if err := s.countAccount(ctx, "other"); err != nil {
+		t.Errorf("other user: %v", err)
+	}
+}
+
+func TestService_countAccountUnlimited(t *testing.T) {
+	s := &Service{cache: cache.NewMemory(0)}
+
+	for range 100 {
+		if err := s.countAccount(context.Background(), "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestService_Simulate(t *testing.T) {
+	s := &Service{
+		queue:     make(chan models.Device, 1),
+		scheduled: make(map[string]bool),
+		logger:    zap.NewNop(),
+	}
+
+	s.Simulate(models.Device{ID: "first"})
+	if len(s.queue) != 1 {
+		t.Fatalf("queue length = %d, want 1", len(s.queue))
+	}
+
+	// the device being simulated is marked for another pass
+	s.Simulate(models.Device{ID: "first"})
+	if len(s.queue) != 1 || !s.scheduled["first"] {
+		t.Errorf("queue length = %d, scheduled = %v", len(s.queue), s.scheduled)
+	}
+
+	// the device which doesn't fit is left for the sweep
+	s.Simulate(models.Device{ID: "second"})
+	if _, ok := s.scheduled["second"]; ok {
+		t.Error("device is scheduled while the queue is full")
+	}
+}
@@ -0,0 +1,142 @@
+package sandbox
+
+import (
+	"maps"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+)
+
+// FailingSuffix is the ending of the phone numbers which are always failed
+// by the simulation, so the error handling can be tested.
+const FailingSuffix = "0000"
+
+// simulatedError is the error reported for the failing recipients.
+const simulatedError = "RESULT_ERROR_GENERIC_FAILURE: simulated failure"
+
+// event is the webhook event of the simulated transition.
+type event struct {
+	Type    smsgateway.WebhookEvent
+	Payload map[string]any
+}
+
+// outcomes decides the result of the simulated sending for each recipient.
+type outcomes struct {
+	// FailedRate is the share of the recipients failed on sending.
+	FailedRate float64
+	// DeliveredRate is the share of the sent recipients that are delivered.
+	DeliveredRate float64
+
+	rand func() float64
+}
+
+func (o outcomes) fails(phoneNumber string) bool {
+	if strings.HasSuffix(phoneNumber, FailingSuffix) {
+		return true
+	}
+
+	return o.FailedRate > 0 && o.random() < o.FailedRate
+}
+
+func (o outcomes) delivered() bool {
+	return o.DeliveredRate >= 1 || o.random() < o.DeliveredRate
+}
+
+func (o outcomes) random() float64 {
+	if o.rand == nil {
+		return rand.Float64()
+	}
+
+	return o.rand()
+}
+
+// sendStep returns the state of the processed message after sending with
+// the webhook events of the recipients. The message is Sent if at least one
+// recipient is sent, otherwise it's Failed.
+func sendStep(message messages.MessageStateIn, at time.Time, o outcomes) (messages.MessageStateIn, []event) {
+	next := nextState(message)
+	next.State = messages.ProcessingStateFailed
+	next.States[string(messages.ProcessingStateProcessed)] = at
+
+	events := []event{}
+	for i, r := range message.Recipients {
+		if o.fails(r.PhoneNumber) {
+			next.Recipients[i] = failedRecipient(r.PhoneNumber)
+			events = append(events, newEvent(smsgateway.WebhookEventSmsFailed, message.ID, r.PhoneNumber, "failedAt", at))
+			continue
+		}
+
+		next.State = messages.ProcessingStateSent
+		next.Recipients[i] = smsgateway.RecipientState{PhoneNumber: r.PhoneNumber, State: smsgateway.ProcessingStateSent}
+		events = append(events, newEvent(smsgateway.WebhookEventSmsSent, message.ID, r.PhoneNumber, "sentAt", at))
+	}
+
+	next.States[string(next.State)] = at
+
+	return next, events
+}
+
+// deliverStep returns the state of the sent message after the delivery
+// reports with the webhook events of the delivered recipients. The message is
+// Delivered if at least one recipient is delivered, otherwise it stays Sent.
+func deliverStep(message messages.MessageStateIn, at time.Time, o outcomes) (messages.MessageStateIn, []event) {
+	next := nextState(message)
+	next.State = messages.ProcessingStateSent
+
+	events := []event{}
+	for i, r := range message.Recipients {
+		if r.State != smsgateway.ProcessingStateSent || !o.delivered() {
+			next.Recipients[i] = r
+			continue
+		}
+
+		next.State = messages.ProcessingStateDelivered
+		next.Recipients[i] = smsgateway.RecipientState{PhoneNumber: r.PhoneNumber, State: smsgateway.ProcessingStateDelivered}
+		events = append(events, newEvent(smsgateway.WebhookEventSmsDelivered, message.ID, r.PhoneNumber, "deliveredAt", at))
+	}
+
+	next.States[string(next.State)] = at
+
+	return next, events
+}
+
+func nextState(message messages.MessageStateIn) messages.MessageStateIn {
+	next := messages.MessageStateIn{
+		ID:         message.ID,
+		Recipients: make([]smsgateway.RecipientState, len(message.Recipients)),
+		States:     maps.Clone(message.States),
+	}
+	if next.States == nil {
+		next.States = make(map[string]time.Time)
+	}
+
+	return next
+}
+
+func failedRecipient(phoneNumber string) smsgateway.RecipientState {
+	err := simulatedError
+	return smsgateway.RecipientState{
+		PhoneNumber: phoneNumber,
+		State:       smsgateway.ProcessingStateFailed,
+		Error:       &err,
+	}
+}
+
+// newEvent builds the event with the payload layout of the mobile app.
+func newEvent(eventType smsgateway.WebhookEvent, messageID, phoneNumber, timeField string, at time.Time) event {
+	payload := map[string]any{
+		"messageId":   messageID,
+		"phoneNumber": phoneNumber,
+		timeField:     at,
+	}
+	if eventType == smsgateway.WebhookEventSmsFailed {
+		reason := simulatedError
+		payload["reason"] = reason
+		payload["errorCode"] = messages.NormalizeDeliveryError(&reason)
+	}
+
+	return event{Type: eventType, Payload: payload}
+}
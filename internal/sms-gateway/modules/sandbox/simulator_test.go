@@ -0,0 +1,73 @@
+package sandbox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+)
+
+func TestSimulationSteps(t *testing.T) {
+	at := time.Date(2025, 10, 31, 12, 0, 0, 0, time.UTC)
+	message := messages.MessageStateIn{
+		ID:    "msg",
+		State: messages.ProcessingStateProcessed,
+		Recipients: []smsgateway.RecipientState{
+			{PhoneNumber: "+79990001234", State: smsgateway.ProcessingStateProcessed},
+			{PhoneNumber: "+79990000000", State: smsgateway.ProcessingStateProcessed},
+		},
+	}
+	o := outcomes{DeliveredRate: 1}
+
+	sent, events := sendStep(message, at, o)
+	if sent.State != messages.ProcessingStateSent {
+		t.Errorf("state = %s, want %s", sent.State, messages.ProcessingStateSent)
+	}
+	if sent.Recipients[0].State != smsgateway.ProcessingStateSent || sent.Recipients[1].State != smsgateway.ProcessingStateFailed {
+		t.Errorf("recipients = %+v", sent.Recipients)
+	}
+	if len(events) != 2 || events[0].Type != smsgateway.WebhookEventSmsSent || events[1].Type != smsgateway.WebhookEventSmsFailed {
+		t.Errorf("events = %+v", events)
+	}
+	if _, ok := sent.States[string(messages.ProcessingStateProcessed)]; !ok {
+		t.Error("Processed state is missing in history")
+	}
+
+	delivered, events := deliverStep(sent, at.Add(time.Second), o)
+	if delivered.State != messages.ProcessingStateDelivered {
+		t.Errorf("state = %s, want %s", delivered.State, messages.ProcessingStateDelivered)
+	}
+	if delivered.Recipients[1].State != smsgateway.ProcessingStateFailed {
+		t.Errorf("failed recipient changed to %s", delivered.Recipients[1].State)
+	}
+	if len(events) != 1 || events[0].Type != smsgateway.WebhookEventSmsDelivered {
+		t.Errorf("events = %+v", events)
+	}
+	if len(delivered.States) != 3 {
+		t.Errorf("states history = %v", delivered.States)
+	}
+}
+
+func TestSimulationOutcomes(t *testing.T) {
+	message := messages.MessageStateIn{
+		ID:         "msg",
+		Recipients: []smsgateway.RecipientState{{PhoneNumber: "+79990001234"}},
+	}
+
+	failed, _ := sendStep(message, time.Now(), outcomes{FailedRate: 0.5, rand: func() float64 { return 0.2 }})
+	if failed.State != messages.ProcessingStateFailed {
+		t.Errorf("state = %s, want %s", failed.State, messages.ProcessingStateFailed)
+	}
+
+	o := outcomes{FailedRate: 0.5, DeliveredRate: 0.5, rand: func() float64 { return 0.7 }}
+	sent, _ := sendStep(message, time.Now(), o)
+	if sent.State != messages.ProcessingStateSent {
+		t.Fatalf("state = %s, want %s", sent.State, messages.ProcessingStateSent)
+	}
+
+	undelivered, events := deliverStep(sent, time.Now(), o)
+	if undelivered.State != messages.ProcessingStateSent || len(events) != 0 {
+		t.Errorf("state = %s, events = %+v, want Sent without events", undelivered.State, events)
+	}
+}
@@ -0,0 +1,129 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/messages"
+	"github.com/capcom6/go-helpers/anys"
+	"github.com/capcom6/go-helpers/slices"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// virtualDeviceName is the name of the device registered by the virtual
+// device mode.
+const virtualDeviceName = "Virtual Device"
+
+// runVirtualDevice registers the virtual device and processes its pending
+// messages until the context is canceled.
+func (s *Service) runVirtualDevice(ctx context.Context) {
+	config := s.config.VirtualDevice
+
+	device, err := s.registerVirtualDevice()
+	if err != nil {
+		s.logger.Error("Can't register virtual device", zap.Error(err))
+		return
+	}
+
+	logger := s.logger.With(zap.String("device_id", device.ID))
+	logger.Info("Virtual device started", zap.String("user_id", device.UserID))
+
+	events, disconnect := s.sseSvc.Connect(device.ID)
+	defer disconnect()
+
+	interval := config.PollInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	o := outcomes{FailedRate: config.FailedRate, DeliveredRate: config.DeliveredRate}
+	for {
+		more, err := s.pullPending(ctx, device, o, config.Latency)
+		if err != nil {
+			logger.Error("Can't process pending messages", zap.Error(err))
+		}
+		if more && err == nil && ctx.Err() == nil {
+			continue
+		}
+
+		// notifications are rare, so the queue is checked on any of them
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-events:
+		}
+	}
+}
+
+// pullPending marks the pending messages as Processed like the mobile app
+// and simulates their sending. It reports whether more messages are pending
+// than were selected.
+func (s *Service) pullPending(ctx context.Context, device models.Device, o outcomes, delay time.Duration) (bool, error) {
+	pending, total, err := s.messagesSvc.SelectPending(device.ID, messages.MessagesOrderFIFO)
+	if err != nil {
+		return false, fmt.Errorf("can't select pending messages: %w", err)
+	}
+
+	batch := make([]simulated, 0, len(pending))
+	for _, message := range pending {
+		state := messages.MessageStateIn{
+			ID:    message.ID,
+			State: messages.ProcessingStateProcessed,
+			Recipients: slices.Map(message.PhoneNumbers, func(phoneNumber string) smsgateway.RecipientState {
+				return smsgateway.RecipientState{PhoneNumber: phoneNumber, State: smsgateway.ProcessingStateProcessed}
+			}),
+			States: map[string]time.Time{string(messages.ProcessingStateProcessed): time.Now()},
+		}
+
+		if err := s.messagesSvc.UpdateState(device.ID, state); err != nil {
+			return false, fmt.Errorf("can't mark message %s as processed: %w", message.ID, err)
+		}
+
+		batch = append(batch, simulated{
+			state:              state,
+			withDeliveryReport: anys.OrDefault(message.WithDeliveryReport, true),
+		})
+	}
+
+	s.process(ctx, device, batch, o, delay)
+
+	return total > int64(len(pending)), nil
+}
+
+// registerVirtualDevice returns the virtual device of the configured user,
+// the user and the device are created on the first start.
+func (s *Service) registerVirtualDevice() (models.Device, error) {
+	config := s.config.VirtualDevice
+	if config.Login == "" || config.Password == "" {
+		return models.Device{}, errors.New("login and password are required")
+	}
+
+	user, err := s.authSvc.AuthorizeUser(config.Login, config.Password)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user, err = s.authSvc.RegisterUser(config.Login, config.Password)
+	}
+	if err != nil {
+		return models.Device{}, fmt.Errorf("can't authorize user: %w", err)
+	}
+
+	items, err := s.devicesSvc.Select(user.ID)
+	if err != nil {
+		return models.Device{}, fmt.Errorf("can't select devices: %w", err)
+	}
+
+	for _, device := range items {
+		if anys.OrDefault(device.Name, "") == virtualDeviceName && device.PushToken == nil {
+			return device, nil
+		}
+	}
+
+	return s.authSvc.RegisterDevice(user, anys.AsPointer(virtualDeviceName), nil)
+}
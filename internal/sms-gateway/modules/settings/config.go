@@ -0,0 +1,7 @@
+package settings
+
+type Config struct {
+	// EncryptionKey is the secret used to encrypt sensitive settings at rest.
+	// Sensitive settings are stored in plaintext if it's empty.
+	EncryptionKey string
+}
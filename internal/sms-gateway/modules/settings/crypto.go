@@ -0,0 +1,184 @@
+package settings
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ruleEncrypted marks a setting that is encrypted at rest and masked in
+	// public responses.
+	ruleEncrypted = "encrypted"
+	// ruleMasked marks a public setting whose value is replaced with maskedValue.
+	ruleMasked = "masked"
+)
+
+const (
+	encryptedPrefix = "enc:v1:"
+	maskedValue     = "********"
+)
+
+var ErrEncryptionKeyMissing = errors.New("settings encryption key is not configured")
+
+type valueCipher struct {
+	aead cipher.AEAD
+}
+
+// newValueCipher creates an AES-GCM cipher with the key derived from the
+// secret. It returns nil if the secret is empty.
+func newValueCipher(secret string) (*valueCipher, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, fmt.Errorf("can't create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("can't create gcm: %w", err)
+	}
+
+	return &valueCipher{aead: aead}, nil
+}
+
+func (c *valueCipher) encrypt(value any) (string, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("can't marshal value: %w", err)
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("can't generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *valueCipher) decrypt(value string) (any, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("can't decode value: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("encrypted value is too short")
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't decrypt value: %w", err)
+	}
+
+	var result any
+	if err := json.Unmarshal(plaintext, &result); err != nil {
+		return nil, fmt.Errorf("can't unmarshal value: %w", err)
+	}
+
+	return result, nil
+}
+
+func isEncrypted(value any) bool {
+	s, ok := value.(string)
+	return ok && strings.HasPrefix(s, encryptedPrefix)
+}
+
+// encryptMap encrypts the values marked with ruleEncrypted in place. Masked
+// values returned by the API are replaced with the current ones or dropped,
+// so they don't overwrite the stored secrets.
+func encryptMap(m, current map[string]any, r map[string]any, c *valueCipher) error {
+	for field, rule := range r {
+		value, ok := m[field]
+		if !ok || value == nil {
+			continue
+		}
+
+		if ruleObj, ok := rule.(map[string]any); ok {
+			dataObj, ok := value.(map[string]any)
+			if !ok {
+				continue
+			}
+			currentObj, _ := current[field].(map[string]any)
+			if err := encryptMap(dataObj, currentObj, ruleObj, c); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if rule != ruleEncrypted {
+			continue
+		}
+
+		if value == maskedValue {
+			if currentValue, ok := current[field]; ok {
+				m[field] = currentValue
+			} else {
+				delete(m, field)
+			}
+			continue
+		}
+
+		if c == nil || isEncrypted(value) {
+			continue
+		}
+
+		encrypted, err := c.encrypt(value)
+		if err != nil {
+			return fmt.Errorf("can't encrypt field '%s': %w", field, err)
+		}
+		m[field] = encrypted
+	}
+
+	return nil
+}
+
+// decryptMap returns a copy of the map with the values marked with
+// ruleEncrypted decrypted.
+func decryptMap(m map[string]any, r map[string]any, c *valueCipher) (map[string]any, error) {
+	result := make(map[string]any, len(m))
+	for field, value := range m {
+		rule := r[field]
+
+		if ruleObj, ok := rule.(map[string]any); ok {
+			if dataObj, ok := value.(map[string]any); ok {
+				decrypted, err := decryptMap(dataObj, ruleObj, c)
+				if err != nil {
+					return nil, err
+				}
+				result[field] = decrypted
+				continue
+			}
+		}
+
+		if rule == ruleEncrypted && isEncrypted(value) {
+			if c == nil {
+				return nil, ErrEncryptionKeyMissing
+			}
+
+			decrypted, err := c.decrypt(value.(string))
+			if err != nil {
+				return nil, fmt.Errorf("can't decrypt field '%s': %w", field, err)
+			}
+			result[field] = decrypted
+			continue
+		}
+
+		result[field] = value
+	}
+
+	return result, nil
+}
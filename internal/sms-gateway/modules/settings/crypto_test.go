@@ -0,0 +1,95 @@
+package settings
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecryptMap(t *testing.T) {
+	c, err := newValueCipher("secret")
+	if err != nil {
+		t.Fatalf("newValueCipher() error = %v", err)
+	}
+
+	settings := map[string]any{
+		"encryption": map[string]any{"passphrase": "pass"},
+		"webhooks":   map[string]any{"retry_count": 3.0, "signing_key": "key"},
+	}
+
+	if err := encryptMap(settings, nil, rules, c); err != nil {
+		t.Fatalf("encryptMap() error = %v", err)
+	}
+
+	passphrase := settings["encryption"].(map[string]any)["passphrase"]
+	if !isEncrypted(passphrase) || strings.Contains(passphrase.(string), "pass") {
+		t.Errorf("passphrase is not encrypted: %v", passphrase)
+	}
+	if got := settings["webhooks"].(map[string]any)["retry_count"]; got != 3.0 {
+		t.Errorf("retry_count = %v, want 3", got)
+	}
+
+	decrypted, err := decryptMap(settings, rules, c)
+	if err != nil {
+		t.Fatalf("decryptMap() error = %v", err)
+	}
+
+	want := map[string]any{
+		"encryption": map[string]any{"passphrase": "pass"},
+		"webhooks":   map[string]any{"retry_count": 3.0, "signing_key": "key"},
+	}
+	if !reflect.DeepEqual(decrypted, want) {
+		t.Errorf("decryptMap() = %v, want %v", decrypted, want)
+	}
+
+	other, _ := newValueCipher("other")
+	if _, err := decryptMap(settings, rules, other); err == nil {
+		t.Error("decryptMap() with wrong key expected error")
+	}
+	if _, err := decryptMap(settings, rules, nil); err != ErrEncryptionKeyMissing {
+		t.Errorf("decryptMap() without key error = %v, want %v", err, ErrEncryptionKeyMissing)
+	}
+}
+
+func TestEncryptMapMasked(t *testing.T) {
+	c, _ := newValueCipher("secret")
+
+	current := map[string]any{
+		"webhooks": map[string]any{"signing_key": "enc:v1:stored"},
+	}
+
+	settings := map[string]any{
+		"encryption": map[string]any{"passphrase": maskedValue},
+		"webhooks":   map[string]any{"signing_key": maskedValue},
+	}
+	if err := encryptMap(settings, current, rules, c); err != nil {
+		t.Fatalf("encryptMap() error = %v", err)
+	}
+
+	if _, ok := settings["encryption"].(map[string]any)["passphrase"]; ok {
+		t.Error("masked passphrase without current value must be dropped")
+	}
+	if got := settings["webhooks"].(map[string]any)["signing_key"]; got != "enc:v1:stored" {
+		t.Errorf("signing_key = %v, want current value", got)
+	}
+}
+
+func TestFilterMapMasked(t *testing.T) {
+	settings := map[string]any{
+		"encryption": map[string]any{"passphrase": "enc:v1:abc"},
+		"webhooks":   map[string]any{"retry_count": 3.0, "signing_key": nil},
+	}
+
+	got, err := filterMap(settings, rulesPublic)
+	if err != nil {
+		t.Fatalf("filterMap() error = %v", err)
+	}
+
+	want := map[string]any{
+		"encryption": map[string]any{"passphrase": maskedValue},
+		"webhooks":   map[string]any{"retry_count": 3.0, "signing_key": nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filterMap() = %v, want %v", got, want)
+	}
+}
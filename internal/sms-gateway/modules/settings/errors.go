@@ -0,0 +1,15 @@
+package settings
+
+import "errors"
+
+var (
+	ErrProfileNotFound = errors.New("settings profile not found")
+	ErrProfileExists   = errors.New("settings profile with the same name already exists")
+)
+
+// ErrValidation is returned when the settings update can't be applied.
+type ErrValidation string
+
+func (e ErrValidation) Error() string {
+	return string(e)
+}
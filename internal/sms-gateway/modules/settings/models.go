@@ -16,9 +16,52 @@ type DeviceSettings struct {
 	models.TimedModel
 }
 
+// Profile is the named settings template of the user. Assigned to devices or
+// groups of devices, it's applied on top of the user's settings.
+type Profile struct {
+	ID       string         `gorm:"primaryKey;type:char(21)"`
+	UserID   string         `gorm:"not null;type:varchar(32);uniqueIndex:unq_settings_profiles_user_name,priority:1"`
+	Name     string         `gorm:"not null;type:varchar(64);uniqueIndex:unq_settings_profiles_user_name,priority:2"`
+	Settings map[string]any `gorm:"not null;type:json;serializer:json"`
+
+	Assignments []ProfileAssignment `gorm:"foreignKey:ProfileID;constraint:OnDelete:CASCADE"`
+
+	models.TimedModel
+}
+
+func (Profile) TableName() string {
+	return "settings_profiles"
+}
+
+// ProfileTargetType is the kind of the profile assignment target.
+type ProfileTargetType string
+
+const (
+	ProfileTargetDevice ProfileTargetType = "device"
+	ProfileTargetGroup  ProfileTargetType = "group"
+)
+
+// ProfileAssignment assigns the profile to the device or the group. A target
+// has at most one profile.
+type ProfileAssignment struct {
+	UserID     string            `gorm:"primaryKey;type:varchar(32)"`
+	TargetType ProfileTargetType `gorm:"primaryKey;type:enum('device','group')"`
+	Target     string            `gorm:"primaryKey;type:varchar(64)"`
+	ProfileID  string            `gorm:"not null;type:char(21);index:idx_settings_profile_assignments_profile_id"`
+
+	models.TimedModel
+}
+
+func (ProfileAssignment) TableName() string {
+	return "settings_profile_assignments"
+}
+
 func Migrate(db *gorm.DB) error {
 	if err := db.AutoMigrate(&DeviceSettings{}); err != nil {
 		return fmt.Errorf("device_settings migration failed: %w", err)
 	}
+	if err := db.AutoMigrate(&Profile{}, &ProfileAssignment{}); err != nil {
+		return fmt.Errorf("settings_profiles migration failed: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,201 @@
+package settings
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// PatchOperation is a single RFC 6902 JSON Patch operation.
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// mergePatch applies RFC 7386 JSON Merge Patch to the target. Null values
+// remove the corresponding keys. The target is modified in place.
+func mergePatch(target, patch map[string]any) map[string]any {
+	if target == nil {
+		target = map[string]any{}
+	}
+
+	for field, value := range patch {
+		if value == nil {
+			delete(target, field)
+			continue
+		}
+
+		if patchObj, ok := value.(map[string]any); ok {
+			targetObj, _ := target[field].(map[string]any)
+			target[field] = mergePatch(targetObj, patchObj)
+			continue
+		}
+
+		target[field] = value
+	}
+
+	return target
+}
+
+// applyJSONPatch applies RFC 6902 JSON Patch operations to a copy of the
+// document. Only object members are addressable. Either all operations are
+// applied or an error is returned.
+func applyJSONPatch(doc map[string]any, ops []PatchOperation) (map[string]any, error) {
+	result := deepCopy(doc).(map[string]any)
+
+	for i, op := range ops {
+		if err := applyPatchOperation(result, op); err != nil {
+			return nil, ErrValidation(fmt.Sprintf("operation %d (%s %s): %s", i, op.Op, op.Path, err))
+		}
+	}
+
+	return result, nil
+}
+
+func applyPatchOperation(doc map[string]any, op PatchOperation) error {
+	switch op.Op {
+	case "add":
+		return patchAdd(doc, op.Path, deepCopy(op.Value))
+	case "remove":
+		_, err := patchRemove(doc, op.Path)
+		return err
+	case "replace":
+		if _, err := patchRemove(doc, op.Path); err != nil {
+			return err
+		}
+		return patchAdd(doc, op.Path, deepCopy(op.Value))
+	case "move":
+		if op.Path == op.From {
+			return nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return fmt.Errorf("can't move a value into itself")
+		}
+		value, err := patchRemove(doc, op.From)
+		if err != nil {
+			return err
+		}
+		return patchAdd(doc, op.Path, value)
+	case "copy":
+		value, err := patchGet(doc, op.From)
+		if err != nil {
+			return err
+		}
+		return patchAdd(doc, op.Path, deepCopy(value))
+	case "test":
+		value, err := patchGet(doc, op.Path)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return fmt.Errorf("test failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported operation")
+	}
+}
+
+// parsePointer splits RFC 6901 JSON Pointer into unescaped reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid pointer `%s`", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// patchParent returns the object containing the value at the pointer and the
+// member name.
+func patchParent(doc map[string]any, pointer string) (map[string]any, string, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tokens) == 0 {
+		return nil, "", fmt.Errorf("the whole document can't be changed")
+	}
+
+	parent := doc
+	for _, token := range tokens[:len(tokens)-1] {
+		child, ok := parent[token].(map[string]any)
+		if !ok {
+			return nil, "", fmt.Errorf("path `%s` doesn't exist", pointer)
+		}
+		parent = child
+	}
+
+	return parent, tokens[len(tokens)-1], nil
+}
+
+func patchGet(doc map[string]any, pointer string) (any, error) {
+	if pointer == "" {
+		return doc, nil
+	}
+
+	parent, key, err := patchParent(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := parent[key]
+	if !ok {
+		return nil, fmt.Errorf("path `%s` doesn't exist", pointer)
+	}
+
+	return value, nil
+}
+
+func patchAdd(doc map[string]any, pointer string, value any) error {
+	parent, key, err := patchParent(doc, pointer)
+	if err != nil {
+		return err
+	}
+
+	parent[key] = value
+	return nil
+}
+
+func patchRemove(doc map[string]any, pointer string) (any, error) {
+	parent, key, err := patchParent(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := parent[key]
+	if !ok {
+		return nil, fmt.Errorf("path `%s` doesn't exist", pointer)
+	}
+	delete(parent, key)
+
+	return value, nil
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			result[key] = deepCopy(item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = deepCopy(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,121 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]any{
+		"messages": map[string]any{"limit_value": 10.0, "limit_period": "Daily"},
+		"ping":     map[string]any{"interval_seconds": 60.0},
+	}
+	patch := map[string]any{
+		"messages": map[string]any{"limit_value": nil, "send_interval_min": 5.0},
+		"ping":     nil,
+		"logs":     map[string]any{"lifetime_days": 7.0},
+	}
+
+	want := map[string]any{
+		"messages": map[string]any{"limit_period": "Daily", "send_interval_min": 5.0},
+		"logs":     map[string]any{"lifetime_days": 7.0},
+	}
+	if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergePatch() = %v, want %v", got, want)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := func() map[string]any {
+		return map[string]any{
+			"messages": map[string]any{"limit_value": 10.0, "limit_period": "Daily"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ops     []PatchOperation
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "remove and add",
+			ops: []PatchOperation{
+				{Op: "remove", Path: "/messages/limit_value"},
+				{Op: "add", Path: "/ping", Value: map[string]any{"interval_seconds": 30.0}},
+			},
+			want: map[string]any{
+				"messages": map[string]any{"limit_period": "Daily"},
+				"ping":     map[string]any{"interval_seconds": 30.0},
+			},
+		},
+		{
+			name: "test and replace",
+			ops: []PatchOperation{
+				{Op: "test", Path: "/messages/limit_value", Value: 10.0},
+				{Op: "replace", Path: "/messages/limit_value", Value: 20.0},
+			},
+			want: map[string]any{
+				"messages": map[string]any{"limit_value": 20.0, "limit_period": "Daily"},
+			},
+		},
+		{
+			name: "move and copy",
+			ops: []PatchOperation{
+				{Op: "copy", From: "/messages/limit_value", Path: "/messages/send_interval_min"},
+				{Op: "move", From: "/messages/limit_period", Path: "/messages/sim_selection_mode"},
+			},
+			want: map[string]any{
+				"messages": map[string]any{"limit_value": 10.0, "send_interval_min": 10.0, "sim_selection_mode": "Daily"},
+			},
+		},
+		{
+			name: "failed test",
+			ops: []PatchOperation{
+				{Op: "remove", Path: "/messages/limit_period"},
+				{Op: "test", Path: "/messages/limit_value", Value: 11.0},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "remove missing",
+			ops:     []PatchOperation{{Op: "remove", Path: "/ping/interval_seconds"}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported",
+			ops:     []PatchOperation{{Op: "merge", Path: "/ping"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := doc()
+			got, err := applyJSONPatch(source, tt.ops)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyJSONPatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(source, doc()) {
+				t.Errorf("applyJSONPatch() modified the source: %v", source)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyJSONPatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	got, err := parsePointer("/a~1b/c~0d")
+	if err != nil {
+		t.Fatalf("parsePointer() error = %v", err)
+	}
+	if want := []string{"a/b", "c~d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePointer() = %v, want %v", got, want)
+	}
+
+	if _, err := parsePointer("a"); err == nil {
+		t.Error("parsePointer() expected error for pointer without leading slash")
+	}
+}
@@ -0,0 +1,94 @@
+package settings
+
+import (
+	"fmt"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+// serverGroups are the settings groups applied by the server, the devices
+// don't use them.
+var serverGroups = []string{"dedup"}
+
+// FilterProfile validates the settings profile applied to the devices on top
+// of the user's settings. Sensitive settings are kept only at the user level,
+// so they are dropped from the profile.
+func (s *Service) FilterProfile(profile map[string]any) (map[string]any, error) {
+	if len(profile) == 0 {
+		return nil, nil
+	}
+
+	filtered, err := filterMap(profile, rules)
+	if err != nil {
+		return nil, ErrValidation(err.Error())
+	}
+
+	dropEncrypted(filtered, rules)
+
+	return filtered, nil
+}
+
+// GetDeviceSettings returns the user's settings for the device. The profile
+// assigned to the group of the device, the profile assigned to the device and
+// the settings of the device itself are applied in this order as RFC 7386
+// JSON Merge Patch. The groups applied by the server are not returned.
+func (s *Service) GetDeviceSettings(device models.Device) (map[string]any, error) {
+	settings, err := s.GetSettings(device.UserID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	groupProfile, deviceProfile, err := s.profiles.Resolve(device.UserID, device.ID, device.Group)
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve settings profiles: %w", err)
+	}
+
+	profiles := make([]map[string]any, 0, 3)
+	if groupProfile != nil {
+		profiles = append(profiles, groupProfile.Settings)
+	}
+	if deviceProfile != nil {
+		profiles = append(profiles, deviceProfile.Settings)
+	}
+	profiles = append(profiles, device.Settings)
+
+	return deviceSettings(settings, profiles...), nil
+}
+
+// deviceSettings applies the profiles to the user's settings in order and
+// drops the groups applied by the server.
+func deviceSettings(settings map[string]any, profiles ...map[string]any) map[string]any {
+	for _, profile := range profiles {
+		settings = applyProfile(settings, profile)
+	}
+
+	for _, group := range serverGroups {
+		delete(settings, group)
+	}
+
+	return settings
+}
+
+func applyProfile(settings, profile map[string]any) map[string]any {
+	if len(profile) == 0 {
+		return settings
+	}
+
+	return mergePatch(settings, deepCopy(profile).(map[string]any))
+}
+
+// dropEncrypted removes the values marked with ruleEncrypted in place.
+func dropEncrypted(m map[string]any, r map[string]any) {
+	for field, rule := range r {
+		switch rule := rule.(type) {
+		case map[string]any:
+			if obj, ok := m[field].(map[string]any); ok {
+				dropEncrypted(obj, rule)
+			}
+		case string:
+			if rule == ruleEncrypted {
+				delete(m, field)
+			}
+		}
+	}
+}
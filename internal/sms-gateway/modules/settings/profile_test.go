@@ -0,0 +1,100 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestService_FilterProfile(t *testing.T) {
+	s := &Service{}
+
+	profile, err := s.FilterProfile(map[string]any{
+		"messages": map[string]any{"limit_value": 100.0, "unknown": true},
+		"webhooks": map[string]any{"retry_count": 3.0, "signing_key": "secret"},
+		"unknown":  map[string]any{"value": 1.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"messages": map[string]any{"limit_value": 100.0},
+		"webhooks": map[string]any{"retry_count": 3.0},
+	}
+	if !reflect.DeepEqual(profile, want) {
+		t.Errorf("FilterProfile() = %v, want %v", profile, want)
+	}
+
+	if _, err := s.FilterProfile(map[string]any{"messages": "invalid"}); err == nil {
+		t.Error("FilterProfile() expected error for invalid group")
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	settings := map[string]any{
+		"messages": map[string]any{"limit_value": 10.0, "limit_period": "PerDay"},
+		"ping":     map[string]any{"interval_seconds": 60.0},
+	}
+	profile := map[string]any{
+		"messages": map[string]any{"limit_value": 100.0},
+		"ping":     map[string]any{"interval_seconds": nil},
+	}
+
+	got := applyProfile(settings, profile)
+
+	want := map[string]any{
+		"messages": map[string]any{"limit_value": 100.0, "limit_period": "PerDay"},
+		"ping":     map[string]any{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyProfile() = %v, want %v", got, want)
+	}
+
+	// the profile is shared by devices and must not be modified
+	if _, ok := profile["ping"].(map[string]any)["interval_seconds"]; !ok {
+		t.Error("applyProfile() modified the profile")
+	}
+}
+
+func TestDeviceSettings(t *testing.T) {
+	settings := map[string]any{
+		"messages": map[string]any{"limit_value": 10.0},
+		"dedup":    map[string]any{"strategy": "content"},
+	}
+
+	got := deviceSettings(settings,
+		map[string]any{"messages": map[string]any{"limit_value": 100.0}},
+		map[string]any{"dedup": map[string]any{"window_seconds": 60.0}},
+		nil,
+	)
+
+	want := map[string]any{
+		"messages": map[string]any{"limit_value": 100.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deviceSettings() = %v, want %v", got, want)
+	}
+}
+
+func TestProfile_Targets(t *testing.T) {
+	profile := Profile{
+		Assignments: []ProfileAssignment{
+			{TargetType: ProfileTargetDevice, Target: "device-1"},
+			{TargetType: ProfileTargetGroup, Target: "berlin"},
+			{TargetType: ProfileTargetDevice, Target: "device-2"},
+		},
+	}
+
+	deviceIDs, groups := profile.Targets()
+	if !reflect.DeepEqual(deviceIDs, []string{"device-1", "device-2"}) {
+		t.Errorf("deviceIDs = %v", deviceIDs)
+	}
+	if !reflect.DeepEqual(groups, []string{"berlin"}) {
+		t.Errorf("groups = %v", groups)
+	}
+
+	deviceIDs, groups = Profile{}.Targets()
+	if deviceIDs == nil || groups == nil || len(deviceIDs)+len(groups) != 0 {
+		t.Errorf("Targets() of unassigned profile = %v, %v, want empty", deviceIDs, groups)
+	}
+}
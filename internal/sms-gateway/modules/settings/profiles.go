@@ -0,0 +1,133 @@
+package settings
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ProfileIn is the named settings profile to create or update.
+type ProfileIn struct {
+	Name     string
+	Settings map[string]any
+}
+
+// SelectProfiles returns the settings profiles of the user, ordered by name.
+func (s *Service) SelectProfiles(userID string) ([]Profile, error) {
+	return s.profiles.Select(userID)
+}
+
+// GetProfile returns the settings profile of the user.
+func (s *Service) GetProfile(userID, id string) (Profile, error) {
+	return s.profiles.Get(userID, id)
+}
+
+// CreateProfile creates the settings profile. Sensitive settings are dropped
+// as for any device profile.
+func (s *Service) CreateProfile(userID string, in ProfileIn) (Profile, error) {
+	settings, err := s.filterProfileIn(in)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	profile := Profile{
+		ID:       s.idGen(),
+		UserID:   userID,
+		Name:     in.Name,
+		Settings: settings,
+	}
+	if err := s.profiles.Insert(&profile); err != nil {
+		return Profile{}, err
+	}
+
+	return s.profiles.Get(userID, profile.ID)
+}
+
+// UpdateProfile replaces the name and the settings of the profile and
+// notifies the devices.
+func (s *Service) UpdateProfile(userID, id string, in ProfileIn) (Profile, error) {
+	settings, err := s.filterProfileIn(in)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	profile := Profile{
+		ID:       id,
+		UserID:   userID,
+		Name:     in.Name,
+		Settings: settings,
+	}
+	if err := s.profiles.Update(&profile); err != nil {
+		return Profile{}, err
+	}
+
+	s.notifyDevices(userID)
+
+	return s.profiles.Get(userID, id)
+}
+
+// DeleteProfile removes the profile with its assignments and notifies the
+// devices.
+func (s *Service) DeleteProfile(userID, id string) error {
+	if err := s.profiles.Delete(userID, id); err != nil {
+		return err
+	}
+
+	s.notifyDevices(userID)
+
+	return nil
+}
+
+// AssignProfile replaces the devices and the groups the profile is assigned
+// to and notifies the devices. A device or a group has at most one profile,
+// so the targets assigned to other profiles are moved to this one.
+func (s *Service) AssignProfile(userID, id string, deviceIDs, groups []string) (Profile, error) {
+	targets := make([]ProfileAssignment, 0, len(deviceIDs)+len(groups))
+	for _, deviceID := range uniq(deviceIDs) {
+		targets = append(targets, ProfileAssignment{TargetType: ProfileTargetDevice, Target: deviceID})
+	}
+	for _, group := range uniq(groups) {
+		targets = append(targets, ProfileAssignment{TargetType: ProfileTargetGroup, Target: group})
+	}
+
+	if err := s.profiles.Assign(userID, id, targets); err != nil {
+		return Profile{}, err
+	}
+
+	s.notifyDevices(userID)
+
+	return s.profiles.Get(userID, id)
+}
+
+func (s *Service) filterProfileIn(in ProfileIn) (map[string]any, error) {
+	settings, err := s.FilterProfile(in.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile settings: %w", err)
+	}
+	if settings == nil {
+		settings = map[string]any{}
+	}
+
+	return settings, nil
+}
+
+func uniq(items []string) []string {
+	result := slices.Clone(items)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// Targets returns the IDs of the devices and the groups the profile is
+// assigned to.
+func (p Profile) Targets() (deviceIDs, groups []string) {
+	deviceIDs, groups = []string{}, []string{}
+	for _, assignment := range p.Assignments {
+		switch assignment.TargetType {
+		case ProfileTargetDevice:
+			deviceIDs = append(deviceIDs, assignment.Target)
+		case ProfileTargetGroup:
+			groups = append(groups, assignment.Target)
+		}
+	}
+
+	return deviceIDs, groups
+}
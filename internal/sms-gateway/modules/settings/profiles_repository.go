@@ -0,0 +1,187 @@
+package settings
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type profilesRepository struct {
+	db *gorm.DB
+}
+
+// Insert stores the profile.
+func (r *profilesRepository) Insert(profile *Profile) error {
+	return translateProfileError(r.db.Omit(clause.Associations).Create(profile).Error)
+}
+
+// Select returns the profiles of the user with their assignments, ordered by
+// name.
+func (r *profilesRepository) Select(userID string) ([]Profile, error) {
+	items := []Profile{}
+	err := r.db.
+		Preload("Assignments").
+		Where("user_id = ?", userID).
+		Order("name").
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+// Get returns the profile of the user with its assignments.
+func (r *profilesRepository) Get(userID, id string) (Profile, error) {
+	profile := Profile{}
+	err := r.db.
+		Preload("Assignments").
+		Where("user_id = ? AND id = ?", userID, id).
+		Take(&profile).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return profile, ErrProfileNotFound
+	}
+
+	return profile, err
+}
+
+// Update replaces the name and the settings of the profile.
+func (r *profilesRepository) Update(profile *Profile) error {
+	res := r.db.
+		Model(profile).
+		Where("user_id = ?", profile.UserID).
+		Select("name", "settings").
+		Updates(profile)
+	if err := translateProfileError(res.Error); err != nil {
+		return err
+	}
+	if res.RowsAffected == 0 {
+		// MySQL reports zero affected rows if nothing has changed
+		_, err := r.Get(profile.UserID, profile.ID)
+		return err
+	}
+
+	return nil
+}
+
+// Delete removes the profile of the user, its assignments are removed by the
+// foreign key.
+func (r *profilesRepository) Delete(userID, id string) error {
+	res := r.db.
+		Where("user_id = ? AND id = ?", userID, id).
+		Delete(&Profile{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrProfileNotFound
+	}
+
+	return nil
+}
+
+// Assign replaces the targets of the profile. The targets assigned to other
+// profiles are moved to this one.
+func (r *profilesRepository) Assign(userID, profileID string, targets []ProfileAssignment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		profile := Profile{}
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND id = ?", userID, profileID).
+			Take(&profile).
+			Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProfileNotFound
+			}
+			return err
+		}
+
+		if err := tx.
+			Where("user_id = ? AND profile_id = ?", userID, profileID).
+			Delete(&ProfileAssignment{}).
+			Error; err != nil {
+			return err
+		}
+
+		if len(targets) == 0 {
+			return nil
+		}
+
+		for i := range targets {
+			targets[i].UserID = userID
+			targets[i].ProfileID = profileID
+		}
+
+		return tx.
+			Clauses(clause.OnConflict{DoUpdates: clause.AssignmentColumns([]string{"profile_id"})}).
+			Create(&targets).
+			Error
+	})
+}
+
+// resolvedProfile is the profile with the type of its assignment target.
+type resolvedProfile struct {
+	TargetType ProfileTargetType
+	ID         string
+	UserID     string
+	Name       string
+	Settings   map[string]any `gorm:"serializer:json"`
+}
+
+// Resolve returns the profiles assigned to the group and to the device, nil
+// if there is none. The assignments and the profiles are loaded with a single
+// query.
+func (r *profilesRepository) Resolve(userID, deviceID string, group *string) (groupProfile, deviceProfile *Profile, err error) {
+	targets := r.db.
+		Where("a.target_type = ? AND a.target = ?", ProfileTargetDevice, deviceID)
+	if group != nil {
+		targets = targets.
+			Or("a.target_type = ? AND a.target = ?", ProfileTargetGroup, *group)
+	}
+
+	rows := []resolvedProfile{}
+	if err := r.db.
+		Table("settings_profile_assignments AS a").
+		Select("a.target_type, p.id, p.user_id, p.name, p.settings").
+		Joins("JOIN settings_profiles AS p ON p.id = a.profile_id").
+		Where("a.user_id = ?", userID).
+		Where(targets).
+		Scan(&rows).
+		Error; err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range rows {
+		profile := &Profile{
+			ID:       row.ID,
+			UserID:   row.UserID,
+			Name:     row.Name,
+			Settings: row.Settings,
+		}
+
+		switch row.TargetType {
+		case ProfileTargetDevice:
+			deviceProfile = profile
+		case ProfileTargetGroup:
+			groupProfile = profile
+		}
+	}
+
+	return groupProfile, deviceProfile, nil
+}
+
+func translateProfileError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrProfileExists
+	}
+
+	return err
+}
+
+func newProfilesRepository(db *gorm.DB) *profilesRepository {
+	return &profilesRepository{
+		db: db,
+	}
+}
@@ -52,6 +52,39 @@ func (r *repository) UpdateSettings(settings *DeviceSettings) (*DeviceSettings,
 	return updatedSettings, err
 }
 
+// PatchSettings applies the patch function to the current settings of a user
+// and stores the result.
+func (r *repository) PatchSettings(userID string, patch func(map[string]any) (map[string]any, error)) (*DeviceSettings, error) {
+	var patchedSettings *DeviceSettings
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		source := &DeviceSettings{UserID: userID}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Limit(1).Find(source).Error; err != nil {
+			return err
+		}
+
+		if source.Settings == nil {
+			source.Settings = map[string]any{}
+		}
+
+		patched, err := patch(source.Settings)
+		if err != nil {
+			return err
+		}
+
+		settings := &DeviceSettings{
+			UserID:   userID,
+			Settings: patched,
+		}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(settings).Error; err != nil {
+			return err
+		}
+
+		patchedSettings = settings
+		return nil
+	})
+	return patchedSettings, err
+}
+
 // ReplaceSettings replaces the settings for a user.
 //
 // This function will overwrite all existing settings for the user.
@@ -1,6 +1,7 @@
 package settings
 
 import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -9,29 +10,52 @@ import (
 type ServiceParams struct {
 	fx.In
 
+	Config Config
+
 	Repository *repository
+	Profiles   *profilesRepository
 
 	EventsSvc *events.Service
 
+	IDGen db.IDGen
+
 	Logger *zap.Logger
 }
 
 type Service struct {
 	settings *repository
+	profiles *profilesRepository
+	cipher   *valueCipher
 
 	eventsSvc *events.Service
 
+	idGen db.IDGen
+
 	logger *zap.Logger
 }
 
-func NewService(params ServiceParams) *Service {
+func NewService(params ServiceParams) (*Service, error) {
+	cipher, err := newValueCipher(params.Config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := params.Logger.Named("service")
+	if cipher == nil {
+		logger.Warn("settings encryption key is not configured, sensitive settings are stored in plaintext")
+	}
+
 	return &Service{
 		settings: params.Repository,
+		profiles: params.Profiles,
+		cipher:   cipher,
 
 		eventsSvc: params.EventsSvc,
 
-		logger: params.Logger.Named("service"),
-	}
+		idGen: params.IDGen,
+
+		logger: logger,
+	}, nil
 }
 
 func (s *Service) GetSettings(userID string, public bool) (map[string]any, error) {
@@ -41,7 +65,7 @@ func (s *Service) GetSettings(userID string, public bool) (map[string]any, error
 	}
 
 	if !public {
-		return settings.Settings, nil
+		return decryptMap(settings.Settings, rules, s.cipher)
 	}
 
 	return filterMap(settings.Settings, rulesPublic)
@@ -53,6 +77,10 @@ func (s *Service) UpdateSettings(userID string, settings map[string]any) (map[st
 		return nil, err
 	}
 
+	if err := encryptMap(filtered, nil, rules, s.cipher); err != nil {
+		return nil, err
+	}
+
 	updatedSettings, err := s.settings.UpdateSettings(&DeviceSettings{
 		UserID:   userID,
 		Settings: filtered,
@@ -72,6 +100,15 @@ func (s *Service) ReplaceSettings(userID string, settings map[string]any) (map[s
 		return nil, err
 	}
 
+	current, err := s.settings.GetSettings(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := encryptMap(filtered, current.Settings, rules, s.cipher); err != nil {
+		return nil, err
+	}
+
 	updated, err := s.settings.ReplaceSettings(&DeviceSettings{
 		UserID:   userID,
 		Settings: filtered,
@@ -85,6 +122,53 @@ func (s *Service) ReplaceSettings(userID string, settings map[string]any) (map[s
 	return filterMap(updated.Settings, rulesPublic)
 }
 
+// MergePatchSettings applies RFC 7386 JSON Merge Patch to the settings, so
+// null values remove the corresponding keys.
+func (s *Service) MergePatchSettings(userID string, patch map[string]any) (map[string]any, error) {
+	return s.patchSettings(userID, func(current map[string]any) (map[string]any, error) {
+		return mergePatch(current, patch), nil
+	})
+}
+
+// JSONPatchSettings applies RFC 6902 JSON Patch operations to the settings.
+func (s *Service) JSONPatchSettings(userID string, ops []PatchOperation) (map[string]any, error) {
+	return s.patchSettings(userID, func(current map[string]any) (map[string]any, error) {
+		return applyJSONPatch(current, ops)
+	})
+}
+
+func (s *Service) patchSettings(userID string, apply func(map[string]any) (map[string]any, error)) (map[string]any, error) {
+	updated, err := s.settings.PatchSettings(userID, func(stored map[string]any) (map[string]any, error) {
+		current, err := decryptMap(stored, rules, s.cipher)
+		if err != nil {
+			return nil, err
+		}
+
+		patched, err := apply(current)
+		if err != nil {
+			return nil, err
+		}
+
+		filtered, err := filterMap(patched, rules)
+		if err != nil {
+			return nil, ErrValidation(err.Error())
+		}
+
+		if err := encryptMap(filtered, stored, rules, s.cipher); err != nil {
+			return nil, err
+		}
+
+		return filtered, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyDevices(userID)
+
+	return filterMap(updated.Settings, rulesPublic)
+}
+
 // notifyDevices asynchronously notifies all the user's devices.
 func (s *Service) notifyDevices(userID string) {
 	go func(userID string) {
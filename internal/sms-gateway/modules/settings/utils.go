@@ -4,15 +4,17 @@ import "fmt"
 
 var rules = map[string]any{
 	"encryption": map[string]any{
-		"passphrase": "",
+		"passphrase": ruleEncrypted,
 	},
 	"messages": map[string]any{
-		"send_interval_min":  "",
-		"send_interval_max":  "",
-		"limit_period":       "",
-		"limit_value":        "",
-		"sim_selection_mode": "",
-		"log_lifetime_days":  "",
+		"send_interval_min":     "",
+		"send_interval_max":     "",
+		"limit_period":          "",
+		"limit_value":           "",
+		"sim_selection_mode":    "",
+		"log_lifetime_days":     "",
+		"priority_burst_value":  "",
+		"priority_burst_period": "",
 	},
 	"ping": map[string]any{
 		"interval_seconds": "",
@@ -23,19 +25,36 @@ var rules = map[string]any{
 	"webhooks": map[string]any{
 		"internet_required": "",
 		"retry_count":       "",
-		"signing_key":       "",
+		"signing_key":       ruleEncrypted,
+	},
+	"dedup": map[string]any{
+		"window_seconds": "",
+		"strategy":       "",
+	},
+	"sims": map[string]any{
+		"min_balance": "",
+	},
+	"routing": map[string]any{
+		"countries": "",
+	},
+	"send_windows": map[string]any{
+		"countries": "",
 	},
 }
 
 var rulesPublic = map[string]any{
-	"encryption": map[string]any{},
+	"encryption": map[string]any{
+		"passphrase": ruleMasked,
+	},
 	"messages": map[string]any{
-		"send_interval_min":  "",
-		"send_interval_max":  "",
-		"limit_period":       "",
-		"limit_value":        "",
-		"sim_selection_mode": "",
-		"log_lifetime_days":  "",
+		"send_interval_min":     "",
+		"send_interval_max":     "",
+		"limit_period":          "",
+		"limit_value":           "",
+		"sim_selection_mode":    "",
+		"log_lifetime_days":     "",
+		"priority_burst_value":  "",
+		"priority_burst_period": "",
 	},
 	"ping": map[string]any{
 		"interval_seconds": "",
@@ -46,6 +65,20 @@ var rulesPublic = map[string]any{
 	"webhooks": map[string]any{
 		"internet_required": "",
 		"retry_count":       "",
+		"signing_key":       ruleMasked,
+	},
+	"dedup": map[string]any{
+		"window_seconds": "",
+		"strategy":       "",
+	},
+	"sims": map[string]any{
+		"min_balance": "",
+	},
+	"routing": map[string]any{
+		"countries": "",
+	},
+	"send_windows": map[string]any{
+		"countries": "",
 	},
 }
 
@@ -65,10 +98,14 @@ func filterMap(m map[string]any, r map[string]any) (map[string]any, error) {
 			} else {
 				return nil, fmt.Errorf("the field: '%s' is not a map to dive", field)
 			}
-		} else if _, ok := rule.(string); ok {
+		} else if ruleStr, ok := rule.(string); ok {
 			if _, ok := m[field]; !ok {
 				continue
 			}
+			if ruleStr == ruleMasked && m[field] != nil {
+				result[field] = maskedValue
+				continue
+			}
 			result[field] = m[field]
 		}
 	}
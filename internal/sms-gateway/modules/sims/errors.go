@@ -0,0 +1,7 @@
+package sims
+
+import "errors"
+
+var (
+	ErrPaused = errors.New("SIM balance is below the threshold")
+)
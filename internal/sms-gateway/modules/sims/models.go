@@ -0,0 +1,40 @@
+package sims
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"gorm.io/gorm"
+)
+
+// SIM is the last balance and plan report of the device SIM card.
+type SIM struct {
+	ID        uint64 `gorm:"primaryKey;type:BIGINT UNSIGNED;autoIncrement"`
+	DeviceID  string `gorm:"not null;type:char(21);uniqueIndex:unq_device_sims_device_sim,priority:1"`
+	SimNumber uint8  `gorm:"not null;type:tinyint(1) unsigned;uniqueIndex:unq_device_sims_device_sim,priority:2"`
+	UserID    string `gorm:"not null;type:varchar(32);index:idx_device_sims_user"`
+
+	Operator  *string    `gorm:"type:varchar(64)"`
+	Balance   *float64   `gorm:"type:decimal(12,2)"`
+	Currency  *string    `gorm:"type:varchar(8)"`
+	Plan      *string    `gorm:"type:varchar(128)"`
+	ExpiresAt *time.Time `gorm:"type:datetime(3)"`
+
+	ReportedAt time.Time `gorm:"not null;type:datetime(3)"`
+
+	Device models.Device `gorm:"foreignKey:DeviceID;constraint:OnDelete:CASCADE"`
+
+	models.TimedModel
+}
+
+func (SIM) TableName() string {
+	return "device_sims"
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&SIM{}); err != nil {
+		return fmt.Errorf("device_sims migration failed: %w", err)
+	}
+	return nil
+}
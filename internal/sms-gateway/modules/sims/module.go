@@ -0,0 +1,25 @@
+package sims
+
+import (
+	"github.com/capcom6/go-infra-fx/db"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"sims",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("sims")
+	}),
+	fx.Provide(
+		newRepository,
+		fx.Private,
+	),
+	fx.Provide(
+		NewService,
+	),
+)
+
+func init() {
+	db.RegisterMigration(Migrate)
+}
@@ -0,0 +1,44 @@
+package sims
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// Upsert stores the reports replacing the previous ones of the same SIMs.
+func (r *repository) Upsert(items []SIM) error {
+	return r.db.
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"operator", "balance", "currency", "plan", "expires_at", "reported_at"}),
+		}).
+		Omit("Device").
+		Create(&items).
+		Error
+}
+
+// Select returns the SIMs of the user's devices ordered by device and SIM
+// number. If deviceID is set, only the SIMs of the device are returned.
+func (r *repository) Select(userID string, deviceID *string) ([]SIM, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if deviceID != nil {
+		query = query.Where("device_id = ?", *deviceID)
+	}
+
+	items := []SIM{}
+	err := query.
+		Order("device_id, sim_number").
+		Find(&items).
+		Error
+
+	return items, err
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
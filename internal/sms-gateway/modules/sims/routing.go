@@ -0,0 +1,73 @@
+package sims
+
+import (
+	"fmt"
+	"math"
+)
+
+// minBalanceFromSettings returns the balance threshold from the "sims"
+// settings group, nil if it isn't set.
+func minBalanceFromSettings(settings map[string]any) *float64 {
+	group, _ := settings["sims"].(map[string]any)
+
+	value, ok := group["min_balance"].(float64)
+	if !ok {
+		return nil
+	}
+
+	return &value
+}
+
+// route returns the SIM number to send the message through. The requested
+// SIM is kept if it isn't paused. If no SIM is requested and some SIMs are
+// paused, the reported SIM with the highest balance is selected, otherwise
+// the selection is left to the device.
+func route(items []SIM, minBalance *float64, simNumber *uint8) (*uint8, error) {
+	if minBalance == nil {
+		return simNumber, nil
+	}
+
+	paused := map[uint8]bool{}
+	for _, sim := range items {
+		if sim.Balance != nil && *sim.Balance < *minBalance {
+			paused[sim.SimNumber] = true
+		}
+	}
+
+	if len(paused) == 0 {
+		return simNumber, nil
+	}
+
+	if simNumber != nil {
+		if paused[*simNumber] {
+			return nil, fmt.Errorf("%w: SIM %d", ErrPaused, *simNumber)
+		}
+		return simNumber, nil
+	}
+
+	var best *SIM
+	for i, sim := range items {
+		if paused[sim.SimNumber] {
+			continue
+		}
+		if best == nil || balanceOf(sim) > balanceOf(*best) {
+			best = &items[i]
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("%w: all SIMs of the device", ErrPaused)
+	}
+
+	return &best.SimNumber, nil
+}
+
+// balanceOf returns the SIM balance, SIMs with the known balance above the
+// threshold are preferred over the ones without the reported balance.
+func balanceOf(sim SIM) float64 {
+	if sim.Balance == nil {
+		return math.Inf(-1)
+	}
+
+	return *sim.Balance
+}
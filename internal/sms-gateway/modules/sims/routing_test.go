@@ -0,0 +1,94 @@
+package sims
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	balance := func(v float64) *float64 { return &v }
+	sim := func(v uint8) *uint8 { return &v }
+
+	items := []SIM{
+		{SimNumber: 1, Balance: balance(0.5)},
+		{SimNumber: 2, Balance: balance(20)},
+		{SimNumber: 3},
+	}
+
+	tests := []struct {
+		name       string
+		items      []SIM
+		minBalance *float64
+		simNumber  *uint8
+		want       *uint8
+		wantErr    error
+	}{
+		{
+			name:      "no threshold",
+			items:     items,
+			simNumber: sim(1),
+			want:      sim(1),
+		},
+		{
+			name:       "nothing paused",
+			items:      items,
+			minBalance: balance(0.1),
+			want:       nil,
+		},
+		{
+			name:       "requested SIM is active",
+			items:      items,
+			minBalance: balance(1),
+			simNumber:  sim(3),
+			want:       sim(3),
+		},
+		{
+			name:       "requested SIM is paused",
+			items:      items,
+			minBalance: balance(1),
+			simNumber:  sim(1),
+			wantErr:    ErrPaused,
+		},
+		{
+			name:       "highest balance selected",
+			items:      items,
+			minBalance: balance(1),
+			want:       sim(2),
+		},
+		{
+			name:       "unknown balance selected",
+			items:      items,
+			minBalance: balance(50),
+			want:       sim(3),
+		},
+		{
+			name:       "all paused",
+			items:      items[:2],
+			minBalance: balance(50),
+			wantErr:    ErrPaused,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := route(tt.items, tt.minBalance, tt.simNumber)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("route() error = %v, want %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("route() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinBalanceFromSettings(t *testing.T) {
+	if got := minBalanceFromSettings(nil); got != nil {
+		t.Errorf("minBalanceFromSettings() = %v, want nil", *got)
+	}
+
+	got := minBalanceFromSettings(map[string]any{"sims": map[string]any{"min_balance": 1.5}})
+	if got == nil || *got != 1.5 {
+		t.Errorf("minBalanceFromSettings() = %v, want 1.5", got)
+	}
+}
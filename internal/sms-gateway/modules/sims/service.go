@@ -0,0 +1,101 @@
+package sims
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/capcom6/go-helpers/slices"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Report is the SIM balance and plan data parsed by the device from the
+// carrier USSD or SMS response.
+type Report struct {
+	SimNumber uint8
+	Operator  *string
+	Balance   *float64
+	Currency  *string
+	Plan      *string
+	ExpiresAt *time.Time
+}
+
+type ServiceParams struct {
+	fx.In
+
+	SIMs *repository
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	sims *repository
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		sims: params.SIMs,
+
+		logger: params.Logger,
+	}
+}
+
+// Report stores the reports of the device SIMs.
+func (s *Service) Report(device models.Device, reports []Report) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	items := slices.Map(reports, func(r Report) SIM {
+		return SIM{
+			DeviceID:   device.ID,
+			SimNumber:  r.SimNumber,
+			UserID:     device.UserID,
+			Operator:   r.Operator,
+			Balance:    r.Balance,
+			Currency:   r.Currency,
+			Plan:       r.Plan,
+			ExpiresAt:  r.ExpiresAt,
+			ReportedAt: now,
+		}
+	})
+
+	if err := s.sims.Upsert(items); err != nil {
+		return fmt.Errorf("can't store SIM reports: %w", err)
+	}
+
+	return nil
+}
+
+// Select returns the last reports of the user's SIMs. If deviceID is set, only
+// the SIMs of the device are returned.
+func (s *Service) Select(userID string, deviceID *string) ([]SIM, error) {
+	items, err := s.sims.Select(userID, deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("can't select SIMs: %w", err)
+	}
+
+	return items, nil
+}
+
+// Route returns the SIM number to send the message from the device through,
+// skipping SIMs with the balance below the `sims.min_balance` user setting.
+// It returns ErrPaused if the requested SIM or all SIMs of the device are
+// paused.
+func (s *Service) Route(device models.Device, simNumber *uint8, userSettings map[string]any) (*uint8, error) {
+	minBalance := minBalanceFromSettings(userSettings)
+	if minBalance == nil {
+		return simNumber, nil
+	}
+
+	items, err := s.sims.Select(device.UserID, &device.ID)
+	if err != nil {
+		return nil, fmt.Errorf("can't select SIMs: %w", err)
+	}
+
+	return route(items, minBalance, simNumber)
+}
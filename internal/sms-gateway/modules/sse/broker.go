@@ -0,0 +1,71 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/android-sms-gateway/core/redis"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	brokerChannel = "sms-gateway:sse:events"
+	brokerStream  = "sms-gateway:sse:stream"
+)
+
+// broker fans the events out to all the service instances, so the device
+// receives the event regardless of the instance it is connected to.
+type broker interface {
+	// Publish sends the payload to the subscribers of all the instances.
+	Publish(ctx context.Context, payload []byte) error
+	// Subscribe calls the handler for each published payload until the
+	// context is done.
+	Subscribe(ctx context.Context, handler func(payload []byte)) error
+	Close() error
+}
+
+// newBroker returns the broker of the configured URL, or nil for the
+// in-process delivery of a single instance.
+func newBroker(config Config, lc fx.Lifecycle, logger *zap.Logger) (broker, error) {
+	if config.brokerURL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(config.brokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse broker url: %w", err)
+	}
+
+	var b broker
+	switch u.Scheme {
+	case "memory":
+		return nil, nil
+	case "redis":
+		client, err := redis.New(redis.Config{URL: config.brokerURL})
+		if err != nil {
+			return nil, fmt.Errorf("can't create redis client: %w", err)
+		}
+		b = newRedisBroker(client, brokerChannel)
+	case "redis+stream":
+		u.Scheme = "redis"
+		client, err := redis.New(redis.Config{URL: u.String()})
+		if err != nil {
+			return nil, fmt.Errorf("can't create redis client: %w", err)
+		}
+		b = newRedisStreamBroker(client, brokerStream)
+	default:
+		return nil, fmt.Errorf("invalid broker scheme: %s", u.Scheme)
+	}
+
+	logger.Info("SSE events are fanned out via broker", zap.String("scheme", u.Scheme))
+
+	lc.Append(fx.Hook{
+		OnStop: func(_ context.Context) error {
+			return b.Close()
+		},
+	})
+
+	return b, nil
+}
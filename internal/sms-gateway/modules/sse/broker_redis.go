@@ -0,0 +1,61 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisBroker publishes the events to a Redis pub/sub channel. The events
+// published while an instance is disconnected are lost for it, the devices
+// catch up on the next poll.
+type redisBroker struct {
+	client  *redis.Client
+	channel string
+}
+
+func newRedisBroker(client *redis.Client, channel string) *redisBroker {
+	return &redisBroker{
+		client:  client,
+		channel: channel,
+	}
+}
+
+// Publish implements broker.
+func (b *redisBroker) Publish(ctx context.Context, payload []byte) error {
+	if err := b.client.Publish(ctx, b.channel, payload).Err(); err != nil {
+		return fmt.Errorf("can't publish event: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe implements broker. The subscription is restored by the client
+// after a connection loss.
+func (b *redisBroker) Subscribe(ctx context.Context, handler func(payload []byte)) error {
+	sub := b.client.Subscribe(ctx, b.channel)
+	defer sub.Close()
+
+	if _, err := sub.Receive(ctx); err != nil {
+		return fmt.Errorf("can't subscribe to channel: %w", err)
+	}
+
+	ch := sub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			handler([]byte(msg.Payload))
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Close implements broker.
+func (b *redisBroker) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,128 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// streamMaxLen is the approximate number of the events kept in the
+	// stream for the instances catching up after a connection loss.
+	streamMaxLen = 10_000
+	// streamReadBlock is the max time of a single read, so the closed
+	// subscription is noticed.
+	streamReadBlock = 5 * time.Second
+	// streamReadCount is the max number of the events of a single read.
+	streamReadCount = 100
+
+	streamPayloadField = "payload"
+)
+
+// redisStreamBroker publishes the events to a Redis stream read by all the
+// instances. Unlike redisBroker, an instance resubscribing after a
+// connection loss or a Redis failover resumes from the last received event,
+// so the events published in between are still delivered.
+type redisStreamBroker struct {
+	client *redis.Client
+	stream string
+
+	mu sync.Mutex
+	// lastID is the ID of the last received event, empty before the first
+	// subscription
+	lastID string
+}
+
+func newRedisStreamBroker(client *redis.Client, stream string) *redisStreamBroker {
+	return &redisStreamBroker{
+		client: client,
+		stream: stream,
+	}
+}
+
+// Publish implements broker.
+func (b *redisStreamBroker) Publish(ctx context.Context, payload []byte) error {
+	err := b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]any{streamPayloadField: payload},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("can't publish event: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe implements broker. The first subscription starts with the events
+// published after it, the next ones resume after the last received event.
+func (b *redisStreamBroker) Subscribe(ctx context.Context, handler func(payload []byte)) error {
+	lastID, err := b.startID(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		streams, err := b.client.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{b.stream, lastID},
+			Count:   streamReadCount,
+			Block:   streamReadBlock,
+		}).Result()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("can't read stream: %w", err)
+		}
+
+		for _, stream := range streams {
+			for _, message := range stream.Messages {
+				lastID = message.ID
+				if payload, ok := message.Values[streamPayloadField].(string); ok {
+					handler([]byte(payload))
+				}
+			}
+		}
+
+		b.mu.Lock()
+		b.lastID = lastID
+		b.mu.Unlock()
+	}
+}
+
+// startID returns the ID to read the stream after. The ID of the last event
+// is resolved explicitly instead of "$", so the events published before the
+// first read aren't missed.
+func (b *redisStreamBroker) startID(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.lastID != "" {
+		return b.lastID, nil
+	}
+
+	messages, err := b.client.XRevRangeN(ctx, b.stream, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("can't get last event: %w", err)
+	}
+
+	b.lastID = "0-0"
+	if len(messages) > 0 {
+		b.lastID = messages[0].ID
+	}
+
+	return b.lastID, nil
+}
+
+// Close implements broker.
+func (b *redisStreamBroker) Close() error {
+	return b.client.Close()
+}
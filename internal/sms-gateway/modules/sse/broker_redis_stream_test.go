@@ -0,0 +1,174 @@
+package sse
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const testStream = "test:events"
+
+func newTestStreamBroker(t *testing.T, server *miniredis.Miniredis) *redisStreamBroker {
+	t.Helper()
+
+	b := newRedisStreamBroker(redis.NewClient(&redis.Options{
+		Addr: server.Addr(),
+	}), testStream)
+	t.Cleanup(func() {
+		_ = b.Close()
+	})
+
+	return b
+}
+
+// testSubscriber runs the subscriptions of the broker one at a time and
+// collects the received payloads.
+type testSubscriber struct {
+	t        *testing.T
+	broker   *redisStreamBroker
+	received chan string
+}
+
+func newTestSubscriber(t *testing.T, broker *redisStreamBroker) *testSubscriber {
+	return &testSubscriber{t: t, broker: broker, received: make(chan string, 10)}
+}
+
+// subscribe starts the subscription and returns the channel with its result.
+func (s *testSubscriber) subscribe(ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		done <- s.broker.Subscribe(ctx, func(payload []byte) {
+			s.received <- string(payload)
+		})
+	}()
+
+	// the start ID is resolved before the read
+	time.Sleep(100 * time.Millisecond)
+
+	return done
+}
+
+func (s *testSubscriber) expect(want string) {
+	s.t.Helper()
+
+	select {
+	case got := <-s.received:
+		if got != want {
+			s.t.Errorf("payload = %q, want %q", got, want)
+		}
+	case <-time.After(streamReadBlock + time.Second):
+		s.t.Fatalf("payload %q is not received", want)
+	}
+}
+
+func (s *testSubscriber) expectNone() {
+	s.t.Helper()
+
+	select {
+	case got := <-s.received:
+		s.t.Errorf("unexpected payload %q", got)
+	default:
+	}
+}
+
+func publish(t *testing.T, b *redisStreamBroker, payload string) {
+	t.Helper()
+
+	if err := b.Publish(context.Background(), []byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRedisStreamBroker_Resubscribe checks that the events published while
+// the instance is unsubscribed are received after the resubscription.
+func TestRedisStreamBroker_Resubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	publisher := newTestStreamBroker(t, server)
+	subscriber := newTestSubscriber(t, newTestStreamBroker(t, server))
+
+	// published before the first subscription
+	publish(t, publisher, "skipped")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := subscriber.subscribe(ctx)
+	publish(t, publisher, "first")
+	subscriber.expect("first")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	publish(t, publisher, "missed")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	subscriber.subscribe(ctx)
+	subscriber.expect("missed")
+	subscriber.expectNone()
+}
+
+// TestRedisStreamBroker_Failover checks that the subscription broken by the
+// failover to the replica resumes after the last received event, so the
+// events published before the resubscription are neither lost nor repeated.
+func TestRedisStreamBroker_Failover(t *testing.T) {
+	primary := miniredis.RunT(t)
+	publisher := newTestStreamBroker(t, primary)
+
+	// the subscriber follows the current primary like a Sentinel client
+	addr := atomic.Value{}
+	addr.Store(primary.Addr())
+	subscriber := newTestSubscriber(t, newRedisStreamBroker(redis.NewClient(&redis.Options{
+		Dialer: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr.Load().(string))
+		},
+	}), testStream))
+	t.Cleanup(func() {
+		_ = subscriber.broker.Close()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := subscriber.subscribe(ctx)
+	publish(t, publisher, "before")
+	subscriber.expect("before")
+	publish(t, publisher, "during")
+	subscriber.expect("during")
+
+	// the replica has the replicated stream, the last event isn't received
+	// before the failover
+	replica := miniredis.RunT(t)
+	entries, err := primary.Stream(testStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if _, err := replica.XAdd(testStream, entry.ID, entry.Values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := replica.XAdd(testStream, "*", []string{streamPayloadField, "failover"}); err != nil {
+		t.Fatal(err)
+	}
+
+	addr.Store(replica.Addr())
+	primary.Close()
+
+	// the broken read is either retried by the client or resubscribed
+	go func() {
+		for {
+			if err := <-done; err == nil || ctx.Err() != nil {
+				return
+			}
+			done = subscriber.subscribe(ctx)
+		}
+	}()
+
+	subscriber.expect("failover")
+	subscriber.expectNone()
+}
@@ -0,0 +1,122 @@
+package sse
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// coalescedEvent is the data of the event merged from several events of the
+// same type.
+type coalescedEvent struct {
+	Count  int               `json:"count"`
+	Events []json.RawMessage `json:"events"`
+}
+
+// pendingEvents are the events of a single type waiting for the window of the
+// device to end.
+type pendingEvents struct {
+	deviceID string
+	name     string
+	data     []json.RawMessage
+
+	// the merged event is traced and timed by the first one, which starts
+	// the window
+	traceID string
+	sentAt  time.Time
+
+	timer *time.Timer
+}
+
+// event returns the single event as is, several events are merged into one
+// with the data of each in the `events` field.
+func (p *pendingEvents) event() eventWrapper {
+	if len(p.data) == 1 {
+		return eventWrapper{name: p.name, data: p.data[0], traceID: p.traceID, sentAt: p.sentAt}
+	}
+
+	data, _ := json.Marshal(coalescedEvent{Count: len(p.data), Events: p.data})
+
+	return eventWrapper{name: p.name, data: data, traceID: p.traceID, sentAt: p.sentAt}
+}
+
+// coalescer merges the events of the same type sent to the device within the
+// window of the type into one, so a burst of events is written and wakes up
+// the device once.
+type coalescer struct {
+	windows map[string]time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingEvents
+
+	flush func(deviceID string, event eventWrapper, count int)
+}
+
+func newCoalescer(windows map[string]time.Duration, flush func(deviceID string, event eventWrapper, count int)) *coalescer {
+	return &coalescer{
+		windows: windows,
+		pending: make(map[string]*pendingEvents),
+		flush:   flush,
+	}
+}
+
+// Coalesces reports whether the events of the type are coalesced.
+func (c *coalescer) Coalesces(eventType string) bool {
+	return c.windows[eventType] > 0
+}
+
+// Add appends the event to the pending ones of its type, the first event
+// starts the window.
+func (c *coalescer) Add(deviceID string, event eventWrapper) {
+	key := deviceID + "/" + event.name
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.pending[key]
+	if !ok {
+		item = &pendingEvents{deviceID: deviceID, name: event.name, traceID: event.traceID, sentAt: event.sentAt}
+		item.timer = time.AfterFunc(c.windows[event.name], func() {
+			c.flushKey(key, item)
+		})
+		c.pending[key] = item
+	}
+	item.data = append(item.data, event.data)
+}
+
+// Len returns the number of the pending merged events.
+func (c *coalescer) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.pending)
+}
+
+// Close sends all pending events.
+func (c *coalescer) Close() {
+	c.mu.Lock()
+	items := make([]*pendingEvents, 0, len(c.pending))
+	for key, item := range c.pending {
+		item.timer.Stop()
+		items = append(items, item)
+		delete(c.pending, key)
+	}
+	c.mu.Unlock()
+
+	for _, item := range items {
+		c.flush(item.deviceID, item.event(), len(item.data))
+	}
+}
+
+func (c *coalescer) flushKey(key string, item *pendingEvents) {
+	c.mu.Lock()
+	if c.pending[key] != item {
+		// already sent on close
+		c.mu.Unlock()
+		return
+	}
+	delete(c.pending, key)
+	c.mu.Unlock()
+
+	c.flush(item.deviceID, item.event(), len(item.data))
+}
@@ -0,0 +1,107 @@
+package sse
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"go.uber.org/zap"
+)
+
+func TestCoalescer(t *testing.T) {
+	type flushed struct {
+		deviceID string
+		event    eventWrapper
+		count    int
+	}
+	ch := make(chan flushed, 8)
+	c := newCoalescer(map[string]time.Duration{"burst": 50 * time.Millisecond}, func(deviceID string, event eventWrapper, count int) {
+		ch <- flushed{deviceID, event, count}
+	})
+
+	if c.Coalesces("other") {
+		t.Error("event without window is coalesced")
+	}
+
+	for _, data := range []string{`{"id":"1"}`, `{"id":"2"}`, `{"id":"3"}`} {
+		c.Add("a", eventWrapper{name: "burst", data: []byte(data)})
+	}
+	c.Add("b", eventWrapper{name: "burst", data: []byte(`{}`)})
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	got := map[string]flushed{}
+	for range 2 {
+		select {
+		case f := <-ch:
+			got[f.deviceID] = f
+		case <-time.After(time.Second):
+			t.Fatal("events aren't flushed after the window")
+		}
+	}
+
+	if f := got["a"]; f.count != 3 || f.event.name != "burst" || string(f.event.data) != `{"count":3,"events":[{"id":"1"},{"id":"2"},{"id":"3"}]}` {
+		t.Errorf("merged event = %+v, data %s", f, f.event.data)
+	}
+	// the single event is sent as is
+	if f := got["b"]; f.count != 1 || string(f.event.data) != `{}` {
+		t.Errorf("single event = %+v, data %s", f, f.event.data)
+	}
+
+	// the pending events are sent on close
+	c.Add("a", eventWrapper{name: "burst", data: []byte(`{}`)})
+	c.Close()
+	if len(ch) != 1 || c.Len() != 0 {
+		t.Errorf("flushed on close = %d, pending = %d", len(ch), c.Len())
+	}
+}
+
+func TestService_Coalesce(t *testing.T) {
+	config := NewConfig(WithCoalesceWindows(map[string]time.Duration{
+		string(smsgateway.PushMessageEnqueued): 50 * time.Millisecond,
+	}))
+	svc := NewService(config, nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+	// nothing to coalesce without connection
+	if err := svc.Send("device", Event{Type: smsgateway.PushMessageEnqueued}); err == nil {
+		t.Error("Send() without connection succeeded")
+	}
+
+	conn := svc.registerConnection("device", transportSSE, nil)
+	defer svc.removeConnection("device", conn.id)
+
+	for range 3 {
+		if err := svc.Send("device", Event{Type: smsgateway.PushMessageEnqueued}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := svc.Send("device", Event{Type: smsgateway.PushSettingsUpdated}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the other types aren't delayed
+	if event := <-conn.channel; event.name != string(smsgateway.PushSettingsUpdated) {
+		t.Errorf("first event = %s, want %s", event.name, smsgateway.PushSettingsUpdated)
+	}
+
+	select {
+	case event := <-conn.channel:
+		data := coalescedEvent{}
+		if err := json.Unmarshal(event.data, &data); err != nil {
+			t.Fatal(err)
+		}
+		if event.name != string(smsgateway.PushMessageEnqueued) || data.Count != 3 || len(data.Events) != 3 {
+			t.Errorf("coalesced event = %s %s", event.name, event.data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("coalesced event isn't delivered")
+	}
+
+	if len(conn.channel) != 0 {
+		t.Errorf("extra events = %d", len(conn.channel))
+	}
+}
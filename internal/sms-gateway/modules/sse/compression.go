@@ -0,0 +1,115 @@
+package sse
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// compressor is the compressing writer, the pending data is flushed without
+// closing the stream.
+type compressor interface {
+	io.Writer
+	Flush() error
+	Close() error
+	Reset(w io.Writer)
+}
+
+// The compressors are reused by the next connections. Each one holds about
+// 200-300KB for the lifetime of the connection, so the compression of
+// thousands of the long-lived streams takes GBs of memory.
+var (
+	gzipPool = sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return w
+	}}
+	zlibPool = sync.Pool{New: func() any {
+		w, _ := zlib.NewWriterLevel(nil, zlib.BestSpeed)
+		return w
+	}}
+)
+
+// eventStream writes the events to the buffer of the connection, compressed
+// if the encoding is negotiated.
+type eventStream struct {
+	w          *bufio.Writer
+	compressor compressor
+	pool       *sync.Pool
+}
+
+// negotiateEncoding returns the content encoding of the stream accepted by
+// the device, empty without compression.
+func negotiateEncoding(c *fiber.Ctx) string {
+	// the first offer is accepted without the header
+	if c.Get(fiber.HeaderAcceptEncoding) == "" {
+		return ""
+	}
+
+	return c.AcceptsEncodings(encodingGzip, encodingDeflate)
+}
+
+func newEventStream(w *bufio.Writer, encoding string) *eventStream {
+	s := &eventStream{w: w}
+
+	switch encoding {
+	case encodingGzip:
+		s.pool = &gzipPool
+	case encodingDeflate:
+		// the HTTP deflate encoding is the zlib format
+		s.pool = &zlibPool
+	default:
+		return s
+	}
+
+	s.compressor = s.pool.Get().(compressor)
+	s.compressor.Reset(w)
+
+	return s
+}
+
+func (s *eventStream) Write(p []byte) (int, error) {
+	if s.compressor == nil {
+		return s.w.Write(p)
+	}
+
+	return s.compressor.Write(p)
+}
+
+// Flush writes the compressed data of the event to the connection, so the
+// event isn't held by the compressor until the next ones.
+func (s *eventStream) Flush() error {
+	if s.compressor != nil {
+		if err := s.compressor.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return s.w.Flush()
+}
+
+// Close ends the compressed stream and releases the compressor.
+func (s *eventStream) Close() error {
+	if s.compressor == nil {
+		return nil
+	}
+
+	err := s.compressor.Close()
+	// the compressor doesn't keep the connection after the reset
+	s.compressor.Reset(io.Discard)
+	s.pool.Put(s.compressor)
+	s.compressor = nil
+	if err != nil {
+		return err
+	}
+
+	return s.w.Flush()
+}
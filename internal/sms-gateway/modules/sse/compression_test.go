@@ -0,0 +1,45 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+)
+
+func TestEventStream_Reuse(t *testing.T) {
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		encodingGzip:    func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		encodingDeflate: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	}
+
+	for encoding, newReader := range readers {
+		t.Run(encoding, func(t *testing.T) {
+			// the released compressor is reused by the next streams
+			for i := range 3 {
+				buf := bytes.Buffer{}
+				s := newEventStream(bufio.NewWriter(&buf), encoding)
+				if _, err := s.Write([]byte("data: event\n\n")); err != nil {
+					t.Fatal(err)
+				}
+				if err := s.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				r, err := newReader(&buf)
+				if err != nil {
+					t.Fatalf("stream %d: %v", i, err)
+				}
+				data, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("stream %d: %v", i, err)
+				}
+				if string(data) != "data: event\n\n" {
+					t.Errorf("stream %d = %q", i, data)
+				}
+			}
+		})
+	}
+}
@@ -1,17 +1,71 @@
 package sse
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type configOption func(*Config)
 
+// OverflowPolicy defines how an event is handled when the buffer of the
+// connection is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest drops the new event.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowDropOldest drops the oldest buffered event to queue the new one.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDisconnect closes the connection, so the device reconnects and
+	// gets the missed events by the replay.
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+// ParseOverflowPolicy parses the overflow policy, the empty value defaults to
+// OverflowDropNewest.
+func ParseOverflowPolicy(value string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(value); policy {
+	case "":
+		return OverflowDropNewest, nil
+	case OverflowDropNewest, OverflowDropOldest, OverflowDisconnect:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q: expected drop-newest, drop-oldest or disconnect", value)
+	}
+}
+
 type Config struct {
 	keepAlivePeriod time.Duration
+	brokerURL       string
+
+	replayBufferSize int
+
+	bufferSize     int
+	overflowPolicy OverflowPolicy
+
+	writeTimeout time.Duration
+
+	coalesceWindows map[string]time.Duration
+
+	compression bool
 }
 
-const defaultKeepAlivePeriod = 15 * time.Second
+const (
+	defaultKeepAlivePeriod  = 15 * time.Second
+	defaultReplayBufferSize = 32
+	defaultBufferSize       = 8
+	defaultWriteTimeout     = 10 * time.Second
+)
 
 var defaultConfig = Config{
 	keepAlivePeriod: defaultKeepAlivePeriod,
+
+	replayBufferSize: defaultReplayBufferSize,
+
+	bufferSize:     defaultBufferSize,
+	overflowPolicy: OverflowDropNewest,
+
+	writeTimeout: defaultWriteTimeout,
 }
 
 func NewConfig(opts ...configOption) Config {
@@ -37,3 +91,83 @@ func WithKeepAlivePeriod(d time.Duration) configOption {
 		c.keepAlivePeriod = d
 	}
 }
+
+// WithBrokerURL sets the URL of the broker that fans the events out to the
+// instances, e.g. "redis://...". Empty delivers the events in-process.
+func WithBrokerURL(url string) configOption {
+	return func(c *Config) {
+		c.brokerURL = url
+	}
+}
+
+// WithReplayBufferSize sets the number of the last events per device replayed
+// on reconnect with the Last-Event-ID header, zero disables the replay.
+func WithReplayBufferSize(size int) configOption {
+	return func(c *Config) {
+		c.replayBufferSize = max(size, 0)
+	}
+}
+
+// WithBufferSize sets the number of the events buffered per connection until
+// they're written to the device.
+func WithBufferSize(size int) configOption {
+	if size <= 0 {
+		size = defaultBufferSize
+	}
+
+	return func(c *Config) {
+		c.bufferSize = size
+	}
+}
+
+// WithOverflowPolicy sets the handling of the events for the connection with
+// the full buffer, an unknown policy falls back to OverflowDropNewest, see
+// ParseOverflowPolicy to reject it.
+func WithOverflowPolicy(policy OverflowPolicy) configOption {
+	switch policy {
+	case OverflowDropNewest, OverflowDropOldest, OverflowDisconnect:
+	default:
+		policy = OverflowDropNewest
+	}
+
+	return func(c *Config) {
+		c.overflowPolicy = policy
+	}
+}
+
+// WithWriteTimeout sets the time limit of a single write to the device, the
+// device that doesn't consume the events within it is disconnected.
+func WithWriteTimeout(d time.Duration) configOption {
+	if d <= 0 {
+		d = defaultWriteTimeout
+	}
+
+	return func(c *Config) {
+		c.writeTimeout = d
+	}
+}
+
+// WithCoalesceWindows sets the windows per event type, the events of the type
+// sent to the device within the window are merged into one. The types without
+// positive window aren't coalesced.
+func WithCoalesceWindows(windows map[string]time.Duration) configOption {
+	filtered := make(map[string]time.Duration, len(windows))
+	for eventType, window := range windows {
+		if window > 0 {
+			filtered[eventType] = window
+		}
+	}
+
+	return func(c *Config) {
+		c.coalesceWindows = filtered
+	}
+}
+
+// WithCompression enables the gzip or deflate compression of the events
+// streams negotiated with the Accept-Encoding header. It's disabled by
+// default: each compressed connection holds a compressor of about 200-300KB.
+func WithCompression(enabled bool) configOption {
+	return func(c *Config) {
+		c.compression = enabled
+	}
+}
@@ -0,0 +1,29 @@
+package sse
+
+import "testing"
+
+func TestParseOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    OverflowPolicy
+		wantErr bool
+	}{
+		{value: "", want: OverflowDropNewest},
+		{value: "drop-newest", want: OverflowDropNewest},
+		{value: "drop-oldest", want: OverflowDropOldest},
+		{value: "disconnect", want: OverflowDisconnect},
+		{value: "drop", wantErr: true},
+		{value: "Disconnect", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOverflowPolicy(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseOverflowPolicy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseOverflowPolicy(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
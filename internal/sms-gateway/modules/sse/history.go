@@ -0,0 +1,206 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+const (
+	// historyTTL is how long the event is kept for the replay.
+	historyTTL = 10 * time.Minute
+	// ackTTL is how long the acknowledged event ID is kept after the last
+	// ack, it outlives the acknowledged events.
+	ackTTL = historyTTL
+	// seqTTL is how long the sequence of the event IDs lives since the first
+	// event. The sequence then starts over, which the replay treats as the
+	// reset of the IDs.
+	seqTTL = 24 * time.Hour
+	// maxAckAttempts is the number of attempts to store the acknowledged ID
+	// changed concurrently.
+	maxAckAttempts = 10
+)
+
+var (
+	// ErrReplayDisabled is returned by Ack when the events history is
+	// disabled.
+	ErrReplayDisabled = errors.New("events replay is disabled")
+	// ErrUnknownEvent is returned by Ack for the ID which hasn't been
+	// assigned to the device's events.
+	ErrUnknownEvent = errors.New("unknown event id")
+)
+
+type historyEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// history keeps the last events of the devices in the cache with the
+// increasing per device IDs, so the events missed while the device was
+// reconnecting are replayed. With the shared cache the IDs are consistent
+// across the instances.
+type history struct {
+	seq    cache.Cache
+	events *cache.Typed[historyEvent]
+	size   int64
+}
+
+func newHistory(c cache.Cache, size int) *history {
+	return &history{
+		seq:    c,
+		events: cache.NewTyped[historyEvent](c),
+		size:   int64(size),
+	}
+}
+
+// Append stores the event and returns its ID. The first ID of the sequence
+// drops the acknowledged one, which belongs to the previous sequence.
+func (h *history) Append(ctx context.Context, deviceID string, event eventWrapper) (int64, error) {
+	id, err := h.seq.Increment(ctx, seqKey(deviceID), 1, cache.WithTTL(seqTTL))
+	if err != nil {
+		return 0, fmt.Errorf("can't assign event id: %w", err)
+	}
+	if id == 1 {
+		if err := h.seq.Delete(ctx, ackKey(deviceID)); err != nil {
+			return 0, fmt.Errorf("can't reset acknowledged id: %w", err)
+		}
+	}
+
+	if err := h.events.Set(ctx, eventKey(deviceID, id), historyEvent{
+		Event: event.name,
+		Data:  event.data,
+	}, cache.WithTTL(historyTTL)); err != nil {
+		return 0, fmt.Errorf("can't store event: %w", err)
+	}
+
+	return id, nil
+}
+
+// Since returns the stored events after the ID in order, at most the size of
+// the history. The expired events and the IDs assigned but not stored yet
+// are skipped, the latter are delivered live. An ID ahead of the last
+// assigned one means the IDs were reset, so all the stored events are
+// returned.
+func (h *history) Since(ctx context.Context, deviceID string, lastID int64) ([]eventWrapper, error) {
+	last, err := h.last(ctx, deviceID)
+	if err != nil || last == 0 {
+		return nil, err
+	}
+
+	from := lastID + 1
+	if lastID > last || last-lastID > h.size {
+		from = max(last-h.size+1, 1)
+	}
+
+	events := make([]eventWrapper, 0, last-from+1)
+	for id := from; id <= last; id++ {
+		event, err := h.events.Get(ctx, eventKey(deviceID, id))
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't get event: %w", err)
+		}
+
+		events = append(events, eventWrapper{id: id, name: event.Event, data: event.Data})
+	}
+
+	return events, nil
+}
+
+// Ack stores the ID of the last event processed by the device. The stored ID
+// only grows, so a stale ack doesn't move the replay back. It returns
+// ErrUnknownEvent for the ID which hasn't been assigned yet.
+func (h *history) Ack(ctx context.Context, deviceID string, id int64) error {
+	last, err := h.last(ctx, deviceID)
+	if err != nil {
+		return err
+	}
+	if id > last {
+		return fmt.Errorf("%w: %d is after the last event %d", ErrUnknownEvent, id, last)
+	}
+
+	value := strconv.FormatInt(id, 10)
+	for range maxAckAttempts {
+		current, err := h.seq.Get(ctx, ackKey(deviceID))
+		switch {
+		case errors.Is(err, cache.ErrKeyNotFound):
+			err = h.seq.SetOrFail(ctx, ackKey(deviceID), value, cache.WithTTL(ackTTL))
+		case err != nil:
+			return fmt.Errorf("can't get acknowledged event id: %w", err)
+		default:
+			acked, parseErr := strconv.ParseInt(current, 10, 64)
+			if parseErr == nil && acked >= id {
+				return nil
+			}
+			err = h.seq.CompareAndSwap(ctx, ackKey(deviceID), current, value, cache.WithTTL(ackTTL))
+		}
+
+		if errors.Is(err, cache.ErrKeyExists) || errors.Is(err, cache.ErrValueMismatch) || errors.Is(err, cache.ErrKeyNotFound) {
+			// the device acknowledged the events concurrently
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("can't store acknowledged event id: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("can't store acknowledged event id: %w", cache.ErrValueMismatch)
+}
+
+// last returns the ID of the last event of the device, zero if there are
+// none.
+func (h *history) last(ctx context.Context, deviceID string) (int64, error) {
+	value, err := h.seq.Get(ctx, seqKey(deviceID))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("can't get last event id: %w", err)
+	}
+
+	last, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("can't parse last event id: %w", err)
+	}
+
+	return last, nil
+}
+
+// Acked returns the ID of the last event processed by the device, zero if the
+// device doesn't acknowledge the events.
+func (h *history) Acked(ctx context.Context, deviceID string) (int64, error) {
+	value, err := h.seq.Get(ctx, ackKey(deviceID))
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("can't get acknowledged event id: %w", err)
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("can't parse acknowledged event id: %w", err)
+	}
+
+	return id, nil
+}
+
+func seqKey(deviceID string) string {
+	return deviceID + ":seq"
+}
+
+func ackKey(deviceID string) string {
+	return deviceID + ":ack"
+}
+
+func eventKey(deviceID string, id int64) string {
+	return deviceID + ":" + strconv.FormatInt(id, 10)
+}
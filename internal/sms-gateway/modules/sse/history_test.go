@@ -0,0 +1,159 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+	h := newHistory(c, 3)
+
+	for i := 1; i <= 5; i++ {
+		id, err := h.Append(ctx, "device", eventWrapper{name: fmt.Sprintf("event-%d", i), data: []byte("{}")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != int64(i) {
+			t.Fatalf("Append() = %d, want %d", id, i)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		lastID int64
+		want   []int64
+	}{
+		{name: "missed", lastID: 3, want: []int64{4, 5}},
+		{name: "up to date", lastID: 5, want: []int64{}},
+		{name: "beyond size", lastID: 1, want: []int64{3, 4, 5}},
+		{name: "reset ids", lastID: 100, want: []int64{3, 4, 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := h.Since(ctx, "device", tt.lastID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := []int64{}
+			for _, event := range events {
+				if event.name != fmt.Sprintf("event-%d", event.id) {
+					t.Errorf("event %d name = %q", event.id, event.name)
+				}
+				ids = append(ids, event.id)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("Since() ids = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+
+	if events, err := h.Since(ctx, "other", 1); err != nil || len(events) != 0 {
+		t.Errorf("Since() of another device = %v, %v, want none", events, err)
+	}
+
+	// the expired events are skipped
+	if err := c.Delete(ctx, eventKey("device", 4)); err != nil {
+		t.Fatal(err)
+	}
+	events, err := h.Since(ctx, "device", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].id != 5 {
+		t.Errorf("Since() = %v, want event 5", events)
+	}
+
+	if ttl, err := c.GetTTL(ctx, seqKey("device")); err != nil || ttl <= 0 || ttl > seqTTL {
+		t.Errorf("sequence TTL = %v, %v, want up to %v", ttl, err, seqTTL)
+	}
+}
+
+func TestHistory_Reset(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+	h := newHistory(c, 3)
+
+	for range 2 {
+		if _, err := h.Append(ctx, "device", eventWrapper{name: "event", data: []byte("{}")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Ack(ctx, "device", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// the expired sequence starts over and drops the ack of the previous one
+	if err := c.Delete(ctx, seqKey("device")); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := h.Append(ctx, "device", eventWrapper{name: "event", data: []byte("{}")}); err != nil || id != 1 {
+		t.Fatalf("Append() = %d, %v, want 1", id, err)
+	}
+
+	acked, err := h.Acked(ctx, "device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acked != 0 {
+		t.Errorf("Acked() after reset = %d, want 0", acked)
+	}
+}
+
+func TestHistory_Ack(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+	h := newHistory(c, 3)
+
+	if err := h.Ack(ctx, "device", 1); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Ack() without events error = %v, want %v", err, ErrUnknownEvent)
+	}
+
+	for range 3 {
+		if _, err := h.Append(ctx, "device", eventWrapper{name: "event", data: []byte("{}")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := h.Ack(ctx, "device", 4); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Ack() after the last event error = %v, want %v", err, ErrUnknownEvent)
+	}
+
+	for _, id := range []int64{2, 1, 3, 2} {
+		if err := h.Ack(ctx, "device", id); err != nil {
+			t.Fatalf("Ack(%d) error = %v", id, err)
+		}
+	}
+
+	acked, err := h.Acked(ctx, "device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acked != 3 {
+		t.Errorf("Acked() = %d, want the greatest ack 3", acked)
+	}
+
+	if ttl, err := c.GetTTL(ctx, ackKey("device")); err != nil || ttl <= 0 || ttl > ackTTL {
+		t.Errorf("ack TTL = %v, %v, want up to %v", ttl, err, ackTTL)
+	}
+}
+
+func TestFormatEvent(t *testing.T) {
+	event := eventWrapper{name: "MessageEnqueued", data: []byte(`{}`)}
+	if got, want := formatEvent(event), "event: MessageEnqueued\ndata: {}"; got != want {
+		t.Errorf("formatEvent() = %q, want %q", got, want)
+	}
+
+	event.id = 7
+	if got, want := formatEvent(event), "id: 7\nevent: MessageEnqueued\ndata: {}"; got != want {
+		t.Errorf("formatEvent() = %q, want %q", got, want)
+	}
+}
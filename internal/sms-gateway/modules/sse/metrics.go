@@ -1,6 +1,9 @@
 package sse
 
 import (
+	"time"
+
+	appmetrics "github.com/android-sms-gateway/server/internal/sms-gateway/modules/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -12,14 +15,27 @@ const (
 	MetricConnectionErrors  = "connection_errors_total"
 	MetricEventLatency      = "event_delivery_latency_seconds"
 	MetricKeepalivesSent    = "keepalives_sent_total"
+	MetricEventsReplayed    = "events_replayed_total"
+	MetricEventsDropped     = "events_dropped_total"
+	MetricEventsFiltered    = "events_filtered_total"
+	MetricEventsCoalesced   = "events_coalesced_total"
+	MetricEventsUnacked     = "events_unacked"
+
+	MetricWebSocketActiveConnections = "active_connections"
+	MetricWebSocketMessagesSent      = "messages_sent_total"
+	MetricWebSocketErrors            = "errors_total"
+	MetricWebSocketPingsSent         = "pings_sent_total"
 
 	LabelEventType = "event_type"
 	LabelErrorType = "error_type"
+	LabelPolicy    = "policy"
 
-	ErrorTypeBufferFull   = "buffer_full"
-	ErrorTypeNoConnection = "no_connection"
-	ErrorTypeWriteFailure = "write_failure"
-	ErrorTypeMarshalError = "marshal_error"
+	ErrorTypeBufferFull     = "buffer_full"
+	ErrorTypeNoConnection   = "no_connection"
+	ErrorTypeWriteFailure   = "write_failure"
+	ErrorTypeReadFailure    = "read_failure"
+	ErrorTypeMarshalError   = "marshal_error"
+	ErrorTypePublishFailure = "publish_failure"
 )
 
 // metrics contains all Prometheus metrics for the SSE module
@@ -29,6 +45,16 @@ type metrics struct {
 	connectionErrors     *prometheus.CounterVec
 	eventDeliveryLatency *prometheus.HistogramVec
 	keepalivesSent       *prometheus.CounterVec
+	eventsReplayed       prometheus.Counter
+	eventsDropped        *prometheus.CounterVec
+	eventsFiltered       *prometheus.CounterVec
+	eventsCoalesced      *prometheus.CounterVec
+	eventsUnacked        prometheus.Gauge
+
+	wsActiveConnections prometheus.Gauge
+	wsMessagesSent      *prometheus.CounterVec
+	wsErrors            *prometheus.CounterVec
+	wsPingsSent         prometheus.Counter
 }
 
 // newMetrics creates and initializes all SSE metrics
@@ -56,8 +82,12 @@ func newMetrics() *metrics {
 			Namespace: "sms",
 			Subsystem: "sse",
 			Name:      MetricEventLatency,
-			Help:      "Event delivery latency in seconds",
-			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+			Help:      "Event delivery latency in seconds, from the event being sent to its write to the SSE stream",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+
+			NativeHistogramBucketFactor:     appmetrics.NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  appmetrics.NativeHistogramMaxBucketNumber,
+			NativeHistogramMinResetDuration: appmetrics.NativeHistogramMinResetDuration,
 		}, []string{}),
 		keepalivesSent: promauto.NewCounterVec(prometheus.CounterOpts{
 			Namespace: "sms",
@@ -65,16 +95,79 @@ func newMetrics() *metrics {
 			Name:      MetricKeepalivesSent,
 			Help:      "Total keepalive messages sent",
 		}, []string{}),
+		eventsReplayed: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "sse",
+			Name:      MetricEventsReplayed,
+			Help:      "Total number of SSE events replayed on reconnect",
+		}),
+		eventsDropped: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "sse",
+			Name:      MetricEventsDropped,
+			Help:      "Total number of events dropped on connection buffer overflow, labeled by overflow policy",
+		}, []string{LabelPolicy}),
+		eventsFiltered: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "sse",
+			Name:      MetricEventsFiltered,
+			Help:      "Total number of events skipped by the event types filter of the connection",
+		}, []string{LabelEventType}),
+		eventsCoalesced: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "sse",
+			Name:      MetricEventsCoalesced,
+			Help:      "Total number of events merged into the other events of the same type, labeled by event type",
+		}, []string{LabelEventType}),
+		eventsUnacked: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "sms",
+			Subsystem: "sse",
+			Name:      MetricEventsUnacked,
+			Help:      "Current number of events written to the connected devices that acknowledge the events and not acknowledged yet",
+		}),
+
+		wsActiveConnections: promauto.NewGauge(prometheus.GaugeOpts{
+			Namespace: "sms",
+			Subsystem: "websocket",
+			Name:      MetricWebSocketActiveConnections,
+			Help:      "Current number of active WebSocket event connections",
+		}),
+		wsMessagesSent: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "websocket",
+			Name:      MetricWebSocketMessagesSent,
+			Help:      "Total number of events written to WebSocket connections, labeled by event type",
+		}, []string{LabelEventType}),
+		wsErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "websocket",
+			Name:      MetricWebSocketErrors,
+			Help:      "Total number of WebSocket connection errors, labeled by error type",
+		}, []string{LabelErrorType}),
+		wsPingsSent: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "websocket",
+			Name:      MetricWebSocketPingsSent,
+			Help:      "Total WebSocket pings sent",
+		}),
 	}
 
 	return metrics
 }
 
-func (m *metrics) IncrementActiveConnections() {
+func (m *metrics) IncrementActiveConnections(t transport) {
+	if t == transportWebSocket {
+		m.wsActiveConnections.Inc()
+		return
+	}
 	m.activeConnections.WithLabelValues().Inc()
 }
 
-func (m *metrics) DecrementActiveConnections() {
+func (m *metrics) DecrementActiveConnections(t transport) {
+	if t == transportWebSocket {
+		m.wsActiveConnections.Dec()
+		return
+	}
 	m.activeConnections.WithLabelValues().Dec()
 }
 
@@ -86,12 +179,60 @@ func (m *metrics) IncrementConnectionErrors(errorType string) {
 	m.connectionErrors.WithLabelValues(errorType).Inc()
 }
 
-func (m *metrics) ObserveEventDeliveryLatency(f func()) {
-	timer := prometheus.NewTimer(m.eventDeliveryLatency.WithLabelValues())
-	f()
-	timer.ObserveDuration()
+// ObserveEventDeliveryLatency records the time since the event was sent. The
+// observation is linked to the trace of the event if traceID is not empty.
+// The replayed events without the send time aren't observed.
+func (m *metrics) ObserveEventDeliveryLatency(sentAt time.Time, traceID string) {
+	if sentAt.IsZero() {
+		return
+	}
+
+	// the event received from another instance may be ahead of the clock
+	latency := max(time.Since(sentAt), 0)
+	appmetrics.ObserveWithExemplar(m.eventDeliveryLatency.WithLabelValues(), latency.Seconds(), traceID)
 }
 
 func (m *metrics) IncrementKeepalivesSent() {
 	m.keepalivesSent.WithLabelValues().Inc()
 }
+
+func (m *metrics) IncrementEventsReplayed(count int) {
+	m.eventsReplayed.Add(float64(count))
+}
+
+func (m *metrics) IncrementWebSocketMessagesSent(eventType string) {
+	m.wsMessagesSent.WithLabelValues(eventType).Inc()
+}
+
+func (m *metrics) IncrementWebSocketErrors(errorType string) {
+	m.wsErrors.WithLabelValues(errorType).Inc()
+}
+
+func (m *metrics) IncrementWebSocketPingsSent() {
+	m.wsPingsSent.Inc()
+}
+
+func (m *metrics) IncrementEventsDropped(policy OverflowPolicy, count int) {
+	m.eventsDropped.WithLabelValues(string(policy)).Add(float64(count))
+}
+
+// IncrementEventsFiltered increments the counter of the events skipped by the
+// connection filter
+func (m *metrics) IncrementEventsFiltered(eventType string) {
+	m.eventsFiltered.WithLabelValues(eventType).Inc()
+}
+
+// IncrementEventsCoalesced increments the counter of the events merged into
+// the other events
+func (m *metrics) IncrementEventsCoalesced(eventType string, count int) {
+	m.eventsCoalesced.WithLabelValues(eventType).Add(float64(count))
+}
+
+// AddEventsUnacked changes the number of the written events waiting for the
+// acknowledgement
+func (m *metrics) AddEventsUnacked(delta int) {
+	if delta == 0 {
+		return
+	}
+	m.eventsUnacked.Add(float64(delta))
+}
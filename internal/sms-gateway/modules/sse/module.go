@@ -3,6 +3,7 @@ package sse
 import (
 	"context"
 
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -16,12 +17,25 @@ var Module = fx.Module(
 		newMetrics,
 		fx.Private,
 	),
+	fx.Provide(
+		newBroker,
+		fx.Private,
+	),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("sse")
+	}, fx.Private),
 	fx.Provide(
 		NewService,
 	),
 	fx.Invoke(func(lc fx.Lifecycle, svc *Service) {
+		ctx, cancel := context.WithCancel(context.Background())
 		lc.Append(fx.Hook{
+			OnStart: func(_ context.Context) error {
+				go svc.Run(ctx)
+				return nil
+			},
 			OnStop: func(ctx context.Context) error {
+				cancel()
 				return svc.Close(ctx)
 			},
 		})
@@ -4,10 +4,17 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/pkg/cache"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/google/uuid"
@@ -16,9 +23,16 @@ import (
 
 type Service struct {
 	config Config
+	// broker fans the events out to the instances, nil for a single instance
+	broker broker
+	// history replays the missed events, nil if disabled
+	history *history
+	// coalescer merges the bursts of the events, nil if disabled
+	coalescer *coalescer
 
 	mu          sync.RWMutex
 	connections map[string][]*sseConnection
+	observers   []Observer
 
 	logger  *zap.Logger
 	metrics *metrics
@@ -26,59 +40,429 @@ type Service struct {
 
 type sseConnection struct {
 	id          string
+	transport   transport
 	channel     chan eventWrapper
 	closeSignal chan struct{}
+
+	// overflow is closed to disconnect the device with the full buffer
+	overflow     chan struct{}
+	overflowOnce sync.Once
+
+	connectedAt time.Time
+	// events are the accepted event types, nil accepts all
+	events map[string]struct{}
+
+	statsMu          sync.Mutex
+	eventsSent       uint64
+	lastWriteError   string
+	lastWriteErrorAt *time.Time
+
+	// acking is set once the device acknowledges the events, then the
+	// written events are tracked in unacked until acknowledged
+	ackMu   sync.Mutex
+	acking  bool
+	unacked []int64
 }
 
+// accepts reports whether the event type is delivered to the connection.
+func (c *sseConnection) accepts(eventType string) bool {
+	if c.events == nil {
+		return true
+	}
+
+	_, ok := c.events[eventType]
+	return ok
+}
+
+// written records the result of the event write to the device.
+func (c *sseConnection) written(err error) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	if err != nil {
+		now := time.Now()
+		c.lastWriteError = err.Error()
+		c.lastWriteErrorAt = &now
+		return
+	}
+
+	c.eventsSent++
+}
+
+// track records the written event waiting for the acknowledgement, at most
+// limit events are kept. It returns the change of the number of the tracked
+// events.
+func (c *sseConnection) track(id int64, limit int) int {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+
+	if !c.acking || id == 0 || slices.Contains(c.unacked, id) {
+		return 0
+	}
+
+	c.unacked = append(c.unacked, id)
+	if len(c.unacked) > limit {
+		// the older events can't be replayed anyway
+		c.unacked = c.unacked[1:]
+		return 0
+	}
+
+	return 1
+}
+
+// ack starts the tracking of the written events and removes the events up to
+// the ID. It returns the number of the removed events.
+func (c *sseConnection) ack(id int64) int {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+
+	c.acking = true
+
+	before := len(c.unacked)
+	c.unacked = slices.DeleteFunc(c.unacked, func(unacked int64) bool {
+		return unacked <= id
+	})
+
+	return before - len(c.unacked)
+}
+
+// untrack stops the tracking and returns the number of the events which
+// weren't acknowledged.
+func (c *sseConnection) untrack() int {
+	c.ackMu.Lock()
+	defer c.ackMu.Unlock()
+
+	count := len(c.unacked)
+	c.acking = false
+	c.unacked = nil
+
+	return count
+}
+
+// info returns the snapshot of the connection state.
+func (c *sseConnection) info(deviceID string) ConnectionInfo {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	return ConnectionInfo{
+		DeviceID:         deviceID,
+		ConnectionID:     c.id,
+		Transport:        string(c.transport),
+		ConnectedAt:      c.connectedAt,
+		EventsSent:       c.eventsSent,
+		LastWriteError:   c.lastWriteError,
+		LastWriteErrorAt: c.lastWriteErrorAt,
+	}
+}
+
+// ConnectionInfo describes the active connection of the device to this
+// instance.
+type ConnectionInfo struct {
+	DeviceID     string
+	ConnectionID string
+	// Transport is either "sse" or "websocket"
+	Transport   string
+	ConnectedAt time.Time
+	// EventsSent is the number of the events written to the device,
+	// including the replayed ones
+	EventsSent uint64
+	// LastWriteError is the error of the last failed write, empty if there
+	// were none
+	LastWriteError   string
+	LastWriteErrorAt *time.Time
+}
+
+func (c *sseConnection) disconnect() {
+	c.overflowOnce.Do(func() {
+		close(c.overflow)
+	})
+}
+
+// Observer is called for each event published by any instance, e.g. to wake
+// up the long-polling requests of the device.
+type Observer func(deviceID string, eventType smsgateway.PushEventType)
+
+// transport is the protocol of the device connection.
+type transport string
+
+const (
+	transportSSE       transport = "sse"
+	transportWebSocket transport = "websocket"
+)
+
 type eventWrapper struct {
+	// id is the ID of the event in the history, zero without history
+	id   int64
 	name string
 	data []byte
+
+	// traceID is the trace ID of the request which caused the event
+	traceID string
+	// sentAt is the time the event was sent by the publisher, zero for the
+	// replayed events
+	sentAt time.Time
+}
+
+// brokerMessage is the event published to the broker.
+type brokerMessage struct {
+	DeviceID string          `json:"deviceId"`
+	ID       int64           `json:"id,omitempty"`
+	Event    string          `json:"event"`
+	Data     json.RawMessage `json:"data"`
+	TraceID  string          `json:"traceId,omitempty"`
+	SentAt   time.Time       `json:"sentAt,omitzero"`
+	// Ack is set for the acknowledgement of the events up to the ID
+	Ack bool `json:"ack,omitempty"`
 }
 
-func NewService(config Config, logger *zap.Logger, metrics *metrics) *Service {
-	return &Service{
-		config: config,
+const (
+	sendTimeout      = 5 * time.Second
+	resubscribeDelay = 5 * time.Second
+)
+
+var errNoConnection = errors.New("no connection")
+
+func NewService(config Config, broker broker, c cache.Cache, logger *zap.Logger, metrics *metrics) *Service {
+	var h *history
+	if config.replayBufferSize > 0 {
+		h = newHistory(c, config.replayBufferSize)
+	}
+
+	s := &Service{
+		config:  config,
+		broker:  broker,
+		history: h,
 
 		connections: make(map[string][]*sseConnection),
 
 		logger:  logger,
 		metrics: metrics,
 	}
+	if len(config.coalesceWindows) > 0 {
+		s.coalescer = newCoalescer(config.coalesceWindows, s.sendCoalesced)
+	}
+
+	return s
 }
 
+// Send delivers the event to the connections of the device. With the broker
+// the event is published to all the instances and the error only reports the
+// failed publishing. With the history the event is also kept for the replay
+// on reconnect. The events of the coalesced types are sent when the window
+// ends, the errors of their delivery are only logged.
 func (s *Service) Send(deviceID string, event Event) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		// Increment connection errors metric for marshaling error
+		s.metrics.IncrementConnectionErrors(ErrorTypeMarshalError)
+		return fmt.Errorf("can't marshal event: %w", err)
+	}
 
-	connections, exists := s.connections[deviceID]
-	if !exists {
-		// Increment connection errors metric for no connection
-		s.metrics.IncrementConnectionErrors(ErrorTypeNoConnection)
-		return fmt.Errorf("no connection for device %s", deviceID)
+	wrapper := eventWrapper{name: string(event.Type), data: data, traceID: event.TraceID, sentAt: time.Now()}
+
+	// without the broker the event for the device without connection is only
+	// kept for the replay, there is nothing to coalesce
+	if s.coalescer != nil && s.coalescer.Coalesces(wrapper.name) && (s.broker != nil || s.connected(deviceID)) {
+		s.coalescer.Add(deviceID, wrapper)
+		return nil
 	}
 
-	data, err := json.Marshal(event.Data)
+	return s.send(deviceID, wrapper)
+}
+
+// sendCoalesced sends the event merged from the count events. The error is
+// only logged, the events were accepted by Send.
+func (s *Service) sendCoalesced(deviceID string, event eventWrapper, count int) {
+	s.metrics.IncrementEventsCoalesced(event.name, count-1)
+
+	if err := s.send(deviceID, event); err != nil && !errors.Is(err, errNoConnection) {
+		s.logger.Warn("Failed to send coalesced event",
+			zap.String("device_id", deviceID),
+			zap.String("event", event.name),
+			zap.Int("count", count),
+			zap.Error(err))
+	}
+}
+
+// send keeps the event for the replay and delivers it to the local
+// connections or publishes it to the broker.
+func (s *Service) send(deviceID string, wrapper eventWrapper) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	var err error
+	if s.history != nil {
+		// the event is still delivered to the connected devices
+		if wrapper.id, err = s.history.Append(ctx, deviceID, wrapper); err != nil {
+			s.logger.Warn("Failed to keep event for replay", zap.String("device_id", deviceID), zap.Error(err))
+		}
+	}
+
+	if s.broker == nil {
+		err := s.deliver(deviceID, wrapper)
+		if errors.Is(err, errNoConnection) {
+			// Increment connection errors metric for no connection
+			s.metrics.IncrementConnectionErrors(ErrorTypeNoConnection)
+		}
+		return err
+	}
+
+	payload, err := json.Marshal(brokerMessage{
+		DeviceID: deviceID,
+		ID:       wrapper.id,
+		Event:    wrapper.name,
+		Data:     wrapper.data,
+		TraceID:  wrapper.traceID,
+		SentAt:   wrapper.sentAt,
+	})
 	if err != nil {
-		// Increment connection errors metric for marshaling error
 		s.metrics.IncrementConnectionErrors(ErrorTypeMarshalError)
 		return fmt.Errorf("can't marshal event: %w", err)
 	}
 
-	sent := 0
-	for _, conn := range connections {
+	if err := s.broker.Publish(ctx, payload); err != nil {
+		s.metrics.IncrementConnectionErrors(ErrorTypePublishFailure)
+		return err
+	}
+
+	return nil
+}
+
+// Ack records the ID of the last event processed by the device. The ack is
+// cumulative, the events up to the ID aren't re-sent on reconnect, so the
+// device acknowledges the event after processing all the previous ones. After
+// the first ack the events after the acknowledged one are replayed on
+// reconnect even if the device received them. It returns ErrReplayDisabled
+// without the replay and ErrUnknownEvent for the ID not assigned yet.
+func (s *Service) Ack(deviceID string, id int64) error {
+	if s.history == nil {
+		return ErrReplayDisabled
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	if err := s.history.Ack(ctx, deviceID, id); err != nil {
+		return err
+	}
+
+	if s.broker == nil {
+		s.acked(deviceID, id)
+		return nil
+	}
+
+	// the device may be connected to another instance
+	payload, err := json.Marshal(brokerMessage{DeviceID: deviceID, ID: id, Ack: true})
+	if err != nil {
+		s.metrics.IncrementConnectionErrors(ErrorTypeMarshalError)
+		return fmt.Errorf("can't marshal ack: %w", err)
+	}
+
+	if err := s.broker.Publish(ctx, payload); err != nil {
+		s.metrics.IncrementConnectionErrors(ErrorTypePublishFailure)
+		return err
+	}
+
+	return nil
+}
+
+// acked removes the acknowledged events of the local connections of the
+// device.
+func (s *Service) acked(deviceID string, id int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, conn := range s.connections[deviceID] {
+		s.metrics.AddEventsUnacked(-conn.ack(id))
+	}
+}
+
+// Observe registers the observer of the events received from the broker. It
+// is never called without the broker.
+func (s *Service) Observe(observer Observer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.observers = append(s.observers, observer)
+}
+
+// Run delivers the events published by all the instances to the local
+// connections until the context is done. It is a no-op without the broker.
+func (s *Service) Run(ctx context.Context) {
+	if s.broker == nil {
+		return
+	}
+
+	for {
+		err := s.broker.Subscribe(ctx, s.receive)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.logger.Error("Failed to subscribe to events", zap.Error(err))
+		}
+
 		select {
-		case conn.channel <- eventWrapper{string(event.Type), data}:
-			// Message sent successfully
+		case <-time.After(resubscribeDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) receive(payload []byte) {
+	var msg brokerMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		s.logger.Error("Failed to decode published event", zap.Error(err))
+		return
+	}
+
+	if msg.Ack {
+		s.acked(msg.DeviceID, msg.ID)
+		return
+	}
+
+	s.mu.RLock()
+	observers := s.observers
+	s.mu.RUnlock()
+	for _, observer := range observers {
+		observer(msg.DeviceID, smsgateway.PushEventType(msg.Event))
+	}
+
+	// most instances have no connection of the device
+	event := eventWrapper{id: msg.ID, name: msg.Event, data: msg.Data, traceID: msg.TraceID, sentAt: msg.SentAt}
+	if err := s.deliver(msg.DeviceID, event); err != nil && !errors.Is(err, errNoConnection) {
+		s.logger.Warn("Failed to deliver published event", zap.String("device_id", msg.DeviceID), zap.Error(err))
+	}
+}
+
+// deliver writes the event to the local connections of the device.
+func (s *Service) deliver(deviceID string, event eventWrapper) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	connections, exists := s.connections[deviceID]
+	if !exists {
+		return fmt.Errorf("%w for device %s", errNoConnection, deviceID)
+	}
+
+	sent, filtered := 0, 0
+	for _, conn := range connections {
+		if !conn.accepts(event.name) {
+			filtered++
+			s.metrics.IncrementEventsFiltered(event.name)
+			continue
+		}
+		if s.push(deviceID, conn, event) {
 			sent++
-		case <-conn.closeSignal:
-			s.logger.Warn("Connection closed while sending event", zap.String("device_id", deviceID), zap.String("connection_id", conn.id))
-		default:
-			s.logger.Warn("Connection buffer full while sending event", zap.String("device_id", deviceID), zap.String("connection_id", conn.id))
-			// Increment connection errors metric for buffer full
-			s.metrics.IncrementConnectionErrors(ErrorTypeBufferFull)
 		}
 	}
 
+	if filtered == len(connections) {
+		// the device isn't interested in the event
+		return nil
+	}
+
 	if sent == 0 {
 		// Increment connection errors metric for no active connection
 		s.metrics.IncrementConnectionErrors(ErrorTypeNoConnection)
@@ -86,12 +470,65 @@ func (s *Service) Send(deviceID string, event Event) error {
 	}
 
 	// Count events sent
-	s.metrics.IncrementEventsSent(string(event.Type))
+	s.metrics.IncrementEventsSent(event.name)
 
 	return nil
 }
 
+// push queues the event to the connection, the full buffer is handled by the
+// overflow policy. It reports whether the event is queued.
+func (s *Service) push(deviceID string, conn *sseConnection, event eventWrapper) bool {
+	select {
+	case conn.channel <- event:
+		// Message sent successfully
+		return true
+	case <-conn.closeSignal:
+		s.logger.Warn("Connection closed while sending event", zap.String("device_id", deviceID), zap.String("connection_id", conn.id))
+		return false
+	default:
+	}
+
+	policy := s.config.overflowPolicy
+	s.logger.Warn("Connection buffer full while sending event",
+		zap.String("device_id", deviceID),
+		zap.String("connection_id", conn.id),
+		zap.String("policy", string(policy)))
+	// Increment connection errors metric for buffer full
+	s.metrics.IncrementConnectionErrors(ErrorTypeBufferFull)
+
+	switch policy {
+	case OverflowDropOldest:
+		select {
+		case <-conn.channel:
+			s.metrics.IncrementEventsDropped(policy, 1)
+		default:
+		}
+		select {
+		case conn.channel <- event:
+			return true
+		default:
+			// the buffer is refilled concurrently
+			s.metrics.IncrementEventsDropped(policy, 1)
+			return false
+		}
+	case OverflowDisconnect:
+		// the buffered events are lost with the connection
+		s.metrics.IncrementEventsDropped(policy, 1+len(conn.channel))
+		conn.disconnect()
+		return false
+	default:
+		s.metrics.IncrementEventsDropped(policy, 1)
+		return false
+	}
+}
+
 func (s *Service) Close(_ context.Context) error {
+	// the pending events are kept for the replay or published to the
+	// other instances
+	if s.coalescer != nil {
+		s.coalescer.Close()
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -104,52 +541,95 @@ func (s *Service) Close(_ context.Context) error {
 	return nil
 }
 
-func (s *Service) Handler(deviceID string, c *fiber.Ctx) error {
+// Handler streams the events of the device until the connection is closed.
+// If events aren't empty, only the events of these types are delivered to the
+// connection. The stream is compressed with the gzip or deflate encoding
+// accepted by the device unless the compression is disabled.
+func (s *Service) Handler(deviceID string, events []string, c *fiber.Ctx) error {
 	c.Set("Content-Type", "text/event-stream")
 	c.Set("Cache-Control", "no-cache")
 	c.Set("Connection", "keep-alive")
 	c.Set("Transfer-Encoding", "chunked")
 
-	c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
-		conn := s.registerConnection(deviceID)
+	encoding := ""
+	if s.config.compression {
+		encoding = negotiateEncoding(c)
+		c.Vary(fiber.HeaderAcceptEncoding)
+	}
+	if encoding != "" {
+		c.Set(fiber.HeaderContentEncoding, encoding)
+	}
+
+	// the ID of the last event received by the reconnecting device, the
+	// invalid one is ignored
+	lastEventID, _ := strconv.ParseInt(c.Get(fiber.HeaderLastEventID), 10, 64)
+	// the stream is copied to the connection by the server, the write
+	// deadline of the connection fails the copy to the stalled device
+	netConn := c.Context().Conn()
+
+	c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(bw *bufio.Writer) {
+		w := newEventStream(bw, encoding)
+		defer func() {
+			_ = w.Close()
+		}()
+
+		conn := s.registerConnection(deviceID, transportSSE, events)
 		defer s.removeConnection(deviceID, conn.id)
+		defer func() {
+			_ = netConn.SetWriteDeadline(time.Time{})
+		}()
+
+		logger := s.logger.With(zap.String("device_id", deviceID), zap.String("connection_id", conn.id))
+
+		// the events sent after the registration may be both replayed and
+		// received from the channel, the latter are skipped
+		replayed, err := s.resume(deviceID, conn, lastEventID, func(event eventWrapper) error {
+			if !conn.accepts(event.name) {
+				return nil
+			}
+			err := s.writeToStream(netConn, w, formatEvent(event))
+			s.written(conn, event, err)
+			return err
+		})
+		if err != nil {
+			logger.Warn("Failed to replay events", zap.Error(err))
+			return
+		}
 
-		// Conditionally create ticker
-		var ticker *time.Ticker
+		// the nil channel never fires without keep alive
+		var keepAlives <-chan time.Time
 		if s.config.keepAlivePeriod > 0 {
-			ticker = time.NewTicker(s.config.keepAlivePeriod)
+			ticker := time.NewTicker(s.config.keepAlivePeriod)
 			defer ticker.Stop()
+			keepAlives = ticker.C
 		}
 
 		for {
 			select {
 			case event := <-conn.channel:
-				s.metrics.ObserveEventDeliveryLatency(func() {
-					if err := s.writeToStream(w, fmt.Sprintf("event: %s\ndata: %s", event.name, utils.UnsafeString(event.data))); err != nil {
-						s.logger.Warn("Failed to write event data",
-							zap.String("device_id", deviceID),
-							zap.String("connection_id", conn.id),
-							zap.Error(err))
-						return
-					}
-				})
-			// Conditionally handle ticker events
-			case <-func() <-chan time.Time {
-				if ticker != nil {
-					return ticker.C
+				if replayed.contains(event.id) {
+					continue
+				}
+				err := s.writeToStream(netConn, w, formatEvent(event))
+				s.written(conn, event, err)
+				if err != nil {
+					// the device doesn't consume the events, it reconnects
+					// and gets the missed ones by the replay
+					logger.Warn("Failed to write event data, disconnecting", zap.Error(err))
+					return
 				}
-				// Return nil channel that never fires when disabled
-				return make(chan time.Time)
-			}():
-				if err := s.writeToStream(w, ":keepalive"); err != nil {
-					s.logger.Warn("Failed to write keepalive",
-						zap.String("device_id", deviceID),
-						zap.String("connection_id", conn.id),
-						zap.Error(err))
+				s.metrics.ObserveEventDeliveryLatency(event.sentAt, event.traceID)
+			case <-keepAlives:
+				if err := s.writeToStream(netConn, w, ":keepalive"); err != nil {
+					conn.written(err)
+					logger.Warn("Failed to write keepalive, disconnecting", zap.Error(err))
 					return
 				}
 				// Count keepalives sent
 				s.metrics.IncrementKeepalivesSent()
+			case <-conn.overflow:
+				logger.Warn("Disconnecting on buffer overflow")
+				return
 			case <-conn.closeSignal:
 				return
 			}
@@ -159,15 +639,200 @@ func (s *Service) Handler(deviceID string, c *fiber.Ctx) error {
 	return nil
 }
 
-func (s *Service) writeToStream(w *bufio.Writer, data string) error {
+// connected reports whether the device has a connection to this instance.
+func (s *Service) connected(deviceID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.connections[deviceID]
+	return ok
+}
+
+// Connections returns the active connections to this instance ordered by
+// the device and the connection time.
+func (s *Service) Connections() []ConnectionInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]ConnectionInfo, 0, len(s.connections))
+	for deviceID, connections := range s.connections {
+		for _, conn := range connections {
+			items = append(items, conn.info(deviceID))
+		}
+	}
+
+	slices.SortFunc(items, func(a, b ConnectionInfo) int {
+		if c := strings.Compare(a.DeviceID, b.DeviceID); c != 0 {
+			return c
+		}
+		return a.ConnectedAt.Compare(b.ConnectedAt)
+	})
+
+	return items
+}
+
+// Connect registers an in-process connection of the device, e.g. of the
+// virtual device. The returned channel receives the types of the sent events
+// until the returned function is called. The connection disconnected by the
+// overflow policy is registered again and gets the missed events by the
+// replay, as a device reconnecting with the last event ID would.
+func (s *Service) Connect(deviceID string) (<-chan string, func()) {
+	conn := s.registerConnection(deviceID, transportSSE, nil)
+	ch := make(chan string, cap(conn.channel))
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		var lastEventID int64
+		var replayed replayedIDs
+		write := func(event eventWrapper) error {
+			select {
+			case ch <- event.name:
+				conn.written(nil)
+			default:
+			}
+			lastEventID = max(lastEventID, event.id)
+			return nil
+		}
+
+		for {
+			select {
+			case event := <-conn.channel:
+				if replayed.contains(event.id) {
+					continue
+				}
+				_ = write(event)
+			case <-conn.overflow:
+				s.logger.Warn("Reconnecting on buffer overflow", zap.String("device_id", deviceID), zap.String("connection_id", conn.id))
+				s.removeConnection(deviceID, conn.id)
+				conn = s.registerConnection(deviceID, transportSSE, nil)
+
+				var err error
+				if replayed, err = s.replay(deviceID, lastEventID, write); err != nil {
+					s.logger.Warn("Failed to replay events", zap.String("device_id", deviceID), zap.Error(err))
+				}
+			case <-conn.closeSignal:
+				return
+			case <-done:
+				s.removeConnection(deviceID, conn.id)
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// written records the result of the event write to the connection and tracks
+// the written event until acknowledged.
+func (s *Service) written(conn *sseConnection, event eventWrapper, err error) {
+	conn.written(err)
+	if err == nil {
+		s.metrics.AddEventsUnacked(conn.track(event.id, s.config.replayBufferSize))
+	}
+}
+
+// resume replays the events to the connected device. The device that
+// acknowledges the events gets the ones after the acknowledged event, even if
+// it received them, and its connection tracks the written events.
+func (s *Service) resume(deviceID string, conn *sseConnection, lastEventID int64, write func(eventWrapper) error) (replayedIDs, error) {
+	if s.history == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	ackedID, err := s.history.Acked(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if ackedID > 0 {
+		conn.ack(ackedID)
+		if lastEventID <= 0 || ackedID < lastEventID {
+			lastEventID = ackedID
+		}
+	}
+
+	return s.replay(deviceID, lastEventID, write)
+}
+
+// replay writes the events the device missed after the last event ID and
+// returns the IDs of the written events. Only these events are skipped when
+// received from the channel, so an event stored after the replay has read
+// the history is delivered live rather than lost.
+func (s *Service) replay(deviceID string, lastEventID int64, write func(eventWrapper) error) (replayedIDs, error) {
+	if s.history == nil || lastEventID <= 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	events, err := s.history.Since(ctx, deviceID, lastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed := make(replayedIDs, len(events))
+	for _, event := range events {
+		if err := write(event); err != nil {
+			return nil, err
+		}
+		replayed[event.id] = struct{}{}
+	}
+	s.metrics.IncrementEventsReplayed(len(events))
+
+	return replayed, nil
+}
+
+// replayedIDs is the set of the IDs of the replayed events.
+type replayedIDs map[int64]struct{}
+
+// contains reports whether the event with the ID has been replayed.
+func (r replayedIDs) contains(id int64) bool {
+	_, ok := r[id]
+	return id != 0 && ok
+}
+
+// formatEvent returns the event in the stream format, with the ID for the
+// replay if it is known.
+func formatEvent(event eventWrapper) string {
+	if event.id == 0 {
+		return fmt.Sprintf("event: %s\ndata: %s", event.name, utils.UnsafeString(event.data))
+	}
+
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s", event.id, event.name, utils.UnsafeString(event.data))
+}
+
+// writeToStream writes the data to the stream of the connection. The write
+// fails if the device doesn't consume the data within the write timeout.
+func (s *Service) writeToStream(conn net.Conn, w *eventStream, data string) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(s.config.writeTimeout)); err != nil {
+		s.metrics.IncrementConnectionErrors(ErrorTypeWriteFailure)
+		return err
+	}
+
 	if _, err := fmt.Fprintf(w, "%s\n\n", data); err != nil {
 		s.metrics.IncrementConnectionErrors(ErrorTypeWriteFailure)
 		return err
 	}
-	return w.Flush()
+	if err := w.Flush(); err != nil {
+		s.metrics.IncrementConnectionErrors(ErrorTypeWriteFailure)
+		return err
+	}
+
+	return nil
 }
 
-func (s *Service) registerConnection(deviceID string) *sseConnection {
+func (s *Service) registerConnection(deviceID string, t transport, events []string) *sseConnection {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -175,8 +840,18 @@ func (s *Service) registerConnection(deviceID string) *sseConnection {
 
 	conn := &sseConnection{
 		id:          connID,
-		channel:     make(chan eventWrapper, 8),
+		transport:   t,
+		channel:     make(chan eventWrapper, s.config.bufferSize),
 		closeSignal: make(chan struct{}),
+		overflow:    make(chan struct{}),
+		connectedAt: time.Now(),
+	}
+
+	if len(events) > 0 {
+		conn.events = make(map[string]struct{}, len(events))
+		for _, event := range events {
+			conn.events[event] = struct{}{}
+		}
 	}
 
 	if _, ok := s.connections[deviceID]; !ok {
@@ -186,9 +861,9 @@ func (s *Service) registerConnection(deviceID string) *sseConnection {
 	s.connections[deviceID] = append(s.connections[deviceID], conn)
 
 	// Increment active connections metric
-	s.metrics.IncrementActiveConnections()
+	s.metrics.IncrementActiveConnections(t)
 
-	s.logger.Info("Registering SSE connection", zap.String("device_id", deviceID), zap.String("connection_id", connID))
+	s.logger.Info("Registering SSE connection", zap.String("device_id", deviceID), zap.String("connection_id", connID), zap.String("transport", string(t)))
 
 	return conn
 }
@@ -202,14 +877,16 @@ func (s *Service) removeConnection(deviceID, connID string) {
 			if conn.id == connID {
 				close(conn.closeSignal)
 				s.connections[deviceID] = append(connections[:i], connections[i+1:]...)
+				// the unacknowledged events are replayed on reconnect
+				s.metrics.AddEventsUnacked(-conn.untrack())
 				s.logger.Info("Removing SSE connection", zap.String("device_id", deviceID), zap.String("connection_id", connID))
+
+				// Decrement active connections metric
+				s.metrics.DecrementActiveConnections(conn.transport)
 				break
 			}
 		}
 
-		// Decrement active connections metric
-		s.metrics.DecrementActiveConnections()
-
 		if len(s.connections[deviceID]) == 0 {
 			delete(s.connections, deviceID)
 		}
@@ -0,0 +1,688 @@
+package sse
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+var testMetrics = newMetrics()
+
+// fanoutBroker delivers the published payloads to all the subscribers, like
+// a pub/sub channel shared by the instances.
+type fanoutBroker struct {
+	mu       sync.Mutex
+	handlers []func([]byte)
+}
+
+func (b *fanoutBroker) Publish(_ context.Context, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, handler := range b.handlers {
+		handler(payload)
+	}
+	return nil
+}
+
+func (b *fanoutBroker) Subscribe(ctx context.Context, handler func([]byte)) error {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+
+	<-ctx.Done()
+	return nil
+}
+
+func (b *fanoutBroker) Close() error {
+	return nil
+}
+
+func (b *fanoutBroker) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.handlers)
+}
+
+func TestService_Send(t *testing.T) {
+	m := testMetrics
+	event := Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{}}
+
+	t.Run("in-process", func(t *testing.T) {
+		svc := NewService(NewConfig(), nil, cache.NewMemory(0), zap.NewNop(), m)
+
+		if err := svc.Send("device", event); err == nil {
+			t.Error("Send() without connection error = nil")
+		}
+
+		events, disconnect := svc.Connect("device")
+		defer disconnect()
+
+		if err := svc.Send("device", event); err != nil {
+			t.Fatal(err)
+		}
+		expectEvent(t, events)
+	})
+
+	t.Run("broker", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		b := &fanoutBroker{}
+		c := cache.NewMemory(0)
+		sender := NewService(NewConfig(), b, c, zap.NewNop(), m)
+		receiver := NewService(NewConfig(), b, c, zap.NewNop(), m)
+		go sender.Run(ctx)
+		go receiver.Run(ctx)
+
+		for b.subscribers() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+
+		events, disconnect := receiver.Connect("device")
+		defer disconnect()
+
+		if err := sender.Send("device", event); err != nil {
+			t.Fatal(err)
+		}
+		expectEvent(t, events)
+	})
+}
+
+func expectEvent(t *testing.T, events <-chan string) {
+	t.Helper()
+
+	select {
+	case name := <-events:
+		if name != string(smsgateway.PushMessageEnqueued) {
+			t.Errorf("event = %q, want %q", name, smsgateway.PushMessageEnqueued)
+		}
+	case <-time.After(time.Second):
+		t.Error("event is not delivered")
+	}
+}
+
+func TestService_SendTrace(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := &fanoutBroker{}
+	c := cache.NewMemory(0)
+	config := NewConfig(WithCoalesceWindows(map[string]time.Duration{
+		string(smsgateway.PushSettingsUpdated): 10 * time.Millisecond,
+	}))
+	sender := NewService(config, b, c, zap.NewNop(), testMetrics)
+	receiver := NewService(config, b, c, zap.NewNop(), testMetrics)
+	go sender.Run(ctx)
+	go receiver.Run(ctx)
+
+	for b.subscribers() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	conn := receiver.registerConnection("device", transportSSE, nil)
+	defer receiver.removeConnection("device", conn.id)
+
+	// the plain event and the coalesced one keep the trace and the send
+	// time across the instances
+	for _, eventType := range []smsgateway.PushEventType{smsgateway.PushMessageEnqueued, smsgateway.PushSettingsUpdated} {
+		before := time.Now()
+		if err := sender.Send("device", Event{Type: eventType, TraceID: traceID}); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case event := <-conn.channel:
+			if event.traceID != traceID {
+				t.Errorf("trace ID of %s = %q, want %q", eventType, event.traceID, traceID)
+			}
+			if event.sentAt.Before(before) || event.sentAt.After(time.Now()) {
+				t.Errorf("send time of %s = %s, want the time of Send()", eventType, event.sentAt)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s is not delivered", eventType)
+		}
+	}
+}
+
+func TestService_Overflow(t *testing.T) {
+	tests := []struct {
+		policy       OverflowPolicy
+		want         []int64
+		disconnected bool
+	}{
+		{policy: OverflowDropNewest, want: []int64{1, 2}},
+		{policy: OverflowDropOldest, want: []int64{2, 3}},
+		{policy: OverflowDisconnect, want: []int64{1, 2}, disconnected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			config := NewConfig(WithBufferSize(2), WithOverflowPolicy(tt.policy))
+			svc := NewService(config, nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+			conn := svc.registerConnection("device", transportSSE, nil)
+			defer svc.removeConnection("device", conn.id)
+
+			for id := int64(1); id <= 3; id++ {
+				_ = svc.deliver("device", eventWrapper{id: id, name: string(smsgateway.PushMessageEnqueued)})
+			}
+
+			got := []int64{}
+			for len(conn.channel) > 0 {
+				got = append(got, (<-conn.channel).id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("buffered = %v, want %v", got, tt.want)
+			}
+
+			select {
+			case <-conn.overflow:
+				if !tt.disconnected {
+					t.Error("connection is disconnected")
+				}
+			default:
+				if tt.disconnected {
+					t.Error("connection isn't disconnected")
+				}
+			}
+		})
+	}
+}
+
+// TestService_ConnectOverflow checks that the in-process connection
+// disconnected on overflow is registered again and keeps receiving events.
+func TestService_ConnectOverflow(t *testing.T) {
+	config := NewConfig(WithBufferSize(1), WithOverflowPolicy(OverflowDisconnect))
+	svc := NewService(config, nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+	event := Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{}}
+
+	events, disconnect := svc.Connect("device")
+
+	if err := svc.Send("device", event); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events)
+
+	first := svc.Connections()
+	if len(first) != 1 {
+		t.Fatalf("connections = %d, want 1", len(first))
+	}
+	svc.mu.RLock()
+	svc.connections["device"][0].disconnect()
+	svc.mu.RUnlock()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		items := svc.Connections()
+		if len(items) == 1 && items[0].ConnectionID != first[0].ConnectionID {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("connections = %+v, want the new connection", items)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := svc.Send("device", event); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events)
+
+	disconnect()
+	if items := svc.Connections(); len(items) != 0 {
+		t.Errorf("connections = %+v, want none", items)
+	}
+}
+
+// TestService_Failover checks that the device reconnected to another
+// instance after the failure of its instance gets both the missed and the
+// new events, regardless of the instance which sends them.
+func TestService_Failover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := &fanoutBroker{}
+	c := cache.NewMemory(0)
+	failed := NewService(NewConfig(), b, c, zap.NewNop(), testMetrics)
+	survivor := NewService(NewConfig(), b, c, zap.NewNop(), testMetrics)
+	sender := NewService(NewConfig(), b, c, zap.NewNop(), testMetrics)
+
+	failedCtx, failedCancel := context.WithCancel(ctx)
+	go failed.Run(failedCtx)
+	go survivor.Run(ctx)
+	go sender.Run(ctx)
+
+	for b.subscribers() < 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	observed := make(chan string, 3)
+	survivor.Observe(func(deviceID string, _ smsgateway.PushEventType) {
+		observed <- deviceID
+	})
+
+	event := Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{}}
+
+	events, _ := failed.Connect("device")
+	if err := sender.Send("device", event); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events)
+
+	failedCancel()
+	if err := failed.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// published while the device is reconnecting
+	if err := sender.Send("device", event); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := 0
+	if _, err := survivor.replay("device", 1, func(eventWrapper) error {
+		replayed++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if replayed != 1 {
+		t.Errorf("replayed = %d, want 1", replayed)
+	}
+
+	events, disconnect := survivor.Connect("device")
+	defer disconnect()
+
+	if err := sender.Send("device", event); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events)
+
+	for range 3 {
+		select {
+		case deviceID := <-observed:
+			if deviceID != "device" {
+				t.Errorf("observed device = %q, want %q", deviceID, "device")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("event is not observed")
+		}
+	}
+}
+
+func TestService_Connections(t *testing.T) {
+	svc := NewService(NewConfig(), nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+	second := svc.registerConnection("b", transportWebSocket, nil)
+	defer svc.removeConnection("b", second.id)
+	first := svc.registerConnection("a", transportSSE, nil)
+	defer svc.removeConnection("a", first.id)
+
+	first.written(nil)
+	first.written(nil)
+	second.written(errors.New("broken pipe"))
+
+	items := svc.Connections()
+	if len(items) != 2 {
+		t.Fatalf("len(Connections()) = %d, want 2", len(items))
+	}
+
+	if items[0].DeviceID != "a" || items[0].ConnectionID != first.id || items[0].Transport != "sse" || items[0].EventsSent != 2 || items[0].LastWriteError != "" {
+		t.Errorf("Connections()[0] = %+v", items[0])
+	}
+	if items[1].DeviceID != "b" || items[1].Transport != "websocket" || items[1].EventsSent != 0 || items[1].LastWriteError != "broken pipe" || items[1].LastWriteErrorAt == nil {
+		t.Errorf("Connections()[1] = %+v", items[1])
+	}
+
+	svc.removeConnection("a", first.id)
+	if items := svc.Connections(); len(items) != 1 {
+		t.Errorf("len(Connections()) after removal = %d, want 1", len(items))
+	}
+}
+
+func TestService_EventsFilter(t *testing.T) {
+	svc := NewService(NewConfig(), nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+	filtered := svc.registerConnection("device", transportSSE, []string{string(smsgateway.PushSettingsUpdated)})
+	defer svc.removeConnection("device", filtered.id)
+
+	// the event is skipped without an error, the device doesn't need it
+	if err := svc.deliver("device", eventWrapper{name: string(smsgateway.PushMessageEnqueued)}); err != nil {
+		t.Errorf("deliver() of filtered event error = %v", err)
+	}
+	if len(filtered.channel) != 0 {
+		t.Error("filtered event is delivered")
+	}
+
+	all := svc.registerConnection("device", transportSSE, nil)
+	defer svc.removeConnection("device", all.id)
+
+	if err := svc.deliver("device", eventWrapper{name: string(smsgateway.PushMessageEnqueued)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.deliver("device", eventWrapper{name: string(smsgateway.PushSettingsUpdated)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filtered.channel) != 1 || (<-filtered.channel).name != string(smsgateway.PushSettingsUpdated) {
+		t.Error("accepted event isn't delivered to the filtered connection")
+	}
+	if len(all.channel) != 2 {
+		t.Errorf("delivered to unfiltered connection = %d, want 2", len(all.channel))
+	}
+}
+
+func TestService_HandlerWriteTimeout(t *testing.T) {
+	svc := NewService(NewConfig(WithKeepAlivePeriod(0), WithWriteTimeout(100*time.Millisecond)), nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/events", func(c *fiber.Ctx) error {
+		return svc.Handler("device", nil, c)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	defer func() { _ = app.Shutdown() }()
+
+	// the device connects and never reads the stream
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.(*net.TCPConn).SetReadBuffer(1024)
+	if _, err := conn.Write([]byte("GET /events HTTP/1.1\r\nHost: localhost\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	event := Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{"id": strings.Repeat("x", 64*1024)}}
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if len(svc.Connections()) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the socket buffers are filled, then the stalled device is disconnected
+	for time.Now().Before(deadline) {
+		if err := svc.Send("device", event); errors.Is(err, errNoConnection) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Error("stalled device isn't disconnected")
+}
+
+func TestService_Ack(t *testing.T) {
+	event := Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{}}
+
+	svc := NewService(NewConfig(), nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+	resume := func(conn *sseConnection, lastEventID int64) []int64 {
+		t.Helper()
+
+		ids := []int64{}
+		if _, err := svc.resume("device", conn, lastEventID, func(event eventWrapper) error {
+			ids = append(ids, event.id)
+			svc.written(conn, event, nil)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+	write := func(conn *sseConnection) {
+		t.Helper()
+
+		if err := svc.Send("device", event); err != nil {
+			t.Fatal(err)
+		}
+		svc.written(conn, <-conn.channel, nil)
+	}
+
+	conn := svc.registerConnection("device", transportSSE, nil)
+	if ids := resume(conn, 0); len(ids) != 0 {
+		t.Errorf("replayed without ack = %v", ids)
+	}
+
+	// the events aren't tracked until the device acknowledges them
+	write(conn)
+	if len(conn.unacked) != 0 {
+		t.Errorf("unacked before the first ack = %v", conn.unacked)
+	}
+
+	if err := svc.Ack("device", 1); err != nil {
+		t.Fatal(err)
+	}
+	write(conn)
+	write(conn)
+	if !slices.Equal(conn.unacked, []int64{2, 3}) {
+		t.Errorf("unacked = %v, want [2 3]", conn.unacked)
+	}
+
+	if err := svc.Ack("device", 2); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(conn.unacked, []int64{3}) {
+		t.Errorf("unacked after ack = %v, want [3]", conn.unacked)
+	}
+	svc.removeConnection("device", conn.id)
+	if conn.unacked != nil {
+		t.Errorf("unacked after disconnect = %v", conn.unacked)
+	}
+
+	// the received but unacknowledged event is re-sent on reconnect
+	conn = svc.registerConnection("device", transportSSE, nil)
+	defer svc.removeConnection("device", conn.id)
+	if ids := resume(conn, 3); !slices.Equal(ids, []int64{3}) {
+		t.Errorf("replayed = %v, want [3]", ids)
+	}
+	if !slices.Equal(conn.unacked, []int64{3}) {
+		t.Errorf("unacked after reconnect = %v, want [3]", conn.unacked)
+	}
+}
+
+func TestService_ReplayGap(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+	svc := NewService(NewConfig(), nil, c, zap.NewNop(), testMetrics)
+
+	for range 3 {
+		if _, err := svc.history.Append(ctx, "device", eventWrapper{name: "event", data: []byte("{}")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the ID is assigned, but the event isn't stored yet
+	if err := c.Delete(ctx, eventKey("device", 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := svc.replay("device", 1, func(eventWrapper) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the replayed events are skipped live, the missing one is delivered
+	for id, want := range map[int64]bool{1: false, 2: false, 3: true} {
+		if got := replayed.contains(id); got != want {
+			t.Errorf("contains(%d) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestService_AckReplayDisabled(t *testing.T) {
+	svc := NewService(NewConfig(WithReplayBufferSize(0)), nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+	if err := svc.Ack("device", 1); !errors.Is(err, ErrReplayDisabled) {
+		t.Errorf("Ack() error = %v, want %v", err, ErrReplayDisabled)
+	}
+}
+
+func TestService_AckBroker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := &fanoutBroker{}
+	c := cache.NewMemory(0)
+	connected := NewService(NewConfig(), b, c, zap.NewNop(), testMetrics)
+	receiver := NewService(NewConfig(), b, c, zap.NewNop(), testMetrics)
+	go connected.Run(ctx)
+	go receiver.Run(ctx)
+
+	for b.subscribers() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	conn := connected.registerConnection("device", transportSSE, nil)
+	defer connected.removeConnection("device", conn.id)
+	conn.ack(0)
+	event := eventWrapper{name: string(smsgateway.PushMessageEnqueued), data: []byte("{}")}
+	id, err := connected.history.Append(ctx, "device", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.id = id
+	connected.written(conn, event, nil)
+
+	// the device acknowledges the event to another instance
+	if err := receiver.Ack("device", 1); err != nil {
+		t.Fatal(err)
+	}
+	conn.ackMu.Lock()
+	unacked := len(conn.unacked)
+	conn.ackMu.Unlock()
+	if unacked != 0 {
+		t.Errorf("unacked = %d, want 0", unacked)
+	}
+	if len(conn.channel) != 0 {
+		t.Error("ack is delivered as an event")
+	}
+}
+
+func TestService_HandlerCompression(t *testing.T) {
+	tests := []struct {
+		name           string
+		compression    bool
+		acceptEncoding string
+		want           string
+		reader         func(io.Reader) (io.Reader, error)
+	}{
+		{name: "gzip", compression: true, acceptEncoding: "deflate;q=0.5, gzip", want: "gzip",
+			reader: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }},
+		{name: "deflate", compression: true, acceptEncoding: "deflate", want: "deflate",
+			reader: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }},
+		{name: "not accepted", compression: true, acceptEncoding: ""},
+		{name: "unsupported", compression: true, acceptEncoding: "br"},
+		{name: "disabled", compression: false, acceptEncoding: "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := NewConfig(WithKeepAlivePeriod(0), WithCompression(tt.compression))
+			svc := NewService(config, nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+			app := fiber.New(fiber.Config{DisableStartupMessage: true})
+			app.Get("/events", func(c *fiber.Ctx) error {
+				return svc.Handler("device", nil, c)
+			})
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go func() { _ = app.Listener(ln) }()
+			defer func() { _ = app.Shutdown() }()
+			defer func() { _ = svc.Close(context.Background()) }()
+
+			req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.acceptEncoding != "" {
+				req.Header.Set(fiber.HeaderAcceptEncoding, tt.acceptEncoding)
+			}
+
+			// the headers are sent with the first event, each event is
+			// flushed, so it's readable while the stream is open
+			sent := make(chan error, 1)
+			go func() {
+				for len(svc.Connections()) == 0 {
+					time.Sleep(time.Millisecond)
+				}
+				for range 2 {
+					if err := svc.Send("device", Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{"id": "message"}}); err != nil {
+						sent <- err
+						return
+					}
+				}
+				sent <- nil
+			}()
+
+			client := http.Client{Transport: &http.Transport{DisableCompression: true}}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if err := <-sent; err != nil {
+				t.Fatal(err)
+			}
+			if got := resp.Header.Get(fiber.HeaderContentEncoding); got != tt.want {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.want)
+			}
+
+			var body io.Reader = resp.Body
+			if tt.reader != nil {
+				if body, err = tt.reader(resp.Body); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := bufio.NewReader(body)
+			for id := 1; id <= 2; id++ {
+				event := ""
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						t.Fatalf("event %d: %v", id, err)
+					}
+					if line == "\n" {
+						break
+					}
+					event += line
+				}
+
+				want := fmt.Sprintf("id: %d\nevent: MessageEnqueued\ndata: {\"id\":\"message\"}\n", id)
+				if event != want {
+					t.Errorf("event = %q, want %q", event, want)
+				}
+			}
+		})
+	}
+}
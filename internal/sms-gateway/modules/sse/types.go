@@ -7,4 +7,7 @@ import (
 type Event struct {
 	Type smsgateway.PushEventType `json:"event"`
 	Data map[string]string        `json:"data"`
+	// TraceID is the trace ID of the request which caused the event, empty
+	// if unknown. The delivery latency of the event is linked to the trace.
+	TraceID string `json:"-"`
 }
@@ -0,0 +1,145 @@
+package sse
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// WebSocketMessage is the event written to the WebSocket connection as a
+// JSON text message.
+type WebSocketMessage struct {
+	// The ID of the event for the Last-Event-ID header on reconnect, omitted
+	// without replay.
+	ID int64 `json:"id,omitempty" example:"7"`
+	// The event type.
+	Event string `json:"event" example:"MessageEnqueued"`
+	// The event data.
+	Data json.RawMessage `json:"data" swaggertype:"object"`
+}
+
+// WebSocketHandler upgrades the request to WebSocket and streams the events of
+// the device until the connection is closed. The events are distributed as
+// for the SSE connections, the missed ones are replayed after the ID in the
+// Last-Event-ID header. If events aren't empty, only the events of these types
+// are delivered.
+func (s *Service) WebSocketHandler(deviceID string, events []string, c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	// the ID of the last event received by the reconnecting device, the
+	// invalid one is ignored
+	lastEventID, _ := strconv.ParseInt(c.Get(fiber.HeaderLastEventID), 10, 64)
+
+	return websocket.New(func(ws *websocket.Conn) {
+		s.serveWebSocket(deviceID, events, ws, lastEventID)
+	})(c)
+}
+
+func (s *Service) serveWebSocket(deviceID string, events []string, ws *websocket.Conn, lastEventID int64) {
+	conn := s.registerConnection(deviceID, transportWebSocket, events)
+	defer s.removeConnection(deviceID, conn.id)
+
+	logger := s.logger.With(zap.String("device_id", deviceID), zap.String("connection_id", conn.id))
+
+	replayed, err := s.resume(deviceID, conn, lastEventID, func(event eventWrapper) error {
+		if !conn.accepts(event.name) {
+			return nil
+		}
+		err := s.writeWebSocket(ws, event)
+		s.written(conn, event, err)
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed to replay events", zap.Error(err))
+		return
+	}
+
+	// the messages from the device are discarded, reading handles the
+	// control frames and detects the closed connection
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					s.metrics.IncrementWebSocketErrors(ErrorTypeReadFailure)
+					logger.Warn("WebSocket connection failed", zap.Error(err))
+				}
+				return
+			}
+		}
+	}()
+
+	var pings <-chan time.Time
+	if s.config.keepAlivePeriod > 0 {
+		ticker := time.NewTicker(s.config.keepAlivePeriod)
+		defer ticker.Stop()
+		pings = ticker.C
+	}
+
+	for {
+		select {
+		case event := <-conn.channel:
+			if replayed.contains(event.id) {
+				continue
+			}
+			err := s.writeWebSocket(ws, event)
+			s.written(conn, event, err)
+			if err != nil {
+				logger.Warn("Failed to write event", zap.Error(err))
+				return
+			}
+		case <-pings:
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(s.config.writeTimeout)); err != nil {
+				conn.written(err)
+				s.metrics.IncrementWebSocketErrors(ErrorTypeWriteFailure)
+				logger.Warn("Failed to write ping", zap.Error(err))
+				return
+			}
+			s.metrics.IncrementWebSocketPingsSent()
+		case <-closed:
+			return
+		case <-conn.overflow:
+			logger.Warn("Disconnecting on buffer overflow")
+			_ = ws.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "buffer overflow"),
+				time.Now().Add(s.config.writeTimeout),
+			)
+			return
+		case <-conn.closeSignal:
+			_ = ws.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown"),
+				time.Now().Add(s.config.writeTimeout),
+			)
+			return
+		}
+	}
+}
+
+func (s *Service) writeWebSocket(ws *websocket.Conn, event eventWrapper) error {
+	if err := ws.SetWriteDeadline(time.Now().Add(s.config.writeTimeout)); err != nil {
+		s.metrics.IncrementWebSocketErrors(ErrorTypeWriteFailure)
+		return err
+	}
+
+	if err := ws.WriteJSON(WebSocketMessage{
+		ID:    event.id,
+		Event: event.name,
+		Data:  event.data,
+	}); err != nil {
+		s.metrics.IncrementWebSocketErrors(ErrorTypeWriteFailure)
+		return err
+	}
+
+	s.metrics.IncrementWebSocketMessagesSent(event.name)
+
+	return nil
+}
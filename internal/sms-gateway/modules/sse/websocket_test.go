@@ -0,0 +1,72 @@
+package sse
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	fasthttpws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func TestService_ServeWebSocket(t *testing.T) {
+	svc := NewService(NewConfig(), nil, cache.NewMemory(0), zap.NewNop(), testMetrics)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", func(c *fiber.Ctx) error {
+		return svc.WebSocketHandler("device", nil, c)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = app.Listener(ln) }()
+	defer func() { _ = app.Shutdown() }()
+
+	event := Event{Type: smsgateway.PushMessageEnqueued, Data: map[string]string{"id": "message"}}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/ws", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUpgradeRequired {
+		t.Errorf("status without upgrade = %d, want %d", resp.StatusCode, fiber.StatusUpgradeRequired)
+	}
+
+	// the events sent without connection are replayed after the last received one
+	for range 2 {
+		_ = svc.Send("device", event)
+	}
+
+	header := map[string][]string{fiber.HeaderLastEventID: {"1"}}
+	conn, _, err := fasthttpws.DefaultDialer.Dial("ws://"+ln.Addr().String()+"/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	expectMessage(t, conn, 2)
+
+	if err := svc.Send("device", event); err != nil {
+		t.Fatal(err)
+	}
+	expectMessage(t, conn, 3)
+}
+
+func expectMessage(t *testing.T, conn *fasthttpws.Conn, id int64) {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	msg := WebSocketMessage{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != id || msg.Event != string(smsgateway.PushMessageEnqueued) || string(msg.Data) != `{"id":"message"}` {
+		t.Errorf("message = %+v, want event %d", msg, id)
+	}
+}
@@ -0,0 +1,8 @@
+package stats
+
+import "time"
+
+type Config struct {
+	// Interval is the period of the daily aggregates refresh.
+	Interval time.Duration
+}
@@ -0,0 +1,7 @@
+package stats
+
+import "errors"
+
+var (
+	ErrInvalidRange = errors.New("invalid date range")
+)
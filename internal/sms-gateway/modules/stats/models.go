@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"gorm.io/gorm"
+)
+
+// DailyStats is the aggregate of the user's activity for a UTC day.
+type DailyStats struct {
+	UserID string    `gorm:"primaryKey;type:varchar(32)"`
+	Date   time.Time `gorm:"primaryKey;type:date"`
+
+	MessagesTotal     uint64 `gorm:"not null;default:0"`
+	MessagesPending   uint64 `gorm:"not null;default:0"`
+	MessagesProcessed uint64 `gorm:"not null;default:0"`
+	MessagesSent      uint64 `gorm:"not null;default:0"`
+	MessagesDelivered uint64 `gorm:"not null;default:0"`
+	MessagesFailed    uint64 `gorm:"not null;default:0"`
+
+	// ActiveDevices is the number of devices seen during the day.
+	ActiveDevices uint64 `gorm:"not null;default:0"`
+
+	// Webhooks delivered by the server, the ones delivered by the devices
+	// directly aren't reported to the server.
+	WebhooksDelivered uint64 `gorm:"not null;default:0"`
+	WebhooksFailed    uint64 `gorm:"not null;default:0"`
+
+	models.TimedModel
+}
+
+func (DailyStats) TableName() string {
+	return "stats_daily"
+}
+
+// WebhooksSuccessRate returns the share of successfully delivered webhooks,
+// nil if there were no deliveries.
+func (s DailyStats) WebhooksSuccessRate() *float64 {
+	total := s.WebhooksDelivered + s.WebhooksFailed
+	if total == 0 {
+		return nil
+	}
+
+	rate := float64(s.WebhooksDelivered) / float64(total)
+	return &rate
+}
+
+// DailyCountryStats is the aggregate of the user's recipients by the detected
+// country for a UTC day. Country is empty for recipients with unknown country.
+type DailyCountryStats struct {
+	UserID  string    `gorm:"primaryKey;type:varchar(32)"`
+	Date    time.Time `gorm:"primaryKey;type:date"`
+	Country string    `gorm:"primaryKey;type:char(2)"`
+
+	RecipientsTotal     uint64 `gorm:"not null;default:0"`
+	RecipientsDelivered uint64 `gorm:"not null;default:0"`
+	RecipientsFailed    uint64 `gorm:"not null;default:0"`
+
+	models.TimedModel
+}
+
+func (DailyCountryStats) TableName() string {
+	return "stats_daily_countries"
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&DailyStats{}, &DailyCountryStats{}); err != nil {
+		return fmt.Errorf("stats migration failed: %w", err)
+	}
+	return nil
+}
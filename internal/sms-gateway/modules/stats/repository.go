@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type repository struct {
+	db *gorm.DB
+}
+
+// AggregateMessages replaces the message counters of the day with the states
+// of the messages created during the day.
+func (r *repository) AggregateMessages(ctx context.Context, day time.Time) error {
+	rawSQL := "INSERT INTO `stats_daily` (`user_id`, `date`, `messages_total`, `messages_pending`, `messages_processed`, `messages_sent`, `messages_delivered`, `messages_failed`)\n" +
+		"SELECT `user_id`, ?, COUNT(*), SUM(`state` = 'Pending'), SUM(`state` = 'Processed'), SUM(`state` = 'Sent'), SUM(`state` = 'Delivered'), SUM(`state` = 'Failed')\n" +
+		"FROM `messages` WHERE `user_id` <> '' AND `created_at` >= ? AND `created_at` < ? GROUP BY `user_id`\n" +
+		"ON DUPLICATE KEY UPDATE `messages_total` = VALUES(`messages_total`), `messages_pending` = VALUES(`messages_pending`), " +
+		"`messages_processed` = VALUES(`messages_processed`), `messages_sent` = VALUES(`messages_sent`), " +
+		"`messages_delivered` = VALUES(`messages_delivered`), `messages_failed` = VALUES(`messages_failed`)"
+
+	return r.db.WithContext(ctx).Exec(rawSQL, day, day, day.AddDate(0, 0, 1)).Error
+}
+
+// AggregateCountries replaces the recipient counters of the day grouped by
+// the detected country with the states of the recipients of the messages
+// created during the day.
+func (r *repository) AggregateCountries(ctx context.Context, day time.Time) error {
+	rawSQL := "INSERT INTO `stats_daily_countries` (`user_id`, `date`, `country`, `recipients_total`, `recipients_delivered`, `recipients_failed`)\n" +
+		"SELECT `m`.`user_id`, ?, COALESCE(`r`.`country`, ''), COUNT(*), SUM(`r`.`state` = 'Delivered'), SUM(`r`.`state` = 'Failed')\n" +
+		"FROM `messages` `m` JOIN `message_recipients` `r` ON `r`.`message_id` = `m`.`id`\n" +
+		"WHERE `m`.`user_id` <> '' AND `m`.`created_at` >= ? AND `m`.`created_at` < ? GROUP BY `m`.`user_id`, COALESCE(`r`.`country`, '')\n" +
+		"ON DUPLICATE KEY UPDATE `recipients_total` = VALUES(`recipients_total`), " +
+		"`recipients_delivered` = VALUES(`recipients_delivered`), `recipients_failed` = VALUES(`recipients_failed`)"
+
+	return r.db.WithContext(ctx).Exec(rawSQL, day, day, day.AddDate(0, 0, 1)).Error
+}
+
+// AggregateDevices replaces the number of active devices of the day with the
+// number of devices seen since the start of the day.
+func (r *repository) AggregateDevices(ctx context.Context, day time.Time) error {
+	rawSQL := "INSERT INTO `stats_daily` (`user_id`, `date`, `active_devices`)\n" +
+		"SELECT `user_id`, ?, COUNT(*) FROM `devices` WHERE `last_seen` >= ? GROUP BY `user_id`\n" +
+		"ON DUPLICATE KEY UPDATE `active_devices` = VALUES(`active_devices`)"
+
+	return r.db.WithContext(ctx).Exec(rawSQL, day, day).Error
+}
+
+// IncrementWebhooks increments the webhook delivery counter of the day.
+func (r *repository) IncrementWebhooks(userID string, day time.Time, delivered bool) error {
+	stats := DailyStats{UserID: userID, Date: day}
+	column := "webhooks_failed"
+	if delivered {
+		column = "webhooks_delivered"
+		stats.WebhooksDelivered = 1
+	} else {
+		stats.WebhooksFailed = 1
+	}
+
+	return r.db.
+		Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]any{
+				column: gorm.Expr(column + " + 1"),
+			}),
+		}).
+		Create(&stats).
+		Error
+}
+
+// Select returns the user's aggregates for the days in the range, inclusive.
+func (r *repository) Select(userID string, from, to time.Time) ([]DailyStats, error) {
+	stats := []DailyStats{}
+	err := r.db.
+		Where("user_id = ? AND date >= ? AND date <= ?", userID, from, to).
+		Order("date").
+		Find(&stats).
+		Error
+
+	return stats, err
+}
+
+// SelectCountries returns the user's aggregates by country for the days in
+// the range, inclusive.
+func (r *repository) SelectCountries(userID string, from, to time.Time) ([]DailyCountryStats, error) {
+	stats := []DailyCountryStats{}
+	err := r.db.
+		Where("user_id = ? AND date >= ? AND date <= ?", userID, from, to).
+		Order("date, country").
+		Find(&stats).
+		Error
+
+	return stats, err
+}
+
+func newRepository(db *gorm.DB) *repository {
+	return &repository{
+		db: db,
+	}
+}
@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// maxRangeDays is the maximum number of days returned by a single request.
+const maxRangeDays = 366
+
+type ServiceParams struct {
+	fx.In
+
+	Config Config
+
+	Stats *repository
+
+	Logger *zap.Logger
+}
+
+type Service struct {
+	config Config
+
+	stats *repository
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) *Service {
+	return &Service{
+		config: params.Config,
+
+		stats: params.Stats,
+
+		logger: params.Logger,
+	}
+}
+
+// Run periodically refreshes the aggregates of today and yesterday, so the
+// states of messages changed after midnight are counted too.
+func (s *Service) Run(ctx context.Context) {
+	if s.config.Interval <= 0 {
+		s.logger.Info("Daily stats aggregation is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.aggregate(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) aggregate(ctx context.Context) {
+	today := truncateDay(time.Now())
+
+	for _, day := range []time.Time{today.AddDate(0, 0, -1), today} {
+		if err := s.stats.AggregateMessages(ctx, day); err != nil {
+			s.logger.Error("Can't aggregate messages", zap.Time("date", day), zap.Error(err))
+		}
+		if err := s.stats.AggregateCountries(ctx, day); err != nil {
+			s.logger.Error("Can't aggregate countries", zap.Time("date", day), zap.Error(err))
+		}
+	}
+
+	if err := s.stats.AggregateDevices(ctx, today); err != nil {
+		s.logger.Error("Can't aggregate devices", zap.Time("date", today), zap.Error(err))
+	}
+}
+
+// RecordWebhook counts the server-side webhook delivery attempt of the user.
+func (s *Service) RecordWebhook(userID string, delivered bool) {
+	if err := s.stats.IncrementWebhooks(userID, truncateDay(time.Now()), delivered); err != nil {
+		s.logger.Error("Can't record webhook delivery", zap.String("user_id", userID), zap.Error(err))
+	}
+}
+
+// SelectDaily returns the user's daily aggregates for the days from the range,
+// inclusive. Days without activity are omitted.
+func (s *Service) SelectDaily(userID string, from, to time.Time) ([]DailyStats, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.stats.Select(userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("can't select stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SelectDailyByCountry returns the user's daily recipient aggregates grouped
+// by the destination country for the days from the range, inclusive.
+func (s *Service) SelectDailyByCountry(userID string, from, to time.Time) ([]DailyCountryStats, error) {
+	from, to, err := dateRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.stats.SelectCountries(userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("can't select stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// dateRange truncates the range bounds to UTC days and validates the range.
+func dateRange(from, to time.Time) (time.Time, time.Time, error) {
+	from, to = truncateDay(from), truncateDay(to)
+
+	if to.Before(from) {
+		return from, to, fmt.Errorf("%w: `to` is before `from`", ErrInvalidRange)
+	}
+	if to.Sub(from) >= maxRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("%w: range exceeds %d days", ErrInvalidRange, maxRangeDays)
+	}
+
+	return from, to, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
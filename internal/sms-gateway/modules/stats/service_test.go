@@ -0,0 +1,86 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDateRange(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "same day",
+			from:     day("2025-10-16").Add(13 * time.Hour),
+			to:       day("2025-10-16").Add(15 * time.Hour),
+			wantFrom: day("2025-10-16"),
+			wantTo:   day("2025-10-16"),
+		},
+		{
+			name:     "non-UTC",
+			from:     time.Date(2025, 10, 16, 1, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60)),
+			to:       day("2025-10-20"),
+			wantFrom: day("2025-10-15"),
+			wantTo:   day("2025-10-20"),
+		},
+		{
+			name:     "max range",
+			from:     day("2025-01-01"),
+			to:       day("2026-01-01"),
+			wantFrom: day("2025-01-01"),
+			wantTo:   day("2026-01-01"),
+		},
+		{
+			name:    "too long",
+			from:    day("2024-01-01"),
+			to:      day("2025-01-02"),
+			wantErr: true,
+		},
+		{
+			name:    "reversed",
+			from:    day("2025-10-16"),
+			to:      day("2025-10-15"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := dateRange(tt.from, tt.to)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRange) {
+					t.Fatalf("dateRange() error = %v, want %v", err, ErrInvalidRange)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dateRange() error = %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("dateRange() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestDailyStats_WebhooksSuccessRate(t *testing.T) {
+	if rate := (DailyStats{}).WebhooksSuccessRate(); rate != nil {
+		t.Errorf("WebhooksSuccessRate() = %v, want nil", *rate)
+	}
+
+	rate := DailyStats{WebhooksDelivered: 3, WebhooksFailed: 1}.WebhooksSuccessRate()
+	if rate == nil || *rate != 0.75 {
+		t.Errorf("WebhooksSuccessRate() = %v, want 0.75", rate)
+	}
+}
@@ -0,0 +1,27 @@
+package storage
+
+import "time"
+
+type Config struct {
+	// Endpoint is the URL of the S3-compatible server, e.g.
+	// "https://s3.eu-central-1.amazonaws.com" or "http://minio:9000". Empty →
+	// object storage is disabled.
+	Endpoint string
+	// PublicEndpoint is the URL of the same server reachable by the clients
+	// of presigned URLs. Empty → Endpoint.
+	PublicEndpoint string
+	// Region of the bucket, used for request signing.
+	Region string
+	// Bucket keeps the objects of all the features, each under its own prefix.
+	Bucket string
+
+	AccessKey string
+	SecretKey string
+
+	// PathStyle puts the bucket in the path instead of the host name, as
+	// required by MinIO and most self-hosted servers.
+	PathStyle bool
+
+	// Timeout limits a single request.
+	Timeout time.Duration
+}
@@ -0,0 +1,9 @@
+package storage
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("object not found")
+	ErrInvalidConfig = errors.New("invalid storage config")
+	ErrInvalidTTL    = errors.New("invalid presigned URL lifetime")
+)
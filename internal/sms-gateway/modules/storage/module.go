@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var Module = fx.Module(
+	"storage",
+	fx.Decorate(func(log *zap.Logger) *zap.Logger {
+		return log.Named("storage")
+	}),
+	fx.Provide(NewService),
+)
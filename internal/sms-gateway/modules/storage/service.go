@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultRegion is used for signing if the region isn't set, most
+	// self-hosted servers accept any region.
+	defaultRegion = "us-east-1"
+
+	// MaxPresignTTL is the longest lifetime of presigned URLs accepted by S3.
+	MaxPresignTTL = 7 * 24 * time.Hour
+)
+
+// Object describes the stored object.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+type ServiceParams struct {
+	fx.In
+
+	Config    Config
+	EgressSvc *egress.Service
+
+	Logger *zap.Logger
+}
+
+// Service is the client of the S3-compatible object storage shared by the
+// features which keep files outside of the database and cache.
+type Service struct {
+	config Config
+
+	client    *s3.Client
+	presigner *s3.PresignClient
+
+	logger *zap.Logger
+}
+
+func NewService(params ServiceParams) (*Service, error) {
+	config := params.Config
+
+	s := &Service{
+		config: config,
+
+		logger: params.Logger.Named("service"),
+	}
+
+	if config.Endpoint == "" {
+		return s, nil
+	}
+
+	endpoint, err := parseEndpoint(config.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: endpoint must be an http(s) URL", ErrInvalidConfig)
+	}
+	publicEndpoint := endpoint
+	if config.PublicEndpoint != "" {
+		if publicEndpoint, err = parseEndpoint(config.PublicEndpoint); err != nil {
+			return nil, fmt.Errorf("%w: public endpoint must be an http(s) URL", ErrInvalidConfig)
+		}
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("%w: bucket is required", ErrInvalidConfig)
+	}
+
+	region := config.Region
+	if region == "" {
+		region = defaultRegion
+	}
+
+	options := s3.Options{
+		Region:       region,
+		BaseEndpoint: aws.String(endpoint.String()),
+		UsePathStyle: config.PathStyle,
+		HTTPClient:   params.EgressSvc.Client(egress.DestinationStorage, config.Timeout),
+		Credentials:  aws.NewCredentialsCache(staticCredentials(config.AccessKey, config.SecretKey)),
+		// most S3-compatible servers don't support the newer checksums
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	}
+
+	s.client = s3.New(options)
+	s.presigner = s3.NewPresignClient(s3.New(options, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(publicEndpoint.String())
+	}))
+
+	return s, nil
+}
+
+// Enabled returns true if the storage endpoint is configured.
+func (s *Service) Enabled() bool {
+	return s.client != nil
+}
+
+// Put stores the object, replacing the existing one.
+func (s *Service) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if !s.Enabled() {
+		return fmt.Errorf("%w: storage is disabled", ErrInvalidConfig)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(s.config.Bucket),
+		Key:           aws.String(key),
+		Body:          bytes.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		return fmt.Errorf("can't put object: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the content of the object.
+//
+// If the object doesn't exist, it returns ErrNotFound.
+func (s *Service) Get(ctx context.Context, key string) ([]byte, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("%w: storage is disabled", ErrInvalidConfig)
+	}
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get object: %w", err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("can't read object: %w", err)
+	}
+
+	return data, nil
+}
+
+// Delete removes the object. Missing objects are ignored.
+func (s *Service) Delete(ctx context.Context, key string) error {
+	if !s.Enabled() {
+		return fmt.Errorf("%w: storage is disabled", ErrInvalidConfig)
+	}
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("can't delete object: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the objects with keys starting with the prefix, sorted by key.
+func (s *Service) List(ctx context.Context, prefix string) ([]Object, error) {
+	if !s.Enabled() {
+		return nil, fmt.Errorf("%w: storage is disabled", ErrInvalidConfig)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.config.Bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	objects := []Object{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("can't list objects: %w", err)
+		}
+
+		for _, c := range page.Contents {
+			objects = append(objects, Object{
+				Key:          aws.ToString(c.Key),
+				Size:         aws.ToInt64(c.Size),
+				LastModified: aws.ToTime(c.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+// PresignGet returns the URL to download the object without credentials
+// until ttl passes. The URL grants only GET of the single object. If filename
+// is set, the object is downloaded as an attachment with this name.
+//
+// ttl must be positive and not longer than MaxPresignTTL.
+func (s *Service) PresignGet(key string, ttl time.Duration, filename string) (string, error) {
+	if !s.Enabled() {
+		return "", fmt.Errorf("%w: storage is disabled", ErrInvalidConfig)
+	}
+	if ttl < time.Second || ttl > MaxPresignTTL {
+		return "", fmt.Errorf("%w: %s", ErrInvalidTTL, ttl)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	}
+	if filename != "" {
+		input.ResponseContentDisposition = aws.String(mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+
+	req, err := s.presigner.PresignGetObject(context.Background(), input, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("can't presign object: %w", err)
+	}
+
+	return req.URL, nil
+}
+
+func staticCredentials(accessKey, secretKey string) aws.CredentialsProviderFunc {
+	return func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: accessKey, SecretAccessKey: secretKey}, nil
+	}
+}
+
+// isNotFound returns true if the error reports the missing object.
+func isNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return true
+	}
+
+	var respErr *awshttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
+
+func parseEndpoint(value string) (*url.URL, error) {
+	endpoint, err := url.Parse(strings.TrimRight(value, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, errors.New("invalid scheme or host")
+	}
+
+	return endpoint, nil
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
+	"go.uber.org/zap"
+)
+
+// fakeServer is a minimal path-style S3 server keeping the objects in memory.
+type fakeServer struct {
+	bucket string
+
+	mux     sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	switch {
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = data
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, "<Error><Code>NoSuchKey</Code></Error>")
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeServer) list(w http.ResponseWriter, prefix string) {
+	keys := []string{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	_, _ = io.WriteString(w, "<ListBucketResult>")
+	for _, key := range keys {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>%d</Size><LastModified>2025-01-01T00:00:00Z</LastModified></Contents>", key, len(f.objects[key]))
+	}
+	_, _ = io.WriteString(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+}
+
+func newTestService(t *testing.T, endpoint string) *Service {
+	t.Helper()
+
+	return newTestServiceWithConfig(t, Config{
+		Endpoint:  endpoint,
+		Bucket:    "bucket",
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: true,
+		Timeout:   time.Second,
+	})
+}
+
+func newTestServiceWithConfig(t *testing.T, config Config) *Service {
+	t.Helper()
+
+	egressSvc, err := egress.NewService(egress.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewService(ServiceParams{
+		Config:    config,
+		EgressSvc: egressSvc,
+		Logger:    zap.NewNop(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestService_Objects(t *testing.T) {
+	server := httptest.NewServer(&fakeServer{bucket: "bucket", objects: map[string][]byte{}})
+	defer server.Close()
+
+	ctx := context.Background()
+	s := newTestService(t, server.URL)
+
+	if err := s.Put(ctx, "exports/1.json", []byte("first"), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "exports/2.json", []byte("second"), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "logs/1.json", []byte("other"), "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := s.Get(ctx, "exports/2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Errorf("Get() = %q, want %q", data, "second")
+	}
+
+	objects, err := s.List(ctx, "exports/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Key != "exports/1.json" || objects[1].Size != 6 {
+		t.Errorf("List() = %+v", objects)
+	}
+
+	if err := s.Delete(ctx, "exports/1.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "exports/1.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Delete(ctx, "exports/1.json"); err != nil {
+		t.Errorf("Delete() of missing object error = %v", err)
+	}
+}
+
+func TestService_PresignGet(t *testing.T) {
+	s := newTestServiceWithConfig(t, Config{
+		Endpoint:       "http://minio:9000",
+		PublicEndpoint: "https://files.example.com/s3",
+		Bucket:         "bucket",
+		AccessKey:      "access",
+		SecretKey:      "secret",
+		PathStyle:      true,
+	})
+
+	link, err := s.PresignGet("exports/1.json", time.Hour, "export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "files.example.com" || u.Path != "/s3/bucket/exports/1.json" {
+		t.Errorf("PresignGet() = %s, want the object at the public endpoint", link)
+	}
+
+	query := u.Query()
+	if query.Get("X-Amz-Expires") != "3600" || query.Get("X-Amz-Signature") == "" {
+		t.Errorf("PresignGet() query = %v", query)
+	}
+	if got := query.Get("response-content-disposition"); got != "attachment; filename=export.json" {
+		t.Errorf("response-content-disposition = %q", got)
+	}
+
+	for _, ttl := range []time.Duration{0, MaxPresignTTL + time.Second} {
+		if _, err := s.PresignGet("exports/1.json", ttl, ""); !errors.Is(err, ErrInvalidTTL) {
+			t.Errorf("PresignGet(%s) error = %v, want %v", ttl, err, ErrInvalidTTL)
+		}
+	}
+}
+
+func TestService_Disabled(t *testing.T) {
+	s := newTestService(t, "")
+
+	if s.Enabled() {
+		t.Fatal("Enabled() = true for empty endpoint")
+	}
+	if err := s.Put(context.Background(), "key", nil, ""); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Put() error = %v, want %v", err, ErrInvalidConfig)
+	}
+}
@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+)
+
+// batch is the pending combination of the events of a single webhook.
+type batch struct {
+	Webhook   WebhookDTO
+	UserID    string
+	DeviceID  *string
+	Event     smsgateway.WebhookEvent
+	MessageID string
+	Payloads  []map[string]any
+
+	timer *time.Timer
+}
+
+// Payload returns the combined payload of the batched events.
+func (b *batch) Payload() map[string]any {
+	items := make([]any, len(b.Payloads))
+	for i, p := range b.Payloads {
+		items[i] = p
+	}
+
+	payload := map[string]any{
+		"count":  len(items),
+		"events": items,
+	}
+	if b.MessageID != "" {
+		payload["messageId"] = b.MessageID
+	}
+
+	return payload
+}
+
+// batcher aggregates the events per webhook and passes the batches to the
+// flush function when the window ends or the size limit is reached. Only the
+// events emitted by the server are batched, the devices deliver their own
+// webhooks unbatched.
+type batcher struct {
+	mu      sync.Mutex
+	batches map[string]*batch
+
+	flush func(*batch)
+}
+
+func newBatcher(flush func(*batch)) *batcher {
+	return &batcher{
+		batches: make(map[string]*batch),
+		flush:   flush,
+	}
+}
+
+// Add appends the event payload to the batch of the webhook.
+func (b *batcher) Add(userID string, deviceID *string, webhook WebhookDTO, event smsgateway.WebhookEvent, payload map[string]any) {
+	config := webhook.Batch
+
+	messageID := ""
+	if config.GroupBy != BatchGroupNone {
+		messageID, _ = payload["messageId"].(string)
+	}
+
+	key := userID + "/" + webhook.ID + "/" + messageID
+	if deviceID != nil {
+		key += "/" + *deviceID
+	}
+
+	b.mu.Lock()
+	item, ok := b.batches[key]
+	if !ok {
+		item = &batch{
+			Webhook:   webhook,
+			UserID:    userID,
+			DeviceID:  deviceID,
+			Event:     event,
+			MessageID: messageID,
+		}
+		item.timer = time.AfterFunc(time.Duration(config.Window)*time.Second, func() {
+			b.flushKey(key, item)
+		})
+		b.batches[key] = item
+	}
+	item.Payloads = append(item.Payloads, payload)
+
+	full := config.Size > 0 && uint(len(item.Payloads)) >= config.Size
+	if full {
+		item.timer.Stop()
+		delete(b.batches, key)
+	}
+	b.mu.Unlock()
+
+	if full {
+		b.flush(item)
+	}
+}
+
+// Len returns the number of pending batches.
+func (b *batcher) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.batches)
+}
+
+// Close sends all pending batches.
+func (b *batcher) Close() {
+	b.mu.Lock()
+	items := make([]*batch, 0, len(b.batches))
+	for key, item := range b.batches {
+		item.timer.Stop()
+		items = append(items, item)
+		delete(b.batches, key)
+	}
+	b.mu.Unlock()
+
+	for _, item := range items {
+		b.flush(item)
+	}
+}
+
+func (b *batcher) flushKey(key string, item *batch) {
+	b.mu.Lock()
+	if b.batches[key] != item {
+		// already sent by the size limit or on close
+		b.mu.Unlock()
+		return
+	}
+	delete(b.batches, key)
+	b.mu.Unlock()
+
+	b.flush(item)
+}
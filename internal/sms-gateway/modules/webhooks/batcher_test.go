@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+)
+
+func TestBatcher(t *testing.T) {
+	var mu sync.Mutex
+	flushed := []*batch{}
+	b := newBatcher(func(item *batch) {
+		mu.Lock()
+		defer mu.Unlock()
+		flushed = append(flushed, item)
+	})
+
+	byMessage := WebhookDTO{
+		Webhook: smsgateway.Webhook{ID: "by-message"},
+		Batch:   &Batch{Window: 60, Size: 2, GroupBy: BatchGroupMessage},
+	}
+	all := WebhookDTO{
+		Webhook: smsgateway.Webhook{ID: "all"},
+		Batch:   &Batch{Window: 60, GroupBy: BatchGroupNone},
+	}
+
+	for _, messageID := range []string{"a", "b", "a"} {
+		payload := map[string]any{"messageId": messageID}
+		b.Add("user", nil, byMessage, smsgateway.WebhookEventSmsSent, payload)
+		b.Add("user", nil, all, smsgateway.WebhookEventSmsSent, payload)
+	}
+
+	mu.Lock()
+	if len(flushed) != 1 || flushed[0].MessageID != "a" || len(flushed[0].Payloads) != 2 {
+		t.Fatalf("size limit flush = %+v, want single batch of message a", flushed)
+	}
+	mu.Unlock()
+
+	if b.Len() != 2 {
+		t.Errorf("Len() = %d, want 2 pending batches", b.Len())
+	}
+
+	b.Close()
+
+	if b.Len() != 0 {
+		t.Errorf("Len() after Close() = %d, want 0", b.Len())
+	}
+
+	if len(flushed) != 3 {
+		t.Fatalf("flushed %d batches, want 3", len(flushed))
+	}
+	for _, item := range flushed[1:] {
+		switch item.Webhook.ID {
+		case "by-message":
+			if item.MessageID != "b" || len(item.Payloads) != 1 {
+				t.Errorf("message batch = %+v, want single event of message b", item)
+			}
+		case "all":
+			payload := item.Payload()
+			if payload["count"] != 3 {
+				t.Errorf("count = %v, want 3", payload["count"])
+			}
+			if _, ok := payload["messageId"]; ok {
+				t.Error("messageId is set for ungrouped batch")
+			}
+		}
+	}
+}
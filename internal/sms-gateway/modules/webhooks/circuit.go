@@ -0,0 +1,232 @@
+package webhooks
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	circuitFailureThreshold = 5
+	circuitOpenTimeout      = time.Minute
+	circuitMaxOpenTimeout   = time.Hour
+	// circuitMaxHeld is the number of the events held per webhook while its
+	// breaker is open, the oldest ones are dropped above it
+	circuitMaxHeld = 100
+)
+
+// CircuitState is the state of the circuit breaker of a webhook.
+type CircuitState string
+
+const (
+	// CircuitClosed delivers the events as usual.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen skips the events until the retry time.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen delivers a single event to check the endpoint, the
+	// circuit is closed on success and opened again on failure.
+	CircuitHalfOpen CircuitState = "half-open"
+	// CircuitUnknown is reported for the webhooks delivered by the devices,
+	// the server doesn't track their deliveries.
+	CircuitUnknown CircuitState = "unknown"
+)
+
+// Circuit describes the circuit breaker of a webhook. The breaker opens after
+// consecutive failed deliveries of the server-side events, so the events are
+// held instead of waiting for the retries of an unavailable endpoint. The held
+// events are delivered once the breaker is closed.
+type Circuit struct {
+	// The breaker state, `unknown` for the webhooks delivered by the devices.
+	State CircuitState `json:"state" example:"open" enums:"closed,open,half-open,unknown"`
+	// The number of consecutive failed deliveries.
+	Failures uint `json:"failures" example:"5"`
+	// The error of the last failed delivery.
+	LastError string `json:"lastError,omitempty" example:"unexpected status code 503"`
+	// The time of the last failed delivery.
+	LastFailureAt *time.Time `json:"lastFailureAt,omitempty" example:"2025-10-16T12:00:00Z"`
+	// The time the next event is delivered to check the endpoint, only for the open breaker.
+	NextRetryAt *time.Time `json:"nextRetryAt,omitempty" example:"2025-10-16T12:01:00Z"`
+	// The number of the events held until the breaker is closed, the oldest ones are dropped above 100.
+	Held uint `json:"held,omitempty" example:"12"`
+}
+
+// heldDelivery is the event held while the breaker is open.
+type heldDelivery struct {
+	url        string
+	data       []byte
+	signingKey string
+}
+
+type circuit struct {
+	failures      uint
+	lastError     string
+	lastFailureAt time.Time
+
+	// opens is the number of consecutive openings, the open timeout doubles
+	// with each one
+	opens       uint
+	nextRetryAt time.Time
+	probing     bool
+
+	held []heldDelivery
+}
+
+func (c *circuit) state(now time.Time) CircuitState {
+	switch {
+	case c.opens == 0:
+		return CircuitClosed
+	case c.probing || !now.Before(c.nextRetryAt):
+		return CircuitHalfOpen
+	default:
+		return CircuitOpen
+	}
+}
+
+// circuits tracks the circuit breakers of the webhooks. The state is kept in
+// memory, so each server instance opens the breakers on its own.
+type circuits struct {
+	mu    sync.Mutex
+	items map[string]*circuit
+
+	onChange func(CircuitState)
+}
+
+func newCircuits(onChange func(CircuitState)) *circuits {
+	return &circuits{
+		items:    make(map[string]*circuit),
+		onChange: onChange,
+	}
+}
+
+// Allow reports whether the event may be delivered. The first call after the
+// open timeout switches the breaker to half-open and lets a single delivery
+// through.
+func (c *circuits) Allow(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok || item.opens == 0 {
+		return true
+	}
+	if item.probing || now.Before(item.nextRetryAt) {
+		return false
+	}
+
+	item.probing = true
+	c.onChange(CircuitHalfOpen)
+
+	return true
+}
+
+// Hold keeps the event rejected by Allow until the breaker is closed. It
+// returns true if the oldest held event is dropped to make room.
+func (c *circuits) Hold(key string, delivery heldDelivery) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		item = &circuit{}
+		c.items[key] = item
+	}
+
+	dropped := len(item.held) >= circuitMaxHeld
+	if dropped {
+		item.held = item.held[1:]
+	}
+	item.held = append(item.held, delivery)
+
+	return dropped
+}
+
+// Success closes the breaker and returns the held events to deliver.
+func (c *circuits) Success(key string) []heldDelivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+
+	delete(c.items, key)
+	if item.opens > 0 {
+		c.onChange(CircuitClosed)
+	}
+
+	return item.held
+}
+
+// Failure counts the failed delivery and opens the breaker when the threshold
+// is reached or the half-open check fails.
+func (c *circuits) Failure(key string, err error, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		item = &circuit{}
+		c.items[key] = item
+	}
+
+	item.failures++
+	item.lastError = err.Error()
+	item.lastFailureAt = now
+
+	// the deliveries started before the breaker opened don't extend it
+	if !item.probing && (item.opens > 0 || item.failures < circuitFailureThreshold) {
+		return
+	}
+
+	timeout := circuitOpenTimeout << min(item.opens, 16)
+	item.opens++
+	item.nextRetryAt = now.Add(min(timeout, circuitMaxOpenTimeout))
+	item.probing = false
+	c.onChange(CircuitOpen)
+}
+
+// Get returns the breaker state.
+func (c *circuits) Get(key string, now time.Time) Circuit {
+	circuit, _ := c.Lookup(key, now)
+	return circuit
+}
+
+// Lookup is like Get, but also reports whether the breaker has tracked
+// failures since it was closed.
+func (c *circuits) Lookup(key string, now time.Time) (Circuit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.items[key]
+	if !ok {
+		return Circuit{State: CircuitClosed}, false
+	}
+
+	lastFailureAt := item.lastFailureAt
+	result := Circuit{
+		State:         item.state(now),
+		Failures:      item.failures,
+		LastError:     item.lastError,
+		LastFailureAt: &lastFailureAt,
+		Held:          uint(len(item.held)),
+	}
+	if result.State == CircuitOpen {
+		nextRetryAt := item.nextRetryAt
+		result.NextRetryAt = &nextRetryAt
+	}
+
+	return result, true
+}
+
+// Reset closes the breaker and drops the held events, e.g. when the webhook
+// is replaced or deleted.
+func (c *circuits) Reset(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+}
+
+func circuitKey(userID, webhookID string) string {
+	return userID + "/" + webhookID
+}
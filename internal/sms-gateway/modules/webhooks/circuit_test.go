@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCircuits(t *testing.T) {
+	transitions := []CircuitState{}
+	c := newCircuits(func(state CircuitState) {
+		transitions = append(transitions, state)
+	})
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	errDelivery := errors.New("unexpected status code 503")
+
+	for range circuitFailureThreshold - 1 {
+		c.Failure("key", errDelivery, now)
+	}
+	if !c.Allow("key", now) {
+		t.Fatal("Allow() = false below the failure threshold")
+	}
+	if got := c.Get("key", now); got.State != CircuitClosed || got.Failures != circuitFailureThreshold-1 {
+		t.Errorf("Get() = %+v, want closed with %d failures", got, circuitFailureThreshold-1)
+	}
+
+	c.Failure("key", errDelivery, now)
+	if c.Allow("key", now.Add(circuitOpenTimeout-time.Second)) {
+		t.Error("Allow() = true for the open circuit")
+	}
+	got := c.Get("key", now)
+	if got.State != CircuitOpen || got.LastError != errDelivery.Error() ||
+		got.NextRetryAt == nil || !got.NextRetryAt.Equal(now.Add(circuitOpenTimeout)) {
+		t.Errorf("Get() = %+v, want open until %s", got, now.Add(circuitOpenTimeout))
+	}
+
+	// a single check is let through after the timeout
+	now = now.Add(circuitOpenTimeout)
+	if !c.Allow("key", now) {
+		t.Fatal("Allow() = false after the open timeout")
+	}
+	if c.Allow("key", now) {
+		t.Error("Allow() = true while the check is in progress")
+	}
+	if got := c.Get("key", now); got.State != CircuitHalfOpen {
+		t.Errorf("Get().State = %s, want %s", got.State, CircuitHalfOpen)
+	}
+
+	// the failed check doubles the timeout
+	c.Failure("key", errDelivery, now)
+	if got := c.Get("key", now); got.NextRetryAt == nil || !got.NextRetryAt.Equal(now.Add(2*circuitOpenTimeout)) {
+		t.Errorf("Get() = %+v, want open until %s", got, now.Add(2*circuitOpenTimeout))
+	}
+
+	now = now.Add(2 * circuitOpenTimeout)
+	if !c.Allow("key", now) {
+		t.Fatal("Allow() = false after the open timeout")
+	}
+	c.Success("key")
+	if got := c.Get("key", now); got.State != CircuitClosed || got.Failures != 0 {
+		t.Errorf("Get() = %+v, want closed without failures", got)
+	}
+
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if !slices.Equal(transitions, want) {
+		t.Errorf("transitions = %v, want %v", transitions, want)
+	}
+}
+
+func TestCircuits_MaxOpenTimeout(t *testing.T) {
+	c := newCircuits(func(CircuitState) {})
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	for range circuitFailureThreshold {
+		c.Failure("key", errors.New("timeout"), now)
+	}
+	for range 10 {
+		now = *c.Get("key", now).NextRetryAt
+		c.Allow("key", now)
+		c.Failure("key", errors.New("timeout"), now)
+	}
+
+	if got := c.Get("key", now); !got.NextRetryAt.Equal(now.Add(circuitMaxOpenTimeout)) {
+		t.Errorf("NextRetryAt = %s, want %s", got.NextRetryAt, now.Add(circuitMaxOpenTimeout))
+	}
+}
+
+func TestCircuits_Hold(t *testing.T) {
+	c := newCircuits(func(CircuitState) {})
+
+	now := time.Date(2025, 10, 16, 12, 0, 0, 0, time.UTC)
+	for range circuitFailureThreshold {
+		c.Failure("key", errors.New("timeout"), now)
+	}
+
+	for i := range circuitMaxHeld {
+		if c.Hold("key", heldDelivery{url: fmt.Sprint(i)}) {
+			t.Fatalf("Hold() dropped an event below the limit")
+		}
+	}
+	if !c.Hold("key", heldDelivery{url: "last"}) {
+		t.Error("Hold() = false above the limit")
+	}
+	if got := c.Get("key", now); got.Held != circuitMaxHeld {
+		t.Errorf("Get().Held = %d, want %d", got.Held, circuitMaxHeld)
+	}
+
+	held := c.Success("key")
+	if len(held) != circuitMaxHeld || held[0].url != "1" || held[len(held)-1].url != "last" {
+		t.Errorf("Success() returned %d events from %v to %v, want the newest %d", len(held), held[0].url, held[len(held)-1].url, circuitMaxHeld)
+	}
+	if _, ok := c.Lookup("key", now); ok {
+		t.Error("Lookup() = true after Success()")
+	}
+
+	for range circuitFailureThreshold {
+		c.Failure("key", errors.New("timeout"), now)
+	}
+	c.Hold("key", heldDelivery{url: "dropped"})
+	c.Reset("key")
+	if held := c.Success("key"); len(held) != 0 {
+		t.Errorf("Success() after Reset() = %v, want no events", held)
+	}
+}
@@ -4,11 +4,26 @@ import (
 	"github.com/android-sms-gateway/client-go/smsgateway"
 )
 
-func webhookToDTO(model *Webhook) smsgateway.Webhook {
-	return smsgateway.Webhook{
-		ID:       model.ExtID,
-		DeviceID: model.DeviceID,
-		URL:      model.URL,
-		Event:    model.Event,
+func webhookToDTO(model *Webhook) WebhookDTO {
+	var batch *Batch
+	if model.BatchWindow > 0 {
+		batch = &Batch{
+			Window:  model.BatchWindow,
+			Size:    model.BatchSize,
+			GroupBy: model.BatchGroupBy,
+		}
+	}
+
+	return WebhookDTO{
+		Webhook: smsgateway.Webhook{
+			ID:       model.ExtID,
+			DeviceID: model.DeviceID,
+			URL:      model.URL,
+			Event:    model.Event,
+		},
+		Format:     model.Format,
+		Batch:      batch,
+		Filter:     model.Filter,
+		VerifiedAt: model.VerifiedAt,
 	}
 }
@@ -0,0 +1,65 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestDispatcher_Deliver(t *testing.T) {
+	body := []byte(`{"event":"device:registered"}`)
+	calls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if got, want := r.Header.Get(headerAttempt), strconv.Itoa(calls); got != want {
+			t.Errorf("attempt = %s, want %s", got, want)
+		}
+		if got, want := r.Header.Get(headerRetriesLeft), strconv.Itoa(deliveryAttempts-calls); got != want {
+			t.Errorf("retries left = %s, want %s", got, want)
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		got, _ := io.ReadAll(r.Body)
+		if string(got) != string(body) {
+			t.Errorf("body = %s, want %s", got, body)
+		}
+
+		timestamp := r.Header.Get(headerTimestamp)
+		if timestamp == "" {
+			t.Error("timestamp header is missing")
+		}
+		if want := sign("key", body, timestamp); r.Header.Get(headerSignature) != want {
+			t.Errorf("signature = %s, want %s", r.Header.Get(headerSignature), want)
+		}
+	}))
+	defer server.Close()
+
+	d := &dispatcher{client: server.Client()}
+	if err := d.Deliver(context.Background(), server.URL, body, "key"); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+func TestDispatcher_Unsigned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(headerSignature) != "" || r.Header.Get(headerTimestamp) != "" {
+			t.Error("request without signing key must not be signed")
+		}
+	}))
+	defer server.Close()
+
+	d := &dispatcher{client: server.Client()}
+	if err := d.Deliver(context.Background(), server.URL, []byte(`{}`), ""); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package webhooks
+
+import (
+	"slices"
+	"time"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+)
+
+// EventDeliveryDegraded is delivered by the server when the failure rate of
+// the user's or device's messages spikes.
+const EventDeliveryDegraded smsgateway.WebhookEvent = "alert:delivery_degraded"
+
+// EventExportCompleted is delivered by the server when the device completes
+// the requested messages export.
+const EventExportCompleted smsgateway.WebhookEvent = "export:completed"
+
+// ServerEvents are the events delivered by the server rather than by the
+// devices. Webhooks for these events are not passed to the devices.
+var ServerEvents = []smsgateway.WebhookEvent{
+	smsgateway.WebhookEvent(devices.LifecycleRegistered),
+	smsgateway.WebhookEvent(devices.LifecycleRenamed),
+	smsgateway.WebhookEvent(devices.LifecycleSuspended),
+	smsgateway.WebhookEvent(devices.LifecycleResumed),
+	smsgateway.WebhookEvent(devices.LifecycleDeleted),
+	smsgateway.WebhookEvent(devices.LifecycleTokenRotated),
+	smsgateway.WebhookEvent(devices.LifecycleAppVersionChanged),
+	EventDeliveryDegraded,
+	EventExportCompleted,
+}
+
+// IsServerEvent returns true if the event is delivered by the server.
+func IsServerEvent(event smsgateway.WebhookEvent) bool {
+	return slices.Contains(ServerEvents, event)
+}
+
+// Format defines the payload layout sent to the webhook URL.
+type Format string
+
+const (
+	// FormatDefault is the nested payload with event, deviceId and payload fields.
+	FormatDefault Format = "default"
+	// FormatFlat is a single level key/value object compatible with no-code
+	// platforms like Zapier or IFTTT, see Flatten. The server flattens only
+	// the events it delivers, the webhooks delivered by the devices, such as
+	// `sms:received` without filter, get the format with the registration and
+	// the app versions which don't support it send the default payload.
+	FormatFlat Format = "flat"
+)
+
+func (f Format) IsValid() bool {
+	return f == FormatDefault || f == FormatFlat
+}
+
+// BatchedEvents are the message state change events which can be batched.
+var BatchedEvents = []smsgateway.WebhookEvent{
+	smsgateway.WebhookEventSmsSent,
+	smsgateway.WebhookEventSmsDelivered,
+	smsgateway.WebhookEventSmsFailed,
+}
+
+// IsBatchedEvent returns true if the events of this type can be batched.
+func IsBatchedEvent(event smsgateway.WebhookEvent) bool {
+	return slices.Contains(BatchedEvents, event)
+}
+
+// BatchGroup defines which events are combined into a single batch.
+type BatchGroup string
+
+const (
+	// BatchGroupMessage combines the events of the same message.
+	BatchGroupMessage BatchGroup = "message"
+	// BatchGroupNone combines all events of the webhook.
+	BatchGroupNone BatchGroup = "none"
+)
+
+func (g BatchGroup) IsValid() bool {
+	return g == BatchGroupMessage || g == BatchGroupNone
+}
+
+// Batch configures the aggregation of the state change events into a single
+// request with the combined payload.
+type Batch struct {
+	// The aggregation window in seconds, the batch is sent when it ends.
+	Window uint `json:"window" validate:"required,min=1,max=3600" example:"10"`
+	// The maximum number of events in the batch, the batch is sent earlier when reached.
+	Size uint `json:"size,omitempty" validate:"max=1000" example:"100"`
+	// The grouping of the events, `message` if not set.
+	GroupBy BatchGroup `json:"groupBy,omitempty" example:"message" enums:"message,none"`
+}
+
+// IncomingMessage is the SMS received by the device.
+type IncomingMessage struct {
+	// Message ID on the device
+	ID string `json:"messageId" validate:"required,max=64" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// Sender phone number or name
+	PhoneNumber string `json:"phoneNumber" validate:"required,max=128" example:"+79990001234"`
+	// Message text
+	Message string `json:"message" example:"Your code is 123456"`
+	// SIM card number
+	SimNumber *uint8 `json:"simNumber,omitempty" example:"1"`
+	// Time when the message was received
+	ReceivedAt time.Time `json:"receivedAt" validate:"required" example:"2025-10-16T12:00:00Z"`
+}
+
+// WebhookDTO is the webhook registration with server-side options.
+type WebhookDTO struct {
+	smsgateway.Webhook
+
+	// The payload format, `default` if not set. The server flattens the events it delivers, the webhooks delivered by the devices, such as `sms:received` without filter, are flattened by the app versions which support it only.
+	Format Format `json:"format,omitempty" example:"flat" enums:"default,flat"`
+
+	// Combine the state change events into batches, only for `sms:sent`, `sms:delivered` and `sms:failed`. Only the events delivered by the server, such as the ones of the sandbox devices and of the messages failed by the server, are batched; the devices deliver their events one by one.
+	Batch *Batch `json:"batch,omitempty"`
+
+	// Deliver only the matching incoming messages, only for `sms:received`. The filtered webhooks are delivered by the server from the messages reported by the devices, the apps which don't report the incoming messages don't trigger them.
+	Filter *Filter `json:"filter,omitempty"`
+
+	// Perform the challenge handshake before activating the webhook.
+	Verify bool `json:"verify,omitempty" example:"true"`
+	// The time of the successful challenge handshake.
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty" example:"2025-10-16T12:00:00Z"`
+}
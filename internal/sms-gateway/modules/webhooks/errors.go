@@ -1,6 +1,17 @@
 package webhooks
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound indicates the webhook doesn't exist.
+	ErrNotFound = errors.New("webhook not found")
+	// ErrReceiveBusy indicates the incoming message can't be delivered now
+	// and should be reported again later.
+	ErrReceiveBusy = errors.New("too many incoming messages, retry later")
+)
 
 type ValidationError struct {
 	Field string
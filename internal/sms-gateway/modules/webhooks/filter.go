@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"slices"
+	"strings"
+)
+
+// Filter selects the incoming messages delivered to the `sms:received`
+// webhook. The criteria are combined with AND and the values of a criterion
+// with OR, the criterion without values matches all messages.
+type Filter struct {
+	// The devices which received the message.
+	DeviceIDs []string `json:"deviceIds,omitempty" validate:"max=100,dive,required" example:"PyDmBQZZXYmyxMwED8Fzy"`
+	// The prefixes of the sender phone number or name, case-insensitive.
+	SenderPrefixes []string `json:"senderPrefixes,omitempty" validate:"max=100,dive,required,max=128" example:"+7999"`
+	// The keywords contained in the message text, case-insensitive.
+	Keywords []string `json:"keywords,omitempty" validate:"max=100,dive,required,max=128" example:"code"`
+}
+
+// IsEmpty reports whether the filter matches all messages.
+func (f Filter) IsEmpty() bool {
+	return len(f.DeviceIDs) == 0 && len(f.SenderPrefixes) == 0 && len(f.Keywords) == 0
+}
+
+// Matches reports whether the message received by the device passes the
+// filter.
+func (f Filter) Matches(deviceID string, message IncomingMessage) bool {
+	if len(f.DeviceIDs) > 0 && !slices.Contains(f.DeviceIDs, deviceID) {
+		return false
+	}
+
+	if len(f.SenderPrefixes) > 0 {
+		sender := strings.ToLower(message.PhoneNumber)
+		if !slices.ContainsFunc(f.SenderPrefixes, func(prefix string) bool {
+			return strings.HasPrefix(sender, strings.ToLower(prefix))
+		}) {
+			return false
+		}
+	}
+
+	if len(f.Keywords) > 0 {
+		text := strings.ToLower(message.Message)
+		if !slices.ContainsFunc(f.Keywords, func(keyword string) bool {
+			return strings.Contains(text, strings.ToLower(keyword))
+		}) {
+			return false
+		}
+	}
+
+	return true
+}
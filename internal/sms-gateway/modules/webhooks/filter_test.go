@@ -0,0 +1,54 @@
+package webhooks
+
+import "testing"
+
+func TestFilter_Matches(t *testing.T) {
+	message := IncomingMessage{
+		ID:          "message",
+		PhoneNumber: "+79990001234",
+		Message:     "Your CODE is 123456",
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		deviceID string
+		want     bool
+	}{
+		{name: "empty", filter: Filter{}, deviceID: "device", want: true},
+		{name: "device", filter: Filter{DeviceIDs: []string{"other", "device"}}, deviceID: "device", want: true},
+		{name: "other device", filter: Filter{DeviceIDs: []string{"other"}}, deviceID: "device", want: false},
+		{name: "sender prefix", filter: Filter{SenderPrefixes: []string{"+1", "+7999"}}, deviceID: "device", want: true},
+		{name: "other sender", filter: Filter{SenderPrefixes: []string{"+1"}}, deviceID: "device", want: false},
+		{name: "keyword", filter: Filter{Keywords: []string{"code"}}, deviceID: "device", want: true},
+		{name: "missing keyword", filter: Filter{Keywords: []string{"password"}}, deviceID: "device", want: false},
+		{
+			name:     "all criteria",
+			filter:   Filter{DeviceIDs: []string{"device"}, SenderPrefixes: []string{"+7"}, Keywords: []string{"code"}},
+			deviceID: "device",
+			want:     true,
+		},
+		{
+			name:     "one criterion fails",
+			filter:   Filter{DeviceIDs: []string{"device"}, SenderPrefixes: []string{"+1"}, Keywords: []string{"code"}},
+			deviceID: "device",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(tt.deviceID, message); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_MatchesSenderName(t *testing.T) {
+	filter := Filter{SenderPrefixes: []string{"google"}}
+
+	if !filter.Matches("device", IncomingMessage{PhoneNumber: "Google"}) {
+		t.Error("sender name prefix isn't matched case-insensitively")
+	}
+}
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// flatKeySeparator joins keys of nested objects in the flat format.
+const flatKeySeparator = "_"
+
+// Flatten converts the webhook body to the flat format. Nested objects are
+// inlined with keys joined by underscore, arrays of scalar values are joined
+// with comma and other arrays are indexed, e.g.
+//
+//	{"event": "sms:received", "payload": {"message": "Hi", "phoneNumber": "+1"}}
+//
+// becomes
+//
+//	{"event": "sms:received", "message": "Hi", "phoneNumber": "+1"}
+//
+// The `payload` object is inlined without prefix, so automation platforms show
+// its fields at the top level.
+func Flatten(body map[string]any) map[string]any {
+	result := make(map[string]any, len(body))
+
+	keys := make([]string, 0, len(body))
+	for k := range body {
+		keys = append(keys, k)
+	}
+	// process payload last, so envelope fields win on conflicts
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i] != "payload" && keys[j] == "payload"
+	})
+
+	for _, k := range keys {
+		v := body[k]
+		if k == "payload" {
+			if payload, ok := v.(map[string]any); ok {
+				flattenInto(result, "", payload, true)
+				continue
+			}
+		}
+		flattenValue(result, k, v, false)
+	}
+
+	return result
+}
+
+func flattenInto(result map[string]any, prefix string, obj map[string]any, keepExisting bool) {
+	for k, v := range obj {
+		flattenValue(result, joinKey(prefix, k), v, keepExisting)
+	}
+}
+
+func flattenValue(result map[string]any, key string, value any, keepExisting bool) {
+	switch v := value.(type) {
+	case map[string]any:
+		flattenInto(result, key, v, keepExisting)
+	case []any:
+		if isScalarSlice(v) {
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			setFlat(result, key, strings.Join(items, ","), keepExisting)
+			return
+		}
+		for i, item := range v {
+			flattenValue(result, joinKey(key, fmt.Sprint(i)), item, keepExisting)
+		}
+	default:
+		setFlat(result, key, v, keepExisting)
+	}
+}
+
+func setFlat(result map[string]any, key string, value any, keepExisting bool) {
+	if _, ok := result[key]; ok && keepExisting {
+		return
+	}
+	result[key] = value
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + flatKeySeparator + key
+}
+
+func isScalarSlice(items []any) bool {
+	for _, item := range items {
+		switch item.(type) {
+		case map[string]any, []any:
+			return false
+		}
+	}
+	return true
+}
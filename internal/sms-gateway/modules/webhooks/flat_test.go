@@ -0,0 +1,71 @@
+package webhooks
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name string
+		body map[string]any
+		want map[string]any
+	}{
+		{
+			name: "Received message",
+			body: map[string]any{
+				"event":     "sms:received",
+				"deviceId":  "dev1",
+				"webhookId": "wh1",
+				"payload": map[string]any{
+					"message":     "Hello",
+					"phoneNumber": "+79990001234",
+					"simNumber":   float64(1),
+				},
+			},
+			want: map[string]any{
+				"event":       "sms:received",
+				"deviceId":    "dev1",
+				"webhookId":   "wh1",
+				"message":     "Hello",
+				"phoneNumber": "+79990001234",
+				"simNumber":   float64(1),
+			},
+		},
+		{
+			name: "Nested objects and arrays",
+			body: map[string]any{
+				"event": "sms:sent",
+				"payload": map[string]any{
+					"phoneNumbers": []any{"+1", "+2"},
+					"sim":          map[string]any{"number": float64(2)},
+					"parts":        []any{map[string]any{"id": "a"}},
+				},
+			},
+			want: map[string]any{
+				"event":        "sms:sent",
+				"phoneNumbers": "+1,+2",
+				"sim_number":   float64(2),
+				"parts_0_id":   "a",
+			},
+		},
+		{
+			name: "Envelope wins on conflict",
+			body: map[string]any{
+				"event":   "sms:received",
+				"payload": map[string]any{"event": "other"},
+			},
+			want: map[string]any{
+				"event": "sms:received",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Flatten(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flatten() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
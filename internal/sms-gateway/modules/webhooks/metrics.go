@@ -0,0 +1,67 @@
+package webhooks
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	MetricCircuitTransitionsTotal = "circuit_transitions_total"
+	MetricSkippedTotal            = "skipped_total"
+	MetricFilteredTotal           = "filtered_total"
+	MetricDroppedTotal            = "dropped_total"
+
+	LabelState = "state"
+)
+
+type metrics struct {
+	transitionsCounter *prometheus.CounterVec
+	skippedCounter     prometheus.Counter
+	filteredCounter    prometheus.Counter
+	droppedCounter     prometheus.Counter
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		transitionsCounter: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "webhooks",
+			Name:      MetricCircuitTransitionsTotal,
+			Help:      "Total number of webhook circuit breaker transitions by target state",
+		}, []string{LabelState}),
+		skippedCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "webhooks",
+			Name:      MetricSkippedTotal,
+			Help:      "Total number of webhook deliveries skipped by open circuit breakers",
+		}),
+		filteredCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "webhooks",
+			Name:      MetricFilteredTotal,
+			Help:      "Total number of incoming messages not delivered to webhooks by their filters",
+		}),
+		droppedCounter: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "sms",
+			Subsystem: "webhooks",
+			Name:      MetricDroppedTotal,
+			Help:      "Total number of server events dropped by the full delivery queue",
+		}),
+	}
+}
+
+func (m *metrics) IncTransition(state CircuitState) {
+	m.transitionsCounter.WithLabelValues(string(state)).Inc()
+}
+
+func (m *metrics) IncSkipped() {
+	m.skippedCounter.Inc()
+}
+
+func (m *metrics) IncFiltered() {
+	m.filteredCounter.Inc()
+}
+
+func (m *metrics) IncDropped() {
+	m.droppedCounter.Inc()
+}
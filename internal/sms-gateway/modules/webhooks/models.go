@@ -1,6 +1,8 @@
 package webhooks
 
 import (
+	"time"
+
 	"github.com/android-sms-gateway/client-go/smsgateway"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"gorm.io/gorm"
@@ -16,6 +18,16 @@ type Webhook struct {
 	URL   string                  `json:"url"   validate:"required,http_url"   gorm:"not null;type:varchar(256)"`
 	Event smsgateway.WebhookEvent `json:"event" gorm:"not null;type:varchar(32)"`
 
+	Format Format `json:"format" gorm:"not null;type:varchar(16);default:default"`
+
+	BatchWindow  uint       `json:"batch_window"   gorm:"not null;type:int unsigned;default:0"`
+	BatchSize    uint       `json:"batch_size"     gorm:"not null;type:int unsigned;default:0"`
+	BatchGroupBy BatchGroup `json:"batch_group_by" gorm:"not null;type:varchar(16);default:''"`
+
+	Filter *Filter `json:"filter,omitempty" gorm:"type:json;serializer:json"`
+
+	VerifiedAt *time.Time `json:"verified_at,omitempty" gorm:"type:datetime(3)"`
+
 	User   models.User    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Device *models.Device `gorm:"foreignKey:DeviceID;constraint:OnDelete:CASCADE"`
 
@@ -1,6 +1,12 @@
 package webhooks
 
 import (
+	"context"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/validation"
 	"github.com/capcom6/go-infra-fx/db"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -12,9 +18,31 @@ var Module = fx.Module(
 		return log.Named("webhooks")
 	}),
 	fx.Provide(NewRepository, fx.Private),
+	fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
+		return factory.New("webhooks")
+	}, fx.Private),
+	fx.Provide(newMetrics, fx.Private),
+	fx.Provide(validation.AsRegistration(newValidation)),
 	fx.Provide(
 		NewService,
 	),
+	fx.Invoke(func(lc fx.Lifecycle, devicesSvc *devices.Service, exportsSvc *exports.Service, svc *Service) {
+		devicesSvc.OnLifecycle(svc.onDeviceEvent)
+		exportsSvc.OnCompleted(svc.onExportCompleted)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		lc.Append(fx.Hook{
+			OnStart: func(_ context.Context) error {
+				go svc.Run(ctx)
+				return nil
+			},
+			OnStop: func(_ context.Context) error {
+				cancel()
+				svc.Close()
+				return nil
+			},
+		})
+	}),
 )
 
 func init() {
@@ -0,0 +1,33 @@
+package webhooks
+
+import (
+	"sync"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+)
+
+// ReceivedHandler is called in the background for each incoming message
+// reported by the devices. The number of the messages handled concurrently is
+// bounded, so the handler may block for a limited time only.
+type ReceivedHandler func(device models.Device, message IncomingMessage)
+
+type receivedHandlers struct {
+	mux      sync.RWMutex
+	handlers []ReceivedHandler
+}
+
+func (r *receivedHandlers) add(handler ReceivedHandler) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	r.handlers = append(r.handlers, handler)
+}
+
+func (r *receivedHandlers) emit(device models.Device, message IncomingMessage) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+
+	for _, h := range r.handlers {
+		h(device, message)
+	}
+}
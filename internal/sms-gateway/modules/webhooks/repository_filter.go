@@ -1,6 +1,9 @@
 package webhooks
 
-import "gorm.io/gorm"
+import (
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"gorm.io/gorm"
+)
 
 type SelectFilter func(*selectFilter)
 
@@ -16,11 +19,36 @@ func WithUserID(userID string) SelectFilter {
 	}
 }
 
+// WithEvent creates a SelectFilter that filters by event type.
+func WithEvent(event smsgateway.WebhookEvent) SelectFilter {
+	return func(f *selectFilter) {
+		f.event = &event
+	}
+}
+
+// WithoutEvents creates a SelectFilter that excludes the given event types.
+func WithoutEvents(events ...smsgateway.WebhookEvent) SelectFilter {
+	return func(f *selectFilter) {
+		f.excludedEvents = append(f.excludedEvents, events...)
+	}
+}
+
+// WithFiltered creates a SelectFilter that filters by the presence of the
+// incoming messages filter.
+func WithFiltered(filtered bool) SelectFilter {
+	return func(f *selectFilter) {
+		f.filtered = &filtered
+	}
+}
+
 type selectFilter struct {
-	userID        string
-	extID         *string
-	deviceID      *string
-	deviceIDExact bool
+	userID         string
+	extID          *string
+	deviceID       *string
+	deviceIDExact  bool
+	event          *smsgateway.WebhookEvent
+	excludedEvents []smsgateway.WebhookEvent
+	filtered       *bool
 }
 
 func newFilter(filters ...SelectFilter) *selectFilter {
@@ -57,5 +85,18 @@ func (f *selectFilter) apply(query *gorm.DB) *gorm.DB {
 			query = query.Where("device_id = ? OR device_id IS NULL", *f.deviceID)
 		}
 	}
+	if f.event != nil {
+		query = query.Where("event = ?", *f.event)
+	}
+	if len(f.excludedEvents) > 0 {
+		query = query.Where("event NOT IN ?", f.excludedEvents)
+	}
+	if f.filtered != nil {
+		if *f.filtered {
+			query = query.Where("filter IS NOT NULL")
+		} else {
+			query = query.Where("filter IS NULL")
+		}
+	}
 	return query
 }
@@ -0,0 +1,133 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// newSafeClient returns the HTTP client that refuses to connect to loopback,
+// private, link-local and unspecified addresses and doesn't follow redirects.
+func newSafeClient(timeout time.Duration, proxy func(*http.Request) (*url.URL, error)) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: newSafeTransport(timeout, proxy),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// safeTransport checks the target of the request, not the proxy: the direct
+// connections are checked by the dialer, the proxied requests are checked by
+// resolving the target host before they are passed to the proxy. So the
+// proxy itself may be on the loopback or private network.
+type safeTransport struct {
+	timeout  time.Duration
+	proxy    func(*http.Request) (*url.URL, error)
+	resolver *net.Resolver
+
+	direct *http.Transport
+
+	mu      sync.Mutex
+	proxied map[string]*http.Transport
+}
+
+func newSafeTransport(timeout time.Duration, proxy func(*http.Request) (*url.URL, error)) *safeTransport {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if forbiddenIP(net.ParseIP(host)) {
+				return ErrForbiddenAddress
+			}
+
+			return nil
+		},
+	}
+
+	return &safeTransport{
+		timeout:  timeout,
+		proxy:    proxy,
+		resolver: net.DefaultResolver,
+
+		direct: &http.Transport{
+			DialContext: dialer.DialContext,
+		},
+		proxied: make(map[string]*http.Transport),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *safeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		proxyURL *url.URL
+		err      error
+	)
+	if t.proxy != nil {
+		if proxyURL, err = t.proxy(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if proxyURL == nil {
+		return t.direct.RoundTrip(req)
+	}
+
+	if err := t.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+
+	return t.proxiedTransport(proxyURL).RoundTrip(req)
+}
+
+// checkHost resolves the host and refuses it if any of its addresses is
+// forbidden.
+func (t *safeTransport) checkHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if forbiddenIP(ip) {
+			return ErrForbiddenAddress
+		}
+		return nil
+	}
+
+	addrs, err := t.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("can't resolve %s: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if forbiddenIP(addr.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+
+	return nil
+}
+
+func (t *safeTransport) proxiedTransport(proxyURL *url.URL) *http.Transport {
+	key := proxyURL.String()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	transport, ok := t.proxied[key]
+	if !ok {
+		transport = &http.Transport{
+			Proxy:       http.ProxyURL(proxyURL),
+			DialContext: (&net.Dialer{Timeout: t.timeout}).DialContext,
+		}
+		t.proxied[key] = transport
+	}
+
+	return transport
+}
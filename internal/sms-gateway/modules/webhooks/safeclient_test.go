@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSafeClient_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newSafeClient(time.Second, http.ProxyURL(proxyURL))
+
+	// the proxy on the loopback is allowed, the target is checked
+	resp, err := client.Post("http://192.0.2.1/webhook", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	_ = resp.Body.Close()
+
+	for _, target := range []string{"http://127.0.0.1:8080/", "http://10.0.0.1/", "http://localhost/"} {
+		if _, err := client.Post(target, "application/json", nil); !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("Post(%s) error = %v, want %v", target, err, ErrForbiddenAddress)
+		}
+	}
+
+	if len(proxied) != 1 || proxied[0] != "http://192.0.2.1/webhook" {
+		t.Errorf("proxied requests = %v", proxied)
+	}
+}
+
+func TestSafeClient_Direct(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request to loopback address must be rejected")
+	}))
+	defer server.Close()
+
+	client := newSafeClient(time.Second, func(*http.Request) (*url.URL, error) { return nil, nil })
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Get() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
@@ -1,56 +1,124 @@
 package webhooks
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/db"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/devices"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/egress"
 	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/events"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/exports"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/settings"
+	"github.com/android-sms-gateway/server/internal/sms-gateway/modules/stats"
+	"github.com/android-sms-gateway/server/pkg/cache"
 	"github.com/capcom6/go-helpers/slices"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	// receiveWorkers is the max number of the incoming messages delivered
+	// concurrently, the reports beyond it are rejected and retried by the
+	// devices.
+	receiveWorkers = 16
+	// receivedTTL is how long the reported incoming message is remembered,
+	// so the repeated report isn't delivered again.
+	receivedTTL = 24 * time.Hour
+	// emitWorkers is the number of the server events delivered concurrently.
+	emitWorkers = 8
+	// emitQueueSize is the number of the server events waiting for the
+	// workers, the events beyond it are dropped.
+	emitQueueSize = 1024
+)
+
+// emitted is the server event waiting for the delivery.
+type emitted struct {
+	userID   string
+	deviceID *string
+	event    smsgateway.WebhookEvent
+	payload  map[string]any
+}
+
 type ServiceParams struct {
 	fx.In
 
 	IDGen db.IDGen
 
 	Webhooks *Repository
+	Cache    cache.Cache
 
-	DevicesSvc *devices.Service
-	EventsSvc  *events.Service
+	DevicesSvc  *devices.Service
+	EventsSvc   *events.Service
+	SettingsSvc *settings.Service
+	StatsSvc    *stats.Service
+	EgressSvc   *egress.Service
 
-	Logger *zap.Logger
+	Metrics *metrics
+	Logger  *zap.Logger
 }
 
 type Service struct {
 	idgen db.IDGen
 
 	webhooks *Repository
+	cache    cache.Cache
+
+	devicesSvc  *devices.Service
+	eventsSvc   *events.Service
+	settingsSvc *settings.Service
+	statsSvc    *stats.Service
 
-	devicesSvc *devices.Service
-	eventsSvc  *events.Service
+	verifier   *verifier
+	dispatcher *dispatcher
+	batcher    *batcher
+	circuits   *circuits
 
-	logger *zap.Logger
+	received receivedHandlers
+	// receiving limits the incoming messages delivered concurrently
+	receiving chan struct{}
+	// emitting holds the server events until the workers deliver them
+	emitting chan emitted
+
+	metrics *metrics
+	logger  *zap.Logger
 }
 
 func NewService(params ServiceParams) *Service {
-	return &Service{
+	s := &Service{
 		idgen: params.IDGen,
 
 		webhooks: params.Webhooks,
+		cache:    params.Cache,
+
+		devicesSvc:  params.DevicesSvc,
+		eventsSvc:   params.EventsSvc,
+		settingsSvc: params.SettingsSvc,
+		statsSvc:    params.StatsSvc,
+
+		verifier:   newVerifier(params.EgressSvc.Proxy(egress.DestinationWebhooks)),
+		dispatcher: newDispatcher(params.EgressSvc.Proxy(egress.DestinationWebhooks)),
 
-		devicesSvc: params.DevicesSvc,
-		eventsSvc:  params.EventsSvc,
+		receiving: make(chan struct{}, receiveWorkers),
+		emitting:  make(chan emitted, emitQueueSize),
 
-		logger: params.Logger,
+		metrics: params.Metrics,
+		logger:  params.Logger,
 	}
+	s.batcher = newBatcher(s.sendBatch)
+	s.circuits = newCircuits(s.metrics.IncTransition)
+
+	return s
 }
 
 // _select retrieves a list of webhooks that match the provided filters.
-func (s *Service) _select(filters ...SelectFilter) ([]smsgateway.Webhook, error) {
+func (s *Service) _select(filters ...SelectFilter) ([]WebhookDTO, error) {
 	items, err := s.webhooks.Select(filters...)
 	if err != nil {
 		return nil, fmt.Errorf("can't select webhooks: %w", err)
@@ -61,17 +129,45 @@ func (s *Service) _select(filters ...SelectFilter) ([]smsgateway.Webhook, error)
 
 // Select returns a list of webhooks for a specific user that match the provided filters.
 // It ensures that the filter includes the user's ID.
-func (s *Service) Select(userID string, filters ...SelectFilter) ([]smsgateway.Webhook, error) {
+func (s *Service) Select(userID string, filters ...SelectFilter) ([]WebhookDTO, error) {
 	filters = append(filters, WithUserID(userID))
 
 	return s._select(filters...)
 }
 
-// Replace creates or updates a webhook for a given user. After replacing the webhook,
-// it asynchronously notifies all the user's devices. Returns an error if the operation fails.
-func (s *Service) Replace(userID string, webhook smsgateway.Webhook) error {
-	if !smsgateway.IsValidWebhookEvent(webhook.Event) {
-		return newValidationError("event", string(webhook.Event), fmt.Errorf("enum value expected"))
+// Replace creates or updates a webhook for a given user. If verification is
+// requested, the webhook is stored only after the endpoint echoes the
+// challenge. After replacing the webhook, it asynchronously notifies all the
+// user's devices. Returns the stored webhook or an error if the operation fails.
+func (s *Service) Replace(ctx context.Context, userID string, webhook WebhookDTO) (WebhookDTO, error) {
+	if !smsgateway.IsValidWebhookEvent(webhook.Event) && !IsServerEvent(webhook.Event) {
+		return webhook, newValidationError("event", string(webhook.Event), fmt.Errorf("enum value expected"))
+	}
+
+	if webhook.Format == "" {
+		webhook.Format = FormatDefault
+	}
+	if !webhook.Format.IsValid() {
+		return webhook, newValidationError("format", string(webhook.Format), fmt.Errorf("enum value expected"))
+	}
+
+	if webhook.Batch != nil {
+		if !IsBatchedEvent(webhook.Event) {
+			return webhook, newValidationError("batch", string(webhook.Event), fmt.Errorf("only state change events can be batched"))
+		}
+		if webhook.Batch.GroupBy == "" {
+			webhook.Batch.GroupBy = BatchGroupMessage
+		}
+		if !webhook.Batch.GroupBy.IsValid() {
+			return webhook, newValidationError("batch.groupBy", string(webhook.Batch.GroupBy), fmt.Errorf("enum value expected"))
+		}
+	}
+
+	if webhook.Filter != nil && webhook.Filter.IsEmpty() {
+		webhook.Filter = nil
+	}
+	if webhook.Filter != nil && webhook.Event != smsgateway.WebhookEventSmsReceived {
+		return webhook, newValidationError("filter", string(webhook.Event), fmt.Errorf("only incoming messages can be filtered"))
 	}
 
 	if webhook.ID == "" {
@@ -82,43 +178,124 @@ func (s *Service) Replace(userID string, webhook smsgateway.Webhook) error {
 	if webhook.DeviceID != nil {
 		ok, err := s.devicesSvc.Exists(userID, devices.WithID(*webhook.DeviceID))
 		if err != nil {
-			return fmt.Errorf("failed to select devices: %w", err)
+			return webhook, fmt.Errorf("failed to select devices: %w", err)
 		}
 		if !ok {
-			return newValidationError("device_id", *webhook.DeviceID, devices.ErrNotFound)
+			return webhook, newValidationError("device_id", *webhook.DeviceID, devices.ErrNotFound)
+		}
+	}
+	if webhook.Filter != nil {
+		for _, deviceID := range webhook.Filter.DeviceIDs {
+			ok, err := s.devicesSvc.Exists(userID, devices.WithID(deviceID))
+			if err != nil {
+				return webhook, fmt.Errorf("failed to select devices: %w", err)
+			}
+			if !ok {
+				return webhook, newValidationError("filter.deviceIds", deviceID, devices.ErrNotFound)
+			}
 		}
 	}
 
+	webhook.VerifiedAt = nil
+	if webhook.Verify {
+		if err := s.verifier.Verify(ctx, webhook.ID, webhook.URL); err != nil {
+			s.logger.Info("Webhook verification failed",
+				zap.String("user_id", userID),
+				zap.String("webhook_id", webhook.ID),
+				zap.Error(err),
+			)
+
+			// the response of the endpoint isn't disclosed to the caller
+			if !errors.Is(err, ErrForbiddenAddress) {
+				err = ErrVerificationFailed
+			}
+			return webhook, newValidationError("url", webhook.URL, err)
+		}
+
+		now := time.Now()
+		webhook.VerifiedAt = &now
+	}
+
 	model := Webhook{
 		ExtID:    webhook.ID,
 		UserID:   userID,
 		DeviceID: webhook.DeviceID,
 		URL:      webhook.URL,
 		Event:    webhook.Event,
+		Format:   webhook.Format,
+		Filter:   webhook.Filter,
+
+		VerifiedAt: webhook.VerifiedAt,
+	}
+	if webhook.Batch != nil {
+		model.BatchWindow = webhook.Batch.Window
+		model.BatchSize = webhook.Batch.Size
+		model.BatchGroupBy = webhook.Batch.GroupBy
 	}
 
 	if err := s.webhooks.Replace(&model); err != nil {
-		return fmt.Errorf("can't replace webhook: %w", err)
+		return webhook, fmt.Errorf("can't replace webhook: %w", err)
 	}
 
+	s.circuits.Reset(circuitKey(userID, webhook.ID))
+
 	s.notifyDevices(userID, webhook.DeviceID)
 
-	return nil
+	webhook.Verify = false
+
+	return webhook, nil
 }
 
 // Delete removes webhooks for a specific user that match the provided filters.
 // It ensures that the filter includes the user's ID.
 func (s *Service) Delete(userID string, filters ...SelectFilter) error {
 	filters = append(filters, WithUserID(userID))
+
+	items, err := s.webhooks.Select(filters...)
+	if err != nil {
+		return fmt.Errorf("can't select webhooks: %w", err)
+	}
+
 	if err := s.webhooks.Delete(filters...); err != nil {
 		return fmt.Errorf("can't delete webhooks: %w", err)
 	}
 
+	for _, item := range items {
+		s.circuits.Reset(circuitKey(userID, item.ExtID))
+	}
+
 	s.notifyDevices(userID, nil)
 
 	return nil
 }
 
+// GetCircuit returns the circuit breaker state of the user's webhook. The
+// breakers apply to the events delivered by the server only, the webhooks
+// delivered by the devices are reported as unknown unless the server has
+// failed to deliver them an event, e.g. a relayed message state.
+func (s *Service) GetCircuit(userID, id string) (Circuit, error) {
+	items, err := s.webhooks.Select(WithUserID(userID), WithExtID(id))
+	if err != nil {
+		return Circuit{}, fmt.Errorf("can't select webhooks: %w", err)
+	}
+	if len(items) == 0 {
+		return Circuit{}, ErrNotFound
+	}
+
+	circuit, ok := s.circuits.Lookup(circuitKey(userID, id), time.Now())
+	if !ok && deliveredByDevice(items[0]) {
+		return Circuit{State: CircuitUnknown}, nil
+	}
+
+	return circuit, nil
+}
+
+// deliveredByDevice reports whether the events of the webhook are delivered
+// by the devices rather than by the server.
+func deliveredByDevice(webhook *Webhook) bool {
+	return !IsServerEvent(webhook.Event) && webhook.Filter == nil
+}
+
 // notifyDevices asynchronously notifies all the user's devices.
 func (s *Service) notifyDevices(userID string, deviceID *string) {
 	go func(userID string, deviceID *string) {
@@ -127,3 +304,314 @@ func (s *Service) notifyDevices(userID string, deviceID *string) {
 		}
 	}(userID, deviceID)
 }
+
+// onDeviceEvent delivers the device lifecycle event to the user's webhooks.
+func (s *Service) onDeviceEvent(event devices.LifecycleEvent) {
+	device := event.Device
+	s.Emit(device.UserID, &device.ID, smsgateway.WebhookEvent(event.Type), deviceEventPayload(event))
+}
+
+// onExportCompleted delivers the completed export with the download link to
+// the user's webhooks.
+func (s *Service) onExportCompleted(event exports.CompletedEvent) {
+	export := event.Export
+	s.Emit(export.UserID, &export.DeviceID, EventExportCompleted, exportEventPayload(event))
+}
+
+// Emit queues the server-side event for the delivery to the user's webhooks
+// by the workers. If deviceID is set, the webhooks of other devices are
+// skipped. The event is dropped if the queue is full.
+func (s *Service) Emit(userID string, deviceID *string, event smsgateway.WebhookEvent, payload map[string]any) {
+	select {
+	case s.emitting <- emitted{userID: userID, deviceID: deviceID, event: event, payload: payload}:
+	default:
+		s.metrics.IncDropped()
+		s.logger.Warn("webhooks queue is full, the event is dropped",
+			zap.String("event", string(event)),
+			zap.String("user_id", userID),
+		)
+	}
+}
+
+// Run delivers the emitted events until the context is canceled, the events
+// being delivered are canceled with it.
+func (s *Service) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for range emitWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item := <-s.emitting:
+					s.deliver(ctx, item.userID, item.deviceID, item.event, item.payload)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Service) deliver(ctx context.Context, userID string, deviceID *string, event smsgateway.WebhookEvent, payload map[string]any) {
+	logger := s.logger.With(
+		zap.String("event", string(event)),
+		zap.String("user_id", userID),
+	)
+
+	filters := []SelectFilter{WithUserID(userID), WithEvent(event)}
+	if deviceID != nil {
+		logger = logger.With(zap.String("device_id", *deviceID))
+		filters = append(filters, WithDeviceID(*deviceID, false))
+	}
+
+	items, err := s._select(filters...)
+	if err != nil {
+		logger.Error("can't select webhooks", zap.Error(err))
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	signingKey := s.signingKey(userID)
+
+	for _, item := range items {
+		if item.Batch != nil && IsBatchedEvent(event) {
+			s.batcher.Add(userID, deviceID, item, event, payload)
+			continue
+		}
+
+		s.send(ctx, logger, userID, deviceID, item, event, payload, signingKey)
+	}
+}
+
+// OnReceived registers the handler called for each incoming message reported
+// by the devices.
+func (s *Service) OnReceived(handler ReceivedHandler) {
+	s.received.add(handler)
+}
+
+// Receive delivers the incoming message reported by the device to the user's
+// filtered `sms:received` webhooks and passes it to the registered handlers in
+// the background. The webhooks without filter are delivered by the device
+// itself.
+//
+// The message repeated by the device is accepted without delivery. It
+// returns ErrReceiveBusy if the max number of the messages is being
+// delivered, so the device retries later.
+func (s *Service) Receive(ctx context.Context, device models.Device, message IncomingMessage) error {
+	key := receivedKey(device.ID, message.ID)
+	if err := s.cache.SetOrFail(ctx, key, "", cache.WithTTL(receivedTTL)); errors.Is(err, cache.ErrKeyExists) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("can't store incoming message: %w", err)
+	}
+
+	select {
+	case s.receiving <- struct{}{}:
+	default:
+		if err := s.cache.Delete(ctx, key); err != nil {
+			s.logger.Warn("can't forget incoming message", zap.String("device_id", device.ID), zap.Error(err))
+		}
+		return ErrReceiveBusy
+	}
+
+	go func() {
+		defer func() { <-s.receiving }()
+		s.receive(device.UserID, device.ID, message)
+		s.received.emit(device, message)
+	}()
+
+	return nil
+}
+
+func (s *Service) receive(userID, deviceID string, message IncomingMessage) {
+	event := smsgateway.WebhookEventSmsReceived
+	logger := s.logger.With(
+		zap.String("event", string(event)),
+		zap.String("user_id", userID),
+		zap.String("device_id", deviceID),
+	)
+
+	items, err := s._select(
+		WithUserID(userID),
+		WithEvent(event),
+		WithDeviceID(deviceID, false),
+		WithFiltered(true),
+	)
+	if err != nil {
+		logger.Error("can't select webhooks", zap.Error(err))
+		return
+	}
+
+	matched := make([]WebhookDTO, 0, len(items))
+	for _, item := range items {
+		if item.Filter != nil && !item.Filter.Matches(deviceID, message) {
+			s.metrics.IncFiltered()
+			continue
+		}
+		matched = append(matched, item)
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	payload := incomingMessagePayload(message)
+	signingKey := s.signingKey(userID)
+	for _, item := range matched {
+		s.send(context.Background(), logger, userID, &deviceID, item, event, payload, signingKey)
+	}
+}
+
+// receivedKey identifies the incoming message of the device.
+func receivedKey(deviceID, messageID string) string {
+	return "received:" + deviceID + ":" + messageID
+}
+
+// sendBatch delivers the combined payload of the batched events.
+func (s *Service) sendBatch(b *batch) {
+	logger := s.logger.With(
+		zap.String("event", string(b.Event)),
+		zap.String("user_id", b.UserID),
+		zap.Int("batch_size", len(b.Payloads)),
+	)
+
+	s.send(context.Background(), logger, b.UserID, b.DeviceID, b.Webhook, b.Event, b.Payload(), s.signingKey(b.UserID))
+}
+
+func (s *Service) send(ctx context.Context, logger *zap.Logger, userID string, deviceID *string, item WebhookDTO, event smsgateway.WebhookEvent, payload map[string]any, signingKey string) {
+	body := map[string]any{
+		"id":        s.idgen(),
+		"webhookId": item.ID,
+		"event":     event,
+		"payload":   payload,
+	}
+	if deviceID != nil {
+		body["deviceId"] = *deviceID
+	}
+	if item.Format == FormatFlat {
+		body = Flatten(body)
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		logger.Error("can't marshal webhook body", zap.String("webhook_id", item.ID), zap.Error(err))
+		return
+	}
+
+	s.dispatch(ctx, logger, userID, item.ID, heldDelivery{url: item.URL, data: data, signingKey: signingKey})
+}
+
+// dispatch delivers the event through the webhook's circuit breaker. While
+// the breaker is open, the event is held and delivered once it's closed.
+func (s *Service) dispatch(ctx context.Context, logger *zap.Logger, userID, webhookID string, delivery heldDelivery) {
+	logger = logger.With(zap.String("webhook_id", webhookID))
+
+	key := circuitKey(userID, webhookID)
+	if !s.circuits.Allow(key, time.Now()) {
+		if s.circuits.Hold(key, delivery) {
+			s.metrics.IncSkipped()
+			s.statsSvc.RecordWebhook(userID, false)
+			logger.Warn("webhook circuit is open, the oldest held event is dropped")
+		}
+		return
+	}
+
+	err := s.dispatcher.Deliver(ctx, delivery.url, delivery.data, delivery.signingKey)
+	if err != nil && ctx.Err() != nil {
+		// the shutdown isn't the failure of the webhook
+		logger.Warn("webhook delivery is canceled", zap.Error(err))
+		return
+	}
+	s.statsSvc.RecordWebhook(userID, err == nil)
+	if err != nil {
+		s.circuits.Failure(key, err, time.Now())
+		logger.Error("can't deliver webhook", zap.Error(err))
+		return
+	}
+
+	held := s.circuits.Success(key)
+	if len(held) > 0 {
+		logger.Info("webhook circuit is closed, delivering held events", zap.Int("count", len(held)))
+	}
+	for _, item := range held {
+		s.dispatch(ctx, logger, userID, webhookID, item)
+	}
+}
+
+// PendingBatches returns the number of batches waiting for the window to
+// pass or the size limit.
+func (s *Service) PendingBatches() int {
+	return s.batcher.Len()
+}
+
+// Close sends the pending batches.
+func (s *Service) Close() {
+	s.batcher.Close()
+}
+
+// signingKey returns the user's webhooks signing key, an empty string if it
+// isn't set.
+func (s *Service) signingKey(userID string) string {
+	userSettings, err := s.settingsSvc.GetSettings(userID, false)
+	if err != nil {
+		s.logger.Warn("can't get user settings", zap.String("user_id", userID), zap.Error(err))
+		return ""
+	}
+
+	group, _ := userSettings["webhooks"].(map[string]any)
+	key, _ := group["signing_key"].(string)
+
+	return key
+}
+
+func deviceEventPayload(event devices.LifecycleEvent) map[string]any {
+	payload := map[string]any{
+		"name":       event.Device.Name,
+		"appVersion": event.Device.AppVersion,
+	}
+
+	switch event.Type {
+	case devices.LifecycleRenamed:
+		payload["previousName"] = event.Previous
+	case devices.LifecycleAppVersionChanged:
+		payload["previousAppVersion"] = event.Previous
+	}
+
+	return payload
+}
+
+// incomingMessagePayload returns the payload of the `sms:received` event in
+// the layout of the events delivered by the device.
+func incomingMessagePayload(message IncomingMessage) map[string]any {
+	payload := map[string]any{
+		"messageId":   message.ID,
+		"message":     message.Message,
+		"phoneNumber": message.PhoneNumber,
+		"receivedAt":  message.ReceivedAt,
+	}
+	if message.SimNumber != nil {
+		payload["simNumber"] = *message.SimNumber
+	}
+
+	return payload
+}
+
+func exportEventPayload(event exports.CompletedEvent) map[string]any {
+	payload := map[string]any{
+		"exportId": event.Export.ID,
+		"since":    event.Export.Since,
+		"until":    event.Export.Until,
+		"count":    len(event.Export.Messages),
+	}
+
+	if event.Artifact != nil {
+		payload["url"] = event.URL
+		payload["size"] = event.Artifact.Size
+		payload["expiresAt"] = event.Artifact.ExpiresAt
+	}
+
+	return payload
+}
@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/internal/sms-gateway/models"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestService_Receive(t *testing.T) {
+	ctx := context.Background()
+	device := models.Device{ID: "device", UserID: "user"}
+	message := IncomingMessage{ID: "message", PhoneNumber: "+79990001234"}
+
+	// all the workers are busy, so nothing is delivered in the background
+	s := &Service{
+		cache:     cache.NewMemory(0),
+		receiving: make(chan struct{}, 1),
+		logger:    zap.NewNop(),
+	}
+	s.receiving <- struct{}{}
+
+	for range 2 {
+		// the rejected message isn't remembered, so the retry isn't skipped
+		if err := s.Receive(ctx, device, message); !errors.Is(err, ErrReceiveBusy) {
+			t.Fatalf("Receive() error = %v, want %v", err, ErrReceiveBusy)
+		}
+	}
+
+	// the accepted message is skipped when reported again
+	if err := s.cache.SetOrFail(ctx, receivedKey(device.ID, message.ID), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Receive(ctx, device, message); err != nil {
+		t.Errorf("Receive() of the repeated message error = %v, want nil", err)
+	}
+
+	// the same message ID of another device is a different message
+	other := models.Device{ID: "other", UserID: "user"}
+	if err := s.Receive(ctx, other, message); !errors.Is(err, ErrReceiveBusy) {
+		t.Errorf("Receive() of another device error = %v, want %v", err, ErrReceiveBusy)
+	}
+}
+
+func TestService_Emit(t *testing.T) {
+	s := &Service{
+		emitting: make(chan emitted, 1),
+		metrics: &metrics{
+			droppedCounter: prometheus.NewCounter(prometheus.CounterOpts{Name: "dropped"}),
+		},
+		logger: zap.NewNop(),
+	}
+
+	// the second event doesn't fit the queue
+	s.Emit("user", nil, "device:suspended", nil)
+	s.Emit("user", nil, "device:resumed", nil)
+
+	if len(s.emitting) != 1 {
+		t.Fatalf("queued = %d, want 1", len(s.emitting))
+	}
+	if item := <-s.emitting; item.event != "device:suspended" {
+		t.Errorf("queued event = %q, want %q", item.event, "device:suspended")
+	}
+	if dropped := testutil.ToFloat64(s.metrics.droppedCounter); dropped != 1 {
+		t.Errorf("dropped = %v, want 1", dropped)
+	}
+
+	// the workers stop with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() isn't stopped by the canceled context")
+	}
+}
@@ -0,0 +1,31 @@
+package webhooks
+
+import (
+	"github.com/android-sms-gateway/server/internal/sms-gateway/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+func newValidation() validation.Registration {
+	return validation.StructRule(validateWebhook, WebhookDTO{})
+}
+
+// validateWebhook checks the fields of the embedded smsgateway.Webhook, which
+// has no server-side rules.
+func validateWebhook(sl validator.StructLevel) {
+	webhook, ok := sl.Current().Interface().(WebhookDTO)
+	if !ok {
+		return
+	}
+
+	if webhook.DeviceID != nil && !validation.IsNanoID(*webhook.DeviceID) {
+		sl.ReportError(*webhook.DeviceID, "DeviceID", "deviceId", validation.TagNanoID, "")
+	}
+
+	if webhook.Filter != nil {
+		for _, deviceID := range webhook.Filter.DeviceIDs {
+			if !validation.IsNanoID(deviceID) {
+				sl.ReportError(deviceID, "Filter.DeviceIDs", "filter.deviceIds", validation.TagNanoID, "")
+			}
+		}
+	}
+}
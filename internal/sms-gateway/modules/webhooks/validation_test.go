@@ -0,0 +1,46 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/android-sms-gateway/client-go/smsgateway"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestValidateWebhook(t *testing.T) {
+	v := validator.New()
+	if err := newValidation()(v); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := "PyDmBQZZXYmyxMwED8Fzy"
+	invalid := "device"
+
+	tests := []struct {
+		name     string
+		deviceID *string
+		filter   *Filter
+		wantErr  bool
+	}{
+		{name: "no device", deviceID: nil},
+		{name: "valid device", deviceID: &valid},
+		{name: "invalid device", deviceID: &invalid, wantErr: true},
+		{name: "valid filter", filter: &Filter{DeviceIDs: []string{valid}, Keywords: []string{"code"}}},
+		{name: "invalid filter device", filter: &Filter{DeviceIDs: []string{invalid}}, wantErr: true},
+		{name: "empty filter keyword", filter: &Filter{Keywords: []string{""}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dto := WebhookDTO{Webhook: smsgateway.Webhook{
+				ID:       "webhook",
+				DeviceID: tt.deviceID,
+				URL:      "https://example.com/webhook",
+				Event:    smsgateway.WebhookEventSmsReceived,
+			}, Filter: tt.filter}
+			if err := v.Struct(dto); (err != nil) != tt.wantErr {
+				t.Errorf("Struct() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
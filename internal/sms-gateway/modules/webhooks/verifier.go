@@ -0,0 +1,135 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// VerificationEvent is the event name of the challenge request.
+	VerificationEvent = "webhook:verification"
+
+	verificationTimeout   = 10 * time.Second
+	verificationMaxLength = 4 * 1024
+)
+
+var (
+	ErrVerificationFailed = errors.New("endpoint verification failed")
+	ErrForbiddenAddress   = errors.New("address is not allowed")
+)
+
+type verificationRequest struct {
+	Event     string `json:"event"`
+	WebhookID string `json:"webhookId"`
+	Challenge string `json:"challenge"`
+}
+
+type verificationResponse struct {
+	Challenge string `json:"challenge"`
+}
+
+// verifier performs the challenge handshake with the webhook endpoint: the
+// endpoint must respond with the challenge either as a plain text body or as
+// the `challenge` field of a JSON object.
+type verifier struct {
+	client *http.Client
+}
+
+func newVerifier(proxy func(*http.Request) (*url.URL, error)) *verifier {
+	return &verifier{
+		client: newSafeClient(verificationTimeout, proxy),
+	}
+}
+
+// forbiddenIP reports whether the requests to the address are refused, so
+// webhooks can't reach the internal network of the server.
+func forbiddenIP(ip net.IP) bool {
+	return ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast()
+}
+
+// Verify sends the challenge to the URL and checks the response. The details
+// of the failure are wrapped for logging, they must not be returned to the
+// caller as is.
+func (v *verifier) Verify(ctx context.Context, webhookID, url string) error {
+	challenge, err := newChallenge()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(verificationRequest{
+		Event:     VerificationEvent,
+		WebhookID: webhookID,
+		Challenge: challenge,
+	})
+	if err != nil {
+		return fmt.Errorf("can't marshal challenge: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "android-sms-gateway/1.x (server; golang)")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("can't send challenge: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: unexpected status code %d", ErrVerificationFailed, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, verificationMaxLength))
+	if err != nil {
+		return fmt.Errorf("can't read response: %w", err)
+	}
+
+	if !matchChallenge(body, challenge) {
+		return ErrVerificationFailed
+	}
+
+	return nil
+}
+
+func matchChallenge(body []byte, challenge string) bool {
+	if strings.TrimSpace(string(body)) == challenge {
+		return true
+	}
+
+	res := verificationResponse{}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return false
+	}
+
+	return res.Challenge == challenge
+}
+
+func newChallenge() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("can't generate challenge: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
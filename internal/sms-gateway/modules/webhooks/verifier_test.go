@@ -0,0 +1,105 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, req verificationRequest)
+		wantErr bool
+	}{
+		{
+			name: "plain text",
+			handler: func(w http.ResponseWriter, req verificationRequest) {
+				fmt.Fprintln(w, req.Challenge)
+			},
+		},
+		{
+			name: "json",
+			handler: func(w http.ResponseWriter, req verificationRequest) {
+				_ = json.NewEncoder(w).Encode(verificationResponse{Challenge: req.Challenge})
+			},
+		},
+		{
+			name: "wrong challenge",
+			handler: func(w http.ResponseWriter, req verificationRequest) {
+				fmt.Fprint(w, "ok")
+			},
+			wantErr: true,
+		},
+		{
+			name: "error status",
+			handler: func(w http.ResponseWriter, req verificationRequest) {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, req.Challenge)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req := verificationRequest{}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Event != VerificationEvent || req.WebhookID != "webhook" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				tt.handler(w, req)
+			}))
+			defer server.Close()
+
+			v := &verifier{client: server.Client()}
+			err := v.Verify(context.Background(), "webhook", server.URL)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifier_ForbiddenAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request to loopback address must be rejected")
+	}))
+	defer server.Close()
+
+	err := newVerifier(nil).Verify(context.Background(), "webhook", server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
+
+func TestForbiddenIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"0.0.0.0", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"fd00::1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"8.8.8.8", false},
+		{"2001:4860:4860::8888", false},
+	}
+
+	for _, tt := range tests {
+		if got := forbiddenIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("forbiddenIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package online
 
 import (
+	appmetrics "github.com/android-sms-gateway/server/internal/sms-gateway/modules/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -57,6 +58,10 @@ func newMetrics() *metrics {
 			Name:      metricCacheLatency,
 			Help:      "Cache operation latency in seconds",
 			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+
+			NativeHistogramBucketFactor:     appmetrics.NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  appmetrics.NativeHistogramMaxBucketNumber,
+			NativeHistogramMinResetDuration: appmetrics.NativeHistogramMinResetDuration,
 		}),
 
 		persistenceLatency: promauto.NewHistogram(prometheus.HistogramOpts{
@@ -65,6 +70,10 @@ func newMetrics() *metrics {
 			Name:      metricPersistenceLatency,
 			Help:      "Persistence operation latency in seconds",
 			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+
+			NativeHistogramBucketFactor:     appmetrics.NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  appmetrics.NativeHistogramMaxBucketNumber,
+			NativeHistogramMinResetDuration: appmetrics.NativeHistogramMinResetDuration,
 		}),
 
 		persistenceErrors: promauto.NewCounter(prometheus.CounterOpts{
@@ -2,12 +2,20 @@ package online
 
 import (
 	"context"
+	"time"
 
 	"github.com/android-sms-gateway/server/internal/sms-gateway/cache"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+const (
+	// writeBufferSize and writeBufferInterval bound the online statuses kept
+	// in memory before they are written to the cache in a single batch.
+	writeBufferSize     = 500
+	writeBufferInterval = time.Second
+)
+
 func Module() fx.Option {
 	return fx.Module(
 		"online",
@@ -15,7 +23,7 @@ func Module() fx.Option {
 			return log.Named("online")
 		}),
 		fx.Provide(func(factory cache.Factory) (cache.Cache, error) {
-			return factory.New("online")
+			return factory.New("online", cache.WithWriteBuffer(writeBufferSize, writeBufferInterval))
 		}, fx.Private),
 		fx.Provide(newMetrics),
 		fx.Provide(New),
@@ -11,6 +11,14 @@ import (
 	"go.uber.org/zap"
 )
 
+// persistBatchSize is the maximum number of statuses stored at once.
+const persistBatchSize = 1000
+
+// PersistInterval is how often the online statuses are stored as the last
+// seen time of the devices, so the stored time lags behind by up to the
+// interval.
+const PersistInterval = time.Minute
+
 type Service interface {
 	Run(ctx context.Context)
 	SetOnline(ctx context.Context, deviceID string)
@@ -37,7 +45,7 @@ func New(devicesSvc *devices.Service, cache cache.Cache, logger *zap.Logger, met
 }
 
 func (s *service) Run(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Minute)
+	ticker := time.NewTicker(PersistInterval)
 	defer ticker.Stop()
 
 	for {
@@ -77,52 +85,64 @@ func (s *service) SetOnline(ctx context.Context, deviceID string) {
 }
 
 func (s *service) persist(ctx context.Context) error {
-	var drainErr, persistErr error
+	var err error
 
 	s.metrics.ObservePersistenceLatency(func() {
-		items, err := s.cache.Drain(ctx)
-		if err != nil {
-			drainErr = fmt.Errorf("can't drain cache: %w", err)
-			s.metrics.IncrementCacheOperation(operationDrain, statusError)
-			return
+		total := 0
+		for {
+			var count int
+			count, err = s.persistBatch(ctx)
+			total += count
+
+			if err != nil || count < persistBatchSize {
+				break
+			}
 		}
-		s.metrics.IncrementCacheOperation(operationDrain, statusSuccess)
-		s.metrics.SetBatchSize(len(items))
 
-		if len(items) == 0 {
+		s.metrics.SetBatchSize(total)
+
+		if total == 0 && err == nil {
 			s.logger.Debug("No online statuses to persist")
-			return
 		}
-		s.logger.Debug("Drained cache", zap.Int("count", len(items)))
+	})
 
-		timestamps := maps.MapValues(items, func(v string) time.Time {
-			t, err := time.Parse(time.RFC3339, v)
-			if err != nil {
-				s.logger.Warn("Can't parse last seen", zap.String("last_seen", v), zap.Error(err))
-				return time.Now().UTC()
-			}
+	return err
+}
 
-			return t
-		})
+// persistBatch drains up to persistBatchSize statuses from the cache and
+// stores them, so a large number of devices doesn't produce a single huge
+// update. It returns the number of drained statuses.
+func (s *service) persistBatch(ctx context.Context) (int, error) {
+	items, err := s.cache.DrainN(ctx, persistBatchSize)
+	if err != nil {
+		s.metrics.IncrementCacheOperation(operationDrain, statusError)
+		return 0, fmt.Errorf("can't drain cache: %w", err)
+	}
+	s.metrics.IncrementCacheOperation(operationDrain, statusSuccess)
 
-		s.logger.Debug("Parsed last seen timestamps", zap.Int("count", len(timestamps)))
+	if len(items) == 0 {
+		return 0, nil
+	}
+	s.logger.Debug("Drained cache", zap.Int("count", len(items)))
 
-		if err := s.devicesSvc.SetLastSeen(ctx, timestamps); err != nil {
-			persistErr = fmt.Errorf("can't set last seen: %w", err)
-			s.metrics.IncrementPersistenceError()
-			return
+	timestamps := maps.MapValues(items, func(v string) time.Time {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.logger.Warn("Can't parse last seen", zap.String("last_seen", v), zap.Error(err))
+			return time.Now().UTC()
 		}
 
-		s.logger.Info("Set last seen", zap.Int("count", len(timestamps)))
+		return t
 	})
 
-	if drainErr != nil {
-		return drainErr
-	}
+	s.logger.Debug("Parsed last seen timestamps", zap.Int("count", len(timestamps)))
 
-	if persistErr != nil {
-		return persistErr
+	if err := s.devicesSvc.SetLastSeen(ctx, timestamps); err != nil {
+		s.metrics.IncrementPersistenceError()
+		return len(items), fmt.Errorf("can't set last seen: %w", err)
 	}
 
-	return nil
+	s.logger.Info("Set last seen", zap.Int("count", len(timestamps)))
+
+	return len(items), nil
 }
@@ -0,0 +1,210 @@
+package redaction
+
+import (
+	"encoding/json"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// phoneKeys are the log and object fields holding phone numbers, the keys
+// are compared by fieldKey.
+var phoneKeys = map[string]struct{}{
+	"phone":        {},
+	"phonenumber":  {},
+	"phonenumbers": {},
+	"recipient":    {},
+	"recipients":   {},
+}
+
+// contentKeys are the log and object fields holding message content, the
+// keys are compared by fieldKey.
+var contentKeys = map[string]struct{}{
+	"content": {},
+	"text":    {},
+	"body":    {},
+}
+
+// fieldKey normalizes the key, so `phone_number` and `phoneNumber` match.
+func fieldKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+func isPhoneKey(key string) bool {
+	_, ok := phoneKeys[fieldKey(key)]
+	return ok
+}
+
+func isContentKey(key string) bool {
+	_, ok := contentKeys[fieldKey(key)]
+	return ok
+}
+
+// core redacts the entries before they're written by the wrapped core.
+type core struct {
+	zapcore.Core
+
+	redactor *Redactor
+}
+
+// WrapLogger returns the logger that masks the phone numbers and hides the
+// message content. The fields are recognized by the keys, including the
+// fields of the objects logged with zap.Any or zap.Object. The phone numbers
+// in the international format are also masked in the messages and errors.
+func WrapLogger(logger *zap.Logger, redactor *Redactor) *zap.Logger {
+	if !redactor.Enabled() {
+		return logger
+	}
+
+	return logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &core{Core: c, redactor: redactor}
+	}))
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{
+		Core:     c.Core.With(c.fields(fields)),
+		redactor: c.redactor,
+	}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.Text(ent.Message)
+
+	return c.Core.Write(ent, c.fields(fields))
+}
+
+func (c *core) fields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		redacted[i] = c.field(f)
+	}
+
+	return redacted
+}
+
+func (c *core) field(f zapcore.Field) zapcore.Field {
+	if isContentKey(f.Key) && c.redactor.config.HideContent {
+		if f.Type == zapcore.StringType {
+			return zap.String(f.Key, c.redactor.Content(f.String))
+		}
+		return zap.String(f.Key, redactedContent)
+	}
+
+	phone := isPhoneKey(f.Key)
+	switch f.Type {
+	case zapcore.StringType:
+		if phone {
+			return zap.String(f.Key, c.redactor.Phone(f.String))
+		}
+		return f
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			return zap.String(f.Key, c.redactor.Text(err.Error()))
+		}
+	case zapcore.SkipType:
+		return f
+	}
+
+	if phone {
+		// the lists and the other values can't be masked element-wise
+		return zap.String(f.Key, redactedContent)
+	}
+
+	switch f.Type {
+	case zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.InlineMarshalerType:
+		if value, ok := fieldValue(f); ok {
+			return zap.Any(f.Key, c.value(f.Key, value))
+		}
+		// the value that can't be inspected may hold anything
+		return zap.String(f.Key, redactedContent)
+	}
+
+	return f
+}
+
+// fieldValue returns the value of the object field as the tree of maps,
+// slices and scalars, the way the JSON encoder would write it.
+func fieldValue(f zapcore.Field) (any, bool) {
+	if f.Type == zapcore.ReflectType {
+		return decode(f.Interface)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	if f.Type == zapcore.InlineMarshalerType {
+		return enc.Fields, true
+	}
+
+	value, ok := enc.Fields[f.Key]
+	return value, ok
+}
+
+// value redacts the fields of the object by the keys.
+func (c *core) value(key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for k, item := range v {
+			redacted[k] = c.value(k, item)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = c.value(key, item)
+		}
+		return redacted
+	case string:
+		switch {
+		case isContentKey(key):
+			return c.redactor.Content(v)
+		case isPhoneKey(key):
+			return c.redactor.Phone(v)
+		}
+		return v
+	case nil, bool, float64:
+		return v
+	}
+
+	// the other values, e.g. reflected by the object marshalers, are
+	// inspected the way they're encoded
+	decoded, ok := decode(value)
+	if !ok {
+		return redactedContent
+	}
+	switch decoded.(type) {
+	case map[string]any, []any:
+		return c.value(key, decoded)
+	}
+	if isPhoneKey(key) || isContentKey(key) {
+		return c.value(key, decoded)
+	}
+
+	return value
+}
+
+// decode returns the JSON representation of the value as the tree of maps,
+// slices and scalars.
+func decode(value any) (any, bool) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+
+	var decoded any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, false
+	}
+
+	return decoded, true
+}
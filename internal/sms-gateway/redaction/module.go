@@ -0,0 +1,12 @@
+package redaction
+
+import (
+	"go.uber.org/fx"
+)
+
+// Module provides the Redactor and wraps the logger. It isn't an fx.Module,
+// so the decorated logger is used by all the modules of the application.
+var Module = fx.Options(
+	fx.Provide(New),
+	fx.Decorate(WrapLogger),
+)
@@ -0,0 +1,95 @@
+package redaction
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// redactedContent replaces the hidden message content.
+const redactedContent = "[redacted]"
+
+type Config struct {
+	// Enabled turns the redaction on, the logs are left as is otherwise.
+	Enabled bool
+	// PhoneVisibleChars is the number of the trailing digits of phone numbers
+	// left visible, the other digits are masked.
+	PhoneVisibleChars int
+	// HideContent replaces the message content.
+	HideContent bool
+}
+
+// phoneRegexp matches the phone numbers in the international format in free
+// text, e.g. error messages. The leading plus is required, so the timestamps,
+// counters and IDs aren't matched.
+var phoneRegexp = regexp.MustCompile(`\+\d{7,15}\b`)
+
+// Redactor masks the personal data, so the operators may keep the logs
+// without the phone numbers and message content of the users.
+type Redactor struct {
+	config Config
+}
+
+func New(config Config) *Redactor {
+	return &Redactor{
+		config: config,
+	}
+}
+
+// Enabled reports whether the redaction is turned on.
+func (r *Redactor) Enabled() bool {
+	return r.config.Enabled
+}
+
+// Phone masks all but the last PhoneVisibleChars digits of the phone number.
+// A number without more digits than visible ones is masked completely.
+func (r *Redactor) Phone(phone string) string {
+	if !r.config.Enabled {
+		return phone
+	}
+
+	digits := 0
+	for _, c := range phone {
+		if unicode.IsDigit(c) {
+			digits++
+		}
+	}
+
+	visible := max(r.config.PhoneVisibleChars, 0)
+	if digits <= visible {
+		visible = 0
+	}
+
+	masked := digits - visible
+	var b strings.Builder
+	b.Grow(len(phone))
+	for _, c := range phone {
+		if masked > 0 && unicode.IsDigit(c) {
+			b.WriteRune('*')
+			masked--
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// Content hides the message content when HideContent is set.
+func (r *Redactor) Content(content string) string {
+	if !r.config.Enabled || !r.config.HideContent || content == "" {
+		return content
+	}
+
+	return redactedContent
+}
+
+// Text masks the phone numbers in the international format found in free
+// text.
+func (r *Redactor) Text(text string) string {
+	if !r.config.Enabled {
+		return text
+	}
+
+	return phoneRegexp.ReplaceAllStringFunc(text, r.Phone)
+}
@@ -0,0 +1,140 @@
+package redaction
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactor_Phone(t *testing.T) {
+	tests := []struct {
+		name    string
+		visible int
+		phone   string
+		want    string
+	}{
+		{name: "e164", visible: 4, phone: "+79990001234", want: "+*******1234"},
+		{name: "formatted", visible: 4, phone: "+7 (999) 000-12-34", want: "+* (***) ***-12-34"},
+		{name: "no visible", visible: 0, phone: "+79990001234", want: "+***********"},
+		{name: "short", visible: 4, phone: "1234", want: "****"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(Config{Enabled: true, PhoneVisibleChars: tt.visible})
+			if got := r.Phone(tt.phone); got != tt.want {
+				t.Errorf("Phone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+
+	if got := New(Config{}).Phone("+79990001234"); got != "+79990001234" {
+		t.Errorf("Phone() without redaction = %q", got)
+	}
+}
+
+func TestRedactor_Text(t *testing.T) {
+	r := New(Config{Enabled: true, PhoneVisibleChars: 2})
+
+	got := r.Text("can't send to +79990001234 from device Ab12345678cd at 2025-10-16, message 1234567890 at 1760702400")
+	want := "can't send to +*********34 from device Ab12345678cd at 2025-10-16, message 1234567890 at 1760702400"
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestWrapLogger(t *testing.T) {
+	obs, logs := observer.New(zapcore.DebugLevel)
+	r := New(Config{Enabled: true, PhoneVisibleChars: 4, HideContent: true})
+	logger := WrapLogger(zap.New(obs), r).With(zap.String("phone", "+79990001234"))
+
+	logger.Info("message to +79990001234 failed",
+		zap.String("text", "secret code 1234"),
+		zap.Strings("recipients", []string{"+79990001234"}),
+		zap.Error(errors.New("invalid phone +79990001234")),
+		zap.String("device_id", "device"),
+		zap.String("message_id", "1760702400123"),
+	)
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(entries))
+	}
+	if entries[0].Message != "message to +*******1234 failed" {
+		t.Errorf("Message = %q", entries[0].Message)
+	}
+
+	want := map[string]any{
+		"phone":      "+*******1234",
+		"text":       redactedContent,
+		"recipients": redactedContent,
+		"error":      "invalid phone +*******1234",
+		"device_id":  "device",
+		"message_id": "1760702400123",
+	}
+	got := entries[0].ContextMap()
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+}
+
+func TestWrapLogger_Objects(t *testing.T) {
+	type message struct {
+		ID           string   `json:"id"`
+		PhoneNumbers []string `json:"phoneNumbers"`
+		Text         string   `json:"text"`
+		CreatedAt    int64    `json:"createdAt"`
+	}
+
+	obs, logs := observer.New(zapcore.DebugLevel)
+	r := New(Config{Enabled: true, PhoneVisibleChars: 4, HideContent: true})
+	logger := WrapLogger(zap.New(obs), r)
+
+	logger.Info("message",
+		zap.Any("message", message{ID: "1234567890", PhoneNumbers: []string{"+79990001234"}, Text: "secret", CreatedAt: 1760702400}),
+		zap.Object("device", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("id", "device")
+			enc.AddString("phone_number", "+79990001234")
+			return nil
+		})),
+	)
+
+	entries := logs.AllUntimed()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %d, want 1", len(entries))
+	}
+	got := entries[0].ContextMap()
+
+	msg, ok := got["message"].(map[string]any)
+	if !ok {
+		t.Fatalf("message = %#v, want object", got["message"])
+	}
+	if phones, _ := msg["phoneNumbers"].([]any); len(phones) != 1 || phones[0] != "+*******1234" {
+		t.Errorf("message.phoneNumbers = %v", msg["phoneNumbers"])
+	}
+	if msg["text"] != redactedContent {
+		t.Errorf("message.text = %v, want %s", msg["text"], redactedContent)
+	}
+	if msg["id"] != "1234567890" {
+		t.Errorf("message.id = %v, want 1234567890", msg["id"])
+	}
+	if msg["createdAt"] != float64(1760702400) {
+		t.Errorf("message.createdAt = %v, want 1760702400", msg["createdAt"])
+	}
+
+	device, ok := got["device"].(map[string]any)
+	if !ok {
+		t.Fatalf("device = %#v, want object", got["device"])
+	}
+	if device["phone_number"] != "+*******1234" {
+		t.Errorf("device.phone_number = %v", device["phone_number"])
+	}
+	if device["id"] != "device" {
+		t.Errorf("device.id = %v, want device", device["id"])
+	}
+}
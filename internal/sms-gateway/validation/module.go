@@ -0,0 +1,28 @@
+package validation
+
+import (
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/fx"
+)
+
+type params struct {
+	fx.In
+
+	Validator     *validator.Validate
+	Registrations []Registration `group:"validator-registrations"`
+}
+
+// Module registers the built-in rules and the rules provided by the modules
+// with AsRegistration on the shared validator before the handlers start.
+var Module = fx.Module(
+	"validation",
+	fx.Invoke(func(p params) error {
+		for _, register := range append(builtin, p.Registrations...) {
+			if err := register(p.Validator); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}),
+)
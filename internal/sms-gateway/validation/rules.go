@@ -0,0 +1,153 @@
+package validation
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/nyaruka/phonenumbers"
+)
+
+const (
+	// TagNanoID validates the IDs generated by the server, e.g. device IDs.
+	TagNanoID = "nanoid"
+	// TagE164 validates the phone numbers in the E.164 format. It replaces
+	// the built-in rule of the validator, which checks the format only, so
+	// the number must also be valid for its country.
+	TagE164 = "e164"
+	// TagCron validates the cron expressions of five fields or the
+	// descriptors like `@daily`.
+	TagCron = "cron"
+)
+
+var nanoIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{21}$`)
+
+// builtin are the rules available to all the DTOs.
+var builtin = []Registration{
+	Rule(TagNanoID, isNanoID),
+	Rule(TagE164, isE164),
+	Rule(TagCron, isCron),
+}
+
+// IsNanoID reports whether the value is an ID generated by the server.
+func IsNanoID(value string) bool {
+	return nanoIDRegexp.MatchString(value)
+}
+
+func isNanoID(fl validator.FieldLevel) bool {
+	return IsNanoID(fl.Field().String())
+}
+
+// IsE164 reports whether the value is a valid phone number in the E.164
+// format, e.g. `+79990001234`.
+func IsE164(value string) bool {
+	if !strings.HasPrefix(value, "+") {
+		return false
+	}
+
+	phone, err := phonenumbers.Parse(value, "")
+	if err != nil || !phonenumbers.IsValidNumber(phone) {
+		return false
+	}
+
+	// the formatting characters are accepted by the parser only
+	return phonenumbers.Format(phone, phonenumbers.E164) == value
+}
+
+func isE164(fl validator.FieldLevel) bool {
+	return IsE164(fl.Field().String())
+}
+
+// cronField is the range of the values of a cron expression field.
+type cronField struct {
+	min, max int
+	names    []string
+}
+
+var cronFields = []cronField{
+	{min: 0, max: 59},
+	{min: 0, max: 23},
+	{min: 1, max: 31},
+	{min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// both 0 and 7 are Sunday
+	{min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var cronDescriptors = map[string]struct{}{
+	"@yearly":   {},
+	"@annually": {},
+	"@monthly":  {},
+	"@weekly":   {},
+	"@daily":    {},
+	"@midnight": {},
+	"@hourly":   {},
+}
+
+// IsCron reports whether the value is a cron expression of minute, hour, day
+// of month, month and day of week fields, or a descriptor like `@daily`. The
+// fields are lists of values, ranges and steps, e.g. `*/15 9-18 * * mon-fri`.
+func IsCron(value string) bool {
+	if _, ok := cronDescriptors[strings.ToLower(value)]; ok {
+		return true
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) != len(cronFields) {
+		return false
+	}
+
+	for i, field := range fields {
+		for _, item := range strings.Split(field, ",") {
+			if !cronFields[i].valid(strings.ToLower(item)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func isCron(fl validator.FieldLevel) bool {
+	return IsCron(fl.Field().String())
+}
+
+// valid reports whether the item of the field list is `*`, a value or a
+// range, optionally with a step.
+func (f cronField) valid(item string) bool {
+	if base, step, ok := strings.Cut(item, "/"); ok {
+		if n, err := strconv.Atoi(step); err != nil || n < 1 || n > f.max {
+			return false
+		}
+		item = base
+	}
+
+	if item == "*" {
+		return true
+	}
+
+	if from, to, ok := strings.Cut(item, "-"); ok {
+		start, ok1 := f.value(from)
+		end, ok2 := f.value(to)
+		return ok1 && ok2 && start <= end
+	}
+
+	_, ok := f.value(item)
+	return ok
+}
+
+// value returns the number of the field value given by the number or the name.
+func (f cronField) value(s string) (int, bool) {
+	for i, name := range f.names {
+		if s == name {
+			return f.min + i, true
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || strings.HasPrefix(s, "+") || n < f.min || n > f.max {
+		return 0, false
+	}
+
+	return n, true
+}
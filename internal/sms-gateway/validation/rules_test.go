@@ -0,0 +1,107 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func newValidator(t *testing.T) *validator.Validate {
+	t.Helper()
+
+	v := validator.New()
+	for _, register := range builtin {
+		if err := register(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return v
+}
+
+func TestNanoID(t *testing.T) {
+	v := newValidator(t)
+
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "PyDmBQZZXYmyxMwED8Fzy", valid: true},
+		{value: "fL2m4IirEvh9BvTf6TI-_", valid: true},
+		{value: "PyDmBQZZXYmyxMwED8Fz", valid: false},
+		{value: "PyDmBQZZXYmyxMwED8Fzy1", valid: false},
+		{value: "PyDmBQZZXYmyxMwED8Fz!", valid: false},
+		{value: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := v.Var(tt.value, TagNanoID)
+		if (err == nil) != tt.valid {
+			t.Errorf("Var(%q) error = %v, want valid %v", tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestE164(t *testing.T) {
+	v := newValidator(t)
+
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "+79990001234", valid: true},
+		{value: "+4915112345678", valid: true},
+		{value: "+12025550123", valid: true},
+		{value: "79990001234", valid: false},
+		{value: "+7 999 000-12-34", valid: false},
+		{value: "+7999000123", valid: false},
+		{value: "+999123456789", valid: false},
+		{value: "+7999000123a", valid: false},
+		{value: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := v.Var(tt.value, TagE164)
+		if (err == nil) != tt.valid {
+			t.Errorf("Var(%q) error = %v, want valid %v", tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestCron(t *testing.T) {
+	v := newValidator(t)
+
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "* * * * *", valid: true},
+		{value: "*/15 9-18 * * mon-fri", valid: true},
+		{value: "0 0 1,15 JAN-JUN/2 0", valid: true},
+		{value: "30 8 * * 7", valid: true},
+		{value: "0-59/5 0 31 12 sun", valid: true},
+		{value: "@daily", valid: true},
+		{value: "@Hourly", valid: true},
+		{value: "* * * *", valid: false},
+		{value: "* * * * * *", valid: false},
+		{value: "60 * * * *", valid: false},
+		{value: "* 24 * * *", valid: false},
+		{value: "* * 0 * *", valid: false},
+		{value: "* * * 13 *", valid: false},
+		{value: "* * * * 8", valid: false},
+		{value: "10-5 * * * *", valid: false},
+		{value: "*/0 * * * *", valid: false},
+		{value: "+5 * * * *", valid: false},
+		{value: "1,,2 * * * *", valid: false},
+		{value: "* * * foo *", valid: false},
+		{value: "@every", valid: false},
+		{value: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := v.Var(tt.value, TagCron)
+		if (err == nil) != tt.valid {
+			t.Errorf("Var(%q) error = %v, want valid %v", tt.value, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/fx"
+)
+
+// Registration registers custom rules on the validator shared by all the
+// handlers.
+type Registration func(v *validator.Validate) error
+
+// Rule returns the Registration of the field rule available by the tag.
+func Rule(tag string, fn validator.Func) Registration {
+	return func(v *validator.Validate) error {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return fmt.Errorf("can't register %q rule: %w", tag, err)
+		}
+
+		return nil
+	}
+}
+
+// StructRule returns the Registration of the struct-level rule applied to the
+// values of the types.
+func StructRule(fn validator.StructLevelFunc, types ...any) Registration {
+	return func(v *validator.Validate) error {
+		v.RegisterStructValidation(fn, types...)
+		return nil
+	}
+}
+
+// AsRegistration annotates the constructor of a Registration, so the rules of
+// the module are registered with the built-in ones.
+func AsRegistration(f any) any {
+	return fx.Annotate(
+		f,
+		fx.ResultTags(`group:"validator-registrations"`),
+	)
+}
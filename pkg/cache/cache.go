@@ -1,7 +1,17 @@
 package cache
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
+// NoExpiration is returned by GetTTL for items that never expire.
+const NoExpiration time.Duration = -1
+
+// Cache is a key-value store with expiring items. The backends agree on the
+// expired items: they are skipped by Keys and Drain and reported as missing
+// with ErrKeyNotFound. With lazy expiration they are reported with
+// ErrKeyExpired, which matches ErrKeyNotFound, until they are cleaned up.
 type Cache interface {
 	// Set sets the value for the given key in the cache.
 	Set(ctx context.Context, key string, value string, opts ...Option) error
@@ -11,26 +21,137 @@ type Cache interface {
 
 	// Get gets the value for the given key from the cache.
 	//
-	// If the key is not found, it returns ErrKeyNotFound.
-	// If the key has expired, it returns ErrKeyExpired.
+	// If the key is not found or has expired, it returns ErrKeyNotFound, or
+	// ErrKeyExpired for the expired key with lazy expiration.
 	// Otherwise, it returns the value and nil.
 	Get(ctx context.Context, key string) (string, error)
 
+	// SetBytes is like Set, but takes the value as bytes. Values are stored
+	// as is, so binary data doesn't need to be encoded.
+	SetBytes(ctx context.Context, key string, value []byte, opts ...Option) error
+
+	// GetBytes is like Get, but returns the value as bytes.
+	GetBytes(ctx context.Context, key string) ([]byte, error)
+
+	// CompareAndSwap atomically replaces the value of the given key with
+	// newValue if the stored value equals oldValue. Options apply to the new
+	// value as in Set.
+	//
+	// If the key is not found or has expired, it returns ErrKeyNotFound.
+	// If the stored value differs from oldValue, it returns ErrValueMismatch.
+	CompareAndSwap(ctx context.Context, key string, oldValue, newValue string, opts ...Option) error
+
+	// GetOrSet gets the value for the given key, or loads and stores it if the
+	// key is not found or has expired.
+	//
+	// Concurrent calls for the same key share a single load. If the key is
+	// populated concurrently by another process, the stored value is returned.
+	// Load errors are returned as is and nothing is stored.
+	GetOrSet(ctx context.Context, key string, load func() (string, error), opts ...Option) (string, error)
+
 	// GetAndDelete is like Get, but also deletes the key from the cache.
 	GetAndDelete(ctx context.Context, key string) (string, error)
 
+	// Increment atomically adds delta to the integer value of the given key
+	// and returns the new value. A missing or expired key is treated as zero.
+	// Options set the expiration only if the key has none, so a counter
+	// window isn't extended by subsequent increments.
+	//
+	// If the stored value is not an integer, it returns ErrInvalidValue.
+	Increment(ctx context.Context, key string, delta int64, opts ...Option) (int64, error)
+
+	// Decrement is like Increment, but subtracts delta.
+	Decrement(ctx context.Context, key string, delta int64, opts ...Option) (int64, error)
+
+	// GetTTL returns the remaining lifetime of the item, or NoExpiration if
+	// the item never expires.
+	//
+	// If the key is not found or has expired, it returns ErrKeyNotFound, or
+	// ErrKeyExpired for the expired key with lazy expiration.
+	GetTTL(ctx context.Context, key string) (time.Duration, error)
+
+	// Touch sets the lifetime of the existing item to ttl without rewriting
+	// the value. Zero or negative ttl removes the expiration.
+	//
+	// If the key is not found or has expired, it returns ErrKeyNotFound.
+	Touch(ctx context.Context, key string, ttl time.Duration) error
+
 	// Delete removes the item associated with the given key from the cache.
 	// If the key does not exist, it performs no action and returns nil.
 	// The operation is safe for concurrent use.
 	Delete(ctx context.Context, key string) error
 
+	// InvalidateTag removes all the items with the tag attached by WithTags.
+	InvalidateTag(ctx context.Context, tag string) error
+
+	// Keys returns the keys of the non-expired items matching the glob-style
+	// pattern in no particular order. The pattern follows the Redis syntax,
+	// empty pattern matches all keys. Unlike Drain, the cache is left intact.
+	//
+	// If the pattern is malformed, it returns ErrInvalidPattern. If the
+	// context is canceled, it returns the context error.
+	Keys(ctx context.Context, pattern string) ([]string, error)
+
 	// Cleanup removes all expired items from the cache.
-	// The operation is safe for concurrent use.
+	// The operation is safe for concurrent use. If the context is canceled,
+	// it returns the context error, some items may be already removed.
 	Cleanup(ctx context.Context) error
 
 	// Drain returns a map of all the non-expired items in the cache.
 	// The returned map is a snapshot of the cache at the time of the call.
 	// The cache is cleared after the call.
-	// The operation is safe for concurrent use.
+	// The operation is safe for concurrent use. If the context is canceled,
+	// it returns the context error and the items are kept.
 	Drain(ctx context.Context) (map[string]string, error)
+
+	// DrainN is like Drain, but atomically removes and returns at most n
+	// non-expired items, so a large cache can be processed in bounded
+	// chunks. The choice of the items is arbitrary. If n isn't positive,
+	// nothing is removed.
+	DrainN(ctx context.Context, n int) (map[string]string, error)
+
+	// Close releases the resources held by the cache, such as background
+	// goroutines. Stored items are kept. It's safe to call Close more than
+	// once.
+	Close() error
+}
+
+// Stats describes the usage of a cache.
+type Stats struct {
+	// Entries is the number of stored items, including expired ones not yet
+	// cleaned up.
+	Entries int
+	// Bytes is the total size of stored keys and values.
+	Bytes int64
+	// Evictions is the number of items evicted to stay within the limits.
+	Evictions uint64
+	// Hits and Misses are the numbers of lookups that found the item and
+	// that found nothing or an expired item.
+	Hits   uint64
+	Misses uint64
+	// Expirations is the number of expired items removed by the cleanup.
+	Expirations uint64
+}
+
+// StatsProvider is implemented by caches that track their usage.
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// Flusher is implemented by caches that buffer writes.
+type Flusher interface {
+	// Flush writes the buffered items out, e.g. before shutdown.
+	Flush(ctx context.Context) error
+}
+
+// ExpireHandler is called for every item removed from the cache because it
+// has expired. The value is empty if the backend doesn't keep the values of
+// expired items.
+type ExpireHandler func(key, value string)
+
+// ExpireNotifier is implemented by caches that report expired items.
+type ExpireNotifier interface {
+	// OnExpire registers the handler called after expired items are removed.
+	// Handlers are called synchronously, so they must not block.
+	OnExpire(handler ExpireHandler)
 }
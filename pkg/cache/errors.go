@@ -5,8 +5,28 @@ import "errors"
 var (
 	// ErrKeyNotFound indicates no value exists for the given key.
 	ErrKeyNotFound = errors.New("key not found")
-	// ErrKeyExpired indicates a value exists but has expired.
-	ErrKeyExpired = errors.New("key expired")
+	// ErrKeyExpired indicates a value exists but has expired. It's returned
+	// only by the caches with lazy expiration and matches ErrKeyNotFound, so
+	// the callers that don't distinguish the cases check ErrKeyNotFound only.
+	ErrKeyExpired error = expiredError{}
 	// ErrKeyExists indicates a conflicting set when the key already exists.
 	ErrKeyExists = errors.New("key already exists")
+	// ErrValueMismatch indicates the stored value differs from the expected
+	// one on compare-and-swap.
+	ErrValueMismatch = errors.New("value mismatch")
+	// ErrInvalidValue indicates the stored value can't be decoded by Typed or
+	// isn't an integer counter.
+	ErrInvalidValue = errors.New("invalid value")
+	// ErrInvalidPattern indicates a malformed pattern of Keys.
+	ErrInvalidPattern = errors.New("invalid pattern")
 )
+
+type expiredError struct{}
+
+func (expiredError) Error() string {
+	return "key expired"
+}
+
+func (expiredError) Is(target error) bool {
+	return target == ErrKeyNotFound
+}
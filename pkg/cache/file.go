@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// fileFormatVersion is the version of the snapshot format, files of other
+// versions are rejected.
+const fileFormatVersion = 1
+
+type fileCache struct {
+	*memoryCache
+
+	path string
+
+	// saveMux serializes the snapshots, so an older one never replaces a
+	// newer one
+	saveMux sync.Mutex
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// fileSnapshot is the content of the cache file.
+type fileSnapshot struct {
+	Version int        `json:"version"`
+	Items   []fileItem `json:"items"`
+}
+
+type fileItem struct {
+	Key        string    `json:"key"`
+	Value      string    `json:"value"`
+	ValidUntil time.Time `json:"valid_until,omitzero"`
+	Tags       []string  `json:"tags,omitempty"`
+}
+
+// NewFile creates a memory cache persisted to the file at path, so the items
+// survive restarts of a single node. The items are loaded from the file if it
+// exists, the ones expired meanwhile are dropped without notifying the
+// OnExpire handlers. The cache is saved every saveInterval and on Close, zero
+// saveInterval saves it only on Close, so the changes since the last save are
+// lost if the process crashes. Close must be called to save the cache and stop
+// the background goroutines. The options are the ones of NewMemory.
+func NewFile(path string, ttl, saveInterval time.Duration, opts ...MemoryOption) (Cache, error) {
+	f := &fileCache{
+		memoryCache: newMemory(ttl, opts...),
+		path:        path,
+	}
+
+	if err := f.load(); err != nil {
+		_ = f.memoryCache.Close()
+		return nil, err
+	}
+
+	if saveInterval > 0 {
+		f.stop = make(chan struct{})
+		f.done = make(chan struct{})
+		go f.saver(saveInterval)
+	}
+
+	return f, nil
+}
+
+// Close implements Cache. It stops the background goroutines and saves the
+// cache.
+func (f *fileCache) Close() error {
+	f.closeOnce.Do(func() {
+		if f.stop == nil {
+			return
+		}
+
+		close(f.stop)
+		<-f.done
+	})
+
+	return errors.Join(f.memoryCache.Close(), f.save())
+}
+
+// Flush implements Flusher. It saves the cache to the file.
+func (f *fileCache) Flush(_ context.Context) error {
+	return f.save()
+}
+
+func (f *fileCache) saver(interval time.Duration) {
+	defer close(f.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			// failed saves are retried on the next tick and on Close
+			_ = f.save()
+		}
+	}
+}
+
+// save writes the non-expired items to the file. The file is replaced
+// atomically, so it's never left partially written.
+func (f *fileCache) save() error {
+	f.saveMux.Lock()
+	defer f.saveMux.Unlock()
+
+	data, err := json.Marshal(fileSnapshot{
+		Version: fileFormatVersion,
+		Items:   f.snapshot(),
+	})
+	if err != nil {
+		return fmt.Errorf("can't encode cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("can't create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("can't write cache file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("can't write cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("can't write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("can't replace cache file: %w", err)
+	}
+
+	return nil
+}
+
+func (f *fileCache) load() error {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't read cache file: %w", err)
+	}
+
+	snapshot := fileSnapshot{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("can't decode cache file: %w", err)
+	}
+	if snapshot.Version != fileFormatVersion {
+		return fmt.Errorf("unsupported cache file version: %d", snapshot.Version)
+	}
+
+	f.restore(snapshot.Items)
+
+	return nil
+}
+
+// snapshot returns the non-expired items, from the least to the most recently
+// used ones if the cache is bounded.
+func (m *memoryCache) snapshot() []fileItem {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	now := time.Now()
+	items := make([]fileItem, 0, len(m.items))
+	add := func(key string, item *memoryItem) {
+		if item.isExpired(now) {
+			return
+		}
+
+		tags := make([]string, 0, len(item.tags))
+		for tag := range item.tags {
+			tags = append(tags, tag)
+		}
+		slices.Sort(tags)
+
+		items = append(items, fileItem{
+			Key:        key,
+			Value:      item.value,
+			ValidUntil: item.validUntil,
+			Tags:       tags,
+		})
+	}
+
+	if m.lru == nil {
+		for key, item := range m.items {
+			add(key, item)
+		}
+
+		return items
+	}
+
+	for elem := m.lru.Back(); elem != nil; elem = elem.Prev() {
+		key := elem.Value.(string)
+		add(key, m.items[key])
+	}
+
+	return items
+}
+
+// restore stores the non-expired items in order, so the last ones are the
+// most recently used.
+func (m *memoryCache) restore(items []fileItem) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	now := time.Now()
+	for _, item := range items {
+		restored := newItem(item.Value, options{validUntil: item.ValidUntil, tags: item.Tags})
+		if restored.isExpired(now) {
+			continue
+		}
+
+		m.set(item.Key, restored)
+	}
+}
+
+var _ StatsProvider = (*fileCache)(nil)
+var _ Flusher = (*fileCache)(nil)
+var _ ExpireNotifier = (*fileCache)(nil)
+var _ scopedCache = (*fileCache)(nil)
@@ -0,0 +1,145 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestFileCache_Persistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, err := cache.NewFile(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Set(ctx, "key", "value")
+	_ = c.Set(ctx, "ttl", "value", cache.WithTTL(time.Hour))
+	_ = c.Set(ctx, "expiring", "value", cache.WithTTL(50*time.Millisecond))
+	_ = c.Set(ctx, "tagged", "value", cache.WithTags("tag"))
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	c, err = cache.NewFile(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if value, err := c.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Get() = %q, %v, want %q", value, err, "value")
+	}
+	if ttl, err := c.GetTTL(ctx, "ttl"); err != nil || ttl <= 59*time.Minute {
+		t.Errorf("GetTTL() = %s, %v, want about an hour", ttl, err)
+	}
+	if _, err := c.Get(ctx, "expiring"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Get() of expired item error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+
+	if err := c.InvalidateTag(ctx, "tag"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "tagged"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Get() of invalidated item error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+}
+
+func TestFileCache_SaveInterval(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, err := cache.NewFile(path, 0, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_ = c.Set(ctx, "key", "value")
+	time.Sleep(50 * time.Millisecond)
+
+	// the saved file is loaded by another instance while the first one runs
+	other, err := cache.NewFile(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, err := other.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Get() = %q, %v, want %q", value, err, "value")
+	}
+}
+
+func TestFileCache_Flush(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, err := cache.NewFile(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_ = c.Set(ctx, "key", "value")
+
+	flusher, ok := c.(cache.Flusher)
+	if !ok {
+		t.Fatal("file cache doesn't implement Flusher")
+	}
+	if err := flusher.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Stat() error = %v", err)
+	}
+}
+
+func TestFileCache_LRUOrder(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	c, err := cache.NewFile(path, 0, 0, cache.WithMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = c.Set(ctx, "a", "1")
+	_ = c.Set(ctx, "b", "2")
+	_, _ = c.Get(ctx, "a")
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = cache.NewFile(path, 0, 0, cache.WithMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// "b" is the least recently used one after the restart too
+	_ = c.Set(ctx, "c", "3")
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Get(%q) error = %v, want %v", "b", err, cache.ErrKeyNotFound)
+	}
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Errorf("Get(%q) error = %v", "a", err)
+	}
+}
+
+func TestFileCache_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.NewFile(path, 0, 0); err == nil {
+		t.Error("NewFile() error = nil for invalid file")
+	}
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// getOrSet implements Cache.GetOrSet on top of Get and SetOrFail. Concurrent
+// loads of the same key in the process are deduplicated by the group. If the
+// key was populated by another process in the meantime, the stored value wins,
+// so all callers observe the same value.
+func getOrSet(
+	ctx context.Context,
+	c Cache,
+	group *singleflight.Group,
+	key string,
+	load func() (string, error),
+	opts ...Option,
+) (string, error) {
+	value, err := c.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if !isMiss(err) {
+		return "", err
+	}
+
+	res, err, _ := group.Do(key, func() (any, error) {
+		// the previous flight may have populated the key just now
+		if value, err := c.Get(ctx, key); err == nil {
+			return value, nil
+		} else if !isMiss(err) {
+			return "", err
+		}
+
+		value, err := load()
+		if err != nil {
+			return "", err
+		}
+
+		err = c.SetOrFail(ctx, key, value, opts...)
+		if errors.Is(err, ErrKeyExists) {
+			if stored, err := c.Get(ctx, key); err == nil {
+				return stored, nil
+			}
+			return value, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return value, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return res.(string), nil
+}
+
+func isMiss(err error) bool {
+	return errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrKeyExpired)
+}
@@ -0,0 +1,121 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_GetOrSetDeduplicatesLoads(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func() (string, error) {
+		loads.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			value, err := c.GetOrSet(ctx, "key", load)
+			if err != nil {
+				t.Errorf("GetOrSet failed: %v", err)
+			}
+			results[i] = value
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected 1 load, got %d", n)
+	}
+	for i, value := range results {
+		if value != "value" {
+			t.Errorf("Caller %d got %q", i, value)
+		}
+	}
+
+	if value, err := c.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Expected the value to be stored, got %q, %v", value, err)
+	}
+}
+
+func TestMemoryCache_GetOrSetExisting(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "key", "stored")
+
+	value, err := c.GetOrSet(ctx, "key", func() (string, error) {
+		t.Error("Load must not be called for existing key")
+		return "loaded", nil
+	})
+	if err != nil || value != "stored" {
+		t.Errorf("Expected stored value, got %q, %v", value, err)
+	}
+}
+
+func TestMemoryCache_GetOrSetExpired(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "key", "old", cache.WithTTL(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	value, err := c.GetOrSet(ctx, "key", func() (string, error) {
+		return "new", nil
+	}, cache.WithTTL(time.Minute))
+	if err != nil || value != "new" {
+		t.Errorf("Expected reloaded value, got %q, %v", value, err)
+	}
+}
+
+func TestMemoryCache_GetOrSetLoadError(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	errLoad := errors.New("load failed")
+	if _, err := c.GetOrSet(ctx, "key", func() (string, error) {
+		return "", errLoad
+	}); !errors.Is(err, errLoad) {
+		t.Errorf("Expected load error, got %v", err)
+	}
+
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected nothing to be stored, got %v", err)
+	}
+}
+
+func TestTyped_GetOrSet(t *testing.T) {
+	c := cache.NewTyped[typedValue](cache.NewMemory(0))
+	ctx := context.Background()
+
+	loaded, err := c.GetOrSet(ctx, "key", func() (typedValue, error) {
+		return typedValue{Name: "a", Count: 1}, nil
+	})
+	if err != nil || loaded.Name != "a" || loaded.Count != 1 {
+		t.Fatalf("Unexpected result %+v, %v", loaded, err)
+	}
+
+	stored, err := c.Get(ctx, "key")
+	if err != nil || stored.Name != loaded.Name || stored.Count != loaded.Count {
+		t.Errorf("Expected stored value %+v, got %+v, %v", loaded, stored, err)
+	}
+}
@@ -1,30 +1,107 @@
 package cache
 
 import (
+	"container/list"
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
+// ctxCheckInterval is the number of items between the context checks in the
+// passes over all items.
+const ctxCheckInterval = 1024
+
 type memoryCache struct {
 	items map[string]*memoryItem
 	ttl   time.Duration
 
+	maxEntries int
+	maxBytes   int64
+
+	// lazyExpiration reports the expired items with ErrKeyExpired rather
+	// than ErrKeyNotFound
+	lazyExpiration bool
+
+	// lru orders keys from the most to the least recently used, it's nil if
+	// the cache is unbounded
+	lru       *list.List
+	bytes     int64
+	evictions atomic.Uint64
+
+	// tags maps the tags to the keys of the items they are attached to
+	tags map[string]map[string]struct{}
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	expirations atomic.Uint64
+
+	expireHandlers []ExpireHandler
+	expireMux      sync.RWMutex
+
+	flights singleflight.Group
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
 	mux sync.RWMutex
 }
 
-func NewMemory(ttl time.Duration) Cache {
-	return &memoryCache{
+// NewMemory creates an in-memory cache. Items expire after ttl unless
+// overridden per item, zero ttl means no expiration. The cache is unbounded
+// unless WithMaxEntries or WithMaxBytes is set, in which case the least
+// recently used items are evicted to stay within the limits. If
+// WithJanitorInterval is set, Close must be called to stop the background
+// cleanup.
+func NewMemory(ttl time.Duration, opts ...MemoryOption) Cache {
+	return newMemory(ttl, opts...)
+}
+
+func newMemory(ttl time.Duration, opts ...MemoryOption) *memoryCache {
+	o := memoryOptions{}
+	o.apply(opts...)
+
+	m := &memoryCache{
 		items: make(map[string]*memoryItem),
 		ttl:   ttl,
+		tags:  make(map[string]map[string]struct{}),
+
+		maxEntries: o.maxEntries,
+		maxBytes:   o.maxBytes,
+
+		lazyExpiration: o.lazyExpiration,
 
 		mux: sync.RWMutex{},
 	}
+
+	if m.bounded() {
+		m.lru = list.New()
+	}
+
+	if o.janitorInterval > 0 {
+		m.stop = make(chan struct{})
+		m.done = make(chan struct{})
+		go m.janitor(o.janitorInterval)
+	}
+
+	return m
 }
 
 type memoryItem struct {
 	value      string
 	validUntil time.Time
+	// tags is a map rather than a slice to keep the item small, nil if the
+	// item has no tags
+	tags map[string]struct{}
+
+	size int64
+	elem *list.Element
 }
 
 func newItem(value string, opts options) *memoryItem {
@@ -32,6 +109,12 @@ func newItem(value string, opts options) *memoryItem {
 		value:      value,
 		validUntil: opts.validUntil,
 	}
+	if len(opts.tags) > 0 {
+		item.tags = make(map[string]struct{}, len(opts.tags))
+		for _, tag := range opts.tags {
+			item.tags[tag] = struct{}{}
+		}
+	}
 
 	return item
 }
@@ -40,9 +123,148 @@ func (i *memoryItem) isExpired(now time.Time) bool {
 	return !i.validUntil.IsZero() && now.After(i.validUntil)
 }
 
-// Cleanup implements Cache.
-func (m *memoryCache) Cleanup(_ context.Context) error {
-	m.cleanup(func() {})
+// Stats implements StatsProvider.
+func (m *memoryCache) Stats() Stats {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	return Stats{
+		Entries:     len(m.items),
+		Bytes:       m.bytes,
+		Evictions:   m.evictions.Load(),
+		Hits:        m.hits.Load(),
+		Misses:      m.misses.Load(),
+		Expirations: m.expirations.Load(),
+	}
+}
+
+// OnExpire implements ExpireNotifier. Expired items are removed by Cleanup,
+// Drain and the janitor, so handlers aren't called until then.
+func (m *memoryCache) OnExpire(handler ExpireHandler) {
+	m.expireMux.Lock()
+	defer m.expireMux.Unlock()
+
+	m.expireHandlers = append(m.expireHandlers, handler)
+}
+
+// Cleanup implements Cache. If the context is canceled, the pass stops and
+// the items already removed stay removed.
+func (m *memoryCache) Cleanup(ctx context.Context) error {
+	return m.cleanup(ctx, "", func() {})
+}
+
+// CompareAndSwap implements Cache.
+func (m *memoryCache) CompareAndSwap(_ context.Context, key string, oldValue, newValue string, opts ...Option) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	item, ok := m.items[key]
+	if !ok || item.isExpired(time.Now()) {
+		return ErrKeyNotFound
+	}
+
+	if item.value != oldValue {
+		return ErrValueMismatch
+	}
+
+	m.set(key, m.newItem(newValue, opts...))
+
+	return nil
+}
+
+// Close implements Cache.
+func (m *memoryCache) Close() error {
+	m.closeOnce.Do(func() {
+		if m.stop == nil {
+			return
+		}
+
+		close(m.stop)
+		<-m.done
+	})
+
+	return nil
+}
+
+// Increment implements Cache.
+func (m *memoryCache) Increment(_ context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	var (
+		value      int64
+		validUntil time.Time
+		tags       map[string]struct{}
+	)
+
+	if item, ok := m.items[key]; ok && !item.isExpired(time.Now()) {
+		current, err := strconv.ParseInt(item.value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: not an integer", ErrInvalidValue)
+		}
+		value = current
+		validUntil = item.validUntil
+		tags = item.tags
+	}
+
+	value += delta
+
+	item := m.newItem(strconv.FormatInt(value, 10), opts...)
+	if !validUntil.IsZero() {
+		item.validUntil = validUntil
+	}
+	if len(tags) > 0 {
+		item.tags = tags
+	}
+	m.set(key, item)
+
+	return value, nil
+}
+
+// Decrement implements Cache.
+func (m *memoryCache) Decrement(ctx context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	return m.Increment(ctx, key, -delta, opts...)
+}
+
+// GetTTL implements Cache.
+func (m *memoryCache) GetTTL(_ context.Context, key string) (time.Duration, error) {
+	m.mux.RLock()
+	item, ok := m.items[key]
+	m.mux.RUnlock()
+
+	if !ok {
+		return 0, ErrKeyNotFound
+	}
+
+	if item.validUntil.IsZero() {
+		return NoExpiration, nil
+	}
+
+	ttl := time.Until(item.validUntil)
+	if ttl < 0 {
+		return 0, m.expiredErr()
+	}
+
+	return ttl, nil
+}
+
+// Touch implements Cache.
+func (m *memoryCache) Touch(_ context.Context, key string, ttl time.Duration) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	item, ok := m.items[key]
+	if !ok || item.isExpired(time.Now()) {
+		return ErrKeyNotFound
+	}
+
+	// items are read outside the lock, so the touched one is replaced instead
+	// of being modified in place
+	touched := &memoryItem{value: item.value, tags: item.tags}
+	if ttl > 0 {
+		touched.validUntil = time.Now().Add(ttl)
+	}
+	m.set(key, touched)
 
 	return nil
 }
@@ -50,20 +272,80 @@ func (m *memoryCache) Cleanup(_ context.Context) error {
 // Delete implements Cache.
 func (m *memoryCache) Delete(_ context.Context, key string) error {
 	m.mux.Lock()
-	delete(m.items, key)
+	if item, ok := m.items[key]; ok {
+		m.remove(key, item)
+	}
 	m.mux.Unlock()
 
 	return nil
 }
 
-// Drain implements Cache.
-func (m *memoryCache) Drain(_ context.Context) (map[string]string, error) {
+// InvalidateTag implements Cache.
+func (m *memoryCache) InvalidateTag(_ context.Context, tag string) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	for key := range m.tags[tag] {
+		if item, ok := m.items[key]; ok {
+			m.remove(key, item)
+		}
+	}
+
+	return nil
+}
+
+// Keys implements Cache.
+func (m *memoryCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	match, err := compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	keys := []string{}
+	n := 0
+
+	m.mux.RLock()
+	for key, item := range m.items {
+		if n++; n%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+		}
+
+		if !item.isExpired(now) && match.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	m.mux.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Drain implements Cache. If the context is canceled before the items are
+// taken, the cache is left intact.
+func (m *memoryCache) Drain(ctx context.Context) (map[string]string, error) {
 	var cpy map[string]*memoryItem
 
-	m.cleanup(func() {
+	err := m.cleanup(ctx, "", func() {
 		cpy = m.items
 		m.items = make(map[string]*memoryItem)
+		m.tags = make(map[string]map[string]struct{})
+		m.bytes = 0
+		if m.lru != nil {
+			m.lru.Init()
+		}
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	items := make(map[string]string, len(cpy))
 	for key, item := range cpy {
@@ -73,23 +355,91 @@ func (m *memoryCache) Drain(_ context.Context) (map[string]string, error) {
 	return items, nil
 }
 
+// DrainN implements Cache. If the context is canceled before the items are
+// taken, the cache is left intact.
+func (m *memoryCache) DrainN(ctx context.Context, n int) (map[string]string, error) {
+	if n <= 0 {
+		return map[string]string{}, nil
+	}
+
+	return m.drainPrefix(ctx, "", n)
+}
+
+// drainPrefix implements scopedCache.
+func (m *memoryCache) drainPrefix(ctx context.Context, prefix string, n int) (map[string]string, error) {
+	var items map[string]string
+
+	err := m.cleanup(ctx, prefix, func() {
+		items = make(map[string]string)
+		for key, item := range m.items {
+			if n > 0 && len(items) == n {
+				break
+			}
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			items[key] = item.value
+			m.remove(key, item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// cleanupPrefix implements scopedCache.
+func (m *memoryCache) cleanupPrefix(ctx context.Context, prefix string) error {
+	return m.cleanup(ctx, prefix, func() {})
+}
+
 // Get implements Cache.
 func (m *memoryCache) Get(_ context.Context, key string) (string, error) {
 	return m.getValue(func() (*memoryItem, bool) {
-		m.mux.RLock()
+		if m.lru == nil {
+			m.mux.RLock()
+			item, ok := m.items[key]
+			m.mux.RUnlock()
+
+			return item, ok
+		}
+
+		m.mux.Lock()
 		item, ok := m.items[key]
-		m.mux.RUnlock()
+		if ok {
+			m.lru.MoveToFront(item.elem)
+		}
+		m.mux.Unlock()
 
 		return item, ok
 	})
 }
 
+// GetBytes implements Cache.
+func (m *memoryCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	value, err := m.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(value), nil
+}
+
+// GetOrSet implements Cache.
+func (m *memoryCache) GetOrSet(ctx context.Context, key string, load func() (string, error), opts ...Option) (string, error) {
+	return getOrSet(ctx, m, &m.flights, key, load, opts...)
+}
+
 // GetAndDelete implements Cache.
 func (m *memoryCache) GetAndDelete(_ context.Context, key string) (string, error) {
 	return m.getValue(func() (*memoryItem, bool) {
 		m.mux.Lock()
 		item, ok := m.items[key]
-		delete(m.items, key)
+		if ok {
+			m.remove(key, item)
+		}
 		m.mux.Unlock()
 
 		return item, ok
@@ -99,12 +449,17 @@ func (m *memoryCache) GetAndDelete(_ context.Context, key string) (string, error
 // Set implements Cache.
 func (m *memoryCache) Set(_ context.Context, key string, value string, opts ...Option) error {
 	m.mux.Lock()
-	m.items[key] = m.newItem(value, opts...)
+	m.set(key, m.newItem(value, opts...))
 	m.mux.Unlock()
 
 	return nil
 }
 
+// SetBytes implements Cache.
+func (m *memoryCache) SetBytes(ctx context.Context, key string, value []byte, opts ...Option) error {
+	return m.Set(ctx, key, string(value), opts...)
+}
+
 // SetOrFail implements Cache.
 func (m *memoryCache) SetOrFail(_ context.Context, key string, value string, opts ...Option) error {
 	m.mux.Lock()
@@ -116,7 +471,7 @@ func (m *memoryCache) SetOrFail(_ context.Context, key string, value string, opt
 		}
 	}
 
-	m.items[key] = m.newItem(value, opts...)
+	m.set(key, m.newItem(value, opts...))
 	return nil
 }
 
@@ -132,20 +487,97 @@ func (m *memoryCache) newItem(value string, opts ...Option) *memoryItem {
 	return newItem(value, o)
 }
 
+func (m *memoryCache) bounded() bool {
+	return m.maxEntries > 0 || m.maxBytes > 0
+}
+
+// set stores the item replacing the existing one and evicts the least
+// recently used items if the limits are exceeded. Must be called with the
+// write lock held.
+func (m *memoryCache) set(key string, item *memoryItem) {
+	if old, ok := m.items[key]; ok {
+		m.remove(key, old)
+	}
+
+	item.size = int64(len(key) + len(item.value))
+	m.items[key] = item
+	m.bytes += item.size
+
+	for tag := range item.tags {
+		keys, ok := m.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			m.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	if m.lru == nil {
+		return
+	}
+
+	item.elem = m.lru.PushFront(key)
+	m.evict()
+}
+
+// remove deletes the item. Must be called with the write lock held.
+func (m *memoryCache) remove(key string, item *memoryItem) {
+	delete(m.items, key)
+	m.bytes -= item.size
+
+	for tag := range item.tags {
+		delete(m.tags[tag], key)
+		if len(m.tags[tag]) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+
+	if m.lru != nil && item.elem != nil {
+		m.lru.Remove(item.elem)
+	}
+}
+
+// evict removes the least recently used items until the cache fits the
+// limits. Must be called with the write lock held.
+func (m *memoryCache) evict() {
+	for (m.maxEntries > 0 && len(m.items) > m.maxEntries) || (m.maxBytes > 0 && m.bytes > m.maxBytes) {
+		back := m.lru.Back()
+		if back == nil {
+			return
+		}
+
+		key := back.Value.(string)
+		m.remove(key, m.items[key])
+		m.evictions.Add(1)
+	}
+}
+
 func (m *memoryCache) getItem(getter func() (*memoryItem, bool)) (*memoryItem, error) {
 	item, ok := getter()
 
 	if !ok {
+		m.misses.Add(1)
 		return nil, ErrKeyNotFound
 	}
 
 	if item.isExpired(time.Now()) {
-		return nil, ErrKeyExpired
+		m.misses.Add(1)
+		return nil, m.expiredErr()
 	}
 
+	m.hits.Add(1)
 	return item, nil
 }
 
+// expiredErr returns the error reporting an expired item.
+func (m *memoryCache) expiredErr() error {
+	if m.lazyExpiration {
+		return ErrKeyExpired
+	}
+
+	return ErrKeyNotFound
+}
+
 func (m *memoryCache) getValue(getter func() (*memoryItem, bool)) (string, error) {
 	item, err := m.getItem(getter)
 	if err != nil {
@@ -155,16 +587,73 @@ func (m *memoryCache) getValue(getter func() (*memoryItem, bool)) (string, error
 	return item.value, nil
 }
 
-func (m *memoryCache) cleanup(cb func()) {
+func (m *memoryCache) janitor(interval time.Duration) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			_ = m.cleanup(context.Background(), "", func() {})
+		}
+	}
+}
+
+// cleanup removes the expired items with the key prefix and calls cb under
+// the lock. If the context is canceled, it stops without calling cb and
+// returns the context error, the handlers are still called for the removed
+// items.
+func (m *memoryCache) cleanup(ctx context.Context, prefix string, cb func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	t := time.Now()
 
+	m.expireMux.RLock()
+	defer m.expireMux.RUnlock()
+
+	expired := map[string]string{}
+
+	var err error
+	n := 0
+
 	m.mux.Lock()
 	for key, item := range m.items {
-		if item.isExpired(t) {
-			delete(m.items, key)
+		if n++; n%ctxCheckInterval == 0 {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+		}
+
+		if item.isExpired(t) && strings.HasPrefix(key, prefix) {
+			m.remove(key, item)
+			m.expirations.Add(1)
+
+			if len(m.expireHandlers) > 0 {
+				expired[key] = item.value
+			}
 		}
 	}
 
-	cb()
+	if err == nil {
+		cb()
+	}
 	m.mux.Unlock()
+
+	for key, value := range expired {
+		for _, h := range m.expireHandlers {
+			h(key, value)
+		}
+	}
+
+	return err
 }
+
+var _ StatsProvider = (*memoryCache)(nil)
+var _ ExpireNotifier = (*memoryCache)(nil)
+var _ scopedCache = (*memoryCache)(nil)
@@ -0,0 +1,58 @@
+package cache_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_Bytes(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	// not valid UTF-8, with zero bytes
+	value := []byte{0x00, 0xff, 0xfe, 0x80, 0x00, 'a'}
+
+	if err := c.SetBytes(ctx, "blob", value); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.GetBytes(ctx, "blob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Errorf("GetBytes() = %v, want %v", got, value)
+	}
+
+	// the stored value isn't shared with the caller
+	value[0] = 0x01
+	got[1] = 0x01
+	if got, _ := c.GetBytes(ctx, "blob"); got[0] != 0x00 || got[1] != 0xff {
+		t.Errorf("GetBytes() = %v, stored value was modified", got)
+	}
+
+	if str, _ := c.Get(ctx, "blob"); str != "\x00\xff\xfe\x80\x00a" {
+		t.Errorf("Get() = %q, want the same bytes", str)
+	}
+}
+
+func TestMemoryCache_BytesMissing(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	if _, err := c.GetBytes(ctx, "missing"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("GetBytes() error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+
+	_ = c.SetBytes(ctx, "expired", []byte("value"), cache.WithTTL(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := c.GetBytes(ctx, "expired"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("GetBytes() error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,70 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_CompareAndSwap(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	if err := c.CompareAndSwap(ctx, "key", "a", "b"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	_ = c.Set(ctx, "key", "a")
+
+	if err := c.CompareAndSwap(ctx, "key", "x", "b"); !errors.Is(err, cache.ErrValueMismatch) {
+		t.Errorf("Expected ErrValueMismatch, got %v", err)
+	}
+	if value, _ := c.Get(ctx, "key"); value != "a" {
+		t.Errorf("Expected the value to be kept, got %q", value)
+	}
+
+	if err := c.CompareAndSwap(ctx, "key", "a", "b", cache.WithTTL(10*time.Millisecond)); err != nil {
+		t.Fatalf("CompareAndSwap failed: %v", err)
+	}
+	if value, _ := c.Get(ctx, "key"); value != "b" {
+		t.Errorf("Expected the swapped value, got %q", value)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := c.CompareAndSwap(ctx, "key", "b", "c"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound for expired key, got %v", err)
+	}
+}
+
+func TestMemoryCache_CompareAndSwapConcurrent(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "leader", "")
+
+	const candidates = 50
+	var (
+		wg      sync.WaitGroup
+		winners atomic.Int32
+	)
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if err := c.CompareAndSwap(ctx, "leader", "", string(rune('A'+i))); err == nil {
+				winners.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := winners.Load(); n != 1 {
+		t.Errorf("Expected exactly 1 winner, got %d", n)
+	}
+}
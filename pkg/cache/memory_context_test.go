@@ -0,0 +1,117 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+// cancelAfter is a context canceled after its error is checked n times.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+
+	return nil
+}
+
+func fillExpired(t *testing.T, c cache.Cache, count int) {
+	t.Helper()
+
+	for i := range count {
+		if err := c.Set(context.Background(), strconv.Itoa(i), "value", cache.WithTTL(time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+}
+
+func TestMemoryCache_CanceledContext(t *testing.T) {
+	c := cache.NewMemory(0)
+	fillExpired(t, c, 10)
+	_ = c.Set(context.Background(), "key", "value")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := c.Cleanup(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Cleanup() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := c.Drain(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Drain() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := c.DrainN(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("DrainN() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := c.Keys(ctx, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Keys() error = %v, want %v", err, context.Canceled)
+	}
+
+	if entries := c.(cache.StatsProvider).Stats().Entries; entries != 11 {
+		t.Errorf("Entries = %d, want all 11 items kept", entries)
+	}
+}
+
+func TestMemoryCache_CleanupCanceledMidway(t *testing.T) {
+	const count = 5000
+
+	c := cache.NewMemory(0)
+	fillExpired(t, c, count)
+
+	expired := 0
+	c.(cache.ExpireNotifier).OnExpire(func(_, _ string) {
+		expired++
+	})
+
+	// the first check passes, the next one in the loop cancels the pass
+	err := c.Cleanup(&cancelAfter{Context: context.Background(), n: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Cleanup() error = %v, want %v", err, context.Canceled)
+	}
+
+	entries := c.(cache.StatsProvider).Stats().Entries
+	if entries == 0 || entries == count {
+		t.Errorf("Entries = %d, want partially cleaned up", entries)
+	}
+	if expired != count-entries {
+		t.Errorf("expire handler called %d times, want %d", expired, count-entries)
+	}
+
+	if err := c.Cleanup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if entries := c.(cache.StatsProvider).Stats().Entries; entries != 0 {
+		t.Errorf("Entries after Cleanup() = %d, want 0", entries)
+	}
+}
+
+func TestMemoryCache_DrainCanceledMidway(t *testing.T) {
+	const count = 5000
+
+	c := cache.NewMemory(0)
+	for i := range count {
+		_ = c.Set(context.Background(), strconv.Itoa(i), "value")
+	}
+
+	if _, err := c.Drain(&cancelAfter{Context: context.Background(), n: 1}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Drain() error = %v, want %v", err, context.Canceled)
+	}
+
+	items, err := c.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != count {
+		t.Errorf("Drain() returned %d items, want all %d kept", len(items), count)
+	}
+}
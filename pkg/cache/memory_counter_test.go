@@ -0,0 +1,72 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_IncrementDecrement(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	if v, err := c.Increment(ctx, "counter", 5); err != nil || v != 5 {
+		t.Fatalf("Expected 5, got %d, %v", v, err)
+	}
+	if v, err := c.Decrement(ctx, "counter", 2); err != nil || v != 3 {
+		t.Fatalf("Expected 3, got %d, %v", v, err)
+	}
+	if v, err := c.Get(ctx, "counter"); err != nil || v != "3" {
+		t.Errorf("Expected stored value 3, got %q, %v", v, err)
+	}
+}
+
+func TestMemoryCache_IncrementKeepsExpiration(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	_, _ = c.Increment(ctx, "counter", 1, cache.WithTTL(30*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	// must not extend the window
+	_, _ = c.Increment(ctx, "counter", 1, cache.WithTTL(30*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	if v, err := c.Increment(ctx, "counter", 1); err != nil || v != 1 {
+		t.Errorf("Expected counter to restart after expiration, got %d, %v", v, err)
+	}
+}
+
+func TestMemoryCache_IncrementInvalidValue(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "key", "text")
+
+	if _, err := c.Increment(ctx, "key", 1); !errors.Is(err, cache.ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue, got %v", err)
+	}
+}
+
+func TestMemoryCache_ConcurrentIncrement(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	const goroutines = 50
+	var wg sync.WaitGroup
+	for range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = c.Increment(ctx, "counter", 1)
+		}()
+	}
+	wg.Wait()
+
+	if v, err := c.Get(ctx, "counter"); err != nil || v != "50" {
+		t.Errorf("Expected 50, got %q, %v", v, err)
+	}
+}
@@ -0,0 +1,100 @@
+package cache_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_DrainN(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+
+	for i := range 5 {
+		if err := c.Set(ctx, strconv.Itoa(i), "value-"+strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = c.Set(ctx, "expired", "value", cache.WithTTL(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	drained := map[string]string{}
+	for _, want := range []int{2, 2, 1, 0} {
+		items, err := c.DrainN(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != want {
+			t.Fatalf("DrainN() returned %d items, want %d", len(items), want)
+		}
+
+		for key, value := range items {
+			if _, ok := drained[key]; ok {
+				t.Errorf("DrainN() returned %q twice", key)
+			}
+			drained[key] = value
+		}
+	}
+
+	for i := range 5 {
+		key := strconv.Itoa(i)
+		if drained[key] != "value-"+key {
+			t.Errorf("drained[%q] = %q, want %q", key, drained[key], "value-"+key)
+		}
+	}
+	if _, ok := drained["expired"]; ok {
+		t.Error("DrainN() returned the expired item")
+	}
+}
+
+func TestMemoryCache_DrainNNonPositive(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+	_ = c.Set(ctx, "key", "value")
+
+	for _, n := range []int{0, -1} {
+		items, err := c.DrainN(ctx, n)
+		if err != nil || len(items) != 0 {
+			t.Errorf("DrainN(%d) = %v, %v, want empty", n, items, err)
+		}
+	}
+
+	if _, err := c.Get(ctx, "key"); err != nil {
+		t.Errorf("Get() after DrainN(0) error = %v", err)
+	}
+}
+
+func TestTyped_DrainN(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewTyped[int](cache.NewMemory(0))
+
+	for i := range 3 {
+		if err := c.Set(ctx, strconv.Itoa(i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := c.DrainN(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("DrainN() returned %d items, want 2", len(items))
+	}
+	for key, value := range items {
+		if key != strconv.Itoa(value) {
+			t.Errorf("items[%q] = %d", key, value)
+		}
+	}
+
+	rest, err := c.Drain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rest) != 1 {
+		t.Errorf("Drain() after DrainN() returned %d items, want 1", len(rest))
+	}
+}
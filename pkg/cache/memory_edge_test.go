@@ -55,8 +55,8 @@ func TestMemoryCache_ImmediateExpiration(t *testing.T) {
 	time.Sleep(2 * ttl)
 
 	_, err = c.Get(ctx, key)
-	if err != cache.ErrKeyExpired {
-		t.Errorf("Expected ErrKeyExpired, got %v", err)
+	if err != cache.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
 	}
 }
 
@@ -184,8 +184,8 @@ func TestMemoryCache_MixedTTLScenarios(t *testing.T) {
 
 	// Short TTL key should be expired, others should still be there
 	_, err := c.Get(ctx, "short-ttl")
-	if err != cache.ErrKeyExpired {
-		t.Errorf("Expected ErrKeyExpired for short-ttl, got %v", err)
+	if err != cache.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound for short-ttl, got %v", err)
 	}
 
 	for key := range keys {
@@ -203,8 +203,8 @@ func TestMemoryCache_MixedTTLScenarios(t *testing.T) {
 
 	// Medium TTL key should be expired, others should still be there
 	_, err = c.Get(ctx, "medium-ttl")
-	if err != cache.ErrKeyExpired {
-		t.Errorf("Expected ErrKeyExpired for medium-ttl, got %v", err)
+	if err != cache.ErrKeyNotFound {
+		t.Errorf("Expected ErrKeyNotFound for medium-ttl, got %v", err)
 	}
 
 	for key := range keys {
@@ -0,0 +1,93 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_MaxEntriesEvictsLeastRecentlyUsed(t *testing.T) {
+	c := cache.NewMemory(0, cache.WithMaxEntries(2))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", "1")
+	_ = c.Set(ctx, "b", "2")
+
+	// touch "a" so "b" becomes the least recently used
+	if _, err := c.Get(ctx, "a"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	_ = c.Set(ctx, "c", "3")
+
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected b to be evicted, got %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("Expected %s to be kept, got %v", key, err)
+		}
+	}
+
+	stats := c.(cache.StatsProvider).Stats()
+	if stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestMemoryCache_MaxBytes(t *testing.T) {
+	c := cache.NewMemory(0, cache.WithMaxBytes(10))
+	ctx := context.Background()
+
+	for i := range 5 {
+		// 2 bytes per item
+		_ = c.Set(ctx, strconv.Itoa(i), "v")
+	}
+	_ = c.Set(ctx, "big", "1234567")
+
+	stats := c.(cache.StatsProvider).Stats()
+	if stats.Bytes > 10 {
+		t.Errorf("Expected at most 10 bytes, got %d", stats.Bytes)
+	}
+	if stats.Evictions != 5 {
+		t.Errorf("Expected 5 evictions, got %d", stats.Evictions)
+	}
+	if v, err := c.Get(ctx, "big"); err != nil || v != "1234567" {
+		t.Errorf("Expected big item to be kept, got %q, %v", v, err)
+	}
+}
+
+func TestMemoryCache_BoundedOverwriteAndDelete(t *testing.T) {
+	c := cache.NewMemory(0, cache.WithMaxEntries(2))
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "a", "1")
+	_ = c.Set(ctx, "a", "22")
+	_ = c.Set(ctx, "b", "2")
+
+	if v, err := c.Get(ctx, "a"); err != nil || v != "22" {
+		t.Errorf("Expected overwritten value, got %q, %v", v, err)
+	}
+
+	_ = c.Delete(ctx, "a")
+	if _, err := c.GetAndDelete(ctx, "b"); err != nil {
+		t.Errorf("GetAndDelete failed: %v", err)
+	}
+
+	stats := c.(cache.StatsProvider).Stats()
+	if stats.Entries != 0 || stats.Bytes != 0 || stats.Evictions != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	_ = c.Set(ctx, "c", "3")
+	items, _ := c.Drain(ctx)
+	if len(items) != 1 {
+		t.Errorf("Expected 1 drained item, got %d", len(items))
+	}
+	if stats := c.(cache.StatsProvider).Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Unexpected stats after drain: %+v", stats)
+	}
+}
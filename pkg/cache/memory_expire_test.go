@@ -0,0 +1,62 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_OnExpire(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	mux := sync.Mutex{}
+	expired := map[string]string{}
+	c.(cache.ExpireNotifier).OnExpire(func(key, value string) {
+		mux.Lock()
+		expired[key] = value
+		mux.Unlock()
+	})
+
+	_ = c.Set(ctx, "short", "value", cache.WithTTL(time.Millisecond))
+	_ = c.Set(ctx, "forever", "value")
+	_ = c.Set(ctx, "deleted", "value", cache.WithTTL(time.Millisecond))
+	_ = c.Delete(ctx, "deleted")
+
+	time.Sleep(5 * time.Millisecond)
+
+	if err := c.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup failed: %v", err)
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+
+	if len(expired) != 1 || expired["short"] != "value" {
+		t.Errorf("Expected only the short item to be reported, got %v", expired)
+	}
+}
+
+func TestMemoryCache_OnExpireJanitor(t *testing.T) {
+	c := cache.NewMemory(0, cache.WithJanitorInterval(5*time.Millisecond))
+	defer c.Close()
+
+	done := make(chan string, 1)
+	c.(cache.ExpireNotifier).OnExpire(func(key, _ string) {
+		done <- key
+	})
+
+	_ = c.Set(context.Background(), "short", "value", cache.WithTTL(time.Millisecond))
+
+	select {
+	case key := <-done:
+		if key != "short" {
+			t.Errorf("Expected short to expire, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the janitor to report the expired item")
+	}
+}
@@ -0,0 +1,56 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_JanitorRemovesExpired(t *testing.T) {
+	c := cache.NewMemory(0, cache.WithJanitorInterval(5*time.Millisecond))
+	defer c.Close()
+
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "short", "value", cache.WithTTL(10*time.Millisecond))
+	_ = c.Set(ctx, "long", "value")
+
+	time.Sleep(50 * time.Millisecond)
+
+	stats := c.(cache.StatsProvider).Stats()
+	if stats.Entries != 1 {
+		t.Errorf("Expected 1 entry after cleanup, got %d", stats.Entries)
+	}
+	if _, err := c.Get(ctx, "long"); err != nil {
+		t.Errorf("Expected long-lived item to be kept, got %v", err)
+	}
+}
+
+func TestMemoryCache_CloseStopsJanitor(t *testing.T) {
+	c := cache.NewMemory(0, cache.WithJanitorInterval(5*time.Millisecond))
+	ctx := context.Background()
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Second Close failed: %v", err)
+	}
+
+	_ = c.Set(ctx, "short", "value", cache.WithTTL(time.Millisecond))
+	time.Sleep(30 * time.Millisecond)
+
+	if stats := c.(cache.StatsProvider).Stats(); stats.Entries != 1 {
+		t.Errorf("Expected expired item to be kept after Close, got %d entries", stats.Entries)
+	}
+}
+
+func TestMemoryCache_CloseWithoutJanitor(t *testing.T) {
+	c := cache.NewMemory(0)
+
+	if err := c.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
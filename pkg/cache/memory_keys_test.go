@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_Keys(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	for _, key := range []string{"online:dev1", "online:dev2", "online:dev/3", "offline:dev1", "a*b", "a-b"} {
+		_ = c.Set(ctx, key, "value")
+	}
+	_ = c.Set(ctx, "online:expired", "value", cache.WithTTL(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"", []string{"online:dev1", "online:dev2", "online:dev/3", "offline:dev1", "a*b", "a-b"}},
+		{"online:*", []string{"online:dev1", "online:dev2", "online:dev/3"}},
+		{"*:dev?", []string{"online:dev1", "online:dev2", "offline:dev1"}},
+		{"online:dev[12]", []string{"online:dev1", "online:dev2"}},
+		{"online:dev[^1]", []string{"online:dev2"}},
+		{"online:dev[1-2]", []string{"online:dev1", "online:dev2"}},
+		{`a\*b`, []string{"a*b"}},
+		{"a[-*]b", []string{"a*b", "a-b"}},
+		{"missing*", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			keys, err := c.Keys(ctx, tt.pattern)
+			if err != nil {
+				t.Fatalf("Keys(%q) returned error: %v", tt.pattern, err)
+			}
+
+			slices.Sort(keys)
+			slices.Sort(tt.want)
+			if !slices.Equal(keys, tt.want) {
+				t.Errorf("Keys(%q) = %v, want %v", tt.pattern, keys, tt.want)
+			}
+		})
+	}
+
+	// the cache is left intact
+	if _, err := c.Get(ctx, "online:dev1"); err != nil {
+		t.Errorf("Expected item to be kept, got %v", err)
+	}
+}
+
+func TestMemoryCache_KeysInvalidPattern(t *testing.T) {
+	c := cache.NewMemory(0)
+
+	for _, pattern := range []string{"online:[dev", "[z-a]"} {
+		if _, err := c.Keys(context.Background(), pattern); !errors.Is(err, cache.ErrInvalidPattern) {
+			t.Errorf("Keys(%q) expected ErrInvalidPattern, got %v", pattern, err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_StatsLookups(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "key", "value")
+	_ = c.Set(ctx, "expired", "value", cache.WithTTL(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+
+	_, _ = c.Get(ctx, "key")
+	_, _ = c.Get(ctx, "key")
+	_, _ = c.Get(ctx, "missing")
+	_, _ = c.Get(ctx, "expired")
+	_, _ = c.GetAndDelete(ctx, "key")
+
+	stats := c.(cache.StatsProvider).Stats()
+	if stats.Hits != 3 || stats.Misses != 2 {
+		t.Errorf("Expected 3 hits and 2 misses, got %d and %d", stats.Hits, stats.Misses)
+	}
+	if stats.Expirations != 0 {
+		t.Errorf("Expected no expirations before cleanup, got %d", stats.Expirations)
+	}
+
+	_ = c.Cleanup(ctx)
+
+	stats = c.(cache.StatsProvider).Stats()
+	if stats.Expirations != 1 || stats.Entries != 0 {
+		t.Errorf("Expected 1 expiration and no entries, got %d and %d", stats.Expirations, stats.Entries)
+	}
+}
@@ -0,0 +1,108 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_InvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+
+	_ = c.Set(ctx, "a", "1", cache.WithTags("user:1", "devices"))
+	_ = c.Set(ctx, "b", "2", cache.WithTags("user:2", "devices"))
+	_ = c.Set(ctx, "c", "3", cache.WithTags("user:1"))
+	_ = c.Set(ctx, "d", "4")
+
+	if err := c.InvalidateTag(ctx, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, cache.ErrKeyNotFound) {
+			t.Errorf("Get(%q) error = %v, want %v", key, err, cache.ErrKeyNotFound)
+		}
+	}
+	for _, key := range []string{"b", "d"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("Get(%q) error = %v", key, err)
+		}
+	}
+
+	if err := c.InvalidateTag(ctx, "devices"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Get(%q) error = %v, want %v", "b", err, cache.ErrKeyNotFound)
+	}
+
+	if err := c.InvalidateTag(ctx, "unknown"); err != nil {
+		t.Errorf("InvalidateTag() of unknown tag error = %v", err)
+	}
+}
+
+func TestMemoryCache_TagsReplacedOnWrite(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+
+	_ = c.Set(ctx, "key", "1", cache.WithTags("old"))
+	_ = c.Set(ctx, "key", "2", cache.WithTags("new"))
+
+	_ = c.InvalidateTag(ctx, "old")
+	if value, err := c.Get(ctx, "key"); err != nil || value != "2" {
+		t.Fatalf("Get() = %q, %v after invalidation of the replaced tag", value, err)
+	}
+
+	_ = c.Set(ctx, "key", "3")
+	_ = c.InvalidateTag(ctx, "new")
+	if _, err := c.Get(ctx, "key"); err != nil {
+		t.Errorf("Get() of untagged item error = %v", err)
+	}
+}
+
+func TestMemoryCache_TagsKept(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+
+	_ = c.Set(ctx, "touched", "value", cache.WithTags("tag"))
+	if err := c.Touch(ctx, "touched", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Increment(ctx, "counter", 1, cache.WithTags("tag")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Increment(ctx, "counter", 1, cache.WithTags("other")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.InvalidateTag(ctx, "tag"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"touched", "counter"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, cache.ErrKeyNotFound) {
+			t.Errorf("Get(%q) error = %v, want %v", key, err, cache.ErrKeyNotFound)
+		}
+	}
+}
+
+func TestMemoryCache_TagsDrained(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+
+	_ = c.Set(ctx, "key", "1", cache.WithTags("tag"))
+	if _, err := c.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the drained item must not be tracked by the tag anymore
+	_ = c.Set(ctx, "key", "2")
+	_ = c.InvalidateTag(ctx, "tag")
+	if _, err := c.Get(ctx, "key"); err != nil {
+		t.Errorf("Get() error = %v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+func TestMemoryCache_GetTTL(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	if _, err := c.GetTTL(ctx, "missing"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	_ = c.Set(ctx, "forever", "value")
+	if ttl, err := c.GetTTL(ctx, "forever"); err != nil || ttl != cache.NoExpiration {
+		t.Errorf("Expected NoExpiration, got %v, %v", ttl, err)
+	}
+
+	_ = c.Set(ctx, "key", "value", cache.WithTTL(time.Minute))
+	ttl, err := c.GetTTL(ctx, "key")
+	if err != nil {
+		t.Fatalf("GetTTL failed: %v", err)
+	}
+	if ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected TTL within a minute, got %v", ttl)
+	}
+
+	_ = c.Set(ctx, "expired", "value", cache.WithTTL(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.GetTTL(ctx, "expired"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestMemoryCache_Touch(t *testing.T) {
+	c := cache.NewMemory(0)
+	ctx := context.Background()
+
+	if err := c.Touch(ctx, "missing", time.Minute); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	_ = c.Set(ctx, "key", "value", cache.WithTTL(20*time.Millisecond))
+	if err := c.Touch(ctx, "key", time.Minute); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if value, err := c.Get(ctx, "key"); err != nil || value != "value" {
+		t.Errorf("Expected the touched item to be kept, got %q, %v", value, err)
+	}
+	if ttl, _ := c.GetTTL(ctx, "key"); ttl <= 30*time.Second {
+		t.Errorf("Expected the extended TTL, got %v", ttl)
+	}
+
+	if err := c.Touch(ctx, "key", 0); err != nil {
+		t.Fatalf("Touch failed: %v", err)
+	}
+	if ttl, err := c.GetTTL(ctx, "key"); err != nil || ttl != cache.NoExpiration {
+		t.Errorf("Expected NoExpiration, got %v, %v", ttl, err)
+	}
+
+	_ = c.Set(ctx, "expired", "value", cache.WithTTL(10*time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	if err := c.Touch(ctx, "expired", time.Minute); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestMemoryCache_DefaultExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0)
+
+	for _, key := range []string{"get", "bytes", "ttl", "take"} {
+		_ = c.Set(ctx, key, "value", cache.WithTTL(time.Millisecond))
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// the expired items are reported as missing before they are removed
+	_, getErr := c.Get(ctx, "get")
+	_, bytesErr := c.GetBytes(ctx, "bytes")
+	_, ttlErr := c.GetTTL(ctx, "ttl")
+	_, takeErr := c.GetAndDelete(ctx, "take")
+	for _, err := range []error{getErr, bytesErr, ttlErr, takeErr} {
+		if err != cache.ErrKeyNotFound {
+			t.Errorf("error = %v, want %v", err, cache.ErrKeyNotFound)
+		}
+	}
+}
+
+func TestMemoryCache_LazyExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory(0, cache.WithLazyExpiration())
+
+	for _, key := range []string{"get", "ttl", "take"} {
+		_ = c.Set(ctx, key, "value", cache.WithTTL(time.Millisecond))
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	_, getErr := c.Get(ctx, "get")
+	_, ttlErr := c.GetTTL(ctx, "ttl")
+	_, takeErr := c.GetAndDelete(ctx, "take")
+	for _, err := range []error{getErr, ttlErr, takeErr} {
+		if err != cache.ErrKeyExpired {
+			t.Errorf("error = %v, want %v", err, cache.ErrKeyExpired)
+		}
+		if !errors.Is(err, cache.ErrKeyNotFound) {
+			t.Errorf("errors.Is(%v, ErrKeyNotFound) = false", err)
+		}
+	}
+
+	if err := c.Cleanup(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "get"); err != cache.ErrKeyNotFound {
+		t.Errorf("Get() after Cleanup() error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+}
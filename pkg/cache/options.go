@@ -7,6 +7,7 @@ type Option func(*options)
 
 type options struct {
 	validUntil time.Time
+	tags       []string
 }
 
 func (o *options) apply(opts ...Option) *options {
@@ -36,3 +37,120 @@ func WithValidUntil(validUntil time.Time) Option {
 		o.validUntil = validUntil
 	}
 }
+
+// WithTags is an Option that attaches the tags to an item, so it's removed by
+// InvalidateTag of any of them. Every write of the item replaces its tags,
+// except Touch, which keeps them, and Increment and Decrement, which attach
+// the tags only to an item without tags.
+func WithTags(tags ...string) Option {
+	return func(o *options) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// MemoryOption configures the memory cache.
+type MemoryOption func(*memoryOptions)
+
+type memoryOptions struct {
+	maxEntries      int
+	maxBytes        int64
+	janitorInterval time.Duration
+	lazyExpiration  bool
+}
+
+func (o *memoryOptions) apply(opts ...MemoryOption) *memoryOptions {
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithMaxEntries limits the number of items in the memory cache. The least
+// recently used items are evicted when the limit is exceeded. Zero means no
+// limit.
+func WithMaxEntries(n int) MemoryOption {
+	return func(o *memoryOptions) {
+		o.maxEntries = max(n, 0)
+	}
+}
+
+// WithMaxBytes limits the total size of keys and values in the memory cache.
+// The least recently used items are evicted when the limit is exceeded. Zero
+// means no limit.
+func WithMaxBytes(n int64) MemoryOption {
+	return func(o *memoryOptions) {
+		o.maxBytes = max(n, 0)
+	}
+}
+
+// WithJanitorInterval starts a background goroutine removing expired items
+// from the memory cache at the given interval. The goroutine is stopped by
+// Close. Zero disables the janitor, so expired items are removed only by
+// Cleanup and Drain.
+func WithJanitorInterval(d time.Duration) MemoryOption {
+	return func(o *memoryOptions) {
+		o.janitorInterval = max(d, 0)
+	}
+}
+
+// WithLazyExpiration makes the memory cache report the expired items with
+// ErrKeyExpired until they are removed by Cleanup, Drain or the janitor. By
+// default the expired items are reported as missing with ErrKeyNotFound.
+func WithLazyExpiration() MemoryOption {
+	return func(o *memoryOptions) {
+		o.lazyExpiration = true
+	}
+}
+
+// RedisOption configures the Redis cache.
+type RedisOption func(*redisOptions)
+
+type redisOptions struct {
+	bufferSize     int
+	bufferInterval time.Duration
+	bufferLimit    int
+	lazyExpiration bool
+}
+
+func (o *redisOptions) apply(opts ...RedisOption) *redisOptions {
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithWriteBuffer enables the write-behind buffer of the Redis cache. Set
+// calls are kept in memory, coalesced per key, and written in a single
+// pipeline when the buffer holds size items or every interval, zero disables
+// the corresponding trigger. Other operations flush the buffer first, so they
+// observe the buffered writes. Buffered items are lost if the process exits
+// without Close or Flush.
+func WithWriteBuffer(size int, interval time.Duration) RedisOption {
+	return func(o *redisOptions) {
+		o.bufferSize = max(size, 0)
+		o.bufferInterval = max(interval, 0)
+	}
+}
+
+// WithWriteBufferLimit caps the items kept by the write buffer while the
+// flushes fail, redisBufferLimit by default and never less than the buffer
+// size. At the limit Set writes the item directly after flushing the buffer
+// and fails if it can't be flushed, the failed batch items exceeding the limit
+// are dropped.
+func WithWriteBufferLimit(limit int) RedisOption {
+	return func(o *redisOptions) {
+		o.bufferLimit = max(limit, 0)
+	}
+}
+
+// WithRedisLazyExpiration is WithLazyExpiration for the Redis cache. Redis
+// removes the expired items on its own, so they are recognized by the expiry
+// index and reported with ErrKeyExpired until Cleanup removes the index
+// entries.
+func WithRedisLazyExpiration() RedisOption {
+	return func(o *redisOptions) {
+		o.lazyExpiration = true
+	}
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// compilePattern converts the glob-style pattern of Keys to a regular
+// expression. It follows the Redis syntax: `*` matches any sequence, `?` any
+// single character, `[...]` a character class with optional `^` negation and
+// ranges, `\` escapes the next character. Empty pattern matches all keys.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	runes := []rune(pattern)
+
+	b := strings.Builder{}
+	b.WriteString(`^(?s:`)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end := classEnd(runes, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated character class", ErrInvalidPattern)
+			}
+
+			start := i + 1
+			b.WriteByte('[')
+			if runes[start] == '^' {
+				b.WriteByte('^')
+				start++
+			}
+			for j := start; j < end; j++ {
+				switch c := runes[j]; {
+				case c == '\\' && j+1 < end:
+					j++
+					writeClassRune(&b, runes[j])
+				case c == '-' && j > start && j+1 < end:
+					b.WriteByte('-')
+				default:
+					writeClassRune(&b, c)
+				}
+			}
+			b.WriteByte(']')
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`)$`)
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPattern, err)
+	}
+
+	return re, nil
+}
+
+// EscapePattern escapes the special characters of the pattern syntax, so the
+// string is matched literally.
+func EscapePattern(s string) string {
+	b := strings.Builder{}
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// classEnd returns the index of the bracket closing the character class that
+// starts at i, or -1 if there is none.
+func classEnd(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++
+		case ']':
+			return i
+		}
+	}
+
+	return -1
+}
+
+// writeClassRune writes the literal rune of a character class, escaping ASCII
+// non-word characters which may have a special meaning in the class.
+func writeClassRune(b *strings.Builder, c rune) {
+	if c <= unicode.MaxASCII && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+		b.WriteByte('\\')
+	}
+	b.WriteRune(c)
+}
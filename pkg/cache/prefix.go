@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// scopedCache is implemented by the backends that drain and clean up the
+// items of a key prefix on their own.
+type scopedCache interface {
+	// drainPrefix removes and returns the non-expired items with the key
+	// prefix, at most n items if n is positive. The keys keep the prefix.
+	drainPrefix(ctx context.Context, prefix string, n int) (map[string]string, error)
+	// cleanupPrefix removes the expired items with the key prefix.
+	cleanupPrefix(ctx context.Context, prefix string) error
+}
+
+type prefixedCache struct {
+	parent Cache
+	prefix string
+}
+
+// WithPrefix returns the view of the cache that prepends the prefix to all
+// the keys, so several namespaces share one cache without conflicts. Keys and
+// Drain return the keys without the prefix, Drain, DrainN and Cleanup affect
+// only the items of the namespace.
+//
+// Close of the view is a no-op, the parent cache owns the resources.
+func WithPrefix(c Cache, prefix string) Cache {
+	if p, ok := c.(*prefixedCache); ok {
+		return &prefixedCache{parent: p.parent, prefix: p.prefix + prefix}
+	}
+
+	return &prefixedCache{parent: c, prefix: prefix}
+}
+
+// Set implements Cache.
+func (p *prefixedCache) Set(ctx context.Context, key string, value string, opts ...Option) error {
+	return p.parent.Set(ctx, p.prefix+key, value, p.options(opts)...)
+}
+
+// SetOrFail implements Cache.
+func (p *prefixedCache) SetOrFail(ctx context.Context, key string, value string, opts ...Option) error {
+	return p.parent.SetOrFail(ctx, p.prefix+key, value, p.options(opts)...)
+}
+
+// Get implements Cache.
+func (p *prefixedCache) Get(ctx context.Context, key string) (string, error) {
+	return p.parent.Get(ctx, p.prefix+key)
+}
+
+// SetBytes implements Cache.
+func (p *prefixedCache) SetBytes(ctx context.Context, key string, value []byte, opts ...Option) error {
+	return p.parent.SetBytes(ctx, p.prefix+key, value, p.options(opts)...)
+}
+
+// GetBytes implements Cache.
+func (p *prefixedCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	return p.parent.GetBytes(ctx, p.prefix+key)
+}
+
+// CompareAndSwap implements Cache.
+func (p *prefixedCache) CompareAndSwap(ctx context.Context, key string, oldValue, newValue string, opts ...Option) error {
+	return p.parent.CompareAndSwap(ctx, p.prefix+key, oldValue, newValue, p.options(opts)...)
+}
+
+// GetOrSet implements Cache.
+func (p *prefixedCache) GetOrSet(ctx context.Context, key string, load func() (string, error), opts ...Option) (string, error) {
+	return p.parent.GetOrSet(ctx, p.prefix+key, load, p.options(opts)...)
+}
+
+// GetAndDelete implements Cache.
+func (p *prefixedCache) GetAndDelete(ctx context.Context, key string) (string, error) {
+	return p.parent.GetAndDelete(ctx, p.prefix+key)
+}
+
+// Increment implements Cache.
+func (p *prefixedCache) Increment(ctx context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	return p.parent.Increment(ctx, p.prefix+key, delta, p.options(opts)...)
+}
+
+// Decrement implements Cache.
+func (p *prefixedCache) Decrement(ctx context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	return p.parent.Decrement(ctx, p.prefix+key, delta, p.options(opts)...)
+}
+
+// GetTTL implements Cache.
+func (p *prefixedCache) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	return p.parent.GetTTL(ctx, p.prefix+key)
+}
+
+// Touch implements Cache.
+func (p *prefixedCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return p.parent.Touch(ctx, p.prefix+key, ttl)
+}
+
+// Delete implements Cache.
+func (p *prefixedCache) Delete(ctx context.Context, key string) error {
+	return p.parent.Delete(ctx, p.prefix+key)
+}
+
+// InvalidateTag implements Cache. The tags are namespaced like the keys.
+func (p *prefixedCache) InvalidateTag(ctx context.Context, tag string) error {
+	return p.parent.InvalidateTag(ctx, p.prefix+tag)
+}
+
+// Keys implements Cache.
+func (p *prefixedCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	keys, err := p.parent.Keys(ctx, EscapePattern(p.prefix)+pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, p.prefix)
+	}
+
+	return keys, nil
+}
+
+// Cleanup implements Cache. If the parent cache can't clean up a single
+// namespace, the expired items of the whole parent cache are removed.
+func (p *prefixedCache) Cleanup(ctx context.Context) error {
+	if scoped, ok := p.parent.(scopedCache); ok {
+		return scoped.cleanupPrefix(ctx, p.prefix)
+	}
+
+	return p.parent.Cleanup(ctx)
+}
+
+// Drain implements Cache.
+func (p *prefixedCache) Drain(ctx context.Context) (map[string]string, error) {
+	return p.drain(ctx, 0)
+}
+
+// DrainN implements Cache.
+func (p *prefixedCache) DrainN(ctx context.Context, n int) (map[string]string, error) {
+	if n <= 0 {
+		return map[string]string{}, nil
+	}
+
+	return p.drain(ctx, n)
+}
+
+// Close implements Cache.
+func (p *prefixedCache) Close() error {
+	return nil
+}
+
+// options appends the option that prepends the prefix to the tags.
+func (p *prefixedCache) options(opts []Option) []Option {
+	return append(slices.Clip(opts), func(o *options) {
+		for i, tag := range o.tags {
+			o.tags[i] = p.prefix + tag
+		}
+	})
+}
+
+// drain removes and returns the items of the namespace, at most n items if n
+// is positive.
+func (p *prefixedCache) drain(ctx context.Context, n int) (map[string]string, error) {
+	var (
+		items map[string]string
+		err   error
+	)
+
+	if scoped, ok := p.parent.(scopedCache); ok {
+		items, err = scoped.drainPrefix(ctx, p.prefix, n)
+	} else {
+		items, err = p.drainKeys(ctx, n)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(items))
+	for key, value := range items {
+		result[strings.TrimPrefix(key, p.prefix)] = value
+	}
+
+	return result, nil
+}
+
+// drainKeys takes the items of the namespace one by one. Each item is
+// returned once, but the items set concurrently may be missed.
+func (p *prefixedCache) drainKeys(ctx context.Context, n int) (map[string]string, error) {
+	keys, err := p.parent.Keys(ctx, EscapePattern(p.prefix)+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if n > 0 && len(items) == n {
+			break
+		}
+
+		value, err := p.parent.GetAndDelete(ctx, key)
+		if errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrKeyExpired) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		items[key] = value
+	}
+
+	return items, nil
+}
@@ -0,0 +1,170 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+// plainCache hides the optional interfaces of the wrapped cache.
+type plainCache struct {
+	cache.Cache
+}
+
+func TestWithPrefix_Isolation(t *testing.T) {
+	ctx := context.Background()
+	parent := cache.NewMemory(0)
+	a := cache.WithPrefix(parent, "a:")
+	b := cache.WithPrefix(parent, "b:")
+
+	_ = a.Set(ctx, "key", "value-a")
+	_ = b.Set(ctx, "key", "value-b")
+
+	if value, _ := a.Get(ctx, "key"); value != "value-a" {
+		t.Errorf("a.Get() = %q, want %q", value, "value-a")
+	}
+	if value, _ := parent.Get(ctx, "b:key"); value != "value-b" {
+		t.Errorf("parent.Get() = %q, want %q", value, "value-b")
+	}
+
+	if err := a.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Get(ctx, "key"); err != nil {
+		t.Errorf("b.Get() after a.Delete() error = %v", err)
+	}
+
+	nested := cache.WithPrefix(a, "nested:")
+	_ = nested.Set(ctx, "key", "value")
+	if _, err := parent.Get(ctx, "a:nested:key"); err != nil {
+		t.Errorf("parent.Get() of nested key error = %v", err)
+	}
+}
+
+func TestWithPrefix_Keys(t *testing.T) {
+	ctx := context.Background()
+	parent := cache.NewMemory(0)
+	c := cache.WithPrefix(parent, "a*:")
+
+	_ = c.Set(ctx, "one", "1")
+	_ = c.Set(ctx, "two", "2")
+	_ = parent.Set(ctx, "ab:one", "other")
+
+	keys, err := c.Keys(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"one", "two"}) {
+		t.Errorf("Keys() = %v, want [one two]", keys)
+	}
+
+	keys, err = c.Keys(ctx, "t*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"two"}) {
+		t.Errorf("Keys(t*) = %v, want [two]", keys)
+	}
+
+	if _, err := c.Keys(ctx, "[a"); !errors.Is(err, cache.ErrInvalidPattern) {
+		t.Errorf("Keys([a) error = %v, want %v", err, cache.ErrInvalidPattern)
+	}
+}
+
+func TestWithPrefix_Drain(t *testing.T) {
+	for name, parent := range map[string]cache.Cache{
+		"scoped": cache.NewMemory(0),
+		"plain":  plainCache{cache.NewMemory(0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			c := cache.WithPrefix(parent, "a:")
+
+			_ = c.Set(ctx, "one", "1")
+			_ = c.Set(ctx, "two", "2")
+			_ = c.Set(ctx, "three", "3")
+			_ = parent.Set(ctx, "b:one", "other")
+
+			items, err := c.DrainN(ctx, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 2 {
+				t.Errorf("DrainN() returned %d items, want 2", len(items))
+			}
+
+			rest, err := c.Drain(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, value := range rest {
+				items[key] = value
+			}
+
+			want := map[string]string{"one": "1", "two": "2", "three": "3"}
+			if len(items) != len(want) {
+				t.Errorf("drained %v, want %v", items, want)
+			}
+			for key, value := range want {
+				if items[key] != value {
+					t.Errorf("drained[%q] = %q, want %q", key, items[key], value)
+				}
+			}
+
+			if value, _ := parent.Get(ctx, "b:one"); value != "other" {
+				t.Errorf("item of other namespace = %q, want kept", value)
+			}
+		})
+	}
+}
+
+func TestWithPrefix_Cleanup(t *testing.T) {
+	ctx := context.Background()
+	parent := cache.NewMemory(0)
+	c := cache.WithPrefix(parent, "a:")
+
+	_ = c.Set(ctx, "key", "value", cache.WithTTL(time.Millisecond))
+	_ = parent.Set(ctx, "b:key", "value", cache.WithTTL(time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	if err := c.Cleanup(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries := parent.(cache.StatsProvider).Stats().Entries; entries != 1 {
+		t.Errorf("Entries = %d, want the expired item of other namespace kept", entries)
+	}
+}
+
+func TestWithPrefix_InvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	parent := cache.NewMemory(0)
+	a := cache.WithPrefix(parent, "a:")
+	b := cache.WithPrefix(parent, "b:")
+
+	_ = a.Set(ctx, "key", "value-a", cache.WithTags("tag"))
+	_ = b.Set(ctx, "key", "value-b", cache.WithTags("tag"))
+
+	if err := a.InvalidateTag(ctx, "tag"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("a.Get() error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+	if _, err := b.Get(ctx, "key"); err != nil {
+		t.Errorf("b.Get() error = %v", err)
+	}
+
+	if err := parent.InvalidateTag(ctx, "b:tag"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("b.Get() after parent.InvalidateTag() error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+}
@@ -4,32 +4,284 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
 	redisCacheKey = "cache"
 
+	// redisExpirySuffix is appended to the cache key to get the key of the
+	// sorted set of items expiration times. Redis doesn't report which hash
+	// fields have expired, so OnExpire finds them by this index.
+	redisExpirySuffix = ":expiry"
+
+	// redisTagsSuffix is appended to the cache key to get the key of the hash
+	// of the tags attached to the items, and redisTagSuffix to get the prefix
+	// of the keys of the sets of the items with the tag.
+	redisTagsSuffix = ":tags"
+	redisTagSuffix  = ":tag:"
+
+	// redisTagsSeparator separates the tags of an item in the tags hash.
+	redisTagsSeparator = "\x00"
+
+	// redisSweepBatch is the number of index entries checked per sweep script
+	// call.
+	redisSweepBatch = 100
+
+	// redisStatsTimeout limits the items count request of Stats.
+	redisStatsTimeout = time.Second
+
+	// redisBufferLimit is the default number of items kept by the write
+	// buffer while the flushes fail.
+	redisBufferLimit = 10_000
+
+	// redisFlushTimeout limits the background and the final flushes of the
+	// write buffer.
+	redisFlushTimeout = 5 * time.Second
+
+	// redisScanCount is the number of fields requested per HSCAN iteration.
+	redisScanCount = 100
+
+	// redisTagsLib is prepended to the scripts maintaining the tags. KEYS[1]
+	// is the items hash and KEYS[3] is the tags hash. A tag set expires with
+	// the last of its members, or never if any member has no expiration.
+	redisTagsLib = `
+local function tag_key(tag)
+	return KEYS[1] .. '` + redisTagSuffix + `' .. tag
+end
+
+local function split_tags(tags)
+	local result = {}
+	local start = 1
+	while true do
+		local i = string.find(tags, '\0', start, true)
+		table.insert(result, string.sub(tags, start, (i or 0) - 1))
+		if not i then
+			return result
+		end
+		start = i + 1
+	end
+end
+
+local function untag(field)
+	local tags = redis.call('HGET', KEYS[3], field)
+	if tags then
+		for _, tag in ipairs(split_tags(tags)) do
+			redis.call('SREM', tag_key(tag), field)
+		end
+		redis.call('HDEL', KEYS[3], field)
+	end
+end
+
+local function tag(field, tags, valid_until)
+	redis.call('HSET', KEYS[3], field, tags)
+	local ttl = 0
+	if valid_until > 0 then
+		redis.call('HPEXPIREAT', KEYS[3], valid_until, 'FIELDS', 1, field)
+		local now = redis.call('TIME')
+		ttl = valid_until - (now[1] * 1000 + math.floor(now[2] / 1000))
+	else
+		redis.call('HPERSIST', KEYS[3], 'FIELDS', 1, field)
+	end
+	for _, t in ipairs(split_tags(tags)) do
+		local key = tag_key(t)
+		redis.call('SADD', key, field)
+		if valid_until == 0 then
+			redis.call('PERSIST', key)
+		else
+			local current = redis.call('PTTL', key)
+			if redis.call('SCARD', key) == 1 or (current >= 0 and current < ttl) then
+				redis.call('PEXPIREAT', key, valid_until)
+			end
+		end
+	end
+end
+`
+
 	// getAndDeleteScript atomically gets and deletes a hash field
-	getAndDeleteScript = `
+	getAndDeleteScript = redisTagsLib + `
 local value = redis.call('HGET', KEYS[1], ARGV[1])
 if value then
 	redis.call('HDEL', KEYS[1], ARGV[1])
+	redis.call('ZREM', KEYS[2], ARGV[1])
+	untag(ARGV[1])
 	return value
 else
 	return false
 end
 `
 
-	hgetallAndDeleteScript = `
+	// deleteScript deletes a hash field with its index entries
+	deleteScript = redisTagsLib + `
+redis.call('HDEL', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+untag(ARGV[1])
+`
+
+	// setTagsScript replaces the tags of the ARGV[1] field with the ARGV[2]
+	// ones expiring at ARGV[3], the existing tags are kept if ARGV[4] is set
+	setTagsScript = redisTagsLib + `
+if ARGV[4] == '1' and redis.call('HEXISTS', KEYS[3], ARGV[1]) == 1 then
+	return 0
+end
+untag(ARGV[1])
+if ARGV[2] ~= '' then
+	tag(ARGV[1], ARGV[2], tonumber(ARGV[3]))
+end
+return 1
+`
+
+	// touchTagsScript moves the expiration of the tags of the ARGV[1] field to
+	// ARGV[2], 0 for none
+	touchTagsScript = redisTagsLib + `
+local tags = redis.call('HGET', KEYS[3], ARGV[1])
+if tags then
+	tag(ARGV[1], tags, tonumber(ARGV[2]))
+end
+`
+
+	// compareAndSwapScript atomically replaces a hash field value if it equals
+	// the expected one, returns -1 if the field is missing, 0 on mismatch and 1
+	// on success. The expiration is indexed if ARGV[6] is set
+	compareAndSwapScript = redisTagsLib + `
+local value = redis.call('HGET', KEYS[1], ARGV[1])
+if not value then
+	return -1
+end
+if value ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[3])
+if tonumber(ARGV[4]) > 0 then
+	redis.call('HPEXPIREAT', KEYS[1], ARGV[4], 'FIELDS', 1, ARGV[1])
+	if ARGV[6] == '1' then
+		redis.call('ZADD', KEYS[2], ARGV[4], ARGV[1])
+	end
+else
+	redis.call('ZREM', KEYS[2], ARGV[1])
+end
+untag(ARGV[1])
+if ARGV[5] ~= '' then
+	tag(ARGV[1], ARGV[5], tonumber(ARGV[4]))
+end
+return 1
+`
+
+	// setOrFailScript atomically sets a hash field with its expiration if the
+	// field is missing, returns 0 if it exists and 1 on success. The
+	// expiration is indexed if ARGV[5] is set
+	setOrFailScript = redisTagsLib + `
+if redis.call('HSETNX', KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('HPEXPIREAT', KEYS[1], ARGV[3], 'FIELDS', 1, ARGV[1])
+	if ARGV[5] == '1' then
+		redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+	end
+else
+	redis.call('ZREM', KEYS[2], ARGV[1])
+end
+untag(ARGV[1])
+if ARGV[4] ~= '' then
+	tag(ARGV[1], ARGV[4], tonumber(ARGV[3]))
+end
+return 1
+`
+
+	hgetallAndDeleteScript = redisTagsLib + `
 local items = redis.call('HGETALL', KEYS[1])
 if #items > 0 then
   local ok = pcall(redis.call, 'UNLINK', KEYS[1])
   if not ok then redis.call('DEL', KEYS[1]) end
 end
+for _, tags in ipairs(redis.call('HVALS', KEYS[3])) do
+	for _, t in ipairs(split_tags(tags)) do
+		redis.call('DEL', tag_key(t))
+	end
+end
+redis.call('DEL', KEYS[2], KEYS[3])
+return items
+`
+
+	// drainNScript removes and returns up to ARGV[1] random fields with their
+	// values, expired fields are never returned
+	drainNScript = redisTagsLib + `
+local items = redis.call('HRANDFIELD', KEYS[1], ARGV[1], 'WITHVALUES')
+for i = 1, #items, 2 do
+	redis.call('HDEL', KEYS[1], items[i])
+	redis.call('ZREM', KEYS[2], items[i])
+	untag(items[i])
+end
 return items
+`
+
+	// drainPrefixScript removes and returns the fields matching the ARGV[1]
+	// pattern with their values, up to ARGV[2] fields if it's positive. HSCAN
+	// may return a field more than once, so only the fields actually deleted
+	// are returned
+	drainPrefixScript = redisTagsLib + `
+local limit = tonumber(ARGV[2])
+local result = {}
+local cursor = '0'
+repeat
+	local res = redis.call('HSCAN', KEYS[1], cursor, 'MATCH', ARGV[1], 'COUNT', ARGV[3])
+	cursor = res[1]
+	local items = res[2]
+	for i = 1, #items, 2 do
+		if redis.call('HDEL', KEYS[1], items[i]) == 1 then
+			redis.call('ZREM', KEYS[2], items[i])
+			untag(items[i])
+			table.insert(result, items[i])
+			table.insert(result, items[i + 1])
+			if limit > 0 and #result >= limit * 2 then
+				return result
+			end
+		end
+	end
+until cursor == '0'
+return result
+`
+
+	// invalidateTagScript removes the members of the tag set which still have
+	// the tag ARGV[1] attached, the set may keep the expired items overwritten
+	// without the tag. Returns the number of removed items
+	invalidateTagScript = redisTagsLib + `
+local removed = 0
+for _, field in ipairs(redis.call('SMEMBERS', KEYS[4])) do
+	local tags = redis.call('HGET', KEYS[3], field)
+	if tags and string.find(ARGV[2] .. tags .. ARGV[2], ARGV[2] .. ARGV[1] .. ARGV[2], 1, true) then
+		redis.call('HDEL', KEYS[1], field)
+		redis.call('ZREM', KEYS[2], field)
+		untag(field)
+		removed = removed + 1
+	end
+end
+redis.call('DEL', KEYS[4])
+return removed
+`
+
+	// sweepExpiredScript removes the due entries of the expiry index and
+	// returns the number of checked entries followed by the fields that no
+	// longer exist, i.e. have expired. Checking a field expires it if it's
+	// due, so the result doesn't depend on the server expiration cycle.
+	sweepExpiredScript = `
+local now = redis.call('TIME')
+local ms = now[1] * 1000 + math.floor(now[2] / 1000)
+local fields = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ms, 'LIMIT', 0, tonumber(ARGV[1]))
+local result = {#fields}
+for _, field in ipairs(fields) do
+	redis.call('ZREM', KEYS[2], field)
+	if redis.call('HEXISTS', KEYS[1], field) == 0 then
+		table.insert(result, field)
+	end
+end
+return result
 `
 )
 
@@ -39,84 +291,481 @@ type redisCache struct {
 	key string
 
 	ttl time.Duration
+
+	// expiry is the key of the sorted set of items expiration times
+	expiry string
+	// tags is the key of the hash of the tags attached to the items
+	tags string
+
+	flights singleflight.Group
+
+	// buffer holds the Set calls not written yet, nil if writes aren't
+	// buffered
+	buffer *redisBuffer
+
+	// lazyExpiration reports the items expired according to the expiry index
+	// with ErrKeyExpired rather than ErrKeyNotFound
+	lazyExpiration bool
+	// indexed reports whether the expiry index is maintained, it's needed for
+	// the lazy expiration and the OnExpire handlers only
+	indexed atomic.Bool
+
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	expirations atomic.Uint64
+
+	expireHandlers []ExpireHandler
+	expireMux      sync.RWMutex
+	watchOnce      sync.Once
+	watchers       sync.WaitGroup
+	stop           chan struct{}
+	closeOnce      sync.Once
 }
 
-func NewRedis(client *redis.Client, prefix string, ttl time.Duration) Cache {
+func NewRedis(client *redis.Client, prefix string, ttl time.Duration, opts ...RedisOption) Cache {
 	if prefix != "" && !strings.HasSuffix(prefix, ":") {
 		prefix += ":"
 	}
 
-	return &redisCache{
+	o := new(redisOptions).apply(opts...)
+
+	r := &redisCache{
 		client: client,
 
 		key: prefix + redisCacheKey,
 
 		ttl: ttl,
+
+		expiry: prefix + redisCacheKey + redisExpirySuffix,
+		tags:   prefix + redisCacheKey + redisTagsSuffix,
+
+		lazyExpiration: o.lazyExpiration,
+
+		stop: make(chan struct{}),
+	}
+
+	r.indexed.Store(o.lazyExpiration)
+
+	if o.bufferSize > 0 || o.bufferInterval > 0 {
+		r.buffer = newRedisBuffer(o.bufferSize, o.bufferLimit)
+	}
+	if o.bufferInterval > 0 {
+		r.watchers.Add(1)
+		go r.flushPeriodically(o.bufferInterval)
+	}
+
+	return r
+}
+
+// Stats implements StatsProvider. Hits, misses and expirations are counted by
+// this instance only, expirations are tracked only if OnExpire handlers are
+// registered. Entries are requested from Redis and are zero on error. Bytes
+// and evictions are managed by Redis and aren't tracked.
+func (r *redisCache) Stats() Stats {
+	ctx, cancel := context.WithTimeout(context.Background(), redisStatsTimeout)
+	defer cancel()
+
+	entries, _ := r.client.HLen(ctx, r.key).Result()
+
+	return Stats{
+		Entries:     int(entries),
+		Hits:        r.hits.Load(),
+		Misses:      r.misses.Load(),
+		Expirations: r.expirations.Load(),
 	}
 }
 
-// Cleanup implements Cache.
-func (r *redisCache) Cleanup(_ context.Context) error {
+// OnExpire implements ExpireNotifier. The first call subscribes to the
+// keyspace notifications of the cache, so the server must have them enabled
+// for hash commands, e.g. `notify-keyspace-events Kh`. The expired items are
+// shared between the instances, each of them is reported to one instance only.
+// Values of expired items aren't kept by Redis, so handlers get empty values.
+// The expiration times are indexed after the first call only, so the items
+// stored before it aren't reported, and all the instances sharing the cache
+// must register the handlers.
+func (r *redisCache) OnExpire(handler ExpireHandler) {
+	r.indexed.Store(true)
+
+	r.expireMux.Lock()
+	r.expireHandlers = append(r.expireHandlers, handler)
+	r.expireMux.Unlock()
+
+	r.watchOnce.Do(func() {
+		r.watchers.Add(1)
+		go r.watchExpirations()
+	})
+}
+
+// Cleanup implements Cache. Expired items are removed by Redis, with lazy
+// expiration their expiry index entries are removed too.
+func (r *redisCache) Cleanup(ctx context.Context) error {
+	if !r.lazyExpiration {
+		return nil
+	}
+
+	return r.sweepExpired(ctx)
+}
+
+// CompareAndSwap implements Cache.
+func (r *redisCache) CompareAndSwap(ctx context.Context, key string, oldValue, newValue string, opts ...Option) error {
+	if err := r.Flush(ctx); err != nil {
+		return err
+	}
+
+	options := new(options)
+	if r.ttl > 0 {
+		options.validUntil = time.Now().Add(r.ttl)
+	}
+	options.apply(opts...)
+
+	var validUntil int64
+	if !options.validUntil.IsZero() {
+		validUntil = options.validUntil.UnixMilli()
+	}
+
+	res, err := r.client.Eval(
+		ctx, compareAndSwapScript, []string{r.key, r.expiry, r.tags},
+		key, oldValue, newValue, validUntil, joinTags(options.tags), r.indexFlag(),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("can't swap cache item: %w", err)
+	}
+
+	switch res {
+	case -1:
+		return ErrKeyNotFound
+	case 0:
+		return ErrValueMismatch
+	}
+
+	return nil
+}
+
+// Close implements Cache. It stops the background goroutines and flushes the
+// buffered writes, the client is owned by the caller, so it's left open.
+func (r *redisCache) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+	r.watchers.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisFlushTimeout)
+	defer cancel()
+
+	return r.Flush(ctx)
+}
+
+// Increment implements Cache.
+func (r *redisCache) Increment(ctx context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	if err := r.Flush(ctx); err != nil {
+		return 0, err
+	}
+
+	options := new(options)
+	if r.ttl > 0 {
+		options.validUntil = time.Now().Add(r.ttl)
+	}
+	options.apply(opts...)
+
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incr = p.HIncrBy(ctx, r.key, key, delta)
+		if !options.validUntil.IsZero() {
+			p.HExpireAtWithArgs(ctx, r.key, options.validUntil, redis.HExpireArgs{NX: true}, key)
+			if r.indexed.Load() {
+				p.ZAddNX(ctx, r.expiry, redis.Z{Score: float64(options.validUntil.UnixMilli()), Member: key})
+			}
+		}
+		if len(options.tags) > 0 {
+			// the tags of the existing item are kept
+			p.Eval(ctx, setTagsScript, []string{r.key, r.expiry, r.tags},
+				key, joinTags(options.tags), unixMilli(options.validUntil), "1")
+		}
+		return nil
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not an integer") {
+			return 0, fmt.Errorf("%w: not an integer", ErrInvalidValue)
+		}
+
+		return 0, fmt.Errorf("can't increment cache item: %w", err)
+	}
+
+	return incr.Val(), nil
+}
+
+// Decrement implements Cache.
+func (r *redisCache) Decrement(ctx context.Context, key string, delta int64, opts ...Option) (int64, error) {
+	return r.Increment(ctx, key, -delta, opts...)
+}
+
+// GetTTL implements Cache.
+func (r *redisCache) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	if err := r.Flush(ctx); err != nil {
+		return 0, err
+	}
+
+	res, err := r.client.HPTTL(ctx, r.key, key).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, r.missing(ctx, key)
+		}
+
+		return 0, fmt.Errorf("can't get cache item ttl: %w", err)
+	}
+
+	if len(res) == 0 {
+		return 0, r.missing(ctx, key)
+	}
+
+	switch res[0] {
+	case -2:
+		return 0, r.missing(ctx, key)
+	case -1:
+		return NoExpiration, nil
+	}
+
+	return time.Duration(res[0]) * time.Millisecond, nil
+}
+
+// Touch implements Cache.
+func (r *redisCache) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	if err := r.Flush(ctx); err != nil {
+		return err
+	}
+
+	var (
+		res []int64
+		err error
+	)
+	if ttl > 0 {
+		res, err = r.client.HPExpire(ctx, r.key, ttl, key).Result()
+	} else {
+		res, err = r.client.HPersist(ctx, r.key, key).Result()
+	}
+	if err != nil {
+		if err == redis.Nil {
+			return ErrKeyNotFound
+		}
+
+		return fmt.Errorf("can't set cache item ttl: %w", err)
+	}
+
+	if len(res) == 0 || res[0] == -2 {
+		return ErrKeyNotFound
+	}
+
+	// the index is updated only for existing items, so it has no entries for
+	// items that have never been stored
+	var validUntil time.Time
+	if ttl > 0 {
+		validUntil = time.Now().Add(ttl)
+	}
+	_, err = r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		if ttl > 0 && r.indexed.Load() {
+			p.ZAdd(ctx, r.expiry, redis.Z{Score: float64(validUntil.UnixMilli()), Member: key})
+		} else {
+			p.ZRem(ctx, r.expiry, key)
+		}
+		p.Eval(ctx, touchTagsScript, []string{r.key, r.expiry, r.tags}, key, unixMilli(validUntil))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("can't update cache expiry index: %w", err)
+	}
+
 	return nil
 }
 
 // Delete implements Cache.
 func (r *redisCache) Delete(ctx context.Context, key string) error {
-	if err := r.client.HDel(ctx, r.key, key).Err(); err != nil {
+	if err := r.Flush(ctx); err != nil {
+		return err
+	}
+
+	if err := r.client.Eval(ctx, deleteScript, []string{r.key, r.expiry, r.tags}, key).Err(); err != nil && err != redis.Nil {
 		return fmt.Errorf("can't delete cache item: %w", err)
 	}
 
 	return nil
 }
 
+// Keys implements Cache. It iterates the hash with HSCAN, so it doesn't
+// block the server on large caches.
+func (r *redisCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	// validate the pattern the same way as the memory cache does
+	if _, err := compilePattern(pattern); err != nil {
+		return nil, err
+	}
+	if pattern == "" {
+		pattern = "*"
+	}
+
+	// HSCAN may return a field more than once
+	seen := make(map[string]struct{})
+	keys := []string{}
+
+	var cursor uint64
+	for {
+		fields, next, err := r.client.HScanNoValues(ctx, r.key, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("can't scan cache keys: %w", err)
+		}
+
+		for _, field := range fields {
+			if _, ok := seen[field]; ok {
+				continue
+			}
+			seen[field] = struct{}{}
+			keys = append(keys, field)
+		}
+
+		if next == 0 {
+			return keys, nil
+		}
+		cursor = next
+	}
+}
+
 // Drain implements Cache.
 func (r *redisCache) Drain(ctx context.Context) (map[string]string, error) {
-	res, err := r.client.Eval(ctx, hgetallAndDeleteScript, []string{r.key}).Result()
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Eval(ctx, hgetallAndDeleteScript, []string{r.key, r.expiry, r.tags}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("can't drain cache: %w", err)
+	}
+
+	return pairsToMap(res), nil
+}
+
+// DrainN implements Cache.
+func (r *redisCache) DrainN(ctx context.Context, n int) (map[string]string, error) {
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	if n <= 0 {
+		return map[string]string{}, nil
+	}
+
+	res, err := r.client.Eval(ctx, drainNScript, []string{r.key, r.expiry, r.tags}, n).Result()
+	if err != nil {
+		return nil, fmt.Errorf("can't drain cache: %w", err)
+	}
+
+	return pairsToMap(res), nil
+}
+
+// drainPrefix implements scopedCache.
+func (r *redisCache) drainPrefix(ctx context.Context, prefix string, n int) (map[string]string, error) {
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	res, err := r.client.Eval(
+		ctx, drainPrefixScript, []string{r.key, r.expiry, r.tags},
+		EscapePattern(prefix)+"*", n, redisScanCount,
+	).Result()
 	if err != nil {
 		return nil, fmt.Errorf("can't drain cache: %w", err)
 	}
 
+	return pairsToMap(res), nil
+}
+
+// cleanupPrefix implements scopedCache. Expired items are removed by Redis,
+// the expiry index is shared by the prefixes, so it's cleaned up as a whole.
+func (r *redisCache) cleanupPrefix(ctx context.Context, _ string) error {
+	return r.Cleanup(ctx)
+}
+
+// pairsToMap converts the flat field-value reply of a script to a map.
+func pairsToMap(res any) map[string]string {
 	arr, ok := res.([]any)
 	if !ok || len(arr) == 0 {
-		return map[string]string{}, nil
+		return map[string]string{}
 	}
 
 	out := make(map[string]string, len(arr)/2)
-	for i := 0; i < len(arr); i += 2 {
+	for i := 0; i+1 < len(arr); i += 2 {
 		f, _ := arr[i].(string)
 		v, _ := arr[i+1].(string)
 		out[f] = v
 	}
 
-	return out, nil
+	return out
 }
 
 // Get implements Cache.
 func (r *redisCache) Get(ctx context.Context, key string) (string, error) {
+	if err := r.Flush(ctx); err != nil {
+		return "", err
+	}
+
 	val, err := r.client.HGet(ctx, r.key, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return "", ErrKeyNotFound
+			r.misses.Add(1)
+			return "", r.missing(ctx, key)
 		}
 
 		return "", fmt.Errorf("can't get cache item: %w", err)
 	}
 
+	r.hits.Add(1)
 	return val, nil
 }
 
+// GetBytes implements Cache.
+func (r *redisCache) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	val, err := r.client.HGet(ctx, r.key, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			r.misses.Add(1)
+			return nil, r.missing(ctx, key)
+		}
+
+		return nil, fmt.Errorf("can't get cache item: %w", err)
+	}
+
+	r.hits.Add(1)
+	return val, nil
+}
+
+// GetOrSet implements Cache.
+func (r *redisCache) GetOrSet(ctx context.Context, key string, load func() (string, error), opts ...Option) (string, error) {
+	return getOrSet(ctx, r, &r.flights, key, load, opts...)
+}
+
 // GetAndDelete implements Cache.
 func (r *redisCache) GetAndDelete(ctx context.Context, key string) (string, error) {
-	result, err := r.client.Eval(ctx, getAndDeleteScript, []string{r.key}, key).Result()
+	if err := r.Flush(ctx); err != nil {
+		return "", err
+	}
+
+	result, err := r.client.Eval(ctx, getAndDeleteScript, []string{r.key, r.expiry, r.tags}, key).Result()
 	if err != nil {
 		return "", fmt.Errorf("can't get cache item: %w", err)
 	}
 
 	if value, ok := result.(string); ok {
+		r.hits.Add(1)
 		return value, nil
 	}
 
-	return "", ErrKeyNotFound
+	r.misses.Add(1)
+	return "", r.missing(ctx, key)
 }
 
 // Set implements Cache.
@@ -127,11 +776,24 @@ func (r *redisCache) Set(ctx context.Context, key string, value string, opts ...
 	}
 	options.apply(opts...)
 
-	_, err := r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
-		p.HSet(ctx, r.key, key, value)
-		if !options.validUntil.IsZero() {
-			p.HExpireAt(ctx, r.key, options.validUntil, key)
+	if r.buffer != nil {
+		full, added := r.buffer.add(key, redisWrite{value: value, options: options})
+		if added {
+			if full {
+				return r.Flush(ctx)
+			}
+			return nil
 		}
+
+		// the buffer is at the limit as the flushes fail, the item is written
+		// directly once the buffer is flushed
+		if err := r.Flush(ctx); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		r.write(ctx, p, key, value, options)
 		return nil
 	})
 	if err != nil {
@@ -141,15 +803,30 @@ func (r *redisCache) Set(ctx context.Context, key string, value string, opts ...
 	return nil
 }
 
-// SetOrFail implements Cache.
-func (r *redisCache) SetOrFail(ctx context.Context, key string, value string, opts ...Option) error {
-	val, err := r.client.HSetNX(ctx, r.key, key, value).Result()
-	if err != nil {
-		return fmt.Errorf("can't set cache item: %w", err)
+// write queues the commands storing the item.
+func (r *redisCache) write(ctx context.Context, p redis.Pipeliner, key, value string, options *options) {
+	p.HSet(ctx, r.key, key, value)
+	if !options.validUntil.IsZero() {
+		p.HExpireAt(ctx, r.key, options.validUntil, key)
 	}
+	if !options.validUntil.IsZero() && r.indexed.Load() {
+		p.ZAdd(ctx, r.expiry, redis.Z{Score: float64(options.validUntil.UnixMilli()), Member: key})
+	} else {
+		p.ZRem(ctx, r.expiry, key)
+	}
+	p.Eval(ctx, setTagsScript, []string{r.key, r.expiry, r.tags},
+		key, joinTags(options.tags), unixMilli(options.validUntil), "0")
+}
 
-	if !val {
-		return ErrKeyExists
+// SetBytes implements Cache.
+func (r *redisCache) SetBytes(ctx context.Context, key string, value []byte, opts ...Option) error {
+	return r.Set(ctx, key, string(value), opts...)
+}
+
+// SetOrFail implements Cache.
+func (r *redisCache) SetOrFail(ctx context.Context, key string, value string, opts ...Option) error {
+	if err := r.Flush(ctx); err != nil {
+		return err
 	}
 
 	options := new(options)
@@ -158,11 +835,146 @@ func (r *redisCache) SetOrFail(ctx context.Context, key string, value string, op
 	}
 	options.apply(opts...)
 
+	var validUntil int64
 	if !options.validUntil.IsZero() {
-		if err := r.client.HExpireAt(ctx, r.key, options.validUntil).Err(); err != nil {
-			return fmt.Errorf("can't set cache item ttl: %w", err)
-		}
+		validUntil = options.validUntil.UnixMilli()
+	}
+
+	res, err := r.client.Eval(
+		ctx, setOrFailScript, []string{r.key, r.expiry, r.tags},
+		key, value, validUntil, joinTags(options.tags), r.indexFlag(),
+	).Int()
+	if err != nil {
+		return fmt.Errorf("can't set cache item: %w", err)
+	}
+
+	if res == 0 {
+		return ErrKeyExists
 	}
 
 	return nil
 }
+
+// InvalidateTag implements Cache.
+func (r *redisCache) InvalidateTag(ctx context.Context, tag string) error {
+	if err := r.Flush(ctx); err != nil {
+		return err
+	}
+
+	err := r.client.Eval(
+		ctx, invalidateTagScript, []string{r.key, r.expiry, r.tags, r.tagKey(tag)},
+		tag, redisTagsSeparator,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("can't invalidate cache tag: %w", err)
+	}
+
+	return nil
+}
+
+// missing returns the error reporting a missing item. With lazy expiration
+// the item is reported as expired while the expiry index keeps its past
+// expiration time.
+func (r *redisCache) missing(ctx context.Context, key string) error {
+	if !r.lazyExpiration {
+		return ErrKeyNotFound
+	}
+
+	score, err := r.client.ZScore(ctx, r.expiry, key).Result()
+	if err != nil || int64(score) > time.Now().UnixMilli() {
+		return ErrKeyNotFound
+	}
+
+	return ErrKeyExpired
+}
+
+func (r *redisCache) tagKey(tag string) string {
+	return r.key + redisTagSuffix + tag
+}
+
+// indexFlag is the script argument enabling the expiry index.
+func (r *redisCache) indexFlag() string {
+	if r.indexed.Load() {
+		return "1"
+	}
+
+	return "0"
+}
+
+// unixMilli returns the script argument of the expiration time, 0 for none.
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixMilli()
+}
+
+// joinTags encodes the tags for the tags hash, empty if there are none.
+func joinTags(tags []string) string {
+	return strings.Join(tags, redisTagsSeparator)
+}
+
+// watchExpirations sweeps the expiry index on every expiration of the cache
+// items until the cache is closed.
+func (r *redisCache) watchExpirations() {
+	defer r.watchers.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	channel := fmt.Sprintf("__keyspace@%d__:%s", r.client.Options().DB, r.key)
+	sub := r.client.Subscribe(ctx, channel)
+	defer sub.Close()
+
+	// report the items expired while nobody was watching
+	_ = r.sweepExpired(ctx)
+
+	messages := sub.Channel()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if msg.Payload == "hexpired" {
+				// errors are retried on the next notification
+				_ = r.sweepExpired(ctx)
+			}
+		}
+	}
+}
+
+// sweepExpired calls the handlers for the items expired since the last sweep.
+func (r *redisCache) sweepExpired(ctx context.Context) error {
+	for {
+		res, err := r.client.Eval(ctx, sweepExpiredScript, []string{r.key, r.expiry}, redisSweepBatch).Slice()
+		if err != nil {
+			return fmt.Errorf("can't sweep expired cache items: %w", err)
+		}
+		if len(res) == 0 {
+			return nil
+		}
+
+		r.expireMux.RLock()
+		for _, field := range res[1:] {
+			key, _ := field.(string)
+			r.expirations.Add(1)
+			for _, h := range r.expireHandlers {
+				h(key, "")
+			}
+		}
+		r.expireMux.RUnlock()
+
+		if checked, _ := res[0].(int64); checked < redisSweepBatch {
+			return nil
+		}
+	}
+}
+
+var _ StatsProvider = (*redisCache)(nil)
+var _ Flusher = (*redisCache)(nil)
+var _ ExpireNotifier = (*redisCache)(nil)
+var _ scopedCache = (*redisCache)(nil)
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisWrite is the buffered Set of an item.
+type redisWrite struct {
+	value   string
+	options *options
+}
+
+// redisBuffer coalesces the Set calls of the Redis cache per key.
+type redisBuffer struct {
+	// size is the number of items that triggers the flush, zero for no limit
+	size int
+	// limit is the number of items kept while the flushes fail
+	limit int
+
+	pending map[string]redisWrite
+	mux     sync.Mutex
+
+	// flushMux serializes the flushes, so an older batch never overwrites
+	// a newer one and the operations wait for the flush in progress
+	flushMux sync.Mutex
+}
+
+func newRedisBuffer(size, limit int) *redisBuffer {
+	if limit == 0 {
+		limit = redisBufferLimit
+	}
+
+	return &redisBuffer{
+		size:    size,
+		limit:   max(limit, size),
+		pending: make(map[string]redisWrite),
+	}
+}
+
+// add buffers the write replacing the pending one of the key and reports
+// whether the buffer is full. The write of a new key isn't buffered at the
+// limit, added is false then.
+func (b *redisBuffer) add(key string, w redisWrite) (full, added bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if _, ok := b.pending[key]; !ok && len(b.pending) >= b.limit {
+		return true, false
+	}
+
+	b.pending[key] = w
+
+	return b.size > 0 && len(b.pending) >= b.size, true
+}
+
+// take removes and returns the pending writes.
+func (b *redisBuffer) take() map[string]redisWrite {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	batch := b.pending
+	b.pending = make(map[string]redisWrite, len(batch))
+
+	return batch
+}
+
+// restore returns the batch that failed to be written to the buffer, the keys
+// written again meanwhile keep the newer values. The items exceeding the limit
+// are dropped, their number is returned.
+func (b *redisBuffer) restore(batch map[string]redisWrite) int {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	dropped := 0
+	for key, w := range batch {
+		if _, ok := b.pending[key]; ok {
+			continue
+		}
+		if len(b.pending) >= b.limit {
+			dropped++
+			continue
+		}
+		b.pending[key] = w
+	}
+
+	return dropped
+}
+
+// Flush implements Flusher. It writes the buffered items in a single pipeline,
+// on error they are kept in the buffer for the next flush up to the limit.
+func (r *redisCache) Flush(ctx context.Context) error {
+	if r.buffer == nil {
+		return nil
+	}
+
+	r.buffer.flushMux.Lock()
+	defer r.buffer.flushMux.Unlock()
+
+	batch := r.buffer.take()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	_, err := r.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for key, w := range batch {
+			r.write(ctx, p, key, w.value, w.options)
+		}
+		return nil
+	})
+	if err != nil {
+		if dropped := r.buffer.restore(batch); dropped > 0 {
+			return fmt.Errorf("can't flush cache items, %d dropped: %w", dropped, err)
+		}
+		return fmt.Errorf("can't flush cache items: %w", err)
+	}
+
+	return nil
+}
+
+// flushPeriodically flushes the buffer at the interval until Close. Failed
+// flushes are retried on the next tick.
+func (r *redisCache) flushPeriodically(interval time.Duration) {
+	defer r.watchers.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), redisFlushTimeout)
+			_ = r.Flush(ctx)
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// newBufferedCache returns the buffered cache backed by miniredis, the cache
+// without the buffer sharing its items and the server to fail the commands.
+func newBufferedCache(t *testing.T, opts ...cache.RedisOption) (cache.Cache, cache.Cache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	buffered := cache.NewRedis(client, "test:", 0, opts...)
+	direct := cache.NewRedis(client, "test:", 0)
+	t.Cleanup(func() {
+		server.SetError("")
+		_ = buffered.Close()
+		_ = direct.Close()
+		_ = client.Close()
+	})
+
+	return buffered, direct, server
+}
+
+func flush(t *testing.T, c cache.Cache) error {
+	t.Helper()
+
+	f, ok := c.(cache.Flusher)
+	if !ok {
+		t.Fatal("cache doesn't implement Flusher")
+	}
+
+	return f.Flush(context.Background())
+}
+
+func TestRedis_WriteBuffer(t *testing.T) {
+	ctx := context.Background()
+	c, direct, _ := newBufferedCache(t, cache.WithWriteBuffer(2, 0))
+
+	if err := c.Set(ctx, "first", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := direct.Get(ctx, "first"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Fatalf("Get() of buffered item error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+
+	if err := c.Set(ctx, "second", "2"); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"first": "1", "second": "2"} {
+		if value, err := direct.Get(ctx, key); err != nil || value != want {
+			t.Errorf("Get(%q) after full buffer = %q, %v, want %q", key, value, err, want)
+		}
+	}
+}
+
+func TestRedis_WriteBuffer_FlushError(t *testing.T) {
+	ctx := context.Background()
+	c, direct, server := newBufferedCache(t, cache.WithWriteBuffer(10, 0))
+
+	if err := c.Set(ctx, "key", "old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "other", "kept"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.SetError("down")
+	if err := flush(t, c); err == nil {
+		t.Fatal("Flush() error = nil, want error while Redis is down")
+	}
+	server.SetError("")
+
+	if err := c.Set(ctx, "key", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flush(t, c); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if value, err := direct.Get(ctx, "key"); err != nil || value != "new" {
+		t.Errorf("Get() of rewritten item = %q, %v, want %q", value, err, "new")
+	}
+	if value, err := direct.Get(ctx, "other"); err != nil || value != "kept" {
+		t.Errorf("Get() of restored item = %q, %v, want %q", value, err, "kept")
+	}
+}
+
+func TestRedis_WriteBuffer_Limit(t *testing.T) {
+	const limit = 3
+
+	ctx := context.Background()
+	c, direct, server := newBufferedCache(t, cache.WithWriteBuffer(0, time.Hour), cache.WithWriteBufferLimit(limit))
+	server.SetError("down")
+
+	for i := range limit {
+		if err := c.Set(ctx, fmt.Sprintf("key-%d", i), "value"); err != nil {
+			t.Fatalf("Set() below the limit error = %v", err)
+		}
+	}
+	if err := c.Set(ctx, "key-0", "updated"); err != nil {
+		t.Fatalf("Set() of buffered key at the limit error = %v", err)
+	}
+	if err := c.Set(ctx, "extra", "value"); err == nil {
+		t.Fatal("Set() of new key at the limit error = nil, want flush error")
+	}
+	if err := flush(t, c); err == nil {
+		t.Fatal("Flush() error = nil, want error while Redis is down")
+	}
+
+	server.SetError("")
+	if err := c.Set(ctx, "extra", "value"); err != nil {
+		t.Fatalf("Set() of new key after recovery error = %v", err)
+	}
+	if value, err := direct.Get(ctx, "extra"); err != nil || value != "value" {
+		t.Errorf("Get() of item written at the limit = %q, %v, want it written directly", value, err)
+	}
+	if value, err := direct.Get(ctx, "key-0"); err != nil || value != "updated" {
+		t.Errorf("Get() of buffered item = %q, %v, want %q flushed", value, err, "updated")
+	}
+	for i := 1; i < limit; i++ {
+		if _, err := direct.Get(ctx, fmt.Sprintf("key-%d", i)); err != nil {
+			t.Errorf("Get() of buffered item %d error = %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/android-sms-gateway/server/pkg/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisURLEnv names the variable with the URL of the Redis server, 7.4 or
+// later for the hash fields expiration. The tests of the expiration and the
+// tags run against it only and are skipped without it: miniredis doesn't
+// implement HPEXPIREAT, HPTTL and HPERSIST. The other tests use miniredis.
+const redisURLEnv = "CACHE_TEST_REDIS_URL"
+
+// newMiniredisCache returns the cache backed by miniredis, for the tests
+// which don't rely on the hash fields expiration.
+func newMiniredisCache(t *testing.T) cache.Cache {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	c := cache.NewRedis(client, "test:", 0)
+	t.Cleanup(func() {
+		_ = c.Close()
+		_ = client.Close()
+	})
+
+	return c
+}
+
+func TestRedis_SetOrFail(t *testing.T) {
+	ctx := context.Background()
+	c := newMiniredisCache(t)
+
+	if err := c.SetOrFail(ctx, "key", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetOrFail(ctx, "key", "second"); !errors.Is(err, cache.ErrKeyExists) {
+		t.Errorf("SetOrFail() of existing item error = %v, want %v", err, cache.ErrKeyExists)
+	}
+	if value, err := c.Get(ctx, "key"); err != nil || value != "first" {
+		t.Errorf("Get() = %q, %v, want %q", value, err, "first")
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetOrFail(ctx, "key", "third"); err != nil {
+		t.Fatalf("SetOrFail() of deleted item error = %v", err)
+	}
+	if value, _ := c.Get(ctx, "key"); value != "third" {
+		t.Errorf("Get() = %q, want %q", value, "third")
+	}
+}
+
+func TestRedis_SetOrFail_RaceNoExpiration(t *testing.T) {
+	const workers = 32
+
+	ctx := context.Background()
+	c := newMiniredisCache(t)
+
+	var (
+		wg      sync.WaitGroup
+		mux     sync.Mutex
+		winners []string
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value := fmt.Sprintf("value-%d", i)
+			err := c.SetOrFail(ctx, "key", value)
+			if errors.Is(err, cache.ErrKeyExists) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mux.Lock()
+			winners = append(winners, value)
+			mux.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("SetOrFail() succeeded %d times, want once", len(winners))
+	}
+	if value, _ := c.Get(ctx, "key"); value != winners[0] {
+		t.Errorf("Get() = %q, want %q", value, winners[0])
+	}
+}
+
+func newRedisCache(t *testing.T, ttl time.Duration, opts ...cache.RedisOption) cache.Cache {
+	t.Helper()
+
+	c, _, _ := newRedisCacheWithClient(t, ttl, opts...)
+	return c
+}
+
+// newRedisCacheWithClient also returns the client and the key prefix, so the
+// tests can check the keys of the cache.
+func newRedisCacheWithClient(t *testing.T, ttl time.Duration, opts ...cache.RedisOption) (cache.Cache, *redis.Client, string) {
+	t.Helper()
+
+	url, ok := os.LookupEnv(redisURLEnv)
+	if !ok {
+		t.Skipf("%s is not set", redisURLEnv)
+	}
+
+	clientOpts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := redis.NewClient(clientOpts)
+	prefix := fmt.Sprintf("test:%s:%d:", t.Name(), time.Now().UnixNano())
+	c := cache.NewRedis(client, prefix, ttl, opts...)
+	t.Cleanup(func() {
+		_, _ = c.Drain(context.Background())
+		_ = c.Close()
+		_ = client.Close()
+	})
+
+	return c, client, prefix
+}
+
+func TestRedis_SetOrFail_TTL(t *testing.T) {
+	ctx := context.Background()
+	c := newRedisCache(t, time.Hour)
+
+	if err := c.SetOrFail(ctx, "custom", "value", cache.WithTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := c.GetTTL(ctx, "custom"); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("GetTTL() = %s, %v, want up to %s", ttl, err, time.Minute)
+	}
+
+	if err := c.SetOrFail(ctx, "default", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := c.GetTTL(ctx, "default"); err != nil || ttl <= time.Minute || ttl > time.Hour {
+		t.Errorf("GetTTL() = %s, %v, want up to %s", ttl, err, time.Hour)
+	}
+
+	if err := c.SetOrFail(ctx, "custom", "other"); !errors.Is(err, cache.ErrKeyExists) {
+		t.Errorf("SetOrFail() of existing item error = %v, want %v", err, cache.ErrKeyExists)
+	}
+	if ttl, _ := c.GetTTL(ctx, "custom"); ttl > time.Minute {
+		t.Errorf("GetTTL() after failed SetOrFail() = %s, want up to %s", ttl, time.Minute)
+	}
+}
+
+func TestRedis_SetOrFail_NoExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := newRedisCache(t, 0)
+
+	if err := c.SetOrFail(ctx, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := c.GetTTL(ctx, "key"); err != nil || ttl != cache.NoExpiration {
+		t.Errorf("GetTTL() = %s, %v, want %s", ttl, err, cache.NoExpiration)
+	}
+}
+
+func TestRedis_SetOrFail_Expired(t *testing.T) {
+	ctx := context.Background()
+	c := newRedisCache(t, 0)
+
+	if err := c.SetOrFail(ctx, "key", "first", cache.WithTTL(100*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	if err := c.SetOrFail(ctx, "key", "second"); err != nil {
+		t.Fatalf("SetOrFail() of expired item error = %v", err)
+	}
+	if value, _ := c.Get(ctx, "key"); value != "second" {
+		t.Errorf("Get() = %q, want %q", value, "second")
+	}
+}
+
+func TestRedis_SetOrFail_Race(t *testing.T) {
+	const workers = 32
+
+	ctx := context.Background()
+	c := newRedisCache(t, 0)
+
+	var (
+		wg      sync.WaitGroup
+		mux     sync.Mutex
+		winners []string
+	)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value := fmt.Sprintf("value-%d", i)
+			err := c.SetOrFail(ctx, "key", value, cache.WithTTL(time.Minute), cache.WithTags("tag"))
+			if errors.Is(err, cache.ErrKeyExists) {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mux.Lock()
+			winners = append(winners, value)
+			mux.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(winners) != 1 {
+		t.Fatalf("SetOrFail() succeeded %d times, want once", len(winners))
+	}
+	if value, _ := c.Get(ctx, "key"); value != winners[0] {
+		t.Errorf("Get() = %q, want %q", value, winners[0])
+	}
+	if ttl, err := c.GetTTL(ctx, "key"); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("GetTTL() = %s, %v, want up to %s", ttl, err, time.Minute)
+	}
+
+	if err := c.InvalidateTag(ctx, "tag"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Get() after InvalidateTag() error = %v, want %v", err, cache.ErrKeyNotFound)
+	}
+}
+
+func TestRedis_ExpiredErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		opts []cache.RedisOption
+		want error
+	}{
+		{name: "default", want: cache.ErrKeyNotFound},
+		{name: "lazy", opts: []cache.RedisOption{cache.WithRedisLazyExpiration()}, want: cache.ErrKeyExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newRedisCache(t, 0, tt.opts...)
+
+			for _, key := range []string{"get", "ttl", "take"} {
+				_ = c.Set(ctx, key, "value", cache.WithTTL(100*time.Millisecond))
+			}
+			time.Sleep(200 * time.Millisecond)
+
+			_, getErr := c.Get(ctx, "get")
+			_, ttlErr := c.GetTTL(ctx, "ttl")
+			_, takeErr := c.GetAndDelete(ctx, "take")
+			for _, err := range []error{getErr, ttlErr, takeErr} {
+				if err != tt.want {
+					t.Errorf("error = %v, want %v", err, tt.want)
+				}
+			}
+
+			if err := c.Cleanup(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.Get(ctx, "get"); err != cache.ErrKeyNotFound {
+				t.Errorf("Get() after Cleanup() error = %v, want %v", err, cache.ErrKeyNotFound)
+			}
+		})
+	}
+}
+
+func TestRedis_TagSets(t *testing.T) {
+	ctx := context.Background()
+	c, client, prefix := newRedisCacheWithClient(t, 0)
+	tagKey := prefix + "cache:tag:tag"
+
+	members := func() []string {
+		t.Helper()
+
+		res, err := client.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	for _, key := range []string{"delete", "take", "drain", "untagged"} {
+		if err := c.Set(ctx, key, "value", cache.WithTTL(time.Minute), cache.WithTags("tag")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ttl, err := client.PTTL(ctx, tagKey).Result(); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("tag set ttl = %s, %v, want up to %s", ttl, err, time.Minute)
+	}
+
+	if err := c.Set(ctx, "untagged", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "delete"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetAndDelete(ctx, "take"); err != nil {
+		t.Fatal(err)
+	}
+	if got := members(); len(got) != 1 || got[0] != "drain" {
+		t.Errorf("tag set = %v, want [drain]", got)
+	}
+
+	if _, err := c.DrainN(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := client.Exists(ctx, tagKey).Result(); exists != 0 {
+		t.Errorf("tag set exists after drain: %v", members())
+	}
+
+	if err := c.Set(ctx, "persistent", "value", cache.WithTags("tag")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set(ctx, "expiring", "value", cache.WithTTL(time.Minute), cache.WithTags("tag")); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := client.PTTL(ctx, tagKey).Result(); ttl != -1 {
+		t.Errorf("tag set ttl = %s, want no expiration", ttl)
+	}
+}
@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// maxUpdateAttempts is the number of attempts of Update to swap the value
+// changed concurrently.
+const maxUpdateAttempts = 10
+
+// Typed wraps a Cache to store values of type T. Values are encoded as JSON,
+// so it works with any backend.
+type Typed[T any] struct {
+	cache Cache
+}
+
+// NewTyped returns the typed view of the cache.
+func NewTyped[T any](cache Cache) *Typed[T] {
+	return &Typed[T]{
+		cache: cache,
+	}
+}
+
+// Set sets the value for the given key in the cache.
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, opts ...Option) error {
+	data, err := t.encode(value)
+	if err != nil {
+		return err
+	}
+
+	return t.cache.Set(ctx, key, data, opts...)
+}
+
+// SetOrFail is like Set, but returns ErrKeyExists if the key already exists.
+func (t *Typed[T]) SetOrFail(ctx context.Context, key string, value T, opts ...Option) error {
+	data, err := t.encode(value)
+	if err != nil {
+		return err
+	}
+
+	return t.cache.SetOrFail(ctx, key, data, opts...)
+}
+
+// Get gets the value for the given key from the cache.
+//
+// It returns the same errors as Cache.Get and ErrInvalidValue if the stored
+// value can't be decoded.
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	data, err := t.cache.Get(ctx, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return t.decode(key, data)
+}
+
+// CompareAndSwap replaces the value if the stored one equals oldValue.
+func (t *Typed[T]) CompareAndSwap(ctx context.Context, key string, oldValue, newValue T, opts ...Option) error {
+	oldData, err := t.encode(oldValue)
+	if err != nil {
+		return err
+	}
+
+	newData, err := t.encode(newValue)
+	if err != nil {
+		return err
+	}
+
+	return t.cache.CompareAndSwap(ctx, key, oldData, newData, opts...)
+}
+
+// Update atomically replaces the stored value with the one returned by
+// update. The value is swapped only if it hasn't changed since it was read,
+// otherwise update is called again with the current value, up to
+// maxUpdateAttempts times. Options apply to the new value as in Set.
+//
+// If the key is not found or has expired, it returns ErrKeyNotFound. Errors of
+// update are returned as is and nothing is stored. If the value keeps
+// changing, it returns ErrValueMismatch.
+func (t *Typed[T]) Update(ctx context.Context, key string, update func(T) (T, error), opts ...Option) (T, error) {
+	var zero T
+	for range maxUpdateAttempts {
+		oldData, err := t.cache.Get(ctx, key)
+		if err != nil {
+			return zero, err
+		}
+
+		value, err := t.decode(key, oldData)
+		if err != nil {
+			return zero, err
+		}
+
+		value, err = update(value)
+		if err != nil {
+			return value, err
+		}
+
+		newData, err := t.encode(value)
+		if err != nil {
+			return zero, err
+		}
+
+		err = t.cache.CompareAndSwap(ctx, key, oldData, newData, opts...)
+		if errors.Is(err, ErrValueMismatch) {
+			continue
+		}
+		if err != nil {
+			return zero, err
+		}
+
+		return value, nil
+	}
+
+	return zero, ErrValueMismatch
+}
+
+// GetOrSet gets the value for the given key, or loads and stores it. See
+// Cache.GetOrSet for details.
+func (t *Typed[T]) GetOrSet(ctx context.Context, key string, load func() (T, error), opts ...Option) (T, error) {
+	data, err := t.cache.GetOrSet(ctx, key, func() (string, error) {
+		value, err := load()
+		if err != nil {
+			return "", err
+		}
+
+		return t.encode(value)
+	}, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return t.decode(key, data)
+}
+
+// GetAndDelete is like Get, but also deletes the key from the cache.
+func (t *Typed[T]) GetAndDelete(ctx context.Context, key string) (T, error) {
+	data, err := t.cache.GetAndDelete(ctx, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return t.decode(key, data)
+}
+
+// GetTTL returns the remaining lifetime of the item.
+func (t *Typed[T]) GetTTL(ctx context.Context, key string) (time.Duration, error) {
+	return t.cache.GetTTL(ctx, key)
+}
+
+// Touch sets the lifetime of the existing item without rewriting the value.
+func (t *Typed[T]) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	return t.cache.Touch(ctx, key, ttl)
+}
+
+// Delete removes the item associated with the given key from the cache.
+func (t *Typed[T]) Delete(ctx context.Context, key string) error {
+	return t.cache.Delete(ctx, key)
+}
+
+// InvalidateTag removes all the items with the tag attached by WithTags.
+func (t *Typed[T]) InvalidateTag(ctx context.Context, tag string) error {
+	return t.cache.InvalidateTag(ctx, tag)
+}
+
+// Keys returns the keys of the non-expired items matching the pattern.
+func (t *Typed[T]) Keys(ctx context.Context, pattern string) ([]string, error) {
+	return t.cache.Keys(ctx, pattern)
+}
+
+// Cleanup removes all expired items from the cache.
+func (t *Typed[T]) Cleanup(ctx context.Context) error {
+	return t.cache.Cleanup(ctx)
+}
+
+// Drain returns all the non-expired items and clears the cache. Items that
+// can't be decoded are dropped and reported with ErrInvalidValue along with
+// the decoded ones.
+func (t *Typed[T]) Drain(ctx context.Context) (map[string]T, error) {
+	items, err := t.cache.Drain(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.decodeAll(items)
+}
+
+// DrainN is like Drain, but removes at most n items.
+func (t *Typed[T]) DrainN(ctx context.Context, n int) (map[string]T, error) {
+	items, err := t.cache.DrainN(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.decodeAll(items)
+}
+
+// decodeAll decodes the drained items, the ones that can't be decoded are
+// dropped and reported.
+func (t *Typed[T]) decodeAll(items map[string]string) (map[string]T, error) {
+	result := make(map[string]T, len(items))
+	errs := []error{}
+	for key, data := range items {
+		value, err := t.decode(key, data)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result[key] = value
+	}
+
+	return result, errors.Join(errs...)
+}
+
+func (t *Typed[T]) encode(value T) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("can't marshal value: %w", err)
+	}
+
+	return string(data), nil
+}
+
+func (t *Typed[T]) decode(key, data string) (T, error) {
+	var value T
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return value, fmt.Errorf("%w: key %q: %w", ErrInvalidValue, key, err)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,170 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/android-sms-gateway/server/pkg/cache"
+)
+
+type typedValue struct {
+	Name  string    `json:"name"`
+	Count int       `json:"count"`
+	At    time.Time `json:"at"`
+}
+
+func TestTyped_SetAndGet(t *testing.T) {
+	c := cache.NewTyped[typedValue](cache.NewMemory(0))
+	ctx := context.Background()
+
+	value := typedValue{Name: "test", Count: 3, At: time.Date(2025, 11, 3, 12, 0, 0, 0, time.UTC)}
+	if err := c.Set(ctx, "key", value); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	got, err := c.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got != value {
+		t.Errorf("Expected %+v, got %+v", value, got)
+	}
+}
+
+func TestTyped_GetNotFound(t *testing.T) {
+	c := cache.NewTyped[typedValue](cache.NewMemory(0))
+
+	_, err := c.Get(context.Background(), "missing")
+	if !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestTyped_GetInvalidValue(t *testing.T) {
+	raw := cache.NewMemory(0)
+	c := cache.NewTyped[typedValue](raw)
+	ctx := context.Background()
+
+	if err := raw.Set(ctx, "key", "not json"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	_, err := c.Get(ctx, "key")
+	if !errors.Is(err, cache.ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue, got %v", err)
+	}
+}
+
+func TestTyped_SetOrFail(t *testing.T) {
+	c := cache.NewTyped[[]int](cache.NewMemory(0))
+	ctx := context.Background()
+
+	if err := c.SetOrFail(ctx, "key", []int{1, 2}); err != nil {
+		t.Fatalf("SetOrFail failed: %v", err)
+	}
+
+	if err := c.SetOrFail(ctx, "key", []int{3}); !errors.Is(err, cache.ErrKeyExists) {
+		t.Errorf("Expected ErrKeyExists, got %v", err)
+	}
+}
+
+func TestTyped_GetAndDelete(t *testing.T) {
+	c := cache.NewTyped[typedValue](cache.NewMemory(0))
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "key", typedValue{Name: "test"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	got, err := c.GetAndDelete(ctx, "key")
+	if err != nil {
+		t.Fatalf("GetAndDelete failed: %v", err)
+	}
+	if got.Name != "test" {
+		t.Errorf("Expected test, got %s", got.Name)
+	}
+
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestTyped_Update(t *testing.T) {
+	c := cache.NewTyped[[]int](cache.NewMemory(0))
+	ctx := context.Background()
+
+	if _, err := c.Update(ctx, "key", func(v []int) ([]int, error) { return v, nil }); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+
+	if err := c.Set(ctx, "key", []int{}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	const workers = 8
+	wg := sync.WaitGroup{}
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, err := c.Update(ctx, "key", func(v []int) ([]int, error) { return append(v, i), nil })
+				if errors.Is(err, cache.ErrValueMismatch) {
+					continue
+				}
+				if err != nil {
+					t.Errorf("Update failed: %v", err)
+				}
+				return
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := c.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(got) != workers {
+		t.Errorf("Expected %d values, got %v", workers, got)
+	}
+
+	errAbort := errors.New("abort")
+	if _, err := c.Update(ctx, "key", func(v []int) ([]int, error) { return nil, errAbort }); !errors.Is(err, errAbort) {
+		t.Errorf("Expected %v, got %v", errAbort, err)
+	}
+	if got, _ := c.Get(ctx, "key"); len(got) != workers {
+		t.Errorf("Expected the value to be kept, got %v", got)
+	}
+}
+
+func TestTyped_Drain(t *testing.T) {
+	raw := cache.NewMemory(0)
+	c := cache.NewTyped[typedValue](raw)
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "a", typedValue{Name: "a"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Set(ctx, "b", typedValue{Name: "b"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := raw.Set(ctx, "invalid", "{"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	items, err := c.Drain(ctx)
+	if !errors.Is(err, cache.ErrInvalidValue) {
+		t.Errorf("Expected ErrInvalidValue, got %v", err)
+	}
+	if len(items) != 2 || items["a"].Name != "a" || items["b"].Name != "b" {
+		t.Errorf("Unexpected items: %+v", items)
+	}
+
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound after drain, got %v", err)
+	}
+}
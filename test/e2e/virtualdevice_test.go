@@ -0,0 +1,77 @@
+package e2e
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const (
+	virtualDeviceLogin    = "virtual"
+	virtualDevicePassword = "virtual-password"
+)
+
+func TestVirtualDevice_MessageOutcomes(t *testing.T) {
+	client := publicUserClient.Clone().
+		SetBasicAuth(virtualDeviceLogin, virtualDevicePassword)
+
+	cases := []struct {
+		name          string
+		phoneNumber   string
+		expectedState string
+	}{
+		{
+			name:          "delivered",
+			phoneNumber:   "+79990001234",
+			expectedState: "Delivered",
+		},
+		{
+			name:          "failed",
+			phoneNumber:   "+79990000000",
+			expectedState: "Failed",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := client.R().
+				SetHeader("Content-Type", "application/json").
+				SetBody(map[string]any{
+					"message":      "test",
+					"phoneNumbers": []string{c.phoneNumber},
+				}).
+				Post("messages")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode() != 202 {
+				t.Fatal(res.StatusCode(), res.String())
+			}
+
+			var created messageState
+			if err := json.Unmarshal(res.Body(), &created); err != nil {
+				t.Fatal(err)
+			}
+
+			state := ""
+			for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(200 * time.Millisecond) {
+				res, err := client.R().Get("messages/" + created.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var current messageState
+				if err := json.Unmarshal(res.Body(), &current); err != nil {
+					t.Fatal(err)
+				}
+
+				state = current.State
+				if state == c.expectedState {
+					return
+				}
+			}
+
+			t.Errorf("expected state %s, got %s", c.expectedState, state)
+		})
+	}
+}